@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/config"
+	"github.com/otaviocarvalho/tramuntana/internal/federation"
+	"github.com/spf13/cobra"
+)
+
+func newCoordinatorCmd() *cobra.Command {
+	var envFile string
+	cmd := &cobra.Command{
+		Use:   "coordinator",
+		Short: "Serve one Telegram bot for several hosts running serve",
+		Long: `Coordinator owns the bot token and routes each topic to one of the hosts in
+TRAMUNTANA_FEDERATION_HOSTS. Hosts run serve with TRAMUNTANA_COORDINATOR set to
+this coordinator's URL and TELEGRAM_BOT_TOKEN set to "<host>:<secret>". A new
+topic's first message asks which host it runs on; /host shows the choice.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var files []string
+			if envFile != "" {
+				files = append(files, envFile)
+			}
+			cfg, err := config.Load(files...)
+			if err != nil {
+				return fmt.Errorf("loading config: %w", err)
+			}
+			return runCoordinator(cfg)
+		},
+	}
+	cmd.Flags().StringVar(&envFile, "config", "", "path to .env config file")
+	return cmd
+}
+
+func runCoordinator(cfg *config.Config) error {
+	if cfg.FederationAddr == "" {
+		return fmt.Errorf("TRAMUNTANA_FEDERATION_ADDR is required")
+	}
+	hosts, err := federation.ParseHosts(cfg.FederationHosts)
+	if err != nil {
+		return fmt.Errorf("invalid TRAMUNTANA_FEDERATION_HOSTS: %w", err)
+	}
+	if len(hosts) == 0 {
+		return fmt.Errorf("TRAMUNTANA_FEDERATION_HOSTS is required")
+	}
+	routes, err := federation.LoadRoutes(filepath.Join(cfg.TramuntanaDir, federation.File))
+	if err != nil {
+		return err
+	}
+
+	c := federation.New(federation.Options{
+		Endpoint: tgbotapi.APIEndpoint,
+		Token:    cfg.TelegramBotToken,
+		Hosts:    hosts,
+		Routes:   routes,
+		Allowed:  cfg.IsAllowedUser,
+	})
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+	errc := make(chan error, 1)
+	go func() { errc <- c.Serve(ctx, cfg.FederationAddr) }()
+	go c.Run(ctx)
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+		return <-errc
+	}
+}
@@ -3,19 +3,21 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/signal"
-	"path/filepath"
 	"syscall"
+	"time"
+	_ "time/tzdata" // /timezone works on hosts without a zone database
 
 	"github.com/joho/godotenv"
 	"github.com/otaviocarvalho/tramuntana/hook"
-	"github.com/otaviocarvalho/tramuntana/internal/bot"
+	"github.com/otaviocarvalho/tramuntana/internal/chaos"
 	"github.com/otaviocarvalho/tramuntana/internal/config"
-	"github.com/otaviocarvalho/tramuntana/internal/monitor"
-	"github.com/otaviocarvalho/tramuntana/internal/queue"
-	"github.com/otaviocarvalho/tramuntana/internal/state"
+	"github.com/otaviocarvalho/tramuntana/internal/dryrun"
+	"github.com/otaviocarvalho/tramuntana/internal/serve"
+	"github.com/otaviocarvalho/tramuntana/internal/telemetry"
 	"github.com/spf13/cobra"
 )
 
@@ -24,6 +26,9 @@ var (
 	cfgPath     string
 	cfg         *config.Config
 	installHook bool
+	dryRun      bool
+	dryRunOut   string
+	chaosSpec   string
 )
 
 func main() {
@@ -39,6 +44,11 @@ func main() {
 			if cfgPath != "" {
 				_ = godotenv.Load(cfgPath)
 			}
+			if dryRun {
+				// No bot is needed; fill in the required settings if absent
+				setenvDefault("TELEGRAM_BOT_TOKEN", "dry-run")
+				setenvDefault("ALLOWED_USERS", "1")
+			}
 			var err error
 			cfg, err = config.Load()
 			if err != nil {
@@ -51,6 +61,10 @@ func main() {
 		},
 	}
 	serveCmd.Flags().StringVar(&cfgPath, "config", "", "path to .env config file")
+	serveCmd.Flags().BoolVar(&dryRun, "dry-run", false, "print Telegram requests instead of sending them; stdin lines become topic messages")
+	serveCmd.Flags().StringVar(&dryRunOut, "dry-run-out", "", "with --dry-run, append requests to this file instead of stdout")
+	serveCmd.Flags().StringVar(&chaosSpec, "chaos", "", "inject random faults: a probability, or pairs like tmux=0.1,telegram=0.05,jsonl=0.02")
+	serveCmd.Flags().MarkHidden("chaos")
 
 	hookCmd := &cobra.Command{
 		Use:   "hook",
@@ -72,62 +86,94 @@ func main() {
 		},
 	}
 
-	rootCmd.AddCommand(serveCmd, hookCmd, versionCmd)
+	rootCmd.AddCommand(serveCmd, hookCmd, newReplayCmd(), newMigrateCmd(), newCoordinatorCmd(), versionCmd)
 
 	if err := rootCmd.Execute(); err != nil {
 		os.Exit(1)
 	}
 }
 
-func runServe() error {
-	// Create bot
-	b, err := bot.New(cfg)
-	if err != nil {
-		return fmt.Errorf("creating bot: %w", err)
+// setenvDefault sets an environment variable unless it already has a value.
+func setenvDefault(key, value string) {
+	if os.Getenv(key) == "" {
+		os.Setenv(key, value)
+	}
+}
+
+// newDryRunBridge creates a bridge whose Telegram requests go to the console
+// sink. Console input arrives from the first allowed user in topic 1 of the
+// first allowed group.
+func newDryRunBridge() (*serve.Bridge, error) {
+	out, dest := io.Writer(os.Stdout), "stdout"
+	if dryRunOut != "" {
+		f, err := os.OpenFile(dryRunOut, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("opening dry-run output: %w", err)
+		}
+		out, dest = f, dryRunOut
 	}
 
-	// Load monitor state
-	msPath := filepath.Join(cfg.TramuntanaDir, "monitor_state.json")
-	ms, err := state.LoadMonitorState(msPath)
+	console := dryrun.Console{UserID: cfg.AllowedUsers[0], ChatID: -100, ThreadID: 1}
+	if len(cfg.AllowedGroups) > 0 {
+		console.ChatID = cfg.AllowedGroups[0]
+	}
+	api, err := dryrun.NewConsoleAPI(out, os.Stdin, console)
 	if err != nil {
-		log.Printf("Warning: loading monitor state: %v (starting fresh)", err)
-		ms = state.NewMonitorState()
+		return nil, err
 	}
-	b.SetMonitorState(ms)
+	log.Printf("Dry run: requests go to %s; type messages for user %d in chat %d topic %d (cb:<data> presses a button)",
+		dest, console.UserID, console.ChatID, console.ThreadID)
+	return serve.NewWithAPI(cfg, api)
+}
 
-	// Startup recovery: reconcile state with live tmux windows
-	liveBindings := b.ReconcileState()
-	log.Printf("Startup: %d live bindings recovered", liveBindings)
+func runServe() error {
+	if cfg.LegacyLayout {
+		log.Printf("Using %s for config and state; run `tramuntana migrate` to move to the XDG directories", cfg.TramuntanaDir)
+	}
 
-	// Create message queue
-	q := queue.New(b.API())
-	b.SetQueue(q)
+	// Create bridge
+	var br *serve.Bridge
+	var err error
+	if dryRun {
+		br, err = newDryRunBridge()
+	} else {
+		br, err = serve.New(cfg)
+	}
+	if err != nil {
+		return fmt.Errorf("creating bot: %w", err)
+	}
 
-	// Create session monitor
-	mon := monitor.New(cfg, b.State(), ms, q)
-	mon.PlanHandler = b.HandlePlanFromMonitor
+	// Fault injection for resilience testing; inside tracing so spans see the faults
+	if chaosSpec != "" {
+		rates, err := chaos.Parse(chaosSpec)
+		if err != nil {
+			return fmt.Errorf("parsing --chaos: %w", err)
+		}
+		chaos.Enable(rates, time.Now().UnixNano())
+		br.API().Client = chaos.WrapHTTPClient(br.API().Client)
+		log.Printf("WARNING: chaos mode injecting faults: %v", rates)
+	}
 
-	// Create status poller
-	sp := bot.NewStatusPoller(b, q, mon)
+	// Export spans for updates, tmux commands, Bot API calls and deliveries
+	if cfg.Tracing {
+		shutdown, err := telemetry.Setup(context.Background())
+		if err != nil {
+			return fmt.Errorf("setting up tracing: %w", err)
+		}
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := shutdown(ctx); err != nil {
+				log.Printf("Error flushing traces: %v", err)
+			}
+		}()
+		br.API().Client = telemetry.WrapHTTPClient(br.API().Client)
+	}
 
 	// Context for graceful shutdown
 	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
 
-	// Start monitor in background
-	go mon.Run(ctx)
-
-	// Start status poller in background
-	go sp.Run(ctx)
-
-	// Run bot (blocks until ctx is cancelled)
-	err = b.Run(ctx)
-
-	// Graceful shutdown: save all state
-	log.Println("Saving state...")
-	if err := ms.ForceSave(msPath); err != nil {
-		log.Printf("Error saving monitor state: %v", err)
-	}
-
-	return err
+	// Run the bridge (blocks until ctx is cancelled, then saves state)
+	return br.Run(ctx)
 }
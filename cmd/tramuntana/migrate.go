@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/otaviocarvalho/tramuntana/internal/layout"
+	"github.com/spf13/cobra"
+)
+
+func newMigrateCmd() *cobra.Command {
+	var dryRun bool
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Move files from the flat Tramuntana directory to the XDG directories",
+		Long: `Migrate moves everything in TRAMUNTANA_DIR (or ~/.tramuntana) into the XDG
+base directories: .env and webhooks.json to $XDG_CONFIG_HOME/tramuntana, all
+other files to $XDG_STATE_HOME/tramuntana. Stop serve first. Nothing already in
+the XDG directories is overwritten.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runMigrate(dryRun)
+		},
+	}
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "print what would be moved without moving it")
+	return cmd
+}
+
+func runMigrate(dryRun bool) error {
+	from := layout.Resolve()
+	if !from.Flat() {
+		fmt.Printf("Already using the XDG layout (config %s, state %s).\n", from.Config, from.State)
+		return nil
+	}
+	if _, err := os.Stat(from.State); os.IsNotExist(err) {
+		fmt.Printf("Nothing to migrate: %s does not exist.\n", from.State)
+		return nil
+	}
+
+	to := layout.XDG()
+	moves, err := layout.Migrate(from.State, to, dryRun)
+	for _, m := range moves {
+		fmt.Printf("%s -> %s\n", m.From, m.To)
+	}
+	if err != nil {
+		return err
+	}
+	if dryRun {
+		fmt.Printf("Dry run: %d entries would be moved.\n", len(moves))
+		return nil
+	}
+	fmt.Printf("Moved %d entries.\n", len(moves))
+	if os.Getenv("TRAMUNTANA_DIR") != "" {
+		fmt.Println("Unset TRAMUNTANA_DIR so serve and the hook use the new directories.")
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/joho/godotenv"
+	"github.com/otaviocarvalho/tramuntana/internal/config"
+	"github.com/otaviocarvalho/tramuntana/internal/dryrun"
+	"github.com/otaviocarvalho/tramuntana/internal/monitor"
+	"github.com/otaviocarvalho/tramuntana/internal/queue"
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+	"github.com/spf13/cobra"
+)
+
+// replayOptions holds the replay subcommand's flags.
+type replayOptions struct {
+	speed     float64
+	verbosity string
+	send      bool
+	chatID    int64
+	threadID  int
+	config    string
+}
+
+func newReplayCmd() *cobra.Command {
+	var opts replayOptions
+	cmd := &cobra.Command{
+		Use:   "replay <transcript.jsonl>",
+		Short: "Replay a recorded Claude transcript through the message pipeline",
+		Long: `Replay feeds a saved Claude Code transcript through the monitor, renderer and
+message queue, printing the Telegram requests that would be made. With --send
+the messages are delivered to a real topic instead.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runReplay(args[0], opts)
+		},
+	}
+	cmd.Flags().Float64Var(&opts.speed, "speed", 0, "playback speed relative to the recorded timestamps (0 = no pauses)")
+	cmd.Flags().StringVar(&opts.verbosity, "verbosity", "", "verbosity profile (default: TRAMUNTANA_VERBOSITY or normal)")
+	cmd.Flags().BoolVar(&opts.send, "send", false, "send to Telegram instead of printing (needs --chat and a bot token)")
+	cmd.Flags().Int64Var(&opts.chatID, "chat", 0, "chat ID to send to with --send")
+	cmd.Flags().IntVar(&opts.threadID, "thread", 0, "topic (message thread) ID to send to with --send")
+	cmd.Flags().StringVar(&opts.config, "config", "", "path to .env config file (with --send)")
+	return cmd
+}
+
+func runReplay(path string, opts replayOptions) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var api *tgbotapi.BotAPI
+	replayCfg := &config.Config{Verbosity: os.Getenv("TRAMUNTANA_VERBOSITY")}
+	if opts.send {
+		if opts.chatID == 0 {
+			return fmt.Errorf("--send needs --chat")
+		}
+		if opts.config != "" {
+			_ = godotenv.Load(opts.config)
+		}
+		replayCfg, err = config.Load()
+		if err != nil {
+			return fmt.Errorf("loading config: %w", err)
+		}
+		api, err = tgbotapi.NewBotAPI(replayCfg.TelegramBotToken)
+		if err != nil {
+			return fmt.Errorf("creating bot API: %w", err)
+		}
+	} else {
+		api, err = dryrun.NewAPI(os.Stdout)
+		if err != nil {
+			return err
+		}
+	}
+	if opts.verbosity != "" {
+		replayCfg.Verbosity = opts.verbosity
+	}
+	if replayCfg.Verbosity == "" {
+		replayCfg.Verbosity = "normal"
+	}
+
+	q := queue.New(api)
+	mon := monitor.New(replayCfg, state.NewState(), state.NewMonitorState(), q)
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	target := monitor.ReplayTarget{UserID: 1, ChatID: opts.chatID, ThreadID: opts.threadID, WindowID: "replay"}
+	n, err := mon.Replay(ctx, f, target, opts.speed)
+	if err != nil {
+		return err
+	}
+
+	// Let the queue finish delivering before exiting
+	for !q.Idle() {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+	log.Printf("Replayed %d entries from %s", n, path)
+	return nil
+}
@@ -7,7 +7,9 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
 
+	"github.com/otaviocarvalho/tramuntana/internal/layout"
 	"github.com/otaviocarvalho/tramuntana/internal/state"
 	"github.com/otaviocarvalho/tramuntana/internal/tmux"
 )
@@ -17,29 +19,33 @@ type hookInput struct {
 	SessionID     string `json:"session_id"`
 	CWD           string `json:"cwd"`
 	HookEventName string `json:"hook_event_name"`
+	Message       string `json:"message"` // Notification only
 }
 
+// hookEvents are the Claude Code hooks Install registers.
+var hookEvents = []string{"SessionStart", "Notification"}
+
 var uuidRegex = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
 
-// Run executes the SessionStart hook logic:
-// reads stdin JSON, gets tmux pane info, writes to session_map.json.
-// Does NOT import config package — uses TRAMUNTANA_DIR env or ~/.tramuntana.
+// Run executes the hook: reads stdin JSON and, for SessionStart, writes the
+// window's session to session_map.json; for Notification, appends it to
+// notifications.jsonl for the bot to deliver.
+// Does NOT import config package — finds the state directory with layout.Resolve.
 func Run() error {
 	var input hookInput
 	if err := json.NewDecoder(os.Stdin).Decode(&input); err != nil {
 		return fmt.Errorf("reading stdin JSON: %w", err)
 	}
 
-	if input.HookEventName != "SessionStart" {
-		return nil // ignore non-SessionStart hooks
+	switch input.HookEventName {
+	case "SessionStart", "Notification":
+	default:
+		return nil // ignore other hooks
 	}
 
 	if !uuidRegex.MatchString(input.SessionID) {
 		return fmt.Errorf("invalid session_id: %q", input.SessionID)
 	}
-	if !filepath.IsAbs(input.CWD) {
-		return fmt.Errorf("cwd is not absolute: %q", input.CWD)
-	}
 
 	paneID := os.Getenv("TMUX_PANE")
 	if paneID == "" {
@@ -62,19 +68,28 @@ func Run() error {
 	windowName := parts[2]
 	key := sessionName + ":" + windowID
 
-	// Resolve tramuntana dir
-	dir := os.Getenv("TRAMUNTANA_DIR")
-	if dir == "" {
-		home, err := os.UserHomeDir()
-		if err != nil {
-			return fmt.Errorf("getting home dir: %w", err)
-		}
-		dir = filepath.Join(home, ".tramuntana")
-	}
+	// Resolve tramuntana state dir
+	dir := layout.Resolve().State
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return fmt.Errorf("creating tramuntana dir: %w", err)
 	}
 
+	if input.HookEventName == "Notification" {
+		if strings.TrimSpace(input.Message) == "" {
+			return nil
+		}
+		return state.AppendNotification(filepath.Join(dir, state.NotificationsFile), state.HookNotification{
+			Time:      time.Now(),
+			Key:       key,
+			SessionID: input.SessionID,
+			Message:   input.Message,
+		})
+	}
+
+	if !filepath.IsAbs(input.CWD) {
+		return fmt.Errorf("cwd is not absolute: %q", input.CWD)
+	}
+
 	sessionMapPath := filepath.Join(dir, "session_map.json")
 
 	return state.ReadModifyWriteSessionMap(sessionMapPath, func(data map[string]state.SessionMapEntry) {
@@ -121,29 +136,27 @@ func Install() error {
 	}
 
 	hookCommand := exePath + " hook"
-
-	// Check if already installed
 	if isHookInstalled(settings, hookCommand) {
 		fmt.Println("Hook already installed.")
 		return nil
 	}
 
-	// Add hook entry
+	// Add the hook for each event that doesn't have it yet
 	hooks, _ := settings["hooks"].(map[string]any)
 	if hooks == nil {
 		hooks = make(map[string]any)
 	}
-
-	sessionStart, _ := hooks["SessionStart"].([]any)
-
-	hookEntry := map[string]any{
-		"type":    "command",
-		"command": hookCommand,
-		"timeout": 5,
+	for _, event := range hookEvents {
+		if isEventHookInstalled(settings, event, hookCommand) {
+			continue
+		}
+		entries, _ := hooks[event].([]any)
+		hooks[event] = append(entries, map[string]any{
+			"type":    "command",
+			"command": hookCommand,
+			"timeout": 5,
+		})
 	}
-
-	sessionStart = append(sessionStart, hookEntry)
-	hooks["SessionStart"] = sessionStart
 	settings["hooks"] = hooks
 
 	// Write back atomically
@@ -160,20 +173,32 @@ func Install() error {
 	return nil
 }
 
-// isHookInstalled checks if a hook with the given command is already present.
+// isHookInstalled reports whether the hook is registered for every event in
+// hookEvents.
 func isHookInstalled(settings map[string]any, command string) bool {
+	for _, event := range hookEvents {
+		if !isEventHookInstalled(settings, event, command) {
+			return false
+		}
+	}
+	return true
+}
+
+// isEventHookInstalled reports whether command, or a tramuntana hook installed
+// from another path, is registered for event.
+func isEventHookInstalled(settings map[string]any, event, command string) bool {
 	hooks, _ := settings["hooks"].(map[string]any)
 	if hooks == nil {
 		return false
 	}
-	sessionStart, _ := hooks["SessionStart"].([]any)
-	for _, entry := range sessionStart {
+	entries, _ := hooks[event].([]any)
+	for _, entry := range entries {
 		m, _ := entry.(map[string]any)
 		if m == nil {
 			continue
 		}
 		cmd, _ := m["command"].(string)
-		if strings.Contains(cmd, "tramuntana hook") {
+		if cmd == command || strings.HasSuffix(cmd, "tramuntana hook") {
 			return true
 		}
 	}
@@ -31,9 +31,46 @@ func TestIsHookInstalled(t *testing.T) {
 							"timeout": 5,
 						},
 					},
+					"Notification": []any{
+						map[string]any{
+							"type":    "command",
+							"command": "/usr/bin/tramuntana hook",
+							"timeout": 5,
+						},
+					},
+				},
+			},
+			command: "/usr/bin/tramuntana hook",
+			want:    true,
+		},
+		{
+			name: "missing an event",
+			settings: map[string]any{
+				"hooks": map[string]any{
+					"SessionStart": []any{
+						map[string]any{
+							"type":    "command",
+							"command": "/usr/bin/tramuntana hook",
+						},
+					},
 				},
 			},
 			command: "/usr/bin/tramuntana hook",
+			want:    false,
+		},
+		{
+			name: "renamed binary",
+			settings: map[string]any{
+				"hooks": map[string]any{
+					"SessionStart": []any{
+						map[string]any{"type": "command", "command": "/opt/bridge/tm hook"},
+					},
+					"Notification": []any{
+						map[string]any{"type": "command", "command": "/opt/bridge/tm hook"},
+					},
+				},
+			},
+			command: "/opt/bridge/tm hook",
 			want:    true,
 		},
 		{
@@ -98,6 +135,7 @@ func TestInstall_CreatesSettingsFile(t *testing.T) {
 		"timeout": 5,
 	}
 	hooks["SessionStart"] = []any{hookEntry}
+	hooks["Notification"] = []any{hookEntry}
 	settings["hooks"] = hooks
 
 	out, _ := json.MarshalIndent(settings, "", "  ")
@@ -134,3 +172,17 @@ func TestUUIDRegex(t *testing.T) {
 		}
 	}
 }
+
+func TestIsEventHookInstalled(t *testing.T) {
+	settings := map[string]any{
+		"hooks": map[string]any{
+			"SessionStart": []any{map[string]any{"type": "command", "command": "/usr/bin/tramuntana hook"}},
+		},
+	}
+	if !isEventHookInstalled(settings, "SessionStart", "/usr/local/bin/tramuntana hook") {
+		t.Error("SessionStart hook should be installed")
+	}
+	if isEventHookInstalled(settings, "Notification", "/usr/local/bin/tramuntana hook") {
+		t.Error("Notification hook should be missing, so Install adds it")
+	}
+}
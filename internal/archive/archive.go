@@ -0,0 +1,137 @@
+// Package archive keeps the transcripts of closed sessions, compressed
+// together with a rendered digest, in a directory or an S3 bucket, so they
+// can be read after Claude's own files are gone.
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Names of the files inside an archive.
+const (
+	TranscriptFile = "transcript.jsonl"
+	DigestFile     = "digest.txt"
+)
+
+// maxEntrySize bounds one file read back out of an archive.
+const maxEntrySize = 512 << 20
+
+// Store keeps archives by name.
+type Store interface {
+	Name() string
+	Put(ctx context.Context, name string, data []byte) error
+	Get(ctx context.Context, name string) ([]byte, error)
+}
+
+// Bundle compresses a transcript and its digest into one tar.gz archive.
+func Bundle(transcript []byte, digest string, modTime time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, f := range []struct {
+		name string
+		data []byte
+	}{
+		{TranscriptFile, transcript},
+		{DigestFile, []byte(digest)},
+	} {
+		hdr := &tar.Header{Name: f.name, Mode: 0o600, Size: int64(len(f.data)), ModTime: modTime}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write(f.data); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Open reads the transcript and digest back out of an archive.
+func Open(data []byte) (transcript []byte, digest string, err error) {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("archive: %w", err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	found := false
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("archive: %w", err)
+		}
+		body, err := io.ReadAll(io.LimitReader(tr, maxEntrySize))
+		if err != nil {
+			return nil, "", fmt.Errorf("archive: %w", err)
+		}
+		switch hdr.Name {
+		case TranscriptFile:
+			transcript, found = body, true
+		case DigestFile:
+			digest = string(body)
+		}
+	}
+	if !found {
+		return nil, "", fmt.Errorf("archive: no %s", TranscriptFile)
+	}
+	return transcript, digest, nil
+}
+
+// Dir stores archives as files in a directory, created on first use.
+type Dir struct {
+	Path string
+}
+
+func (d Dir) Name() string { return d.Path }
+
+func (d Dir) Put(ctx context.Context, name string, data []byte) error {
+	path, err := d.path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	// Write under a temporary name so a crash never leaves half an archive
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (d Dir) Get(ctx context.Context, name string) ([]byte, error) {
+	path, err := d.path(name)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
+
+// path resolves name inside the directory, refusing names that leave it.
+func (d Dir) path(name string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(name))
+	if clean == "." || filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("archive: invalid name %q", name)
+	}
+	return filepath.Join(d.Path, clean), nil
+}
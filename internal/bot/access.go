@@ -0,0 +1,198 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+)
+
+// accessPromptInterval is how often an unknown user is offered the
+// "Request access" button.
+const accessPromptInterval = time.Hour
+
+// isOwner reports whether a user is listed in ALLOWED_USERS. Only owners
+// decide access requests; granted users cannot admit others.
+func (b *Bot) isOwner(userID int64) bool {
+	return b.config.IsAllowedUser(userID)
+}
+
+// offerAccessRequest replies to an unauthorized user's message with a
+// "Request access" button, at most once per accessPromptInterval. Messages in
+// groups that are not allowed are still ignored.
+func (b *Bot) offerAccessRequest(msg *tgbotapi.Message) {
+	if msg.From == nil || (msg.Chat.ID < 0 && !b.config.IsAllowedGroup(msg.Chat.ID)) {
+		return
+	}
+
+	b.mu.Lock()
+	if b.accessPrompts == nil {
+		b.accessPrompts = make(map[int64]time.Time)
+	}
+	if last, ok := b.accessPrompts[msg.From.ID]; ok && time.Since(last) < accessPromptInterval {
+		b.mu.Unlock()
+		return
+	}
+	b.accessPrompts[msg.From.ID] = time.Now()
+	b.mu.Unlock()
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Request access", "access_req"),
+	))
+	if _, err := b.sendMessageWithKeyboard(msg.Chat.ID, getThreadID(msg), "You are not authorized to use this bot.", keyboard); err != nil {
+		log.Printf("Error offering access request: %v", err)
+	}
+}
+
+// processAccessRequest forwards an unauthorized user's request to the admin
+// chat with Approve and Deny buttons.
+func (b *Bot) processAccessRequest(cq *tgbotapi.CallbackQuery) {
+	if cq.Message == nil {
+		return
+	}
+	b.answerCallback(cq.ID, "")
+	chatID := cq.Message.Chat.ID
+	threadID := getThreadIDFromCallback(cq)
+	userID := cq.From.ID
+
+	b.mu.Lock()
+	if b.accessRequests == nil {
+		b.accessRequests = make(map[int64]string)
+	}
+	_, pending := b.accessRequests[userID]
+	if !pending {
+		b.accessRequests[userID] = senderName(cq.From)
+	}
+	b.mu.Unlock()
+	if pending {
+		b.editMessageText(chatID, cq.Message.MessageID, "Access already requested. You'll be notified once it is decided.")
+		return
+	}
+
+	data := fmt.Sprintf("%d:%d:%d", userID, chatID, threadID)
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Approve", "access_ok:"+data),
+		tgbotapi.NewInlineKeyboardButtonData("Deny", "access_no:"+data),
+	))
+	if _, err := b.sendMessageWithKeyboard(b.config.AdminChatID, 0, formatAccessRequest(cq.From, cq.Message.Chat), keyboard); err != nil {
+		log.Printf("Error sending access request to admin chat: %v", err)
+		b.mu.Lock()
+		delete(b.accessRequests, userID)
+		b.mu.Unlock()
+		b.editMessageText(chatID, cq.Message.MessageID, "Could not reach the bot owner. Try again later.")
+		return
+	}
+	b.editMessageText(chatID, cq.Message.MessageID, "Access requested. You'll be notified once it is decided.")
+}
+
+// processAccessCallback applies an owner's Approve or Deny decision.
+func (b *Bot) processAccessCallback(cq *tgbotapi.CallbackQuery) {
+	if cq.Message == nil {
+		return
+	}
+	action, rest, _ := strings.Cut(cq.Data, ":")
+	parts := strings.Split(rest, ":")
+	if len(parts) != 3 {
+		return
+	}
+	userID, err1 := strconv.ParseInt(parts[0], 10, 64)
+	chatID, err2 := strconv.ParseInt(parts[1], 10, 64)
+	threadID, err3 := strconv.Atoi(parts[2])
+	if err1 != nil || err2 != nil || err3 != nil {
+		return
+	}
+	if !b.isOwner(cq.From.ID) {
+		return
+	}
+
+	b.mu.Lock()
+	name, ok := b.accessRequests[userID]
+	delete(b.accessRequests, userID)
+	b.mu.Unlock()
+	if !ok {
+		name = parts[0]
+	}
+
+	switch action {
+	case "access_ok":
+		b.state.GrantAccess(parts[0], state.AccessGrant{Name: name, GrantedBy: senderName(cq.From), GrantedAt: time.Now()})
+		b.saveState()
+		b.editMessageText(cq.Message.Chat.ID, cq.Message.MessageID, fmt.Sprintf("✅ Access granted to %s (%d) by %s.", name, userID, senderName(cq.From)))
+		b.reply(chatID, threadID, "Access granted. Send a message to get started.")
+	case "access_no":
+		b.editMessageText(cq.Message.Chat.ID, cq.Message.MessageID, fmt.Sprintf("❌ Access denied to %s (%d) by %s.", name, userID, senderName(cq.From)))
+		b.reply(chatID, threadID, "Your access request was denied.")
+	}
+}
+
+// handleAccessCommand lists granted users or revokes one. Owners only.
+func (b *Bot) handleAccessCommand(msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+	threadID := getThreadID(msg)
+	if !b.isOwner(msg.From.ID) {
+		b.reply(chatID, threadID, "Only the bot owner can manage access.")
+		return
+	}
+
+	args := strings.Fields(msg.CommandArguments())
+	switch {
+	case len(args) == 0:
+		b.reply(chatID, threadID, formatAccessGrants(b.state.AccessGrants()))
+	case len(args) == 2 && args[0] == "revoke":
+		if !b.state.RevokeAccess(args[1]) {
+			b.reply(chatID, threadID, "No access grant for user "+args[1])
+			return
+		}
+		b.saveState()
+		b.reply(chatID, threadID, "Revoked access for user "+args[1])
+	default:
+		b.reply(chatID, threadID, "Usage: /access | /access revoke <user_id>")
+	}
+}
+
+// formatAccessRequest describes a requesting user for the admin chat.
+func formatAccessRequest(u *tgbotapi.User, chat *tgbotapi.Chat) string {
+	name := strings.TrimSpace(u.FirstName + " " + u.LastName)
+	if u.UserName != "" {
+		name += " @" + u.UserName
+	}
+	where := "a private chat"
+	if chat != nil && chat.ID < 0 {
+		where = "group " + strconv.FormatInt(chat.ID, 10)
+		if chat.Title != "" {
+			where = chat.Title
+		}
+	}
+	return fmt.Sprintf("🔑 Access request from %s (ID %d) in %s.", strings.TrimSpace(name), u.ID, where)
+}
+
+// formatAccessGrants renders the allowlist overlay, oldest grant first.
+func formatAccessGrants(grants map[string]state.AccessGrant) string {
+	if len(grants) == 0 {
+		return "No users were granted access. Users in ALLOWED_USERS always have access."
+	}
+	ids := make([]string, 0, len(grants))
+	for id := range grants {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return grants[ids[i]].GrantedAt.Before(grants[ids[j]].GrantedAt)
+	})
+
+	lines := []string{fmt.Sprintf("Granted access (%d):", len(grants))}
+	for _, id := range ids {
+		g := grants[id]
+		line := fmt.Sprintf("%s %s", id, g.Name)
+		if g.GrantedBy != "" {
+			line += fmt.Sprintf(" (by %s, %s)", g.GrantedBy, g.GrantedAt.Format("2006-01-02"))
+		}
+		lines = append(lines, line)
+	}
+	lines = append(lines, "", "Revoke with /access revoke <user_id>")
+	return strings.Join(lines, "\n")
+}
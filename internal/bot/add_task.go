@@ -47,7 +47,6 @@ func (b *Bot) handleAddCommand(msg *tgbotapi.Message) {
 func (b *Bot) executeAddWithTitle(msg *tgbotapi.Message, title string) {
 	chatID := msg.Chat.ID
 	threadID := getThreadID(msg)
-	userID := msg.From.ID
 
 	title = strings.TrimSpace(title)
 	if title == "" {
@@ -75,14 +74,14 @@ func (b *Bot) executeAddWithTitle(msg *tgbotapi.Message, title string) {
 
 	// Store wizard state
 	b.mu.Lock()
-	b.addTaskStates[userID] = &addTaskState{
+	b.addTaskStates.set(messageWizardKey(msg), &addTaskState{
 		Title:     title,
 		Project:   project,
 		Step:      addStepPriority,
 		MessageID: sent.MessageID,
 		ChatID:    chatID,
 		ThreadID:  threadID,
-	}
+	})
 	b.mu.Unlock()
 }
 
@@ -134,15 +133,15 @@ func buildConfirmationKeyboard(taskID string) tgbotapi.InlineKeyboardMarkup {
 // processAddTaskCallback routes task_* callbacks.
 func (b *Bot) processAddTaskCallback(cq *tgbotapi.CallbackQuery) {
 	data := cq.Data
-	userID := cq.From.ID
+	k := callbackWizardKey(cq)
 
 	switch {
 	case strings.HasPrefix(data, "task_pri:"):
-		b.handleTaskPriority(cq, userID, data)
+		b.handleTaskPriority(cq, k, data)
 	case data == "task_skip":
-		b.handleTaskSkipBody(cq, userID)
+		b.handleTaskSkipBody(cq, k)
 	case data == "task_cancel":
-		b.handleTaskCancel(cq, userID)
+		b.handleTaskCancel(cq, k)
 	case strings.HasPrefix(data, "task_pick:"):
 		b.handleTaskPick(cq, data)
 	default:
@@ -151,9 +150,9 @@ func (b *Bot) processAddTaskCallback(cq *tgbotapi.CallbackQuery) {
 }
 
 // handleTaskPriority handles priority selection.
-func (b *Bot) handleTaskPriority(cq *tgbotapi.CallbackQuery, userID int64, data string) {
+func (b *Bot) handleTaskPriority(cq *tgbotapi.CallbackQuery, k wizardKey, data string) {
 	b.mu.Lock()
-	ats, ok := b.addTaskStates[userID]
+	ats, ok := b.addTaskStates.get(k)
 	if !ok {
 		b.mu.Unlock()
 		return
@@ -176,23 +175,23 @@ func (b *Bot) handleTaskPriority(cq *tgbotapi.CallbackQuery, userID int64, data
 }
 
 // handleTaskSkipBody skips the body and creates the task immediately.
-func (b *Bot) handleTaskSkipBody(cq *tgbotapi.CallbackQuery, userID int64) {
-	b.mu.RLock()
-	ats, ok := b.addTaskStates[userID]
-	b.mu.RUnlock()
+func (b *Bot) handleTaskSkipBody(cq *tgbotapi.CallbackQuery, k wizardKey) {
+	b.mu.Lock()
+	ats, ok := b.addTaskStates.get(k)
+	b.mu.Unlock()
 	if !ok {
 		return
 	}
 
-	b.createTask(ats, userID, "")
+	b.createTask(ats, k, "")
 }
 
 // handleTaskCancel cancels the wizard.
-func (b *Bot) handleTaskCancel(cq *tgbotapi.CallbackQuery, userID int64) {
+func (b *Bot) handleTaskCancel(cq *tgbotapi.CallbackQuery, k wizardKey) {
 	b.mu.Lock()
-	ats, ok := b.addTaskStates[userID]
+	ats, ok := b.addTaskStates.get(k)
 	if ok {
-		delete(b.addTaskStates, userID)
+		b.addTaskStates.remove(k)
 	}
 	b.mu.Unlock()
 
@@ -240,11 +239,11 @@ func (b *Bot) handleAddTaskReply(msg *tgbotapi.Message) bool {
 		return false
 	}
 
-	userID := msg.From.ID
+	k := messageWizardKey(msg)
 
-	b.mu.RLock()
-	ats, ok := b.addTaskStates[userID]
-	b.mu.RUnlock()
+	b.mu.Lock()
+	ats, ok := b.addTaskStates.get(k)
+	b.mu.Unlock()
 	if !ok {
 		return false
 	}
@@ -262,15 +261,15 @@ func (b *Bot) handleAddTaskReply(msg *tgbotapi.Message) bool {
 		return false
 	}
 
-	b.createTask(ats, userID, body)
+	b.createTask(ats, k, body)
 	return true
 }
 
 // createTask calls the bridge to create the task and shows the confirmation message.
-func (b *Bot) createTask(ats *addTaskState, userID int64, body string) {
+func (b *Bot) createTask(ats *addTaskState, k wizardKey, body string) {
 	// Clean up wizard state
 	b.mu.Lock()
-	delete(b.addTaskStates, userID)
+	b.addTaskStates.remove(k)
 	b.mu.Unlock()
 
 	result, err := b.minuanoBridge.Add(ats.Title, ats.Project, body, ats.Priority)
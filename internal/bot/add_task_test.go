@@ -133,15 +133,6 @@ func TestHandleAddTaskReply_NoState(t *testing.T) {
 func TestHandleAddTaskReply_WrongMessage(t *testing.T) {
 	b := newTestBot(t)
 
-	// Set up wizard state for user 100
-	b.addTaskStates[100] = &addTaskState{
-		Title:     "Test task",
-		Step:      addStepBody,
-		MessageID: 5,
-		ChatID:    -1001,
-		ThreadID:  7,
-	}
-
 	msg := &tgbotapi.Message{
 		MessageID: 10,
 		Text:      "Some body text",
@@ -152,6 +143,15 @@ func TestHandleAddTaskReply_WrongMessage(t *testing.T) {
 		},
 	}
 
+	// Set up wizard state for user 100
+	b.addTaskStates.set(messageWizardKey(msg), &addTaskState{
+		Title:     "Test task",
+		Step:      addStepBody,
+		MessageID: 5,
+		ChatID:    -1001,
+		ThreadID:  7,
+	})
+
 	if b.handleAddTaskReply(msg) {
 		t.Error("should return false when reply is to wrong message")
 	}
@@ -160,15 +160,6 @@ func TestHandleAddTaskReply_WrongMessage(t *testing.T) {
 func TestHandleAddTaskReply_WrongStep(t *testing.T) {
 	b := newTestBot(t)
 
-	// Set up wizard state at priority step (not body step)
-	b.addTaskStates[100] = &addTaskState{
-		Title:     "Test task",
-		Step:      addStepPriority,
-		MessageID: 5,
-		ChatID:    -1001,
-		ThreadID:  7,
-	}
-
 	msg := &tgbotapi.Message{
 		MessageID: 10,
 		Text:      "Some body text",
@@ -179,6 +170,15 @@ func TestHandleAddTaskReply_WrongStep(t *testing.T) {
 		},
 	}
 
+	// Set up wizard state at priority step (not body step)
+	b.addTaskStates.set(messageWizardKey(msg), &addTaskState{
+		Title:     "Test task",
+		Step:      addStepPriority,
+		MessageID: 5,
+		ChatID:    -1001,
+		ThreadID:  7,
+	})
+
 	if b.handleAddTaskReply(msg) {
 		t.Error("should return false when on wrong step")
 	}
@@ -0,0 +1,106 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/otaviocarvalho/tramuntana/internal/config"
+	"github.com/otaviocarvalho/tramuntana/internal/notify"
+)
+
+// newAlerter builds the alert sinks configured outside Telegram; nil if
+// there are none.
+func newAlerter(cfg *config.Config) *notify.Alerter {
+	var sinks []notify.Sink
+	if cfg.AlertNtfyURL != "" {
+		sinks = append(sinks, notify.Ntfy{URL: cfg.AlertNtfyURL, Token: cfg.AlertNtfyToken})
+	}
+	if cfg.AlertPushoverToken != "" {
+		sinks = append(sinks, notify.Pushover{Token: cfg.AlertPushoverToken, User: cfg.AlertPushoverUser})
+	}
+	if cfg.AlertSMTPAddr != "" {
+		sinks = append(sinks, notify.SMTP{
+			Addr:     cfg.AlertSMTPAddr,
+			Username: cfg.AlertSMTPUser,
+			Password: cfg.AlertSMTPPassword,
+			From:     cfg.AlertSMTPFrom,
+			To:       cfg.AlertSMTPTo,
+		})
+	}
+	return notify.New(notify.DefaultCooldown, sinks...)
+}
+
+// alertTitle prefixes an alert's title with the host, since one alert
+// channel may serve several bridges.
+func alertTitle(title string) string {
+	if host, err := os.Hostname(); err == nil {
+		return fmt.Sprintf("tramuntana@%s: %s", host, title)
+	}
+	return "tramuntana: " + title
+}
+
+// alertFloodBan alerts when Telegram rate-limits a chat for at least
+// TRAMUNTANA_ALERT_FLOOD_MINUTES, during which nothing reaches it.
+func (b *Bot) alertFloodBan(chatID int64, wait time.Duration) {
+	if b.config.AlertFloodBan <= 0 || wait < b.config.AlertFloodBan {
+		return
+	}
+	b.alerts.Alert(fmt.Sprintf("flood:%d", chatID), alertTitle("Telegram flood ban"),
+		fmt.Sprintf("Telegram rate-limited chat %d for %v. Messages to it are held until %s.",
+			chatID, wait.Round(time.Second), time.Now().Add(wait).Format("15:04")))
+}
+
+// alertStateSave alerts when state.json can't be written, so bindings and
+// settings changed since would be lost on restart.
+func (b *Bot) alertStateSave(err error) {
+	title := "State not saved"
+	if errors.Is(err, syscall.ENOSPC) {
+		title = "Disk full: state not saved"
+	}
+	b.alerts.Alert("state_save", alertTitle(title),
+		fmt.Sprintf("Writing state.json in %s failed: %v. Changes since the last save are lost if the bot restarts.", b.config.TramuntanaDir, err))
+}
+
+// restartNoticeInterval rate-limits the admin chat notices for a subsystem
+// the watchdog keeps restarting.
+const restartNoticeInterval = 10 * time.Minute
+
+// ReportRestart tells the admin chat and the alert sinks that the watchdog
+// restarted a subsystem (the session monitor or status poller) after it
+// panicked or stalled.
+func (b *Bot) ReportRestart(subsystem, reason string) {
+	if len(reason) > 300 {
+		reason = reason[:300] + "…"
+	}
+	text := fmt.Sprintf("The watchdog restarted the %s: %s.", subsystem, reason)
+	b.alerts.Alert("watchdog:"+subsystem, alertTitle(subsystem+" restarted"), text)
+
+	b.mu.Lock()
+	if b.restartNotices == nil {
+		b.restartNotices = make(map[string]time.Time)
+	}
+	if last, ok := b.restartNotices[subsystem]; ok && time.Since(last) < restartNoticeInterval {
+		b.mu.Unlock()
+		return
+	}
+	b.restartNotices[subsystem] = time.Now()
+	b.mu.Unlock()
+	b.reply(b.config.AdminChatID, 0, "⚠️ "+text+" It continues from where it stopped.")
+}
+
+// alertRecoveryFailed alerts when a dead session couldn't be restarted.
+func (b *Bot) alertRecoveryFailed(cwd string, err error) {
+	b.alerts.Alert("recovery:"+cwd, alertTitle("Session died and restart failed"),
+		fmt.Sprintf("The Claude session in %s died and could not be restarted: %v", cwd, err))
+}
+
+// alertRestartFailed alerts when the watchdog couldn't relaunch Claude in a
+// window whose Claude process exited.
+func (b *Bot) alertRestartFailed(windowID string, err error) {
+	ws, _ := b.state.GetWindowState(windowID)
+	b.alerts.Alert("restart:"+windowID, alertTitle("Claude exited and restart failed"),
+		fmt.Sprintf("Claude exited in window %s (%s) and could not be restarted: %v", windowID, ws.CWD, err))
+}
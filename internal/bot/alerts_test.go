@@ -0,0 +1,83 @@
+package bot
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/otaviocarvalho/tramuntana/internal/config"
+	"github.com/otaviocarvalho/tramuntana/internal/notify"
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+	"github.com/otaviocarvalho/tramuntana/internal/testharness"
+)
+
+type alertSink chan string
+
+func (s alertSink) Name() string { return "test" }
+
+func (s alertSink) Send(ctx context.Context, title, message string) error {
+	s <- title + ": " + message
+	return nil
+}
+
+func TestAlertFloodBan(t *testing.T) {
+	sink := make(alertSink, 4)
+	b := &Bot{config: &config.Config{AlertFloodBan: 10 * time.Minute}, alerts: notify.New(time.Hour, sink)}
+
+	b.alertFloodBan(-1001, 30*time.Second)
+	b.alertFloodBan(-1001, 15*time.Minute)
+	select {
+	case got := <-sink:
+		if !strings.Contains(got, "Telegram flood ban: Telegram rate-limited chat -1001 for 15m0s") {
+			t.Errorf("alert = %q", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no alert for a 15-minute ban")
+	}
+	select {
+	case got := <-sink:
+		t.Errorf("unexpected second alert %q", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestE2E_ReportRestartNotifiesAdminChat(t *testing.T) {
+	h := startE2E(t, "fresh")
+	h.cfg.AdminChatID = e2eUser
+
+	h.bot.ReportRestart("session monitor", "panic: boom")
+	h.bot.ReportRestart("session monitor", "panic: boom")
+	got := h.tg.WaitForText("sendMessage", "The watchdog restarted the session monitor: panic: boom.")
+	if got.Params["chat_id"] != strconv.FormatInt(e2eUser, 10) {
+		t.Errorf("restart notice sent to chat %s, want the admin chat", got.Params["chat_id"])
+	}
+	h.bot.ReportRestart("status poller", "no progress for 2m0s")
+	h.tg.WaitForText("sendMessage", "restarted the status poller")
+	if n := len(h.tg.Calls("sendMessage")); n != 2 {
+		t.Errorf("sent %d notices, want one per subsystem", n)
+	}
+}
+
+func TestE2E_FailedWatchdogRestartAlerts(t *testing.T) {
+	h := startE2E(t, "fresh")
+	sink := make(alertSink, 4)
+	h.bot.alerts = notify.New(time.Hour, sink)
+	windowID := h.tmux.AddWindow("api", "/work/api")
+	h.bot.state.SetWindowState(windowID, state.WindowState{SessionID: "sess-1", CWD: "/work/api"})
+	h.tmux.Kill(windowID)
+
+	h.tg.PushCallback(e2eChat, e2eThread, e2eUser, 7, "wd_restart:"+windowID)
+	h.tg.WaitFor("answerCallbackQuery", func(c testharness.Call) bool {
+		return c.Params["text"] == "Failed to restart"
+	})
+	select {
+	case got := <-sink:
+		if !strings.Contains(got, "Claude exited and restart failed: Claude exited in window "+windowID+" (/work/api) and could not be restarted") {
+			t.Errorf("alert = %q", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no alert for a failed restart")
+	}
+}
@@ -0,0 +1,178 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// allowRulePattern matches Claude permission rules: a tool name with an
+// optional specifier, e.g. "Read", "Bash(git *)" or "mcp__github__get_issue".
+var allowRulePattern = regexp.MustCompile(`^[A-Za-z][\w-]*(\(.+\))?$`)
+
+const allowUsage = "Usage: /allow <rule> | /allow list | /allow remove <rule>\nExample: /allow Bash(git *)"
+
+// handleAllowCommand manages the allow rules in the bound project's
+// .claude/settings.json, so Claude stops prompting for those permissions.
+func (b *Bot) handleAllowCommand(msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+	threadID := getThreadID(msg)
+
+	windowID, bound := b.resolveWindow(msg)
+	if !bound {
+		b.reply(chatID, threadID, "Topic not bound to a session. Send a message to bind.")
+		return
+	}
+	ws, ok := b.state.GetWindowState(windowID)
+	if !ok || ws.CWD == "" {
+		b.reply(chatID, threadID, "Project directory unknown for this session.")
+		return
+	}
+	path := filepath.Join(ws.CWD, ".claude", "settings.json")
+
+	args := strings.TrimSpace(msg.CommandArguments())
+	verb, rest, _ := strings.Cut(args, " ")
+	rest = strings.TrimSpace(rest)
+
+	switch {
+	case args == "" || args == "list":
+		settings, err := readClaudeSettings(path)
+		if err != nil {
+			b.reply(chatID, threadID, fmt.Sprintf("Error: %v", err))
+			return
+		}
+		rules := allowRules(settings)
+		if len(rules) == 0 {
+			b.reply(chatID, threadID, "No allow rules in "+path+"\n\n"+allowUsage)
+			return
+		}
+		b.reply(chatID, threadID, fmt.Sprintf("Allow rules in %s:\n\n%s", path, strings.Join(rules, "\n")))
+
+	case verb == "remove":
+		if rest == "" {
+			b.reply(chatID, threadID, allowUsage)
+			return
+		}
+		removed, err := updateClaudeSettings(path, func(settings map[string]any) bool {
+			return removeAllowRule(settings, rest)
+		})
+		if err != nil {
+			b.reply(chatID, threadID, fmt.Sprintf("Error: %v", err))
+			return
+		}
+		if !removed {
+			b.reply(chatID, threadID, "No such allow rule: "+rest)
+			return
+		}
+		b.reply(chatID, threadID, "Removed allow rule: "+rest)
+
+	default:
+		if !allowRulePattern.MatchString(args) {
+			b.reply(chatID, threadID, "Invalid rule: "+args+"\n\n"+allowUsage)
+			return
+		}
+		added, err := updateClaudeSettings(path, func(settings map[string]any) bool {
+			return addAllowRule(settings, args)
+		})
+		if err != nil {
+			b.reply(chatID, threadID, fmt.Sprintf("Error: %v", err))
+			return
+		}
+		if !added {
+			b.reply(chatID, threadID, "Already allowed: "+args)
+			return
+		}
+		b.reply(chatID, threadID, fmt.Sprintf("Allowed %s in %s", args, path))
+	}
+}
+
+// readClaudeSettings reads a Claude settings file. A missing file is empty settings.
+func readClaudeSettings(path string) (map[string]any, error) {
+	settings := make(map[string]any)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return settings, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading settings: %w", err)
+	}
+	if len(strings.TrimSpace(string(data))) == 0 {
+		return settings, nil
+	}
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return settings, nil
+}
+
+// updateClaudeSettings applies fn to a Claude settings file and writes it back
+// if fn reports a change. Other settings are preserved.
+func updateClaudeSettings(path string, fn func(settings map[string]any) bool) (bool, error) {
+	settings, err := readClaudeSettings(path)
+	if err != nil {
+		return false, err
+	}
+	if !fn(settings) {
+		return false, nil
+	}
+
+	out, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return false, fmt.Errorf("marshaling settings: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return false, fmt.Errorf("creating .claude dir: %w", err)
+	}
+	if err := os.WriteFile(path, append(out, '\n'), 0644); err != nil {
+		return false, fmt.Errorf("writing settings: %w", err)
+	}
+	return true, nil
+}
+
+// allowRules returns the permissions.allow rules in settings.
+func allowRules(settings map[string]any) []string {
+	perms, _ := settings["permissions"].(map[string]any)
+	list, _ := perms["allow"].([]any)
+	var rules []string
+	for _, r := range list {
+		if s, ok := r.(string); ok {
+			rules = append(rules, s)
+		}
+	}
+	return rules
+}
+
+// addAllowRule appends rule to permissions.allow. Returns false if already present.
+func addAllowRule(settings map[string]any, rule string) bool {
+	for _, r := range allowRules(settings) {
+		if r == rule {
+			return false
+		}
+	}
+	perms, _ := settings["permissions"].(map[string]any)
+	if perms == nil {
+		perms = make(map[string]any)
+		settings["permissions"] = perms
+	}
+	list, _ := perms["allow"].([]any)
+	perms["allow"] = append(list, rule)
+	return true
+}
+
+// removeAllowRule deletes rule from permissions.allow. Returns false if absent.
+func removeAllowRule(settings map[string]any, rule string) bool {
+	perms, _ := settings["permissions"].(map[string]any)
+	list, _ := perms["allow"].([]any)
+	for i, r := range list {
+		if r == rule {
+			perms["allow"] = append(list[:i:i], list[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package bot
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAllowRulePattern(t *testing.T) {
+	for _, rule := range []string{"Read", "Bash(git *)", "WebFetch(domain:example.com)", "mcp__github__get_issue"} {
+		if !allowRulePattern.MatchString(rule) {
+			t.Errorf("%q should be a valid rule", rule)
+		}
+	}
+	for _, rule := range []string{"", "list all", "Bash(", "(git *)", "Bash()"} {
+		if allowRulePattern.MatchString(rule) {
+			t.Errorf("%q should be rejected", rule)
+		}
+	}
+}
+
+func TestAllowRules_AddRemovePreservesSettings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".claude", "settings.json")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	existing := `{"model": "opus", "permissions": {"allow": ["Read"], "deny": ["Bash(rm *)"]}}`
+	if err := os.WriteFile(path, []byte(existing), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	add := func(rule string) bool {
+		changed, err := updateClaudeSettings(path, func(s map[string]any) bool { return addAllowRule(s, rule) })
+		if err != nil {
+			t.Fatal(err)
+		}
+		return changed
+	}
+	if !add("Bash(git *)") {
+		t.Error("new rule should be added")
+	}
+	if add("Read") {
+		t.Error("existing rule should not be added twice")
+	}
+
+	settings, err := readClaudeSettings(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := allowRules(settings); !reflect.DeepEqual(got, []string{"Read", "Bash(git *)"}) {
+		t.Errorf("allow rules = %q", got)
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), `"model": "opus"`) || !strings.Contains(string(data), `"Bash(rm *)"`) {
+		t.Errorf("other settings lost:\n%s", data)
+	}
+
+	if !removeAllowRule(settings, "Read") || removeAllowRule(settings, "Read") {
+		t.Error("remove should succeed once")
+	}
+	if got := allowRules(settings); !reflect.DeepEqual(got, []string{"Bash(git *)"}) {
+		t.Errorf("allow rules after remove = %q", got)
+	}
+}
+
+func TestReadClaudeSettings_Missing(t *testing.T) {
+	settings, err := readClaudeSettings(filepath.Join(t.TempDir(), "settings.json"))
+	if err != nil || len(settings) != 0 {
+		t.Errorf("missing file: %v, %v", settings, err)
+	}
+}
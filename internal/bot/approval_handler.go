@@ -184,8 +184,5 @@ func (b *Bot) findChatIDForTopic(topicID int) int64 {
 		}
 	}
 	// Fallback: use first allowed group
-	if len(b.config.AllowedGroups) > 0 {
-		return b.config.AllowedGroups[0]
-	}
-	return 0
+	return b.config.FirstAllowedGroup()
 }
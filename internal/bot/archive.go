@@ -0,0 +1,259 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/archive"
+	"github.com/otaviocarvalho/tramuntana/internal/config"
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+)
+
+// archiveTimeout bounds archiving one session or reading one back, upload
+// and download included.
+const archiveTimeout = 5 * time.Minute
+
+// archiveListMax caps the sessions /p_history archived lists.
+const archiveListMax = 20
+
+// archiveCacheMax caps the archived sessions kept parsed in memory, so paging
+// through one doesn't download and parse it again on every click.
+const archiveCacheMax = 4
+
+// cachedArchive is an archived session read back for the history browser.
+type cachedArchive struct {
+	id      string
+	entries []historyEntry
+	label   string
+}
+
+// newArchiveStore builds the configured transcript archive; nil if none.
+func newArchiveStore(cfg *config.Config) archive.Store {
+	switch {
+	case cfg.ArchiveDir != "":
+		return archive.Dir{Path: cfg.ArchiveDir}
+	case cfg.ArchiveS3Bucket != "":
+		return archive.S3{
+			Bucket:       cfg.ArchiveS3Bucket,
+			Region:       cfg.ArchiveS3Region,
+			Endpoint:     cfg.ArchiveS3Endpoint,
+			Prefix:       cfg.ArchiveS3Prefix,
+			AccessKey:    cfg.AWSAccessKeyID,
+			SecretKey:    cfg.AWSSecretAccessKey,
+			SessionToken: cfg.AWSSessionToken,
+		}
+	}
+	return nil
+}
+
+// pendingArchive is a session captured for archiving before its bindings
+// and session map entries are cleaned up.
+type pendingArchive struct {
+	path    string // transcript JSONL
+	prune   bool   // delete the transcript once archived
+	session state.ArchivedSession
+}
+
+// captureArchive snapshots what archiving a window's session needs. Call it
+// before the window is cleaned up; nil when archiving is off or there is no
+// transcript. reason is "died" or "closed".
+func (b *Bot) captureArchive(windowID, reason string, threadIDs []string) *pendingArchive {
+	if b.archives == nil {
+		return nil
+	}
+	path := b.findJSONLForWindow(windowID)
+	if path == "" {
+		return nil
+	}
+	ws, _ := b.state.GetWindowState(windowID)
+	name, _ := b.state.GetWindowDisplayName(windowID)
+	if name == "" {
+		name = ws.WindowName
+	}
+	return &pendingArchive{
+		path: path,
+		// A dead session may be resumed from its transcript; only a closed
+		// topic's is safe to delete
+		prune: b.config.ArchivePrune && reason == "closed",
+		session: state.ArchivedSession{
+			SessionID:  strings.TrimSuffix(filepath.Base(path), ".jsonl"),
+			WindowName: name,
+			CWD:        ws.CWD,
+			ThreadIDs:  threadIDs,
+			Reason:     reason,
+		},
+	}
+}
+
+// archiveSession compresses a captured session's transcript, with a digest
+// rendered like /p_history, into the archive store and records it. Failures
+// are logged and alerted; the transcript is kept.
+func (b *Bot) archiveSession(p *pendingArchive) {
+	if p == nil {
+		return
+	}
+	fail := func(err error) {
+		log.Printf("Error archiving session %s: %v", p.session.SessionID, err)
+		b.alerts.Alert("archive", alertTitle("Transcript not archived"),
+			fmt.Sprintf("Archiving session %s (%s) to %s failed: %v. Its transcript stays at %s.",
+				p.session.SessionID, p.session.WindowName, b.archives.Name(), err, p.path))
+	}
+
+	transcript, err := os.ReadFile(p.path)
+	if err != nil {
+		fail(err)
+		return
+	}
+	entries := readEntries(bytes.NewReader(transcript))
+	a := p.session
+	a.Entries = len(entries)
+	a.Bytes = int64(len(transcript))
+	a.ArchivedAt = time.Now()
+	if len(entries) > 0 {
+		a.StartedAt = entries[0].Timestamp
+	}
+	a.Name = fmt.Sprintf("%s-%s.tar.gz", a.ArchivedAt.UTC().Format("20060102-150405"), a.SessionID)
+
+	data, err := archive.Bundle(transcript, formatArchiveDigest(a, entries), a.ArchivedAt)
+	if err != nil {
+		fail(err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), archiveTimeout)
+	defer cancel()
+	if err := b.archives.Put(ctx, a.Name, data); err != nil {
+		fail(err)
+		return
+	}
+	b.state.AddArchive(a)
+	b.saveState()
+	log.Printf("Archived session %s (%d entries, %d bytes) to %s as %s", a.SessionID, a.Entries, len(data), b.archives.Name(), a.Name)
+
+	if p.prune {
+		if err := os.Remove(p.path); err != nil {
+			log.Printf("Error removing archived transcript %s: %v", p.path, err)
+		}
+	}
+}
+
+// formatArchiveDigest renders an archived session as plain text: a header,
+// then every entry the way /p_history shows it.
+func formatArchiveDigest(a state.ArchivedSession, entries []historyEntry) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Session:  %s\n", a.SessionID)
+	fmt.Fprintf(&sb, "Window:   %s\n", a.WindowName)
+	fmt.Fprintf(&sb, "Dir:      %s\n", a.CWD)
+	fmt.Fprintf(&sb, "Topics:   %s\n", strings.Join(a.ThreadIDs, ", "))
+	if !a.StartedAt.IsZero() {
+		fmt.Fprintf(&sb, "Started:  %s\n", a.StartedAt.UTC().Format(time.RFC3339))
+	}
+	fmt.Fprintf(&sb, "Archived: %s (session %s)\n", a.ArchivedAt.UTC().Format(time.RFC3339), a.Reason)
+	fmt.Fprintf(&sb, "Entries:  %d\n\n", len(entries))
+	for _, e := range entries {
+		if !e.Timestamp.IsZero() {
+			sb.WriteString(e.Timestamp.UTC().Format("2006-01-02 15:04:05") + " ")
+		}
+		sb.WriteString(formatHistoryEntry(e) + "\n")
+	}
+	return sb.String()
+}
+
+// showArchivedSessions lists this topic's archived sessions, newest first,
+// with a button to browse each.
+func (b *Bot) showArchivedSessions(msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+	threadID := getThreadID(msg)
+	ids := b.state.ArchivesForThread(strconv.Itoa(threadID))
+	if len(ids) == 0 {
+		if b.archives == nil {
+			b.reply(chatID, threadID, "Archiving is off. Set TRAMUNTANA_ARCHIVE_DIR or TRAMUNTANA_ARCHIVE_S3_BUCKET to archive sessions when they end.")
+		} else {
+			b.reply(chatID, threadID, "No archived sessions in this topic yet. Sessions are archived when they die or the topic is closed.")
+		}
+		return
+	}
+
+	loc := b.state.GetUserSettings(strconv.FormatInt(msg.From.ID, 10)).Location()
+	if loc == nil {
+		loc = time.Local
+	}
+	var sb strings.Builder
+	sb.WriteString("🗄 Archived sessions in this topic, newest first (read-only):\n")
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for i, id := range ids {
+		if i == archiveListMax {
+			fmt.Fprintf(&sb, "\n… and %d older", len(ids)-archiveListMax)
+			break
+		}
+		a, _ := b.state.GetArchive(id)
+		when := a.ArchivedAt.In(loc).Format("Jan 2 15:04")
+		fmt.Fprintf(&sb, "\n%d. %s — %s, %s, %d entries", i+1, a.WindowName, when, a.Reason, a.Entries)
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%d. %s · %s", i+1, a.WindowName, when), formatHistCallback(-1, "a/"+id)),
+		))
+	}
+	b.sendMessageWithKeyboard(chatID, threadID, sb.String(), tgbotapi.NewInlineKeyboardMarkup(rows...))
+}
+
+// readArchivedEntries reads an archived session back from the store, or from
+// the cache of recently read ones. The label names it in the history
+// browser's title.
+func (b *Bot) readArchivedEntries(id string) (entries []historyEntry, label string, err error) {
+	b.mu.Lock()
+	for _, c := range b.archiveCache {
+		if c.id == id {
+			b.mu.Unlock()
+			return c.entries, c.label, nil
+		}
+	}
+	b.mu.Unlock()
+
+	a, ok := b.state.GetArchive(id)
+	if !ok {
+		return nil, "", fmt.Errorf("no such archive")
+	}
+	if b.archives == nil {
+		return nil, "", fmt.Errorf("archiving is no longer configured")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), archiveTimeout)
+	defer cancel()
+	data, err := b.archives.Get(ctx, a.Name)
+	if err != nil {
+		return nil, "", err
+	}
+	transcript, _, err := archive.Open(data)
+	if err != nil {
+		return nil, "", err
+	}
+	label = fmt.Sprintf("%s, archived %s", a.WindowName, a.ArchivedAt.Format("Jan 2"))
+	entries = readEntries(bytes.NewReader(transcript))
+
+	b.mu.Lock()
+	if len(b.archiveCache) == archiveCacheMax {
+		b.archiveCache = b.archiveCache[1:]
+	}
+	b.archiveCache = append(b.archiveCache, cachedArchive{id: id, entries: entries, label: label})
+	b.mu.Unlock()
+	return entries, label, nil
+}
+
+// uniqueThreadIDs returns the distinct threads of a window's bindings.
+func uniqueThreadIDs(users []state.UserThread) []string {
+	var ids []string
+	seen := make(map[string]bool)
+	for _, ut := range users {
+		if !seen[ut.ThreadID] {
+			seen[ut.ThreadID] = true
+			ids = append(ids, ut.ThreadID)
+		}
+	}
+	return ids
+}
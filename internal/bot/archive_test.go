@@ -0,0 +1,79 @@
+package bot
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/otaviocarvalho/tramuntana/internal/archive"
+	"github.com/otaviocarvalho/tramuntana/internal/events"
+	"github.com/otaviocarvalho/tramuntana/internal/testharness"
+)
+
+func TestE2E_DeadSessionArchivedAndBrowsable(t *testing.T) {
+	h := startE2E(t, "fresh")
+	store := archive.Dir{Path: filepath.Join(h.home, "archive")}
+	h.bot.archives = store
+	windowID := h.tmux.AddWindow("api", "/work/api")
+	userID, threadID := strconv.FormatInt(e2eUser, 10), strconv.Itoa(e2eThread)
+	h.bot.state.BindThread(userID, threadID, windowID)
+	h.bot.state.SetGroupChatID(userID, threadID, e2eChat)
+	h.writeSession(t, windowID, "sess-1", "/work/api")
+	h.appendTranscript(t, "sess-1",
+		`{"type":"user","message":{"content":"fix the flaky test"}}`,
+		`{"type":"assistant","message":{"content":[{"type":"text","text":"Fixed the race in the worker pool"}]}}`,
+	)
+
+	h.bus.WindowDead.Publish(events.WindowDead{WindowID: windowID})
+	h.tg.WaitForText("sendMessage", "Session died")
+	var ids []string
+	for deadline := time.Now().Add(5 * time.Second); len(ids) == 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("session was not archived")
+		}
+		time.Sleep(20 * time.Millisecond)
+		ids = h.bot.state.ArchivesForThread(threadID)
+	}
+	a, _ := h.bot.state.GetArchive(ids[0])
+	if a.SessionID != "sess-1" || a.Reason != "died" || a.Entries != 2 {
+		t.Errorf("archive = %+v", a)
+	}
+	data, err := store.Get(context.Background(), a.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, digest, err := archive.Open(data); err != nil || !strings.Contains(digest, "You: fix the flaky test") {
+		t.Errorf("digest = %q, %v", digest, err)
+	}
+	// A dead session may be resumed, so its transcript stays
+	if _, err := os.Stat(filepath.Join(h.home, ".claude", "projects", "-work-api", "sess-1.jsonl")); err != nil {
+		t.Errorf("transcript removed: %v", err)
+	}
+
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "/p_history archived")
+	list := h.tg.WaitForText("sendMessage", "Archived sessions in this topic")
+	if !strings.Contains(list.Params["text"], "died, 2 entries") || !strings.Contains(list.Params["reply_markup"], "hist_-1:a/"+ids[0]) {
+		t.Errorf("list = %q, markup %q", list.Params["text"], list.Params["reply_markup"])
+	}
+	h.tg.PushCallback(e2eChat, e2eThread, e2eUser, list.MessageID, "hist_-1:a/"+ids[0])
+	page := h.tg.WaitForText("editMessageText", "archived")
+	if !strings.Contains(page.Params["text"], "> Fixed the race in the worker pool") {
+		t.Errorf("page = %q", page.Params["text"])
+	}
+
+	// Paging again reads the session from the cache, not the store
+	if err := os.Remove(filepath.Join(store.Path, a.Name)); err != nil {
+		t.Fatal(err)
+	}
+	h.tg.PushCallback(e2eChat, e2eThread, e2eUser, list.MessageID, "hist_0:a/"+ids[0])
+	h.tg.WaitFor("editMessageText", func(c testharness.Call) bool {
+		return len(h.tg.Calls("editMessageText")) == 2
+	})
+	if last := h.tg.Calls("editMessageText")[1]; !strings.Contains(last.Params["text"], "> Fixed the race in the worker pool") {
+		t.Errorf("cached page = %q", last.Params["text"])
+	}
+}
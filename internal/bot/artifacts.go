@@ -0,0 +1,234 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/monitor"
+)
+
+const (
+	// maxArtifactOffers caps how many "Send file" buttons stay answerable.
+	maxArtifactOffers = 100
+	// artifactMaxAge is how much older than its tool result a Bash-mentioned
+	// file may be and still count as written by that command.
+	artifactMaxAge = 2 * time.Minute
+)
+
+var (
+	artifactOffers   = make(map[int]string) // offer ID → file path
+	artifactOffersMu sync.Mutex
+	nextArtifactID   = 1
+)
+
+// offerArtifact remembers a file for a "Send file" button and returns its offer ID.
+func offerArtifact(path string) int {
+	artifactOffersMu.Lock()
+	defer artifactOffersMu.Unlock()
+	id := nextArtifactID
+	nextArtifactID++
+	artifactOffers[id] = path
+	delete(artifactOffers, id-maxArtifactOffers)
+	return id
+}
+
+// detectArtifacts returns the files matching patterns that successful Write
+// and Bash tool calls in parsed produced. Relative paths resolve against cwd.
+func detectArtifacts(parsed []monitor.ParsedEntry, cwd string, patterns []string) []string {
+	var found []string
+	seen := make(map[string]bool)
+	add := func(path string, notBefore time.Time) {
+		if !filepath.IsAbs(path) {
+			if cwd == "" {
+				return
+			}
+			path = filepath.Join(cwd, path)
+		}
+		path = filepath.Clean(path)
+		if seen[path] || !matchesArtifact(path, cwd, patterns) {
+			return
+		}
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() || info.ModTime().Before(notBefore) {
+			return
+		}
+		seen[path] = true
+		found = append(found, path)
+	}
+
+	for _, pe := range parsed {
+		if pe.ContentType != "tool_result" || pe.IsError {
+			continue
+		}
+		switch pe.ToolName {
+		case "Write":
+			add(pe.ToolInput, time.Time{})
+		case "Bash":
+			// Only files the command just touched, not ones it merely read
+			ref := pe.Timestamp
+			if ref.IsZero() {
+				ref = time.Now()
+			}
+			for _, arg := range bashPathArgs(pe.ToolInput) {
+				add(arg, ref.Add(-artifactMaxAge))
+			}
+		}
+	}
+	return found
+}
+
+// bashPathArgs extracts the words of a shell command that could be file paths,
+// including redirect targets and --flag=value values.
+func bashPathArgs(cmd string) []string {
+	var args []string
+	for _, word := range strings.Fields(cmd) {
+		word = strings.TrimLeft(word, "0123456789&>")
+		if _, value, ok := strings.Cut(word, "="); ok {
+			word = value
+		}
+		word = strings.Trim(word, `"';`)
+		if word == "" || strings.HasPrefix(word, "-") {
+			continue
+		}
+		args = append(args, word)
+	}
+	return args
+}
+
+// matchesArtifact reports whether path matches one of the patterns. Patterns
+// with a slash match the path relative to cwd; others match the file name.
+func matchesArtifact(path, cwd string, patterns []string) bool {
+	base := filepath.Base(path)
+	rel := path
+	if cwd != "" {
+		if r, err := filepath.Rel(cwd, path); err == nil && !strings.HasPrefix(r, "..") {
+			rel = r
+		}
+	}
+	for _, p := range patterns {
+		name := base
+		if strings.Contains(p, "/") {
+			name = rel
+		}
+		if ok, _ := filepath.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// deliverArtifacts sends or offers newly written artifacts to the window's topics.
+func (b *Bot) deliverArtifacts(windowID string, paths []string) {
+	type target struct {
+		chatID   int64
+		threadID int
+	}
+	var targets []target
+	seen := make(map[target]bool)
+	lite := make(map[target]bool) // topics with a /lite user are only offered files
+	for _, ut := range b.state.FindUsersForWindow(windowID) {
+		chatID, ok := b.state.GetGroupChatID(ut.UserID, ut.ThreadID)
+		if !ok {
+			continue
+		}
+		threadID, _ := strconv.Atoi(ut.ThreadID)
+		t := target{chatID, threadID}
+		if !seen[t] {
+			seen[t] = true
+			targets = append(targets, t)
+		}
+		if b.state.GetUserSettings(ut.UserID).Lite {
+			lite[t] = true
+		}
+	}
+	if len(targets) == 0 {
+		return
+	}
+
+	roots := b.allowedRoots()
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil || !withinRoots(path, roots) {
+			continue
+		}
+
+		var offer []target
+		for _, t := range targets {
+			if info.Size() > b.config.ArtifactAutoSend || lite[t] {
+				offer = append(offer, t)
+				continue
+			}
+			if _, err := b.sendFile(t.chatID, t.threadID, path); err != nil {
+				log.Printf("Error sending artifact %s: %v", path, err)
+			}
+		}
+		if len(offer) == 0 {
+			continue
+		}
+
+		id := offerArtifact(path)
+		text := fmt.Sprintf("📎 Claude wrote %s (%s)", path, formatFileSize(info.Size()))
+		keyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Send file", fmt.Sprintf("art_send:%d", id)),
+		))
+		for _, t := range offer {
+			if _, err := b.sendMessageWithKeyboard(t.chatID, t.threadID, text, keyboard); err != nil {
+				log.Printf("Error offering artifact %s: %v", path, err)
+			}
+		}
+	}
+}
+
+// processArtifactCallback sends the file behind a "Send file" button.
+func (b *Bot) processArtifactCallback(cq *tgbotapi.CallbackQuery) {
+	if cq.Message == nil {
+		return
+	}
+	id, err := strconv.Atoi(strings.TrimPrefix(cq.Data, "art_send:"))
+	if err != nil {
+		return
+	}
+	chatID := cq.Message.Chat.ID
+	threadID := getThreadIDFromCallback(cq)
+
+	artifactOffersMu.Lock()
+	path, ok := artifactOffers[id]
+	artifactOffersMu.Unlock()
+	if !ok {
+		b.editMessageText(chatID, cq.Message.MessageID, "File offer expired. Use /c_get to browse for it.")
+		return
+	}
+	if !withinRoots(path, b.allowedRoots()) {
+		b.editMessageText(chatID, cq.Message.MessageID, path+" is outside the allowed directories.")
+		return
+	}
+
+	b.editMessageText(chatID, cq.Message.MessageID, "📎 Sending "+path+"...")
+	messageID := cq.Message.MessageID
+	go func() {
+		if _, err := b.sendFile(chatID, threadID, path); err != nil {
+			b.editMessageText(chatID, messageID, fmt.Sprintf("Error sending file: %v", err))
+			return
+		}
+		b.editMessageText(chatID, messageID, "📎 Sent "+path)
+	}()
+}
+
+// formatFileSize renders a byte count as B, KB or MB.
+func formatFileSize(n int64) string {
+	switch {
+	case n < 1024:
+		return fmt.Sprintf("%d B", n)
+	case n < 1024*1024:
+		return fmt.Sprintf("%.1f KB", float64(n)/1024)
+	default:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1024*1024))
+	}
+}
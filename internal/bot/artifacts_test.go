@@ -0,0 +1,70 @@
+package bot
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/otaviocarvalho/tramuntana/internal/monitor"
+)
+
+func TestBashPathArgs(t *testing.T) {
+	got := bashPathArgs(`python plot.py --out=chart.png -v > "report.md" 2>err.log`)
+	want := []string{"python", "plot.py", "chart.png", "report.md", "err.log"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestMatchesArtifact(t *testing.T) {
+	patterns := []string{"*.png", "dist/*.tar.gz"}
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"/work/api/chart.png", true},
+		{"/work/api/sub/chart.png", true},
+		{"/work/api/dist/app.tar.gz", true},
+		{"/work/api/app.tar.gz", false},
+		{"/work/api/main.go", false},
+	}
+	for _, tt := range tests {
+		if got := matchesArtifact(tt.path, "/work/api", patterns); got != tt.want {
+			t.Errorf("matchesArtifact(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestDetectArtifacts(t *testing.T) {
+	cwd := t.TempDir()
+	for _, name := range []string{"chart.png", "report.md", "old.png", "main.go"} {
+		if err := os.WriteFile(filepath.Join(cwd, name), []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := time.Now().Add(-time.Hour)
+	os.Chtimes(filepath.Join(cwd, "old.png"), old, old)
+
+	parsed := []monitor.ParsedEntry{
+		{ContentType: "tool_result", ToolName: "Write", ToolInput: filepath.Join(cwd, "report.md")},
+		{ContentType: "tool_result", ToolName: "Write", ToolInput: filepath.Join(cwd, "main.go")},
+		{ContentType: "tool_result", ToolName: "Bash", ToolInput: "python plot.py --out chart.png && ls old.png", Timestamp: time.Now()},
+		{ContentType: "tool_result", ToolName: "Bash", ToolInput: "cp a.png missing.png", IsError: true},
+		{ContentType: "tool_use", ToolName: "Write", ToolInput: filepath.Join(cwd, "chart.png")},
+	}
+	got := detectArtifacts(parsed, cwd, []string{"*.png", "*.md"})
+	want := []string{filepath.Join(cwd, "report.md"), filepath.Join(cwd, "chart.png")}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestFormatFileSize(t *testing.T) {
+	for n, want := range map[int64]string{512: "512 B", 2048: "2.0 KB", 3 * 1024 * 1024: "3.0 MB"} {
+		if got := formatFileSize(n); got != want {
+			t.Errorf("formatFileSize(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
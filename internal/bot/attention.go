@@ -0,0 +1,133 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/monitor"
+)
+
+// attentionMinWaiting is how many sessions must be waiting for input at once
+// before a needs-attention summary is opened. An open summary then follows
+// the waiting sessions until none are left.
+const attentionMinWaiting = 2
+
+// attentionItem is a session waiting for input.
+type attentionItem struct {
+	WindowID string
+	Prompt   string // interactive UI name, e.g. "PermissionPrompt"
+	Since    time.Time
+	Reminded bool // a reminder was sent (see remindPrompt)
+}
+
+// newAttentionItem describes a window whose pane shows an interactive prompt.
+func newAttentionItem(windowID, paneText string, now time.Time) attentionItem {
+	item := attentionItem{WindowID: windowID, Since: now}
+	if ui, ok := monitor.ExtractInteractiveContent(paneText); ok {
+		item.Prompt = ui.Name
+	}
+	return item
+}
+
+// promptLabel names an interactive UI for the summary.
+func promptLabel(name string) string {
+	switch {
+	case strings.HasPrefix(name, "AskUserQuestion"):
+		return "question"
+	case name == "PermissionPrompt":
+		return "permission"
+	case name == "ExitPlanMode":
+		return "plan approval"
+	case name == "":
+		return "input"
+	}
+	return name
+}
+
+// attentionTarget returns where the needs-attention summary goes.
+func (b *Bot) attentionTarget() (chatID int64, threadID int, ok bool) {
+	switch {
+	case b.config.AttentionAdmin:
+		return b.config.AdminChatID, 0, true
+	case b.config.AttentionTopicID != 0:
+		topicID := int(b.config.AttentionTopicID)
+		chatID := b.findChatIDForTopic(topicID)
+		return chatID, topicID, chatID != 0
+	}
+	return 0, 0, false
+}
+
+// updateAttention opens, updates or closes the needs-attention summary for the
+// sessions waiting now, oldest first.
+func (b *Bot) updateAttention(items []attentionItem) {
+	chatID, threadID, ok := b.attentionTarget()
+	if !ok {
+		return
+	}
+	b.mu.Lock()
+	msgID := b.attentionMsgID
+	b.mu.Unlock()
+
+	switch {
+	case msgID == 0 && len(items) < attentionMinWaiting:
+		return
+	case len(items) == 0:
+		if err := b.editMessageText(chatID, msgID, "✅ No sessions are waiting for input."); err != nil {
+			log.Printf("Error closing attention summary: %v", err)
+		}
+		b.setAttentionMsgID(0)
+		return
+	}
+
+	text, keyboard := b.formatAttention(items)
+	if msgID != 0 {
+		if err := b.editMessageWithKeyboard(chatID, msgID, text, keyboard); err == nil {
+			return
+		}
+	}
+	sent, err := b.sendMessageWithKeyboard(chatID, threadID, text, keyboard)
+	if err != nil {
+		log.Printf("Error sending attention summary: %v", err)
+		return
+	}
+	b.setAttentionMsgID(sent.MessageID)
+}
+
+func (b *Bot) setAttentionMsgID(id int) {
+	b.mu.Lock()
+	b.attentionMsgID = id
+	b.mu.Unlock()
+}
+
+// formatAttention lists waiting sessions, with a deep link button to each
+// topic.
+func (b *Bot) formatAttention(items []attentionItem) (string, tgbotapi.InlineKeyboardMarkup) {
+	sort.Slice(items, func(i, j int) bool { return items[i].Since.Before(items[j].Since) })
+
+	lines := []string{fmt.Sprintf("🔔 %d sessions need attention:", len(items))}
+	if len(items) == 1 {
+		lines[0] = "🔔 1 session needs attention:"
+	}
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, item := range items {
+		name, ok := b.state.GetWindowDisplayName(item.WindowID)
+		if !ok || name == "" {
+			name = item.WindowID
+		}
+		lines = append(lines, fmt.Sprintf("• %s — %s, since %s", name, promptLabel(item.Prompt), item.Since.Format("15:04")))
+		for _, ut := range b.state.FindUsersForWindow(item.WindowID) {
+			chatID, ok := b.state.GetGroupChatID(ut.UserID, ut.ThreadID)
+			threadID, _ := strconv.Atoi(ut.ThreadID)
+			if link := b.deepLink(chatID, threadID); ok && link != "" {
+				rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonURL("Open "+name, link)))
+				break
+			}
+		}
+	}
+	return strings.Join(lines, "\n"), tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
@@ -0,0 +1,31 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+)
+
+func TestFormatAttention(t *testing.T) {
+	b := newTestBot(t)
+	b.state.BindThread("100", "42", "@1")
+	b.state.SetGroupChatID("100", "42", -1001234567890)
+	b.state.SetWindowDisplayName("@1", "api")
+	b.state.SetWindowState("@2", state.WindowState{})
+
+	now := time.Date(2026, 1, 2, 15, 4, 0, 0, time.Local)
+	text, keyboard := b.formatAttention([]attentionItem{
+		{WindowID: "@2", Prompt: "AskUserQuestion_single", Since: now.Add(time.Minute)},
+		{WindowID: "@1", Prompt: "PermissionPrompt", Since: now},
+	})
+
+	want := "🔔 2 sessions need attention:\n• api — permission, since 15:04\n• @2 — question, since 15:05"
+	if text != want {
+		t.Errorf("text =\n%s\nwant\n%s", text, want)
+	}
+	if len(keyboard.InlineKeyboard) != 1 || !strings.HasSuffix(*keyboard.InlineKeyboard[0][0].URL, "/1234567890/42") {
+		t.Errorf("keyboard = %+v, want one button opening topic 42", keyboard.InlineKeyboard)
+	}
+}
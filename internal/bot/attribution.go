@@ -0,0 +1,74 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+)
+
+// handleAttributionCommand toggles "[from: name]" prefixes on prompts sent from this topic.
+func (b *Bot) handleAttributionCommand(msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+	threadID := getThreadID(msg)
+	threadIDStr := strconv.Itoa(threadID)
+
+	on := !b.state.IsAttributed(threadIDStr)
+	switch strings.ToLower(strings.TrimSpace(msg.CommandArguments())) {
+	case "on":
+		on = true
+	case "off":
+		on = false
+	case "":
+	default:
+		b.reply(chatID, threadID, "Usage: /attribution [on|off]")
+		return
+	}
+
+	b.state.SetAttribution(threadIDStr, on)
+	b.saveState()
+	if on {
+		b.reply(chatID, threadID, "Attribution on: prompts from this topic are prefixed with [from: <sender>].")
+	} else {
+		b.reply(chatID, threadID, "Attribution off.")
+	}
+}
+
+// senderName returns the Telegram handle used to attribute a prompt.
+func senderName(u *tgbotapi.User) string {
+	if u == nil {
+		return "unknown"
+	}
+	if u.UserName != "" {
+		return u.UserName
+	}
+	if u.FirstName != "" {
+		return u.FirstName
+	}
+	return strconv.FormatInt(u.ID, 10)
+}
+
+// attributePrompt prefixes a prompt with its sender.
+func attributePrompt(sender, text string) string {
+	return fmt.Sprintf("[from: %s] %s", sender, text)
+}
+
+// auditPrompt appends a forwarded prompt to audit.jsonl.
+func (b *Bot) auditPrompt(from *tgbotapi.User, threadID int, windowID, text string) {
+	entry := state.AuditEntry{
+		Time:     time.Now(),
+		UserID:   from.ID,
+		User:     senderName(from),
+		ThreadID: strconv.Itoa(threadID),
+		WindowID: windowID,
+		Text:     text,
+	}
+	if err := state.AppendAudit(filepath.Join(b.config.TramuntanaDir, "audit.jsonl"), entry); err != nil {
+		log.Printf("Error writing audit log: %v", err)
+	}
+}
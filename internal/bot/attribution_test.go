@@ -0,0 +1,30 @@
+package bot
+
+import (
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestSenderName(t *testing.T) {
+	tests := []struct {
+		user *tgbotapi.User
+		want string
+	}{
+		{&tgbotapi.User{ID: 1, UserName: "alice", FirstName: "Alice"}, "alice"},
+		{&tgbotapi.User{ID: 2, FirstName: "Bob"}, "Bob"},
+		{&tgbotapi.User{ID: 3}, "3"},
+		{nil, "unknown"},
+	}
+	for _, tt := range tests {
+		if got := senderName(tt.user); got != tt.want {
+			t.Errorf("senderName(%+v) = %q, want %q", tt.user, got, tt.want)
+		}
+	}
+}
+
+func TestAttributePrompt(t *testing.T) {
+	if got := attributePrompt("alice", "fix the tests"); got != "[from: alice] fix the tests" {
+		t.Errorf("got %q", got)
+	}
+}
@@ -0,0 +1,209 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/monitor"
+	"github.com/otaviocarvalho/tramuntana/internal/tmux"
+)
+
+// autoLoop tracks progress of an /auto loop running in a window.
+type autoLoop struct {
+	chatID        int64
+	threadID      int
+	project       string
+	started       time.Time
+	lastActivity  time.Time
+	lastHeartbeat time.Time
+	claimed       []string
+	completed     []string
+	stallAlerted  bool
+}
+
+var (
+	autoLoops   = make(map[string]*autoLoop) // windowID → loop
+	autoLoopsMu sync.Mutex
+)
+
+// startAutoLoop begins tracking an /auto loop, replacing any previous one for the window.
+func startAutoLoop(windowID string, chatID int64, threadID int, project string, now time.Time) {
+	autoLoopsMu.Lock()
+	defer autoLoopsMu.Unlock()
+	autoLoops[windowID] = &autoLoop{
+		chatID:        chatID,
+		threadID:      threadID,
+		project:       project,
+		started:       now,
+		lastActivity:  now,
+		lastHeartbeat: now,
+	}
+}
+
+// stopAutoLoop stops tracking a window's loop and returns it, if any.
+func stopAutoLoop(windowID string) (*autoLoop, bool) {
+	autoLoopsMu.Lock()
+	defer autoLoopsMu.Unlock()
+	loop, ok := autoLoops[windowID]
+	delete(autoLoops, windowID)
+	return loop, ok
+}
+
+// observe updates loop progress from a batch of transcript entries.
+func (l *autoLoop) observe(parsed []monitor.ParsedEntry, now time.Time) {
+	if len(parsed) == 0 {
+		return
+	}
+	l.lastActivity = now
+	l.stallAlerted = false
+
+	for _, pe := range parsed {
+		if pe.ContentType != "tool_result" || pe.ToolName != "Bash" || pe.IsError {
+			continue
+		}
+		if id := monitor.ClaimedTaskID(pe.ToolInput, pe.Text); id != "" {
+			l.claimed = appendIfMissing(l.claimed, id)
+		}
+		if id := monitor.DoneTaskID(pe.ToolInput); id != "" {
+			l.completed = appendIfMissing(l.completed, id)
+		}
+	}
+}
+
+// due reports whether a heartbeat or stall alert should be sent now.
+// A zero interval disables the corresponding check.
+func (l *autoLoop) due(now time.Time, heartbeat, stall time.Duration) (sendHeartbeat, sendStall bool) {
+	if stall > 0 && !l.stallAlerted && now.Sub(l.lastActivity) >= stall {
+		sendStall = true
+	}
+	if heartbeat > 0 && now.Sub(l.lastHeartbeat) >= heartbeat {
+		sendHeartbeat = true
+	}
+	return sendHeartbeat, sendStall
+}
+
+// progress summarises the loop for heartbeat and alert messages.
+func (l *autoLoop) progress(now time.Time) string {
+	s := fmt.Sprintf("running %s · claimed %d · completed %d",
+		now.Sub(l.started).Round(time.Second), len(l.claimed), len(l.completed))
+	if len(l.completed) > 0 {
+		s += " (" + strings.Join(l.completed, ", ") + ")"
+	}
+	return s
+}
+
+// HandleMonitorActivity records transcript activity for any /auto loop, /batch
+// checklist or picked task in the window, checks its project's /budget, and delivers
+// files Claude wrote that match the artifact patterns.
+func (b *Bot) HandleMonitorActivity(windowID string, parsed []monitor.ParsedEntry) {
+	autoLoopsMu.Lock()
+	if loop, ok := autoLoops[windowID]; ok {
+		loop.observe(parsed, time.Now())
+	}
+	autoLoopsMu.Unlock()
+
+	b.updateBatchProgress(windowID, parsed)
+	b.updateTaskLink(windowID, parsed)
+	notePromptFilesRead(windowID, parsed)
+	if b.config.NowSummary != "" || b.config.FeedTopicID != 0 {
+		noteAssistantText(windowID, parsed)
+	}
+	if b.config.FeedTopicID != 0 {
+		b.feedTranscript(windowID, parsed)
+	}
+	trackBashTools(windowID, parsed, time.Now())
+	b.checkBudget(windowID, time.Now())
+
+	if len(b.config.ArtifactPatterns) > 0 {
+		ws, _ := b.state.GetWindowState(windowID)
+		if paths := detectArtifacts(parsed, ws.CWD, b.config.ArtifactPatterns); len(paths) > 0 {
+			go b.deliverArtifacts(windowID, paths)
+		}
+	}
+}
+
+// checkAutoLoops sends due heartbeats and stall alerts for running /auto loops.
+func (b *Bot) checkAutoLoops(now time.Time) {
+	type notice struct {
+		loop     autoLoop
+		windowID string
+		stall    bool
+	}
+	var notices []notice
+
+	autoLoopsMu.Lock()
+	for windowID, loop := range autoLoops {
+		heartbeat, stall := loop.due(now, b.config.AutoHeartbeat, b.config.AutoStallTimeout)
+		if stall {
+			loop.stallAlerted = true
+			notices = append(notices, notice{*loop, windowID, true})
+		}
+		if heartbeat {
+			loop.lastHeartbeat = now
+			if !stall {
+				notices = append(notices, notice{*loop, windowID, false})
+			}
+		}
+	}
+	autoLoopsMu.Unlock()
+
+	for _, n := range notices {
+		if !n.stall {
+			b.reply(n.loop.chatID, n.loop.threadID, "💓 Auto loop: "+n.loop.progress(now))
+			continue
+		}
+		idle := now.Sub(n.loop.lastActivity).Round(time.Minute)
+		text := fmt.Sprintf("⚠️ Auto loop stalled: no activity for %s\n%s", idle, n.loop.progress(now))
+		keyboard := tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("Interrupt", "auto_int:"+n.windowID),
+			),
+		)
+		if _, err := b.sendMessageWithKeyboard(n.loop.chatID, n.loop.threadID, text, keyboard); err != nil {
+			log.Printf("Error sending stall alert: %v", err)
+		}
+	}
+}
+
+// finishAutoLoop posts a summary when an /auto loop's turn completes.
+func (b *Bot) finishAutoLoop(windowID string) {
+	loop, ok := stopAutoLoop(windowID)
+	if !ok {
+		return
+	}
+	b.reply(loop.chatID, loop.threadID, "Auto loop finished: "+loop.progress(time.Now()))
+}
+
+// processAutoCallback handles the Interrupt button on stall alerts.
+func (b *Bot) processAutoCallback(cq *tgbotapi.CallbackQuery) {
+	windowID, ok := strings.CutPrefix(cq.Data, "auto_int:")
+	if !ok || cq.Message == nil {
+		return
+	}
+	chatID := cq.Message.Chat.ID
+
+	if err := tmux.SendSpecialKey(b.config.TmuxSessionName, windowID, "Escape"); err != nil {
+		log.Printf("Error interrupting %s: %v", windowID, err)
+		b.answerCallback(cq.ID, "Failed to interrupt")
+		return
+	}
+	stopAutoLoop(windowID)
+
+	if err := b.editMessageText(chatID, cq.Message.MessageID, cq.Message.Text+"\n\nInterrupted."); err != nil {
+		log.Printf("Error editing stall alert: %v", err)
+	}
+}
+
+// appendIfMissing appends s to list unless it is already present.
+func appendIfMissing(list []string, s string) []string {
+	for _, existing := range list {
+		if existing == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
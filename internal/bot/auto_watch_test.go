@@ -0,0 +1,64 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/otaviocarvalho/tramuntana/internal/monitor"
+)
+
+func TestAutoLoop_Observe(t *testing.T) {
+	start := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	loop := &autoLoop{started: start, lastActivity: start, stallAlerted: true}
+
+	now := start.Add(2 * time.Minute)
+	loop.observe([]monitor.ParsedEntry{
+		{ContentType: "tool_result", ToolName: "Bash", ToolInput: "minuano-claim --project auth", Text: `{"id": "t1"}`},
+		{ContentType: "tool_result", ToolName: "Bash", ToolInput: `minuano-done t1 "ok"`},
+		{ContentType: "tool_result", ToolName: "Bash", ToolInput: `minuano-done t2 "ok"`, IsError: true},
+		{ContentType: "text", Text: "working on it"},
+	}, now)
+
+	if !loop.lastActivity.Equal(now) {
+		t.Errorf("lastActivity = %v, want %v", loop.lastActivity, now)
+	}
+	if loop.stallAlerted {
+		t.Error("activity should reset stall alert")
+	}
+	if len(loop.claimed) != 1 || loop.claimed[0] != "t1" {
+		t.Errorf("claimed = %v", loop.claimed)
+	}
+	if len(loop.completed) != 1 || loop.completed[0] != "t1" {
+		t.Errorf("completed = %v (failed commands should not count)", loop.completed)
+	}
+	if got := loop.progress(now); !strings.Contains(got, "running 2m0s · claimed 1 · completed 1 (t1)") {
+		t.Errorf("progress = %q", got)
+	}
+}
+
+func TestAutoLoop_Due(t *testing.T) {
+	start := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	loop := &autoLoop{started: start, lastActivity: start, lastHeartbeat: start}
+
+	hb, stall := loop.due(start.Add(4*time.Minute), 10*time.Minute, 5*time.Minute)
+	if hb || stall {
+		t.Errorf("nothing should be due yet: hb=%v stall=%v", hb, stall)
+	}
+
+	hb, stall = loop.due(start.Add(6*time.Minute), 10*time.Minute, 5*time.Minute)
+	if hb || !stall {
+		t.Errorf("stall should be due: hb=%v stall=%v", hb, stall)
+	}
+
+	loop.stallAlerted = true
+	hb, stall = loop.due(start.Add(11*time.Minute), 10*time.Minute, 5*time.Minute)
+	if !hb || stall {
+		t.Errorf("heartbeat due, stall already alerted: hb=%v stall=%v", hb, stall)
+	}
+
+	hb, stall = loop.due(start.Add(time.Hour), 0, 0)
+	if hb || stall {
+		t.Error("zero intervals should disable both checks")
+	}
+}
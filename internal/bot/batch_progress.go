@@ -0,0 +1,212 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/otaviocarvalho/tramuntana/internal/monitor"
+)
+
+// batchRun tracks a /batch checklist message for a window.
+type batchRun struct {
+	chatID    int64
+	threadID  int
+	messageID int
+	tasks     []string
+	status    map[string]string // task ID → "done" or "failed"; absent = pending
+	current   string            // task most recently claimed, if any
+	lastText  string            // last rendered checklist, to skip no-op edits
+}
+
+var (
+	batchRuns   = make(map[string]*batchRun) // windowID → run
+	batchRunsMu sync.Mutex
+)
+
+// batchSymbols maps checklist states to their markers.
+var batchSymbols = map[string]string{
+	"done":    "☑",
+	"failed":  "✗",
+	"current": "▶",
+	"pending": "☐",
+}
+
+func newBatchRun(chatID int64, threadID int, tasks []string) *batchRun {
+	return &batchRun{
+		chatID:   chatID,
+		threadID: threadID,
+		tasks:    tasks,
+		status:   make(map[string]string),
+	}
+}
+
+// finished reports whether every task is done or failed.
+func (r *batchRun) finished() bool {
+	return len(r.status) >= len(r.tasks)
+}
+
+// state returns the checklist state of a task.
+func (r *batchRun) state(taskID string) string {
+	if s, ok := r.status[taskID]; ok {
+		return s
+	}
+	if r.current != "" {
+		if _, ok := r.status[r.current]; !ok {
+			if taskID == r.current {
+				return "current"
+			}
+			return "pending"
+		}
+	}
+	// No live claim: the first unfinished task is the one being worked on.
+	for _, id := range r.tasks {
+		if _, ok := r.status[id]; !ok {
+			if id == taskID {
+				return "current"
+			}
+			break
+		}
+	}
+	return "pending"
+}
+
+// observe applies transcript entries to the checklist. Returns true if it changed.
+func (r *batchRun) observe(parsed []monitor.ParsedEntry) bool {
+	changed := false
+	for _, pe := range parsed {
+		if pe.ContentType != "tool_result" || pe.ToolName != "Bash" || pe.IsError {
+			continue
+		}
+		if id := monitor.ClaimedTaskID(pe.ToolInput, pe.Text); id != "" && r.has(id) && id != r.current {
+			r.current = id
+			changed = true
+		}
+		if id := monitor.DoneTaskID(pe.ToolInput); id != "" && r.has(id) && r.status[id] != "done" {
+			r.status[id] = "done"
+			changed = true
+		}
+	}
+	return changed
+}
+
+func (r *batchRun) has(taskID string) bool {
+	for _, id := range r.tasks {
+		if id == taskID {
+			return true
+		}
+	}
+	return false
+}
+
+// text renders the checklist message.
+func (r *batchRun) text() string {
+	done := 0
+	items := make([]string, len(r.tasks))
+	for i, id := range r.tasks {
+		st := r.state(id)
+		if st == "done" {
+			done++
+		}
+		items[i] = batchSymbols[st] + " " + id
+	}
+
+	header := fmt.Sprintf("Batch: %d/%d done", done, len(r.tasks))
+	if r.finished() {
+		header = fmt.Sprintf("Batch complete: %d/%d done", done, len(r.tasks))
+	}
+	return header + "\n" + strings.Join(items, " · ")
+}
+
+// startBatchProgress sends the checklist message and starts tracking it.
+func (b *Bot) startBatchProgress(windowID string, chatID int64, threadID int, tasks []string) {
+	run := newBatchRun(chatID, threadID, tasks)
+	run.lastText = run.text()
+	sent, err := b.sendMessageInThread(chatID, threadID, run.lastText)
+	if err != nil {
+		log.Printf("Error sending batch checklist: %v", err)
+		return
+	}
+	run.messageID = sent.MessageID
+
+	batchRunsMu.Lock()
+	batchRuns[windowID] = run
+	batchRunsMu.Unlock()
+}
+
+// updateBatchProgress applies transcript activity to a window's checklist.
+func (b *Bot) updateBatchProgress(windowID string, parsed []monitor.ParsedEntry) {
+	batchRunsMu.Lock()
+	run, ok := batchRuns[windowID]
+	if !ok || !run.observe(parsed) {
+		batchRunsMu.Unlock()
+		return
+	}
+	if run.finished() {
+		delete(batchRuns, windowID)
+	}
+	text, changed := run.render()
+	chatID, messageID := run.chatID, run.messageID
+	batchRunsMu.Unlock()
+
+	// Edit outside the lock so a slow Telegram call doesn't hold up other
+	// windows' checklists.
+	if changed {
+		b.editBatchMessage(chatID, messageID, text)
+	}
+}
+
+// finishBatchProgress reconciles a window's checklist with Minuano when the
+// batch turn ends, then stops tracking it.
+func (b *Bot) finishBatchProgress(windowID string) {
+	batchRunsMu.Lock()
+	run, ok := batchRuns[windowID]
+	delete(batchRuns, windowID)
+	batchRunsMu.Unlock()
+	if !ok {
+		return
+	}
+
+	for _, id := range run.tasks {
+		if _, known := run.status[id]; known {
+			continue
+		}
+		detail, err := b.minuanoBridge.Show(id)
+		if err != nil || detail.Task == nil {
+			continue
+		}
+		if detail.Task.Status == "done" || detail.Task.Status == "failed" {
+			run.status[id] = detail.Task.Status
+		}
+	}
+	run.current = ""
+
+	if text, changed := run.render(); changed {
+		b.editBatchMessage(run.chatID, run.messageID, text)
+	}
+}
+
+// stopBatchProgress stops tracking a window's checklist without editing it.
+func stopBatchProgress(windowID string) {
+	batchRunsMu.Lock()
+	delete(batchRuns, windowID)
+	batchRunsMu.Unlock()
+}
+
+// render returns the checklist text and whether it differs from the last
+// rendered one, which it becomes.
+func (r *batchRun) render() (string, bool) {
+	text := r.text()
+	if text == r.lastText {
+		return text, false
+	}
+	r.lastText = text
+	return text, true
+}
+
+func (b *Bot) editBatchMessage(chatID int64, messageID int, text string) {
+	if err := b.editMessageText(chatID, messageID, text); err != nil {
+		log.Printf("Error editing batch checklist: %v", err)
+	}
+}
@@ -0,0 +1,64 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/otaviocarvalho/tramuntana/internal/monitor"
+)
+
+func TestBatchRun_Text(t *testing.T) {
+	run := newBatchRun(1, 2, []string{"t1", "t2", "t3"})
+	if got, want := run.text(), "Batch: 0/3 done\n▶ t1 · ☐ t2 · ☐ t3"; got != want {
+		t.Errorf("initial text = %q, want %q", got, want)
+	}
+
+	changed := run.observe([]monitor.ParsedEntry{
+		{ContentType: "tool_result", ToolName: "Bash", ToolInput: `minuano-done t1 "ok"`},
+		{ContentType: "tool_result", ToolName: "Bash", ToolInput: "minuano-pick t3", Text: `{"id": "t3"}`},
+	})
+	if !changed {
+		t.Fatal("observe should report a change")
+	}
+	if got, want := run.text(), "Batch: 1/3 done\n☑ t1 · ☐ t2 · ▶ t3"; got != want {
+		t.Errorf("text = %q, want %q", got, want)
+	}
+
+	run.status["t2"] = "failed"
+	run.observe([]monitor.ParsedEntry{
+		{ContentType: "tool_result", ToolName: "Bash", ToolInput: `minuano-done t3 "ok"`},
+	})
+	if !run.finished() {
+		t.Error("all tasks resolved; run should be finished")
+	}
+	if got, want := run.text(), "Batch complete: 2/3 done\n☑ t1 · ✗ t2 · ☑ t3"; got != want {
+		t.Errorf("final text = %q, want %q", got, want)
+	}
+}
+
+func TestBatchRun_IgnoresUnrelated(t *testing.T) {
+	run := newBatchRun(1, 2, []string{"t1"})
+	changed := run.observe([]monitor.ParsedEntry{
+		{ContentType: "tool_result", ToolName: "Bash", ToolInput: `minuano-done other "ok"`},
+		{ContentType: "tool_result", ToolName: "Bash", ToolInput: `minuano-done t1 "ok"`, IsError: true},
+		{ContentType: "text", Text: "minuano-done t1"},
+	})
+	if changed {
+		t.Error("unrelated or failed entries should not change the checklist")
+	}
+}
+
+func TestBatchRun_RenderSkipsUnchanged(t *testing.T) {
+	run := newBatchRun(1, 2, []string{"t1", "t2"})
+	text, changed := run.render()
+	if !changed || text != run.text() {
+		t.Errorf("first render = %q, %v", text, changed)
+	}
+	if _, changed := run.render(); changed {
+		t.Error("unchanged checklist rendered as changed")
+	}
+	run.status["t1"] = "done"
+	if text, changed := run.render(); !changed || !strings.HasPrefix(text, "Batch: 1/2 done") {
+		t.Errorf("render after progress = %q, %v", text, changed)
+	}
+}
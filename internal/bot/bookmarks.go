@@ -0,0 +1,248 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/queue"
+	"github.com/otaviocarvalho/tramuntana/internal/render"
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+)
+
+// maxListedBookmarks is how many of a topic's most recent bookmarks /bookmarks shows.
+const maxListedBookmarks = 10
+
+// handleBookmarkCommand records the current transcript position under a label.
+func (b *Bot) handleBookmarkCommand(msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+	threadID := getThreadID(msg)
+
+	windowID, bound := b.resolveWindow(msg)
+	if !bound {
+		b.reply(chatID, threadID, "No session bound to this topic.")
+		return
+	}
+	jsonlPath := b.findJSONLForWindow(windowID)
+	if jsonlPath == "" {
+		b.reply(chatID, threadID, "No session transcript found.")
+		return
+	}
+	info, err := os.Stat(jsonlPath)
+	if err != nil {
+		b.reply(chatID, threadID, "No session transcript found.")
+		return
+	}
+	entries := readEntriesUntil(jsonlPath, info.Size())
+	if len(entries) == 0 {
+		b.reply(chatID, threadID, "Session transcript is empty.")
+		return
+	}
+
+	threadIDStr := strconv.Itoa(threadID)
+	label := strings.TrimSpace(msg.CommandArguments())
+	if label == "" {
+		label = defaultBookmarkLabel(entries, len(b.state.GetBookmarks(threadIDStr))+1)
+	}
+
+	b.state.AddBookmark(threadIDStr, state.Bookmark{
+		Label:     label,
+		SessionID: strings.TrimSuffix(filepath.Base(jsonlPath), ".jsonl"),
+		FilePath:  jsonlPath,
+		Offset:    info.Size(),
+		CreatedAt: time.Now(),
+		CreatedBy: senderName(msg.From),
+	})
+	b.saveState()
+	sent, err := b.sendMessageInThread(chatID, threadID, fmt.Sprintf("🔖 Bookmarked: %s\nUse /bookmarks to list.", label))
+	if err != nil {
+		log.Printf("Error sending bookmark confirmation: %v", err)
+		return
+	}
+	b.pinMessage(chatID, threadID, sent.MessageID, pinBookmark)
+}
+
+// handleBookmarksCommand lists the topic's bookmarks with view, resend and delete buttons.
+func (b *Bot) handleBookmarksCommand(msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+	threadID := getThreadID(msg)
+
+	bms := b.state.GetBookmarks(strconv.Itoa(threadID))
+	if len(bms) == 0 {
+		b.reply(chatID, threadID, "No bookmarks in this topic. Use /bookmark [label] to add one.")
+		return
+	}
+	loc := b.state.GetUserSettings(strconv.FormatInt(msg.From.ID, 10)).Location()
+	text, keyboard := buildBookmarkList(bms, loc)
+	b.sendMessageWithKeyboard(chatID, threadID, text, keyboard)
+}
+
+// buildBookmarkList renders the most recent bookmarks and their buttons.
+// Callback data carries each bookmark's ID, so a stale list can't act on a
+// bookmark that has since taken another's place.
+func buildBookmarkList(bms []state.Bookmark, loc *time.Location) (string, tgbotapi.InlineKeyboardMarkup) {
+	first := 0
+	if len(bms) > maxListedBookmarks {
+		first = len(bms) - maxListedBookmarks
+	}
+
+	lines := []string{fmt.Sprintf("Bookmarks (%d)", len(bms)), ""}
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for i := first; i < len(bms); i++ {
+		bm := bms[i]
+		line := fmt.Sprintf("%d. %s", i+1, bm.Label)
+		if ts := render.FormatTimestamp(bm.CreatedAt, loc); ts != "" {
+			line = ts + " " + line
+		}
+		lines = append(lines, line)
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%d View", i+1), fmt.Sprintf("bm_view:%d", bm.ID)),
+			tgbotapi.NewInlineKeyboardButtonData("Resend", fmt.Sprintf("bm_send:%d", bm.ID)),
+			tgbotapi.NewInlineKeyboardButtonData("Delete", fmt.Sprintf("bm_del:%d", bm.ID)),
+		))
+	}
+	return strings.Join(lines, "\n"), tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// processBookmarkCallback handles bm_view, bm_send and bm_del buttons.
+func (b *Bot) processBookmarkCallback(cq *tgbotapi.CallbackQuery) {
+	if cq.Message == nil {
+		return
+	}
+	action, idStr, ok := strings.Cut(strings.TrimPrefix(cq.Data, "bm_"), ":")
+	id, err := strconv.Atoi(idStr)
+	if !ok || err != nil {
+		return
+	}
+
+	chatID := cq.Message.Chat.ID
+	threadID := getThreadIDFromCallback(cq)
+	threadIDStr := strconv.Itoa(threadID)
+	bm, ok := b.state.GetBookmark(threadIDStr, id)
+	if !ok {
+		b.reply(chatID, threadID, "Bookmark no longer exists.")
+		return
+	}
+
+	switch action {
+	case "view":
+		b.viewBookmark(cq, bm)
+	case "send":
+		b.resendBookmark(cq, bm)
+	case "del":
+		if b.state.RemoveBookmark(threadIDStr, id) {
+			b.saveState()
+		}
+		bms := b.state.GetBookmarks(threadIDStr)
+		if len(bms) == 0 {
+			b.editMessageText(chatID, cq.Message.MessageID, "No bookmarks in this topic.")
+			return
+		}
+		loc := b.state.GetUserSettings(strconv.FormatInt(cq.From.ID, 10)).Location()
+		text, keyboard := buildBookmarkList(bms, loc)
+		b.editMessageWithKeyboard(chatID, cq.Message.MessageID, text, keyboard)
+	}
+}
+
+// viewBookmark opens the history browser at the page holding the bookmarked turn.
+// Paging is offered only while the bookmarked transcript is still the topic's
+// current one, since history callbacks resolve the transcript by window.
+func (b *Bot) viewBookmark(cq *tgbotapi.CallbackQuery, bm state.Bookmark) {
+	chatID := cq.Message.Chat.ID
+	threadID := getThreadIDFromCallback(cq)
+
+	prefix := readEntriesUntil(bm.FilePath, bm.Offset)
+	entries := readAllEntries(bm.FilePath)
+	if len(prefix) == 0 || len(entries) == 0 {
+		b.reply(chatID, threadID, "Bookmarked transcript is no longer available.")
+		return
+	}
+	page := bookmarkTurnStart(prefix) / entriesPerPage
+	totalPages := (len(entries) + entriesPerPage - 1) / entriesPerPage
+
+	label := bm.SessionID
+	windowID, bound := b.state.GetWindowForThread(strconv.FormatInt(cq.From.ID, 10), strconv.Itoa(threadID))
+	current := bound && b.findJSONLForWindow(windowID) == bm.FilePath
+	if current {
+		label = windowID
+	}
+
+	loc := b.state.GetUserSettings(strconv.FormatInt(cq.From.ID, 10)).Location()
+	text := "🔖 " + bm.Label + "\n" + formatHistoryPage(entries, page, label, loc)
+	if keyboard := buildHistoryKeyboard(windowID, page, totalPages); current && keyboard != nil {
+		b.sendMessageWithKeyboard(chatID, threadID, text, *keyboard)
+	} else {
+		b.reply(chatID, threadID, text)
+	}
+}
+
+// resendBookmark delivers the bookmarked turn's prompt and replies again.
+func (b *Bot) resendBookmark(cq *tgbotapi.CallbackQuery, bm state.Bookmark) {
+	chatID := cq.Message.Chat.ID
+	threadID := getThreadIDFromCallback(cq)
+
+	prefix := readEntriesUntil(bm.FilePath, bm.Offset)
+	parts := bookmarkTurnParts(prefix[bookmarkTurnStart(prefix):])
+	if len(parts) == 0 {
+		b.reply(chatID, threadID, "Bookmarked turn has no text to resend.")
+		return
+	}
+	parts = append([]string{"🔖 " + bm.Label}, parts...)
+
+	if b.msgQueue == nil {
+		b.reply(chatID, threadID, strings.Join(parts, "\n\n"))
+		return
+	}
+	windowID, _ := b.state.GetWindowForThread(strconv.FormatInt(cq.From.ID, 10), strconv.Itoa(threadID))
+	b.msgQueue.Enqueue(queue.MessageTask{
+		UserID:      cq.From.ID,
+		ThreadID:    threadID,
+		ChatID:      chatID,
+		Parts:       parts,
+		ContentType: "content",
+		WindowID:    windowID,
+	})
+}
+
+// bookmarkTurnStart returns the index of the last user prompt, where the
+// bookmarked turn begins, or 0 if there is none.
+func bookmarkTurnStart(entries []historyEntry) int {
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].Role == "user" && entries[i].ContentType == "text" {
+			return i
+		}
+	}
+	return 0
+}
+
+// bookmarkTurnParts renders a turn's text entries the way the monitor delivers them.
+func bookmarkTurnParts(turn []historyEntry) []string {
+	var parts []string
+	for _, e := range turn {
+		if e.ContentType != "text" || strings.TrimSpace(e.Text) == "" {
+			continue
+		}
+		if e.Role == "user" {
+			parts = append(parts, "\U0001F464 "+render.FormatText(e.Text))
+		} else {
+			parts = append(parts, render.FormatText(e.Text))
+		}
+	}
+	return parts
+}
+
+// defaultBookmarkLabel names a bookmark after the prompt that started its turn.
+func defaultBookmarkLabel(entries []historyEntry, n int) string {
+	start := bookmarkTurnStart(entries)
+	if e := entries[start]; e.Role == "user" && e.ContentType == "text" {
+		if label := truncateText(strings.TrimSpace(e.Text), 40); label != "" {
+			return label
+		}
+	}
+	return fmt.Sprintf("Bookmark %d", n)
+}
@@ -0,0 +1,71 @@
+package bot
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+)
+
+func TestReadEntriesUntil_StopsAtOffset(t *testing.T) {
+	first := `{"type":"user","message":{"content":"first prompt"}}` + "\n" +
+		`{"type":"assistant","message":{"content":[{"type":"text","text":"first answer"}]}}` + "\n"
+	later := `{"type":"user","message":{"content":"second prompt"}}` + "\n"
+	path := filepath.Join(t.TempDir(), "sess.jsonl")
+	if err := os.WriteFile(path, []byte(first+later), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := readEntriesUntil(path, int64(len(first)))
+	if len(entries) != 2 || entries[1].Text != "first answer" {
+		t.Fatalf("unexpected entries: %+v", entries)
+	}
+	if all := readAllEntries(path); len(all) != 3 {
+		t.Errorf("readAllEntries = %d entries, want 3", len(all))
+	}
+}
+
+func TestBookmarkTurn(t *testing.T) {
+	entries := []historyEntry{
+		{Role: "user", ContentType: "text", Text: "old prompt"},
+		{Role: "assistant", ContentType: "text", Text: "old answer"},
+		{Role: "user", ContentType: "text", Text: "fix the login bug\nin detail"},
+		{Role: "assistant", ContentType: "tool_use", Text: "Read(login.go)"},
+		{Role: "assistant", ContentType: "text", Text: "Fixed it."},
+	}
+
+	start := bookmarkTurnStart(entries)
+	if start != 2 {
+		t.Fatalf("turn start = %d, want 2", start)
+	}
+	parts := bookmarkTurnParts(entries[start:])
+	if len(parts) != 2 || !strings.Contains(parts[0], "fix the login bug") || parts[1] != "Fixed it." {
+		t.Errorf("unexpected parts: %q", parts)
+	}
+	if got := defaultBookmarkLabel(entries, 1); got != "fix the login bug" {
+		t.Errorf("label = %q", got)
+	}
+	if got := defaultBookmarkLabel(entries[1:2], 3); got != "Bookmark 3" {
+		t.Errorf("label without a prompt = %q", got)
+	}
+}
+
+func TestBuildBookmarkList(t *testing.T) {
+	var bms []state.Bookmark
+	for i := 0; i < maxListedBookmarks+2; i++ {
+		bms = append(bms, state.Bookmark{ID: i + 1, Label: "bm"})
+	}
+	text, kb := buildBookmarkList(bms, nil)
+	if len(kb.InlineKeyboard) != maxListedBookmarks {
+		t.Fatalf("rows = %d, want %d", len(kb.InlineKeyboard), maxListedBookmarks)
+	}
+	if !strings.HasPrefix(text, "Bookmarks (12)") || strings.Contains(text, "\n1. bm") {
+		t.Errorf("unexpected list text:\n%s", text)
+	}
+	row := kb.InlineKeyboard[0]
+	if *row[0].CallbackData != "bm_view:3" || *row[1].CallbackData != "bm_send:3" || *row[2].CallbackData != "bm_del:3" {
+		t.Errorf("unexpected callbacks: %s %s %s", *row[0].CallbackData, *row[1].CallbackData, *row[2].CallbackData)
+	}
+}
@@ -5,14 +5,21 @@ import (
 	"fmt"
 	"log"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/archive"
 	"github.com/otaviocarvalho/tramuntana/internal/config"
 	"github.com/otaviocarvalho/tramuntana/internal/minuano"
+	"github.com/otaviocarvalho/tramuntana/internal/notify"
 	"github.com/otaviocarvalho/tramuntana/internal/queue"
 	"github.com/otaviocarvalho/tramuntana/internal/state"
+	"github.com/otaviocarvalho/tramuntana/internal/telemetry"
 	"github.com/otaviocarvalho/tramuntana/internal/tmux"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // Bot is the main Telegram bot instance.
@@ -22,39 +29,91 @@ type Bot struct {
 	state  *state.State
 	mu     sync.RWMutex
 
-	// Per-user browse state for directory browser
-	browseStates map[int64]*BrowseState
+	// Per-user, per-topic browse state for directory browser
+	browseStates wizardStates[BrowseState]
 	// Per-user cached window lists for window picker
 	windowCache map[int64][]tmux.Window
-	// Per-user window picker state
-	windowPickerStates map[int64]*windowPickerState
-	// Per-user file browser state for /get command
-	fileBrowseStates map[int64]*FileBrowseState
-	// Per-user add-task wizard state
-	addTaskStates map[int64]*addTaskState
-	// Per-user task picker state (for /pick and /pickw without args)
-	taskPickerStates map[int64]*taskPickerState
+	// Per-user, per-topic window picker state
+	windowPickerStates wizardStates[windowPickerState]
+	// Per-user, per-topic file browser state for /get command
+	fileBrowseStates wizardStates[FileBrowseState]
+	// Per-user, per-topic add-task wizard state
+	addTaskStates wizardStates[addTaskState]
+	// Per-user, per-topic task picker state (for /pick and /pickw without args)
+	taskPickerStates wizardStates[taskPickerState]
 	// Per-user pending input for parameterized commands
 	pendingInputs map[int64]*pendingInput
 	// Per-user pending plan approval state
 	planStates map[int64]*planState
+	// Per-user pending task edit awaiting confirmation
+	taskEditStates map[int64]*taskEditState
+	// Per-user pending choice of how to restart a dead session
+	recoveryOffers map[int64]*recoveryOffer
+	// /compose drafts per user and topic
+	composeDrafts map[wizardKey]*composeDraft
+	// Per-user /broadcast awaiting confirmation
+	broadcasts map[int64]*pendingBroadcast
+	// Per-user prompt held by /confirm until Send is pressed
+	heldPrompts map[wizardKey]*heldPrompt
+	// "chat/thread" topics already told about the pause; the held messages
+	// are kept in state, so they survive a restart
+	heldTopics map[string]bool
+	// Per-user time an unauthorized user was last offered an access request
+	accessPrompts map[int64]time.Time
+	// Per-user access requests awaiting the owner's decision (user → name)
+	accessRequests map[int64]string
+	// Forum topic icon emoji → custom emoji ID, fetched on first use, and
+	// when to fetch again after a failure (or while a fetch is in flight)
+	topicIconIDs      map[string]string
+	topicIconsRetryAt time.Time
+	// Open needs-attention summary message, 0 if none
+	attentionMsgID int
+	// Pending debounced write of state.json, nil if none
+	saveTimer *time.Timer
+	saveMu    sync.Mutex
 	// Monitor state (set by serve command when monitor is started)
 	monitorState *state.MonitorState
 	// Minuano CLI bridge
 	minuanoBridge *minuano.Bridge
 	// Message queue (set after construction via SetQueue)
 	msgQueue *queue.Queue
+	// Usage tracker for daily digests (set by serve command)
+	usage *state.Usage
+	// Event bus subscriptions (set by serve command via SetEventBus)
+	events *eventSubs
+	// Critical alerts sent outside Telegram; nil if none are configured
+	alerts *notify.Alerter
+	// Where ended sessions' transcripts are archived; nil if archiving is off
+	archives archive.Store
+	// Archived sessions recently read by the history browser, oldest first
+	archiveCache []cachedArchive
+	// Subsystem → last watchdog restart notice in the admin chat
+	restartNotices map[string]time.Time
 }
 
 // New creates a new Bot instance.
 func New(cfg *config.Config) (*Bot, error) {
-	api, err := tgbotapi.NewBotAPI(cfg.TelegramBotToken)
+	endpoint := tgbotapi.APIEndpoint
+	if cfg.Coordinator != "" {
+		// Federated host: the coordinator serves the Bot API for our topics
+		endpoint = cfg.Coordinator + "/bot%s/%s"
+	}
+	api, err := tgbotapi.NewBotAPIWithAPIEndpoint(cfg.TelegramBotToken, endpoint)
 	if err != nil {
 		return nil, fmt.Errorf("creating bot API: %w", err)
 	}
 
+	if cfg.Coordinator != "" {
+		host, _, _ := strings.Cut(cfg.TelegramBotToken, ":")
+		log.Printf("Connected to coordinator %s as host %s", cfg.Coordinator, host)
+	}
 	log.Printf("Authorized as @%s", api.Self.UserName)
+	return NewWithAPI(cfg, api)
+}
 
+// NewWithAPI creates a Bot around an existing API client, e.g. one pointed at
+// a fake Bot API server in tests.
+func NewWithAPI(cfg *config.Config, api *tgbotapi.BotAPI) (*Bot, error) {
 	// Load state
 	statePath := filepath.Join(cfg.TramuntanaDir, "state.json")
 	st, err := state.Load(statePath)
@@ -68,18 +127,22 @@ func New(cfg *config.Config) (*Bot, error) {
 	}
 
 	return &Bot{
-		api:                api,
-		config:             cfg,
-		state:              st,
-		browseStates:       make(map[int64]*BrowseState),
-		windowCache:        make(map[int64][]tmux.Window),
-		windowPickerStates: make(map[int64]*windowPickerState),
-		fileBrowseStates:   make(map[int64]*FileBrowseState),
-		addTaskStates:      make(map[int64]*addTaskState),
-		taskPickerStates:   make(map[int64]*taskPickerState),
-		pendingInputs:      make(map[int64]*pendingInput),
-		planStates:         make(map[int64]*planState),
-		minuanoBridge:      minuano.NewBridge(cfg.MinuanoBin, cfg.MinuanoDB),
+		api:            api,
+		config:         cfg,
+		state:          st,
+		windowCache:    make(map[int64][]tmux.Window),
+		pendingInputs:  make(map[int64]*pendingInput),
+		planStates:     make(map[int64]*planState),
+		taskEditStates: make(map[int64]*taskEditState),
+		recoveryOffers: make(map[int64]*recoveryOffer),
+		composeDrafts:  make(map[wizardKey]*composeDraft),
+		heldPrompts:    make(map[wizardKey]*heldPrompt),
+		broadcasts:     make(map[int64]*pendingBroadcast),
+		accessPrompts:  make(map[int64]time.Time),
+		accessRequests: make(map[int64]string),
+		minuanoBridge:  minuano.NewBridge(cfg.MinuanoBin, cfg.MinuanoDB),
+		alerts:         newAlerter(cfg),
+		archives:       newArchiveStore(cfg),
 	}, nil
 }
 
@@ -87,24 +150,67 @@ func New(cfg *config.Config) (*Bot, error) {
 func (b *Bot) registerCommands() {
 	commands := tgbotapi.NewSetMyCommands(
 		tgbotapi.BotCommand{Command: "menu", Description: "Show command menu"},
-		tgbotapi.BotCommand{Command: "c_screenshot", Description: "Terminal screenshot with control keys"},
+		tgbotapi.BotCommand{Command: "c_screenshot", Description: "Terminal screenshot with control keys (settings: appearance)"},
 		tgbotapi.BotCommand{Command: "c_esc", Description: "Send Escape to interrupt Claude"},
+		tgbotapi.BotCommand{Command: "raw", Description: "Type messages as keystrokes, for TUIs like vim"},
+		tgbotapi.BotCommand{Command: "enter", Description: "Press Enter in the terminal"},
 		tgbotapi.BotCommand{Command: "c_clear", Description: "Forward /clear to Claude Code"},
+		tgbotapi.BotCommand{Command: "rewind", Description: "Restore code and conversation to an earlier checkpoint"},
 		tgbotapi.BotCommand{Command: "c_help", Description: "Forward /help to Claude Code"},
+		tgbotapi.BotCommand{Command: "cc", Description: "Forward any slash command to Claude Code"},
 		tgbotapi.BotCommand{Command: "c_get", Description: "Browse and send a file"},
 		tgbotapi.BotCommand{Command: "p_bind", Description: "Bind a Minuano project to this topic"},
+		tgbotapi.BotCommand{Command: "p_projects", Description: "List, create and bind Minuano projects"},
 		tgbotapi.BotCommand{Command: "p_tasks", Description: "List tasks for the bound project"},
 		tgbotapi.BotCommand{Command: "p_add", Description: "Create a new Minuano task"},
 		tgbotapi.BotCommand{Command: "p_delete", Description: "Delete a Minuano task"},
-		tgbotapi.BotCommand{Command: "p_history", Description: "Message history for this topic"},
+		tgbotapi.BotCommand{Command: "p_retitle", Description: "Rename a Minuano task"},
+		tgbotapi.BotCommand{Command: "p_priority", Description: "Change a Minuano task's priority"},
+		tgbotapi.BotCommand{Command: "p_note", Description: "Add a context note to a Minuano task"},
+		tgbotapi.BotCommand{Command: "p_drop", Description: "Cancel a Minuano task"},
+		tgbotapi.BotCommand{Command: "p_history", Description: "Message history for this topic, or its archived sessions"},
 		tgbotapi.BotCommand{Command: "t_pick", Description: "Assign a specific task to Claude"},
 		tgbotapi.BotCommand{Command: "t_pickw", Description: "Pick task in isolated worktree"},
 		tgbotapi.BotCommand{Command: "t_auto", Description: "Auto-claim and work project tasks"},
 		tgbotapi.BotCommand{Command: "t_batch", Description: "Work a list of tasks in order"},
 		tgbotapi.BotCommand{Command: "t_unclaim", Description: "Release a claimed task back to ready"},
 		tgbotapi.BotCommand{Command: "t_merge", Description: "Merge a branch (auto-resolve conflicts)"},
+		tgbotapi.BotCommand{Command: "worktrees", Description: "Worktree disk usage, age and cleanup"},
 		tgbotapi.BotCommand{Command: "t_plan", Description: "Plan and create tasks from a description"},
 		tgbotapi.BotCommand{Command: "plan", Description: "Open a planner session in this topic"},
+		tgbotapi.BotCommand{Command: "compose", Description: "Build a multi-message prompt, then /send it"},
+		tgbotapi.BotCommand{Command: "send", Description: "Send the /compose draft to Claude"},
+		tgbotapi.BotCommand{Command: "discard", Description: "Discard the /compose draft"},
+		tgbotapi.BotCommand{Command: "allow", Description: "Pre-approve a Claude permission for this project"},
+		tgbotapi.BotCommand{Command: "skills", Description: "List the project's Claude skills and run one"},
+		tgbotapi.BotCommand{Command: "bookmark", Description: "Bookmark the current point in the session"},
+		tgbotapi.BotCommand{Command: "bookmarks", Description: "List this topic's bookmarks"},
+		tgbotapi.BotCommand{Command: "buttons", Description: "Quick-action buttons for this topic"},
+		tgbotapi.BotCommand{Command: "fork", Description: "Branch this conversation into a new topic"},
+		tgbotapi.BotCommand{Command: "preamble", Description: "Standing instructions prepended to this topic's prompts"},
+		tgbotapi.BotCommand{Command: "broadcast", Description: "Send one instruction to every session (owner only)"},
+		tgbotapi.BotCommand{Command: "prompts", Description: "Prompt file usage; gc deletes leftovers (owner only)"},
+		tgbotapi.BotCommand{Command: "setup", Description: "Create a topic for every unbound Claude window (owner only)"},
+		tgbotapi.BotCommand{Command: "pause", Description: "Pause all forwarding for maintenance (owner only)"},
+		tgbotapi.BotCommand{Command: "resume_bridge", Description: "Resume forwarding after /pause (owner only)"},
+		tgbotapi.BotCommand{Command: "access", Description: "List or revoke users granted access"},
+		tgbotapi.BotCommand{Command: "status", Description: "Show this topic's session and agent identity"},
+		tgbotapi.BotCommand{Command: "observe", Description: "Make this topic read-only (output only)"},
+		tgbotapi.BotCommand{Command: "sound", Description: "Notify on all output, or only Claude's notifications"},
+		tgbotapi.BotCommand{Command: "quiet", Description: "Set quiet hours, e.g. /quiet 23:00-08:00"},
+		tgbotapi.BotCommand{Command: "lite", Description: "Save bandwidth: text screenshots, one-line tool results"},
+		tgbotapi.BotCommand{Command: "confirm", Description: "Preview each prompt and press Send before it is typed"},
+		tgbotapi.BotCommand{Command: "paging", Description: "Page long output in one message instead of several"},
+		tgbotapi.BotCommand{Command: "thinking", Description: "Show Claude's thinking: short, full or hide"},
+		tgbotapi.BotCommand{Command: "tune", Description: "Tune message merging, splitting and previews here"},
+		tgbotapi.BotCommand{Command: "labels", Description: "Mark messages as answer, tool, status or prompt"},
+		tgbotapi.BotCommand{Command: "find", Description: "Search the terminal's scrollback for text"},
+		tgbotapi.BotCommand{Command: "cleanup", Description: "Delete the bot's status, tool or old messages here"},
+		tgbotapi.BotCommand{Command: "context", Description: "What is in Claude's context: size, prompts, files read"},
+		tgbotapi.BotCommand{Command: "budget", Description: "Cap this project's estimated Claude spend per day or week"},
+		tgbotapi.BotCommand{Command: "why", Description: "Reply to a bot message to see where it came from"},
+		tgbotapi.BotCommand{Command: "attribution", Description: "Prefix prompts with the sender's name"},
+		tgbotapi.BotCommand{Command: "timezone", Description: "Set your timezone for message timestamps"},
 	)
 	if _, err := b.api.Request(commands); err != nil {
 		log.Printf("Warning: failed to register bot commands: %v", err)
@@ -122,7 +228,7 @@ func (b *Bot) Run(ctx context.Context) error {
 	for {
 		select {
 		case <-ctx.Done():
-			b.saveState()
+			b.flushState()
 			log.Println("Bot shutting down.")
 			return nil
 		default:
@@ -131,6 +237,7 @@ func (b *Bot) Run(ctx context.Context) error {
 		updates, err := b.getUpdatesRaw(offset, 30)
 		if err != nil {
 			log.Printf("Error getting updates: %v", err)
+			time.Sleep(time.Second) // don't spin while the API is unreachable
 			continue
 		}
 
@@ -150,6 +257,10 @@ func (b *Bot) Run(ctx context.Context) error {
 
 // handleUpdate routes an update to the appropriate handler.
 func (b *Bot) handleUpdate(update tgbotapi.Update) {
+	// The tmux commands typing a prompt nest under this span
+	ctx, span := telemetry.Start(context.Background(), "telegram.update", updateAttributes(update)...)
+	defer span.End()
+
 	if update.Message != nil {
 		log.Printf("DEBUG: received message from user=%d chat=%d text=%q",
 			update.Message.From.ID, update.Message.Chat.ID, update.Message.Text)
@@ -157,24 +268,62 @@ func (b *Bot) handleUpdate(update tgbotapi.Update) {
 			log.Printf("DEBUG: unauthorized user=%d chat=%d (ALLOWED_USERS=%v, ALLOWED_GROUPS=%v)",
 				update.Message.From.ID, update.Message.Chat.ID,
 				b.config.AllowedUsers, b.config.AllowedGroups)
+			if b.config.AccessRequests {
+				b.offerAccessRequest(update.Message)
+			}
 			return
 		}
-		b.handleMessage(update.Message)
+		b.handleMessage(ctx, update.Message)
 	} else if update.CallbackQuery != nil {
 		log.Printf("DEBUG: callback from user=%d chat=%d data=%q",
 			update.CallbackQuery.From.ID, update.CallbackQuery.Message.Chat.ID, update.CallbackQuery.Data)
 		if !b.isAuthorized(update.CallbackQuery.From.ID, update.CallbackQuery.Message.Chat.ID) {
+			if b.config.AccessRequests && update.CallbackQuery.Data == "access_req" {
+				b.processAccessRequest(update.CallbackQuery)
+				return
+			}
 			log.Printf("DEBUG: unauthorized callback user=%d chat=%d",
 				update.CallbackQuery.From.ID, update.CallbackQuery.Message.Chat.ID)
 			return
 		}
-		b.handleCallback(update.CallbackQuery)
+		b.handleCallback(ctx, update.CallbackQuery)
 	}
 }
 
-// isAuthorized checks if a user/chat is allowed.
+// updateAttributes describes an update for its trace span.
+func updateAttributes(update tgbotapi.Update) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{attribute.Int("telegram.update_id", update.UpdateID)}
+	switch {
+	case update.Message != nil:
+		msg := update.Message
+		attrs = append(attrs,
+			attribute.String("telegram.update_kind", "message"),
+			attribute.Int64("telegram.chat_id", msg.Chat.ID),
+			attribute.Int("telegram.thread_id", getThreadID(msg)),
+		)
+		if msg.From != nil {
+			attrs = append(attrs, attribute.Int64("telegram.user_id", msg.From.ID))
+		}
+		if cmd := msg.Command(); cmd != "" {
+			attrs = append(attrs, attribute.String("telegram.command", cmd))
+		}
+	case update.CallbackQuery != nil:
+		cq := update.CallbackQuery
+		attrs = append(attrs,
+			attribute.String("telegram.update_kind", "callback"),
+			attribute.Int64("telegram.user_id", cq.From.ID),
+		)
+		if cq.Message != nil {
+			attrs = append(attrs, attribute.Int64("telegram.chat_id", cq.Message.Chat.ID))
+		}
+	}
+	return attrs
+}
+
+// isAuthorized checks if a user/chat is allowed. Users are allowed by
+// ALLOWED_USERS or an approved access request.
 func (b *Bot) isAuthorized(userID, chatID int64) bool {
-	if !b.config.IsAllowedUser(userID) {
+	if !b.config.IsAllowedUser(userID) && !b.state.HasAccess(strconv.FormatInt(userID, 10)) {
 		return false
 	}
 	if chatID < 0 && !b.config.IsAllowedGroup(chatID) {
@@ -183,14 +332,27 @@ func (b *Bot) isAuthorized(userID, chatID int64) bool {
 	return true
 }
 
-// handleMessage routes messages to the appropriate handler.
-func (b *Bot) handleMessage(msg *tgbotapi.Message) {
+// handleMessage routes messages to the appropriate handler. ctx carries the
+// update's trace span.
+func (b *Bot) handleMessage(ctx context.Context, msg *tgbotapi.Message) {
 	// Check for forum topic closed events
 	if isForumTopicClosed(msg) {
 		b.handleTopicClose(msg)
 		return
 	}
 
+	// A group upgraded to a supergroup continues under a new chat ID
+	if msg.MigrateToChatID != 0 {
+		b.handleChatMigration(msg.Chat.ID, msg.MigrateToChatID)
+		return
+	}
+
+	b.recordGroupUsername(msg.Chat)
+
+	if b.holdIfPaused(msg) {
+		return
+	}
+
 	// Handle commands
 	if msg.IsCommand() {
 		b.handleCommand(msg)
@@ -199,21 +361,48 @@ func (b *Bot) handleMessage(msg *tgbotapi.Message) {
 
 	// Handle text messages
 	if msg.Text != "" {
-		b.handleTextMessage(msg)
+		b.handleTextMessage(ctx, msg)
 		return
 	}
 }
 
 // handleCallback routes callback queries.
-func (b *Bot) handleCallback(cq *tgbotapi.CallbackQuery) {
-	b.routeCallback(cq)
+func (b *Bot) handleCallback(ctx context.Context, cq *tgbotapi.CallbackQuery) {
+	// Buttons can't be held like messages; most of them act on tmux
+	if b.state.IsPaused() {
+		b.answerCallback(cq.ID, "⏸ The bridge is paused for maintenance.")
+		return
+	}
+	b.routeCallback(ctx, cq)
 }
 
-// saveState persists the current state to disk.
+// saveDebounce is how long saveState waits for further changes before
+// writing state.json.
+const saveDebounce = 2 * time.Second
+
+// saveState schedules a write of the current state to disk, coalescing a
+// burst of changes into one write.
 func (b *Bot) saveState() {
+	b.saveMu.Lock()
+	defer b.saveMu.Unlock()
+	if b.saveTimer == nil {
+		b.saveTimer = time.AfterFunc(saveDebounce, b.flushState)
+	}
+}
+
+// flushState writes the current state to disk now, replacing any pending
+// debounced write.
+func (b *Bot) flushState() {
+	b.saveMu.Lock()
+	defer b.saveMu.Unlock()
+	if b.saveTimer != nil {
+		b.saveTimer.Stop()
+		b.saveTimer = nil
+	}
 	path := filepath.Join(b.config.TramuntanaDir, "state.json")
 	if err := b.state.Save(path); err != nil {
 		log.Printf("Error saving state: %v", err)
+		b.alertStateSave(err)
 	}
 }
 
@@ -239,9 +428,28 @@ func (b *Bot) Config() *config.Config {
 	return b.config
 }
 
-// SetQueue sets the message queue reference for flood control checks.
+// SendText types text into a window the way a topic message would be,
+// refused the same way while paused, observed or over budget.
+func (b *Bot) SendText(windowID, text string) error {
+	if err := b.refuseExternalSend(windowID); err != nil {
+		return err
+	}
+	return b.sendUserText(context.Background(), windowID, text)
+}
+
+// SetQueue sets the message queue reference for flood control checks,
+// pinning of delivered messages and file reference buttons.
 func (b *Bot) SetQueue(q *queue.Queue) {
 	b.msgQueue = q
+	q.SetPinHandler(b.handleQueuedPin)
+	q.SetKeyboardFunc(b.fileRefKeyboard)
+	q.SetSilentFunc(b.silentDelivery)
+	q.SetPagerFunc(b.pagedDelivery)
+	q.SetLimitsFunc(b.tuneLimits)
+	q.SetFloodBanHandler(b.alertFloodBan)
+	q.SetThreadGoneHandler(func(chatID int64, threadID int) {
+		go b.handleTopicGone(chatID, threadID)
+	})
 }
 
 // answerCallback answers an inline callback query with a toast message.
@@ -1,9 +1,12 @@
 package bot
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/otaviocarvalho/tramuntana/internal/config"
+	"github.com/otaviocarvalho/tramuntana/internal/state"
 )
 
 func TestIsAuthorized(t *testing.T) {
@@ -12,7 +15,9 @@ func TestIsAuthorized(t *testing.T) {
 			AllowedUsers:  []int64{100, 200},
 			AllowedGroups: []int64{-100123},
 		},
+		state: state.NewState(),
 	}
+	b.state.GrantAccess("300", state.AccessGrant{Name: "granted"})
 
 	tests := []struct {
 		name   string
@@ -25,6 +30,8 @@ func TestIsAuthorized(t *testing.T) {
 		{"allowed user, disallowed group", 100, -100999, false},
 		{"disallowed user", 999, 999, false},
 		{"allowed user 2", 200, 200, true},
+		{"granted user, allowed group", 300, -100123, true},
+		{"granted user, disallowed group", 300, -100999, false},
 	}
 
 	for _, tt := range tests {
@@ -43,9 +50,35 @@ func TestIsAuthorized_NoGroupRestriction(t *testing.T) {
 			AllowedUsers:  []int64{100},
 			AllowedGroups: nil, // empty = allow all
 		},
+		state: state.NewState(),
 	}
 
 	if !b.isAuthorized(100, -100999) {
 		t.Error("empty AllowedGroups should allow all groups")
 	}
 }
+
+func TestSaveState_Debounced(t *testing.T) {
+	b := newTestBot(t)
+	b.config.TramuntanaDir = t.TempDir()
+	path := filepath.Join(b.config.TramuntanaDir, "state.json")
+
+	b.state.SetWindowDisplayName("@1", "api")
+	b.saveState()
+	b.saveState()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatal("saveState wrote immediately")
+	}
+
+	b.flushState()
+	loaded, err := state.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name, _ := loaded.GetWindowDisplayName("@1"); name != "api" {
+		t.Errorf("saved display name = %q", name)
+	}
+	if b.saveTimer != nil {
+		t.Error("flushState left the debounced write pending")
+	}
+}
@@ -0,0 +1,173 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/tmux"
+)
+
+const broadcastUsage = "Usage: /broadcast [--project <name>] <text>\nSends the text to every bound session (or the project's) after you confirm."
+
+// pendingBroadcast is a /broadcast awaiting confirmation.
+type pendingBroadcast struct {
+	Text      string
+	Windows   []broadcastTarget
+	ChatID    int64
+	MessageID int
+}
+
+// broadcastTarget is a window a broadcast goes to.
+type broadcastTarget struct {
+	WindowID string
+	Name     string
+}
+
+// handleBroadcastCommand handles /broadcast: lists the windows the text would
+// go to and asks for confirmation. Owners only.
+func (b *Bot) handleBroadcastCommand(msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+	threadID := getThreadID(msg)
+	if !b.isOwner(msg.From.ID) {
+		b.reply(chatID, threadID, "Only the bot owner can broadcast.")
+		return
+	}
+
+	project, text := parseBroadcastArgs(msg.CommandArguments())
+	if text == "" {
+		b.reply(chatID, threadID, broadcastUsage)
+		return
+	}
+	targets := b.broadcastTargets(project)
+	if len(targets) == 0 {
+		if project != "" {
+			b.reply(chatID, threadID, fmt.Sprintf("No bound sessions in project %s.", project))
+		} else {
+			b.reply(chatID, threadID, "No bound sessions.")
+		}
+		return
+	}
+
+	var names []string
+	for _, t := range targets {
+		names = append(names, "• "+t.Name)
+	}
+	prompt := fmt.Sprintf("📢 Send to %d session(s)?\n\n%s\n\n%s", len(targets), strings.Join(names, "\n"), text)
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("Send to %d", len(targets)), "bc_send"),
+		tgbotapi.NewInlineKeyboardButtonData("Cancel", "bc_cancel"),
+	))
+	sent, err := b.sendMessageWithKeyboard(chatID, threadID, prompt, keyboard)
+	if err != nil {
+		log.Printf("Error sending broadcast confirmation: %v", err)
+		return
+	}
+
+	b.mu.Lock()
+	b.broadcasts[msg.From.ID] = &pendingBroadcast{Text: text, Windows: targets, ChatID: chatID, MessageID: sent.MessageID}
+	b.mu.Unlock()
+}
+
+// parseBroadcastArgs splits an optional leading "--project <name>" off the text.
+func parseBroadcastArgs(args string) (project, text string) {
+	args = strings.TrimSpace(args)
+	rest, ok := strings.CutPrefix(args, "--project")
+	if !ok || (rest != "" && rest[0] != ' ' && rest[0] != '\t') {
+		return "", args
+	}
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return "", ""
+	}
+	project = fields[0]
+	text = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(rest), project))
+	return project, text
+}
+
+// broadcastTargets returns the bound windows, or those with a topic bound to
+// project, sorted by name. Windows whose topics are all observe-only are left out.
+func (b *Bot) broadcastTargets(project string) []broadcastTarget {
+	var targets []broadcastTarget
+	for windowID := range b.state.AllBoundWindowIDs() {
+		users := b.state.FindUsersForWindow(windowID)
+		if project != "" && b.windowProject(users) != project {
+			continue
+		}
+		writable := false
+		for _, ut := range users {
+			if !b.state.IsObserved(ut.ThreadID) {
+				writable = true
+				break
+			}
+		}
+		if !writable {
+			continue
+		}
+		name, _ := b.state.GetWindowDisplayName(windowID)
+		if name == "" {
+			name = windowID
+		}
+		targets = append(targets, broadcastTarget{WindowID: windowID, Name: name})
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		if targets[i].Name != targets[j].Name {
+			return targets[i].Name < targets[j].Name
+		}
+		return targets[i].WindowID < targets[j].WindowID
+	})
+	return targets
+}
+
+// processBroadcastCallback sends or cancels the user's pending broadcast and
+// edits the confirmation into a per-window report.
+func (b *Bot) processBroadcastCallback(cq *tgbotapi.CallbackQuery) {
+	if cq.Message == nil {
+		return
+	}
+	if !b.isOwner(cq.From.ID) {
+		return
+	}
+	chatID := cq.Message.Chat.ID
+	messageID := cq.Message.MessageID
+
+	b.mu.Lock()
+	pb, ok := b.broadcasts[cq.From.ID]
+	if ok && pb.ChatID == chatID && pb.MessageID == messageID {
+		delete(b.broadcasts, cq.From.ID)
+	} else {
+		ok = false
+	}
+	b.mu.Unlock()
+	if !ok {
+		b.editMessageText(chatID, messageID, "This broadcast expired. Send /broadcast again.")
+		return
+	}
+	if cq.Data == "bc_cancel" {
+		b.editMessageText(chatID, messageID, "Broadcast cancelled.")
+		return
+	}
+
+	b.editMessageText(chatID, messageID, fmt.Sprintf("📢 Sending to %d session(s)…", len(pb.Windows)))
+	lines := []string{"📢 Broadcast: " + pb.Text, ""}
+	failed := 0
+	for _, t := range pb.Windows {
+		err := b.sendUserText(context.Background(), t.WindowID, pb.Text)
+		switch {
+		case err == nil:
+			lines = append(lines, "✅ "+t.Name)
+			continue
+		case tmux.IsWindowDead(err):
+			lines = append(lines, "❌ "+t.Name+": window is gone")
+		default:
+			log.Printf("Error broadcasting to %s: %v", t.WindowID, err)
+			lines = append(lines, fmt.Sprintf("❌ %s: %v", t.Name, err))
+		}
+		failed++
+	}
+	lines = append(lines, "", fmt.Sprintf("Sent to %d of %d.", len(pb.Windows)-failed, len(pb.Windows)))
+	b.editMessageText(chatID, messageID, strings.Join(lines, "\n"))
+}
@@ -0,0 +1,99 @@
+package bot
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+	"github.com/otaviocarvalho/tramuntana/internal/testharness"
+)
+
+func TestParseBroadcastArgs(t *testing.T) {
+	tests := []struct {
+		args, project, text string
+	}{
+		{"wrap up and commit", "", "wrap up and commit"},
+		{"--project api wrap up", "api", "wrap up"},
+		{"--project api", "api", ""},
+		{"--project", "", ""},
+		{"--projectile launch", "", "--projectile launch"},
+	}
+	for _, tt := range tests {
+		project, text := parseBroadcastArgs(tt.args)
+		if project != tt.project || text != tt.text {
+			t.Errorf("parseBroadcastArgs(%q) = %q, %q; want %q, %q", tt.args, project, text, tt.project, tt.text)
+		}
+	}
+}
+
+// bindBroadcastTopic binds a new window to its own topic in the e2e chat.
+func bindBroadcastTopic(h *e2e, name string, threadID int, project string) string {
+	windowID := h.tmux.AddWindow(name, "/work/"+name)
+	userID, thread := strconv.FormatInt(e2eUser, 10), strconv.Itoa(threadID)
+	h.bot.state.BindThread(userID, thread, windowID)
+	h.bot.state.SetGroupChatID(userID, thread, e2eChat)
+	h.bot.state.SetWindowDisplayName(windowID, name)
+	if project != "" {
+		h.bot.state.BindProject(thread, project)
+	}
+	return windowID
+}
+
+func TestE2E_BroadcastConfirmsAndReports(t *testing.T) {
+	h := startE2E(t, "fresh")
+	api := bindBroadcastTopic(h, "api", 42, "backend")
+	web := bindBroadcastTopic(h, "web", 43, "frontend")
+	gone := bindBroadcastTopic(h, "worker", 44, "backend")
+	h.tmux.Kill(gone)
+
+	const text = "wrap up and commit your work"
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "/broadcast "+text)
+	confirm := h.tg.WaitFor("sendMessage", func(c testharness.Call) bool {
+		return strings.Contains(c.Params["reply_markup"], "bc_send")
+	})
+	if !strings.Contains(confirm.Params["text"], "Send to 3 session(s)?\n\n• api\n• web\n• worker") {
+		t.Errorf("confirmation = %q", confirm.Params["text"])
+	}
+	h.tg.PushCallback(e2eChat, e2eThread, e2eUser, confirm.MessageID, "bc_send")
+	report := h.tg.WaitForText("editMessageText", "Sent to 2 of 3.")
+
+	for _, id := range []string{api, web} {
+		if w, _ := h.tmux.Window(id); !slices.Contains(w.Keys, text) {
+			t.Errorf("window %s typed %q", id, w.Keys)
+		}
+	}
+	if !strings.Contains(report.Params["text"], "❌ worker: window is gone") {
+		t.Errorf("report = %q", report.Params["text"])
+	}
+}
+
+func TestE2E_BroadcastProjectFilterAndCancel(t *testing.T) {
+	h := startE2E(t, "fresh")
+	api := bindBroadcastTopic(h, "api", 42, "backend")
+	bindBroadcastTopic(h, "web", 43, "frontend")
+
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "/broadcast --project backend stop")
+	confirm := h.tg.WaitFor("sendMessage", func(c testharness.Call) bool {
+		return strings.Contains(c.Params["reply_markup"], "bc_cancel")
+	})
+	if !strings.Contains(confirm.Params["text"], "Send to 1 session(s)?\n\n• api\n") {
+		t.Errorf("confirmation = %q", confirm.Params["text"])
+	}
+	h.tg.PushCallback(e2eChat, e2eThread, e2eUser, confirm.MessageID, "bc_cancel")
+	h.tg.WaitForText("editMessageText", "Broadcast cancelled.")
+	if w, _ := h.tmux.Window(api); slices.Contains(w.Keys, "stop") {
+		t.Error("cancelled broadcast was sent")
+	}
+}
+
+func TestE2E_BroadcastOwnerOnly(t *testing.T) {
+	h := startE2E(t, "fresh")
+	bindBroadcastTopic(h, "api", 42, "")
+	h.bot.state.GrantAccess("200", state.AccessGrant{GrantedAt: time.Now()})
+
+	h.tg.PushMessage(e2eChat, e2eThread, 200, "/broadcast hello")
+	h.tg.WaitForText("sendMessage", "Only the bot owner can broadcast.")
+}
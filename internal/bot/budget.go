@@ -0,0 +1,297 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+	"github.com/otaviocarvalho/tramuntana/internal/tmux"
+)
+
+const budgetUsage = "Usage: /budget [set <amount>[/day|/week] [pause] | off]\nE.g. /budget set 5.00/day pause"
+
+// handleBudgetCommand handles /budget: shows, sets or removes the spend cap
+// of the project this topic's session counts toward. Setting and removing
+// are for owners.
+func (b *Bot) handleBudgetCommand(msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+	threadID := getThreadID(msg)
+	windowID, bound := b.resolveWindow(msg)
+	if !bound {
+		b.reply(chatID, threadID, "Topic not bound to a session.")
+		return
+	}
+	project := b.budgetProject(windowID)
+	if project == "" {
+		b.reply(chatID, threadID, "This session has no project or directory to budget.")
+		return
+	}
+	label := projectLabel(project)
+
+	args := strings.Fields(strings.ToLower(msg.CommandArguments()))
+	if len(args) == 0 {
+		b.reply(chatID, threadID, b.formatBudget(project, time.Now()))
+		return
+	}
+	if args[0] != "set" && args[0] != "off" {
+		b.reply(chatID, threadID, budgetUsage)
+		return
+	}
+	if !b.isOwner(msg.From.ID) {
+		b.reply(chatID, threadID, "Only the bot owner can change budgets.")
+		return
+	}
+
+	if args[0] == "off" {
+		if !b.state.RemoveBudget(project) {
+			b.reply(chatID, threadID, fmt.Sprintf("%s has no budget.", label))
+			return
+		}
+		b.saveState()
+		b.reply(chatID, threadID, fmt.Sprintf("Budget for %s removed.", label))
+		return
+	}
+
+	bud, err := parseBudget(args[1:])
+	if err != nil {
+		b.reply(chatID, threadID, fmt.Sprintf("Invalid budget: %v.\n%s", err, budgetUsage))
+		return
+	}
+	b.state.SetBudget(project, bud)
+	b.saveState()
+	b.reply(chatID, threadID, "Budget set.\n"+b.formatBudget(project, time.Now()))
+}
+
+// parseBudget parses "<amount>[/day|/week] [pause]", e.g. "5.00/day".
+func parseBudget(args []string) (state.Budget, error) {
+	if len(args) == 0 || len(args) > 2 || (len(args) == 2 && args[1] != "pause") {
+		return state.Budget{}, errors.New("expected an amount and, optionally, pause")
+	}
+	amount, period, _ := strings.Cut(strings.TrimPrefix(args[0], "$"), "/")
+	limit, err := strconv.ParseFloat(amount, 64)
+	if err != nil || limit <= 0 {
+		return state.Budget{}, fmt.Errorf("invalid amount %q", amount)
+	}
+	switch period {
+	case "":
+		period = "day"
+	case "day", "week":
+	default:
+		return state.Budget{}, fmt.Errorf("invalid period %q, use day or week", period)
+	}
+	return state.Budget{Limit: limit, Period: period, Pause: len(args) == 2}, nil
+}
+
+// formatBudget describes a project's budget and its spend so far.
+func (b *Bot) formatBudget(project string, now time.Time) string {
+	label := projectLabel(project)
+	bud, ok := b.state.GetBudget(project)
+	if !ok {
+		return fmt.Sprintf("%s has no budget.\n%s", label, budgetUsage)
+	}
+	key, spent := b.budgetSpend(project, bud, now)
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "💰 Budget for %s: $%.2f/%s", label, bud.Limit, bud.Period)
+	if bud.Pause {
+		sb.WriteString(", interrupting /auto and /batch when crossed")
+	}
+	fmt.Fprintf(&sb, "\nSpent %s: ~$%.2f (estimated at API list prices)", periodLabel(bud.Period), spent)
+	if bud.OverriddenFor == key {
+		fmt.Fprintf(&sb, "\nOver budget, continued by an owner for %s.", periodLabel(bud.Period))
+	}
+	return sb.String()
+}
+
+// budgetPeriod returns the key of the budget period containing now and its
+// days so far: today, or the week since Monday.
+func budgetPeriod(period string, now time.Time) (key string, days []string) {
+	if period != "week" {
+		return state.Day(now), []string{state.Day(now)}
+	}
+	start := now.AddDate(0, 0, -(int(now.Weekday())+6)%7)
+	for d := start; !d.After(now); d = d.AddDate(0, 0, 1) {
+		days = append(days, state.Day(d))
+	}
+	return "week of " + state.Day(start), days
+}
+
+// periodLabel names the current budget period in messages.
+func periodLabel(period string) string {
+	if period == "week" {
+		return "this week"
+	}
+	return "today"
+}
+
+// budgetProject returns the project a window's spend counts toward: its
+// topic's Minuano project, or else its working directory.
+func (b *Bot) budgetProject(windowID string) string {
+	if p := b.windowProject(b.state.FindUsersForWindow(windowID)); p != "" {
+		return p
+	}
+	ws, _ := b.state.GetWindowState(windowID)
+	return ws.CWD
+}
+
+// projectLabel is how a budget project is shown: a Minuano project's name,
+// or a shortened directory.
+func projectLabel(project string) string {
+	if strings.HasPrefix(project, "/") {
+		return shortenPath(project)
+	}
+	return project
+}
+
+// projectWindows returns the bound windows whose spend counts toward project.
+func (b *Bot) projectWindows(project string) []string {
+	var windows []string
+	for windowID := range b.state.AllBoundWindowIDs() {
+		if b.budgetProject(windowID) == project {
+			windows = append(windows, windowID)
+		}
+	}
+	sort.Strings(windows)
+	return windows
+}
+
+// budgetSpend returns the current period's key and the project's estimated
+// spend in it, including windows since closed.
+func (b *Bot) budgetSpend(project string, bud state.Budget, now time.Time) (string, float64) {
+	key, days := budgetPeriod(bud.Period, now)
+	return key, b.usage.ProjectCost(days, project)
+}
+
+// overBudget reports whether the window's project has spent its budget for
+// the period without an owner choosing to continue.
+func (b *Bot) overBudget(windowID string, now time.Time) (project string, bud state.Budget, spent float64, over bool) {
+	if !b.state.HasBudgets() {
+		return "", state.Budget{}, 0, false
+	}
+	project = b.budgetProject(windowID)
+	bud, ok := b.state.GetBudget(project)
+	if !ok {
+		return project, bud, 0, false
+	}
+	key, spent := b.budgetSpend(project, bud, now)
+	return project, bud, spent, spent >= bud.Limit && bud.OverriddenFor != key
+}
+
+// budgetKeyboard is the override button on budget notices.
+func budgetKeyboard(windowID string) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Continue anyway", "budget_ok:"+windowID),
+	))
+}
+
+// rejectIfOverBudget replies with the budget notice and returns true if the
+// window's project is over its budget.
+func (b *Bot) rejectIfOverBudget(msg *tgbotapi.Message, windowID string) bool {
+	project, bud, spent, over := b.overBudget(windowID, time.Now())
+	if !over {
+		return false
+	}
+	text := fmt.Sprintf("💸 %s is over budget: ~$%.2f of $%.2f %s. Nothing is sent to Claude until an owner presses Continue anyway.",
+		projectLabel(project), spent, bud.Limit, periodLabel(bud.Period))
+	if _, err := b.sendMessageWithKeyboard(msg.Chat.ID, getThreadID(msg), text, budgetKeyboard(windowID)); err != nil {
+		log.Printf("Error sending budget notice: %v", err)
+	}
+	return true
+}
+
+// checkBudget notifies the topics of a window's project the first time in a
+// period its estimated spend crosses the budget, interrupting its /auto and
+// /batch runs if the budget says so.
+func (b *Bot) checkBudget(windowID string, now time.Time) {
+	project, bud, spent, over := b.overBudget(windowID, now)
+	key, _ := budgetPeriod(bud.Period, now)
+	if !over || bud.AlertedFor == key {
+		return
+	}
+	bud.AlertedFor = key
+	b.state.SetBudget(project, bud)
+	b.saveState()
+
+	windows := b.projectWindows(project)
+	var interrupted []string
+	if bud.Pause {
+		for _, w := range windows {
+			_, auto := stopAutoLoop(w)
+			batchRunsMu.Lock()
+			_, batch := batchRuns[w]
+			batchRunsMu.Unlock()
+			if !auto && !batch {
+				continue
+			}
+			if err := tmux.SendSpecialKey(b.config.TmuxSessionName, w, "Escape"); err != nil {
+				log.Printf("Error interrupting %s over budget: %v", w, err)
+				continue
+			}
+			name, _ := b.state.GetWindowDisplayName(w)
+			if name == "" {
+				name = w
+			}
+			interrupted = append(interrupted, name)
+		}
+	}
+
+	text := fmt.Sprintf("💸 Budget exceeded for %s: ~$%.2f of $%.2f %s.", projectLabel(project), spent, bud.Limit, periodLabel(bud.Period))
+	if len(interrupted) > 0 {
+		text += "\nInterrupted: " + strings.Join(interrupted, ", ")
+	}
+	text += "\nMessages to its sessions are held until an owner presses Continue anyway."
+	log.Printf("Budget exceeded for %s: $%.2f of $%.2f", project, spent, bud.Limit)
+
+	type topic struct {
+		chatID   int64
+		threadID int
+	}
+	sent := make(map[topic]bool)
+	for _, w := range windows {
+		for _, ut := range b.state.FindUsersForWindow(w) {
+			chatID, ok := b.state.GetGroupChatID(ut.UserID, ut.ThreadID)
+			if !ok {
+				continue
+			}
+			threadID, _ := strconv.Atoi(ut.ThreadID)
+			if t := (topic{chatID, threadID}); !sent[t] {
+				sent[t] = true
+				if _, err := b.sendMessageWithKeyboard(chatID, threadID, text, budgetKeyboard(w)); err != nil {
+					log.Printf("Error sending budget notice: %v", err)
+				}
+			}
+		}
+	}
+}
+
+// processBudgetCallback handles the Continue anyway button: an owner lets
+// the project go over its budget for the rest of the period.
+func (b *Bot) processBudgetCallback(cq *tgbotapi.CallbackQuery) {
+	windowID, ok := strings.CutPrefix(cq.Data, "budget_ok:")
+	if !ok || cq.Message == nil {
+		return
+	}
+	if !b.isOwner(cq.From.ID) {
+		b.answerCallback(cq.ID, "Only the bot owner can override a budget")
+		return
+	}
+	project := b.budgetProject(windowID)
+	bud, ok := b.state.GetBudget(project)
+	if !ok {
+		b.editMessageText(cq.Message.Chat.ID, cq.Message.MessageID, cq.Message.Text+"\n\nThe budget has been removed.")
+		return
+	}
+	bud.OverriddenFor, _ = budgetPeriod(bud.Period, time.Now())
+	b.state.SetBudget(project, bud)
+	b.saveState()
+
+	text := fmt.Sprintf("%s\n\n▶️ Continued by %s: the budget is not enforced for the rest of %s.", cq.Message.Text, senderName(cq.From), periodLabel(bud.Period))
+	if err := b.editMessageText(cq.Message.Chat.ID, cq.Message.MessageID, text); err != nil {
+		log.Printf("Error editing budget notice: %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+package bot
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+)
+
+func TestParseBudget(t *testing.T) {
+	tests := []struct {
+		args []string
+		want state.Budget
+		ok   bool
+	}{
+		{[]string{"5.00/day"}, state.Budget{Limit: 5, Period: "day"}, true},
+		{[]string{"$20/week", "pause"}, state.Budget{Limit: 20, Period: "week", Pause: true}, true},
+		{[]string{"3"}, state.Budget{Limit: 3, Period: "day"}, true},
+		{[]string{"0/day"}, state.Budget{}, false},
+		{[]string{"5/month"}, state.Budget{}, false},
+		{[]string{"5", "now"}, state.Budget{}, false},
+		{nil, state.Budget{}, false},
+	}
+	for _, tt := range tests {
+		got, err := parseBudget(tt.args)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("parseBudget(%q) = %+v, %v", tt.args, got, err)
+		}
+	}
+}
+
+func TestBudgetPeriod(t *testing.T) {
+	thursday := time.Date(2026, 10, 15, 18, 0, 0, 0, time.Local)
+	key, days := budgetPeriod("day", thursday)
+	if key != "2026-10-15" || !reflect.DeepEqual(days, []string{"2026-10-15"}) {
+		t.Errorf("day period = %q, %v", key, days)
+	}
+	key, days = budgetPeriod("week", thursday)
+	if key != "week of 2026-10-12" || !reflect.DeepEqual(days, []string{"2026-10-12", "2026-10-13", "2026-10-14", "2026-10-15"}) {
+		t.Errorf("week period = %q, %v", key, days)
+	}
+}
+
+func TestE2E_BudgetHoldsPromptsUntilOverride(t *testing.T) {
+	h := startE2E(t, "fresh")
+	usage := state.NewUsage()
+	h.bot.SetUsage(usage)
+	windowID := h.tmux.AddWindow("api", "/work/api")
+	userID, threadID := strconv.FormatInt(e2eUser, 10), strconv.Itoa(e2eThread)
+	h.bot.state.BindThread(userID, threadID, windowID)
+	h.bot.state.SetGroupChatID(userID, threadID, e2eChat)
+	h.bot.state.SetWindowState(windowID, state.WindowState{CWD: "/work/api"})
+
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "/budget set 2.50/day")
+	h.tg.WaitForText("sendMessage", "Budget for /work/api: $2.50/day")
+
+	usage.RecordCost(windowID, 3)
+	h.bot.HandleMonitorActivity(windowID, nil)
+	notice := h.tg.WaitForText("sendMessage", "Budget exceeded for /work/api: ~$3.00 of $2.50 today")
+	if !strings.Contains(notice.Params["reply_markup"], "budget_ok:"+windowID) {
+		t.Errorf("budget notice has no override button: %v", notice.Params)
+	}
+	h.bot.HandleMonitorActivity(windowID, nil)
+
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "keep going")
+	h.tg.WaitForText("sendMessage", "/work/api is over budget")
+	if n := len(h.tg.Calls("sendMessage")); n != 3 {
+		t.Errorf("sent %d messages, want 3 (one crossing notice)", n)
+	}
+
+	h.tg.PushCallback(e2eChat, e2eThread, e2eUser, notice.MessageID, "budget_ok:"+windowID)
+	h.tg.WaitForText("editMessageText", "Continued by")
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "keep going")
+	h.tmux.WaitForKeys(windowID, "keep going")
+	w, _ := h.tmux.Window(windowID)
+	if n := strings.Count(strings.Join(w.Keys, "\n"), "keep going"); n != 1 {
+		t.Errorf("prompt typed %d times, want once after the override", n)
+	}
+}
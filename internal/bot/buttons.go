@@ -0,0 +1,151 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+)
+
+const (
+	// maxQuickActions caps the buttons on one topic's keyboard.
+	maxQuickActions = 20
+	// maxQuickActionLabel caps a button label, in characters.
+	maxQuickActionLabel = 32
+	// pinButtons is the pin kind of the latest /buttons keyboard.
+	pinButtons = "buttons"
+)
+
+const buttonsUsage = "Usage:\n" +
+	"/buttons — show this topic's keyboard\n" +
+	"/buttons add <label> = <action> — add or replace a button\n" +
+	"/buttons remove <label>\n" +
+	"/buttons clear\n\n" +
+	"An action runs as if you sent it: a command (/c_get), a bash command (!make test) or a prompt (Show me the diff)."
+
+// handleButtonsCommand manages and shows the topic's quick-action keyboard.
+func (b *Bot) handleButtonsCommand(msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+	threadID := getThreadID(msg)
+	threadIDStr := strconv.Itoa(threadID)
+
+	sub, rest, _ := strings.Cut(strings.TrimSpace(msg.CommandArguments()), " ")
+	rest = strings.TrimSpace(rest)
+	switch sub {
+	case "":
+		b.postQuickActions(chatID, threadID)
+	case "add":
+		label, action, ok := strings.Cut(rest, "=")
+		label, action = strings.TrimSpace(label), strings.TrimSpace(action)
+		if !ok || label == "" || action == "" {
+			b.reply(chatID, threadID, buttonsUsage)
+			return
+		}
+		if len([]rune(label)) > maxQuickActionLabel {
+			b.reply(chatID, threadID, fmt.Sprintf("Labels are limited to %d characters.", maxQuickActionLabel))
+			return
+		}
+		existing := b.state.GetQuickActions(threadIDStr)
+		if len(existing) >= maxQuickActions && quickActionIndex(existing, label) < 0 {
+			b.reply(chatID, threadID, fmt.Sprintf("This topic already has %d buttons; remove one first.", maxQuickActions))
+			return
+		}
+		b.state.SetQuickAction(threadIDStr, state.QuickAction{Label: label, Action: action})
+		b.saveState()
+		b.postQuickActions(chatID, threadID)
+	case "remove":
+		if rest == "" || !b.state.RemoveQuickAction(threadIDStr, rest) {
+			b.reply(chatID, threadID, fmt.Sprintf("No button labelled %q.", rest))
+			return
+		}
+		b.saveState()
+		b.postQuickActions(chatID, threadID)
+	case "clear":
+		b.state.RemoveQuickAction(threadIDStr, "")
+		b.saveState()
+		b.reply(chatID, threadID, "Removed this topic's buttons.")
+	default:
+		b.reply(chatID, threadID, buttonsUsage)
+	}
+}
+
+// postQuickActions sends the topic's keyboard and pins it, superseding the
+// previous one.
+func (b *Bot) postQuickActions(chatID int64, threadID int) {
+	actions := b.state.GetQuickActions(strconv.Itoa(threadID))
+	if len(actions) == 0 {
+		b.reply(chatID, threadID, "No buttons in this topic yet.\n\n"+buttonsUsage)
+		return
+	}
+	sent, err := b.sendMessageWithKeyboard(chatID, threadID, "⚡ Quick actions", buildQuickActionsKeyboard(actions))
+	if err != nil {
+		log.Printf("Error sending quick actions: %v", err)
+		return
+	}
+	b.pinMessage(chatID, threadID, sent.MessageID, pinButtons)
+}
+
+// buildQuickActionsKeyboard lays the buttons out two per row. Callback data
+// carries the index and a hash of the label, so a press on an outdated
+// keyboard is caught rather than running whatever now sits at that index.
+func buildQuickActionsKeyboard(actions []state.QuickAction) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for i := 0; i < len(actions); i += 2 {
+		var row []tgbotapi.InlineKeyboardButton
+		for j := i; j < min(i+2, len(actions)); j++ {
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData(actions[j].Label, quickActionData(j, actions[j].Label)))
+		}
+		rows = append(rows, row)
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// quickActionData is the callback data of button idx: "qa_<idx>_<label hash>".
+func quickActionData(idx int, label string) string {
+	h := fnv.New32a()
+	h.Write([]byte(label))
+	return fmt.Sprintf("qa_%d_%08x", idx, h.Sum32())
+}
+
+// quickActionIndex returns the index of the button with label, or -1.
+func quickActionIndex(actions []state.QuickAction, label string) int {
+	for i, qa := range actions {
+		if qa.Label == label {
+			return i
+		}
+	}
+	return -1
+}
+
+// processQuickActionCallback runs a pressed button's action as a message from
+// the user who pressed it.
+func (b *Bot) processQuickActionCallback(cq *tgbotapi.CallbackQuery) {
+	chatID := cq.Message.Chat.ID
+	threadID := getThreadID(cq.Message)
+	actions := b.state.GetQuickActions(strconv.Itoa(threadID))
+
+	idx, err := strconv.Atoi(strings.Split(strings.TrimPrefix(cq.Data, "qa_"), "_")[0])
+	if err != nil || idx < 0 || idx >= len(actions) || quickActionData(idx, actions[idx].Label) != cq.Data {
+		b.reply(chatID, threadID, "These buttons are out of date. Send /buttons for the current ones.")
+		return
+	}
+
+	msg := syntheticMessage(cq)
+	msg.ReplyToMessage = nil
+	msg.ReplyMarkup = nil
+	msg.Text = actions[idx].Action
+	msg.Entities = nil
+	if strings.HasPrefix(msg.Text, "/") {
+		cmdLen := len(msg.Text)
+		if i := strings.IndexAny(msg.Text, " \n"); i >= 0 {
+			cmdLen = i
+		}
+		msg.Entities = []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: cmdLen}}
+	}
+	b.handleMessage(context.Background(), msg)
+}
@@ -0,0 +1,58 @@
+package bot
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+	"github.com/otaviocarvalho/tramuntana/internal/testharness"
+)
+
+func TestBuildQuickActionsKeyboard(t *testing.T) {
+	actions := []state.QuickAction{
+		{Label: "Test", Action: "!make test"},
+		{Label: "Diff", Action: "Show the diff"},
+		{Label: "Get", Action: "/c_get"},
+	}
+	kb := buildQuickActionsKeyboard(actions)
+	if len(kb.InlineKeyboard) != 2 || len(kb.InlineKeyboard[0]) != 2 || len(kb.InlineKeyboard[1]) != 1 {
+		t.Fatalf("layout = %+v, want rows of 2", kb.InlineKeyboard)
+	}
+	if data := *kb.InlineKeyboard[1][0].CallbackData; data != quickActionData(2, "Get") {
+		t.Errorf("data = %q", data)
+	}
+	if quickActionData(0, "Test") == quickActionData(0, "Diff") {
+		t.Error("data should depend on the label")
+	}
+}
+
+func TestE2E_QuickActionButtons(t *testing.T) {
+	h := startE2E(t, "fresh")
+	windowID := h.tmux.AddWindow("api", "/work/api")
+	userID, threadID := strconv.FormatInt(e2eUser, 10), strconv.Itoa(e2eThread)
+	h.bot.state.BindThread(userID, threadID, windowID)
+	h.bot.state.SetGroupChatID(userID, threadID, e2eChat)
+
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "/buttons add Review = /cc review")
+	h.tg.WaitFor("sendMessage", func(c testharness.Call) bool {
+		return strings.Contains(c.Params["reply_markup"], quickActionData(0, "Review"))
+	})
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "/buttons add Diff = Show me the diff")
+	kb := h.tg.WaitFor("sendMessage", func(c testharness.Call) bool {
+		return strings.Contains(c.Params["reply_markup"], quickActionData(1, "Diff"))
+	})
+
+	h.tg.PushCallback(e2eChat, e2eThread, e2eUser, kb.MessageID, quickActionData(0, "Review"))
+	h.tmux.WaitForKeys(windowID, "/review")
+	h.tg.PushCallback(e2eChat, e2eThread, e2eUser, kb.MessageID, quickActionData(1, "Diff"))
+	h.tmux.WaitForKeys(windowID, "Show me the diff")
+
+	// After removing Review, Diff moves to index 0 and the old keyboard is stale
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "/buttons remove Review")
+	h.tg.WaitFor("sendMessage", func(c testharness.Call) bool {
+		return strings.Contains(c.Params["reply_markup"], quickActionData(0, "Diff"))
+	})
+	h.tg.PushCallback(e2eChat, e2eThread, e2eUser, kb.MessageID, quickActionData(1, "Diff"))
+	h.tg.WaitForText("sendMessage", "out of date")
+}
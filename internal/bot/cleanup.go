@@ -0,0 +1,97 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/queue"
+)
+
+// cleanupOldAge is how old a message must be for /cleanup old.
+const cleanupOldAge = 24 * time.Hour
+
+const cleanupUsage = "Usage: /cleanup <n> | status | tools | old"
+
+// cleanupFilter picks the messages a /cleanup argument deletes from a topic's
+// delivered messages, oldest first. Messages the queue still edits are never
+// picked.
+func cleanupFilter(arg string, msgs []queue.SentMessage, now time.Time) ([]int, bool) {
+	var match func(m queue.SentMessage) bool
+	switch arg {
+	case "status":
+		match = func(m queue.SentMessage) bool { return m.Kind == "status_update" }
+	case "tools":
+		match = func(m queue.SentMessage) bool { return strings.HasPrefix(m.Kind, "tool_") }
+	case "old":
+		match = func(m queue.SentMessage) bool { return now.Sub(m.At) >= cleanupOldAge }
+	default:
+		n, err := strconv.Atoi(arg)
+		if err != nil || n <= 0 {
+			return nil, false
+		}
+		var ids []int
+		for i := len(msgs) - 1; i >= 0 && len(ids) < n; i-- {
+			if !msgs[i].Live {
+				ids = append(ids, msgs[i].MessageID)
+			}
+		}
+		return ids, true
+	}
+	var ids []int
+	for _, m := range msgs {
+		if !m.Live && match(m) {
+			ids = append(ids, m.MessageID)
+		}
+	}
+	return ids, true
+}
+
+// handleCleanupCommand handles /cleanup: deletes the bot's own messages in
+// this topic — the last n, old status messages, tool calls and results, or
+// everything older than a day. Without an argument it counts them.
+func (b *Bot) handleCleanupCommand(msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+	threadID := getThreadID(msg)
+	if b.msgQueue == nil {
+		return
+	}
+	msgs := b.msgQueue.SentMessages(chatID, threadID)
+	arg := strings.ToLower(strings.TrimSpace(msg.CommandArguments()))
+	now := time.Now()
+
+	if arg == "" {
+		status, _ := cleanupFilter("status", msgs, now)
+		tools, _ := cleanupFilter("tools", msgs, now)
+		old, _ := cleanupFilter("old", msgs, now)
+		b.reply(chatID, threadID, fmt.Sprintf("%d bot messages recorded in this topic since the bot started: %d status, %d tool, %d older than a day.\n%s",
+			len(msgs), len(status), len(tools), len(old), cleanupUsage))
+		return
+	}
+	ids, ok := cleanupFilter(arg, msgs, now)
+	if !ok {
+		b.reply(chatID, threadID, cleanupUsage)
+		return
+	}
+	if len(ids) == 0 {
+		b.reply(chatID, threadID, "Nothing to clean up.")
+		return
+	}
+
+	sent, err := b.sendMessageInThread(chatID, threadID, fmt.Sprintf("🧹 Deleting %d message(s)…", len(ids)))
+	if err != nil {
+		log.Printf("Error sending cleanup progress: %v", err)
+		return
+	}
+	go func() {
+		refused := b.msgQueue.DeleteMessages(chatID, ids)
+		text := fmt.Sprintf("🧹 Cleaned up %d message(s); any already gone were skipped.", len(ids))
+		if refused > 0 {
+			text = fmt.Sprintf("🧹 Telegram refused to delete %d of %d message(s) (bots can't delete messages older than 48 hours); the rest are gone.", refused, len(ids))
+		}
+		b.editMessageText(chatID, sent.MessageID, text)
+	}()
+}
@@ -0,0 +1,69 @@
+package bot
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/otaviocarvalho/tramuntana/internal/queue"
+)
+
+func TestCleanupFilter(t *testing.T) {
+	now := time.Now()
+	msgs := []queue.SentMessage{
+		{MessageID: 1, Kind: "status_update", At: now.Add(-30 * time.Hour)},
+		{MessageID: 2, Kind: "tool_use", At: now.Add(-25 * time.Hour)},
+		{MessageID: 3, Kind: "content", At: now.Add(-time.Hour)},
+		{MessageID: 4, Kind: "tool_result", At: now.Add(-time.Minute)},
+		{MessageID: 5, Kind: "tool_use", At: now, Live: true},
+		{MessageID: 6, Kind: "status_update", At: now, Live: true},
+	}
+	tests := []struct {
+		arg  string
+		want []int
+		ok   bool
+	}{
+		{"status", []int{1}, true},
+		{"tools", []int{2, 4}, true},
+		{"old", []int{1, 2}, true},
+		{"2", []int{4, 3}, true},
+		{"10", []int{4, 3, 2, 1}, true},
+		{"0", nil, false},
+		{"everything", nil, false},
+	}
+	for _, tt := range tests {
+		got, ok := cleanupFilter(tt.arg, msgs, now)
+		if ok != tt.ok || !slices.Equal(got, tt.want) {
+			t.Errorf("cleanupFilter(%q) = %v, %v; want %v, %v", tt.arg, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestE2E_CleanupTools(t *testing.T) {
+	h := startE2E(t, "fresh")
+
+	task := queue.MessageTask{UserID: e2eUser, ChatID: e2eChat, ThreadID: e2eThread, WindowID: "@1"}
+	for i, kind := range []string{"tool_use", "content", "tool_result"} {
+		task.ContentType, task.Parts = kind, []string{fmt.Sprintf("message %d", i)}
+		h.q.Enqueue(task)
+	}
+	h.tg.WaitForText("sendMessage", "message 2")
+	for !h.q.Idle() {
+		time.Sleep(10 * time.Millisecond)
+	}
+	sent := h.q.SentMessages(e2eChat, e2eThread)
+
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "/cleanup")
+	h.tg.WaitForText("sendMessage", "3 bot messages recorded in this topic since the bot started: 0 status, 2 tool")
+
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "/cleanup tools")
+	h.tg.WaitForText("editMessageText", "Cleaned up 2 message(s)")
+	calls := h.tg.Calls("deleteMessages")
+	if want := fmt.Sprintf("[%d,%d]", sent[0].MessageID, sent[2].MessageID); len(calls) != 1 || calls[0].Params["message_ids"] != want {
+		t.Errorf("deleteMessages calls = %v, want one for %s", calls, want)
+	}
+	if left := h.q.SentMessages(e2eChat, e2eThread); len(left) != 1 || left[0].Kind != "content" {
+		t.Errorf("left recorded = %+v, want the content message", left)
+	}
+}
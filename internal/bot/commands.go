@@ -9,6 +9,7 @@ import (
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/otaviocarvalho/tramuntana/internal/git"
+	"github.com/otaviocarvalho/tramuntana/internal/queue"
 	"github.com/otaviocarvalho/tramuntana/internal/state"
 	"github.com/otaviocarvalho/tramuntana/internal/tmux"
 )
@@ -19,6 +20,8 @@ func (b *Bot) handleCommand(msg *tgbotapi.Message) {
 	b.clearPendingInput(msg.From.ID)
 
 	switch msg.Command() {
+	case "start":
+		b.handleStart(msg)
 	case "menu":
 		b.handleMenuCommand(msg)
 	case "c_clear":
@@ -31,6 +34,8 @@ func (b *Bot) handleCommand(msg *tgbotapi.Message) {
 		b.forwardCommand(msg, "help")
 	case "c_memory":
 		b.forwardCommand(msg, "memory")
+	case "cc":
+		b.handleCCCommand(msg)
 	case "esc", "c_esc":
 		b.handleEsc(msg)
 	case "c_screenshot":
@@ -41,6 +46,8 @@ func (b *Bot) handleCommand(msg *tgbotapi.Message) {
 		b.handleProject(msg)
 	case "p_tasks":
 		b.handleTasks(msg)
+	case "p_projects", "projects":
+		b.handleProjectsCommand(msg)
 	case "t_pick":
 		b.handlePick(msg)
 	case "t_auto":
@@ -57,14 +64,98 @@ func (b *Bot) handleCommand(msg *tgbotapi.Message) {
 		b.handleMergeCommand(msg)
 	case "p_delete":
 		b.handleDeleteCommand(msg)
+	case "p_retitle", "retitle":
+		b.handleTaskEditCommand(msg, "retitle")
+	case "p_priority", "priority":
+		b.handleTaskEditCommand(msg, "priority")
+	case "p_note", "note":
+		b.handleTaskEditCommand(msg, "note")
+	case "p_drop", "drop":
+		b.handleTaskEditCommand(msg, "drop")
 	case "t_unclaim":
 		b.handleUnclaimCommand(msg)
 	case "t_plan":
 		b.handlePlanCommand(msg)
 	case "plan":
 		b.handlePlannerCommand(msg)
+	case "compose":
+		b.handleComposeCommand(msg)
+	case "send":
+		b.handleSendCommand(msg)
+	case "discard":
+		b.handleDiscardCommand(msg)
+	case "allow":
+		b.handleAllowCommand(msg)
+	case "skills":
+		b.handleSkillsCommand(msg)
+	case "worktrees":
+		b.handleWorktreesCommand(msg)
+	case "bookmark":
+		b.handleBookmarkCommand(msg)
+	case "bookmarks":
+		b.handleBookmarksCommand(msg)
+	case "buttons":
+		b.handleButtonsCommand(msg)
+	case "fork":
+		b.handleForkCommand(msg)
+	case "broadcast":
+		b.handleBroadcastCommand(msg)
+	case "prompts":
+		b.handlePromptsCommand(msg)
+	case "preamble":
+		b.handlePreambleCommand(msg)
+	case "setup":
+		b.handleSetupCommand(msg)
+	case "pause":
+		b.handlePauseCommand(msg)
+	case "resume_bridge":
+		b.handleResumeBridgeCommand(msg)
+	case "timezone":
+		b.handleTimezoneCommand(msg)
+	case "observe":
+		b.handleObserveCommand(msg)
+	case "find":
+		b.handleFindCommand(msg)
+	case "thinking":
+		b.handleThinkingCommand(msg)
+	case "tune":
+		b.handleTuneCommand(msg)
+	case "labels":
+		b.handleLabelsCommand(msg)
+	case "lite":
+		b.handleLiteCommand(msg)
+	case "confirm":
+		b.handleConfirmCommand(msg)
+	case "paging":
+		b.handlePagingCommand(msg)
+	case "quiet":
+		b.handleQuietCommand(msg)
+	case "sound":
+		b.handleSoundCommand(msg)
+	case "attribution":
+		b.handleAttributionCommand(msg)
+	case "status":
+		b.handleStatusCommand(msg)
+	case "access":
+		b.handleAccessCommand(msg)
+	case "cleanup":
+		b.handleCleanupCommand(msg)
+	case "context":
+		b.handleContextCommand(msg)
+	case "budget":
+		b.handleBudgetCommand(msg)
+	case "why":
+		b.handleWhyCommand(msg)
+	case "rewind":
+		b.handleRewindCommand(msg)
+	case "raw":
+		b.handleRawCommand(msg)
+	case "enter":
+		b.handleEnterCommand(msg)
 	default:
-		b.reply(msg.Chat.ID, getThreadID(msg), "Unknown command: /"+msg.Command())
+		if !b.handleRawMessage(msg) && !b.handleCustomCommand(msg) {
+			b.reply(msg.Chat.ID, getThreadID(msg), "Unknown command: /"+msg.Command())
+		}
 	}
 }
 
@@ -76,15 +167,23 @@ func (b *Bot) resolveWindow(msg *tgbotapi.Message) (string, bool) {
 }
 
 // forwardCommand sends a command as text to the bound tmux window.
-// claudeCmd is the Claude-side command name (e.g. "clear", not "c_clear").
+// claudeCmd is the Claude-side command name (e.g. "clear", not "c_clear"),
+// optionally followed by arguments.
 func (b *Bot) forwardCommand(msg *tgbotapi.Message, claudeCmd string) {
+	b.sendToClaude(msg, "/"+claudeCmd)
+}
+
+// sendToClaude types text into the bound tmux window and presses Enter.
+func (b *Bot) sendToClaude(msg *tgbotapi.Message, cmdText string) {
 	windowID, bound := b.resolveWindow(msg)
 	if !bound {
 		b.reply(msg.Chat.ID, getThreadID(msg), "Topic not bound to a session. Send a message to bind.")
 		return
 	}
+	if b.rejectIfObserved(msg) {
+		return
+	}
 
-	cmdText := "/" + claudeCmd
 	if err := tmux.SendKeysWithDelay(b.config.TmuxSessionName, windowID, cmdText, 500); err != nil {
 		if tmux.IsWindowDead(err) {
 			b.handleDeadWindow(msg, windowID, "")
@@ -96,7 +195,7 @@ func (b *Bot) forwardCommand(msg *tgbotapi.Message, claudeCmd string) {
 	}
 
 	// Special handling for /clear: reset session monitoring state
-	if claudeCmd == "clear" {
+	if cmdText == "/clear" {
 		b.resetSessionTracking(windowID)
 	}
 }
@@ -127,6 +226,9 @@ func (b *Bot) handleEsc(msg *tgbotapi.Message) {
 		b.reply(msg.Chat.ID, getThreadID(msg), "Topic not bound to a session.")
 		return
 	}
+	if b.rejectIfObserved(msg) {
+		return
+	}
 
 	if err := tmux.SendSpecialKey(b.config.TmuxSessionName, windowID, "Escape"); err != nil {
 		if tmux.IsWindowDead(err) {
@@ -179,12 +281,12 @@ func (b *Bot) handleGet(msg *tgbotapi.Message) {
 	threadID := getThreadID(msg)
 	userID := msg.From.ID
 
-	// Try to start from the bound session's CWD
+	// Try to start from the bound session's current directory
 	startPath := ""
 	windowID, bound := b.resolveWindow(msg)
 	if bound {
-		if ws, ok := b.state.GetWindowState(windowID); ok && ws.CWD != "" {
-			startPath = ws.CWD
+		if ws, ok := b.state.GetWindowState(windowID); ok {
+			startPath = ws.Dir()
 		}
 	}
 
@@ -193,6 +295,7 @@ func (b *Bot) handleGet(msg *tgbotapi.Message) {
 		home, _ := os.UserHomeDir()
 		startPath = home
 	}
+	startPath = startDir(startPath, b.allowedRoots())
 
 	b.showFileBrowser(chatID, threadID, userID, startPath)
 }
@@ -203,21 +306,36 @@ func (b *Bot) handleAdd(msg *tgbotapi.Message) {
 }
 
 // handleTopicClose handles forum topic close events.
-// It kills the tmux window and cleans up all related state.
 func (b *Bot) handleTopicClose(msg *tgbotapi.Message) {
-	threadID := getThreadID(msg)
+	b.closeTopic(msg.Chat.ID, getThreadID(msg))
+}
+
+// closeTopic kills a topic's tmux window and cleans up all related state.
+// Bindings of the same thread ID in another chat are left alone. It reports
+// whether there was anything to clean up.
+func (b *Bot) closeTopic(chatID int64, threadID int) bool {
 	threadIDStr := strconv.Itoa(threadID)
 
 	// Find all users bound to this thread and clean up each binding
 	cleaned := false
+	archived := make(map[string]bool)
 	for _, userID := range b.state.AllUserIDs() {
 		windowID, bound := b.state.GetWindowForThread(userID, threadIDStr)
 		if !bound {
 			continue
 		}
+		if id, ok := b.state.GetGroupChatID(userID, threadIDStr); ok && id != chatID {
+			continue
+		}
 
 		cleaned = true
 
+		// Archive the transcript before the session map forgets it
+		if !archived[windowID] {
+			archived[windowID] = true
+			go b.archiveSession(b.captureArchive(windowID, "closed", []string{threadIDStr}))
+		}
+
 		// Kill tmux window (ignore errors — may already be dead)
 		tmux.KillWindow(b.config.TmuxSessionName, windowID)
 
@@ -263,6 +381,51 @@ func (b *Bot) handleTopicClose(msg *tgbotapi.Message) {
 		b.saveState()
 		log.Printf("Topic %d closed: cleaned up bindings and killed window", threadID)
 	}
+	return cleaned
+}
+
+// handleTopicGone cleans up after a topic Telegram says no longer exists: the
+// close cleanup plus the topic's settings, bookmarks and pins, which a closed
+// topic keeps in case it is reopened. Telegram sends no update when a topic is
+// deleted; a send failing with "message thread not found" is how it shows.
+// Thread IDs are only unique within a chat, so nothing is touched unless the
+// topic is bound in chatID.
+func (b *Bot) handleTopicGone(chatID int64, threadID int) {
+	threadIDStr := strconv.Itoa(threadID)
+	inChat := false
+	for _, userID := range b.state.AllUserIDs() {
+		if id, ok := b.state.GetGroupChatID(userID, threadIDStr); ok && id == chatID {
+			inChat = true
+			break
+		}
+	}
+	if !inChat {
+		return
+	}
+	b.closeTopic(chatID, threadID)
+	b.state.RemoveThreadSettings(threadIDStr)
+	b.saveState()
+	log.Printf("Topic %d in chat %d is gone: dropped its settings", threadID, chatID)
+}
+
+// checkThreadGone starts the deleted-topic cleanup when a send into threadID
+// failed because the topic no longer exists.
+func (b *Bot) checkThreadGone(chatID int64, threadID int, err error) {
+	if threadID != 0 && queue.IsThreadNotFound(err) {
+		go b.handleTopicGone(chatID, threadID)
+	}
+}
+
+// handleChatMigration moves everything stored for a group to the supergroup it
+// was upgraded to. The new ID replaces the old one in ALLOWED_GROUPS for this
+// run; the operator is asked to update the setting.
+func (b *Bot) handleChatMigration(from, to int64) {
+	n := b.state.MigrateChatID(from, to)
+	b.saveState()
+	if b.config.ReplaceAllowedGroup(from, to) {
+		log.Printf("Group %d migrated to %d: update ALLOWED_GROUPS to keep it allowed after restart", from, to)
+	}
+	log.Printf("Group %d migrated to %d: rewrote %d stored chat references", from, to, n)
 }
 
 // SetMonitorState sets the monitor state reference (called by serve command).
@@ -270,6 +433,12 @@ func (b *Bot) SetMonitorState(ms *state.MonitorState) {
 	b.monitorState = ms
 }
 
+// SetUsage sets the usage tracker reference (called by serve command).
+func (b *Bot) SetUsage(u *state.Usage) {
+	b.usage = u
+	u.SetProjectFunc(b.budgetProject)
+}
+
 // loadSessionMapForReset loads session_map.json for the /clear reset logic.
 func loadSessionMapForReset(path string) (map[string]state.SessionMapEntry, error) {
 	return state.LoadSessionMap(path)
@@ -180,6 +180,26 @@ func TestTopicClose_UnboundIsNoop(t *testing.T) {
 	}
 }
 
+func TestHandleChatMigration(t *testing.T) {
+	b := newTestBot(t)
+	b.config.TramuntanaDir = t.TempDir()
+	b.config.AllowedGroups = []int64{-100, -200}
+	b.state.BindThread("100", "0", "@5")
+	b.state.SetGroupChatID("100", "0", -100)
+
+	b.handleChatMigration(-100, -1001)
+
+	if id, _ := b.state.GetGroupChatID("100", "0"); id != -1001 {
+		t.Errorf("chat ID = %d, want -1001", id)
+	}
+	if !b.isAuthorized(100, -1001) || b.isAuthorized(100, -100) {
+		t.Errorf("allowed groups = %v, want the old group replaced", b.config.AllowedGroups)
+	}
+	if _, ok := b.state.GetWindowForThread("100", "0"); !ok {
+		t.Error("binding should survive the migration")
+	}
+}
+
 func TestAllUserIDs(t *testing.T) {
 	s := state.NewState()
 	s.BindThread("100", "1", "@1")
@@ -0,0 +1,201 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// composePreviewChars is how much of the draft's tail the preview message shows.
+const composePreviewChars = 3000
+
+// composeDraft is a prompt being assembled from several messages.
+type composeDraft struct {
+	ChatID    int64
+	ThreadID  int
+	MessageID int // preview message
+	Parts     []string
+}
+
+// text joins the draft's messages into one prompt.
+func (d *composeDraft) text() string {
+	return strings.Join(d.Parts, "\n\n")
+}
+
+// handleComposeCommand starts buffering this topic's messages into a draft.
+func (b *Bot) handleComposeCommand(msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+	threadID := getThreadID(msg)
+
+	if _, bound := b.resolveWindow(msg); !bound {
+		b.reply(chatID, threadID, "Topic not bound to a session. Send a message to bind.")
+		return
+	}
+
+	draft := &composeDraft{ChatID: chatID, ThreadID: threadID}
+	sent, err := b.sendMessageWithKeyboard(chatID, threadID, formatComposePreview(draft), composeKeyboard())
+	if err != nil {
+		log.Printf("Error sending compose preview: %v", err)
+		return
+	}
+	draft.MessageID = sent.MessageID
+
+	b.mu.Lock()
+	if b.composeDrafts == nil {
+		b.composeDrafts = make(map[wizardKey]*composeDraft)
+	}
+	b.composeDrafts[messageWizardKey(msg)] = draft
+	b.mu.Unlock()
+}
+
+// handleComposeInput appends a message to the sender's draft in this topic.
+// Returns true if the message was consumed (caller must NOT forward to tmux).
+func (b *Bot) handleComposeInput(msg *tgbotapi.Message) bool {
+	b.mu.Lock()
+	draft, ok := b.composeDrafts[messageWizardKey(msg)]
+	if !ok {
+		b.mu.Unlock()
+		return false
+	}
+	draft.Parts = append(draft.Parts, msg.Text)
+	text := formatComposePreview(draft)
+	b.mu.Unlock()
+
+	if err := b.editMessageWithKeyboard(draft.ChatID, draft.MessageID, text, composeKeyboard()); err != nil {
+		log.Printf("Error updating compose preview: %v", err)
+	}
+	return true
+}
+
+// handleSendCommand flushes the sender's draft to Claude.
+func (b *Bot) handleSendCommand(msg *tgbotapi.Message) {
+	b.sendDraft(msg.From, msg.Chat.ID, getThreadID(msg))
+}
+
+// handleDiscardCommand drops the sender's draft.
+func (b *Bot) handleDiscardCommand(msg *tgbotapi.Message) {
+	b.discardDraft(msg.From.ID, msg.Chat.ID, getThreadID(msg))
+}
+
+// processComposeCallback handles the Send and Discard buttons on a preview.
+func (b *Bot) processComposeCallback(cq *tgbotapi.CallbackQuery) {
+	if cq.Message == nil {
+		return
+	}
+	chatID := cq.Message.Chat.ID
+	threadID := getThreadIDFromCallback(cq)
+	switch cq.Data {
+	case "compose_send":
+		b.sendDraft(cq.From, chatID, threadID)
+	case "compose_discard":
+		b.discardDraft(cq.From.ID, chatID, threadID)
+	}
+}
+
+// takeDraft removes and returns a user's draft for a topic.
+func (b *Bot) takeDraft(k wizardKey) *composeDraft {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	draft, ok := b.composeDrafts[k]
+	if !ok {
+		return nil
+	}
+	delete(b.composeDrafts, k)
+	return draft
+}
+
+// restoreDraft puts back a draft that could not be sent, unless a new one was started.
+func (b *Bot) restoreDraft(k wizardKey, draft *composeDraft) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.composeDrafts[k]; !ok {
+		b.composeDrafts[k] = draft
+	}
+}
+
+// sendDraft types the combined draft into the topic's window. The draft is
+// kept if it cannot be delivered.
+func (b *Bot) sendDraft(from *tgbotapi.User, chatID int64, threadID int) {
+	k := wizardKey{UserID: from.ID, ChatID: chatID, ThreadID: threadID}
+	draft := b.takeDraft(k)
+	if draft == nil {
+		b.reply(chatID, threadID, "No draft to send. Start one with /compose.")
+		return
+	}
+	if len(draft.Parts) == 0 {
+		b.restoreDraft(k, draft)
+		b.reply(chatID, threadID, "Draft is empty. Send messages to add to it, or /discard.")
+		return
+	}
+
+	threadIDStr := strconv.Itoa(threadID)
+	windowID, bound := b.state.GetWindowForThread(strconv.FormatInt(from.ID, 10), threadIDStr)
+	switch {
+	case !bound:
+		b.restoreDraft(k, draft)
+		b.reply(chatID, threadID, "Topic not bound to a session. Send a message to bind.")
+		return
+	case b.state.IsObserved(threadIDStr):
+		b.restoreDraft(k, draft)
+		b.reply(chatID, threadID, observeRejection)
+		return
+	case claudeExited(windowID):
+		b.restoreDraft(k, draft)
+		b.reply(chatID, threadID, "Claude is not running in this window. Use the Restart Claude button first.")
+		return
+	}
+
+	text := draft.text()
+	b.auditPrompt(from, threadID, windowID, text)
+	if b.state.IsAttributed(threadIDStr) {
+		text = attributePrompt(senderName(from), text)
+	}
+	if err := b.sendUserText(context.Background(), windowID, text); err != nil {
+		log.Printf("Error sending draft to %s: %v", windowID, err)
+		b.restoreDraft(k, draft)
+		b.reply(chatID, threadID, "Error: failed to send to Claude session. The draft was kept.")
+		return
+	}
+
+	summary := fmt.Sprintf("📝 Sent draft (%d messages, %d characters).", len(draft.Parts), len([]rune(draft.text())))
+	if err := b.editMessageText(draft.ChatID, draft.MessageID, summary); err != nil {
+		log.Printf("Error updating compose preview: %v", err)
+	}
+}
+
+// discardDraft drops a user's draft for a topic.
+func (b *Bot) discardDraft(userID, chatID int64, threadID int) {
+	draft := b.takeDraft(wizardKey{UserID: userID, ChatID: chatID, ThreadID: threadID})
+	if draft == nil {
+		b.reply(chatID, threadID, "No draft to discard.")
+		return
+	}
+	if err := b.editMessageText(draft.ChatID, draft.MessageID, "📝 Draft discarded."); err != nil {
+		log.Printf("Error updating compose preview: %v", err)
+	}
+}
+
+// formatComposePreview renders a draft, showing the tail of long ones.
+func formatComposePreview(d *composeDraft) string {
+	header := fmt.Sprintf("📝 Composing (%d messages). Send messages to add to the draft, then /send or tap Send. /discard aborts.", len(d.Parts))
+	if len(d.Parts) == 0 {
+		return header + "\n\n(empty)"
+	}
+	body := []rune(d.text())
+	if len(body) > composePreviewChars {
+		body = append([]rune("…"), body[len(body)-composePreviewChars:]...)
+	}
+	return header + "\n\n" + string(body)
+}
+
+// composeKeyboard is the preview message's Send and Discard buttons.
+func composeKeyboard() tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Send", "compose_send"),
+		tgbotapi.NewInlineKeyboardButtonData("Discard", "compose_discard"),
+	))
+}
@@ -0,0 +1,43 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatComposePreview(t *testing.T) {
+	d := &composeDraft{}
+	if got := formatComposePreview(d); !strings.HasSuffix(got, "(empty)") {
+		t.Errorf("empty draft preview = %q", got)
+	}
+
+	d.Parts = []string{"intro", strings.Repeat("x", composePreviewChars) + "END"}
+	got := formatComposePreview(d)
+	if !strings.Contains(got, "(2 messages)") || !strings.HasSuffix(got, "END") || strings.Contains(got, "intro") {
+		t.Errorf("long draft preview should show the tail: %q…", got[:80])
+	}
+	if !strings.Contains(got, "…x") {
+		t.Error("truncated preview should be marked")
+	}
+}
+
+func TestTakeAndRestoreDraft(t *testing.T) {
+	b := newTestBot(t)
+	k := wizardKey{UserID: 100, ChatID: -1001, ThreadID: 7}
+	b.composeDrafts = map[wizardKey]*composeDraft{k: {ChatID: -1001, ThreadID: 7, Parts: []string{"a"}}}
+
+	if d := b.takeDraft(wizardKey{UserID: 100, ChatID: -1001, ThreadID: 8}); d != nil {
+		t.Error("draft from another thread should not be taken")
+	}
+	if d := b.takeDraft(wizardKey{UserID: 100, ChatID: -1002, ThreadID: 7}); d != nil {
+		t.Error("draft from another chat should not be taken")
+	}
+	d := b.takeDraft(k)
+	if d == nil || b.takeDraft(k) != nil {
+		t.Fatal("draft should be taken exactly once")
+	}
+	b.restoreDraft(k, d)
+	if b.composeDrafts[k] != d {
+		t.Error("draft should be restored")
+	}
+}
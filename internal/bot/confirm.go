@@ -0,0 +1,222 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// confirmPreviewChars is how much of a held prompt its preview shows; longer
+// ones are shown as their start and end.
+const confirmPreviewChars = 3500
+
+// heldPrompt is a message on its way to a window: typed at once, or held by
+// /confirm until its sender presses Send.
+type heldPrompt struct {
+	Msg       *tgbotapi.Message // the message it came from; replies and recovery go to its topic
+	WindowID  string
+	Sent      string // what the sender wrote, for the audit log
+	Text      string // exactly what is typed, after attribution and the preamble
+	Bash      bool   // a ! command, run through Claude's bash mode
+	MessageID int    // preview message, 0 until held
+	Editing   bool   // waiting for the sender's replacement text
+
+	preambleSent func() // marks the topic's preamble applied once typed
+}
+
+// handleConfirmCommand sets whether this topic previews prompts before they
+// are typed. Usage: /confirm [on|off]; no argument shows the setting.
+func (b *Bot) handleConfirmCommand(msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+	threadID := getThreadID(msg)
+	threadIDStr := strconv.Itoa(threadID)
+
+	switch strings.ToLower(strings.TrimSpace(msg.CommandArguments())) {
+	case "":
+		if b.state.IsConfirmed(threadIDStr) {
+			b.reply(chatID, threadID, "Confirm is on: each prompt is previewed exactly as it will be typed, with Send, Edit and Cancel buttons. Use /confirm off to type prompts at once.")
+		} else {
+			b.reply(chatID, threadID, "Confirm is off: prompts are typed as soon as they arrive. Use /confirm on to preview each one first.")
+		}
+	case "on":
+		b.state.SetConfirmed(threadIDStr, true)
+		b.saveState()
+		b.reply(chatID, threadID, "Confirm on: each prompt is previewed exactly as it will be typed and waits for Send.")
+	case "off":
+		b.state.SetConfirmed(threadIDStr, false)
+		b.saveState()
+		b.reply(chatID, threadID, "Confirm off: prompts are typed as soon as they arrive.")
+	default:
+		b.reply(chatID, threadID, "Usage: /confirm [on|off]")
+	}
+}
+
+// holdPrompt posts a preview of p with Send, Edit and Cancel buttons and
+// holds it until one is pressed. A prompt the sender already had held in the
+// topic is replaced.
+func (b *Bot) holdPrompt(p *heldPrompt) {
+	chatID, threadID := p.Msg.Chat.ID, getThreadID(p.Msg)
+	k := messageWizardKey(p.Msg)
+	sent, err := b.sendMessageWithKeyboard(chatID, threadID, b.formatHeldPrompt(p), confirmKeyboard())
+	if err != nil {
+		log.Printf("Error sending prompt preview: %v", err)
+		b.reply(chatID, threadID, "Error: could not preview the prompt; nothing was typed.")
+		return
+	}
+
+	b.mu.Lock()
+	old := b.heldPrompts[k]
+	var oldMessageID int
+	if old != nil {
+		oldMessageID = old.MessageID
+	}
+	p.MessageID, p.Editing = sent.MessageID, false
+	b.heldPrompts[k] = p
+	b.mu.Unlock()
+
+	if oldMessageID != 0 {
+		note := "✖️ Replaced by a newer message; nothing was typed."
+		if old == p {
+			note = "✏️ Replaced by the edited text below."
+		}
+		if err := b.editMessageText(chatID, oldMessageID, note); err != nil {
+			log.Printf("Error editing prompt preview: %v", err)
+		}
+	}
+}
+
+// formatHeldPrompt renders a held prompt's preview.
+func (b *Bot) formatHeldPrompt(p *heldPrompt) string {
+	name, _ := b.state.GetWindowDisplayName(p.WindowID)
+	if name == "" {
+		name = p.WindowID
+	}
+	var sb strings.Builder
+	if p.Bash {
+		fmt.Fprintf(&sb, "🔎 About to run in %s's bash mode:", name)
+	} else {
+		fmt.Fprintf(&sb, "🔎 About to type into %s:", name)
+	}
+	runes := []rune(p.Text)
+	fmt.Fprintf(&sb, " %d line(s), %d characters", strings.Count(p.Text, "\n")+1, len(runes))
+	if !p.Bash && len(runes) > b.config.LongPasteChars {
+		switch b.config.LongPasteMode {
+		case "file":
+			sb.WriteString(". Too long to type: it is saved to a file and Claude is asked to read it")
+		case "buffer":
+			sb.WriteString(". Pasted in one go")
+		}
+	}
+	sb.WriteString("\n\n")
+	sb.WriteString(previewPromptText(p.Text))
+	return sb.String()
+}
+
+// previewPromptText returns a held prompt's text, as its start and end if it
+// is longer than confirmPreviewChars, so a preview fits in a message.
+func previewPromptText(text string) string {
+	runes := []rune(text)
+	if len(runes) <= confirmPreviewChars {
+		return text
+	}
+	half := confirmPreviewChars / 2
+	return fmt.Sprintf("%s\n… %d characters not shown …\n%s", string(runes[:half]), len(runes)-2*half, string(runes[len(runes)-half:]))
+}
+
+// confirmKeyboard is a held prompt's Send, Edit and Cancel buttons.
+func confirmKeyboard() tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Send", "cf_send"),
+		tgbotapi.NewInlineKeyboardButtonData("Edit", "cf_edit"),
+		tgbotapi.NewInlineKeyboardButtonData("Cancel", "cf_cancel"),
+	))
+}
+
+// processConfirmCallback handles the buttons on a held prompt's preview.
+// Only its sender can press them. Send checks the topic's project budget
+// again, as it was when the prompt arrived; a prompt over budget stays held.
+func (b *Bot) processConfirmCallback(ctx context.Context, cq *tgbotapi.CallbackQuery) {
+	if cq.Message == nil {
+		return
+	}
+	chatID, messageID := cq.Message.Chat.ID, cq.Message.MessageID
+	k := callbackWizardKey(cq)
+
+	b.mu.Lock()
+	p, ok := b.heldPrompts[k]
+	b.mu.Unlock()
+	if !ok || p.MessageID != messageID {
+		b.answerCallback(cq.ID, "Only the prompt's sender can do that, and only on its latest preview")
+		return
+	}
+	if cq.Data == "cf_send" && b.rejectIfOverBudget(p.Msg, p.WindowID) {
+		return
+	}
+	if cq.Data != "cf_edit" {
+		b.mu.Lock()
+		delete(b.heldPrompts, k)
+		b.mu.Unlock()
+	}
+
+	switch cq.Data {
+	case "cf_send":
+		userID := strconv.FormatInt(cq.From.ID, 10)
+		threadID := strconv.Itoa(getThreadID(p.Msg))
+		if windowID, bound := b.state.GetWindowForThread(userID, threadID); !bound || windowID != p.WindowID {
+			b.editConfirmResult(chatID, messageID, "✖️ The topic's session changed; nothing was typed. Send the prompt again.")
+			return
+		}
+		if !b.typePrompt(ctx, cq.From, p) {
+			b.editConfirmResult(chatID, messageID, "✖️ Not typed:\n\n"+previewPromptText(p.Text))
+			return
+		}
+		b.editConfirmResult(chatID, messageID, "✅ Typed:\n\n"+previewPromptText(p.Text))
+	case "cf_edit":
+		b.mu.Lock()
+		p.Editing = true
+		b.mu.Unlock()
+		text := "✏️ Send the corrected text. It replaces this prompt, and is previewed again before it is typed.\n\n" + previewPromptText(p.Text)
+		cancel := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Cancel", "cf_cancel"),
+		))
+		if err := b.editMessageWithKeyboard(chatID, messageID, text, cancel); err != nil {
+			log.Printf("Error editing prompt preview: %v", err)
+		}
+	case "cf_cancel":
+		b.editConfirmResult(chatID, messageID, "✖️ Cancelled; nothing was typed.")
+	}
+}
+
+// editConfirmResult replaces a held prompt's preview with what became of it.
+func (b *Bot) editConfirmResult(chatID int64, messageID int, text string) {
+	if err := b.editMessageText(chatID, messageID, text); err != nil {
+		log.Printf("Error editing prompt preview: %v", err)
+	}
+}
+
+// handleConfirmEdit takes the sender's message as the replacement text of
+// the prompt they are editing in this topic, prepares it the way a new
+// message would be (attribution and preamble), and previews it again.
+// Returns true if the message was consumed (caller must NOT forward to tmux).
+func (b *Bot) handleConfirmEdit(msg *tgbotapi.Message) bool {
+	b.mu.Lock()
+	p, ok := b.heldPrompts[messageWizardKey(msg)]
+	editing := ok && p.Editing
+	b.mu.Unlock()
+	if !editing {
+		return false
+	}
+
+	edited := b.preparePrompt(msg, p.WindowID)
+	b.mu.Lock()
+	edited.MessageID = p.MessageID
+	*p = *edited
+	b.mu.Unlock()
+
+	b.holdPrompt(p)
+	return true
+}
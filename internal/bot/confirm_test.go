@@ -0,0 +1,79 @@
+package bot
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+	"github.com/otaviocarvalho/tramuntana/internal/testharness"
+)
+
+func TestE2E_ConfirmHoldsPromptUntilSend(t *testing.T) {
+	h := startE2E(t, "fresh")
+	windowID := h.tmux.AddWindow("api", "/work/api")
+	userID, threadID := strconv.FormatInt(e2eUser, 10), strconv.Itoa(e2eThread)
+	h.bot.state.BindThread(userID, threadID, windowID)
+	h.bot.state.SetGroupChatID(userID, threadID, e2eChat)
+	h.bot.state.SetPreamble(threadID, state.Preamble{Text: "be careful"})
+	const otherThread = e2eThread + 1
+	otherWindow := h.tmux.AddWindow("web", "/work/web")
+	h.bot.state.BindThread(userID, strconv.Itoa(otherThread), otherWindow)
+	h.bot.state.SetGroupChatID(userID, strconv.Itoa(otherThread), e2eChat)
+	h.bot.state.SetConfirmed(strconv.Itoa(otherThread), true)
+
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "/confirm on")
+	h.tg.WaitForText("sendMessage", "Confirm on")
+
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "restart the\nworkers")
+	preview := h.tg.WaitForText("sendMessage", "About to type into")
+	if !strings.Contains(preview.Params["text"], "[Topic instructions: be careful] restart the\nworkers") ||
+		!strings.Contains(preview.Params["text"], "2 line(s)") {
+		t.Errorf("preview = %q", preview.Params["text"])
+	}
+	if w, _ := h.tmux.Window(windowID); len(w.Keys) != 0 {
+		t.Fatalf("typed before Send: %q", w.Keys)
+	}
+
+	// Someone else can't send it
+	h.tg.PushCallback(e2eChat, e2eThread, e2eUser+1, preview.MessageID, "cf_send")
+
+	// A prompt held in another topic doesn't replace it
+	h.tg.PushMessage(e2eChat, otherThread, e2eUser, "rebuild the site")
+	other := h.tg.WaitForText("sendMessage", "rebuild the site")
+
+	h.tg.PushCallback(e2eChat, e2eThread, e2eUser, preview.MessageID, "cf_edit")
+	h.tg.WaitForText("editMessageText", "Send the corrected text")
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "restart one worker")
+	edited := h.tg.WaitFor("sendMessage", func(c testharness.Call) bool {
+		return strings.Contains(c.Params["text"], "About to type into") &&
+			strings.HasSuffix(c.Params["text"], "\n\n[Topic instructions: be careful] restart one worker")
+	})
+
+	// The first preview no longer sends anything
+	h.tg.PushCallback(e2eChat, e2eThread, e2eUser, preview.MessageID, "cf_send")
+	h.tg.PushCallback(e2eChat, e2eThread, e2eUser, edited.MessageID, "cf_send")
+	h.tmux.WaitForKeys(windowID, "[Topic instructions: be careful] restart one worker")
+	h.tg.WaitForText("editMessageText", "✅ Typed")
+	w, _ := h.tmux.Window(windowID)
+	if keys := strings.Join(w.Keys, "\n"); strings.Contains(keys, "workers") || strings.Count(keys, "restart one worker") != 1 {
+		t.Errorf("keys = %q", w.Keys)
+	}
+
+	h.tg.PushCallback(e2eChat, otherThread, e2eUser, other.MessageID, "cf_send")
+	h.tmux.WaitForKeys(otherWindow, "rebuild the site")
+}
+
+func TestPreviewPromptText(t *testing.T) {
+	if got := previewPromptText("short"); got != "short" {
+		t.Errorf("short prompt = %q", got)
+	}
+	long := strings.Repeat("é", 3*confirmPreviewChars)
+	got := previewPromptText(long)
+	if n := len([]rune(got)); n > confirmPreviewChars+50 {
+		t.Errorf("preview of a %d-rune prompt is %d runes", 3*confirmPreviewChars, n)
+	}
+	if !strings.Contains(got, "characters not shown") {
+		t.Errorf("preview doesn't say what was left out: %q", got[:100])
+	}
+}
@@ -0,0 +1,217 @@
+package bot
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/monitor"
+)
+
+const (
+	// contextWindowTokens is the context window /context measures against.
+	contextWindowTokens = 200_000
+	// contextFullPercent is the fill above which /context suggests compacting.
+	contextFullPercent = 70
+	// contextMaxTurns and contextMaxFiles cap the prompts and files listed.
+	contextMaxTurns = 5
+	contextMaxFiles = 10
+)
+
+// contextPeek summarizes what is in a session's context: everything since
+// its last compaction.
+type contextPeek struct {
+	Tokens    int64            // input and output of the last assistant message
+	Compacted bool             // the session was compacted; only what follows counts
+	Turns     []string         // prompts, oldest first
+	ToolCalls int              // tool calls made
+	Files     []string         // files read, in order of first read
+	Prompts   int64            // tokens estimated from the prompts' text
+	Replies   int64            // tokens estimated from Claude's text
+	Results   map[string]int64 // tool name → tokens estimated from its results' text
+}
+
+// estimateTokens approximates a text's token count at four bytes a token.
+func estimateTokens(text string) int64 {
+	return int64(len(text)+3) / 4
+}
+
+// peekContext summarizes the context from a session's transcript entries.
+func peekContext(entries []*monitor.Entry) contextPeek {
+	var p contextPeek
+	p.Results = make(map[string]int64)
+	toolNames := make(map[string]string) // tool_use ID → tool name
+	seenFiles := make(map[string]bool)
+
+	for _, e := range entries {
+		switch e.Type {
+		case "compact":
+			p = contextPeek{Compacted: true, Results: make(map[string]int64)}
+			seenFiles = make(map[string]bool)
+			continue
+		case "assistant":
+			u := e.Usage
+			if total := u.InputTokens + u.CacheReadInputTokens + u.CacheCreationInputTokens + u.OutputTokens; total > 0 {
+				p.Tokens = total
+			}
+		}
+		for _, b := range e.Blocks {
+			switch b.Type {
+			case "text":
+				if e.Type == "assistant" {
+					p.Replies += estimateTokens(b.Text)
+					continue
+				}
+				p.Prompts += estimateTokens(b.Text)
+				// Command output and caveats are wrapped in tags; prompts aren't
+				if text := strings.TrimSpace(b.Text); text != "" && !strings.HasPrefix(text, "<") {
+					p.Turns = append(p.Turns, text)
+				}
+			case "tool_use":
+				p.ToolCalls++
+				toolNames[b.ToolUseID] = b.ToolName
+				if b.ToolName == "Read" && b.ToolInput != "" && !seenFiles[b.ToolInput] {
+					seenFiles[b.ToolInput] = true
+					p.Files = append(p.Files, b.ToolInput)
+				}
+			case "tool_result":
+				name := toolNames[b.ToolUseID]
+				if name == "" {
+					name = "other"
+				}
+				p.Results[name] += estimateTokens(b.Content)
+			}
+		}
+	}
+	return p
+}
+
+// formatContextPeek renders a context summary; cwd shortens file paths.
+func formatContextPeek(name, cwd string, p contextPeek) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "🧠 Context — %s\n", name)
+	if p.Tokens > 0 {
+		percent := p.Tokens * 100 / contextWindowTokens
+		fmt.Fprintf(&b, "~%s of %s tokens (%d%%), as of Claude's last reply\n",
+			formatTokenCount(p.Tokens), formatTokenCount(contextWindowTokens), percent)
+	}
+	since := "Since the session started"
+	if p.Compacted {
+		since = "Since the last compaction"
+	}
+	fmt.Fprintf(&b, "%s: %d prompt(s), %d tool call(s)\n", since, len(p.Turns), p.ToolCalls)
+
+	type toolTokens struct {
+		name   string
+		tokens int64
+	}
+	var tools []toolTokens
+	var results int64
+	for name, n := range p.Results {
+		tools = append(tools, toolTokens{name, n})
+		results += n
+	}
+	sort.Slice(tools, func(i, j int) bool {
+		if tools[i].tokens != tools[j].tokens {
+			return tools[i].tokens > tools[j].tokens
+		}
+		return tools[i].name < tools[j].name
+	})
+	fmt.Fprintf(&b, "Estimated from the text: prompts ~%s · replies ~%s · tool results ~%s",
+		formatTokenCount(p.Prompts), formatTokenCount(p.Replies), formatTokenCount(results))
+	if len(tools) > 0 {
+		var parts []string
+		for i, t := range tools {
+			if i == 3 {
+				break
+			}
+			parts = append(parts, fmt.Sprintf("%s %s", t.name, formatTokenCount(t.tokens)))
+		}
+		fmt.Fprintf(&b, " (%s)", strings.Join(parts, ", "))
+	}
+	b.WriteString("\n")
+
+	if len(p.Files) > 0 {
+		fmt.Fprintf(&b, "\nFiles read (%d):\n", len(p.Files))
+		for i, f := range p.Files {
+			if i == contextMaxFiles {
+				fmt.Fprintf(&b, "  … +%d more\n", len(p.Files)-contextMaxFiles)
+				break
+			}
+			if cwd != "" {
+				if rel, err := filepath.Rel(cwd, f); err == nil && !strings.HasPrefix(rel, "..") {
+					f = rel
+				}
+			}
+			fmt.Fprintf(&b, "  %s\n", f)
+		}
+	}
+
+	if len(p.Turns) > 0 {
+		b.WriteString("\nRecent prompts:\n")
+		for _, turn := range p.Turns[max(len(p.Turns)-contextMaxTurns, 0):] {
+			fmt.Fprintf(&b, "  • %s\n", truncateText(turn, 80))
+		}
+	}
+
+	if p.Tokens*100 >= contextWindowTokens*contextFullPercent {
+		fmt.Fprintf(&b, "\n💡 Over %d%% full: /c_compact to summarize it, or /c_clear to start over.", contextFullPercent)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// handleContextCommand handles /context: a compact view of what is in the
+// session's context — its size, prompts and files read since the last
+// compaction — to decide whether to /c_compact or /c_clear before the next
+// big instruction.
+func (b *Bot) handleContextCommand(msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+	threadID := getThreadID(msg)
+	windowID, bound := b.resolveWindow(msg)
+	if !bound {
+		b.reply(chatID, threadID, "No session bound to this topic.")
+		return
+	}
+	jsonlPath := b.findJSONLForWindow(windowID)
+	if jsonlPath == "" {
+		b.reply(chatID, threadID, "No session transcript found.")
+		return
+	}
+	entries, err := readTranscriptEntries(jsonlPath)
+	if err != nil {
+		log.Printf("Error reading transcript %s: %v", jsonlPath, err)
+		b.reply(chatID, threadID, "Error: failed to read the session transcript.")
+		return
+	}
+
+	name, _ := b.state.GetWindowDisplayName(windowID)
+	if name == "" {
+		name = windowID
+	}
+	ws, _ := b.state.GetWindowState(windowID)
+	b.reply(chatID, threadID, formatContextPeek(name, ws.CWD, peekContext(entries)))
+}
+
+// readTranscriptEntries parses every entry in a JSONL transcript.
+func readTranscriptEntries(path string) ([]*monitor.Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []*monitor.Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 256*1024), 16*1024*1024) // pasted images make long lines
+	for scanner.Scan() {
+		if entry, err := monitor.ParseLine(scanner.Bytes()); err == nil && entry != nil {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, scanner.Err()
+}
@@ -0,0 +1,78 @@
+package bot
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/otaviocarvalho/tramuntana/internal/monitor"
+)
+
+// contextTranscript is a session that read two files, was compacted, then
+// read one more.
+var contextTranscript = []string{
+	`{"type":"user","message":{"content":"look at the parser"}}`,
+	`{"type":"assistant","message":{"content":[{"type":"tool_use","id":"t1","name":"Read","input":{"file_path":"/work/api/old.go"}}],"usage":{"input_tokens":900000}}}`,
+	`{"type":"system","subtype":"compact_boundary","compactMetadata":{"trigger":"manual"}}`,
+	`{"type":"user","message":{"content":"now fix the lexer"}}`,
+	`{"type":"assistant","message":{"content":[{"type":"tool_use","id":"t2","name":"Read","input":{"file_path":"/work/api/lexer.go"}},{"type":"tool_use","id":"t3","name":"Read","input":{"file_path":"/work/api/lexer.go"}}],"usage":{"input_tokens":10,"cache_read_input_tokens":150000,"output_tokens":100}}}`,
+	`{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"t2","content":"` + strings.Repeat("x", 400) + `"}]}}`,
+	`{"type":"user","message":{"content":"<local-command-stdout>ok</local-command-stdout>"}}`,
+	`{"type":"assistant","message":{"content":[{"type":"text","text":"Fixed."}],"usage":{"input_tokens":10,"cache_read_input_tokens":160000,"output_tokens":5}}}`,
+}
+
+func parseTranscript(t *testing.T, lines []string) []*monitor.Entry {
+	t.Helper()
+	var entries []*monitor.Entry
+	for _, line := range lines {
+		e, err := monitor.ParseLine([]byte(line))
+		if err != nil {
+			t.Fatalf("ParseLine(%s): %v", line, err)
+		}
+		if e != nil {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+func TestPeekContext(t *testing.T) {
+	p := peekContext(parseTranscript(t, contextTranscript))
+	if !p.Compacted || p.Tokens != 160015 || p.ToolCalls != 2 {
+		t.Errorf("peek = %+v", p)
+	}
+	if len(p.Turns) != 1 || p.Turns[0] != "now fix the lexer" {
+		t.Errorf("turns = %q, want only the prompt since compacting", p.Turns)
+	}
+	if len(p.Files) != 1 || p.Files[0] != "/work/api/lexer.go" {
+		t.Errorf("files = %q", p.Files)
+	}
+	if p.Results["Read"] != 100 {
+		t.Errorf("Read results = %d tokens, want 100", p.Results["Read"])
+	}
+
+	got := formatContextPeek("api", "/work/api", p)
+	for _, want := range []string{"~160.0k of 200.0k tokens (80%)", "Since the last compaction: 1 prompt(s), 2 tool call(s)", "tool results ~100 (Read 100)", "  lexer.go", "  • now fix the lexer", "/c_compact"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("formatContextPeek missing %q in:\n%s", want, got)
+		}
+	}
+}
+
+func TestE2E_ContextPeek(t *testing.T) {
+	h := startE2E(t, "fresh")
+	windowID := h.tmux.AddWindow("api", "/work/api")
+	userID, threadID := strconv.FormatInt(e2eUser, 10), strconv.Itoa(e2eThread)
+	h.bot.state.BindThread(userID, threadID, windowID)
+	h.bot.state.SetGroupChatID(userID, threadID, e2eChat)
+	h.writeSession(t, windowID, "sess-1", "/work/api")
+	h.appendTranscript(t, "sess-1", contextTranscript[:2]...)
+
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "/context")
+	got := h.tg.WaitForText("sendMessage", "🧠 Context")
+	for _, want := range []string{"Since the session started: 1 prompt(s), 1 tool call(s)", "/work/api/old.go", "look at the parser"} {
+		if !strings.Contains(got.Params["text"], want) {
+			t.Errorf("/context reply missing %q:\n%s", want, got.Params["text"])
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package bot
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/otaviocarvalho/tramuntana/internal/control"
+	"github.com/otaviocarvalho/tramuntana/internal/queue"
+	"github.com/otaviocarvalho/tramuntana/internal/render"
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+	"github.com/otaviocarvalho/tramuntana/internal/tmux"
+)
+
+// controlSender names the control API in the audit log.
+const controlSender = "control API"
+
+// ControlBackend returns the bot's operations for the local control API.
+func (b *Bot) ControlBackend() control.Backend {
+	return controlBackend{b}
+}
+
+type controlBackend struct{ b *Bot }
+
+func (c controlBackend) Sessions() ([]control.Session, error) {
+	b := c.b
+	windows, err := tmux.ListWindows(b.config.TmuxSessionName)
+	if err != nil {
+		return nil, err
+	}
+	sessions := make([]control.Session, 0, len(windows))
+	for _, w := range windows {
+		s := control.Session{WindowID: w.ID, Name: w.Name, CWD: w.CWD}
+		if ws, ok := b.state.GetWindowState(w.ID); ok {
+			s.SessionID = ws.SessionID
+		}
+		if name, ok := b.state.GetWindowDisplayName(w.ID); ok {
+			s.Name = name
+		}
+		for _, ut := range b.state.FindUsersForWindow(w.ID) {
+			chatID, ok := b.state.GetGroupChatID(ut.UserID, ut.ThreadID)
+			if !ok {
+				continue
+			}
+			userID, _ := strconv.ParseInt(ut.UserID, 10, 64)
+			threadID, _ := strconv.Atoi(ut.ThreadID)
+			s.Topics = append(s.Topics, control.Topic{UserID: userID, ChatID: chatID, ThreadID: threadID})
+		}
+		sort.Slice(s.Topics, func(i, j int) bool { return s.Topics[i].ThreadID < s.Topics[j].ThreadID })
+		sessions = append(sessions, s)
+	}
+	return sessions, nil
+}
+
+func (c controlBackend) SendText(windowID, text string) error {
+	b := c.b
+	if err := b.SendText(windowID, text); err != nil {
+		if tmux.IsWindowDead(err) {
+			return fmt.Errorf("window %s: %w", windowID, control.ErrNotFound)
+		}
+		return err
+	}
+	entry := state.AuditEntry{Time: time.Now(), User: controlSender, WindowID: windowID, Text: text}
+	if err := state.AppendAudit(filepath.Join(b.config.TramuntanaDir, "audit.jsonl"), entry); err != nil {
+		return fmt.Errorf("sent, but writing the audit log failed: %w", err)
+	}
+	return nil
+}
+
+func (c controlBackend) Screenshot(windowID string) ([]byte, error) {
+	b := c.b
+	paneText, err := tmux.CapturePane(b.config.TmuxSessionName, windowID, true)
+	if err != nil {
+		if tmux.IsWindowDead(err) {
+			return nil, fmt.Errorf("window %s: %w", windowID, control.ErrNotFound)
+		}
+		return nil, err
+	}
+	return render.RenderScreenshotWithOptions(paneText, b.screenshotOptions(0))
+}
+
+func (c controlBackend) Bind(bd control.Binding) error {
+	b := c.b
+	if !b.isAuthorized(bd.UserID, bd.ChatID) {
+		return fmt.Errorf("user %d is not allowed in chat %d", bd.UserID, bd.ChatID)
+	}
+	windows, err := tmux.ListWindows(b.config.TmuxSessionName)
+	if err != nil {
+		return err
+	}
+	var window *tmux.Window
+	for i := range windows {
+		if windows[i].ID == bd.WindowID {
+			window = &windows[i]
+		}
+	}
+	if window == nil {
+		return fmt.Errorf("window %s: %w", bd.WindowID, control.ErrNotFound)
+	}
+
+	userID, threadID := strconv.FormatInt(bd.UserID, 10), strconv.Itoa(bd.ThreadID)
+	b.state.BindThread(userID, threadID, window.ID)
+	b.state.SetGroupChatID(userID, threadID, bd.ChatID)
+	b.state.SetWindowDisplayName(window.ID, window.Name)
+	b.saveState()
+	b.renameForumTopic(bd.ChatID, bd.ThreadID, window.Name)
+	return nil
+}
+
+func (c controlBackend) Notify(n control.Notification) (int, error) {
+	b := c.b
+	if b.msgQueue == nil {
+		return 0, fmt.Errorf("message queue not running")
+	}
+	var tasks []queue.MessageTask
+	if n.WindowID != "" {
+		for _, ut := range b.state.FindUsersForWindow(n.WindowID) {
+			chatID, ok := b.state.GetGroupChatID(ut.UserID, ut.ThreadID)
+			if !ok {
+				continue
+			}
+			userID, _ := strconv.ParseInt(ut.UserID, 10, 64)
+			threadID, _ := strconv.Atoi(ut.ThreadID)
+			tasks = append(tasks, queue.MessageTask{UserID: userID, ChatID: chatID, ThreadID: threadID, WindowID: n.WindowID})
+		}
+		if len(tasks) == 0 {
+			return 0, fmt.Errorf("no topic bound to window %s: %w", n.WindowID, control.ErrNotFound)
+		}
+	} else {
+		if !b.config.IsAllowedGroup(n.ChatID) {
+			return 0, fmt.Errorf("chat %d is not an allowed group", n.ChatID)
+		}
+		tasks = append(tasks, queue.MessageTask{ChatID: n.ChatID, ThreadID: n.ThreadID})
+	}
+	for _, t := range tasks {
+		t.Parts = []string{n.Text}
+		t.ContentType = "content"
+		b.msgQueue.Enqueue(t)
+	}
+	return len(tasks), nil
+}
+
+// refuseExternalSend returns why text from outside Telegram must not be typed
+// into a window, with the checks a topic message gets: the bridge is paused,
+// a topic showing the window is observed, or its project is over budget.
+func (b *Bot) refuseExternalSend(windowID string) error {
+	if b.state.IsPaused() {
+		return fmt.Errorf("%w: %w", errPaused, control.ErrRefused)
+	}
+	for _, ut := range b.state.FindUsersForWindow(windowID) {
+		if b.state.IsObserved(ut.ThreadID) {
+			return fmt.Errorf("window %s is shown in observed topic %s: %w", windowID, ut.ThreadID, control.ErrRefused)
+		}
+	}
+	if project, bud, spent, over := b.overBudget(windowID, time.Now()); over {
+		return fmt.Errorf("%s is over budget (~$%.2f of $%.2f %s): %w",
+			projectLabel(project), spent, bud.Limit, periodLabel(bud.Period), control.ErrRefused)
+	}
+	return nil
+}
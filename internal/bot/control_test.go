@@ -0,0 +1,59 @@
+package bot
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/otaviocarvalho/tramuntana/internal/control"
+)
+
+func TestE2E_ControlBindNotifyAndSend(t *testing.T) {
+	h := startE2E(t, "fresh")
+	windowID := h.tmux.AddWindow("api", "/work/api")
+	be := h.bot.ControlBackend()
+
+	if err := be.Bind(control.Binding{UserID: e2eUser, ChatID: e2eChat, ThreadID: e2eThread, WindowID: "@99"}); !errors.Is(err, control.ErrNotFound) {
+		t.Errorf("binding an unknown window: %v", err)
+	}
+	if err := be.Bind(control.Binding{UserID: 555, ChatID: e2eChat, ThreadID: e2eThread, WindowID: windowID}); err == nil {
+		t.Error("bound a topic for a user who is not allowed")
+	}
+	if err := be.Bind(control.Binding{UserID: e2eUser, ChatID: e2eChat, ThreadID: e2eThread, WindowID: windowID}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := h.bot.state.GetWindowForThread(strconv.FormatInt(e2eUser, 10), strconv.Itoa(e2eThread)); got != windowID {
+		t.Fatalf("topic bound to %q, want %s", got, windowID)
+	}
+
+	sessions, err := be.Sessions()
+	i := slices.IndexFunc(sessions, func(s control.Session) bool { return s.WindowID == windowID })
+	if err != nil || i < 0 || len(sessions[i].Topics) != 1 || sessions[i].Topics[0].ThreadID != e2eThread {
+		t.Fatalf("sessions = %+v, %v", sessions, err)
+	}
+
+	if n, err := be.Notify(control.Notification{WindowID: windowID, Text: "deploy finished"}); err != nil || n != 1 {
+		t.Fatalf("notify = %d, %v", n, err)
+	}
+	msg := h.tg.WaitForText("sendMessage", "deploy finished")
+	if msg.Params["message_thread_id"] != strconv.Itoa(e2eThread) {
+		t.Errorf("notification went to thread %q", msg.Params["message_thread_id"])
+	}
+
+	if err := be.SendText(windowID, "run the tests"); err != nil {
+		t.Fatal(err)
+	}
+	h.tmux.WaitForKeys(windowID, "run the tests")
+
+	// Typing from outside Telegram is refused like a topic message
+	h.bot.state.SetObserved(strconv.Itoa(e2eThread), true)
+	if err := be.SendText(windowID, "deploy to prod"); !errors.Is(err, control.ErrRefused) {
+		t.Errorf("send to an observed topic's window: %v", err)
+	}
+	h.bot.state.SetObserved(strconv.Itoa(e2eThread), false)
+	h.bot.state.SetPaused(true)
+	if err := be.SendText(windowID, "deploy to prod"); !errors.Is(err, control.ErrRefused) {
+		t.Errorf("send while paused: %v", err)
+	}
+}
@@ -0,0 +1,67 @@
+package bot
+
+import (
+	"log"
+	"strconv"
+
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+)
+
+// cwdStablePolls is how many consecutive polls a pane must report a new
+// working directory before the window's CWD follows it, so short-lived
+// foreground processes in other directories don't flap it.
+const cwdStablePolls = 3
+
+// cwdWatch tracks a directory a window's pane has moved to.
+type cwdWatch struct {
+	path  string
+	polls int
+}
+
+// observe records one poll's pane path. Returns true once path has been seen
+// for cwdStablePolls consecutive polls.
+func (w *cwdWatch) observe(path string) bool {
+	if path != w.path {
+		w.path, w.polls = path, 0
+	}
+	w.polls++
+	return w.polls == cwdStablePolls
+}
+
+// trackCWD is called by the status poller for each live bound window with the
+// pane's current path. Once the window has settled in another directory its
+// WindowState.CurrentDir is updated and its topics are told. CWD stays the
+// launch directory, which resuming and forking the session need.
+func (sp *StatusPoller) trackCWD(windowID, path string, users []state.UserThread) {
+	ws, ok := sp.bot.state.GetWindowState(windowID)
+	if path == "" || !ok || ws.CWD == "" || path == ws.Dir() {
+		delete(sp.cwds, windowID)
+		return
+	}
+	w, ok := sp.cwds[windowID]
+	if !ok {
+		w = &cwdWatch{}
+		sp.cwds[windowID] = w
+	}
+	if !w.observe(path) {
+		return
+	}
+	delete(sp.cwds, windowID)
+
+	prev, changed := sp.bot.state.SetWindowCurrentDir(windowID, path)
+	if !changed {
+		return
+	}
+	sp.bot.saveState()
+	log.Printf("Window %s changed directory: %s -> %s", windowID, prev, path)
+	text := "📂 Directory changed: " + shortenPath(prev) + " → " + shortenPath(path)
+
+	for _, ut := range users {
+		chatID, ok := sp.bot.state.GetGroupChatID(ut.UserID, ut.ThreadID)
+		if !ok {
+			continue
+		}
+		threadID, _ := strconv.Atoi(ut.ThreadID)
+		sp.bot.reply(chatID, threadID, text)
+	}
+}
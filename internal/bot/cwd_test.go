@@ -0,0 +1,29 @@
+package bot
+
+import "testing"
+
+func TestCWDWatch_Observe(t *testing.T) {
+	w := &cwdWatch{}
+	for i := 1; i < cwdStablePolls; i++ {
+		if w.observe("/work/api/sub") {
+			t.Fatalf("changed after %d polls, want %d", i, cwdStablePolls)
+		}
+	}
+	if !w.observe("/work/api/sub") {
+		t.Fatal("expected change at threshold")
+	}
+	if w.observe("/work/api/sub") {
+		t.Error("should report a change only once")
+	}
+}
+
+func TestCWDWatch_FlapResets(t *testing.T) {
+	w := &cwdWatch{}
+	for i := 0; i < cwdStablePolls-1; i++ {
+		w.observe("/work/api/sub")
+	}
+	w.observe("/tmp")
+	if w.observe("/work/api/sub") {
+		t.Error("count should restart when the path changes")
+	}
+}
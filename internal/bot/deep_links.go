@@ -0,0 +1,93 @@
+package bot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// topicPayloadPrefix starts the /start payload of a topic deep link.
+const topicPayloadPrefix = "topic_"
+
+// recordGroupUsername keeps state in line with a group's public username, so
+// topic links can use the t.me/<username> form.
+func (b *Bot) recordGroupUsername(chat *tgbotapi.Chat) {
+	if chat == nil || chat.ID >= 0 {
+		return
+	}
+	if b.state.SetGroupUsername(chat.ID, chat.UserName) {
+		b.saveState()
+	}
+}
+
+// topicLink is the t.me link that opens a forum topic, or "" for chats
+// without one. Public groups get the t.me/<username> form, which also works
+// for non-members.
+func (b *Bot) topicLink(chatID int64, threadID int) string {
+	const supergroupBase = -1000000000000
+	if chatID >= supergroupBase || threadID == 0 {
+		return ""
+	}
+	if name, ok := b.state.GetGroupUsername(chatID); ok {
+		return fmt.Sprintf("https://t.me/%s/%d", name, threadID)
+	}
+	return fmt.Sprintf("https://t.me/c/%d/%d", supergroupBase-chatID, threadID)
+}
+
+// deepLink is a t.me/<bot>?start= link that, when tapped, has the bot reply
+// with the topic's link. Falls back to the topic link itself when the bot's
+// username is unknown.
+func (b *Bot) deepLink(chatID int64, threadID int) string {
+	if b.api == nil || b.api.Self.UserName == "" {
+		return b.topicLink(chatID, threadID)
+	}
+	if b.topicLink(chatID, threadID) == "" {
+		return ""
+	}
+	return fmt.Sprintf("https://t.me/%s?start=%s%d_%d", b.api.Self.UserName, topicPayloadPrefix, chatID, threadID)
+}
+
+// parseTopicPayload parses a "topic_<chat>_<thread>" /start payload.
+func parseTopicPayload(payload string) (chatID int64, threadID int, ok bool) {
+	rest, ok := strings.CutPrefix(payload, topicPayloadPrefix)
+	if !ok {
+		return 0, 0, false
+	}
+	chatPart, threadPart, ok := strings.Cut(rest, "_")
+	if !ok {
+		return 0, 0, false
+	}
+	chatID, err := strconv.ParseInt(chatPart, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	threadID, err = strconv.Atoi(threadPart)
+	if err != nil || threadID <= 0 {
+		return 0, 0, false
+	}
+	return chatID, threadID, true
+}
+
+// handleStart answers /start, including taps on topic deep links.
+func (b *Bot) handleStart(msg *tgbotapi.Message) {
+	threadID := getThreadID(msg)
+	payload := strings.TrimSpace(msg.CommandArguments())
+	if payload == "" {
+		b.reply(msg.Chat.ID, threadID, "Open a forum topic in your group to start a session.")
+		return
+	}
+	topicChat, topicThread, ok := parseTopicPayload(payload)
+	link := b.topicLink(topicChat, topicThread)
+	if !ok || link == "" {
+		b.reply(msg.Chat.ID, threadID, "This link doesn't point to a topic.")
+		return
+	}
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonURL("Open topic", link)),
+	)
+	if _, err := b.sendMessageWithKeyboard(msg.Chat.ID, threadID, "Topic: "+link, keyboard); err != nil {
+		b.reply(msg.Chat.ID, threadID, "Topic: "+link)
+	}
+}
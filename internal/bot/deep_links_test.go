@@ -0,0 +1,69 @@
+package bot
+
+import (
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestTopicLink(t *testing.T) {
+	b := newTestBot(t)
+	b.state.SetGroupUsername(-1009999999999, "myteam")
+
+	tests := []struct {
+		chatID   int64
+		threadID int
+		want     string
+	}{
+		{-1001234567890, 42, "https://t.me/c/1234567890/42"},
+		{-1009999999999, 42, "https://t.me/myteam/42"}, // public group
+		{-1001234567890, 0, ""},
+		{-4567, 42, ""}, // basic group
+		{100, 42, ""},   // private chat
+	}
+	for _, tt := range tests {
+		if got := b.topicLink(tt.chatID, tt.threadID); got != tt.want {
+			t.Errorf("topicLink(%d, %d) = %q, want %q", tt.chatID, tt.threadID, got, tt.want)
+		}
+	}
+}
+
+func TestRecordGroupUsername(t *testing.T) {
+	b := newTestBot(t)
+	b.config.TramuntanaDir = t.TempDir()
+
+	b.recordGroupUsername(&tgbotapi.Chat{ID: -1001234567890, UserName: "myteam"})
+	b.recordGroupUsername(&tgbotapi.Chat{ID: 100, UserName: "someone"}) // private chat
+	if got := b.topicLink(-1001234567890, 7); got != "https://t.me/myteam/7" {
+		t.Errorf("public group link = %q", got)
+	}
+	if _, ok := b.state.GetGroupUsername(100); ok {
+		t.Error("recorded a private chat's username")
+	}
+
+	b.recordGroupUsername(&tgbotapi.Chat{ID: -1001234567890})
+	if got := b.topicLink(-1001234567890, 7); got != "https://t.me/c/1234567890/7" {
+		t.Errorf("link after the group went private = %q", got)
+	}
+}
+
+func TestParseTopicPayload(t *testing.T) {
+	tests := []struct {
+		payload  string
+		chatID   int64
+		threadID int
+		ok       bool
+	}{
+		{"topic_-1001234567890_42", -1001234567890, 42, true},
+		{"topic_-1001234567890_0", 0, 0, false},
+		{"topic_-1001234567890", 0, 0, false},
+		{"topic_abc_42", 0, 0, false},
+		{"ref_-100_42", 0, 0, false},
+	}
+	for _, tt := range tests {
+		chatID, threadID, ok := parseTopicPayload(tt.payload)
+		if ok != tt.ok || chatID != tt.chatID || threadID != tt.threadID {
+			t.Errorf("parseTopicPayload(%q) = %d, %d, %v", tt.payload, chatID, threadID, ok)
+		}
+	}
+}
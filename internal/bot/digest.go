@@ -0,0 +1,177 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+)
+
+// digestMaxFiles caps how many touched files are listed in a digest.
+const digestMaxFiles = 10
+
+// DigestScheduler persists usage and posts an end-of-day digest to each bound
+// topic. Once a day it also offers to remove stale merged worktrees, and it
+// deletes expired prompt files.
+type DigestScheduler struct {
+	bot          *Bot
+	usage        *state.Usage
+	usagePath    string
+	pollInterval time.Duration
+}
+
+// NewDigestScheduler creates a new DigestScheduler.
+func NewDigestScheduler(bot *Bot, u *state.Usage) *DigestScheduler {
+	return &DigestScheduler{
+		bot:          bot,
+		usage:        u,
+		usagePath:    filepath.Join(bot.config.TramuntanaDir, "usage.json"),
+		pollInterval: 1 * time.Minute,
+	}
+}
+
+// Run starts the scheduler loop. Blocks until ctx is cancelled.
+func (ds *DigestScheduler) Run(ctx context.Context) {
+	if ds.bot.config.DigestTime != "" {
+		log.Printf("Daily digest scheduled at %s", ds.bot.config.DigestTime)
+	}
+	ticker := time.NewTicker(ds.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			ds.save()
+			return
+		case now := <-ticker.C:
+			ds.tick(now)
+			ds.save()
+		}
+	}
+}
+
+func (ds *DigestScheduler) save() {
+	if err := ds.usage.SaveIfDirty(ds.usagePath); err != nil {
+		log.Printf("Error saving usage: %v", err)
+	}
+}
+
+// tick deletes prompt files older than promptFileTTL, checks for stale
+// worktrees on the first tick of each day, and posts the digest once the
+// configured time of day has passed.
+func (ds *DigestScheduler) tick(now time.Time) {
+	if removed := sweepPromptFiles(ds.bot.promptDir(), promptFileTTL, false, now); removed.Files > 0 {
+		log.Printf("Removed %d expired prompt file(s)", removed.Files)
+	}
+	if ds.bot.config.WorktreeCleanupAge > 0 && ds.usage.MarkWorktreeCheck(state.Day(now)) {
+		ds.bot.offerWorktreeCleanup()
+	}
+
+	at := ds.bot.config.DigestTime
+	if at == "" || now.Format("15:04") < at {
+		return
+	}
+	day := state.Day(now)
+	if !ds.usage.MarkDigestSent(day) {
+		return
+	}
+	ds.postDigests(day)
+}
+
+// postDigests sends the day's digest to every topic bound to a window with activity.
+func (ds *DigestScheduler) postDigests(day string) {
+	type topic struct {
+		chatID   int64
+		threadID int
+	}
+	sent := make(map[topic]bool)
+
+	for windowID := range ds.bot.state.AllBoundWindowIDs() {
+		wu, ok := ds.usage.GetWindowUsage(day, windowID)
+		if !ok {
+			continue
+		}
+		ws, _ := ds.bot.state.GetWindowState(windowID)
+
+		for _, ut := range ds.bot.state.FindUsersForWindow(windowID) {
+			chatID, ok := ds.bot.state.GetGroupChatID(ut.UserID, ut.ThreadID)
+			if !ok {
+				continue
+			}
+			threadID, _ := strconv.Atoi(ut.ThreadID)
+			t := topic{chatID, threadID}
+			if sent[t] {
+				continue
+			}
+			sent[t] = true
+
+			project, _ := ds.bot.state.GetProject(ut.ThreadID)
+			link := ds.bot.topicLink(chatID, threadID)
+			ds.bot.reply(chatID, threadID, formatDigest(day, project, ws.CWD, link, wu))
+		}
+	}
+	log.Printf("Posted daily digest for %s to %d topics", day, len(sent))
+}
+
+// formatDigest renders a window's daily usage as a plain-text message, with
+// the topic's link (may be empty) so it can be found again from a forward.
+func formatDigest(day, project, cwd, link string, wu state.WindowUsage) string {
+	var b strings.Builder
+	if project != "" {
+		fmt.Fprintf(&b, "📊 Daily digest — %s (%s)\n", project, day)
+	} else {
+		fmt.Fprintf(&b, "📊 Daily digest — %s\n", day)
+	}
+	if link != "" {
+		fmt.Fprintf(&b, "Topic: %s\n", link)
+	}
+
+	brewed := time.Duration(wu.BrewedSeconds) * time.Second
+	fmt.Fprintf(&b, "Turns: %d · brewed %s\n", wu.Turns, brewed.Round(time.Second))
+
+	if len(wu.TasksPicked) > 0 {
+		fmt.Fprintf(&b, "Tasks picked: %s\n", strings.Join(wu.TasksPicked, ", "))
+	}
+	if len(wu.TasksFinished) > 0 {
+		fmt.Fprintf(&b, "Tasks finished: %s\n", strings.Join(wu.TasksFinished, ", "))
+	}
+
+	files := wu.Files()
+	if len(files) > 0 {
+		fmt.Fprintf(&b, "Files touched (%d):\n", len(files))
+		for i, f := range files {
+			if i == digestMaxFiles {
+				fmt.Fprintf(&b, "  … +%d more\n", len(files)-digestMaxFiles)
+				break
+			}
+			if cwd != "" {
+				if rel, err := filepath.Rel(cwd, f); err == nil && !strings.HasPrefix(rel, "..") {
+					f = rel
+				}
+			}
+			fmt.Fprintf(&b, "  %s\n", f)
+		}
+	}
+
+	// CostUSD adds up monitor.EstimateCost over the day's assistant messages
+	fmt.Fprintf(&b, "Cost: ~$%.2f (tokens: %s in · %s out · %s cache)", wu.CostUSD,
+		formatTokenCount(wu.InputTokens), formatTokenCount(wu.OutputTokens), formatTokenCount(wu.CacheTokens))
+	return b.String()
+}
+
+// formatTokenCount abbreviates a token count (e.g. 1234 → "1.2k").
+func formatTokenCount(n int64) string {
+	switch {
+	case n >= 1_000_000:
+		return fmt.Sprintf("%.1fM", float64(n)/1_000_000)
+	case n >= 1_000:
+		return fmt.Sprintf("%.1fk", float64(n)/1_000)
+	default:
+		return strconv.FormatInt(n, 10)
+	}
+}
@@ -0,0 +1,56 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+)
+
+func TestFormatDigest(t *testing.T) {
+	wu := state.WindowUsage{
+		Turns:         3,
+		BrewedSeconds: 754,
+		TasksPicked:   []string{"t1", "t2"},
+		TasksFinished: []string{"t1"},
+		FilesTouched:  map[string]bool{"/repo/main.go": true, "/etc/hosts": true},
+		InputTokens:   1500,
+		OutputTokens:  42,
+		CacheTokens:   2_300_000,
+		CostUSD:       1.234,
+	}
+	got := formatDigest("2025-03-01", "auth", "/repo", "https://t.me/c/1234567890/42", wu)
+
+	for _, want := range []string{
+		"Daily digest — auth (2025-03-01)\nTopic: https://t.me/c/1234567890/42\n",
+		"Turns: 3 · brewed 12m34s",
+		"Tasks picked: t1, t2",
+		"Tasks finished: t1",
+		"Files touched (2):",
+		"  main.go",
+		"  /etc/hosts",
+		"Cost: ~$1.23 (tokens: 1.5k in · 42 out · 2.3M cache)",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("digest missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestFormatDigest_TruncatesFiles(t *testing.T) {
+	wu := state.WindowUsage{FilesTouched: make(map[string]bool)}
+	for i := 0; i < digestMaxFiles+3; i++ {
+		wu.FilesTouched[fmt.Sprintf("/f%02d", i)] = true
+	}
+	got := formatDigest("2025-03-01", "", "", "", wu)
+	if strings.Contains(got, "Topic:") {
+		t.Errorf("topic line without a link:\n%s", got)
+	}
+	if !strings.Contains(got, "… +3 more") {
+		t.Errorf("expected truncation note:\n%s", got)
+	}
+	if strings.Contains(got, "/f10") {
+		t.Errorf("file beyond limit should be omitted:\n%s", got)
+	}
+}
@@ -26,14 +26,18 @@ type BrowseState struct {
 	MessageID   int
 	ChatID      int64
 	ThreadID    int
+	Launch      state.LaunchChoice // model and permission mode on the options step
+	PerPage     int                // directories per page
 }
 
 // showDirectoryBrowser sends the directory browser keyboard to the user.
 func (b *Bot) showDirectoryBrowser(chatID int64, threadID int, userID int64, pendingText string) {
 	home, _ := os.UserHomeDir()
-	startPath := home
+	roots := b.allowedRoots()
+	startPath := startDir(home, roots)
 
-	text, keyboard, dirs := buildDirectoryBrowser(startPath, 0)
+	perPage := b.browserPageSize(userID, dirsPerPage)
+	text, keyboard, dirs := buildDirectoryBrowser(startPath, 0, perPage, roots)
 
 	msg, err := b.sendMessageWithKeyboard(chatID, threadID, text, keyboard)
 	if err != nil {
@@ -42,7 +46,7 @@ func (b *Bot) showDirectoryBrowser(chatID int64, threadID int, userID int64, pen
 	}
 
 	b.mu.Lock()
-	b.browseStates[userID] = &BrowseState{
+	b.browseStates.set(wizardKey{UserID: userID, ChatID: chatID, ThreadID: threadID}, &BrowseState{
 		CurrentPath: startPath,
 		Page:        0,
 		Dirs:        dirs,
@@ -50,15 +54,17 @@ func (b *Bot) showDirectoryBrowser(chatID int64, threadID int, userID int64, pen
 		MessageID:   msg.MessageID,
 		ChatID:      chatID,
 		ThreadID:    threadID,
-	}
+		PerPage:     perPage,
+	})
 	b.mu.Unlock()
 }
 
-// buildDirectoryBrowser builds the inline keyboard for directory browsing.
-// Returns the display text, keyboard markup, and cached subdirectory names.
-func buildDirectoryBrowser(currentPath string, page int) (string, tgbotapi.InlineKeyboardMarkup, []string) {
+// buildDirectoryBrowser builds the inline keyboard for directory browsing,
+// limited to directories inside roots. Returns the display text, keyboard
+// markup, and cached subdirectory names.
+func buildDirectoryBrowser(currentPath string, page, perPage int, roots []string) (string, tgbotapi.InlineKeyboardMarkup, []string) {
 	entries, err := os.ReadDir(currentPath)
-	if err != nil {
+	if err != nil || !withinRoots(currentPath, roots) {
 		return fmt.Sprintf("Error reading %s", currentPath), tgbotapi.NewInlineKeyboardMarkup(
 			tgbotapi.NewInlineKeyboardRow(
 				tgbotapi.NewInlineKeyboardButtonData("Cancel", "dir_cancel"),
@@ -69,13 +75,13 @@ func buildDirectoryBrowser(currentPath string, page int) (string, tgbotapi.Inlin
 	// Filter to non-hidden directories, sorted
 	var dirs []string
 	for _, e := range entries {
-		if e.IsDir() && !strings.HasPrefix(e.Name(), ".") {
+		if e.IsDir() && !strings.HasPrefix(e.Name(), ".") && withinRoots(filepath.Join(currentPath, e.Name()), roots) {
 			dirs = append(dirs, e.Name())
 		}
 	}
 	sort.Strings(dirs)
 
-	totalPages := (len(dirs) + dirsPerPage - 1) / dirsPerPage
+	totalPages := (len(dirs) + perPage - 1) / perPage
 	if totalPages == 0 {
 		totalPages = 1
 	}
@@ -90,8 +96,8 @@ func buildDirectoryBrowser(currentPath string, page int) (string, tgbotapi.Inlin
 	var rows [][]tgbotapi.InlineKeyboardButton
 
 	// Directory buttons (2 per row)
-	start := page * dirsPerPage
-	end := start + dirsPerPage
+	start := page * perPage
+	end := start + perPage
 	if end > len(dirs) {
 		end = len(dirs)
 	}
@@ -150,20 +156,14 @@ func (b *Bot) processDirectoryCallback(cq *tgbotapi.CallbackQuery) {
 	userID := cq.From.ID
 	data := cq.Data
 
-	b.mu.RLock()
-	bs, ok := b.browseStates[userID]
-	b.mu.RUnlock()
+	b.mu.Lock()
+	bs, ok := b.browseStates.get(callbackWizardKey(cq))
+	b.mu.Unlock()
 
 	if !ok {
 		return
 	}
 
-	// Verify topic match
-	threadID := getThreadID(cq.Message)
-	if threadID != bs.ThreadID {
-		return
-	}
-
 	switch {
 	case strings.HasPrefix(data, "dir_sel:"):
 		b.handleDirSelect(cq, bs, userID)
@@ -172,6 +172,12 @@ func (b *Bot) processDirectoryCallback(cq *tgbotapi.CallbackQuery) {
 	case data == "dir_up":
 		b.handleDirUp(cq, bs, userID)
 	case data == "dir_confirm":
+		b.handleDirOptions(cq, bs, userID)
+	case strings.HasPrefix(data, "dir_model:"), strings.HasPrefix(data, "dir_perm:"):
+		b.handleDirLaunchChoice(cq, bs, userID)
+	case data == "dir_back":
+		b.handleDirBack(cq, bs, userID)
+	case data == "dir_start":
 		b.handleDirConfirm(cq, bs, userID)
 	case data == "dir_cancel":
 		b.handleDirCancel(cq, bs, userID)
@@ -189,11 +195,12 @@ func (b *Bot) handleDirSelect(cq *tgbotapi.CallbackQuery, bs *BrowseState, userI
 
 	newPath := filepath.Join(bs.CurrentPath, bs.Dirs[idx])
 	info, err := os.Stat(newPath)
-	if err != nil || !info.IsDir() {
+	roots := b.allowedRoots()
+	if err != nil || !info.IsDir() || !withinRoots(newPath, roots) {
 		return
 	}
 
-	text, keyboard, dirs := buildDirectoryBrowser(newPath, 0)
+	text, keyboard, dirs := buildDirectoryBrowser(newPath, 0, bs.PerPage, roots)
 	b.editMessageWithKeyboard(bs.ChatID, bs.MessageID, text, keyboard)
 
 	b.mu.Lock()
@@ -210,7 +217,7 @@ func (b *Bot) handleDirPage(cq *tgbotapi.CallbackQuery, bs *BrowseState, userID
 		return
 	}
 
-	text, keyboard, dirs := buildDirectoryBrowser(bs.CurrentPath, page)
+	text, keyboard, dirs := buildDirectoryBrowser(bs.CurrentPath, page, bs.PerPage, b.allowedRoots())
 	b.editMessageWithKeyboard(bs.ChatID, bs.MessageID, text, keyboard)
 
 	b.mu.Lock()
@@ -221,11 +228,12 @@ func (b *Bot) handleDirPage(cq *tgbotapi.CallbackQuery, bs *BrowseState, userID
 
 func (b *Bot) handleDirUp(cq *tgbotapi.CallbackQuery, bs *BrowseState, userID int64) {
 	parent := filepath.Dir(bs.CurrentPath)
-	if parent == bs.CurrentPath {
+	roots := b.allowedRoots()
+	if parent == bs.CurrentPath || !withinRoots(parent, roots) {
 		return // already at root
 	}
 
-	text, keyboard, dirs := buildDirectoryBrowser(parent, 0)
+	text, keyboard, dirs := buildDirectoryBrowser(parent, 0, bs.PerPage, roots)
 	b.editMessageWithKeyboard(bs.ChatID, bs.MessageID, text, keyboard)
 
 	b.mu.Lock()
@@ -235,6 +243,79 @@ func (b *Bot) handleDirUp(cq *tgbotapi.CallbackQuery, bs *BrowseState, userID in
 	b.mu.Unlock()
 }
 
+// buildLaunchOptions builds the options step shown after Select: quick
+// buttons for the new session's model and permission mode, marking lc's.
+func buildLaunchOptions(path string, lc state.LaunchChoice) (string, tgbotapi.InlineKeyboardMarkup) {
+	optionRow := func(prefix string, opts []launchOption, selected string) []tgbotapi.InlineKeyboardButton {
+		var row []tgbotapi.InlineKeyboardButton
+		for i, o := range opts {
+			label := o.Label
+			if o.Value == selected {
+				label = "✓ " + label
+			}
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData(label, fmt.Sprintf("%s:%d", prefix, i)))
+		}
+		return row
+	}
+
+	text := fmt.Sprintf("New session in %s\nModel: %s\nPermission mode: %s",
+		shortenPath(path), optionLabel(launchModels, lc.Model), optionLabel(launchPermissionModes, lc.PermissionMode))
+	return text, tgbotapi.NewInlineKeyboardMarkup(
+		optionRow("dir_model", launchModels, lc.Model),
+		optionRow("dir_perm", launchPermissionModes, lc.PermissionMode),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Back", "dir_back"),
+			tgbotapi.NewInlineKeyboardButtonData("Start", "dir_start"),
+			tgbotapi.NewInlineKeyboardButtonData("Cancel", "dir_cancel"),
+		),
+	)
+}
+
+// handleDirOptions moves from the browser to the options step, preselecting
+// the choice last used in the directory.
+func (b *Bot) handleDirOptions(cq *tgbotapi.CallbackQuery, bs *BrowseState, userID int64) {
+	lc := b.state.GetDirLaunch(bs.CurrentPath)
+	text, keyboard := buildLaunchOptions(bs.CurrentPath, lc)
+	b.editMessageWithKeyboard(bs.ChatID, bs.MessageID, text, keyboard)
+
+	b.mu.Lock()
+	bs.Launch = lc
+	b.mu.Unlock()
+}
+
+func (b *Bot) handleDirLaunchChoice(cq *tgbotapi.CallbackQuery, bs *BrowseState, userID int64) {
+	prefix, idxStr, _ := strings.Cut(cq.Data, ":")
+	idx, err := strconv.Atoi(idxStr)
+	opts := launchModels
+	if prefix == "dir_perm" {
+		opts = launchPermissionModes
+	}
+	if err != nil || idx < 0 || idx >= len(opts) {
+		return
+	}
+
+	b.mu.Lock()
+	if prefix == "dir_perm" {
+		bs.Launch.PermissionMode = opts[idx].Value
+	} else {
+		bs.Launch.Model = opts[idx].Value
+	}
+	lc := bs.Launch
+	b.mu.Unlock()
+
+	text, keyboard := buildLaunchOptions(bs.CurrentPath, lc)
+	b.editMessageWithKeyboard(bs.ChatID, bs.MessageID, text, keyboard)
+}
+
+func (b *Bot) handleDirBack(cq *tgbotapi.CallbackQuery, bs *BrowseState, userID int64) {
+	text, keyboard, dirs := buildDirectoryBrowser(bs.CurrentPath, bs.Page, bs.PerPage, b.allowedRoots())
+	b.editMessageWithKeyboard(bs.ChatID, bs.MessageID, text, keyboard)
+
+	b.mu.Lock()
+	bs.Dirs = dirs
+	b.mu.Unlock()
+}
+
 // createWindowResult holds the result of creating a new tmux window for a directory.
 type createWindowResult struct {
 	WindowID   string
@@ -243,12 +324,26 @@ type createWindowResult struct {
 
 // createWindowForDir creates a new tmux window in the given directory, waits for the
 // session_map entry, binds the thread, and renames the topic. Returns the result or error.
+// Claude may still be starting; send text to the window with sendWhenReady.
 func (b *Bot) createWindowForDir(dir string, userID int64, chatID int64, threadID int) (*createWindowResult, error) {
-	// Build Minuano environment if configured
+	project, _ := b.state.GetProject(strconv.Itoa(threadID))
+	return b.createWindowWithCommand(dir, b.resolveLaunch(dir, project), userID, chatID, threadID)
+}
+
+// createWindowWithCommand is createWindowForDir with an explicit launch spec,
+// used to resume a previous conversation.
+func (b *Bot) createWindowWithCommand(dir string, launch launchSpec, userID int64, chatID int64, threadID int) (*createWindowResult, error) {
+	// Build Minuano environment if configured, plus the directory's overrides
 	env := b.buildMinuanoEnv(filepath.Base(dir))
+	if len(launch.Env) > 0 && env == nil {
+		env = make(map[string]string, len(launch.Env))
+	}
+	for k, v := range launch.Env {
+		env[k] = v
+	}
 
 	// Create new tmux window
-	windowID, err := tmux.NewWindow(b.config.TmuxSessionName, "", dir, b.config.ClaudeCommand, env)
+	windowID, err := tmux.NewWindow(b.config.TmuxSessionName, "", dir, launch.Command, env)
 	if err != nil {
 		return nil, fmt.Errorf("creating window: %w", err)
 	}
@@ -272,6 +367,7 @@ func (b *Bot) createWindowForDir(dir string, userID int64, chatID int64, threadI
 					SessionID:  entry.SessionID,
 					CWD:        entry.CWD,
 					WindowName: entry.WindowName,
+					AgentID:    env["MINUANO_AGENT_ID"],
 				})
 				b.state.SetWindowDisplayName(windowID, entry.WindowName)
 				break
@@ -282,9 +378,6 @@ func (b *Bot) createWindowForDir(dir string, userID int64, chatID int64, threadI
 		}
 	}
 
-	// Wait for Claude Code TUI to be ready before sending any text
-	tmux.WaitForReady(b.config.TmuxSessionName, windowID, 15*time.Second)
-
 	// Bind thread to window
 	userIDStr := strconv.FormatInt(userID, 10)
 	threadIDStr := strconv.Itoa(threadID)
@@ -311,33 +404,48 @@ func (b *Bot) handleDirConfirm(cq *tgbotapi.CallbackQuery, bs *BrowseState, user
 
 	// Clear browse state
 	b.mu.Lock()
-	delete(b.browseStates, userID)
+	b.browseStates.remove(wizardKey{UserID: userID, ChatID: bs.ChatID, ThreadID: bs.ThreadID})
+	lc := bs.Launch
 	b.mu.Unlock()
 
-	// Edit message to show progress
-	b.editMessageText(chatID, bs.MessageID, fmt.Sprintf("Creating session in %s...", shortenPath(selectedPath)))
+	// Remember the choice as the directory's default for next time
+	b.state.SetDirLaunch(selectedPath, lc)
+	b.saveState()
 
-	result, err := b.createWindowForDir(selectedPath, userID, chatID, threadID)
-	if err != nil {
-		log.Printf("Error creating window: %v", err)
-		b.editMessageText(chatID, bs.MessageID, "Error: failed to create session.")
-		return
-	}
+	start := func() {
+		// Edit message to show progress
+		b.editMessageText(chatID, bs.MessageID, fmt.Sprintf("Creating session in %s...", shortenPath(selectedPath)))
+
+		project, _ := b.state.GetProject(strconv.Itoa(threadID))
+		launch := b.resolveLaunch(selectedPath, project)
+		launch.Command = withLaunchChoice(launch.Command, lc)
+		result, err := b.createWindowWithCommand(selectedPath, launch, userID, chatID, threadID)
+		if err != nil {
+			log.Printf("Error creating window: %v", err)
+			b.editMessageText(chatID, bs.MessageID, "Error: failed to create session.")
+			return
+		}
 
-	// Update the browser message
-	b.editMessageText(chatID, bs.MessageID, fmt.Sprintf("Bound to: %s", result.WindowName))
+		// Update the browser message
+		b.editMessageText(chatID, bs.MessageID, fmt.Sprintf("Bound to: %s", result.WindowName))
 
-	// Send pending text
-	if pendingText != "" {
-		if err := tmux.SendKeysWithDelay(b.config.TmuxSessionName, result.WindowID, pendingText, 500); err != nil {
-			log.Printf("Error sending pending text: %v", err)
+		// Send pending text
+		if pendingText != "" {
+			b.sendWhenReady(chatID, threadID, result.WindowID, "your message", func() error {
+				return tmux.SendKeysWithDelay(b.config.TmuxSessionName, result.WindowID, pendingText, 500)
+			})
 		}
 	}
+	if !b.guardSession(chatID, threadID, userID, start) {
+		b.editMessageText(chatID, bs.MessageID, fmt.Sprintf("Session in %s on hold.", shortenPath(selectedPath)))
+		return
+	}
+	start()
 }
 
 func (b *Bot) handleDirCancel(cq *tgbotapi.CallbackQuery, bs *BrowseState, userID int64) {
 	b.mu.Lock()
-	delete(b.browseStates, userID)
+	b.browseStates.remove(wizardKey{UserID: userID, ChatID: bs.ChatID, ThreadID: bs.ThreadID})
 	b.mu.Unlock()
 
 	b.editMessageText(bs.ChatID, bs.MessageID, "Cancelled.")
@@ -345,6 +453,12 @@ func (b *Bot) handleDirCancel(cq *tgbotapi.CallbackQuery, bs *BrowseState, userI
 
 // renameForumTopic renames a Telegram forum topic.
 func (b *Bot) renameForumTopic(chatID int64, threadID int, name string) {
+	b.editForumTopic(chatID, threadID, name, nil)
+}
+
+// editForumTopic renames a forum topic and/or sets its icon. An empty name
+// keeps the name; a nil icon keeps the icon and an empty one removes it.
+func (b *Bot) editForumTopic(chatID int64, threadID int, name string, iconID *string) {
 	if threadID == 0 {
 		return
 	}
@@ -352,17 +466,21 @@ func (b *Bot) renameForumTopic(chatID int64, threadID int, name string) {
 	params.AddNonZero64("chat_id", chatID)
 	params.AddNonZero("message_thread_id", threadID)
 	params.AddNonEmpty("name", name)
+	if iconID != nil {
+		params["icon_custom_emoji_id"] = *iconID
+	}
 	if _, err := b.api.MakeRequest("editForumTopic", params); err != nil {
-		log.Printf("Error renaming topic: %v", err)
+		log.Printf("Error editing topic: %v", err)
 	}
 }
 
 // truncateName truncates a name to maxLen chars, adding ellipsis if needed.
 func truncateName(name string, maxLen int) string {
-	if len(name) <= maxLen {
+	runes := []rune(name)
+	if len(runes) <= maxLen {
 		return name
 	}
-	return name[:maxLen-1] + "\u2026"
+	return string(runes[:maxLen-1]) + "\u2026"
 }
 
 // shortenPath replaces the home directory with ~ in a path.
@@ -13,7 +13,7 @@ func TestBuildDirectoryBrowser_ListsDirs(t *testing.T) {
 	os.Mkdir(filepath.Join(dir, ".hidden"), 0o755) // should be excluded
 	os.WriteFile(filepath.Join(dir, "file.txt"), []byte("hi"), 0o644)
 
-	text, kb, dirs := buildDirectoryBrowser(dir, 0)
+	text, kb, dirs := buildDirectoryBrowser(dir, 0, dirsPerPage, []string{dir})
 
 	if len(dirs) != 2 {
 		t.Fatalf("expected 2 dirs, got %d: %v", len(dirs), dirs)
@@ -36,7 +36,7 @@ func TestBuildDirectoryBrowser_Pagination(t *testing.T) {
 		os.Mkdir(filepath.Join(dir, "dir"+string(rune('a'+i))), 0o755)
 	}
 
-	_, kb, dirs := buildDirectoryBrowser(dir, 0)
+	_, kb, dirs := buildDirectoryBrowser(dir, 0, dirsPerPage, []string{dir})
 	if len(dirs) != 8 {
 		t.Fatalf("expected 8 dirs, got %d", len(dirs))
 	}
@@ -55,7 +55,7 @@ func TestBuildDirectoryBrowser_Pagination(t *testing.T) {
 	}
 
 	// Page 1 should show remaining dirs
-	_, kb2, _ := buildDirectoryBrowser(dir, 1)
+	_, kb2, _ := buildDirectoryBrowser(dir, 1, dirsPerPage, []string{dir})
 	hasBack := false
 	for _, row := range kb2.InlineKeyboard {
 		for _, btn := range row {
@@ -72,7 +72,7 @@ func TestBuildDirectoryBrowser_Pagination(t *testing.T) {
 func TestBuildDirectoryBrowser_EmptyDir(t *testing.T) {
 	dir := t.TempDir()
 
-	text, kb, dirs := buildDirectoryBrowser(dir, 0)
+	text, kb, dirs := buildDirectoryBrowser(dir, 0, dirsPerPage, []string{dir})
 	if len(dirs) != 0 {
 		t.Errorf("expected 0 dirs, got %d", len(dirs))
 	}
@@ -86,7 +86,7 @@ func TestBuildDirectoryBrowser_EmptyDir(t *testing.T) {
 }
 
 func TestBuildDirectoryBrowser_InvalidPath(t *testing.T) {
-	text, _, dirs := buildDirectoryBrowser("/nonexistent/path/that/does/not/exist", 0)
+	text, _, dirs := buildDirectoryBrowser("/nonexistent/path/that/does/not/exist", 0, dirsPerPage, []string{"/"})
 	if dirs != nil {
 		t.Error("dirs should be nil for invalid path")
 	}
@@ -99,7 +99,7 @@ func TestBuildDirectoryBrowser_ActionRow(t *testing.T) {
 	dir := t.TempDir()
 	os.Mkdir(filepath.Join(dir, "sub"), 0o755)
 
-	_, kb, _ := buildDirectoryBrowser(dir, 0)
+	_, kb, _ := buildDirectoryBrowser(dir, 0, dirsPerPage, []string{dir})
 
 	// Last row should be the action row
 	lastRow := kb.InlineKeyboard[len(kb.InlineKeyboard)-1]
@@ -163,7 +163,7 @@ func TestBuildDirectoryBrowser_SortedAlphabetically(t *testing.T) {
 	os.Mkdir(filepath.Join(dir, "apple"), 0o755)
 	os.Mkdir(filepath.Join(dir, "mango"), 0o755)
 
-	_, _, dirs := buildDirectoryBrowser(dir, 0)
+	_, _, dirs := buildDirectoryBrowser(dir, 0, dirsPerPage, []string{dir})
 	if len(dirs) != 3 {
 		t.Fatalf("expected 3 dirs, got %d", len(dirs))
 	}
@@ -177,13 +177,13 @@ func TestBuildDirectoryBrowser_PageBounds(t *testing.T) {
 	os.Mkdir(filepath.Join(dir, "a"), 0o755)
 
 	// Page -1 should clamp to 0
-	_, _, dirs := buildDirectoryBrowser(dir, -1)
+	_, _, dirs := buildDirectoryBrowser(dir, -1, dirsPerPage, []string{dir})
 	if len(dirs) != 1 {
 		t.Errorf("expected 1 dir, got %d", len(dirs))
 	}
 
 	// Page 999 should clamp to last page
-	_, _, dirs = buildDirectoryBrowser(dir, 999)
+	_, _, dirs = buildDirectoryBrowser(dir, 999, dirsPerPage, []string{dir})
 	if len(dirs) != 1 {
 		t.Errorf("expected 1 dir, got %d", len(dirs))
 	}
@@ -0,0 +1,744 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/otaviocarvalho/tramuntana/internal/config"
+	"github.com/otaviocarvalho/tramuntana/internal/events"
+	"github.com/otaviocarvalho/tramuntana/internal/monitor"
+	"github.com/otaviocarvalho/tramuntana/internal/queue"
+	"github.com/otaviocarvalho/tramuntana/internal/render"
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+	"github.com/otaviocarvalho/tramuntana/internal/testharness"
+)
+
+const (
+	e2eSession = "tramuntana-test"
+	e2eUser    = int64(100)
+	e2eChat    = int64(-1001)
+	e2eThread  = 42
+)
+
+// e2e is a bot wired to a fake Telegram server and scripted tmux, with the
+// monitor, queue and event handlers running as in the serve command.
+type e2e struct {
+	bot  *Bot
+	tg   *testharness.Telegram
+	tmux *testharness.Tmux
+	cfg  *config.Config
+	bus  *events.Bus
+	q    *queue.Queue
+	home string
+
+	ctx context.Context
+	wg  sync.WaitGroup
+}
+
+func startE2E(t *testing.T, resumeMode string) *e2e {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	h := &e2e{
+		tg:   testharness.NewTelegram(t),
+		tmux: testharness.NewTmux(t, e2eSession),
+		home: home,
+		cfg: &config.Config{
+			AllowedUsers:        []int64{e2eUser},
+			TramuntanaDir:       filepath.Join(home, ".tramuntana"),
+			TmuxSessionName:     e2eSession,
+			ClaudeCommand:       "claude",
+			MonitorPollInterval: 0.05,
+			Verbosity:           render.DefaultProfile.Name,
+			ResumeMode:          resumeMode,
+			BootstrapPolicy:     "eof",
+			MaxEntriesPerPoll:   200,
+		},
+	}
+	if err := os.MkdirAll(h.cfg.TramuntanaDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := NewWithAPI(h.cfg, h.tg.API())
+	if err != nil {
+		t.Fatalf("NewWithAPI: %v", err)
+	}
+	h.bot = b
+	h.bus = events.New()
+	b.SetEventBus(h.bus)
+	h.q = queue.New(b.API())
+	b.SetQueue(h.q)
+	mon := monitor.New(h.cfg, b.State(), state.NewMonitorState(), h.q)
+	mon.Events = h.bus
+	mon.ActivityHandler = b.HandleMonitorActivity
+
+	ctx, cancel := context.WithCancel(context.Background())
+	h.ctx = ctx
+	t.Cleanup(func() {
+		cancel()
+		h.wg.Wait()
+	})
+	h.goRun(b.RunEventHandlers)
+	h.goRun(mon.Run)
+	h.goRun(func(ctx context.Context) { b.Run(ctx) })
+	return h
+}
+
+// goRun starts a component that stops when the test ends.
+func (h *e2e) goRun(run func(ctx context.Context)) {
+	h.wg.Add(1)
+	go func() {
+		defer h.wg.Done()
+		run(h.ctx)
+	}()
+}
+
+// startStatusPoller runs a status poller with a short interval.
+func (h *e2e) startStatusPoller() {
+	sp := NewStatusPoller(h.bot, h.q, h.bus)
+	sp.pollInterval = 50 * time.Millisecond
+	h.goRun(sp.Run)
+}
+
+// writeSession records a window's Claude session the way the SessionStart hook does.
+func (h *e2e) writeSession(t *testing.T, windowID, sessionID, cwd string) {
+	t.Helper()
+	path := filepath.Join(h.cfg.TramuntanaDir, "session_map.json")
+	err := state.ReadModifyWriteSessionMap(path, func(sm map[string]state.SessionMapEntry) {
+		sm[e2eSession+":"+windowID] = state.SessionMapEntry{SessionID: sessionID, CWD: cwd, WindowName: filepath.Base(cwd)}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// appendTranscript appends JSONL lines to a session's transcript.
+func (h *e2e) appendTranscript(t *testing.T, sessionID string, lines ...string) {
+	t.Helper()
+	dir := filepath.Join(h.home, ".claude", "projects", "-work-api")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.OpenFile(filepath.Join(dir, sessionID+".jsonl"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	for _, line := range lines {
+		fmt.Fprintln(f, line)
+	}
+}
+
+func TestE2E_BindStreamToolEditAndWindowDeath(t *testing.T) {
+	h := startE2E(t, "fresh")
+	h.startStatusPoller()
+	windowID := h.tmux.AddWindow("api", "/work/api")
+
+	// An unbound topic gets the window picker; picking binds and forwards the text
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "hello")
+	picker := h.tg.WaitFor("sendMessage", func(c testharness.Call) bool {
+		return strings.Contains(c.Params["reply_markup"], "win_bind:0")
+	})
+	h.tg.PushCallback(e2eChat, e2eThread, e2eUser, picker.MessageID, "win_bind:0")
+	h.tmux.WaitForKeys(windowID, "hello")
+
+	userID, threadID := strconv.FormatInt(e2eUser, 10), strconv.Itoa(e2eThread)
+	if got, ok := h.bot.state.GetWindowForThread(userID, threadID); !ok || got != windowID {
+		t.Fatalf("binding = %q, %v; want %s", got, ok, windowID)
+	}
+
+	// Transcript entries stream to the topic
+	h.writeSession(t, windowID, "sess-1", "/work/api")
+	h.appendTranscript(t, "sess-1",
+		`{"type":"user","message":{"content":"hello"}}`,
+		`{"type":"assistant","message":{"content":[{"type":"text","text":"Looking at the code"}]}}`)
+	text := h.tg.WaitForText("sendMessage", "Looking at the code")
+	if text.Params["message_thread_id"] != threadID {
+		t.Errorf("delivered to thread %q, want %s", text.Params["message_thread_id"], threadID)
+	}
+
+	// A tool result edits its tool_use message in place
+	h.appendTranscript(t, "sess-1",
+		`{"type":"assistant","message":{"content":[{"type":"tool_use","id":"tu_1","name":"Read","input":{"file_path":"/work/api/main.go"}}]}}`)
+	toolUse := h.tg.WaitForText("sendMessage", "Read")
+	h.appendTranscript(t, "sess-1",
+		`{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"tu_1","content":"package main\nfunc main() {}","is_error":false}]}}`)
+	h.tg.WaitFor("editMessageText", func(c testharness.Call) bool {
+		return c.Params["message_id"] == strconv.Itoa(toolUse.MessageID)
+	})
+
+	// The status poller notices the window is gone and the bot unbinds the topic
+	h.tmux.Kill(windowID)
+	h.tg.WaitForText("sendMessage", "Session died. Send a message to restart.")
+	if _, ok := h.bot.state.GetWindowForThread(userID, threadID); ok {
+		t.Error("topic should be unbound after its window died")
+	}
+}
+
+func TestE2E_DeadWindowRecoveryOnMessage(t *testing.T) {
+	h := startE2E(t, "fresh")
+	oldID := h.tmux.AddWindow("api", "/work/api")
+
+	userID, threadID := strconv.FormatInt(e2eUser, 10), strconv.Itoa(e2eThread)
+	h.bot.state.BindThread(userID, threadID, oldID)
+	h.bot.state.SetGroupChatID(userID, threadID, e2eChat)
+	h.bot.state.SetWindowState(oldID, state.WindowState{SessionID: "sess-1", CWD: "/work/api"})
+
+	// The hook reports each new window's session
+	h.tmux.OnNewWindow = func(w testharness.Window) {
+		path := filepath.Join(h.cfg.TramuntanaDir, "session_map.json")
+		err := state.ReadModifyWriteSessionMap(path, func(sm map[string]state.SessionMapEntry) {
+			sm[e2eSession+":"+w.ID] = state.SessionMapEntry{SessionID: "sess-2", CWD: w.CWD}
+		})
+		if err != nil {
+			t.Errorf("writing session map: %v", err) // not on the test goroutine
+		}
+	}
+
+	h.tmux.Kill(oldID)
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "are you there?")
+	h.tg.WaitForText("sendMessage", "Session died. Restarting...")
+
+	// The topic is rebound to a new window in the same directory
+	var newID string
+	deadline := time.Now().Add(10 * time.Second)
+	for newID == "" && time.Now().Before(deadline) {
+		if got, ok := h.bot.state.GetWindowForThread(userID, threadID); ok && got != oldID {
+			newID = got
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	w, ok := h.tmux.Window(newID)
+	if !ok || w.CWD != "/work/api" {
+		t.Fatalf("topic not rebound to a new window in /work/api: %q %+v", newID, h.tmux.Windows())
+	}
+	h.tmux.WaitForKeys(newID, "are you there?")
+	if ws, _ := h.bot.state.GetWindowState(newID); ws.SessionID != "sess-2" {
+		t.Errorf("new window session = %q, want sess-2", ws.SessionID)
+	}
+}
+
+// startRecovery kills a bound window whose replacement starts with a bare
+// shell, sends text to its topic and returns the new window's ID.
+func startRecovery(t *testing.T, h *e2e, text string) string {
+	t.Helper()
+	oldID := h.tmux.AddWindow("api", "/work/api")
+	userID, threadID := strconv.FormatInt(e2eUser, 10), strconv.Itoa(e2eThread)
+	h.bot.state.BindThread(userID, threadID, oldID)
+	h.bot.state.SetGroupChatID(userID, threadID, e2eChat)
+	h.bot.state.SetWindowState(oldID, state.WindowState{SessionID: "sess-1", CWD: "/work/api"})
+	h.tmux.OnNewWindow = func(w testharness.Window) {
+		h.tmux.SetPane(w.ID, "$ ")
+		h.writeSession(t, w.ID, "sess-2", w.CWD)
+	}
+
+	h.tmux.Kill(oldID)
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, text)
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		if got, ok := h.bot.state.GetWindowForThread(userID, threadID); ok && got != oldID {
+			return got
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("topic not rebound to a new window")
+	return ""
+}
+
+func TestE2E_HeldTextWaitsForReady(t *testing.T) {
+	setReadyWait(t, 100*time.Millisecond, 10*time.Second)
+	h := startE2E(t, "fresh")
+	newID := startRecovery(t, h, "are you there?")
+
+	h.tg.WaitForText("sendMessage", "Claude is still starting")
+	if w, _ := h.tmux.Window(newID); slices.Contains(w.Keys, "are you there?") {
+		t.Fatalf("typed the message before Claude was ready: %q", w.Keys)
+	}
+	// The bot keeps handling updates while it waits
+	h.tg.PushMessage(e2eChat, e2eThread+1, e2eUser, "/labels")
+	h.tg.WaitForText("sendMessage", "Labels in this topic")
+	h.tmux.SetPane(newID, testharness.ReadyPane)
+	h.tmux.WaitForKeys(newID, "are you there?")
+	h.tg.WaitForText("editMessageText", "your message was sent")
+}
+
+func TestE2E_HeldTextDroppedOnTimeout(t *testing.T) {
+	setReadyWait(t, 100*time.Millisecond, time.Second)
+	h := startE2E(t, "fresh")
+	newID := startRecovery(t, h, "are you there?")
+
+	h.tg.WaitForText("editMessageText", "your message wasn't sent")
+	if w, _ := h.tmux.Window(newID); slices.Contains(w.Keys, "are you there?") {
+		t.Errorf("typed the message into a window that never became ready: %q", w.Keys)
+	}
+}
+
+func TestE2E_LostTypingIsRetyped(t *testing.T) {
+	h := startE2E(t, "fresh")
+	windowID := h.tmux.AddWindow("api", "/work/api")
+	userID, threadID := strconv.FormatInt(e2eUser, 10), strconv.Itoa(e2eThread)
+	h.bot.state.BindThread(userID, threadID, windowID)
+	h.bot.state.SetGroupChatID(userID, threadID, e2eChat)
+	h.tmux.DropTyping(windowID, 1)
+
+	text := "rename it to \"parse\"; then run go test 🚀"
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, text)
+	h.tmux.WaitForKeys(windowID, "Enter")
+	w, _ := h.tmux.Window(windowID)
+	if got := strings.Join(w.Keys, " | "); got != "BSpace | "+text+" | Enter" {
+		t.Errorf("keys = %s", got)
+	}
+}
+
+func TestE2E_MultiLineMessageIsPasted(t *testing.T) {
+	h := startE2E(t, "fresh")
+	windowID := h.tmux.AddWindow("api", "/work/api")
+	userID, threadID := strconv.FormatInt(e2eUser, 10), strconv.Itoa(e2eThread)
+	h.bot.state.BindThread(userID, threadID, windowID)
+	h.bot.state.SetGroupChatID(userID, threadID, e2eChat)
+
+	text := "why does this fail?\n\nfunc f() {\n\treturn nil\n}"
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, text)
+	h.tmux.WaitForKeys(windowID, "Enter")
+	w, _ := h.tmux.Window(windowID)
+	if got := strings.Join(w.Keys, " | "); got != text+" | Enter" {
+		t.Errorf("keys = %q", got)
+	}
+}
+
+func setReadyWait(t *testing.T, notice, timeout time.Duration) {
+	t.Helper()
+	prevNotice, prevTimeout := readyNotice, readyTimeout
+	readyNotice, readyTimeout = notice, timeout
+	t.Cleanup(func() { readyNotice, readyTimeout = prevNotice, prevTimeout })
+}
+
+func TestE2E_ObservedTopicRejectsInput(t *testing.T) {
+	h := startE2E(t, "fresh")
+	windowID := h.tmux.AddWindow("api", "/work/api")
+
+	userID, threadID := strconv.FormatInt(e2eUser, 10), strconv.Itoa(e2eThread)
+	h.bot.state.BindThread(userID, threadID, windowID)
+	h.bot.state.SetGroupChatID(userID, threadID, e2eChat)
+	h.bot.state.SetObserved(threadID, true)
+
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "rm -rf everything")
+	h.tg.WaitForText("sendMessage", "observe mode")
+	if w, _ := h.tmux.Window(windowID); len(w.Keys) != 0 {
+		t.Errorf("observed topic typed into tmux: %q", w.Keys)
+	}
+
+	// Navigation and screenshot keys are refused too
+	for _, data := range []string{"nav_enter", formatSSCallback("esc", windowID)} {
+		h.tg.PushCallback(e2eChat, e2eThread, e2eUser, 1, data)
+		h.tg.WaitFor("answerCallbackQuery", func(c testharness.Call) bool {
+			return c.Params["show_alert"] == "true" && strings.Contains(c.Params["text"], "observe mode")
+		})
+	}
+	if w, _ := h.tmux.Window(windowID); len(w.Keys) != 0 {
+		t.Errorf("observed topic pressed keys in tmux: %q", w.Keys)
+	}
+
+	// Output still streams to the topic
+	h.writeSession(t, windowID, "sess-1", "/work/api")
+	h.appendTranscript(t, "sess-1",
+		`{"type":"assistant","message":{"content":[{"type":"text","text":"Still broadcasting"}]}}`)
+	h.tg.WaitForText("sendMessage", "Still broadcasting")
+}
+
+func TestE2E_ArtifactOfferedAndSent(t *testing.T) {
+	h := startE2E(t, "fresh")
+	h.cfg.ArtifactPatterns = []string{"*.png"}
+	windowID := h.tmux.AddWindow("api", "/work/api")
+
+	userID, threadID := strconv.FormatInt(e2eUser, 10), strconv.Itoa(e2eThread)
+	h.bot.state.BindThread(userID, threadID, windowID)
+	h.bot.state.SetGroupChatID(userID, threadID, e2eChat)
+
+	chart := filepath.Join(h.home, "chart.png")
+	if err := os.WriteFile(chart, []byte("png"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	h.writeSession(t, windowID, "sess-1", "/work/api")
+	h.appendTranscript(t, "sess-1",
+		`{"type":"assistant","message":{"content":[{"type":"tool_use","id":"tu_1","name":"Write","input":{"file_path":"`+chart+`"}}]}}`,
+		`{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"tu_1","content":"ok","is_error":false}]}}`)
+
+	offer := h.tg.WaitFor("sendMessage", func(c testharness.Call) bool {
+		return strings.Contains(c.Params["reply_markup"], "art_send:")
+	})
+	var data string
+	if i := strings.Index(offer.Params["reply_markup"], "art_send:"); i >= 0 {
+		data = offer.Params["reply_markup"][i:]
+		data = data[:strings.IndexByte(data, '"')]
+	}
+	h.tg.PushCallback(e2eChat, e2eThread, e2eUser, offer.MessageID, data)
+	h.tg.WaitFor("sendDocument", func(c testharness.Call) bool {
+		return c.Params["message_thread_id"] == threadID
+	})
+}
+
+func TestE2E_LongPasteModes(t *testing.T) {
+	h := startE2E(t, "fresh")
+	h.cfg.LongPasteChars = 50
+	windowID := h.tmux.AddWindow("api", "/work/api")
+
+	userID, threadID := strconv.FormatInt(e2eUser, 10), strconv.Itoa(e2eThread)
+	h.bot.state.BindThread(userID, threadID, windowID)
+	h.bot.state.SetGroupChatID(userID, threadID, e2eChat)
+
+	trace := "panic: boom\n" + strings.Repeat("\tat frame()\n", 10)
+
+	// buffer: pasted as one block, then Enter
+	h.cfg.LongPasteMode = "buffer"
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, trace)
+	h.tmux.WaitForKeys(windowID, trace)
+
+	// file: Claude is pointed at a temp file holding the message
+	h.cfg.LongPasteMode = "file"
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, trace+"again")
+	var ref string
+	deadline := time.Now().Add(5 * time.Second)
+	for ref == "" && time.Now().Before(deadline) {
+		w, _ := h.tmux.Window(windowID)
+		for _, k := range w.Keys {
+			if strings.Contains(k, "tramuntana-paste-") {
+				ref = k
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	start := strings.Index(ref, "/")
+	end := strings.Index(ref, ".txt")
+	if start < 0 || end < 0 {
+		t.Fatalf("no temp file reference typed: %q", ref)
+	}
+	path := ref[start : end+len(".txt")]
+	defer os.Remove(path)
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != trace+"again" {
+		t.Errorf("temp file = %q, %v", data, err)
+	}
+
+	// short messages are still typed directly
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "short")
+	h.tmux.WaitForKeys(windowID, "short")
+}
+
+func TestE2E_ComposeDraft(t *testing.T) {
+	h := startE2E(t, "fresh")
+	windowID := h.tmux.AddWindow("api", "/work/api")
+
+	userID, threadID := strconv.FormatInt(e2eUser, 10), strconv.Itoa(e2eThread)
+	h.bot.state.BindThread(userID, threadID, windowID)
+	h.bot.state.SetGroupChatID(userID, threadID, e2eChat)
+
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "/compose")
+	preview := h.tg.WaitForText("sendMessage", "Composing")
+	// A draft in another topic doesn't replace this one
+	otherWindow := h.tmux.AddWindow("web", "/work/web")
+	h.bot.state.BindThread(userID, strconv.Itoa(e2eThread+1), otherWindow)
+	h.tg.PushMessage(e2eChat, e2eThread+1, e2eUser, "/compose")
+	h.tg.WaitFor("sendMessage", func(c testharness.Call) bool {
+		return strings.Contains(c.Params["text"], "Composing") && c.Params["message_thread_id"] == strconv.Itoa(e2eThread+1)
+	})
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "First paragraph.")
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "Second paragraph.")
+	h.tg.WaitFor("editMessageText", func(c testharness.Call) bool {
+		return strings.Contains(c.Params["text"], "Second paragraph.")
+	})
+	if w, _ := h.tmux.Window(windowID); len(w.Keys) != 0 {
+		t.Fatalf("draft leaked into tmux: %q", w.Keys)
+	}
+
+	h.tg.PushCallback(e2eChat, e2eThread, e2eUser, preview.MessageID, "compose_send")
+	h.tmux.WaitForKeys(windowID, "First paragraph.\n\nSecond paragraph.")
+	h.tg.WaitForText("editMessageText", "Sent draft (2 messages")
+	if w, _ := h.tmux.Window(otherWindow); len(w.Keys) != 0 {
+		t.Errorf("draft sent into the other topic's window: %q", w.Keys)
+	}
+
+	// After sending, messages go straight to Claude again
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "direct")
+	h.tmux.WaitForKeys(windowID, "direct")
+}
+
+func TestE2E_AccessRequestApproved(t *testing.T) {
+	h := startE2E(t, "fresh")
+	h.cfg.AccessRequests = true
+	h.cfg.AdminChatID = e2eUser
+	const stranger = int64(555)
+
+	h.tg.PushMessage(e2eChat, e2eThread, stranger, "hi")
+	offer := h.tg.WaitForText("sendMessage", "not authorized")
+	if offer.Params["reply_markup"] == "" {
+		t.Fatal("expected a Request access button")
+	}
+	// Further messages don't repeat the offer
+	h.tg.PushMessage(e2eChat, e2eThread, stranger, "hello?")
+
+	h.tg.PushCallback(e2eChat, e2eThread, stranger, offer.MessageID, "access_req")
+	request := h.tg.WaitForText("sendMessage", "Access request from")
+	if request.Params["chat_id"] != strconv.FormatInt(e2eUser, 10) {
+		t.Errorf("request sent to chat %s, want the admin chat", request.Params["chat_id"])
+	}
+	if n := len(h.tg.Calls("sendMessage")); n != 2 {
+		t.Errorf("sent %d messages, want the offer and the request", n)
+	}
+
+	data := fmt.Sprintf("access_ok:%d:%d:%d", stranger, e2eChat, e2eThread)
+	h.tg.PushCallback(e2eUser, 0, e2eUser, request.MessageID, data)
+	h.tg.WaitForText("sendMessage", "Access granted")
+	if !h.bot.isAuthorized(stranger, e2eChat) {
+		t.Error("approved user should be authorized")
+	}
+	if grant := h.bot.state.AccessGrants()[strconv.FormatInt(stranger, 10)]; grant.Name != "User555" || grant.GrantedBy != "User100" {
+		t.Errorf("grant = %+v", grant)
+	}
+}
+
+func TestE2E_DeletedTopicForgottenOnSendError(t *testing.T) {
+	h := startE2E(t, "fresh")
+	windowID := h.tmux.AddWindow("api", "/work/api")
+
+	userID, threadID := strconv.FormatInt(e2eUser, 10), strconv.Itoa(e2eThread)
+	h.bot.state.BindThread(userID, threadID, windowID)
+	h.bot.state.SetGroupChatID(userID, threadID, e2eChat)
+	h.bot.state.SetObserved(threadID, true)
+	// The same thread ID in another chat is a different topic
+	h.bot.state.BindThread("200", threadID, "@9")
+	h.bot.state.SetGroupChatID("200", threadID, -2002)
+
+	h.tg.DeleteTopic(e2eChat, e2eThread)
+	h.writeSession(t, windowID, "sess-1", "/work/api")
+	h.appendTranscript(t, "sess-1",
+		`{"type":"assistant","message":{"content":[{"type":"text","text":"Nobody is listening"}]}}`)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_, bound := h.bot.state.GetWindowForThread(userID, threadID)
+		if !bound && !h.bot.state.IsObserved(threadID) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("deleted topic still bound=%v observed=%v", bound, h.bot.state.IsObserved(threadID))
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if _, ok := h.tmux.Window(windowID); ok {
+		t.Error("deleted topic's window not killed")
+	}
+	if _, bound := h.bot.state.GetWindowForThread("200", threadID); !bound {
+		t.Error("topic with the same thread ID in another chat was unbound")
+	}
+}
+
+func TestE2E_WindowNameAndTopicStateSync(t *testing.T) {
+	h := startE2E(t, "fresh")
+	h.cfg.WindowNameTemplate = "{{.Project}} ({{.Dir}})"
+	windowID := h.tmux.AddWindow("claude", "/work/api")
+
+	userID, threadID := strconv.FormatInt(e2eUser, 10), strconv.Itoa(e2eThread)
+	h.bot.state.BindThread(userID, threadID, windowID)
+	h.bot.state.SetGroupChatID(userID, threadID, e2eChat)
+	h.bot.state.SetWindowState(windowID, state.WindowState{CWD: "/work/api"})
+	h.tmux.SetPane(windowID, "✻ Reading file.go\n"+strings.Repeat("─", 40)+"\n> \n")
+	h.startStatusPoller()
+
+	h.tg.WaitFor("editForumTopic", func(c testharness.Call) bool {
+		return c.Params["name"] == "🔵 api (/work/api)" && c.Params["message_thread_id"] == threadID
+	})
+	if w, _ := h.tmux.Window(windowID); w.Name != "api (/work/api)" {
+		t.Errorf("tmux window name = %q", w.Name)
+	}
+
+	// The status line clears: the topic turns idle
+	h.tmux.SetPane(windowID, testharness.ReadyPane)
+	h.tg.WaitFor("editForumTopic", func(c testharness.Call) bool {
+		return c.Params["name"] == "🟢 api (/work/api)"
+	})
+}
+
+func TestE2E_DirectoryChangeUpdatesCurrentDir(t *testing.T) {
+	h := startE2E(t, "fresh")
+	windowID := h.tmux.AddWindow("api", "/work/api")
+
+	userID, threadID := strconv.FormatInt(e2eUser, 10), strconv.Itoa(e2eThread)
+	h.bot.state.BindThread(userID, threadID, windowID)
+	h.bot.state.SetGroupChatID(userID, threadID, e2eChat)
+	h.bot.state.SetWindowState(windowID, state.WindowState{SessionID: "s1", CWD: "/work/api"})
+	h.tmux.SetPane(windowID, testharness.ReadyPane)
+	h.startStatusPoller()
+
+	h.tmux.SetCWD(windowID, "/work/api/web")
+	h.tg.WaitFor("sendMessage", func(c testharness.Call) bool {
+		return strings.Contains(c.Params["text"], "/work/api/web") && c.Params["message_thread_id"] == threadID
+	})
+	// The launch directory stays, for resuming the session
+	if ws, _ := h.bot.state.GetWindowState(windowID); ws.Dir() != "/work/api/web" || ws.CWD != "/work/api" || ws.SessionID != "s1" {
+		t.Errorf("window state = %+v", ws)
+	}
+}
+
+func TestE2E_TopicIconFollowsSessionState(t *testing.T) {
+	h := startE2E(t, "fresh")
+	h.cfg.TopicIcons = config.DefaultTopicIcons
+	windowID := h.tmux.AddWindow("api", "/work/api")
+
+	userID, threadID := strconv.FormatInt(e2eUser, 10), strconv.Itoa(e2eThread)
+	h.bot.state.BindThread(userID, threadID, windowID)
+	h.bot.state.SetGroupChatID(userID, threadID, e2eChat)
+	h.tmux.SetPane(windowID, "✻ Reading file.go\n"+strings.Repeat("─", 40)+"\n> \n")
+	h.startStatusPoller()
+
+	working := h.tg.WaitFor("editForumTopic", func(c testharness.Call) bool {
+		return c.Params["icon_custom_emoji_id"] == "icon-working"
+	})
+	if _, renamed := working.Params["name"]; renamed {
+		t.Error("icons alone should not rename the topic")
+	}
+
+	h.tmux.SetPane(windowID, testharness.ReadyPane)
+	h.tg.WaitFor("editForumTopic", func(c testharness.Call) bool {
+		return c.Params["icon_custom_emoji_id"] == "icon-idle"
+	})
+
+	h.tmux.Kill(windowID)
+	h.tg.WaitFor("editForumTopic", func(c testharness.Call) bool {
+		return c.Params["icon_custom_emoji_id"] == "icon-dead" && c.Params["message_thread_id"] == threadID
+	})
+	if n := len(h.tg.Calls("getForumTopicIconStickers")); n != 1 {
+		t.Errorf("fetched topic icons %d times, want once", n)
+	}
+}
+
+func TestE2E_TopicIconsRetriedAfterFailedFetch(t *testing.T) {
+	h := startE2E(t, "fresh")
+	h.cfg.TopicIcons = config.DefaultTopicIcons
+	h.tg.Flood("getForumTopicIconStickers", 1)
+
+	if _, ok := h.bot.topicIconID(stateIdle); ok {
+		t.Fatal("icon found although fetching failed")
+	}
+	// Within the backoff nothing is fetched; a fetch now would succeed
+	if _, ok := h.bot.topicIconID(stateIdle); ok {
+		t.Fatal("icons fetched again before the retry")
+	}
+
+	h.bot.mu.Lock()
+	h.bot.topicIconsRetryAt = time.Time{}
+	h.bot.mu.Unlock()
+	if id, ok := h.bot.topicIconID(stateIdle); !ok || id != "icon-idle" {
+		t.Errorf("icon after retry = %q, %v", id, ok)
+	}
+}
+
+func TestE2E_NeedsAttentionSummary(t *testing.T) {
+	h := startE2E(t, "fresh")
+	h.cfg.AllowedGroups = []int64{e2eChat}
+	h.cfg.AttentionTopicID = 99
+	permission := "Do you want to proceed?\nAllow this action?\nEsc to cancel\n"
+
+	userID := strconv.FormatInt(e2eUser, 10)
+	var windows []string
+	for i, name := range []string{"api", "web"} {
+		windowID := h.tmux.AddWindow(name, "/work/"+name)
+		threadID := strconv.Itoa(e2eThread + i)
+		h.bot.state.BindThread(userID, threadID, windowID)
+		h.bot.state.SetGroupChatID(userID, threadID, e2eChat)
+		h.bot.state.SetWindowDisplayName(windowID, name)
+		h.tmux.SetPane(windowID, testharness.ReadyPane)
+		windows = append(windows, windowID)
+	}
+	h.startStatusPoller()
+
+	// One waiting session is left to its own topic
+	h.tmux.SetPane(windows[0], permission)
+	h.tg.WaitForText("sendMessage", "Do you want to proceed?")
+	h.tmux.SetPane(windows[1], permission)
+	summary := h.tg.WaitForText("sendMessage", "2 sessions need attention")
+	if summary.Params["message_thread_id"] != "99" || !strings.Contains(summary.Params["text"], "api — permission") {
+		t.Errorf("summary = %v", summary.Params)
+	}
+
+	h.tmux.SetPane(windows[0], testharness.ReadyPane)
+	h.tg.WaitFor("editMessageText", func(c testharness.Call) bool {
+		return c.Params["message_id"] == strconv.Itoa(summary.MessageID) && strings.Contains(c.Params["text"], "1 session needs attention")
+	})
+	h.tmux.SetPane(windows[1], testharness.ReadyPane)
+	h.tg.WaitFor("editMessageText", func(c testharness.Call) bool {
+		return c.Params["message_id"] == strconv.Itoa(summary.MessageID) && strings.Contains(c.Params["text"], "No sessions are waiting")
+	})
+}
+
+func TestE2E_DeepLinkRepliesWithTopicLink(t *testing.T) {
+	h := startE2E(t, "fresh")
+
+	link := h.bot.deepLink(-1001234567890, e2eThread)
+	if link != "https://t.me/test_bot?start=topic_-1001234567890_42" {
+		t.Fatalf("deep link = %q", link)
+	}
+	_, payload, _ := strings.Cut(link, "?start=")
+
+	h.tg.PushMessage(e2eUser, 0, e2eUser, "/start "+payload)
+	reply := h.tg.WaitForText("sendMessage", "Topic: https://t.me/c/1234567890/42")
+	if !strings.Contains(reply.Params["reply_markup"], "Open topic") {
+		t.Errorf("reply_markup = %s, want an Open topic button", reply.Params["reply_markup"])
+	}
+}
+
+func TestE2E_CompactionDividerAndSessionSwitch(t *testing.T) {
+	h := startE2E(t, "fresh")
+	windowID := h.tmux.AddWindow("api", "/work/api")
+	userID, threadID := strconv.FormatInt(e2eUser, 10), strconv.Itoa(e2eThread)
+	h.bot.state.BindThread(userID, threadID, windowID)
+	h.bot.state.SetGroupChatID(userID, threadID, e2eChat)
+
+	h.writeSession(t, windowID, "sess-1", "/work/api")
+	h.appendTranscript(t, "sess-1",
+		`{"type":"assistant","message":{"content":[{"type":"text","text":"Before compaction"}]}}`,
+		`{"type":"system","subtype":"compact_boundary","compactMetadata":{"trigger":"auto","preTokens":155000}}`,
+		`{"type":"user","isCompactSummary":true,"message":{"content":"This session is being continued from a previous conversation"}}`)
+	h.tg.WaitForText("sendMessage", "— context compacted \\(auto\\) —")
+
+	// The window moves to a new session: its transcript is read from the start
+	h.writeSession(t, windowID, "sess-2", "/work/api")
+	h.appendTranscript(t, "sess-2",
+		`{"type":"assistant","message":{"content":[{"type":"text","text":"In the new session"}]}}`)
+	h.tg.WaitForText("sendMessage", "In the new session")
+
+	for _, c := range h.tg.Calls("sendMessage") {
+		if strings.Contains(c.Params["text"], "continued from a previous conversation") {
+			t.Errorf("compact summary delivered: %q", c.Params["text"])
+		}
+	}
+}
+
+func TestE2E_SessionClearedInTerminal(t *testing.T) {
+	h := startE2E(t, "fresh")
+	windowID := h.tmux.AddWindow("api", "/work/api")
+	userID, threadID := strconv.FormatInt(e2eUser, 10), strconv.Itoa(e2eThread)
+	h.bot.state.BindThread(userID, threadID, windowID)
+	h.bot.state.SetGroupChatID(userID, threadID, e2eChat)
+
+	h.writeSession(t, windowID, "sess-1", "/work/api")
+	h.appendTranscript(t, "sess-1",
+		`{"type":"assistant","message":{"content":[{"type":"text","text":"Old conversation"}]}}`)
+	h.tg.WaitForText("sendMessage", "Old conversation")
+
+	// /clear typed in the terminal: only the hook reports the new session
+	h.writeSession(t, windowID, "sess-2", "/work/api")
+	h.tg.WaitForText("sendMessage", "Session cleared")
+	h.appendTranscript(t, "sess-2",
+		`{"type":"assistant","message":{"content":[{"type":"text","text":"Fresh start"}]}}`)
+	h.tg.WaitForText("sendMessage", "Fresh start")
+}
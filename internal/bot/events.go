@@ -0,0 +1,104 @@
+package bot
+
+import (
+	"context"
+	"log"
+	"strconv"
+
+	"github.com/otaviocarvalho/tramuntana/internal/events"
+)
+
+// eventSubs are the bus channels the bot consumes.
+type eventSubs struct {
+	windowDead    <-chan events.WindowDead
+	turnStarted   <-chan events.TurnStarted
+	turnCompleted <-chan events.TurnCompleted
+	interactiveUI <-chan events.InteractiveUIDetected
+}
+
+// SetEventBus subscribes the bot to the events it handles. Call before starting
+// the publishers; RunEventHandlers consumes the subscriptions.
+func (b *Bot) SetEventBus(bus *events.Bus) {
+	b.events = &eventSubs{
+		windowDead:    bus.WindowDead.Subscribe(),
+		turnStarted:   bus.TurnStarted.Subscribe(),
+		turnCompleted: bus.TurnCompleted.Subscribe(),
+		interactiveUI: bus.InteractiveUIDetected.Subscribe(),
+	}
+}
+
+// RunEventHandlers handles bus events one at a time until ctx is cancelled.
+func (b *Bot) RunEventHandlers(ctx context.Context) {
+	if b.events == nil {
+		return
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-b.events.windowDead:
+			b.handleWindowDead(ev)
+		case ev := <-b.events.turnStarted:
+			b.feedTurnStarted(ev)
+		case ev := <-b.events.turnCompleted:
+			b.handleTurnCompleted(ev)
+		case ev := <-b.events.interactiveUI:
+			b.showInteractiveUI(ev.ChatID, ev.ThreadID, ev.UserID, ev.WindowID, ev.PaneText)
+		}
+	}
+}
+
+// topicTarget is a forum topic to notify.
+type topicTarget struct {
+	chatID   int64
+	threadID int
+}
+
+// handleWindowDead unbinds a vanished window and tells its topics.
+func (b *Bot) handleWindowDead(ev events.WindowDead) {
+	users := b.state.FindUsersForWindow(ev.WindowID)
+	if len(users) == 0 {
+		return // already cleaned up by an earlier event
+	}
+	log.Printf("Window %s is dead, cleaning up", ev.WindowID)
+
+	// Save chat IDs and the name before cleanup removes them
+	name, _ := b.state.GetWindowDisplayName(ev.WindowID)
+	var targets []topicTarget
+	for _, ut := range users {
+		uid, _ := strconv.ParseInt(ut.UserID, 10, 64)
+		tid, _ := strconv.Atoi(ut.ThreadID)
+		if cid, ok := b.state.GetGroupChatID(ut.UserID, ut.ThreadID); ok {
+			targets = append(targets, topicTarget{cid, tid})
+		}
+		cancelBashCapture(uid, tid)
+		clearInteractiveUI(uid, tid)
+	}
+	b.feedError(ev.WindowID, "session died")
+	pending := b.captureArchive(ev.WindowID, "died", uniqueThreadIDs(users))
+	cleanupDeadWindow(b, ev.WindowID)
+	go b.archiveSession(pending)
+	b.markTopicsDead(name, targets)
+	for _, t := range targets {
+		b.reply(t.chatID, t.threadID, "Session died. Send a message to restart.")
+	}
+}
+
+// handleTurnCompleted records a finished turn, closes any progress trackers,
+// deletes the prompt files the turn read and summarizes the turn for TRAMUNTANA_NOW_SUMMARY and the feed.
+func (b *Bot) handleTurnCompleted(ev events.TurnCompleted) {
+	b.usage.RecordTurn(ev.WindowID, ev.Elapsed)
+	b.finishAutoLoop(ev.WindowID)
+	b.finishBatchProgress(ev.WindowID)
+	b.finishPickw(ev.WindowID, ev.Elapsed)
+	releasePromptFiles(ev.WindowID)
+
+	var summary string
+	if b.config.NowSummary != "" || b.config.FeedTopicID != 0 {
+		summary = summarizeTurn(takeAssistantText(ev.WindowID))
+	}
+	if summary != "" {
+		b.showTurnSummary(ev.WindowID, summary)
+	}
+	b.feedTurnCompleted(ev, summary)
+}
@@ -0,0 +1,106 @@
+package bot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/otaviocarvalho/tramuntana/internal/events"
+	"github.com/otaviocarvalho/tramuntana/internal/monitor"
+	"github.com/otaviocarvalho/tramuntana/internal/queue"
+)
+
+// Windows whose current turn the feed has announced, so a prompt seen by
+// several bound users starts one turn.
+var (
+	feedTurns   = make(map[string]bool) // windowID → turn in progress
+	feedTurnsMu sync.Mutex
+)
+
+// formatFeedLine is one feed event: time, icon, the session (linked to its
+// topic when there is a link) and what happened.
+func formatFeedLine(at time.Time, icon, name, link, text string) string {
+	if link != "" {
+		name = "[" + name + "](" + link + ")"
+	}
+	return fmt.Sprintf("%s %s %s %s", at.Format("15:04"), icon, name, text)
+}
+
+// postFeed queues a one-line event about a window for the feed topic.
+func (b *Bot) postFeed(windowID, icon, text string) {
+	if b.config.FeedTopicID == 0 || b.msgQueue == nil {
+		return
+	}
+	feedTopic := int(b.config.FeedTopicID)
+	chatID := b.findChatIDForTopic(feedTopic)
+	if chatID == 0 {
+		return
+	}
+
+	name, _ := b.state.GetWindowDisplayName(windowID)
+	if name == "" {
+		name = windowID
+	}
+	var link string
+	for _, ut := range b.state.FindUsersForWindow(windowID) {
+		cid, ok := b.state.GetGroupChatID(ut.UserID, ut.ThreadID)
+		threadID, _ := strconv.Atoi(ut.ThreadID)
+		if ok && threadID != feedTopic {
+			if link = b.topicLink(cid, threadID); link != "" {
+				break
+			}
+		}
+	}
+
+	b.msgQueue.Enqueue(queue.MessageTask{
+		ChatID:      chatID,
+		ThreadID:    feedTopic,
+		Parts:       []string{formatFeedLine(time.Now(), icon, name, link, text)},
+		ContentType: "feed",
+	})
+}
+
+// feedTurnStarted posts a turn's start once.
+func (b *Bot) feedTurnStarted(ev events.TurnStarted) {
+	feedTurnsMu.Lock()
+	started := feedTurns[ev.WindowID]
+	feedTurns[ev.WindowID] = true
+	feedTurnsMu.Unlock()
+	if !started {
+		b.postFeed(ev.WindowID, "▶️", "started a turn")
+	}
+}
+
+// feedTurnCompleted posts a finished turn with its summary, if any.
+func (b *Bot) feedTurnCompleted(ev events.TurnCompleted, summary string) {
+	feedTurnsMu.Lock()
+	delete(feedTurns, ev.WindowID)
+	feedTurnsMu.Unlock()
+	text := "finished after " + ev.Elapsed.Round(time.Second).String()
+	if summary != "" {
+		text += " · " + summary
+	}
+	b.postFeed(ev.WindowID, "✅", text)
+}
+
+// feedTranscript posts Minuano tasks marked done in parsed.
+func (b *Bot) feedTranscript(windowID string, parsed []monitor.ParsedEntry) {
+	for _, pe := range parsed {
+		if pe.ContentType != "tool_result" || pe.ToolName != "Bash" || pe.IsError {
+			continue
+		}
+		if taskID := monitor.DoneTaskID(pe.ToolInput); taskID != "" {
+			b.postFeed(windowID, "🏁", "completed task "+taskID)
+		}
+	}
+}
+
+// feedError posts a session problem, e.g. Claude exiting.
+func (b *Bot) feedError(windowID, text string) {
+	feedTurnsMu.Lock()
+	delete(feedTurns, windowID)
+	feedTurnsMu.Unlock()
+	b.postFeed(windowID, "⚠️", strings.TrimSpace(text))
+}
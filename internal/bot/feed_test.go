@@ -0,0 +1,62 @@
+package bot
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/otaviocarvalho/tramuntana/internal/events"
+	"github.com/otaviocarvalho/tramuntana/internal/testharness"
+)
+
+func TestFormatFeedLine(t *testing.T) {
+	at := time.Date(2026, 3, 1, 14, 2, 0, 0, time.UTC)
+	if got := formatFeedLine(at, "✅", "api", "https://t.me/c/123/42", "finished after 3m0s"); got != "14:02 ✅ [api](https://t.me/c/123/42) finished after 3m0s" {
+		t.Errorf("linked line = %q", got)
+	}
+	if got := formatFeedLine(at, "▶️", "api", "", "started a turn"); got != "14:02 ▶️ api started a turn" {
+		t.Errorf("plain line = %q", got)
+	}
+}
+
+func TestE2E_FeedTopic(t *testing.T) {
+	h := startE2E(t, "fresh")
+	h.cfg.AllowedGroups = []int64{e2eChat}
+	h.cfg.FeedTopicID = 99
+
+	windowID := h.tmux.AddWindow("api", "/work/api")
+	userID, threadID := strconv.FormatInt(e2eUser, 10), strconv.Itoa(e2eThread)
+	h.bot.state.BindThread(userID, threadID, windowID)
+	h.bot.state.SetGroupChatID(userID, threadID, e2eChat)
+	h.bot.state.SetWindowDisplayName(windowID, "api")
+	h.writeSession(t, windowID, "sess-1", "/work/api")
+
+	h.appendTranscript(t, "sess-1",
+		`{"type":"user","message":{"content":"fix the login redirect"}}`,
+		`{"type":"assistant","message":{"content":[{"type":"tool_use","id":"tu_1","name":"Bash","input":{"command":"minuano-done T-7 'fixed'"}}]}}`,
+		`{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"tu_1","content":"ok","is_error":false}]}}`,
+		`{"type":"assistant","message":{"content":[{"type":"text","text":"Fixed the login redirect. The callback now keeps the return URL."}]}}`)
+	h.tg.WaitForText("sendMessage", "Fixed the login redirect")
+	h.bus.TurnCompleted.Publish(events.TurnCompleted{WindowID: windowID, Elapsed: 90 * time.Second})
+
+	feed := h.tg.WaitFor("sendMessage", func(c testharness.Call) bool {
+		return c.Params["message_thread_id"] == "99" && strings.Contains(c.Params["text"], "finished after 1m30s")
+	})
+	if text := feed.Params["text"]; !strings.Contains(text, "api finished after 1m30s · Fixed the login redirect") {
+		t.Errorf("feed text = %q", text)
+	}
+	var lines []string
+	for _, c := range h.tg.Calls("sendMessage") {
+		if c.Params["message_thread_id"] == "99" {
+			lines = append(lines, c.Params["text"])
+		}
+	}
+	all := strings.Join(lines, "\n")
+	if strings.Count(all, "started a turn") != 1 || !strings.Contains(all, "completed task T\\-7") {
+		t.Errorf("feed = %q, want one turn start and the completed task", all)
+	}
+	if feed.Params["disable_notification"] != "true" {
+		t.Error("feed messages should be silent")
+	}
+}
@@ -27,11 +27,13 @@ type FileBrowseState struct {
 	MessageID   int
 	ChatID      int64
 	ThreadID    int
+	PerPage     int // entries per page
 }
 
 // showFileBrowser sends the file browser keyboard to the user.
 func (b *Bot) showFileBrowser(chatID int64, threadID int, userID int64, startPath string) {
-	text, keyboard, entries := buildFileBrowser(startPath, 0)
+	perPage := b.browserPageSize(userID, filesPerPage)
+	text, keyboard, entries := buildFileBrowser(startPath, 0, perPage, b.allowedRoots())
 
 	msg, err := b.sendMessageWithKeyboard(chatID, threadID, text, keyboard)
 	if err != nil {
@@ -40,22 +42,24 @@ func (b *Bot) showFileBrowser(chatID int64, threadID int, userID int64, startPat
 	}
 
 	b.mu.Lock()
-	b.fileBrowseStates[userID] = &FileBrowseState{
+	b.fileBrowseStates.set(wizardKey{UserID: userID, ChatID: chatID, ThreadID: threadID}, &FileBrowseState{
 		CurrentPath: startPath,
 		Page:        0,
 		Entries:     entries,
 		MessageID:   msg.MessageID,
 		ChatID:      chatID,
 		ThreadID:    threadID,
-	}
+		PerPage:     perPage,
+	})
 	b.mu.Unlock()
 }
 
-// buildFileBrowser builds the inline keyboard for file browsing.
-// Returns the display text, keyboard markup, and cached entries.
-func buildFileBrowser(currentPath string, page int) (string, tgbotapi.InlineKeyboardMarkup, []fileBrowseEntry) {
+// buildFileBrowser builds the inline keyboard for file browsing, limited to
+// entries inside roots. Returns the display text, keyboard markup, and cached
+// entries.
+func buildFileBrowser(currentPath string, page, perPage int, roots []string) (string, tgbotapi.InlineKeyboardMarkup, []fileBrowseEntry) {
 	dirEntries, err := os.ReadDir(currentPath)
-	if err != nil {
+	if err != nil || !withinRoots(currentPath, roots) {
 		return fmt.Sprintf("Error reading %s", shortenPath(currentPath)), tgbotapi.NewInlineKeyboardMarkup(
 			tgbotapi.NewInlineKeyboardRow(
 				tgbotapi.NewInlineKeyboardButtonData("..", "get_up"),
@@ -71,8 +75,9 @@ func buildFileBrowser(currentPath string, page int) (string, tgbotapi.InlineKeyb
 			continue
 		}
 		// Follow symlinks to determine if target is a directory
-		info, err := os.Stat(filepath.Join(currentPath, e.Name()))
-		if err != nil {
+		path := filepath.Join(currentPath, e.Name())
+		info, err := os.Stat(path)
+		if err != nil || !withinRoots(path, roots) {
 			continue
 		}
 		entry := fileBrowseEntry{Name: e.Name(), IsDir: info.IsDir()}
@@ -90,7 +95,7 @@ func buildFileBrowser(currentPath string, page int) (string, tgbotapi.InlineKeyb
 	// Directories first, then files
 	entries := append(dirs, files...)
 
-	totalPages := (len(entries) + filesPerPage - 1) / filesPerPage
+	totalPages := (len(entries) + perPage - 1) / perPage
 	if totalPages == 0 {
 		totalPages = 1
 	}
@@ -104,8 +109,8 @@ func buildFileBrowser(currentPath string, page int) (string, tgbotapi.InlineKeyb
 	var rows [][]tgbotapi.InlineKeyboardButton
 
 	// Entry buttons (2 per row)
-	start := page * filesPerPage
-	end := start + filesPerPage
+	start := page * perPage
+	end := start + perPage
 	if end > len(entries) {
 		end = len(entries)
 	}
@@ -178,20 +183,14 @@ func (b *Bot) processFileBrowserCallback(cq *tgbotapi.CallbackQuery) {
 	userID := cq.From.ID
 	data := cq.Data
 
-	b.mu.RLock()
-	fs, ok := b.fileBrowseStates[userID]
-	b.mu.RUnlock()
+	b.mu.Lock()
+	fs, ok := b.fileBrowseStates.get(callbackWizardKey(cq))
+	b.mu.Unlock()
 
 	if !ok {
 		return
 	}
 
-	// Verify topic match
-	threadID := getThreadID(cq.Message)
-	if threadID != fs.ThreadID {
-		return
-	}
-
 	switch {
 	case strings.HasPrefix(data, "get_sel:"):
 		b.handleGetSelect(cq, fs, userID)
@@ -218,7 +217,7 @@ func (b *Bot) handleGetSelect(cq *tgbotapi.CallbackQuery, fs *FileBrowseState, u
 
 	if entry.IsDir {
 		// Navigate into directory
-		text, keyboard, entries := buildFileBrowser(fullPath, 0)
+		text, keyboard, entries := buildFileBrowser(fullPath, 0, fs.PerPage, b.allowedRoots())
 		b.editMessageWithKeyboard(fs.ChatID, fs.MessageID, text, keyboard)
 
 		b.mu.Lock()
@@ -229,44 +228,33 @@ func (b *Bot) handleGetSelect(cq *tgbotapi.CallbackQuery, fs *FileBrowseState, u
 		return
 	}
 
-	// It's a file — stat for size check
-	info, err := os.Stat(fullPath)
-	if err != nil {
-		b.showFileBrowserError(fs, fmt.Sprintf("Error: %v", err))
-		return
-	}
-
-	const maxFileSize = 50 * 1024 * 1024 // 50MB
-	if info.Size() > maxFileSize {
-		b.showFileBrowserError(fs, fmt.Sprintf("File too large: %s (%d MB limit is 50 MB)",
-			entry.Name, info.Size()/(1024*1024)))
+	// The file may have been swapped for a link out of the roots since listing
+	if !withinRoots(fullPath, b.allowedRoots()) {
+		b.showFileBrowserError(fs, fmt.Sprintf("Error: %s is outside the allowed directories", entry.Name))
 		return
 	}
 
-	data, err := os.ReadFile(fullPath)
-	if err != nil {
-		b.showFileBrowserError(fs, fmt.Sprintf("Error reading file: %v", err))
-		return
-	}
-
-	// Send file as document
-	_, err = b.sendDocumentInThread(fs.ChatID, fs.ThreadID, data, entry.Name, tgbotapi.InlineKeyboardMarkup{})
-	if err != nil {
-		b.showFileBrowserError(fs, fmt.Sprintf("Error sending file: %v", err))
-		return
-	}
+	// Send file as document, compressed or split when over the upload limit
+	b.editMessageText(fs.ChatID, fs.MessageID, fmt.Sprintf("Sending %s...", entry.Name))
+	go func() {
+		sent, err := b.sendFile(fs.ChatID, fs.ThreadID, fullPath)
+		if err != nil {
+			b.showFileBrowserError(fs, fmt.Sprintf("Error sending file: %v", err))
+			return
+		}
 
-	// Success — edit browser message and clean up state
-	b.editMessageText(fs.ChatID, fs.MessageID, fmt.Sprintf("Sent: %s", entry.Name))
+		// Success — edit browser message and clean up state
+		b.editMessageText(fs.ChatID, fs.MessageID, sent)
 
-	b.mu.Lock()
-	delete(b.fileBrowseStates, userID)
-	b.mu.Unlock()
+		b.mu.Lock()
+		b.fileBrowseStates.remove(wizardKey{UserID: userID, ChatID: fs.ChatID, ThreadID: fs.ThreadID})
+		b.mu.Unlock()
+	}()
 }
 
 // showFileBrowserError shows an error in the browser message but keeps state alive.
 func (b *Bot) showFileBrowserError(fs *FileBrowseState, errMsg string) {
-	text, keyboard, entries := buildFileBrowser(fs.CurrentPath, fs.Page)
+	text, keyboard, entries := buildFileBrowser(fs.CurrentPath, fs.Page, fs.PerPage, b.allowedRoots())
 	// Prepend error to the header text
 	text = errMsg + "\n\n" + text
 	b.editMessageWithKeyboard(fs.ChatID, fs.MessageID, text, keyboard)
@@ -283,7 +271,7 @@ func (b *Bot) handleGetPage(cq *tgbotapi.CallbackQuery, fs *FileBrowseState, use
 		return
 	}
 
-	text, keyboard, entries := buildFileBrowser(fs.CurrentPath, page)
+	text, keyboard, entries := buildFileBrowser(fs.CurrentPath, page, fs.PerPage, b.allowedRoots())
 	b.editMessageWithKeyboard(fs.ChatID, fs.MessageID, text, keyboard)
 
 	b.mu.Lock()
@@ -294,11 +282,12 @@ func (b *Bot) handleGetPage(cq *tgbotapi.CallbackQuery, fs *FileBrowseState, use
 
 func (b *Bot) handleGetUp(cq *tgbotapi.CallbackQuery, fs *FileBrowseState, userID int64) {
 	parent := filepath.Dir(fs.CurrentPath)
-	if parent == fs.CurrentPath {
+	roots := b.allowedRoots()
+	if parent == fs.CurrentPath || !withinRoots(parent, roots) {
 		return // already at root
 	}
 
-	text, keyboard, entries := buildFileBrowser(parent, 0)
+	text, keyboard, entries := buildFileBrowser(parent, 0, fs.PerPage, roots)
 	b.editMessageWithKeyboard(fs.ChatID, fs.MessageID, text, keyboard)
 
 	b.mu.Lock()
@@ -310,7 +299,7 @@ func (b *Bot) handleGetUp(cq *tgbotapi.CallbackQuery, fs *FileBrowseState, userI
 
 func (b *Bot) handleGetCancel(cq *tgbotapi.CallbackQuery, fs *FileBrowseState, userID int64) {
 	b.mu.Lock()
-	delete(b.fileBrowseStates, userID)
+	b.fileBrowseStates.remove(wizardKey{UserID: userID, ChatID: fs.ChatID, ThreadID: fs.ThreadID})
 	b.mu.Unlock()
 
 	b.editMessageText(fs.ChatID, fs.MessageID, "Cancelled.")
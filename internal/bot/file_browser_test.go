@@ -14,7 +14,7 @@ func TestBuildFileBrowser_DirsFirstThenFiles(t *testing.T) {
 	os.WriteFile(filepath.Join(dir, "bfile.txt"), []byte("hi"), 0o644)
 	os.WriteFile(filepath.Join(dir, "afile.txt"), []byte("hi"), 0o644)
 
-	_, _, entries := buildFileBrowser(dir, 0)
+	_, _, entries := buildFileBrowser(dir, 0, filesPerPage, []string{dir})
 
 	if len(entries) != 4 {
 		t.Fatalf("expected 4 entries, got %d", len(entries))
@@ -44,7 +44,7 @@ func TestBuildFileBrowser_HiddenEntriesExcluded(t *testing.T) {
 	os.Mkdir(filepath.Join(dir, "visible"), 0o755)
 	os.WriteFile(filepath.Join(dir, "readme.md"), []byte("hi"), 0o644)
 
-	_, _, entries := buildFileBrowser(dir, 0)
+	_, _, entries := buildFileBrowser(dir, 0, filesPerPage, []string{dir})
 
 	if len(entries) != 2 {
 		t.Fatalf("expected 2 entries, got %d: %v", len(entries), entries)
@@ -59,7 +59,7 @@ func TestBuildFileBrowser_HiddenEntriesExcluded(t *testing.T) {
 func TestBuildFileBrowser_EmptyDirectory(t *testing.T) {
 	dir := t.TempDir()
 
-	text, kb, entries := buildFileBrowser(dir, 0)
+	text, kb, entries := buildFileBrowser(dir, 0, filesPerPage, []string{dir})
 
 	if len(entries) != 0 {
 		t.Errorf("expected 0 entries, got %d", len(entries))
@@ -74,7 +74,7 @@ func TestBuildFileBrowser_EmptyDirectory(t *testing.T) {
 }
 
 func TestBuildFileBrowser_InvalidPath(t *testing.T) {
-	text, _, entries := buildFileBrowser("/nonexistent/path/xyz", 0)
+	text, _, entries := buildFileBrowser("/nonexistent/path/xyz", 0, filesPerPage, []string{"/"})
 
 	if entries != nil {
 		t.Error("entries should be nil for invalid path")
@@ -94,7 +94,7 @@ func TestBuildFileBrowser_Pagination(t *testing.T) {
 		os.WriteFile(filepath.Join(dir, "file"+string(rune('a'+i))+".txt"), []byte("hi"), 0o644)
 	}
 
-	_, kb, entries := buildFileBrowser(dir, 0)
+	_, kb, entries := buildFileBrowser(dir, 0, filesPerPage, []string{dir})
 
 	if len(entries) != 10 {
 		t.Fatalf("expected 10 entries, got %d", len(entries))
@@ -114,7 +114,7 @@ func TestBuildFileBrowser_Pagination(t *testing.T) {
 	}
 
 	// Page 1 should show remaining entries and have a back button
-	_, kb2, _ := buildFileBrowser(dir, 1)
+	_, kb2, _ := buildFileBrowser(dir, 1, filesPerPage, []string{dir})
 	hasBack := false
 	for _, row := range kb2.InlineKeyboard {
 		for _, btn := range row {
@@ -133,7 +133,7 @@ func TestBuildFileBrowser_NoPaginationWhenFewEntries(t *testing.T) {
 	os.WriteFile(filepath.Join(dir, "a.txt"), []byte("hi"), 0o644)
 	os.WriteFile(filepath.Join(dir, "b.txt"), []byte("hi"), 0o644)
 
-	_, kb, _ := buildFileBrowser(dir, 0)
+	_, kb, _ := buildFileBrowser(dir, 0, filesPerPage, []string{dir})
 
 	for _, row := range kb.InlineKeyboard {
 		for _, btn := range row {
@@ -149,13 +149,13 @@ func TestBuildFileBrowser_PageBounds(t *testing.T) {
 	os.WriteFile(filepath.Join(dir, "a.txt"), []byte("hi"), 0o644)
 
 	// Page -1 should clamp to 0
-	_, _, entries := buildFileBrowser(dir, -1)
+	_, _, entries := buildFileBrowser(dir, -1, filesPerPage, []string{dir})
 	if len(entries) != 1 {
 		t.Errorf("expected 1 entry, got %d", len(entries))
 	}
 
 	// Page 999 should clamp to last page
-	_, _, entries = buildFileBrowser(dir, 999)
+	_, _, entries = buildFileBrowser(dir, 999, filesPerPage, []string{dir})
 	if len(entries) != 1 {
 		t.Errorf("expected 1 entry, got %d", len(entries))
 	}
@@ -167,7 +167,7 @@ func TestBuildFileBrowser_TwoButtonsPerRow(t *testing.T) {
 		os.WriteFile(filepath.Join(dir, "file"+string(rune('a'+i))+".txt"), []byte("hi"), 0o644)
 	}
 
-	_, kb, _ := buildFileBrowser(dir, 0)
+	_, kb, _ := buildFileBrowser(dir, 0, filesPerPage, []string{dir})
 
 	// First two rows should be entry buttons with 2 per row
 	// Last row is the action row (..|Cancel)
@@ -187,7 +187,7 @@ func TestBuildFileBrowser_OddEntryCount(t *testing.T) {
 		os.WriteFile(filepath.Join(dir, "file"+string(rune('a'+i))+".txt"), []byte("hi"), 0o644)
 	}
 
-	_, kb, _ := buildFileBrowser(dir, 0)
+	_, kb, _ := buildFileBrowser(dir, 0, filesPerPage, []string{dir})
 
 	// Row 0: 2 buttons, Row 1: 1 button (odd), then action row
 	if len(kb.InlineKeyboard) < 3 {
@@ -206,7 +206,7 @@ func TestBuildFileBrowser_DirEmoji(t *testing.T) {
 	os.Mkdir(filepath.Join(dir, "subdir"), 0o755)
 	os.WriteFile(filepath.Join(dir, "file.txt"), []byte("hi"), 0o644)
 
-	_, kb, _ := buildFileBrowser(dir, 0)
+	_, kb, _ := buildFileBrowser(dir, 0, filesPerPage, []string{dir})
 
 	// First entry row should have dir with folder emoji and file without
 	row := kb.InlineKeyboard[0]
@@ -226,7 +226,7 @@ func TestBuildFileBrowser_CallbackDataFormat(t *testing.T) {
 	os.Mkdir(filepath.Join(dir, "sub"), 0o755)
 	os.WriteFile(filepath.Join(dir, "f.txt"), []byte("hi"), 0o644)
 
-	_, kb, _ := buildFileBrowser(dir, 0)
+	_, kb, _ := buildFileBrowser(dir, 0, filesPerPage, []string{dir})
 
 	// Entry buttons use get_sel:<index> format
 	row := kb.InlineKeyboard[0]
@@ -242,7 +242,7 @@ func TestBuildFileBrowser_ActionRow(t *testing.T) {
 	dir := t.TempDir()
 	os.Mkdir(filepath.Join(dir, "sub"), 0o755)
 
-	_, kb, _ := buildFileBrowser(dir, 0)
+	_, kb, _ := buildFileBrowser(dir, 0, filesPerPage, []string{dir})
 
 	lastRow := kb.InlineKeyboard[len(kb.InlineKeyboard)-1]
 	if len(lastRow) != 2 {
@@ -262,7 +262,7 @@ func TestBuildFileBrowser_HeaderText(t *testing.T) {
 	os.Mkdir(filepath.Join(dir, "sub2"), 0o755)
 	os.WriteFile(filepath.Join(dir, "f.txt"), []byte("hi"), 0o644)
 
-	text, _, _ := buildFileBrowser(dir, 0)
+	text, _, _ := buildFileBrowser(dir, 0, filesPerPage, []string{dir})
 
 	if !strings.Contains(text, "2 dirs") {
 		t.Errorf("header should show 2 dirs, got: %s", text)
@@ -278,7 +278,7 @@ func TestBuildFileBrowser_FollowsSymlinks(t *testing.T) {
 	os.Mkdir(realDir, 0o755)
 	os.Symlink(realDir, filepath.Join(dir, "linkdir"))
 
-	_, _, entries := buildFileBrowser(dir, 0)
+	_, _, entries := buildFileBrowser(dir, 0, filesPerPage, []string{dir})
 
 	if len(entries) != 2 {
 		t.Fatalf("expected 2 entries, got %d", len(entries))
@@ -297,7 +297,7 @@ func TestBuildFileBrowser_PageIndicator(t *testing.T) {
 		os.WriteFile(filepath.Join(dir, "file"+string(rune('a'+i))+".txt"), []byte("hi"), 0o644)
 	}
 
-	_, kb, _ := buildFileBrowser(dir, 0)
+	_, kb, _ := buildFileBrowser(dir, 0, filesPerPage, []string{dir})
 
 	// Find the noop page indicator button showing "1/2"
 	found := false
@@ -0,0 +1,216 @@
+package bot
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/queue"
+	"github.com/otaviocarvalho/tramuntana/internal/render"
+)
+
+const (
+	// maxFileRefButtons caps how many file references one message gets buttons for.
+	maxFileRefButtons = 3
+	// maxFileRefOffers caps how many file reference buttons stay answerable.
+	maxFileRefOffers = 300
+	// snippetContext is how many lines "Show around line" shows on each side.
+	snippetContext = 10
+	// maxRefLabel caps the file name shown on an "Open" button.
+	maxRefLabel = 40
+)
+
+// fileRefPattern matches path:line references such as src/foo.go:42 or
+// /abs/bar.py:7:3. The path must have an extension, which keeps times and
+// host:port pairs out.
+var fileRefPattern = regexp.MustCompile(`(?:^|[^\w/.~-])((?:~/|/|\./)?(?:[\w@.+-]+/)*[\w@+-][\w@.+-]*\.[A-Za-z0-9]+):(\d+)`)
+
+// fileRef is a file and line mentioned in Claude's text.
+type fileRef struct {
+	Path string // as written
+	Abs  string // resolved against the window's CWD
+	Line int
+}
+
+var (
+	fileRefOffers   = make(map[int]fileRef) // offer ID → reference
+	fileRefOffersMu sync.Mutex
+	nextFileRefID   = 1
+)
+
+// offerFileRef remembers a reference for its buttons and returns its offer ID.
+func offerFileRef(ref fileRef) int {
+	fileRefOffersMu.Lock()
+	defer fileRefOffersMu.Unlock()
+	id := nextFileRefID
+	nextFileRefID++
+	fileRefOffers[id] = ref
+	delete(fileRefOffers, id-maxFileRefOffers)
+	return id
+}
+
+// findFileRefs returns the distinct path:line references in text, in order.
+// Relative paths resolve against cwd; only existing regular files are kept.
+func findFileRefs(text, cwd string) []fileRef {
+	var refs []fileRef
+	seen := make(map[string]bool)
+	for _, m := range fileRefPattern.FindAllStringSubmatch(text, -1) {
+		line, err := strconv.Atoi(m[2])
+		if err != nil || line < 1 {
+			continue
+		}
+		abs := m[1]
+		switch {
+		case strings.HasPrefix(abs, "~/"):
+			home, err := os.UserHomeDir()
+			if err != nil {
+				continue
+			}
+			abs = filepath.Join(home, abs[2:])
+		case !filepath.IsAbs(abs):
+			if cwd == "" {
+				continue
+			}
+			abs = filepath.Join(cwd, abs)
+		}
+		abs = filepath.Clean(abs)
+		key := abs + ":" + m[2]
+		if seen[key] {
+			continue
+		}
+		if info, err := os.Stat(abs); err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		seen[key] = true
+		refs = append(refs, fileRef{Path: m[1], Abs: abs, Line: line})
+	}
+	return refs
+}
+
+// fileRefKeyboard builds "Open" and "Show around line" buttons for the file
+// references in a content message, or returns nil if it has none inside the
+// allowed directories. Set as the queue's keyboard function.
+func (b *Bot) fileRefKeyboard(task queue.MessageTask, text string) *tgbotapi.InlineKeyboardMarkup {
+	if task.WindowID == "" || !strings.Contains(text, ":") {
+		return nil
+	}
+	ws, _ := b.state.GetWindowState(task.WindowID)
+	roots := b.allowedRoots()
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, ref := range findFileRefs(text, ws.Dir()) {
+		if !withinRoots(ref.Abs, roots) {
+			continue
+		}
+		id := offerFileRef(ref)
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Open "+truncateLabel(ref.Path, maxRefLabel), fmt.Sprintf("fref_open:%d", id)),
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("Show around line %d", ref.Line), fmt.Sprintf("fref_show:%d", id)),
+		))
+		if len(rows) == maxFileRefButtons {
+			break
+		}
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	return &keyboard
+}
+
+// truncateLabel shortens s to at most n runes, keeping its end.
+func truncateLabel(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return "…" + string(r[len(r)-n+1:])
+}
+
+// processFileRefCallback sends the file or a snippet behind a file reference button.
+func (b *Bot) processFileRefCallback(cq *tgbotapi.CallbackQuery) {
+	if cq.Message == nil {
+		return
+	}
+	action, idStr, _ := strings.Cut(strings.TrimPrefix(cq.Data, "fref_"), ":")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		return
+	}
+	chatID := cq.Message.Chat.ID
+	threadID := getThreadIDFromCallback(cq)
+
+	fileRefOffersMu.Lock()
+	ref, ok := fileRefOffers[id]
+	fileRefOffersMu.Unlock()
+	if !ok {
+		b.reply(chatID, threadID, "That file link expired. Use /c_get to browse for it.")
+		return
+	}
+	if !withinRoots(ref.Abs, b.allowedRoots()) {
+		b.reply(chatID, threadID, ref.Path+" is outside the allowed directories.")
+		return
+	}
+
+	switch action {
+	case "open":
+		go func() {
+			if _, err := b.sendFile(chatID, threadID, ref.Abs); err != nil {
+				b.reply(chatID, threadID, fmt.Sprintf("Error sending file: %v", err))
+			}
+		}()
+	case "show":
+		snippet, err := readSnippet(ref.Abs, ref.Line, snippetContext)
+		if err != nil {
+			b.reply(chatID, threadID, fmt.Sprintf("Error reading %s: %v", ref.Path, err))
+			return
+		}
+		text := fmt.Sprintf("%s:%d\n```\n%s\n```", ref.Path, ref.Line, snippet)
+		if _, err := b.sendMessageInThreadMD(chatID, threadID, render.ToMarkdownV2(text)); err != nil {
+			b.reply(chatID, threadID, render.ToPlainText(text))
+		}
+	}
+}
+
+// readSnippet returns the lines within context of line (from 1), numbered,
+// with the target line marked.
+func readSnippet(path string, line, context int) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	first, last := max(1, line-context), line+context
+	width := len(strconv.Itoa(last))
+	var out []string
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for n := 1; sc.Scan() && n <= last; n++ {
+		if n < first {
+			continue
+		}
+		marker := " "
+		if n == line {
+			marker = "▶"
+		}
+		text := strings.ReplaceAll(sc.Text(), "\t", "    ")
+		if r := []rune(text); len(r) > 160 {
+			text = string(r[:160]) + "…"
+		}
+		out = append(out, fmt.Sprintf("%s%*d  %s", marker, width, n, text))
+	}
+	if err := sc.Err(); err != nil {
+		return "", err
+	}
+	if len(out) == 0 {
+		return "", fmt.Errorf("the file has no line %d", line)
+	}
+	return strings.Join(out, "\n"), nil
+}
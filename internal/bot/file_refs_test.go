@@ -0,0 +1,98 @@
+package bot
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/otaviocarvalho/tramuntana/internal/testharness"
+)
+
+func TestFindFileRefs(t *testing.T) {
+	cwd := t.TempDir()
+	os.MkdirAll(filepath.Join(cwd, "src"), 0o755)
+	os.WriteFile(filepath.Join(cwd, "src", "foo.go"), []byte("package foo\n"), 0o644)
+	os.WriteFile(filepath.Join(cwd, "main.py"), []byte("print()\n"), 0o644)
+
+	text := "See src/foo.go:42 and (main.py:7:3), again src/foo.go:42, " +
+		"missing.go:1, at 12:30, localhost:8080, " + filepath.Join(cwd, "main.py") + ":9"
+	refs := findFileRefs(text, cwd)
+
+	var got []string
+	for _, r := range refs {
+		got = append(got, fmt.Sprintf("%s@%d", r.Path, r.Line))
+	}
+	want := []string{"src/foo.go@42", "main.py@7", filepath.Join(cwd, "main.py") + "@9"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("refs = %v, want %v", got, want)
+	}
+	if refs[0].Abs != filepath.Join(cwd, "src", "foo.go") {
+		t.Errorf("abs = %q", refs[0].Abs)
+	}
+	if refs := findFileRefs("src/foo.go:42", ""); refs != nil {
+		t.Errorf("relative refs without a cwd = %v", refs)
+	}
+}
+
+func TestReadSnippet(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lines.txt")
+	var lines []string
+	for i := 1; i <= 30; i++ {
+		lines = append(lines, "line "+strconv.Itoa(i))
+	}
+	os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0o644)
+
+	got, err := readSnippet(path, 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := " 1  line 1\n 2  line 2\n▶3  line 3\n 4  line 4\n 5  line 5"
+	if got != want {
+		t.Errorf("snippet =\n%s\nwant\n%s", got, want)
+	}
+	if _, err := readSnippet(path, 99, 2); err == nil {
+		t.Error("expected an error past the end of the file")
+	}
+}
+
+func TestE2E_FileRefButtons(t *testing.T) {
+	h := startE2E(t, "fresh")
+	windowID := h.tmux.AddWindow("api", "/work/api")
+	userID, threadID := strconv.FormatInt(e2eUser, 10), strconv.Itoa(e2eThread)
+	h.bot.state.BindThread(userID, threadID, windowID)
+	h.bot.state.SetGroupChatID(userID, threadID, e2eChat)
+
+	src := filepath.Join(h.home, "handler.go")
+	os.WriteFile(src, []byte("package api\n\nfunc Handle() {\n\tpanic(1)\n}\n"), 0o644)
+	outside := filepath.Join(t.TempDir(), "secret.go")
+	os.WriteFile(outside, []byte("package secret\n"), 0o644)
+
+	h.writeSession(t, windowID, "sess-1", "/work/api")
+	h.appendTranscript(t, "sess-1",
+		`{"type":"assistant","message":{"content":[{"type":"text","text":"The panic is at `+src+`:4, not `+outside+`:1."}]}}`)
+
+	msg := h.tg.WaitFor("sendMessage", func(c testharness.Call) bool {
+		return strings.Contains(c.Params["text"], "The panic is at")
+	})
+	markup := msg.Params["reply_markup"]
+	if !strings.Contains(markup, "handler.go\",\"callback_data\":\"fref_open:") || !strings.Contains(markup, "Show around line 4") {
+		t.Fatalf("reply_markup = %s", markup)
+	}
+	if strings.Contains(markup, "secret.go") {
+		t.Errorf("offered a file outside the allowed roots: %s", markup)
+	}
+	id := markup[strings.Index(markup, "fref_open:")+len("fref_open:"):]
+	id = id[:strings.IndexByte(id, '"')]
+
+	h.tg.PushCallback(e2eChat, e2eThread, e2eUser, msg.MessageID, "fref_show:"+id)
+	h.tg.WaitFor("sendMessage", func(c testharness.Call) bool {
+		return strings.Contains(c.Params["text"], "▶ 4") && strings.Contains(c.Params["text"], "panic(1)")
+	})
+	h.tg.PushCallback(e2eChat, e2eThread, e2eUser, msg.MessageID, "fref_open:"+id)
+	h.tg.WaitFor("sendDocument", func(c testharness.Call) bool {
+		return c.Params["message_thread_id"] == threadID
+	})
+}
@@ -0,0 +1,204 @@
+package bot
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"image"
+	_ "image/gif" // decoders for previews
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"golang.org/x/image/draw"
+)
+
+// uploadLimit is the Bot API cap on an uploaded file. A variable so tests can
+// lower it.
+var uploadLimit int64 = 50 * 1024 * 1024
+
+const (
+	// maxUploadParts caps how many documents one split upload may take.
+	maxUploadParts = 20
+	// previewMaxSide bounds the longer side of an image preview, well within
+	// Telegram's photo limits.
+	previewMaxSide = 2560
+	// previewMaxPixels caps the images decoded for a preview, since decoding
+	// takes 4 bytes of memory per pixel.
+	previewMaxPixels = 50_000_000
+)
+
+// sendFile sends the file at path as a document. Files over the upload limit
+// are gzipped when they are text and split into parts otherwise; images also
+// get a photo preview. Returns a summary of what was sent. Uploads can take a
+// while, so button handlers call it from their own goroutine.
+func (b *Bot) sendFile(chatID int64, threadID int, path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	name := filepath.Base(path)
+
+	if info.Size() <= uploadLimit {
+		data, err := io.ReadAll(f)
+		if err != nil {
+			return "", err
+		}
+		if _, err := b.sendDocumentInThread(chatID, threadID, data, name, tgbotapi.InlineKeyboardMarkup{}); err != nil {
+			return "", err
+		}
+		return "Sent: " + name, nil
+	}
+	if n := partCount(info.Size()); n > maxUploadParts {
+		return "", fmt.Errorf("%s is %s, over the %d-part limit for split uploads", name, formatFileSize(info.Size()), maxUploadParts)
+	}
+
+	kind, err := sniffFile(f)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case strings.HasPrefix(kind, "text/"):
+		var gz bytes.Buffer
+		zw := gzip.NewWriter(&gz)
+		if _, err := io.Copy(zw, f); err != nil {
+			return "", err
+		}
+		if err := zw.Close(); err != nil {
+			return "", err
+		}
+		gzName := name + ".gz"
+		if int64(gz.Len()) <= uploadLimit {
+			if _, err := b.sendDocumentInThread(chatID, threadID, gz.Bytes(), gzName, tgbotapi.InlineKeyboardMarkup{}); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Sent: %s (gzipped from %s)", gzName, formatFileSize(info.Size())), nil
+		}
+		return b.sendParts(chatID, threadID, gzName, &gz, int64(gz.Len()))
+	case kind == "image/png" || kind == "image/jpeg" || kind == "image/gif":
+		// The preview is a courtesy; the original is sent without one
+		if err := b.sendPreview(chatID, threadID, f, name); err != nil {
+			log.Printf("Skipping preview of %s: %v", name, err)
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return "", err
+		}
+	}
+	return b.sendParts(chatID, threadID, name, f, info.Size())
+}
+
+// sniffFile returns the content type of f's first bytes and rewinds it.
+func sniffFile(f *os.File) (string, error) {
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return http.DetectContentType(head[:n]), nil
+}
+
+// partCount is how many upload-sized parts size bytes split into.
+func partCount(size int64) int {
+	return int((size + uploadLimit - 1) / uploadLimit)
+}
+
+// partName names part i (from 1) of n so the parts sort in order.
+func partName(name string, i, n int) string {
+	width := len(fmt.Sprint(n))
+	return fmt.Sprintf("%s.part%0*d", name, width, i)
+}
+
+// sendParts sends r in upload-sized documents, then how to join them.
+func (b *Bot) sendParts(chatID int64, threadID int, name string, r io.Reader, size int64) (string, error) {
+	n := partCount(size)
+	buf := make([]byte, min(size, uploadLimit))
+	for i := 1; i <= n; i++ {
+		read, err := io.ReadFull(r, buf)
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return "", fmt.Errorf("reading part %d of %s: %w", i, name, err)
+		}
+		if _, err := b.sendDocumentInThread(chatID, threadID, buf[:read], partName(name, i, n), tgbotapi.InlineKeyboardMarkup{}); err != nil {
+			return "", fmt.Errorf("sending part %d of %s: %w", i, name, err)
+		}
+	}
+	b.reply(chatID, threadID, fmt.Sprintf("%s was sent in %d parts. Join them with:\ncat %s.part* > %s", name, n, name, name))
+	return fmt.Sprintf("Sent: %s in %d parts", name, n), nil
+}
+
+// sendPreview sends a downscaled JPEG of an image as a photo. Images over
+// previewMaxPixels are refused before decoding.
+func (b *Bot) sendPreview(chatID int64, threadID int, r io.ReadSeeker, name string) error {
+	cfg, _, err := image.DecodeConfig(r)
+	if err != nil {
+		return fmt.Errorf("decoding %s: %w", name, err)
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > previewMaxPixels {
+		return fmt.Errorf("%s is %dx%d, too large to preview", name, cfg.Width, cfg.Height)
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return fmt.Errorf("decoding %s: %w", name, err)
+	}
+	var out bytes.Buffer
+	if err := jpeg.Encode(&out, scaleToFit(img, previewMaxSide), &jpeg.Options{Quality: 85}); err != nil {
+		return err
+	}
+	_, err = b.sendPhotoInThread(chatID, threadID, out.Bytes(), "preview.jpg", "Preview of "+name+"; the original follows in parts.")
+	return err
+}
+
+// scaleToFit shrinks img so its longer side is at most maxSide.
+func scaleToFit(img image.Image, maxSide int) image.Image {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w <= maxSide && h <= maxSide {
+		return img
+	}
+	if w >= h {
+		w, h = maxSide, max(1, h*maxSide/w)
+	} else {
+		w, h = max(1, w*maxSide/h), maxSide
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.ApproxBiLinear.Scale(dst, dst.Bounds(), img, bounds, draw.Src, nil)
+	return dst
+}
+
+// sendPhotoInThread sends a photo with a caption in a forum thread.
+func (b *Bot) sendPhotoInThread(chatID int64, threadID int, data []byte, filename, caption string) (tgbotapi.Message, error) {
+	params := tgbotapi.Params{}
+	params.AddNonZero64("chat_id", chatID)
+	if threadID != 0 {
+		params.AddNonZero("message_thread_id", threadID)
+	}
+	params.AddNonEmpty("caption", caption)
+
+	resp, err := b.api.UploadFiles("sendPhoto", params, []tgbotapi.RequestFile{
+		{Name: "photo", Data: tgbotapi.FileBytes{Name: filename, Bytes: data}},
+	})
+	if err != nil {
+		return tgbotapi.Message{}, fmt.Errorf("sendPhoto: %w", err)
+	}
+
+	var msg tgbotapi.Message
+	json.Unmarshal(resp.Result, &msg)
+	return msg, nil
+}
@@ -0,0 +1,145 @@
+package bot
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func lowerUploadLimit(t *testing.T, n int64) {
+	t.Helper()
+	prev := uploadLimit
+	uploadLimit = n
+	t.Cleanup(func() { uploadLimit = prev })
+}
+
+func randomBytes(n int) []byte {
+	data := make([]byte, n)
+	rand.New(rand.NewSource(1)).Read(data)
+	return data
+}
+
+func uploadedNames(h *e2e, method, field string) []string {
+	var names []string
+	for _, c := range h.tg.Calls(method) {
+		names = append(names, c.Params[field])
+	}
+	return names
+}
+
+func TestSendFile_GzipsLargeText(t *testing.T) {
+	h := startE2E(t, "fresh")
+	lowerUploadLimit(t, 1024)
+	path := filepath.Join(t.TempDir(), "build.log")
+	os.WriteFile(path, bytes.Repeat([]byte("compiling...\n"), 500), 0o644)
+
+	sent, err := h.bot.sendFile(e2eChat, e2eThread, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := uploadedNames(h, "sendDocument", "document"); len(names) != 1 || names[0] != "build.log.gz" {
+		t.Errorf("uploaded %v, want build.log.gz", names)
+	}
+	if !strings.Contains(sent, "gzipped") {
+		t.Errorf("summary = %q", sent)
+	}
+}
+
+func TestSendFile_SplitsLargeBinary(t *testing.T) {
+	h := startE2E(t, "fresh")
+	lowerUploadLimit(t, 1024)
+	path := filepath.Join(t.TempDir(), "core.bin")
+	os.WriteFile(path, randomBytes(2500), 0o644)
+
+	if _, err := h.bot.sendFile(e2eChat, e2eThread, path); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"core.bin.part1", "core.bin.part2", "core.bin.part3"}
+	if names := uploadedNames(h, "sendDocument", "document"); strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Errorf("uploaded %v, want %v", names, want)
+	}
+	h.tg.WaitForText("sendMessage", "cat core.bin.part* > core.bin")
+}
+
+func TestSendFile_PreviewsLargeImage(t *testing.T) {
+	h := startE2E(t, "fresh")
+	lowerUploadLimit(t, 64*1024)
+	img := image.NewRGBA(image.Rect(0, 0, 3000, 60))
+	noise := randomBytes(len(img.Pix))
+	for i := range img.Pix {
+		img.Pix[i] = noise[i] | 0x3
+	}
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+	path := filepath.Join(t.TempDir(), "chart.png")
+	os.WriteFile(path, buf.Bytes(), 0o644)
+
+	if _, err := h.bot.sendFile(e2eChat, e2eThread, path); err != nil {
+		t.Fatal(err)
+	}
+	photos := h.tg.Calls("sendPhoto")
+	if len(photos) != 1 || !strings.Contains(photos[0].Params["caption"], "Preview of chart.png") {
+		t.Fatalf("sendPhoto calls = %+v", photos)
+	}
+	parts := uploadedNames(h, "sendDocument", "document")
+	if len(parts) < 2 || !strings.HasPrefix(parts[0], "chart.png.part") {
+		t.Errorf("uploaded %v, want the original in parts", parts)
+	}
+}
+
+func TestSendFile_SkipsPreviewOfHugeImage(t *testing.T) {
+	h := startE2E(t, "fresh")
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 10, 10)))
+	// Claim 100000x100000 in the header; only the header should be read
+	data := buf.Bytes()
+	ihdr := data[12 : 12+4+13]
+	binary.BigEndian.PutUint32(ihdr[4:], 100000)
+	binary.BigEndian.PutUint32(ihdr[8:], 100000)
+	binary.BigEndian.PutUint32(data[12+4+13:], crc32.ChecksumIEEE(ihdr))
+	lowerUploadLimit(t, int64(len(data)/2+1))
+	path := filepath.Join(t.TempDir(), "huge.png")
+	os.WriteFile(path, data, 0o644)
+
+	if _, err := h.bot.sendFile(e2eChat, e2eThread, path); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(h.tg.Calls("sendPhoto")); n != 0 {
+		t.Errorf("sent %d previews of a huge image", n)
+	}
+	if parts := uploadedNames(h, "sendDocument", "document"); len(parts) != 2 {
+		t.Errorf("uploaded %v, want the original in 2 parts", parts)
+	}
+}
+
+func TestSendFile_RefusesTooManyParts(t *testing.T) {
+	h := startE2E(t, "fresh")
+	lowerUploadLimit(t, 10)
+	path := filepath.Join(t.TempDir(), "huge.bin")
+	os.WriteFile(path, randomBytes(10*maxUploadParts+1), 0o644)
+
+	if _, err := h.bot.sendFile(e2eChat, e2eThread, path); err == nil {
+		t.Fatal("expected an error")
+	}
+	if n := len(h.tg.Calls("sendDocument")); n != 0 {
+		t.Errorf("sent %d parts before refusing", n)
+	}
+}
+
+func TestScaleToFit(t *testing.T) {
+	wide := scaleToFit(image.NewRGBA(image.Rect(0, 0, 5000, 1000)), 2560)
+	if b := wide.Bounds(); b.Dx() != 2560 || b.Dy() != 512 {
+		t.Errorf("wide image scaled to %v", b)
+	}
+	small := image.NewRGBA(image.Rect(0, 0, 100, 100))
+	if scaleToFit(small, 2560) != image.Image(small) {
+		t.Error("small image should be kept as is")
+	}
+}
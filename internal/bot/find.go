@@ -0,0 +1,79 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/render"
+	"github.com/otaviocarvalho/tramuntana/internal/tmux"
+)
+
+// maxFindText bounds the text /find sends back, leaving room for escaping.
+const maxFindText = 3500
+
+// handleFindCommand searches the bound window's scrollback for text with tmux
+// copy mode and sends back the screenful around the match, for output that
+// scrolled off. Usage: /find [-s] <text>; -s sends a screenshot instead.
+func (b *Bot) handleFindCommand(msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+	threadID := getThreadID(msg)
+	windowID, bound := b.resolveWindow(msg)
+	if !bound {
+		b.reply(chatID, threadID, "No session bound to this topic.")
+		return
+	}
+
+	query := strings.TrimSpace(msg.CommandArguments())
+	query, screenshot := strings.CutPrefix(query, "-s ")
+	query = strings.TrimSpace(query)
+	if query == "" || query == "-s" {
+		b.reply(chatID, threadID, "Usage: /find [-s] <text> — search the terminal's scrollback; -s replies with a screenshot")
+		return
+	}
+	if screenshot && b.isLite(msg.From.ID) {
+		screenshot = false // lite mode: the text instead
+	}
+
+	found, ok, err := tmux.FindInHistory(b.config.TmuxSessionName, windowID, query, screenshot)
+	if err != nil {
+		if tmux.IsWindowDead(err) {
+			b.handleDeadWindow(msg, windowID, "")
+			return
+		}
+		log.Printf("Error searching %s: %v", windowID, err)
+		b.reply(chatID, threadID, "Error: failed to search the terminal.")
+		return
+	}
+	if !ok {
+		b.reply(chatID, threadID, fmt.Sprintf("%q is not in the terminal's scrollback.", query))
+		return
+	}
+
+	where := "on screen"
+	if found.LinesUp > 0 {
+		where = fmt.Sprintf("%d lines up", found.LinesUp)
+	}
+	if screenshot {
+		png, err := render.RenderScreenshotWithOptions(found.Text, b.screenshotOptions(msg.From.ID))
+		if err != nil {
+			log.Printf("Error rendering screenshot: %v", err)
+			b.reply(chatID, threadID, "Error: failed to render screenshot.")
+			return
+		}
+		if _, err := b.sendPhotoInThread(chatID, threadID, png, "find.png", fmt.Sprintf("🔎 %q, %s", query, where)); err != nil {
+			log.Printf("Error sending find screenshot: %v", err)
+		}
+		return
+	}
+
+	region := strings.TrimRight(found.Text, "\n ")
+	if len(region) > maxFindText {
+		region = region[:maxFindText] + "\n…"
+	}
+	text := fmt.Sprintf("🔎 %q, %s:\n```\n%s\n```", query, where, region)
+	if _, err := b.sendMessageInThreadMD(chatID, threadID, render.ToMarkdownV2(text)); err != nil {
+		b.reply(chatID, threadID, render.ToPlainText(text))
+	}
+}
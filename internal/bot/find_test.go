@@ -0,0 +1,37 @@
+package bot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestE2E_FindInScrollback(t *testing.T) {
+	h := startE2E(t, "fresh")
+	windowID := h.tmux.AddWindow("api", "/work/api")
+	userID, threadID := strconv.FormatInt(e2eUser, 10), strconv.Itoa(e2eThread)
+	h.bot.state.BindThread(userID, threadID, windowID)
+	h.bot.state.SetGroupChatID(userID, threadID, e2eChat)
+
+	var history []string
+	for i := 1; i <= 100; i++ {
+		history = append(history, fmt.Sprintf("ok  pkg/%03d", i))
+	}
+	history[20] = "--- FAIL: TestParser (0.01s)"
+	h.tmux.SetHistory(windowID, history...)
+
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "/find FAIL: TestParser")
+	c := h.tg.WaitForText("sendMessage", "TestParser")
+	if !strings.Contains(c.Params["text"], "lines up") || !strings.Contains(c.Params["text"], "pkg/022") {
+		t.Errorf("find reply = %q", c.Params["text"])
+	}
+	for _, k := range h.tmux.Windows()[len(h.tmux.Windows())-1].Keys {
+		if strings.Contains(k, "TestParser") {
+			t.Errorf("search typed into the window: %q", k)
+		}
+	}
+
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "/find nothing like this")
+	h.tg.WaitForText("sendMessage", "is not in the terminal")
+}
@@ -0,0 +1,114 @@
+package bot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+)
+
+// handleForkCommand handles /fork [name]: starts a copy of this topic's Claude
+// conversation in a new window and topic, leaving the original untouched.
+func (b *Bot) handleForkCommand(msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+	threadID := getThreadID(msg)
+
+	windowID, bound := b.resolveWindow(msg)
+	if !bound {
+		b.reply(chatID, threadID, "Topic not bound to a session.")
+		return
+	}
+	ws, _ := b.state.GetWindowState(windowID)
+	if ws.SessionID == "" || ws.CWD == "" {
+		b.reply(chatID, threadID, "No Claude session recorded for this window yet. Send Claude a message first.")
+		return
+	}
+
+	parentName, _ := b.state.GetWindowDisplayName(windowID)
+	if parentName == "" {
+		parentName = ws.WindowName
+	}
+	name := strings.TrimSpace(msg.CommandArguments())
+	if name == "" {
+		name = "Fork of " + parentName
+	}
+	name = truncateName(name, maxTopicNameLen-2) // room for the state prefix
+
+	start := func() { b.startFork(msg, windowID, ws, parentName, name) }
+	if b.guardSession(chatID, threadID, msg.From.ID, start) {
+		start()
+	}
+}
+
+// startFork creates the fork's topic and window for handleForkCommand.
+func (b *Bot) startFork(msg *tgbotapi.Message, windowID string, ws state.WindowState, parentName, name string) {
+	chatID := msg.Chat.ID
+	threadID := getThreadID(msg)
+	threadIDStr := strconv.Itoa(threadID)
+
+	newThreadID, err := b.createForumTopic(chatID, name)
+	if err != nil {
+		b.reply(chatID, threadID, fmt.Sprintf("Error creating fork topic: %v", err))
+		return
+	}
+	newThreadIDStr := strconv.Itoa(newThreadID)
+	userIDStr := strconv.FormatInt(msg.From.ID, 10)
+	b.state.SetGroupChatID(userIDStr, newThreadIDStr, chatID)
+
+	project, hasProject := b.state.GetProject(threadIDStr)
+	launch := b.resolveSessionLaunch(ws.CWD, project)
+	launch.Command = claudeLaunchCommand(launch.Command, "fork", ws.SessionID)
+	result, err := b.createWindowWithCommand(ws.CWD, launch, msg.From.ID, chatID, newThreadID)
+	if err != nil {
+		b.reply(chatID, threadID, fmt.Sprintf("Error starting fork: %v", err))
+		return
+	}
+
+	// Keep the fork's name rather than the directory name the window got
+	b.state.SetWindowDisplayName(result.WindowID, name)
+	b.renameForumTopic(chatID, newThreadID, name)
+	if hasProject {
+		b.state.BindProject(newThreadIDStr, project)
+	}
+	b.state.SetFork(newThreadIDStr, state.ForkInfo{
+		ParentThreadID:  threadIDStr,
+		ParentWindowID:  windowID,
+		ParentSessionID: ws.SessionID,
+		CreatedAt:       time.Now(),
+	})
+	b.saveState()
+
+	b.reply(chatID, newThreadID, fmt.Sprintf("🍴 Forked from %s (session %s) in %s.\nThis conversation continues separately; the original topic is unchanged.",
+		parentName, ws.SessionID, ws.CWD))
+	notice := "🍴 Forked into " + name
+	if link := b.topicLink(chatID, newThreadID); link != "" {
+		notice += ": " + link
+	}
+	b.reply(chatID, threadID, notice)
+}
+
+// forkStatusLines describes a topic's fork relationships for /status.
+func (b *Bot) forkStatusLines(chatID int64, threadIDStr string) []string {
+	var lines []string
+	describe := func(threadIDStr string) string {
+		threadID, _ := strconv.Atoi(threadIDStr)
+		if link := b.topicLink(chatID, threadID); link != "" {
+			return link
+		}
+		return "topic " + threadIDStr
+	}
+	if fi, ok := b.state.GetFork(threadIDStr); ok {
+		lines = append(lines, fmt.Sprintf("Forked from: %s (session %s)", describe(fi.ParentThreadID), fi.ParentSessionID))
+	}
+	if children := b.state.ForksOf(threadIDStr); len(children) > 0 {
+		var links []string
+		for _, child := range children {
+			links = append(links, describe(child))
+		}
+		lines = append(lines, "Forks: "+strings.Join(links, ", "))
+	}
+	return lines
+}
@@ -0,0 +1,70 @@
+package bot
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+	"github.com/otaviocarvalho/tramuntana/internal/testharness"
+)
+
+func TestE2E_ForkStartsCopyInNewTopic(t *testing.T) {
+	h := startE2E(t, "fresh")
+	windowID := h.tmux.AddWindow("api", "/work/api")
+	userID, threadID := strconv.FormatInt(e2eUser, 10), strconv.Itoa(e2eThread)
+	h.bot.state.BindThread(userID, threadID, windowID)
+	h.bot.state.SetGroupChatID(userID, threadID, e2eChat)
+	h.bot.state.SetWindowState(windowID, state.WindowState{SessionID: "sess-1", CWD: "/work/api", WindowName: "api"})
+	h.bot.state.BindProject(threadID, "backend")
+	h.bot.state.SetDirLaunch("/work/api", state.LaunchChoice{Model: "opus"})
+	h.tmux.OnNewWindow = func(w testharness.Window) {
+		h.writeSession(t, w.ID, "sess-fork", w.CWD)
+	}
+
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "/fork try sqlite")
+	topic := h.tg.WaitFor("createForumTopic", func(c testharness.Call) bool {
+		return c.Params["name"] == "try sqlite"
+	})
+	h.tg.WaitForText("sendMessage", "Forked into try sqlite")
+
+	newThread := strconv.Itoa(topic.MessageID)
+	forkWindow, ok := h.bot.state.GetWindowForThread(userID, newThread)
+	if !ok || forkWindow == windowID {
+		t.Fatalf("new topic bound to %q, %v", forkWindow, ok)
+	}
+	w, _ := h.tmux.Window(forkWindow)
+	if w.CWD != "/work/api" || !slices.Contains(w.Keys, "claude --model opus --resume sess-1 --fork-session") {
+		t.Errorf("fork window in %q typed %q", w.CWD, w.Keys)
+	}
+	if parent, _ := h.bot.state.GetWindowForThread(userID, threadID); parent != windowID {
+		t.Errorf("original topic rebound to %q", parent)
+	}
+	fi, ok := h.bot.state.GetFork(newThread)
+	if !ok || fi.ParentThreadID != threadID || fi.ParentWindowID != windowID || fi.ParentSessionID != "sess-1" {
+		t.Errorf("fork record = %+v, %v", fi, ok)
+	}
+	if p, _ := h.bot.state.GetProject(newThread); p != "backend" {
+		t.Errorf("fork project = %q", p)
+	}
+
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "/status")
+	h.tg.WaitFor("sendMessage", func(c testharness.Call) bool {
+		return strings.Contains(c.Params["text"], "Forks: topic "+newThread)
+	})
+}
+
+func TestE2E_ForkNeedsSession(t *testing.T) {
+	h := startE2E(t, "fresh")
+	windowID := h.tmux.AddWindow("api", "/work/api")
+	userID, threadID := strconv.FormatInt(e2eUser, 10), strconv.Itoa(e2eThread)
+	h.bot.state.BindThread(userID, threadID, windowID)
+	h.bot.state.SetGroupChatID(userID, threadID, e2eChat)
+
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "/fork")
+	h.tg.WaitForText("sendMessage", "No Claude session recorded")
+	if n := len(h.tg.Calls("createForumTopic")); n != 0 {
+		t.Errorf("created %d topics", n)
+	}
+}
@@ -0,0 +1,88 @@
+package bot
+
+import (
+	"context"
+	"log"
+	"slices"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/config"
+)
+
+// handleCCCommand forwards any slash command to Claude Code:
+// "/cc review src/" types "/review src/".
+func (b *Bot) handleCCCommand(msg *tgbotapi.Message) {
+	cmd := strings.TrimPrefix(strings.TrimSpace(msg.CommandArguments()), "/")
+	if cmd == "" {
+		b.reply(msg.Chat.ID, getThreadID(msg), "Usage: /cc <command> [args], e.g. /cc review or /cc project:deploy")
+		return
+	}
+	b.forwardCommand(msg, cmd)
+}
+
+// handleCustomCommand runs a command that isn't built in: an alias from the
+// bound window's .tramuntana.yaml, else a command on the forward list.
+// Reports whether the command was handled.
+func (b *Bot) handleCustomCommand(msg *tgbotapi.Message) bool {
+	name := msg.Command()
+	args := strings.TrimSpace(msg.CommandArguments())
+	if expansion, ok := b.lookupAlias(msg, name); ok {
+		if args != "" {
+			expansion += " " + args
+		}
+		b.runAlias(msg, expansion)
+		return true
+	}
+	if slices.Contains(b.config.ForwardCommands, name) {
+		cmd := name
+		if args != "" {
+			cmd += " " + args
+		}
+		b.forwardCommand(msg, cmd)
+		return true
+	}
+	return false
+}
+
+// lookupAlias returns the alias name expands to in the bound window's
+// directory, if any.
+func (b *Bot) lookupAlias(msg *tgbotapi.Message, name string) (string, bool) {
+	windowID, bound := b.resolveWindow(msg)
+	if !bound {
+		return "", false
+	}
+	ws, ok := b.state.GetWindowState(windowID)
+	if !ok || ws.CWD == "" {
+		return "", false
+	}
+	pc, err := config.LoadProjectConfig(ws.CWD)
+	if err != nil {
+		log.Printf("Ignoring project config: %v", err)
+		return "", false
+	}
+	if pc == nil {
+		return "", false
+	}
+	expansion, ok := pc.Aliases[name]
+	return expansion, ok && expansion != ""
+}
+
+// runAlias sends an alias expansion to the bound window: "!cmd" runs in
+// Claude's bash mode with its output captured, anything else is typed as a
+// prompt or slash command.
+func (b *Bot) runAlias(msg *tgbotapi.Message, expansion string) {
+	if strings.HasPrefix(expansion, "!") && len(expansion) > 1 {
+		windowID, bound := b.resolveWindow(msg)
+		if !bound {
+			b.reply(msg.Chat.ID, getThreadID(msg), "Topic not bound to a session. Send a message to bind.")
+			return
+		}
+		if b.rejectIfObserved(msg) {
+			return
+		}
+		b.handleBashCommand(context.Background(), msg, windowID, expansion)
+		return
+	}
+	b.sendToClaude(msg, expansion)
+}
@@ -0,0 +1,59 @@
+package bot
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+)
+
+// bindForwardTopic binds the e2e topic to a window in a repository with the
+// given .tramuntana.yaml and returns the window ID.
+func bindForwardTopic(t *testing.T, h *e2e, projectFile string) string {
+	t.Helper()
+	dir := t.TempDir()
+	os.Mkdir(filepath.Join(dir, ".git"), 0o755)
+	if projectFile != "" {
+		os.WriteFile(filepath.Join(dir, ".tramuntana.yaml"), []byte(projectFile), 0o644)
+	}
+	windowID := h.tmux.AddWindow("api", dir)
+	userID, threadID := strconv.FormatInt(e2eUser, 10), strconv.Itoa(e2eThread)
+	h.bot.state.BindThread(userID, threadID, windowID)
+	h.bot.state.SetGroupChatID(userID, threadID, e2eChat)
+	h.bot.state.SetWindowState(windowID, state.WindowState{SessionID: "sess-1", CWD: dir})
+	return windowID
+}
+
+func TestE2E_CCForwardsAnySlashCommand(t *testing.T) {
+	h := startE2E(t, "fresh")
+	windowID := bindForwardTopic(t, h, "")
+
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "/cc review src/")
+	h.tmux.WaitForKeys(windowID, "/review src/")
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "/cc /project:deploy")
+	h.tmux.WaitForKeys(windowID, "/project:deploy")
+}
+
+func TestE2E_ForwardListedCommand(t *testing.T) {
+	h := startE2E(t, "fresh")
+	h.cfg.ForwardCommands = []string{"init"}
+	windowID := bindForwardTopic(t, h, "")
+
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "/init")
+	h.tmux.WaitForKeys(windowID, "/init")
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "/review")
+	h.tg.WaitForText("sendMessage", "Unknown command: /review")
+}
+
+func TestE2E_ProjectAliases(t *testing.T) {
+	h := startE2E(t, "fresh")
+	windowID := bindForwardTopic(t, h, "aliases:\n  rv: /review --strict\n  unit: \"!go test ./...\"\n")
+
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "/rv cmd/")
+	h.tmux.WaitForKeys(windowID, "/review --strict cmd/")
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "/unit")
+	h.tmux.WaitForKeys(windowID, "!")
+	h.tmux.WaitForKeys(windowID, "go test ./...")
+}
@@ -1,6 +1,7 @@
 package bot
 
 import (
+	"context"
 	"log"
 	"strconv"
 	"strings"
@@ -11,7 +12,7 @@ import (
 )
 
 // handleTextMessage forwards user text to the bound tmux window.
-func (b *Bot) handleTextMessage(msg *tgbotapi.Message) {
+func (b *Bot) handleTextMessage(ctx context.Context, msg *tgbotapi.Message) {
 	userID := strconv.FormatInt(msg.From.ID, 10)
 	threadID := strconv.Itoa(getThreadID(msg))
 	chatID := msg.Chat.ID
@@ -26,6 +27,16 @@ func (b *Bot) handleTextMessage(msg *tgbotapi.Message) {
 		return
 	}
 
+	// Buffer into a /compose draft
+	if b.handleComposeInput(msg) {
+		return
+	}
+
+	// Replacement text for a prompt held by /confirm
+	if b.handleConfirmEdit(msg) {
+		return
+	}
+
 	// Cancel any running bash capture for this topic
 	cancelBashCapture(msg.From.ID, getThreadID(msg))
 
@@ -40,23 +51,74 @@ func (b *Bot) handleTextMessage(msg *tgbotapi.Message) {
 		return
 	}
 
+	if b.rejectIfObserved(msg) {
+		return
+	}
+
 	text := msg.Text
 
-	// Handle ! prefix for bash commands
-	if strings.HasPrefix(text, "!") && len(text) > 1 {
-		b.handleBashCommand(msg, windowID, text)
+	// Raw mode types keystrokes for whatever runs in the window
+	if isRawTopic(threadID) {
+		b.sendRawInput(msg, windowID, text)
+		return
+	}
+
+	// Don't type prompts into the bare shell left behind when Claude exits
+	if claudeExited(windowID) {
+		b.reply(chatID, getThreadID(msg), "Claude is not running in this window. Use the Restart Claude button first.")
+		return
+	}
+	if b.rejectIfOverBudget(msg, windowID) {
+		return
+	}
+
+	p := b.preparePrompt(msg, windowID)
+	if b.state.IsConfirmed(threadID) {
+		b.holdPrompt(p)
 		return
 	}
+	b.typePrompt(ctx, msg.From, p)
+}
+
+// preparePrompt turns a message into what is typed for it: a ! command as
+// is, any other text with the topic's attribution and preamble applied.
+func (b *Bot) preparePrompt(msg *tgbotapi.Message, windowID string) *heldPrompt {
+	threadID := strconv.Itoa(getThreadID(msg))
+	text := msg.Text
+
+	// Handle ! prefix for bash commands
+	bash := strings.HasPrefix(text, "!") && len(text) > 1
+	preambleSent := func() {}
+	if !bash {
+		if b.state.IsAttributed(threadID) {
+			text = attributePrompt(senderName(msg.From), text)
+		}
+		text, preambleSent = b.applyPreamble(threadID, windowID, text)
+	}
+	return &heldPrompt{Msg: msg, WindowID: windowID, Sent: msg.Text, Text: text, Bash: bash, preambleSent: preambleSent}
+}
+
+// typePrompt types a prompt, or runs a ! command, in its window. Returns
+// false if a prompt could not be typed; failures are reported to the topic.
+func (b *Bot) typePrompt(ctx context.Context, from *tgbotapi.User, p *heldPrompt) bool {
+	msg := p.Msg
+	b.auditPrompt(from, getThreadID(msg), p.WindowID, p.Sent)
+	if p.Bash {
+		b.handleBashCommand(ctx, msg, p.WindowID, p.Text)
+		return true
+	}
 
-	// Send text to tmux with 500ms delay before Enter
-	if err := tmux.SendKeysWithDelay(b.config.TmuxSessionName, windowID, text, 500); err != nil {
+	if err := b.sendUserText(ctx, p.WindowID, p.Text); err != nil {
 		if tmux.IsWindowDead(err) {
-			b.handleDeadWindow(msg, windowID, text)
-			return
+			b.handleDeadWindow(msg, p.WindowID, p.Text)
+			return false
 		}
-		log.Printf("Error sending keys to %s: %v", windowID, err)
-		b.reply(chatID, getThreadID(msg), "Error: failed to send to Claude session.")
+		log.Printf("Error sending keys to %s: %v", p.WindowID, err)
+		b.reply(msg.Chat.ID, getThreadID(msg), "Error: failed to send to Claude session.")
+		return false
 	}
+	p.preambleSent()
+	return true
 }
 
 // handleUnboundTopic shows window picker or directory browser for an unbound topic.
@@ -92,25 +154,24 @@ func (b *Bot) handleUnboundTopic(msg *tgbotapi.Message) {
 }
 
 // handleBashCommand sends a ! command to Claude's bash mode.
-func (b *Bot) handleBashCommand(msg *tgbotapi.Message, windowID, text string) {
+func (b *Bot) handleBashCommand(ctx context.Context, msg *tgbotapi.Message, windowID, text string) {
 	session := b.config.TmuxSessionName
 
-	// Send ! first to enter bash mode
-	if err := tmux.SendKeys(session, windowID, "!"); err != nil {
-		if tmux.IsWindowDead(err) {
-			b.handleDeadWindow(msg, windowID, text)
-			return
-		}
-		log.Printf("Error sending ! to %s: %v", windowID, err)
-		return
-	}
-
-	// Wait 1 second
-	time.Sleep(1 * time.Second)
-
-	// Send the rest of the command (without !) + Enter
+	// Send ! to enter bash mode, wait for it, then the rest of the command
+	// (without !) + Enter, with no other input to the window in between
 	cmd := text[1:]
-	if err := tmux.SendKeysWithDelay(session, windowID, cmd, 500); err != nil {
+	err := tmux.WithWindowContext(ctx, session, windowID, func(w tmux.Writer) error {
+		if err := w.Keys("!"); err != nil {
+			return err
+		}
+		time.Sleep(1 * time.Second)
+		if err := w.Keys(cmd); err != nil {
+			return err
+		}
+		time.Sleep(500 * time.Millisecond)
+		return w.Enter()
+	})
+	if err != nil {
 		if tmux.IsWindowDead(err) {
 			b.handleDeadWindow(msg, windowID, text)
 			return
@@ -126,9 +187,22 @@ func (b *Bot) handleBashCommand(msg *tgbotapi.Message, windowID, text string) {
 }
 
 // routeCallback routes callback queries to the appropriate handler.
-func (b *Bot) routeCallback(cq *tgbotapi.CallbackQuery) {
+func (b *Bot) routeCallback(ctx context.Context, cq *tgbotapi.CallbackQuery) {
 	data := cq.Data
 
+	// Buttons of an expired or replaced browser or wizard say so instead of
+	// doing nothing
+	if notice := b.staleWizardNotice(cq); notice != "" {
+		b.api.Request(tgbotapi.NewCallbackWithAlert(cq.ID, notice))
+		return
+	}
+
+	// Buttons that type into a read-only topic's session are refused with an
+	// alert
+	if b.rejectObservedCallback(cq) {
+		return
+	}
+
 	// Answer callback to dismiss spinner
 	callback := tgbotapi.NewCallback(cq.ID, "")
 	b.api.Request(callback)
@@ -142,12 +216,18 @@ func (b *Bot) routeCallback(cq *tgbotapi.CallbackQuery) {
 		b.handleHistoryCallback(cq)
 	case strings.HasPrefix(data, "ss_"):
 		b.handleScreenshotCallback(cq)
+	case strings.HasPrefix(data, "sset_"):
+		b.processScreenshotSettingsCallback(cq)
 	case strings.HasPrefix(data, "nav_"):
 		b.handleInteractiveCallback(cq)
 	case strings.HasPrefix(data, "get_"):
 		b.processFileBrowserCallback(cq)
 	case strings.HasPrefix(data, "task_"):
 		b.processAddTaskCallback(cq)
+	case strings.HasPrefix(data, "proj_"):
+		b.processProjectsCallback(cq)
+	case strings.HasPrefix(data, "tedit_"):
+		b.processTaskEditCallback(cq)
 	case strings.HasPrefix(data, "tpick_"):
 		b.processTaskPickerCallback(cq)
 	case strings.HasPrefix(data, "merge_"):
@@ -158,8 +238,42 @@ func (b *Bot) routeCallback(cq *tgbotapi.CallbackQuery) {
 		b.processPlannerCallback(cq, data)
 	case strings.HasPrefix(data, "approval_"):
 		b.processApprovalCallback(cq)
+	case strings.HasPrefix(data, "auto_"):
+		b.processAutoCallback(cq)
+	case strings.HasPrefix(data, "recover_"):
+		b.processRecoveryCallback(cq)
+	case strings.HasPrefix(data, "wd_"):
+		b.processWatchdogCallback(cq)
 	case strings.HasPrefix(data, "menu_"):
 		b.handleMenuCallback(cq)
+	case strings.HasPrefix(data, "compose_"):
+		b.processComposeCallback(cq)
+	case strings.HasPrefix(data, "art_"):
+		b.processArtifactCallback(cq)
+	case strings.HasPrefix(data, "bm_"):
+		b.processBookmarkCallback(cq)
+	case strings.HasPrefix(data, "access_"):
+		b.processAccessCallback(cq)
+	case strings.HasPrefix(data, "qa_"):
+		b.processQuickActionCallback(cq)
+	case strings.HasPrefix(data, "fref_"):
+		b.processFileRefCallback(cq)
+	case strings.HasPrefix(data, "bc_"):
+		b.processBroadcastCallback(cq)
+	case strings.HasPrefix(data, "pg_"):
+		b.processPageCallback(cq)
+	case strings.HasPrefix(data, "skill_"):
+		b.processSkillCallback(cq)
+	case strings.HasPrefix(data, "wt_"):
+		b.processWorktreeCallback(cq)
+	case strings.HasPrefix(data, "rg_"):
+		b.processGuardCallback(cq)
+	case strings.HasPrefix(data, "rw_"):
+		b.processRewindCallback(cq)
+	case strings.HasPrefix(data, "budget_"):
+		b.processBudgetCallback(cq)
+	case strings.HasPrefix(data, "cf_"):
+		b.processConfirmCallback(ctx, cq)
 	case data == "noop":
 		// No-op button (e.g., page counter), already answered above
 	default:
@@ -18,11 +18,8 @@ func newTestBot(t *testing.T) *Bot {
 			AllowedUsers:    []int64{100},
 			TmuxSessionName: "test-session",
 		},
-		state:              state.NewState(),
-		browseStates:       make(map[int64]*BrowseState),
-		windowCache:        make(map[int64][]tmux.Window),
-		windowPickerStates: make(map[int64]*windowPickerState),
-		addTaskStates:      make(map[int64]*addTaskState),
+		state:       state.NewState(),
+		windowCache: make(map[int64][]tmux.Window),
 	}
 }
 
@@ -32,8 +29,7 @@ func TestHandleTextMessage_StoresGroupChatID(t *testing.T) {
 			AllowedUsers:    []int64{100},
 			TmuxSessionName: "test-session",
 		},
-		state:        state.NewState(),
-		browseStates: make(map[int64]*BrowseState),
+		state: state.NewState(),
 	}
 
 	// Set up thread ID cache to simulate forum message
@@ -86,8 +82,7 @@ func TestHandleUnboundTopic_NoWindows(t *testing.T) {
 			AllowedUsers:    []int64{100},
 			TmuxSessionName: "nonexistent-session-for-test",
 		},
-		state:        state.NewState(),
-		browseStates: make(map[int64]*BrowseState),
+		state: state.NewState(),
 	}
 
 	// With no tmux session, ListWindows will fail, so handleUnboundTopic
@@ -3,21 +3,29 @@ package bot
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/otaviocarvalho/tramuntana/internal/monitor"
+	"github.com/otaviocarvalho/tramuntana/internal/render"
 	"github.com/otaviocarvalho/tramuntana/internal/state"
 )
 
 const entriesPerPage = 10
 
 // handleHistoryCommand shows paginated session transcript.
+// "/p_history archived" lists the topic's archived sessions instead.
 func (b *Bot) handleHistoryCommand(msg *tgbotapi.Message) {
+	if strings.EqualFold(strings.TrimSpace(msg.CommandArguments()), "archived") {
+		b.showArchivedSessions(msg)
+		return
+	}
 	windowID, bound := b.resolveWindow(msg)
 	if !bound {
 		b.reply(msg.Chat.ID, getThreadID(msg), "No session bound to this topic.")
@@ -43,7 +51,8 @@ func (b *Bot) handleHistoryCommand(msg *tgbotapi.Message) {
 	totalPages := (len(entries) + entriesPerPage - 1) / entriesPerPage
 	page := totalPages - 1
 
-	text := formatHistoryPage(entries, page, windowID)
+	loc := b.state.GetUserSettings(strconv.FormatInt(msg.From.ID, 10)).Location()
+	text := formatHistoryPage(entries, page, windowID, loc)
 	keyboard := buildHistoryKeyboard(windowID, page, totalPages)
 
 	if keyboard != nil {
@@ -53,24 +62,48 @@ func (b *Bot) handleHistoryCommand(msg *tgbotapi.Message) {
 	}
 }
 
-// handleHistoryCB handles history pagination callbacks.
+// handleHistoryCB handles history pagination callbacks. A windowID of
+// "a/<id>" pages through an archived session, and page -1 opens it at its
+// last page. Archived sessions are read in the background, since the first
+// read downloads the archive.
 func (b *Bot) handleHistoryCB(cq *tgbotapi.CallbackQuery) {
 	page, windowID, ok := parseHistCallbackData(cq.Data)
 	if !ok {
 		return
 	}
 
+	if id, archived := strings.CutPrefix(windowID, "a/"); archived {
+		go func() {
+			entries, title, err := b.readArchivedEntries(id)
+			if err != nil {
+				log.Printf("Error reading archived session %s: %v", id, err)
+				b.editMessageText(cq.Message.Chat.ID, cq.Message.MessageID, "Could not read the archived session: "+err.Error())
+				return
+			}
+			b.showHistoryPage(cq, entries, page, windowID, title)
+		}()
+		return
+	}
 	jsonlPath := b.findJSONLForWindow(windowID)
 	if jsonlPath == "" {
 		return
 	}
+	b.showHistoryPage(cq, readAllEntries(jsonlPath), page, windowID, windowID)
+}
 
-	entries := readAllEntries(jsonlPath)
+// showHistoryPage edits a history browser message to show one page of
+// entries; page -1 is the last page.
+func (b *Bot) showHistoryPage(cq *tgbotapi.CallbackQuery, entries []historyEntry, page int, windowID, title string) {
 	if len(entries) == 0 {
 		return
 	}
+	chatID := cq.Message.Chat.ID
+	messageID := cq.Message.MessageID
 
 	totalPages := (len(entries) + entriesPerPage - 1) / entriesPerPage
+	if page == -1 {
+		page = totalPages - 1
+	}
 	if page < 0 {
 		page = 0
 	}
@@ -78,12 +111,10 @@ func (b *Bot) handleHistoryCB(cq *tgbotapi.CallbackQuery) {
 		page = totalPages - 1
 	}
 
-	text := formatHistoryPage(entries, page, windowID)
+	loc := b.state.GetUserSettings(strconv.FormatInt(cq.From.ID, 10)).Location()
+	text := formatHistoryPage(entries, page, title, loc)
 	keyboard := buildHistoryKeyboard(windowID, page, totalPages)
 
-	chatID := cq.Message.Chat.ID
-	messageID := cq.Message.MessageID
-
 	if keyboard != nil {
 		b.editMessageWithKeyboard(chatID, messageID, text, *keyboard)
 	} else {
@@ -152,10 +183,25 @@ func readAllEntries(path string) []historyEntry {
 		return nil
 	}
 	defer f.Close()
+	return readEntries(f)
+}
+
+// readEntriesUntil reads the entries in the first offset bytes of a JSONL file.
+func readEntriesUntil(path string, offset int64) []historyEntry {
+	f, err := os.Open(path)
+	if err != nil {
+		log.Printf("Error opening JSONL file %s: %v", path, err)
+		return nil
+	}
+	defer f.Close()
+	return readEntries(io.LimitReader(f, offset))
+}
 
+// readEntries parses JSONL transcript lines into history entries.
+func readEntries(r io.Reader) []historyEntry {
 	var entries []historyEntry
 	pending := make(map[string]monitor.PendingTool)
-	scanner := bufio.NewScanner(f)
+	scanner := bufio.NewScanner(r)
 	scanner.Buffer(make([]byte, 0, 256*1024), 1024*1024)
 
 	for scanner.Scan() {
@@ -176,6 +222,7 @@ func readAllEntries(path string) []historyEntry {
 				Text:        pe.Text,
 				ToolName:    pe.ToolName,
 				IsError:     pe.IsError,
+				Timestamp:   pe.Timestamp,
 			})
 		}
 	}
@@ -190,10 +237,12 @@ type historyEntry struct {
 	Text        string
 	ToolName    string
 	IsError     bool
+	Timestamp   time.Time
 }
 
 // formatHistoryPage formats a page of history entries.
-func formatHistoryPage(entries []historyEntry, page int, windowID string) string {
+// Entries are prefixed with their time in loc when loc is non-nil.
+func formatHistoryPage(entries []historyEntry, page int, windowID string, loc *time.Location) string {
 	totalPages := (len(entries) + entriesPerPage - 1) / entriesPerPage
 
 	start := page * entriesPerPage
@@ -210,7 +259,11 @@ func formatHistoryPage(entries []historyEntry, page int, windowID string) string
 	lines = append(lines, "")
 
 	for _, entry := range pageEntries {
-		lines = append(lines, formatHistoryEntry(entry))
+		line := formatHistoryEntry(entry)
+		if ts := render.FormatTimestamp(entry.Timestamp, loc); ts != "" {
+			line = ts + " " + line
+		}
+		lines = append(lines, line)
 	}
 
 	return strings.Join(lines, "\n")
@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestFormatHistoryEntry_Text(t *testing.T) {
@@ -169,7 +170,7 @@ func TestFormatHistoryPage(t *testing.T) {
 		entries[i] = historyEntry{Role: "assistant", ContentType: "text", Text: "Message " + string(rune('A'+i))}
 	}
 
-	text := formatHistoryPage(entries, 0, "@1")
+	text := formatHistoryPage(entries, 0, "@1", nil)
 	if text == "" {
 		t.Error("should produce non-empty text")
 	}
@@ -239,3 +240,17 @@ func containsStr(s, substr string) bool {
 	}
 	return false
 }
+
+func TestFormatHistoryPage_Timestamps(t *testing.T) {
+	entries := []historyEntry{{
+		Role:        "assistant",
+		ContentType: "text",
+		Text:        "done",
+		Timestamp:   time.Date(2025, 6, 1, 23, 5, 0, 0, time.UTC),
+	}}
+	loc := time.FixedZone("UTC+2", 2*3600)
+	text := formatHistoryPage(entries, 0, "@1", loc)
+	if !contains(text, "01:05 > done") {
+		t.Errorf("expected local timestamp prefix, got: %s", text)
+	}
+}
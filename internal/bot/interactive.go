@@ -11,11 +11,15 @@ import (
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/otaviocarvalho/tramuntana/internal/monitor"
+	"github.com/otaviocarvalho/tramuntana/internal/state"
 	"github.com/otaviocarvalho/tramuntana/internal/tmux"
 )
 
 var interactiveRetryRe = regexp.MustCompile(`retry after (\d+)`)
 
+// numberedOptionRe matches a numbered choice in a prompt, e.g. "❯ 1. Yes".
+var numberedOptionRe = regexp.MustCompile(`(?m)^[\s❯>]*([1-9])\.\s`)
+
 // interactiveKey identifies an interactive UI session.
 type interactiveKey struct {
 	UserID   int64
@@ -44,13 +48,18 @@ func (b *Bot) handleInteractiveUI(chatID int64, threadID int, userID int64, wind
 		}
 		return
 	}
+	b.showInteractiveUI(chatID, threadID, userID, windowID, paneText)
+}
 
+// showInteractiveUI sends or updates the keyboard for interactive content in an
+// already captured pane.
+func (b *Bot) showInteractiveUI(chatID int64, threadID int, userID int64, windowID, paneText string) {
 	ui, ok := monitor.ExtractInteractiveContent(paneText)
-	if !ok {
+	if !ok || rewindInProgress(userID, threadID, windowID, ui.Name) {
 		return
 	}
 
-	keyboard := buildInteractiveKeyboard(ui.Name)
+	keyboard := buildInteractiveKeyboard(ui.Name, numberedOptions(ui.Content))
 	text := formatInteractiveContent(ui)
 
 	key := interactiveKey{userID, threadID}
@@ -71,7 +80,7 @@ func (b *Bot) handleInteractiveUI(chatID int64, threadID int, userID int64, wind
 		var msg tgbotapi.Message
 		if err := retryOnFlood(func() error {
 			var sendErr error
-			msg, sendErr = b.sendMessageWithKeyboard(chatID, threadID, text, keyboard)
+			msg, sendErr = b.sendKeyboardMessage(chatID, threadID, text, keyboard, b.quietFor(userID, true))
 			return sendErr
 		}); err != nil {
 			log.Printf("Error sending interactive message after retries: %v", err)
@@ -102,6 +111,68 @@ func clearInteractiveUI(userID int64, threadID int) {
 	interactive.mu.Unlock()
 }
 
+// remindPrompt re-sends a prompt left unanswered since since to each topic
+// bound to the window, with its keyboard and sound (unless /quiet urgent
+// silences it), and alerts the configured sinks. The reminder replaces the
+// tracked interactive message, so refreshes edit it and the original's
+// buttons don't linger. Topics driving a /rewind are left alone.
+func (b *Bot) remindPrompt(windowID, paneText string, since time.Time, users []state.UserThread) {
+	ui, ok := monitor.ExtractInteractiveContent(paneText)
+	if !ok {
+		return
+	}
+	var targets []state.UserThread
+	for _, ut := range users {
+		userID, _ := strconv.ParseInt(ut.UserID, 10, 64)
+		threadID, _ := strconv.Atoi(ut.ThreadID)
+		if !rewindInProgress(userID, threadID, windowID, ui.Name) {
+			targets = append(targets, ut)
+		}
+	}
+	if len(users) > 0 && len(targets) == 0 {
+		return
+	}
+	waited := formatElapsed(time.Since(since))
+	name, _ := b.state.GetWindowDisplayName(windowID)
+	if name == "" {
+		name = windowID
+	}
+	b.alerts.Alert("prompt:"+windowID, alertTitle("Session waiting for input"),
+		fmt.Sprintf("%s has waited %s for a %s answer.", name, waited, promptLabel(ui.Name)))
+
+	text := fmt.Sprintf("⏰ Still waiting for your answer after %s\n%s", waited, formatInteractiveContent(ui))
+	keyboard := buildInteractiveKeyboard(ui.Name, numberedOptions(ui.Content))
+	for _, ut := range targets {
+		chatID, ok := b.state.GetGroupChatID(ut.UserID, ut.ThreadID)
+		if !ok {
+			continue
+		}
+		userID, _ := strconv.ParseInt(ut.UserID, 10, 64)
+		threadID, _ := strconv.Atoi(ut.ThreadID)
+		var msg tgbotapi.Message
+		if err := retryOnFlood(func() error {
+			var sendErr error
+			msg, sendErr = b.sendKeyboardMessage(chatID, threadID, text, keyboard, b.quietFor(userID, true))
+			return sendErr
+		}); err != nil {
+			log.Printf("Error sending prompt reminder: %v", err)
+			continue
+		}
+		key := interactiveKey{userID, threadID}
+		interactive.mu.Lock()
+		original, hadOriginal := interactive.messages[key]
+		interactive.messages[key] = msg.MessageID
+		interactive.modes[key] = windowID
+		interactive.mu.Unlock()
+		if hadOriginal {
+			if err := b.deleteMessage(chatID, original); err != nil {
+				log.Printf("Error deleting prompt %d after its reminder: %v", original, err)
+			}
+		}
+	}
+	log.Printf("Reminded %s's topics of a prompt unanswered for %s", windowID, waited)
+}
+
 // handleInteractiveCallback processes interactive UI navigation callbacks.
 func (b *Bot) handleInteractiveCallback(cq *tgbotapi.CallbackQuery) {
 	userID := cq.From.ID
@@ -147,6 +218,9 @@ func (b *Bot) handleInteractiveCallback(cq *tgbotapi.CallbackQuery) {
 		sendErr = sendKey("Enter")
 		clearInteractiveUI(userID, threadID)
 		return
+	case len(data) == len("nav_1") && data[4] >= '1' && data[4] <= '9':
+		// Typing an option's number selects it
+		sendErr = tmux.SendKeys(session, windowID, data[4:])
 	case data == "nav_refresh":
 		// Just refresh, no key sent
 	default:
@@ -195,9 +269,31 @@ func retryOnFlood(fn func() error) error {
 	return nil // unreachable
 }
 
-// buildInteractiveKeyboard builds the inline keyboard for interactive navigation.
-func buildInteractiveKeyboard(uiType string) tgbotapi.InlineKeyboardMarkup {
+// numberedOptions returns the highest option number in a prompt's content, or
+// 0 if its choices aren't numbered.
+func numberedOptions(content string) int {
+	n := 0
+	for _, m := range numberedOptionRe.FindAllStringSubmatch(content, -1) {
+		n = max(n, int(m[1][0]-'0'))
+	}
+	return n
+}
+
+// numberRowSize is how many option buttons share a row.
+const numberRowSize = 5
+
+// buildInteractiveKeyboard builds the inline keyboard for interactive
+// navigation, starting with a button per numbered option (up to 9) that types
+// its number.
+func buildInteractiveKeyboard(uiType string, options int) tgbotapi.InlineKeyboardMarkup {
 	var rows [][]tgbotapi.InlineKeyboardButton
+	for first := 1; first <= options; first += numberRowSize {
+		var row []tgbotapi.InlineKeyboardButton
+		for n := first; n <= min(first+numberRowSize-1, options); n++ {
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData(strconv.Itoa(n), "nav_"+strconv.Itoa(n)))
+		}
+		rows = append(rows, row)
+	}
 
 	if uiType == "RestoreCheckpoint" {
 		// Vertical-only layout
@@ -246,7 +342,7 @@ func formatInteractiveContent(ui monitor.UIContent) string {
 		name = "Plan Review"
 	} else if name == "PermissionPrompt" {
 		name = "Permission"
-	} else if name == "RestoreCheckpoint" {
+	} else if name == "RestoreCheckpoint" || name == "RestoreConfirm" {
 		name = "Restore"
 	} else if name == "Settings" {
 		name = "Settings"
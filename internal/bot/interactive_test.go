@@ -1,13 +1,17 @@
 package bot
 
 import (
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/otaviocarvalho/tramuntana/internal/monitor"
+	"github.com/otaviocarvalho/tramuntana/internal/testharness"
 )
 
 func TestBuildInteractiveKeyboard_Full(t *testing.T) {
-	kb := buildInteractiveKeyboard("ExitPlanMode")
+	kb := buildInteractiveKeyboard("ExitPlanMode", 0)
 	if len(kb.InlineKeyboard) != 3 {
 		t.Fatalf("expected 3 rows, got %d", len(kb.InlineKeyboard))
 	}
@@ -26,7 +30,7 @@ func TestBuildInteractiveKeyboard_Full(t *testing.T) {
 }
 
 func TestBuildInteractiveKeyboard_RestoreCheckpoint(t *testing.T) {
-	kb := buildInteractiveKeyboard("RestoreCheckpoint")
+	kb := buildInteractiveKeyboard("RestoreCheckpoint", 0)
 	if len(kb.InlineKeyboard) != 2 {
 		t.Fatalf("expected 2 rows for RestoreCheckpoint, got %d", len(kb.InlineKeyboard))
 	}
@@ -36,6 +40,32 @@ func TestBuildInteractiveKeyboard_RestoreCheckpoint(t *testing.T) {
 	}
 }
 
+func TestBuildInteractiveKeyboard_NumberedOptions(t *testing.T) {
+	content := "Do you want to proceed?\n❯ 1. Yes\n  2. Yes, and don't ask again for git commands\n  3. No, and tell Claude what to do differently (esc)"
+	options := numberedOptions(content)
+	if options != 3 {
+		t.Fatalf("numberedOptions = %d, want 3", options)
+	}
+	kb := buildInteractiveKeyboard("PermissionPrompt", options)
+	if len(kb.InlineKeyboard) != 4 {
+		t.Fatalf("expected a number row plus 3, got %d rows", len(kb.InlineKeyboard))
+	}
+	for i, btn := range kb.InlineKeyboard[0] {
+		if want := strconv.Itoa(i + 1); btn.Text != want || *btn.CallbackData != "nav_"+want {
+			t.Errorf("button %d = %q (%s)", i, btn.Text, *btn.CallbackData)
+		}
+	}
+
+	// Nine options take two rows, within Telegram's 8 buttons per row
+	kb = buildInteractiveKeyboard("AskUserQuestion_single", 9)
+	if len(kb.InlineKeyboard[0]) != 5 || len(kb.InlineKeyboard[1]) != 4 {
+		t.Errorf("number rows = %d + %d buttons", len(kb.InlineKeyboard[0]), len(kb.InlineKeyboard[1]))
+	}
+	if numberedOptions("Settings:\n  Theme  dark") != 0 {
+		t.Error("unnumbered prompt has options")
+	}
+}
+
 func TestFormatInteractiveContent(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -73,3 +103,79 @@ func TestCallbackDataPrefixes(t *testing.T) {
 		}
 	}
 }
+
+func TestE2E_NumberButtonTypesOption(t *testing.T) {
+	h := startE2E(t, "fresh")
+	windowID := h.tmux.AddWindow("api", "/work/api")
+	userID, threadID := strconv.FormatInt(e2eUser, 10), strconv.Itoa(e2eThread)
+	h.bot.state.BindThread(userID, threadID, windowID)
+	h.bot.state.SetGroupChatID(userID, threadID, e2eChat)
+	h.tmux.SetPane(windowID, testharness.ReadyPane)
+	h.startStatusPoller()
+
+	h.tmux.SetPane(windowID, "Do you want to proceed?\n❯ 1. Yes\n  2. Yes, and don't ask again\n  3. No\nEsc to cancel\n")
+	prompt := h.tg.WaitForText("sendMessage", "Do you want to proceed?")
+	if !strings.Contains(prompt.Params["reply_markup"], `"callback_data":"nav_3"`) {
+		t.Fatalf("no option buttons: %s", prompt.Params["reply_markup"])
+	}
+	h.tg.PushCallback(e2eChat, e2eThread, e2eUser, prompt.MessageID, "nav_2")
+	h.tmux.WaitForKeys(windowID, "2")
+}
+
+func TestE2E_UnansweredPromptReminder(t *testing.T) {
+	h := startE2E(t, "fresh")
+	h.cfg.PromptReminder = 300 * time.Millisecond
+	windowID := h.tmux.AddWindow("api", "/work/api")
+	userID, threadID := strconv.FormatInt(e2eUser, 10), strconv.Itoa(e2eThread)
+	h.bot.state.BindThread(userID, threadID, windowID)
+	h.bot.state.SetGroupChatID(userID, threadID, e2eChat)
+	h.tmux.SetPane(windowID, testharness.ReadyPane)
+	h.startStatusPoller()
+
+	h.tmux.SetPane(windowID, "Do you want to proceed?\n❯ 1. Yes\n  2. No\nEsc to cancel\n")
+	prompt := h.tg.WaitForText("sendMessage", "Do you want to proceed?")
+	reminder := h.tg.WaitForText("sendMessage", "Still waiting for your answer")
+	if reminder.Params["disable_notification"] == "true" || !strings.Contains(reminder.Params["reply_markup"], `"callback_data":"nav_2"`) {
+		t.Errorf("reminder params = %v", reminder.Params)
+	}
+	// The original prompt and its buttons give way to the reminder
+	h.tg.WaitFor("deleteMessage", func(c testharness.Call) bool {
+		return c.Params["message_id"] == strconv.Itoa(prompt.MessageID)
+	})
+
+	// Its buttons drive the prompt, and only one reminder is sent
+	h.tg.PushCallback(e2eChat, e2eThread, e2eUser, reminder.MessageID, "nav_2")
+	h.tmux.WaitForKeys(windowID, "2")
+	time.Sleep(500 * time.Millisecond)
+	var reminders int
+	for _, c := range h.tg.Calls("sendMessage") {
+		if strings.Contains(c.Params["text"], "Still waiting") {
+			reminders++
+		}
+	}
+	if reminders != 1 {
+		t.Errorf("sent %d reminders, want 1", reminders)
+	}
+}
+
+func TestRemindPrompt_SkipsTopicsDrivingRewind(t *testing.T) {
+	h := startE2E(t, "fresh")
+	windowID := h.tmux.AddWindow("api", "/work/api")
+	userID, threadID := strconv.FormatInt(e2eUser, 10), strconv.Itoa(e2eThread)
+	h.bot.state.BindThread(userID, threadID, windowID)
+	h.bot.state.SetGroupChatID(userID, threadID, e2eChat)
+	key := interactiveKey{e2eUser, e2eThread}
+	rewindMu.Lock()
+	rewinds[key] = &rewindState{windowID: windowID, started: time.Now()}
+	rewindMu.Unlock()
+	defer func() {
+		rewindMu.Lock()
+		delete(rewinds, key)
+		rewindMu.Unlock()
+	}()
+
+	h.bot.remindPrompt(windowID, rewindListPane, time.Now().Add(-time.Hour), h.bot.state.FindUsersForWindow(windowID))
+	if sends := h.tg.Calls("sendMessage"); len(sends) != 0 {
+		t.Errorf("reminded a topic driving /rewind: %v", sends)
+	}
+}
@@ -0,0 +1,77 @@
+package bot
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/config"
+)
+
+// labelOriginNames describe each origin in /labels.
+var labelOriginNames = map[string]string{
+	"assistant": "Claude's answers",
+	"tool":      "tool calls and results",
+	"system":    "status, notices and compaction",
+	"user":      "your prompts",
+}
+
+// handleLabelsCommand shows or sets the glyphs this topic's messages start
+// with, by where their content came from. Usage: /labels [theme|default].
+func (b *Bot) handleLabelsCommand(msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+	threadID := getThreadID(msg)
+	threadIDStr := strconv.Itoa(threadID)
+
+	arg := strings.ToLower(strings.TrimSpace(msg.CommandArguments()))
+	switch {
+	case arg == "":
+		b.reply(chatID, threadID, formatLabels(b.state.GetLabelTheme(threadIDStr), b.labels(threadIDStr)))
+		return
+	case arg == "default":
+		b.state.SetLabelTheme(threadIDStr, "")
+	case config.LabelThemes[arg] != nil:
+		b.state.SetLabelTheme(threadIDStr, arg)
+	default:
+		b.reply(chatID, threadID, "Unknown theme. Usage: /labels ["+strings.Join(labelThemeNames(), "|")+"|default]")
+		return
+	}
+	b.saveState()
+	b.reply(chatID, threadID, "Labels updated.\n"+formatLabels(b.state.GetLabelTheme(threadIDStr), b.labels(threadIDStr)))
+}
+
+// formatLabels shows a topic's theme, the glyph for each origin and the
+// themes it can switch to.
+func formatLabels(name string, theme config.LabelTheme) string {
+	if name == "" {
+		name = "default (TRAMUNTANA_LABELS)"
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "🏷 Labels in this topic: %s", name)
+	for _, origin := range config.LabelOrigins {
+		glyph := theme[origin]
+		if glyph == "" {
+			glyph = "(none)"
+		}
+		fmt.Fprintf(&sb, "\n%s %s", glyph, labelOriginNames[origin])
+	}
+	fmt.Fprintf(&sb, "\n\nThemes: %s. Use /labels <theme>, or /labels default.", strings.Join(labelThemeNames(), ", "))
+	return sb.String()
+}
+
+// labelThemeNames returns the themes /labels offers, sorted.
+func labelThemeNames() []string {
+	names := make([]string, 0, len(config.LabelThemes))
+	for name := range config.LabelThemes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// labels returns the label theme a topic's messages use.
+func (b *Bot) labels(threadID string) config.LabelTheme {
+	return b.config.LabelTheme(b.state.GetLabelTheme(threadID))
+}
@@ -0,0 +1,50 @@
+package bot
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/otaviocarvalho/tramuntana/internal/testharness"
+)
+
+func TestE2E_LabelsMarkContentOrigin(t *testing.T) {
+	h := startE2E(t, "fresh")
+	windowID := h.tmux.AddWindow("api", "/work/api")
+	userID, threadID := strconv.FormatInt(e2eUser, 10), strconv.Itoa(e2eThread)
+	h.bot.state.BindThread(userID, threadID, windowID)
+	h.bot.state.SetGroupChatID(userID, threadID, e2eChat)
+	h.writeSession(t, windowID, "sess-1", "/work/api")
+
+	// The classic theme only marks echoed prompts
+	h.appendTranscript(t, "sess-1",
+		`{"type":"user","message":{"content":"what changed"}}`,
+		`{"type":"assistant","message":{"content":[{"type":"text","text":"Nothing yet"}]}}`,
+	)
+	h.tg.WaitFor("sendMessage", func(c testharness.Call) bool {
+		return strings.Contains(c.Params["text"], "👤 what changed") && strings.Contains(c.Params["text"], "\nNothing yet")
+	})
+
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "/labels neon")
+	h.tg.WaitForText("sendMessage", "Unknown theme")
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "/labels emoji")
+	h.tg.WaitForText("sendMessage", "🤖 Claude's answers")
+	if got := h.bot.state.GetLabelTheme(threadID); got != "emoji" {
+		t.Fatalf("theme = %q", got)
+	}
+
+	h.appendTranscript(t, "sess-1",
+		`{"type":"assistant","message":{"content":[{"type":"tool_use","id":"t1","name":"Bash","input":{"command":"git status"}}]}}`,
+	)
+	h.tg.WaitForText("sendMessage", "🔧 ")
+	h.appendTranscript(t, "sess-1",
+		`{"type":"assistant","message":{"content":[{"type":"text","text":"The tree is clean"}]}}`,
+	)
+	h.tg.WaitForText("sendMessage", "🤖 The tree is clean")
+
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "/labels default")
+	h.tg.WaitForText("sendMessage", "Labels in this topic: default")
+	if got := h.bot.state.GetLabelTheme(threadID); got != "" {
+		t.Errorf("theme after default = %q", got)
+	}
+}
@@ -0,0 +1,159 @@
+package bot
+
+import (
+	"log"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/otaviocarvalho/tramuntana/internal/config"
+	"github.com/otaviocarvalho/tramuntana/internal/git"
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+)
+
+// launchVars are the template fields available in CLAUDE_COMMAND, in a
+// .tramuntana.yaml command, e.g. "claude --add-dir {{.Dir}}/../docs", and in
+// TRAMUNTANA_WINDOW_NAME.
+type launchVars struct {
+	Dir     string // window directory
+	Project string // topic's Minuano project, else the directory name
+	Branch  string // current git branch, empty outside a repository
+}
+
+// launchSpec is how Claude is started in a new window.
+type launchSpec struct {
+	Command string
+	Env     map[string]string // merged over the Minuano environment
+}
+
+// resolveLaunch returns the Claude command and extra environment for a window
+// in dir for a project (may be empty): the directory's .tramuntana.yaml overrides CLAUDE_COMMAND, and the
+// result is rendered with launchVars. A broken override is logged and
+// CLAUDE_COMMAND is used instead.
+func (b *Bot) resolveLaunch(dir, project string) launchSpec {
+	spec := launchSpec{Command: b.config.ClaudeCommand}
+	pc, err := config.LoadProjectConfig(dir)
+	if err != nil {
+		log.Printf("Ignoring project config: %v", err)
+	}
+	if pc != nil {
+		if pc.Command != "" {
+			spec.Command = pc.Command
+		}
+		spec.Env = pc.Env
+	}
+
+	vars := newLaunchVars(dir, project)
+	cmd, err := renderLaunchCommand(spec.Command, vars)
+	if err != nil && spec.Command != b.config.ClaudeCommand {
+		log.Printf("Error rendering Claude command %q: %v", spec.Command, err)
+		cmd, err = renderLaunchCommand(b.config.ClaudeCommand, vars)
+	}
+	if err != nil {
+		log.Printf("Error rendering CLAUDE_COMMAND: %v", err)
+		cmd = b.config.ClaudeCommand
+	}
+	spec.Command = cmd
+	return spec
+}
+
+// resolveSessionLaunch is resolveLaunch with the model and permission mode
+// last picked for dir, for relaunching a session there (watchdog restart,
+// recovery, fork) the way the directory browser first started it.
+func (b *Bot) resolveSessionLaunch(dir, project string) launchSpec {
+	spec := b.resolveLaunch(dir, project)
+	spec.Command = withLaunchChoice(spec.Command, b.state.GetDirLaunch(dir))
+	return spec
+}
+
+// newLaunchVars fills launchVars for a directory and project (may be empty).
+func newLaunchVars(dir, project string) launchVars {
+	vars := launchVars{Dir: dir, Project: project}
+	if vars.Project == "" {
+		vars.Project = filepath.Base(dir)
+	}
+	if branch, err := git.CurrentBranch(dir); err == nil {
+		vars.Branch = branch
+	}
+	return vars
+}
+
+// renderLaunchCommand executes a command template with each field
+// shell-quoted, since the command is typed into a shell: a directory with a
+// space or a branch named "x;touch pwned" stays one word. Commands without
+// template actions are returned unchanged.
+func renderLaunchCommand(cmd string, vars launchVars) (string, error) {
+	return renderTemplate(cmd, launchVars{
+		Dir:     shellQuote(vars.Dir),
+		Project: shellQuote(vars.Project),
+		Branch:  shellQuote(vars.Branch),
+	})
+}
+
+// shellSafeRe matches words a shell takes literally.
+var shellSafeRe = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+// shellQuote returns s as one shell word, single-quoting it unless it is
+// already safe.
+func shellQuote(s string) string {
+	if shellSafeRe.MatchString(s) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// renderTemplate executes a launchVars template as is. Templates without
+// actions are returned unchanged.
+func renderTemplate(text string, vars launchVars) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+	tmpl, err := template.New("command").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, vars); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// launchOption is a quick choice on the directory browser's session options
+// step; an empty Value keeps Claude's default.
+type launchOption struct {
+	Value string
+	Label string
+}
+
+var (
+	launchModels = []launchOption{
+		{"", "Default"}, {"opus", "Opus"}, {"sonnet", "Sonnet"}, {"haiku", "Haiku"},
+	}
+	launchPermissionModes = []launchOption{
+		{"", "Default"}, {"acceptEdits", "Accept edits"}, {"plan", "Plan"}, {"bypassPermissions", "Bypass"},
+	}
+)
+
+// optionLabel returns the label of value in opts, or value itself.
+func optionLabel(opts []launchOption, value string) string {
+	for _, o := range opts {
+		if o.Value == value {
+			return o.Label
+		}
+	}
+	return value
+}
+
+// withLaunchChoice appends --model and --permission-mode to a Claude command
+// for the choices that are set.
+func withLaunchChoice(cmd string, lc state.LaunchChoice) string {
+	if lc.Model != "" {
+		cmd += " --model " + lc.Model
+	}
+	if lc.PermissionMode != "" {
+		cmd += " --permission-mode " + lc.PermissionMode
+	}
+	return cmd
+}
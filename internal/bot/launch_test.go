@@ -0,0 +1,91 @@
+package bot
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+)
+
+func TestRenderLaunchCommand(t *testing.T) {
+	vars := launchVars{Dir: "/src/app", Project: "app", Branch: "main"}
+	got, err := renderLaunchCommand("claude --add-dir {{.Dir}}/docs --name {{.Project}}-{{.Branch}}", vars)
+	if err != nil || got != "claude --add-dir /src/app/docs --name app-main" {
+		t.Errorf("got %q, %v", got, err)
+	}
+	if got, err := renderLaunchCommand("claude", vars); err != nil || got != "claude" {
+		t.Errorf("plain command = %q, %v", got, err)
+	}
+	if _, err := renderLaunchCommand("claude {{.Model}}", vars); err == nil {
+		t.Error("expected error for unknown field")
+	}
+
+	// Fields are typed into a shell, so they are quoted
+	vars = launchVars{Dir: "/src/my app", Project: "it's", Branch: "x;touch pwned"}
+	got, err = renderLaunchCommand("claude --add-dir {{.Dir}}/docs --name {{.Project}}-{{.Branch}}", vars)
+	if want := `claude --add-dir '/src/my app'/docs --name 'it'\''s'-'x;touch pwned'`; err != nil || got != want {
+		t.Errorf("hostile fields = %q, %v; want %q", got, err, want)
+	}
+}
+
+func TestResolveLaunch_ProjectOverride(t *testing.T) {
+	b := newTestBot(t)
+	b.config.ClaudeCommand = "claude --name {{.Project}}"
+
+	dir := t.TempDir()
+	if got := b.resolveLaunch(dir, ""); got.Command != "claude --name "+filepath.Base(dir) || got.Env != nil {
+		t.Errorf("default launch = %+v", got)
+	}
+	if got := b.resolveLaunch(dir, "billing"); got.Command != "claude --name billing" {
+		t.Errorf("project launch = %q", got.Command)
+	}
+
+	override := "command: claude --model opus --add-dir {{.Dir}}/shared\nenv:\n  NODE_ENV: test\n"
+	if err := os.WriteFile(filepath.Join(dir, ".tramuntana.yaml"), []byte(override), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got := b.resolveLaunch(dir, "billing")
+	if got.Command != "claude --model opus --add-dir "+dir+"/shared" || got.Env["NODE_ENV"] != "test" {
+		t.Errorf("override launch = %+v", got)
+	}
+
+	// A broken override falls back to CLAUDE_COMMAND
+	if err := os.WriteFile(filepath.Join(dir, ".tramuntana.yaml"), []byte("command: claude {{.Nope}}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.resolveLaunch(dir, "billing"); got.Command != "claude --name billing" {
+		t.Errorf("fallback launch = %q", got.Command)
+	}
+}
+
+func TestWithLaunchChoice(t *testing.T) {
+	if got := withLaunchChoice("claude", state.LaunchChoice{}); got != "claude" {
+		t.Errorf("default choice = %q", got)
+	}
+	got := withLaunchChoice("claude", state.LaunchChoice{Model: "opus", PermissionMode: "plan"})
+	if got != "claude --model opus --permission-mode plan" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestBuildLaunchOptions(t *testing.T) {
+	text, kb := buildLaunchOptions("/src/app", state.LaunchChoice{Model: "sonnet"})
+	if !strings.Contains(text, "Model: Sonnet") || !strings.Contains(text, "Permission mode: Default") {
+		t.Errorf("text = %q", text)
+	}
+	if len(kb.InlineKeyboard) != 3 {
+		t.Fatalf("rows = %d, want model, mode and actions", len(kb.InlineKeyboard))
+	}
+	models := kb.InlineKeyboard[0]
+	if models[2].Text != "✓ Sonnet" || *models[2].CallbackData != "dir_model:2" {
+		t.Errorf("sonnet button = %q %q", models[2].Text, *models[2].CallbackData)
+	}
+	if modes := kb.InlineKeyboard[1]; modes[0].Text != "✓ Default" {
+		t.Errorf("default mode should be marked, got %q", modes[0].Text)
+	}
+	if start := kb.InlineKeyboard[2][1]; *start.CallbackData != "dir_start" {
+		t.Errorf("start button = %q", *start.CallbackData)
+	}
+}
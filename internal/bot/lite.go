@@ -0,0 +1,70 @@
+package bot
+
+import (
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/render"
+)
+
+// litePaneBytes caps the pane text a lite screenshot sends, from the bottom.
+const litePaneBytes = 3000
+
+// handleLiteCommand toggles the user's bandwidth-saving mode for metered
+// connections. Usage: /lite [on|off]; no argument shows the setting.
+func (b *Bot) handleLiteCommand(msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+	threadID := getThreadID(msg)
+	userID := strconv.FormatInt(msg.From.ID, 10)
+	us := b.state.GetUserSettings(userID)
+
+	switch strings.ToLower(strings.TrimSpace(msg.CommandArguments())) {
+	case "":
+		if us.Lite {
+			b.reply(chatID, threadID, "Lite mode is on: screenshots arrive as text, tool results as one line, files Claude writes are offered instead of sent, and browsers show fewer entries. Use /lite off to leave it.")
+		} else {
+			b.reply(chatID, threadID, "Lite mode is off. Use /lite on to save bandwidth on a metered connection.")
+		}
+	case "on":
+		us.Lite = true
+		b.state.SetUserSettings(userID, us)
+		b.saveState()
+		b.reply(chatID, threadID, "Lite mode on: screenshots arrive as text, tool results as one line, files Claude writes are offered instead of sent, and browsers show fewer entries.")
+	case "off":
+		us.Lite = false
+		b.state.SetUserSettings(userID, us)
+		b.saveState()
+		b.reply(chatID, threadID, "Lite mode off.")
+	default:
+		b.reply(chatID, threadID, "Usage: /lite [on|off]")
+	}
+}
+
+// isLite reports whether a user has lite mode on.
+func (b *Bot) isLite(userID int64) bool {
+	return b.state.GetUserSettings(strconv.FormatInt(userID, 10)).Lite
+}
+
+// browserPageSize is how many entries a browser page shows the user: half
+// of normal in lite mode.
+func (b *Bot) browserPageSize(userID int64, normal int) int {
+	if b.isLite(userID) {
+		return max(normal/2, 2)
+	}
+	return normal
+}
+
+// litePane is a text rendering of pane text in place of a screenshot: its
+// last litePaneBytes, in a code block.
+func litePane(paneText string) string {
+	text := strings.TrimRight(paneText, "\n ")
+	if len(text) > litePaneBytes {
+		cut := len(text) - litePaneBytes
+		if i := strings.IndexByte(text[cut:], '\n'); i >= 0 {
+			cut += i + 1
+		}
+		text = "…\n" + text[cut:]
+	}
+	return render.ToMarkdownV2("```\n" + text + "\n```")
+}
@@ -0,0 +1,44 @@
+package bot
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestLitePane(t *testing.T) {
+	got := litePane("hello\nworld\n\n")
+	if got != "```\nhello\nworld\n```" {
+		t.Errorf("litePane = %q", got)
+	}
+
+	long := strings.Repeat("0123456789\n", litePaneBytes/11+50)
+	got = litePane(long + "last")
+	if len(got) > litePaneBytes+20 || !strings.HasPrefix(got, "```\n…\n0123456789\n") || !strings.HasSuffix(got, "last\n```") {
+		t.Errorf("litePane of long pane: %d bytes, starts %q", len(got), got[:20])
+	}
+}
+
+func TestE2E_LiteScreenshotIsText(t *testing.T) {
+	h := startE2E(t, "fresh")
+	windowID := h.tmux.AddWindow("api", "/work/api")
+	userID, threadID := strconv.FormatInt(e2eUser, 10), strconv.Itoa(e2eThread)
+	h.bot.state.BindThread(userID, threadID, windowID)
+	h.bot.state.SetGroupChatID(userID, threadID, e2eChat)
+	h.tmux.SetPane(windowID, "build ok\n❯ ")
+
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "/lite on")
+	h.tg.WaitForText("sendMessage", "Lite mode on")
+	if got := h.bot.browserPageSize(e2eUser, dirsPerPage); got != dirsPerPage/2 {
+		t.Errorf("lite browser page size = %d, want %d", got, dirsPerPage/2)
+	}
+
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "/c_screenshot")
+	got := h.tg.WaitForText("sendMessage", "build ok")
+	if got.Params["parse_mode"] != "MarkdownV2" || !strings.Contains(got.Params["reply_markup"], "ss_refresh:") {
+		t.Errorf("text screenshot params = %v", got.Params)
+	}
+	if n := len(h.tg.Calls("sendDocument")); n != 0 {
+		t.Errorf("lite screenshot sent %d document(s)", n)
+	}
+}
@@ -6,7 +6,6 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
-	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/otaviocarvalho/tramuntana/internal/git"
@@ -232,7 +231,19 @@ func (b *Bot) executeMerge(msg *tgbotapi.Message, branch string) {
 		log.Printf("Error resetting after conflict in %s: %v", repoRoot, resetErr)
 	}
 
-	b.reply(chatID, threadID, fmt.Sprintf("Conflict in %d files. Creating merge topic...", len(conflictErr.Files)))
+	start := func() { b.startMergeSession(msg, repoRoot, branch, baseBranch, conflictErr.Files) }
+	if b.guardSession(chatID, threadID, msg.From.ID, start) {
+		start()
+	}
+}
+
+// startMergeSession creates a merge topic and a Claude session in repoRoot to
+// resolve the conflicts squash-merging branch into baseBranch.
+func (b *Bot) startMergeSession(msg *tgbotapi.Message, repoRoot, branch, baseBranch string, files []string) {
+	chatID := msg.Chat.ID
+	threadID := getThreadID(msg)
+
+	b.reply(chatID, threadID, fmt.Sprintf("Conflict in %d files. Creating merge topic...", len(files)))
 
 	// Create merge topic
 	topicName := fmt.Sprintf("Merge: %s", branch)
@@ -260,7 +271,7 @@ func (b *Bot) executeMerge(msg *tgbotapi.Message, branch string) {
 	b.saveState()
 
 	// Build conflict resolution prompt — use squash merge in the instructions too
-	conflictList := strings.Join(conflictErr.Files, "\n  - ")
+	conflictList := strings.Join(files, "\n  - ")
 	prompt := fmt.Sprintf(`Merge branch %s into %s.
 
 1. Run: git merge --squash %s
@@ -273,12 +284,10 @@ func (b *Bot) executeMerge(msg *tgbotapi.Message, branch string) {
 7. When done, say "Merge complete" so I know you're finished.`,
 		branch, baseBranch, branch, conflictList)
 
-	// Wait for Claude to start, then send prompt
-	time.Sleep(2 * time.Second)
-	if err := b.sendPromptToTmux(result.WindowID, prompt); err != nil {
-		log.Printf("Error sending merge prompt: %v", err)
-		b.reply(chatID, newThreadID, "Session ready but failed to send merge prompt.")
-	}
+	// Send the prompt once Claude has started
+	b.sendWhenReady(chatID, newThreadID, result.WindowID, "the merge prompt", func() error {
+		return b.sendPromptToTmux(result.WindowID, prompt)
+	})
 
 	b.reply(chatID, threadID, "Merge topic created. Claude is resolving conflicts.")
 }
@@ -295,9 +304,8 @@ func branchTitle(branch string) string {
 // cleanupWorktreeForBranch removes the worktree and branch for a given branch name.
 // Called after a successful merge to clean up.
 func (b *Bot) cleanupWorktreeForBranch(branch string) {
-	for _, threadID := range b.state.AllWorktreeThreadIDs() {
-		wi, ok := b.state.GetWorktreeInfo(threadID)
-		if !ok || wi.Branch != branch {
+	for threadID, wi := range b.state.AllWorktreeInfos() {
+		if wi.Branch != branch {
 			continue
 		}
 		if wi.WorktreeDir != "" {
@@ -1,11 +1,14 @@
 package bot
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"log"
-	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/otaviocarvalho/tramuntana/internal/minuano"
@@ -37,9 +40,21 @@ func (b *Bot) handleProjectCommand(msg *tgbotapi.Message) {
 func (b *Bot) executeProjectBind(msg *tgbotapi.Message, projectName string) {
 	chatID := msg.Chat.ID
 	threadID := getThreadID(msg)
-	threadIDStr := strconv.Itoa(threadID)
-	b.state.BindProject(threadIDStr, projectName)
-	b.saveState()
+	projectName = strings.TrimSpace(projectName)
+
+	projects, err := b.minuanoBridge.Projects()
+	if err != nil {
+		log.Printf("Error listing projects: %v", err)
+		b.reply(chatID, threadID, fmt.Sprintf("Error: could not verify project %s: %v", projectName, err))
+		return
+	}
+	if !findProject(projects, projectName) {
+		b.reply(chatID, threadID, fmt.Sprintf(
+			"Unknown project: %s\nUse /p_projects to pick one, or /p_projects new %s <first task> to create it.", projectName, projectName))
+		return
+	}
+
+	b.bindProject(threadID, projectName)
 	b.reply(chatID, threadID, fmt.Sprintf("Bound to project: %s", projectName))
 }
 
@@ -68,13 +83,18 @@ func (b *Bot) handleTasksCommand(msg *tgbotapi.Message) {
 	}
 
 	// Build text summary
+	agentID := b.agentIDForThread(msg.From.ID, threadID)
 	var lines []string
 	lines = append(lines, fmt.Sprintf("Tasks [%s]:", project))
 	for _, t := range tasks {
 		sym := statusSymbol(t.Status)
 		claimedBy := ""
 		if t.ClaimedBy != nil {
-			claimedBy = fmt.Sprintf(" (%s)", *t.ClaimedBy)
+			if agentID != "" && *t.ClaimedBy == agentID {
+				claimedBy = " (mine)"
+			} else {
+				claimedBy = fmt.Sprintf(" (%s)", *t.ClaimedBy)
+			}
 		}
 		lines = append(lines, fmt.Sprintf("  %s %s — %s [%s]%s",
 			sym, t.ID, t.Title, t.Status, claimedBy))
@@ -94,20 +114,19 @@ func (b *Bot) handleTasksCommand(msg *tgbotapi.Message) {
 
 	if len(rows) > 0 {
 		kb := tgbotapi.InlineKeyboardMarkup{InlineKeyboard: rows}
-		userID := msg.From.ID
 		sent, err := b.sendMessageWithKeyboard(chatID, threadID, strings.Join(lines, "\n"), kb)
 		if err != nil {
 			log.Printf("Error sending tasks with keyboard: %v", err)
 			return
 		}
 		b.mu.Lock()
-		b.taskPickerStates[userID] = &taskPickerState{
+		b.taskPickerStates.set(messageWizardKey(msg), &taskPickerState{
 			Tasks:     tasks,
 			Mode:      "pick",
 			ChatID:    chatID,
 			ThreadID:  threadID,
 			MessageID: sent.MessageID,
-		}
+		})
 		b.mu.Unlock()
 	} else {
 		b.reply(chatID, threadID, strings.Join(lines, "\n"))
@@ -132,6 +151,12 @@ func (b *Bot) handlePickCommand(msg *tgbotapi.Message) {
 		b.reply(chatID, threadID, "Topic not bound to a session.")
 		return
 	}
+	if b.rejectIfObserved(msg) {
+		return
+	}
+	if b.rejectIfOverBudget(msg, windowID) {
+		return
+	}
 
 	prompt, err := b.minuanoBridge.PromptSingle(task.ID)
 	if err != nil {
@@ -150,6 +175,7 @@ func (b *Bot) handlePickCommand(msg *tgbotapi.Message) {
 		return
 	}
 
+	b.usage.RecordTaskPicked(windowID, task.ID)
 	b.reply(chatID, threadID, fmt.Sprintf("Working on task %s...", task.ID))
 }
 
@@ -170,6 +196,12 @@ func (b *Bot) handleAutoCommand(msg *tgbotapi.Message) {
 		b.reply(chatID, threadID, "Topic not bound to a session.")
 		return
 	}
+	if b.rejectIfObserved(msg) {
+		return
+	}
+	if b.rejectIfOverBudget(msg, windowID) {
+		return
+	}
 
 	prompt, err := b.minuanoBridge.PromptAuto(project)
 	if err != nil {
@@ -188,6 +220,7 @@ func (b *Bot) handleAutoCommand(msg *tgbotapi.Message) {
 		return
 	}
 
+	startAutoLoop(windowID, chatID, threadID, project, time.Now())
 	b.reply(chatID, threadID, fmt.Sprintf("Starting autonomous mode for project %s...", project))
 }
 
@@ -226,6 +259,12 @@ func (b *Bot) executeBatch(msg *tgbotapi.Message, args []string) {
 		b.reply(chatID, threadID, "Topic not bound to a session.")
 		return
 	}
+	if b.rejectIfObserved(msg) {
+		return
+	}
+	if b.rejectIfOverBudget(msg, windowID) {
+		return
+	}
 
 	prompt, err := b.minuanoBridge.PromptBatch(args...)
 	if err != nil {
@@ -244,7 +283,8 @@ func (b *Bot) executeBatch(msg *tgbotapi.Message, args []string) {
 		return
 	}
 
-	b.reply(chatID, threadID, fmt.Sprintf("Working on batch: %s...", strings.Join(args, ", ")))
+	b.usage.RecordTaskPicked(windowID, args...)
+	b.startBatchProgress(windowID, chatID, threadID, args)
 }
 
 // handleDeleteCommand deletes a Minuano task.
@@ -401,36 +441,70 @@ func (b *Bot) executeUnclaimTask(chatID int64, threadID int, taskID, title strin
 	b.reply(chatID, threadID, fmt.Sprintf("Unclaimed: %s — %s", taskID, title))
 }
 
-// sendPromptToTmux writes a prompt to a temp file and sends a reference to tmux.
-// Long prompts exceed tmux send-keys limits, so we use a temp file.
+// sendPromptToTmux writes a prompt to a prompt file and sends a reference to tmux.
+// Long prompts exceed tmux send-keys limits, so we use a file.
 func (b *Bot) sendPromptToTmux(windowID, prompt string) error {
-	// Write prompt to temp file
-	tmpFile, err := os.CreateTemp("", "tramuntana-task-*.md")
-	if err != nil {
-		return fmt.Errorf("creating temp file: %w", err)
+	if b.state.IsPaused() {
+		return errPaused
 	}
-	defer tmpFile.Close()
-
-	if _, err := tmpFile.WriteString(prompt); err != nil {
-		return fmt.Errorf("writing prompt: %w", err)
+	// Write prompt to a prompt file
+	path, err := b.writePromptFile(windowID, "tramuntana-task-*.md", prompt)
+	if err != nil {
+		return err
 	}
-	tmpFile.Close()
 
 	// Send reference to tmux
-	ref := fmt.Sprintf("Please read and follow the instructions in %s", tmpFile.Name())
+	ref := fmt.Sprintf("Please read and follow the instructions in %s", path)
 	return tmux.SendKeysWithDelay(b.config.TmuxSessionName, windowID, ref, 500)
 }
 
+// sendUserText types a user message into a window. Messages longer than the
+// long-paste threshold go through a temp file or paste buffer instead of
+// send-keys, per the configured mode.
+func (b *Bot) sendUserText(ctx context.Context, windowID, text string) error {
+	if b.state.IsPaused() {
+		return errPaused
+	}
+	session := b.config.TmuxSessionName
+	if len([]rune(text)) <= b.config.LongPasteChars {
+		return tmux.SendKeysWithDelayContext(ctx, session, windowID, text, 500)
+	}
+
+	switch b.config.LongPasteMode {
+	case "file":
+		path, err := b.writePromptFile(windowID, "tramuntana-paste-*.txt", text)
+		if err != nil {
+			return err
+		}
+		ref := fmt.Sprintf("My message was too long to type, so it is saved in %s. Please read it and respond to it.", path)
+		return tmux.SendKeysWithDelayContext(ctx, session, windowID, ref, 500)
+	case "buffer":
+		return tmux.WithWindowContext(ctx, session, windowID, func(w tmux.Writer) error {
+			if err := w.Paste(text); err != nil {
+				return err
+			}
+			time.Sleep(500 * time.Millisecond)
+			return w.Enter()
+		})
+	default:
+		return tmux.SendKeysWithDelayContext(ctx, session, windowID, text, 500)
+	}
+}
+
 // buildMinuanoEnv returns environment variables to set in tmux windows for Minuano
-// integration. Returns nil if MINUANO_DB is not configured.
+// integration. Each call generates a fresh agent ID so concurrent windows on the
+// same directory claim tasks under distinct identities. Returns nil if MINUANO_DB
+// is not configured.
 func (b *Bot) buildMinuanoEnv(windowName string) map[string]string {
 	if b.config.MinuanoDB == "" {
 		return nil
 	}
 
+	agentID := newAgentID(windowName)
 	env := map[string]string{
-		"DATABASE_URL": b.config.MinuanoDB,
-		"AGENT_ID":     fmt.Sprintf("tramuntana-%s", windowName),
+		"DATABASE_URL":     b.config.MinuanoDB,
+		"AGENT_ID":         agentID,
+		"MINUANO_AGENT_ID": agentID,
 	}
 
 	if b.config.MinuanoScriptsDir != "" {
@@ -440,6 +514,25 @@ func (b *Bot) buildMinuanoEnv(windowName string) map[string]string {
 	return env
 }
 
+// newAgentID returns a unique Minuano agent ID for a window: "tramuntana-<name>-<hex>".
+func newAgentID(windowName string) string {
+	suffix := make([]byte, 3)
+	if _, err := rand.Read(suffix); err != nil {
+		return fmt.Sprintf("tramuntana-%s-%d", windowName, time.Now().UnixNano()%0xffffff)
+	}
+	return fmt.Sprintf("tramuntana-%s-%s", windowName, hex.EncodeToString(suffix))
+}
+
+// agentIDForThread returns the Minuano agent ID of the window bound to a topic.
+func (b *Bot) agentIDForThread(userID int64, threadID int) string {
+	windowID, ok := b.state.GetWindowForThread(strconv.FormatInt(userID, 10), strconv.Itoa(threadID))
+	if !ok {
+		return ""
+	}
+	ws, _ := b.state.GetWindowState(windowID)
+	return ws.AgentID
+}
+
 // statusSymbol returns a display symbol for a task status.
 func statusSymbol(status string) string {
 	switch status {
@@ -2,10 +2,12 @@ package bot
 
 import (
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/otaviocarvalho/tramuntana/internal/config"
+	"github.com/otaviocarvalho/tramuntana/internal/state"
 )
 
 func TestBuildMinuanoEnv(t *testing.T) {
@@ -28,14 +30,26 @@ func TestBuildMinuanoEnv(t *testing.T) {
 		if env["DATABASE_URL"] != "postgres://localhost/minuano" {
 			t.Errorf("DATABASE_URL = %q", env["DATABASE_URL"])
 		}
-		if env["AGENT_ID"] != "tramuntana-mywindow" {
+		if !strings.HasPrefix(env["AGENT_ID"], "tramuntana-mywindow-") {
 			t.Errorf("AGENT_ID = %q", env["AGENT_ID"])
 		}
+		if env["MINUANO_AGENT_ID"] != env["AGENT_ID"] {
+			t.Errorf("MINUANO_AGENT_ID = %q, want %q", env["MINUANO_AGENT_ID"], env["AGENT_ID"])
+		}
 		if _, ok := env["PATH"]; ok {
 			t.Error("PATH should not be set when MinuanoScriptsDir is empty")
 		}
 	})
 
+	t.Run("agent IDs are unique per window", func(t *testing.T) {
+		b := &Bot{config: &config.Config{MinuanoDB: "postgres://localhost/minuano"}}
+		a := b.buildMinuanoEnv("mywindow")["AGENT_ID"]
+		c := b.buildMinuanoEnv("mywindow")["AGENT_ID"]
+		if a == c {
+			t.Errorf("expected distinct agent IDs, both %q", a)
+		}
+	})
+
 	t.Run("includes PATH when scripts dir set", func(t *testing.T) {
 		b := &Bot{config: &config.Config{
 			MinuanoDB:         "postgres://localhost/minuano",
@@ -70,30 +84,32 @@ func TestStatusSymbol(t *testing.T) {
 
 func TestSendPromptToTmux_CreatesFile(t *testing.T) {
 	// We can't test the full flow without a real bot/tmux,
-	// but we can test the temp file creation part.
+	// but we can test the prompt file creation part.
 	prompt := "Test prompt content\nWith multiple lines"
+	b := &Bot{config: &config.Config{CacheDir: t.TempDir()}}
 
-	tmpFile, err := os.CreateTemp("", "tramuntana-task-*.md")
+	path, err := b.writePromptFile("@1", "tramuntana-task-*.md", prompt)
 	if err != nil {
 		t.Fatal(err)
 	}
-	defer os.Remove(tmpFile.Name())
-
-	if _, err := tmpFile.WriteString(prompt); err != nil {
-		t.Fatal(err)
-	}
-	tmpFile.Close()
+	defer dropPromptFiles("@1")
 
 	// Verify file was written correctly
-	data, err := os.ReadFile(tmpFile.Name())
+	data, err := os.ReadFile(path)
 	if err != nil {
 		t.Fatal(err)
 	}
 	if string(data) != prompt {
 		t.Errorf("file content = %q, want %q", string(data), prompt)
 	}
-	if !strings.HasSuffix(tmpFile.Name(), ".md") {
-		t.Errorf("temp file should have .md extension, got %q", tmpFile.Name())
+	if !strings.HasSuffix(path, ".md") {
+		t.Errorf("prompt file should have .md extension, got %q", path)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("prompt file mode = %v, want 0600", info.Mode().Perm())
+	}
+	if info, _ := os.Stat(filepath.Dir(path)); info.Mode().Perm() != 0700 {
+		t.Errorf("prompt dir mode = %v, want 0700", info.Mode().Perm())
 	}
 }
 
@@ -132,3 +148,24 @@ func TestTaskListFormatting(t *testing.T) {
 		t.Error("should show claimed by")
 	}
 }
+
+func TestFormatSessionStatus(t *testing.T) {
+	ws := state.WindowState{CWD: "/repo", SessionID: "s1", AgentID: "tramuntana-repo-a1b2c3"}
+	wt := state.WorktreeInfo{Branch: "minuano/p-t1", WorktreeDir: "/repo/.minuano/worktrees/p-t1", TaskID: "t1"}
+	got := formatSessionStatus("@3", "repo", "p", ws, wt, true)
+	for _, want := range []string{
+		"Window: @3 (repo)",
+		"Directory: /repo",
+		"Agent: tramuntana-repo-a1b2c3",
+		"Project: p",
+		"Worktree: minuano/p-t1 (/repo/.minuano/worktrees/p-t1) — task t1",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in:\n%s", want, got)
+		}
+	}
+
+	if got := formatSessionStatus("@4", "", "", state.WindowState{}, state.WorktreeInfo{}, false); !strings.Contains(got, "Agent: none") {
+		t.Errorf("expected no-agent line, got:\n%s", got)
+	}
+}
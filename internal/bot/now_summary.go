@@ -0,0 +1,161 @@
+package bot
+
+import (
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/otaviocarvalho/tramuntana/internal/monitor"
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+)
+
+// maxNowSummaryLen caps a turn summary, in runes.
+const maxNowSummaryLen = 100
+
+// The latest assistant text per window, summarized when the turn ends, and
+// the last summary, shown in topic names with TRAMUNTANA_NOW_SUMMARY=title.
+var (
+	lastAssistantTexts   = make(map[string]string) // windowID → text
+	turnSummaries        = make(map[string]string) // windowID → summary
+	lastAssistantTextsMu sync.Mutex
+)
+
+// nowSummaryMu keeps pinned "Now:" updates in order, so two quick turns
+// don't both send and pin a new message.
+var nowSummaryMu sync.Mutex
+
+// noteAssistantText remembers the latest assistant text in parsed.
+func noteAssistantText(windowID string, parsed []monitor.ParsedEntry) {
+	for i := len(parsed) - 1; i >= 0; i-- {
+		pe := parsed[i]
+		if pe.Role != "assistant" || pe.ContentType != "text" || strings.TrimSpace(pe.Text) == "" {
+			continue
+		}
+		lastAssistantTextsMu.Lock()
+		lastAssistantTexts[windowID] = pe.Text
+		lastAssistantTextsMu.Unlock()
+		return
+	}
+}
+
+// takeAssistantText returns and forgets a window's latest assistant text, so
+// a turn without new text leaves the previous summary alone.
+func takeAssistantText(windowID string) string {
+	lastAssistantTextsMu.Lock()
+	defer lastAssistantTextsMu.Unlock()
+	text := lastAssistantTexts[windowID]
+	delete(lastAssistantTexts, windowID)
+	return text
+}
+
+// turnSummary returns a window's last turn summary.
+func turnSummary(windowID string) string {
+	lastAssistantTextsMu.Lock()
+	defer lastAssistantTextsMu.Unlock()
+	return turnSummaries[windowID]
+}
+
+// forgetTurnSummary drops a window's text and summary once it is gone.
+func forgetTurnSummary(windowID string) {
+	lastAssistantTextsMu.Lock()
+	defer lastAssistantTextsMu.Unlock()
+	delete(lastAssistantTexts, windowID)
+	delete(turnSummaries, windowID)
+}
+
+// showTurnSummary shows a finished turn's summary the way
+// TRAMUNTANA_NOW_SUMMARY asks: pinned in the background, or in topic names on
+// the status poller's next sync.
+func (b *Bot) showTurnSummary(windowID, summary string) {
+	switch b.config.NowSummary {
+	case "pin":
+		go b.postNowSummary(summary, b.state.FindUsersForWindow(windowID))
+	case "title":
+		lastAssistantTextsMu.Lock()
+		turnSummaries[windowID] = summary
+		lastAssistantTextsMu.Unlock()
+	}
+}
+
+// summarizeTurn reduces assistant text to one line: the opening sentences of
+// its first line of prose (not code, tables or headings), without markdown,
+// until they say something (20 runes), capped at maxNowSummaryLen.
+func summarizeTurn(text string) string {
+	inFence := false
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "```") {
+			inFence = !inFence
+			continue
+		}
+		if inFence || line == "" || strings.HasPrefix(line, "|") || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = stripLineMarkdown(line)
+		if line == "" {
+			continue
+		}
+		summary := line
+		for start := 0; ; {
+			i := strings.IndexAny(line[start:], ".!?")
+			if i < 0 {
+				break
+			}
+			end := start + i + 1
+			if end < len(line) && line[end] != ' ' {
+				start = end // "v1.2", "main.go"
+				continue
+			}
+			summary = line[:end]
+			if utf8.RuneCountInString(summary) >= 20 {
+				break
+			}
+			start = end
+		}
+		return truncateName(strings.TrimSpace(summary), maxNowSummaryLen)
+	}
+	return ""
+}
+
+// stripLineMarkdown drops list and quote markers and inline emphasis from a
+// line.
+func stripLineMarkdown(line string) string {
+	line = strings.TrimLeft(line, ">-*+ ")
+	if i := strings.Index(line, ". "); i > 0 && i <= 3 {
+		if _, err := strconv.Atoi(line[:i]); err == nil {
+			line = line[i+2:] // "1. step"
+		}
+	}
+	line = strings.NewReplacer("**", "", "__", "", "`", "").Replace(line)
+	return strings.TrimSpace(line)
+}
+
+// postNowSummary shows a window's turn summary in each of its topics as a
+// pinned "Now:" message, edited in place after the first turn.
+func (b *Bot) postNowSummary(summary string, users []state.UserThread) {
+	nowSummaryMu.Lock()
+	defer nowSummaryMu.Unlock()
+	text := "📌 Now: " + summary
+	for _, ut := range users {
+		chatID, ok := b.state.GetGroupChatID(ut.UserID, ut.ThreadID)
+		if !ok {
+			continue
+		}
+		if pm, ok := b.state.GetPin(ut.ThreadID, pinNow); ok && pm.ChatID == chatID {
+			err := b.editMessageText(chatID, pm.MessageID, text)
+			if err == nil || strings.Contains(err.Error(), "message is not modified") {
+				continue
+			}
+			log.Printf("Error editing Now message %d, sending a new one: %v", pm.MessageID, err)
+		}
+		threadID, _ := strconv.Atoi(ut.ThreadID)
+		sent, err := b.sendMessageInThread(chatID, threadID, text)
+		if err != nil {
+			log.Printf("Error sending Now message: %v", err)
+			continue
+		}
+		b.pin(chatID, threadID, sent.MessageID, pinNow)
+	}
+}
@@ -0,0 +1,95 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/otaviocarvalho/tramuntana/internal/config"
+	"github.com/otaviocarvalho/tramuntana/internal/monitor"
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+	"github.com/otaviocarvalho/tramuntana/internal/testharness"
+)
+
+func TestSummarizeTurn(t *testing.T) {
+	tests := []struct {
+		name, text, want string
+	}{
+		{"first sentence", "Fixed the race in the file watcher. Tests pass now.", "Fixed the race in the file watcher."},
+		{"short opener", "Done! The parser now handles nested lists.\nMore below.", "Done! The parser now handles nested lists."},
+		{"markdown", "## Summary\n\n- **Renamed** `parse` to `Parse` in main.go", "Renamed parse to Parse in main.go"},
+		{"skips code", "```go\nfunc f() {}\n```\n1. Added v1.2 support to the loader", "Added v1.2 support to the loader"},
+		{"empty", "\n\n", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := summarizeTurn(tt.text); got != tt.want {
+				t.Errorf("summarizeTurn() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	long := summarizeTurn(strings.Repeat("word ", 50))
+	if n := len([]rune(long)); n != maxNowSummaryLen || !strings.HasSuffix(long, "…") {
+		t.Errorf("long summary = %q (%d runes)", long, n)
+	}
+}
+
+func TestNoteAssistantText(t *testing.T) {
+	noteAssistantText("@9", []monitor.ParsedEntry{
+		{Role: "assistant", ContentType: "text", Text: "Looking at the tests."},
+		{Role: "assistant", ContentType: "tool_use", ToolName: "Bash"},
+		{Role: "assistant", ContentType: "text", Text: "All green."},
+		{Role: "user", ContentType: "tool_result", Text: "ok"},
+	})
+	if got := takeAssistantText("@9"); got != "All green." {
+		t.Errorf("takeAssistantText = %q", got)
+	}
+	if got := takeAssistantText("@9"); got != "" {
+		t.Errorf("second take = %q, want empty", got)
+	}
+}
+
+func TestPostNowSummary_EditsPinnedMessage(t *testing.T) {
+	tg := testharness.NewTelegram(t)
+	b := &Bot{
+		config: &config.Config{NowSummary: "pin", TramuntanaDir: t.TempDir()},
+		state:  state.NewState(),
+		api:    tg.API(),
+	}
+	b.state.SetGroupChatID("100", "7", -100)
+	users := []state.UserThread{{UserID: "100", ThreadID: "7"}}
+
+	b.postNowSummary("Writing the migration.", users)
+	b.postNowSummary("Running the tests.", users)
+
+	sends := tg.Calls("sendMessage")
+	if len(sends) != 1 || sends[0].Params["text"] != "📌 Now: Writing the migration." {
+		t.Fatalf("sends = %+v", sends)
+	}
+	if n := len(tg.Calls("pinChatMessage")); n != 1 {
+		t.Errorf("pinned %d messages, want 1", n)
+	}
+	edits := tg.Calls("editMessageText")
+	if len(edits) != 1 || edits[0].Params["text"] != "📌 Now: Running the tests." {
+		t.Errorf("edits = %+v", edits)
+	}
+}
+
+func TestShowTurnSummary_PinsInBackground(t *testing.T) {
+	tg := testharness.NewTelegram(t)
+	b := &Bot{
+		config: &config.Config{NowSummary: "pin", TramuntanaDir: t.TempDir()},
+		state:  state.NewState(),
+		api:    tg.API(),
+	}
+	b.state.SetGroupChatID("100", "7", -100)
+	b.state.BindThread("100", "7", "@3")
+
+	// Two quick turns still share one pinned message
+	b.showTurnSummary("@3", "Writing the migration.")
+	b.showTurnSummary("@3", "Running the tests.")
+	tg.WaitForText("editMessageText", "📌 Now:")
+	if n := len(tg.Calls("sendMessage")); n != 1 {
+		t.Errorf("sent %d Now messages, want 1", n)
+	}
+}
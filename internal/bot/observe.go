@@ -0,0 +1,88 @@
+package bot
+
+import (
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// observeRejection is the reply to input sent to a read-only topic.
+const observeRejection = "This topic is in observe mode: it shows the session's output, but messages are not sent to Claude. Use /observe off to type again."
+
+// handleObserveCommand toggles read-only observation for this topic.
+// Usage: /observe [on|off]; no argument toggles.
+func (b *Bot) handleObserveCommand(msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+	threadID := getThreadID(msg)
+	threadIDStr := strconv.Itoa(threadID)
+
+	on := !b.state.IsObserved(threadIDStr)
+	switch strings.ToLower(strings.TrimSpace(msg.CommandArguments())) {
+	case "on":
+		on = true
+	case "off":
+		on = false
+	case "":
+	default:
+		b.reply(chatID, threadID, "Usage: /observe [on|off]")
+		return
+	}
+
+	b.state.SetObserved(threadIDStr, on)
+	b.saveState()
+	if on {
+		b.reply(chatID, threadID, "Observe mode on: this topic receives the session's output, but messages are no longer sent to Claude.")
+	} else {
+		b.reply(chatID, threadID, "Observe mode off: messages are sent to Claude again.")
+	}
+}
+
+// rejectIfObserved replies with an explanation and returns true if the
+// message's topic is read-only.
+func (b *Bot) rejectIfObserved(msg *tgbotapi.Message) bool {
+	threadID := getThreadID(msg)
+	if !b.state.IsObserved(strconv.Itoa(threadID)) {
+		return false
+	}
+	b.reply(msg.Chat.ID, threadID, observeRejection)
+	return true
+}
+
+// rejectObservedCallback answers with an explanation and returns true if a
+// button that types into or presses keys in the session was pressed in a
+// read-only topic.
+func (b *Bot) rejectObservedCallback(cq *tgbotapi.CallbackQuery) bool {
+	if cq.Message == nil || !typesIntoSession(cq.Data) {
+		return false
+	}
+	if !b.state.IsObserved(strconv.Itoa(getThreadID(cq.Message))) {
+		return false
+	}
+	b.api.Request(tgbotapi.NewCallbackWithAlert(cq.ID, observeRejection))
+	return true
+}
+
+// typesIntoSession reports whether a button's callback data sends keys or a
+// prompt to the topic's session: interactive UI and screenshot keys, task
+// picks, /auto's interrupt, /rewind's steps and a /confirm preview's Send.
+// Buttons that forward a message or command are checked when it is handled.
+func typesIntoSession(data string) bool {
+	switch {
+	case data == "cf_send":
+		return true
+	case strings.HasPrefix(data, "nav_"):
+		return data != "nav_refresh"
+	case strings.HasPrefix(data, "ss_"):
+		action, _, ok := parseSSCallbackData(data)
+		return ok && action != "refresh"
+	case strings.HasPrefix(data, "rw_"):
+		return data != "rw_cancel"
+	}
+	for _, prefix := range []string{"tpick_pick:", "tpick_pickw:", "task_pick:", "auto_int:"} {
+		if strings.HasPrefix(data, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/queue"
+	"github.com/otaviocarvalho/tramuntana/internal/render"
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+)
+
+// handlePagingCommand sets whether this topic's long output arrives as one
+// message with Prev/Next buttons instead of several "[i/N]" messages.
+// Usage: /paging [on|off]; no argument shows the setting.
+func (b *Bot) handlePagingCommand(msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+	threadID := getThreadID(msg)
+	threadIDStr := strconv.Itoa(threadID)
+
+	switch strings.ToLower(strings.TrimSpace(msg.CommandArguments())) {
+	case "":
+		if b.state.IsPaged(threadIDStr) {
+			b.reply(chatID, threadID, "Paging is on: long output arrives as one message with Prev/Next buttons. Use /paging off to split it into messages.")
+		} else {
+			b.reply(chatID, threadID, "Paging is off: long output is split into numbered messages. Use /paging on to page through one message instead.")
+		}
+	case "on":
+		b.state.SetPaged(threadIDStr, true)
+		b.saveState()
+		b.reply(chatID, threadID, "Paging on: long output arrives as one message with Prev/Next buttons.")
+	case "off":
+		b.state.SetPaged(threadIDStr, false)
+		b.saveState()
+		b.reply(chatID, threadID, "Paging off: long output is split into numbered messages.")
+	default:
+		b.reply(chatID, threadID, "Usage: /paging [on|off]")
+	}
+}
+
+// pagedDelivery stores a long message's pages if its topic has paging on and
+// returns the first page's keyboard; nil sends the parts as messages.
+func (b *Bot) pagedDelivery(task queue.MessageTask, pages []string) *tgbotapi.InlineKeyboardMarkup {
+	if !b.state.IsPaged(strconv.Itoa(task.ThreadID)) {
+		return nil
+	}
+	id := b.state.AddPagedMessage(state.PagedMessage{Pages: pages, WindowID: task.WindowID, CreatedAt: time.Now()})
+	b.saveState()
+	return b.pageKeyboard(id, 0, pages, task.WindowID)
+}
+
+// pageKeyboard is the navigation row for one page, followed by the file
+// reference buttons for the text on it.
+func (b *Bot) pageKeyboard(id string, page int, pages []string, windowID string) *tgbotapi.InlineKeyboardMarkup {
+	var nav []tgbotapi.InlineKeyboardButton
+	if page > 0 {
+		nav = append(nav, tgbotapi.NewInlineKeyboardButtonData("‹ Prev", fmt.Sprintf("pg_%s:%d", id, page-1)))
+	}
+	nav = append(nav, tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%d/%d", page+1, len(pages)), "noop"))
+	if page < len(pages)-1 {
+		nav = append(nav, tgbotapi.NewInlineKeyboardButtonData("Next ›", fmt.Sprintf("pg_%s:%d", id, page+1)))
+	}
+	rows := [][]tgbotapi.InlineKeyboardButton{nav}
+	if refs := b.fileRefKeyboard(queue.MessageTask{WindowID: windowID}, pages[page]); refs != nil {
+		rows = append(rows, refs.InlineKeyboard...)
+	}
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	return &keyboard
+}
+
+// processPageCallback turns a paged message to another page: "pg_<id>:<page>".
+func (b *Bot) processPageCallback(cq *tgbotapi.CallbackQuery) {
+	if cq.Message == nil {
+		return
+	}
+	id, pageStr, ok := strings.Cut(strings.TrimPrefix(cq.Data, "pg_"), ":")
+	page, err := strconv.Atoi(pageStr)
+	if !ok || err != nil {
+		return
+	}
+	chatID, threadID := cq.Message.Chat.ID, getThreadIDFromCallback(cq)
+	pm, found := b.state.GetPagedMessage(id)
+	if !found {
+		b.reply(chatID, threadID, "Those pages have expired.")
+		return
+	}
+	if page < 0 || page >= len(pm.Pages) {
+		return
+	}
+	keyboard := b.pageKeyboard(id, page, pm.Pages, pm.WindowID)
+	if err := b.editMessageWithKeyboardMD(chatID, cq.Message.MessageID, render.ToMarkdownV2(pm.Pages[page]), *keyboard); err != nil {
+		if err := b.editMessageWithKeyboard(chatID, cq.Message.MessageID, pm.Pages[page], *keyboard); err != nil {
+			log.Printf("Error turning page: %v", err)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/otaviocarvalho/tramuntana/internal/testharness"
+)
+
+func TestE2E_PagedDeliveryEditsOneMessage(t *testing.T) {
+	h := startE2E(t, "fresh")
+	windowID := h.tmux.AddWindow("api", "/work/api")
+	userID, threadID := strconv.FormatInt(e2eUser, 10), strconv.Itoa(e2eThread)
+	h.bot.state.BindThread(userID, threadID, windowID)
+	h.bot.state.SetGroupChatID(userID, threadID, e2eChat)
+	h.writeSession(t, windowID, "sess-1", "/work/api")
+
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "/paging on")
+	h.tg.WaitForText("sendMessage", "Paging on")
+
+	var lines []string
+	for i := 1; i <= 150; i++ {
+		lines = append(lines, fmt.Sprintf("Line %03d of a long explanation", i))
+	}
+	text, _ := json.Marshal(strings.Join(lines, "\n"))
+	h.appendTranscript(t, "sess-1",
+		`{"type":"assistant","message":{"content":[{"type":"text","text":`+string(text)+`}]}}`)
+
+	first := h.tg.WaitForText("sendMessage", "Line 001")
+	if strings.Contains(first.Params["text"], "Line 150") {
+		t.Fatalf("first page holds everything: %d chars", len(first.Params["text"]))
+	}
+	next := regexp.MustCompile(`pg_[0-9a-f]+:1`).FindString(first.Params["reply_markup"])
+	if next == "" || !strings.Contains(first.Params["reply_markup"], "1/2") {
+		t.Fatalf("no page navigation: %s", first.Params["reply_markup"])
+	}
+
+	h.tg.PushCallback(e2eChat, e2eThread, e2eUser, first.MessageID, next)
+	edit := h.tg.WaitFor("editMessageText", func(c testharness.Call) bool {
+		return strings.Contains(c.Params["text"], "Line 150")
+	})
+	if edit.Params["message_id"] != strconv.Itoa(first.MessageID) || !strings.Contains(edit.Params["reply_markup"], "‹ Prev") {
+		t.Errorf("page 2 edit = %v", edit.Params)
+	}
+	for _, c := range h.tg.Calls("sendMessage") {
+		if strings.Contains(c.Params["text"], "Line 150") {
+			t.Errorf("page 2 sent as its own message")
+		}
+	}
+}
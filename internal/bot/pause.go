@@ -0,0 +1,110 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// maxHeldMessages caps how many messages are held while paused; older ones
+// are dropped first.
+const maxHeldMessages = 500
+
+// errPaused is returned instead of sending to tmux while the bridge is paused.
+var errPaused = errors.New("bridge paused for maintenance")
+
+// handlePauseCommand handles /pause: stops sending to tmux and reading
+// transcripts until /resume_bridge. Owners only.
+func (b *Bot) handlePauseCommand(msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+	threadID := getThreadID(msg)
+	if !b.isOwner(msg.From.ID) {
+		b.reply(chatID, threadID, "Only the bot owner can pause the bridge.")
+		return
+	}
+	if b.state.IsPaused() {
+		b.reply(chatID, threadID, "Already paused. Use /resume_bridge to resume.")
+		return
+	}
+	b.state.SetPaused(true)
+	b.flushState()
+	log.Printf("Bridge paused by %d", msg.From.ID)
+	b.reply(chatID, threadID, "⏸ Bridge paused. Messages and commands are held, and Claude's output is buffered, until /resume_bridge.")
+}
+
+// handleResumeBridgeCommand handles /resume_bridge: leaves maintenance mode and
+// delivers the held messages in the order they arrived. Owners only.
+func (b *Bot) handleResumeBridgeCommand(msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+	threadID := getThreadID(msg)
+	if !b.isOwner(msg.From.ID) {
+		b.reply(chatID, threadID, "Only the bot owner can resume the bridge.")
+		return
+	}
+	if !b.state.IsPaused() {
+		b.reply(chatID, threadID, "The bridge is not paused.")
+		return
+	}
+	b.state.SetPaused(false)
+	held := b.state.TakeHeldMessages()
+	b.flushState()
+
+	b.mu.Lock()
+	b.heldTopics = nil
+	b.mu.Unlock()
+
+	log.Printf("Bridge resumed by %d, delivering %d held message(s)", msg.From.ID, len(held))
+	b.reply(chatID, threadID, fmt.Sprintf("▶️ Bridge resumed. Delivering %d held message(s).", len(held)))
+	for _, raw := range held {
+		var m tgbotapi.Message
+		if err := json.Unmarshal(raw, &m); err != nil {
+			log.Printf("Error reading held message: %v", err)
+			continue
+		}
+		b.handleMessage(context.Background(), &m)
+	}
+}
+
+// holdIfPaused keeps a message for delivery on resume while the bridge is
+// paused, in state so a restart doesn't lose it. The pause controls
+// themselves always go through. The first held message in a topic gets a
+// notice.
+func (b *Bot) holdIfPaused(msg *tgbotapi.Message) bool {
+	if !b.state.IsPaused() {
+		return false
+	}
+	if msg.IsCommand() {
+		switch msg.Command() {
+		case "pause", "resume_bridge":
+			return false
+		}
+	}
+
+	threadID := getThreadID(msg)
+	topic := strconv.FormatInt(msg.Chat.ID, 10) + "/" + strconv.Itoa(threadID)
+	raw, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("Error holding message: %v", err)
+		return true
+	}
+	b.state.HoldMessage(raw, maxHeldMessages)
+	b.flushState()
+
+	b.mu.Lock()
+	if b.heldTopics == nil {
+		b.heldTopics = make(map[string]bool)
+	}
+	notify := !b.heldTopics[topic]
+	b.heldTopics[topic] = true
+	b.mu.Unlock()
+
+	if notify {
+		b.reply(msg.Chat.ID, threadID, "⏸ The bridge is paused for maintenance. Your messages here are held and will be sent when it resumes.")
+	}
+	return true
+}
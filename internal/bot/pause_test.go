@@ -0,0 +1,89 @@
+package bot
+
+import (
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+	"github.com/otaviocarvalho/tramuntana/internal/testharness"
+)
+
+func TestE2E_PauseHoldsInputAndBuffersOutput(t *testing.T) {
+	h := startE2E(t, "fresh")
+	windowID := h.tmux.AddWindow("api", "/work/api")
+	userID, threadID := strconv.FormatInt(e2eUser, 10), strconv.Itoa(e2eThread)
+	h.bot.state.BindThread(userID, threadID, windowID)
+	h.bot.state.SetGroupChatID(userID, threadID, e2eChat)
+	h.writeSession(t, windowID, "sess-1", "/work/api")
+
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "/pause")
+	h.tg.WaitForText("sendMessage", "Bridge paused")
+
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "first")
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "second")
+	h.tg.WaitForText("sendMessage", "held and will be sent when it resumes")
+	h.appendTranscript(t, "sess-1",
+		`{"type":"assistant","message":{"content":[{"type":"text","text":"Written while paused"}]}}`)
+
+	time.Sleep(500 * time.Millisecond)
+	if w, _ := h.tmux.Window(windowID); slices.Contains(w.Keys, "first") {
+		t.Errorf("typed while paused: %q", w.Keys)
+	}
+	notices, delivered := 0, 0
+	for _, c := range h.tg.Calls("sendMessage") {
+		if strings.Contains(c.Params["text"], "held and will be sent") {
+			notices++
+		}
+		if strings.Contains(c.Params["text"], "Written while paused") {
+			delivered++
+		}
+	}
+	if notices != 1 || delivered != 0 {
+		t.Errorf("%d pause notices and %d transcript messages while paused", notices, delivered)
+	}
+
+	// Held messages are on disk, so a restart while paused keeps them
+	saved, err := state.Load(filepath.Join(h.cfg.TramuntanaDir, "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved.HeldMessages) != 2 {
+		t.Errorf("%d held messages saved, want 2", len(saved.HeldMessages))
+	}
+
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "/resume_bridge")
+	h.tg.WaitForText("sendMessage", "Delivering 2 held message(s)")
+	h.tmux.WaitForKeys(windowID, "second")
+	if w, _ := h.tmux.Window(windowID); slices.Index(w.Keys, "first") > slices.Index(w.Keys, "second") {
+		t.Errorf("held messages out of order: %q", w.Keys)
+	}
+	h.tg.WaitForText("sendMessage", "Written while paused")
+	if n := len(h.bot.state.TakeHeldMessages()); n != 0 {
+		t.Errorf("%d held messages left after resuming", n)
+	}
+}
+
+func TestE2E_PauseOwnerOnly(t *testing.T) {
+	h := startE2E(t, "fresh")
+	h.bot.state.GrantAccess("200", state.AccessGrant{GrantedAt: time.Now()})
+
+	h.tg.PushMessage(e2eChat, e2eThread, 200, "/pause")
+	h.tg.WaitForText("sendMessage", "Only the bot owner can pause")
+	if h.bot.state.IsPaused() {
+		t.Error("a granted user paused the bridge")
+	}
+}
+
+func TestE2E_PauseRejectsButtons(t *testing.T) {
+	h := startE2E(t, "fresh")
+	h.bot.state.SetPaused(true)
+
+	h.tg.PushCallback(e2eChat, e2eThread, e2eUser, 1, "noop")
+	h.tg.WaitFor("answerCallbackQuery", func(c testharness.Call) bool {
+		return strings.Contains(c.Params["text"], "paused for maintenance")
+	})
+}
@@ -8,7 +8,7 @@ import (
 
 // pendingInput represents a command waiting for user text input.
 type pendingInput struct {
-	Command  string // "p_bind", "p_add", "t_batch", "t_merge", "t_plan"
+	Command  string // "p_bind", "p_new", "p_add", "t_batch", "t_merge", "t_plan"
 	ChatID   int64
 	ThreadID int
 }
@@ -60,6 +60,8 @@ func (b *Bot) handlePendingInput(msg *tgbotapi.Message) bool {
 	switch pi.Command {
 	case "p_bind":
 		b.executeProjectBind(msg, text)
+	case "p_new":
+		b.executeProjectCreate(msg, text)
 	case "p_add":
 		b.executeAddWithTitle(msg, text)
 	case "t_batch":
@@ -0,0 +1,55 @@
+package bot
+
+import (
+	"log"
+	"strconv"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/queue"
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+)
+
+// Pin kinds. A topic keeps at most one pin of each kind; a new pin supersedes
+// the previous one.
+const (
+	pinPlan     = "plan"     // plan approved via ExitPlanMode
+	pinPickw    = "pickw"    // /t_pickw completion summary
+	pinBookmark = "bookmark" // latest /bookmark
+	pinNow      = "now"      // TRAMUNTANA_NOW_SUMMARY=pin "Now:" message
+)
+
+// pinMessage pins a message in its topic when auto-pin is enabled, unpinning
+// the topic's previous pin of the same kind.
+func (b *Bot) pinMessage(chatID int64, threadID, messageID int, kind string) {
+	if !b.config.AutoPin {
+		return
+	}
+	b.pin(chatID, threadID, messageID, kind)
+}
+
+// pin pins a message in its topic, unpinning the topic's previous pin of the
+// same kind.
+func (b *Bot) pin(chatID int64, threadID, messageID int, kind string) {
+	if messageID == 0 {
+		return
+	}
+	pin := tgbotapi.PinChatMessageConfig{ChatID: chatID, MessageID: messageID, DisableNotification: true}
+	if _, err := b.api.Request(pin); err != nil {
+		log.Printf("Error pinning %s message %d: %v", kind, messageID, err)
+		return
+	}
+
+	prev, ok := b.state.SetPin(strconv.Itoa(threadID), kind, state.PinnedMessage{ChatID: chatID, MessageID: messageID})
+	b.saveState()
+	if ok && prev.MessageID != messageID {
+		unpin := tgbotapi.UnpinChatMessageConfig{ChatID: prev.ChatID, MessageID: prev.MessageID}
+		if _, err := b.api.Request(unpin); err != nil {
+			log.Printf("Error unpinning superseded %s message %d: %v", kind, prev.MessageID, err)
+		}
+	}
+}
+
+// handleQueuedPin pins a message the queue delivered for a task marked with a pin kind.
+func (b *Bot) handleQueuedPin(task queue.MessageTask, messageID int) {
+	b.pinMessage(task.ChatID, task.ThreadID, messageID, task.Pin)
+}
@@ -0,0 +1,43 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/otaviocarvalho/tramuntana/internal/config"
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+	"github.com/otaviocarvalho/tramuntana/internal/testharness"
+)
+
+func TestPinMessage_UnpinsSuperseded(t *testing.T) {
+	tg := testharness.NewTelegram(t)
+	b := &Bot{
+		config: &config.Config{AutoPin: true, TramuntanaDir: t.TempDir()},
+		state:  state.NewState(),
+		api:    tg.API(),
+	}
+
+	b.pinMessage(-100, 7, 10, pinPlan)
+	b.pinMessage(-100, 7, 11, pinBookmark)
+	b.pinMessage(-100, 7, 12, pinPlan)
+
+	if n := len(tg.Calls("pinChatMessage")); n != 3 {
+		t.Errorf("pinned %d messages, want 3", n)
+	}
+	unpins := tg.Calls("unpinChatMessage")
+	if len(unpins) != 1 || unpins[0].Params["message_id"] != "10" {
+		t.Errorf("unexpected unpins: %+v", unpins)
+	}
+	if pm, _ := b.state.GetPin("7", pinPlan); pm.MessageID != 12 {
+		t.Errorf("plan pin = %d, want 12", pm.MessageID)
+	}
+}
+
+func TestPinMessage_DisabledByDefault(t *testing.T) {
+	tg := testharness.NewTelegram(t)
+	b := &Bot{config: &config.Config{}, state: state.NewState(), api: tg.API()}
+
+	b.pinMessage(-100, 7, 10, pinPlan)
+	if n := len(tg.Calls("pinChatMessage")); n != 0 {
+		t.Errorf("pinned %d messages with auto-pin off", n)
+	}
+}
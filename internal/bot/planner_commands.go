@@ -9,6 +9,7 @@ import (
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/state"
 	"github.com/otaviocarvalho/tramuntana/internal/tmux"
 )
 
@@ -54,6 +55,14 @@ func (b *Bot) plannerStart(msg *tgbotapi.Message, chatID int64, threadID int, to
 		return
 	}
 
+	start := func() { b.createPlanner(msg, chatID, threadID, project) }
+	if b.guardSession(chatID, threadID, msg.From.ID, start) {
+		start()
+	}
+}
+
+// createPlanner creates the planner's topic and window for plannerStart.
+func (b *Bot) createPlanner(msg *tgbotapi.Message, chatID int64, threadID int, project string) {
 	b.reply(chatID, threadID, fmt.Sprintf("Creating planner for %s...", project))
 
 	// Create a new Telegram forum topic for the planner
@@ -72,11 +81,15 @@ func (b *Bot) plannerStart(msg *tgbotapi.Message, chatID int64, threadID int, to
 	if env == nil {
 		env = make(map[string]string)
 	}
+	launch := b.resolveLaunch(dir, project)
+	for k, v := range launch.Env {
+		env[k] = v
+	}
 	env["MINUANO_PROJECT"] = project
 
 	// Build planner Claude command
 	claudeCmd := fmt.Sprintf("%s --dangerously-skip-permissions --system-prompt \"$(cat %s)\"",
-		b.config.ClaudeCommand, b.config.PlannerPromptPath)
+		launch.Command, b.config.PlannerPromptPath)
 
 	// Create tmux window with the planner Claude command
 	windowID, err := tmux.NewWindow(b.config.TmuxSessionName, topicName, dir, claudeCmd, env)
@@ -98,6 +111,11 @@ func (b *Bot) plannerStart(msg *tgbotapi.Message, chatID int64, threadID int, to
 	b.state.SetGroupChatID(userIDStr, newThreadIDStr, chatID)
 	b.state.BindProject(newThreadIDStr, project)
 	b.state.SetWindowDisplayName(windowID, topicName)
+	b.state.SetWindowState(windowID, state.WindowState{
+		CWD:        dir,
+		WindowName: topicName,
+		AgentID:    env["MINUANO_AGENT_ID"],
+	})
 	b.saveState()
 
 	// Note: we don't call `minuano planner start` here because it creates
@@ -137,8 +155,9 @@ func (b *Bot) plannerReopen(msg *tgbotapi.Message, chatID int64, threadID int, t
 	userID := strconv.FormatInt(msg.From.ID, 10)
 	if windowID, bound := b.state.GetWindowForThread(userID, topicIDStr); bound {
 		// Window exists, try to restart Claude in it
+		ws, _ := b.state.GetWindowState(windowID)
 		claudeCmd := fmt.Sprintf("%s --dangerously-skip-permissions --system-prompt \"$(cat %s)\"",
-			b.config.ClaudeCommand, b.config.PlannerPromptPath)
+			b.resolveLaunch(ws.CWD, project).Command, b.config.PlannerPromptPath)
 		if err := tmux.SendKeysWithDelay(b.config.TmuxSessionName, windowID, claudeCmd, 500); err != nil {
 			if tmux.IsWindowDead(err) {
 				// Window is dead, fall through to create new one
@@ -0,0 +1,117 @@
+package bot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// maxPreambleLen caps a preamble, which is typed into Claude with every prompt.
+const maxPreambleLen = 500
+
+const preambleUsage = "Usage:\n" +
+	"/preamble — show this topic's preamble\n" +
+	"/preamble set <text> — prepend <text> to prompts from this topic\n" +
+	"/preamble mode turn|session — every prompt, or the first of each Claude session\n" +
+	"/preamble clear — remove it"
+
+// handlePreambleCommand shows or edits this topic's standing instructions.
+func (b *Bot) handlePreambleCommand(msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+	threadID := getThreadID(msg)
+	threadIDStr := strconv.Itoa(threadID)
+
+	args := strings.TrimSpace(msg.CommandArguments())
+	sub, rest, _ := strings.Cut(args, " ")
+	rest = strings.TrimSpace(rest)
+	p, has := b.state.GetPreamble(threadIDStr)
+
+	switch strings.ToLower(sub) {
+	case "":
+		if !has {
+			b.reply(chatID, threadID, "No preamble for this topic.\n\n"+preambleUsage)
+			return
+		}
+		b.reply(chatID, threadID, fmt.Sprintf("Preamble (%s):\n%s", preambleModeText(p.Mode), p.Text))
+	case "set":
+		text := strings.Join(strings.Fields(rest), " ") // typed as one line
+		if text == "" {
+			b.reply(chatID, threadID, preambleUsage)
+			return
+		}
+		if n := len([]rune(text)); n > maxPreambleLen {
+			b.reply(chatID, threadID, fmt.Sprintf("Preamble too long (%d characters, max %d).", n, maxPreambleLen))
+			return
+		}
+		if !has {
+			p.Mode = "turn"
+		}
+		p.Text, p.AppliedSession = text, ""
+		b.state.SetPreamble(threadIDStr, p)
+		b.saveState()
+		b.reply(chatID, threadID, fmt.Sprintf("Preamble set (%s):\n%s", preambleModeText(p.Mode), p.Text))
+	case "mode":
+		mode := strings.ToLower(rest)
+		if mode != "turn" && mode != "session" {
+			b.reply(chatID, threadID, "Usage: /preamble mode turn|session")
+			return
+		}
+		if !has {
+			b.reply(chatID, threadID, "No preamble for this topic. Set one with /preamble set <text>.")
+			return
+		}
+		p.Mode, p.AppliedSession = mode, ""
+		b.state.SetPreamble(threadIDStr, p)
+		b.saveState()
+		b.reply(chatID, threadID, "Preamble now sent "+preambleModeText(mode)+".")
+	case "clear":
+		if b.state.RemovePreamble(threadIDStr) {
+			b.saveState()
+			b.reply(chatID, threadID, "Preamble removed.")
+		} else {
+			b.reply(chatID, threadID, "No preamble for this topic.")
+		}
+	default:
+		b.reply(chatID, threadID, preambleUsage)
+	}
+}
+
+// preambleModeText describes when a preamble is sent.
+func preambleModeText(mode string) string {
+	if mode == "session" {
+		return "with the first prompt of each Claude session"
+	}
+	return "with every prompt"
+}
+
+// applyPreamble prefixes text with the thread's preamble when it is due for
+// the window's current session. The returned function records that it was
+// sent; call it once the prompt has reached Claude.
+func (b *Bot) applyPreamble(threadID, windowID, text string) (string, func()) {
+	p, ok := b.state.GetPreamble(threadID)
+	if !ok {
+		return text, func() {}
+	}
+	ws, _ := b.state.GetWindowState(windowID)
+	if p.Mode == "session" && p.AppliedSession != "" && p.AppliedSession == ws.SessionID {
+		return text, func() {}
+	}
+	sent := func() {
+		if p.Mode != "session" {
+			return
+		}
+		if cur, ok := b.state.GetPreamble(threadID); ok && cur.Text == p.Text {
+			cur.AppliedSession = ws.SessionID
+			b.state.SetPreamble(threadID, cur)
+			b.saveState()
+		}
+	}
+	return preamblePrompt(p.Text, text), sent
+}
+
+// preamblePrompt prefixes a prompt with a topic's instructions.
+func preamblePrompt(preamble, text string) string {
+	return fmt.Sprintf("[Topic instructions: %s] %s", preamble, text)
+}
@@ -0,0 +1,54 @@
+package bot
+
+import (
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+)
+
+func TestE2E_PreambleEveryTurn(t *testing.T) {
+	h := startE2E(t, "fresh")
+	windowID := h.tmux.AddWindow("api", "/work/api")
+	userID, threadID := strconv.FormatInt(e2eUser, 10), strconv.Itoa(e2eThread)
+	h.bot.state.BindThread(userID, threadID, windowID)
+	h.bot.state.SetGroupChatID(userID, threadID, e2eChat)
+
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "/preamble set Answer in\n Portuguese")
+	h.tg.WaitForText("sendMessage", "Preamble set (with every prompt):\nAnswer in Portuguese")
+
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "hello")
+	h.tmux.WaitForKeys(windowID, "[Topic instructions: Answer in Portuguese] hello")
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "again")
+	h.tmux.WaitForKeys(windowID, "[Topic instructions: Answer in Portuguese] again")
+
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "/preamble clear")
+	h.tg.WaitForText("sendMessage", "Preamble removed.")
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "plain")
+	h.tmux.WaitForKeys(windowID, "plain")
+}
+
+func TestE2E_PreambleOncePerSession(t *testing.T) {
+	h := startE2E(t, "fresh")
+	windowID := h.tmux.AddWindow("api", "/work/api")
+	userID, threadID := strconv.FormatInt(e2eUser, 10), strconv.Itoa(e2eThread)
+	h.bot.state.BindThread(userID, threadID, windowID)
+	h.bot.state.SetGroupChatID(userID, threadID, e2eChat)
+	h.bot.state.SetWindowState(windowID, state.WindowState{SessionID: "sess-1", CWD: "/work/api"})
+	h.bot.state.SetPreamble(threadID, state.Preamble{Text: "Never run destructive commands", Mode: "session"})
+
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "first")
+	h.tmux.WaitForKeys(windowID, "[Topic instructions: Never run destructive commands] first")
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "second")
+	h.tmux.WaitForKeys(windowID, "second")
+
+	h.bot.state.SetWindowState(windowID, state.WindowState{SessionID: "sess-2", CWD: "/work/api"})
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "third")
+	h.tmux.WaitForKeys(windowID, "[Topic instructions: Never run destructive commands] third")
+
+	w, _ := h.tmux.Window(windowID)
+	if slices.Contains(w.Keys, "[Topic instructions: Never run destructive commands] second") {
+		t.Errorf("preamble repeated within a session: %q", w.Keys)
+	}
+}
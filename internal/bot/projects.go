@@ -0,0 +1,171 @@
+package bot
+
+import (
+	"fmt"
+	"hash/fnv"
+	"log"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/minuano"
+)
+
+// handleProjectsCommand lists Minuano projects with an inline picker that binds
+// the selected one to this topic. "/p_projects new <name> <first task>"
+// creates a project.
+func (b *Bot) handleProjectsCommand(msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+	threadID := getThreadID(msg)
+
+	args := strings.TrimSpace(msg.CommandArguments())
+	if args == "new" || strings.HasPrefix(args, "new ") {
+		if rest := strings.TrimSpace(strings.TrimPrefix(args, "new")); rest != "" {
+			b.executeProjectCreate(msg, rest)
+			return
+		}
+		b.reply(chatID, threadID, newProjectPrompt)
+		b.setPendingInput(msg.From.ID, "p_new", chatID, threadID)
+		return
+	}
+
+	projects, err := b.minuanoBridge.Projects()
+	if err != nil {
+		log.Printf("Error listing projects: %v", err)
+		b.reply(chatID, threadID, "Error: failed to list projects.")
+		return
+	}
+
+	current, _ := b.state.GetProject(strconv.Itoa(threadID))
+	text, kb := buildProjectsPicker(projects, current)
+	if _, err := b.sendMessageWithKeyboard(chatID, threadID, text, kb); err != nil {
+		log.Printf("Error sending projects picker: %v", err)
+	}
+}
+
+// buildProjectsPicker renders the project list and its bind keyboard.
+func buildProjectsPicker(projects []minuano.Project, current string) (string, tgbotapi.InlineKeyboardMarkup) {
+	var lines []string
+	var rows [][]tgbotapi.InlineKeyboardButton
+	if len(projects) == 0 {
+		lines = append(lines, "No projects yet.")
+	} else {
+		lines = append(lines, "Projects:")
+	}
+	for _, p := range projects {
+		marker := ""
+		if p.ID == current {
+			marker = " ← bound"
+		}
+		lines = append(lines, fmt.Sprintf("  %s — %d open / %d total%s", p.ID, p.Open, p.Total, marker))
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(p.ID, projectBindData(p.ID)),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("➕ New project", "proj_new"),
+		tgbotapi.NewInlineKeyboardButtonData("Cancel", "proj_cancel"),
+	))
+	return strings.Join(lines, "\n"), tgbotapi.InlineKeyboardMarkup{InlineKeyboard: rows}
+}
+
+// processProjectsCallback handles proj_* callbacks from the /p_projects picker.
+func (b *Bot) processProjectsCallback(cq *tgbotapi.CallbackQuery) {
+	if cq.Message == nil {
+		return
+	}
+	chatID := cq.Message.Chat.ID
+	threadID := getThreadID(cq.Message)
+	messageID := cq.Message.MessageID
+
+	switch {
+	case cq.Data == "proj_cancel":
+		b.editMessageText(chatID, messageID, "Project selection cancelled.")
+	case cq.Data == "proj_new":
+		b.editMessageText(chatID, messageID, newProjectPrompt)
+		b.setPendingInput(cq.From.ID, "p_new", chatID, threadID)
+	case strings.HasPrefix(cq.Data, "proj_bind:"):
+		projects, err := b.minuanoBridge.Projects()
+		if err != nil {
+			log.Printf("Error listing projects: %v", err)
+			b.editMessageText(chatID, messageID, "Error: failed to list projects.")
+			return
+		}
+		project := ""
+		for _, p := range projects {
+			if projectBindData(p.ID) == cq.Data {
+				project = p.ID
+				break
+			}
+		}
+		if project == "" {
+			b.editMessageText(chatID, messageID, "That project no longer exists. Send /p_projects for the current list.")
+			return
+		}
+		b.bindProject(threadID, project)
+		b.editMessageText(chatID, messageID, fmt.Sprintf("Bound to project: %s", project))
+	}
+}
+
+// projectBindData is the callback data of a project's bind button:
+// "proj_bind:<ID hash>", since an ID of up to 64 bytes doesn't fit Telegram's
+// 64-byte callback data.
+func projectBindData(id string) string {
+	h := fnv.New32a()
+	h.Write([]byte(id))
+	return fmt.Sprintf("proj_bind:%08x", h.Sum32())
+}
+
+// newProjectPrompt asks for a new project's name and first task.
+const newProjectPrompt = "Send the new project name and its first task, e.g. \"billing Set up the invoice model\":"
+
+// executeProjectCreate creates a project from "<name> <first task>" and binds
+// it. Minuano projects exist through their tasks, so the first task is
+// required.
+func (b *Bot) executeProjectCreate(msg *tgbotapi.Message, text string) {
+	chatID := msg.Chat.ID
+	threadID := getThreadID(msg)
+	name, firstTask, _ := strings.Cut(strings.TrimSpace(text), " ")
+	firstTask = strings.TrimSpace(firstTask)
+
+	if !minuano.ValidProjectName(name) {
+		b.reply(chatID, threadID, "Invalid project name. Use letters, digits, '-' and '_' (max 64).")
+		return
+	}
+	if projects, err := b.minuanoBridge.Projects(); err == nil && findProject(projects, name) {
+		b.bindProject(threadID, name)
+		b.reply(chatID, threadID, fmt.Sprintf("Project %s already exists. Bound to it.", name))
+		return
+	}
+
+	if firstTask == "" {
+		b.reply(chatID, threadID, "A project starts with its first task. "+newProjectPrompt)
+		b.setPendingInput(msg.From.ID, "p_new", chatID, threadID)
+		return
+	}
+
+	result, err := b.minuanoBridge.CreateProject(name, firstTask)
+	if err != nil {
+		log.Printf("Error creating project %s: %v", name, err)
+		b.reply(chatID, threadID, fmt.Sprintf("Error: failed to create project %s.", name))
+		return
+	}
+	b.bindProject(threadID, name)
+	b.reply(chatID, threadID, fmt.Sprintf("Created project %s with its first task: %s (%s)\nBound to it.", name, result.Title, result.ID))
+}
+
+// bindProject binds a topic to a project and persists state.
+func (b *Bot) bindProject(threadID int, project string) {
+	b.state.BindProject(strconv.Itoa(threadID), project)
+	b.saveState()
+}
+
+// findProject reports whether a project with the given ID is in the list.
+func findProject(projects []minuano.Project, id string) bool {
+	for _, p := range projects {
+		if p.ID == id {
+			return true
+		}
+	}
+	return false
+}
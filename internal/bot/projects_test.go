@@ -0,0 +1,49 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/otaviocarvalho/tramuntana/internal/minuano"
+)
+
+func TestBuildProjectsPicker(t *testing.T) {
+	projects := []minuano.Project{{ID: "auth", Total: 4, Open: 2}, {ID: "web", Total: 1, Open: 0}}
+	text, kb := buildProjectsPicker(projects, "auth")
+
+	if !strings.Contains(text, "auth — 2 open / 4 total ← bound") {
+		t.Errorf("bound project not marked:\n%s", text)
+	}
+	if strings.Contains(text, "web — 0 open / 1 total ←") {
+		t.Errorf("unbound project marked:\n%s", text)
+	}
+	if len(kb.InlineKeyboard) != 3 {
+		t.Fatalf("expected 3 rows (2 projects + actions), got %d", len(kb.InlineKeyboard))
+	}
+	if got := *kb.InlineKeyboard[1][0].CallbackData; got != projectBindData("web") {
+		t.Errorf("second row callback = %q", got)
+	}
+	if got := *kb.InlineKeyboard[2][0].CallbackData; got != "proj_new" {
+		t.Errorf("action row callback = %q", got)
+	}
+}
+
+func TestBuildProjectsPicker_Empty(t *testing.T) {
+	text, kb := buildProjectsPicker(nil, "")
+	if !strings.Contains(text, "No projects yet.") {
+		t.Errorf("text = %q", text)
+	}
+	if len(kb.InlineKeyboard) != 1 {
+		t.Errorf("expected only the action row, got %d rows", len(kb.InlineKeyboard))
+	}
+}
+
+func TestProjectBindData_FitsCallbackData(t *testing.T) {
+	long := strings.Repeat("p", 64)
+	if got := projectBindData(long); len(got) > 64 {
+		t.Errorf("callback data is %d bytes", len(got))
+	}
+	if projectBindData("auth") == projectBindData("web") {
+		t.Error("different projects share callback data")
+	}
+}
@@ -0,0 +1,222 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/monitor"
+)
+
+// promptFileTTL is how long a prompt file is kept when Claude is never seen
+// reading it.
+const promptFileTTL = 24 * time.Hour
+
+// Prompt files hold task prompts and long messages too big to type. They are
+// deleted when the turn in which the window's Claude read them with the Read
+// tool ends: Claude reads a long file in chunks, so the first read is not the
+// last.
+var (
+	promptFiles   = make(map[string]*promptFile) // path → file
+	promptFilesMu sync.Mutex
+)
+
+type promptFile struct {
+	windowID string
+	read     bool // Claude has read at least part of it this turn
+}
+
+// promptDir is the private directory prompt files are written to.
+func (b *Bot) promptDir() string {
+	base := b.config.CacheDir
+	if base == "" {
+		base = b.config.TramuntanaDir
+	}
+	return filepath.Join(base, "prompts")
+}
+
+// writePromptFile writes text to a new file named by pattern for windowID's
+// Claude to read, and returns its path. The file is only readable by us
+// (os.CreateTemp uses 0600) in a 0700 directory.
+func (b *Bot) writePromptFile(windowID, pattern, text string) (string, error) {
+	dir := b.promptDir()
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("creating prompt dir: %w", err)
+	}
+	if err := os.Chmod(dir, 0700); err != nil {
+		return "", fmt.Errorf("creating prompt dir: %w", err)
+	}
+	f, err := os.CreateTemp(dir, pattern)
+	if err != nil {
+		return "", fmt.Errorf("creating prompt file: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(text); err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("writing prompt file: %w", err)
+	}
+
+	promptFilesMu.Lock()
+	promptFiles[f.Name()] = &promptFile{windowID: windowID}
+	promptFilesMu.Unlock()
+	return f.Name(), nil
+}
+
+// notePromptFilesRead marks the prompt files windowID's Claude has read.
+func notePromptFilesRead(windowID string, parsed []monitor.ParsedEntry) {
+	promptFilesMu.Lock()
+	defer promptFilesMu.Unlock()
+	for _, pe := range parsed {
+		if pe.ContentType != "tool_result" || pe.ToolName != "Read" || pe.IsError {
+			continue
+		}
+		if pf, ok := promptFiles[pe.ToolInput]; ok && pf.windowID == windowID {
+			pf.read = true
+		}
+	}
+}
+
+// releasePromptFiles deletes the prompt files windowID's Claude read during
+// the turn that just ended.
+func releasePromptFiles(windowID string) {
+	removePromptFiles(func(pf *promptFile) bool { return pf.windowID == windowID && pf.read })
+}
+
+// dropPromptFiles deletes the prompt files of a window that is gone.
+func dropPromptFiles(windowID string) {
+	removePromptFiles(func(pf *promptFile) bool { return pf.windowID == windowID })
+}
+
+// removePromptFiles deletes the prompt files match selects.
+func removePromptFiles(match func(*promptFile) bool) {
+	promptFilesMu.Lock()
+	var paths []string
+	for path, pf := range promptFiles {
+		if match(pf) {
+			paths = append(paths, path)
+			delete(promptFiles, path)
+		}
+	}
+	promptFilesMu.Unlock()
+	for _, path := range paths {
+		removePromptFile(path)
+	}
+}
+
+func removePromptFile(path string) {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		log.Printf("Error removing prompt file %s: %v", path, err)
+	}
+}
+
+// promptDirStats summarizes the prompt directory.
+type promptDirStats struct {
+	Files   int
+	Bytes   int64
+	Waiting int           // still waiting for Claude to read them
+	Oldest  time.Duration // age of the oldest file
+}
+
+// sweepPromptFiles deletes prompt files in dir older than ttl and, with
+// orphans, also those no window is waiting to read (left by an earlier run or
+// read some other way). Returns what was removed.
+func sweepPromptFiles(dir string, ttl time.Duration, orphans bool, now time.Time) promptDirStats {
+	var removed promptDirStats
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return removed
+	}
+	for _, e := range entries {
+		if !e.Type().IsRegular() || !strings.HasPrefix(e.Name(), "tramuntana-") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		promptFilesMu.Lock()
+		_, waiting := promptFiles[path]
+		expired := now.Sub(info.ModTime()) >= ttl
+		if expired {
+			delete(promptFiles, path)
+		}
+		promptFilesMu.Unlock()
+		if !expired && (waiting || !orphans) {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			log.Printf("Error removing prompt file %s: %v", path, err)
+			continue
+		}
+		removed.Files++
+		removed.Bytes += info.Size()
+	}
+	return removed
+}
+
+// scanPromptFiles reports on the prompt files in dir.
+func scanPromptFiles(dir string, now time.Time) promptDirStats {
+	var stats promptDirStats
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return stats
+	}
+	promptFilesMu.Lock()
+	defer promptFilesMu.Unlock()
+	for _, e := range entries {
+		if !e.Type().IsRegular() || !strings.HasPrefix(e.Name(), "tramuntana-") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		stats.Files++
+		stats.Bytes += info.Size()
+		if _, ok := promptFiles[filepath.Join(dir, e.Name())]; ok {
+			stats.Waiting++
+		}
+		if age := now.Sub(info.ModTime()); age > stats.Oldest {
+			stats.Oldest = age
+		}
+	}
+	return stats
+}
+
+// handlePromptsCommand reports on the prompt directory; /prompts gc deletes
+// files no window is waiting to read and expired ones. Owner only.
+func (b *Bot) handlePromptsCommand(msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+	threadID := getThreadID(msg)
+	if !b.isOwner(msg.From.ID) {
+		b.reply(chatID, threadID, "Only the bot owner can manage prompt files.")
+		return
+	}
+
+	dir := b.promptDir()
+	now := time.Now()
+	switch strings.TrimSpace(msg.CommandArguments()) {
+	case "":
+		stats := scanPromptFiles(dir, now)
+		if stats.Files == 0 {
+			b.reply(chatID, threadID, "No prompt files in "+shortenPath(dir)+".")
+			return
+		}
+		b.reply(chatID, threadID, fmt.Sprintf(
+			"Prompt files in %s: %d (%s), %d waiting to be read, oldest %s.\nFiles are deleted when the turn that reads them ends, or after %s. /prompts gc deletes the ones no session is waiting for.",
+			shortenPath(dir), stats.Files, formatFileSize(stats.Bytes), stats.Waiting, formatAge(stats.Oldest), formatAge(promptFileTTL)))
+	case "gc":
+		removed := sweepPromptFiles(dir, promptFileTTL, true, now)
+		left := scanPromptFiles(dir, now)
+		b.reply(chatID, threadID, fmt.Sprintf("Removed %d prompt file(s) (%s); %d still waiting to be read.",
+			removed.Files, formatFileSize(removed.Bytes), left.Waiting))
+	default:
+		b.reply(chatID, threadID, "Usage: /prompts [gc]")
+	}
+}
@@ -0,0 +1,85 @@
+package bot
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/otaviocarvalho/tramuntana/internal/config"
+	"github.com/otaviocarvalho/tramuntana/internal/monitor"
+)
+
+func TestReleasePromptFiles(t *testing.T) {
+	b := &Bot{config: &config.Config{CacheDir: t.TempDir()}}
+	path, err := b.writePromptFile("@1", "tramuntana-task-*.md", "do it")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dropPromptFiles("@1")
+
+	read := []monitor.ParsedEntry{{ContentType: "tool_result", ToolName: "Read", ToolInput: path}}
+	notePromptFilesRead("@2", read)
+	notePromptFilesRead("@1", []monitor.ParsedEntry{{ContentType: "tool_result", ToolName: "Read", ToolInput: path, IsError: true}})
+	releasePromptFiles("@1")
+	if _, err := os.Stat(path); err != nil {
+		t.Fatal("file should survive another window's read and a failed read")
+	}
+
+	// Claude reads long files in chunks: the file stays until the turn ends
+	notePromptFilesRead("@1", read)
+	if _, err := os.Stat(path); err != nil {
+		t.Fatal("file should survive its first read until the turn ends")
+	}
+	notePromptFilesRead("@1", read)
+	releasePromptFiles("@1")
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("file should be deleted when the turn that read it ends")
+	}
+}
+
+func TestDropPromptFiles(t *testing.T) {
+	b := &Bot{config: &config.Config{CacheDir: t.TempDir()}}
+	path, _ := b.writePromptFile("@1", "tramuntana-paste-*.txt", "long message")
+	dropPromptFiles("@1")
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("dead window's prompt file should be deleted")
+	}
+}
+
+func TestSweepPromptFiles(t *testing.T) {
+	b := &Bot{config: &config.Config{TramuntanaDir: t.TempDir()}}
+	waiting, _ := b.writePromptFile("@1", "tramuntana-task-*.md", "waiting")
+	defer dropPromptFiles("@1")
+	dir := b.promptDir()
+	orphan := filepath.Join(dir, "tramuntana-task-old.md")
+	os.WriteFile(orphan, []byte("left by an earlier run"), 0600)
+	other := filepath.Join(dir, "notes.txt")
+	os.WriteFile(other, []byte("not ours"), 0600)
+
+	now := time.Now()
+	if removed := sweepPromptFiles(dir, promptFileTTL, false, now); removed.Files != 0 {
+		t.Errorf("TTL sweep removed %d fresh files", removed.Files)
+	}
+	if stats := scanPromptFiles(dir, now); stats.Files != 2 || stats.Waiting != 1 {
+		t.Errorf("stats = %+v, want 2 files, 1 waiting", stats)
+	}
+
+	removed := sweepPromptFiles(dir, promptFileTTL, true, now)
+	if removed.Files != 1 {
+		t.Errorf("gc removed %d files, want the orphan only", removed.Files)
+	}
+	if _, err := os.Stat(orphan); !os.IsNotExist(err) {
+		t.Error("orphan should be gone")
+	}
+
+	if removed := sweepPromptFiles(dir, promptFileTTL, false, now.Add(promptFileTTL)); removed.Files != 1 {
+		t.Errorf("expired sweep removed %d files, want 1", removed.Files)
+	}
+	if _, err := os.Stat(waiting); !os.IsNotExist(err) {
+		t.Error("expired prompt file should be gone even while waiting")
+	}
+	if _, err := os.Stat(other); err != nil {
+		t.Error("files not written by us are left alone")
+	}
+}
@@ -0,0 +1,88 @@
+package bot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+)
+
+// handleQuietCommand shows or sets the user's quiet hours, read in their
+// /timezone. Usage: /quiet 23:00-08:00 | off | urgent on|off.
+func (b *Bot) handleQuietCommand(msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+	threadID := getThreadID(msg)
+	userID := strconv.FormatInt(msg.From.ID, 10)
+	us := b.state.GetUserSettings(userID)
+
+	args := strings.Fields(strings.ToLower(msg.CommandArguments()))
+	switch {
+	case len(args) == 0:
+		if us.QuietHours == "" {
+			b.reply(chatID, threadID, "Quiet hours are off. Use /quiet 23:00-08:00 to mute deliveries overnight.")
+			return
+		}
+		b.reply(chatID, threadID, fmt.Sprintf("Quiet hours: %s %s%s.\n%s\nUse /quiet off to disable.",
+			us.QuietHours, quietZone(us), quietNow(us), quietUrgentText(us)))
+		return
+	case len(args) == 1 && args[0] == "off":
+		us.QuietHours = ""
+		b.state.SetUserSettings(userID, us)
+		b.saveState()
+		b.reply(chatID, threadID, "Quiet hours disabled.")
+		return
+	case len(args) == 2 && args[0] == "urgent" && (args[1] == "on" || args[1] == "off"):
+		us.QuietAll = args[1] == "off"
+		b.state.SetUserSettings(userID, us)
+		b.saveState()
+		b.reply(chatID, threadID, quietUrgentText(us))
+		return
+	case len(args) != 1:
+		b.reply(chatID, threadID, "Usage: /quiet 23:00-08:00 | off | urgent on|off")
+		return
+	}
+
+	if _, _, err := state.ParseQuietHours(args[0]); err != nil {
+		b.reply(chatID, threadID, fmt.Sprintf("%v\nUsage: /quiet 23:00-08:00", err))
+		return
+	}
+	us.QuietHours = args[0]
+	b.state.SetUserSettings(userID, us)
+	b.saveState()
+	b.reply(chatID, threadID, fmt.Sprintf("Quiet hours set to %s %s%s. Output arrives silently and status messages are skipped.\n%s",
+		us.QuietHours, quietZone(us), quietNow(us), quietUrgentText(us)))
+}
+
+func quietZone(us state.UserSettings) string {
+	if us.Location() == nil {
+		return "server time (set /timezone for yours)"
+	}
+	return us.Timezone
+}
+
+func quietNow(us state.UserSettings) string {
+	if us.InQuietHours(time.Now()) {
+		return ", active now"
+	}
+	return ""
+}
+
+func quietUrgentText(us state.UserSettings) string {
+	if us.QuietAll {
+		return "Notifications and permission prompts are silent too. Use /quiet urgent on to let them sound."
+	}
+	return "Notifications and permission prompts still sound. Use /quiet urgent off to silence them too."
+}
+
+// quietFor reports whether deliveries to userID are muted now: in their quiet
+// hours, and for urgent messages only if they silenced those too.
+func (b *Bot) quietFor(userID int64, urgent bool) bool {
+	if userID == 0 {
+		return false
+	}
+	us := b.state.GetUserSettings(strconv.FormatInt(userID, 10))
+	return us.InQuietHours(time.Now()) && (!urgent || us.QuietAll)
+}
@@ -0,0 +1,54 @@
+package bot
+
+import (
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+)
+
+func TestE2E_QuietHoursSilenceDeliveries(t *testing.T) {
+	h := startE2E(t, "fresh")
+	windowID := h.tmux.AddWindow("api", "/work/api")
+	userID, threadID := strconv.FormatInt(e2eUser, 10), strconv.Itoa(e2eThread)
+	h.bot.state.BindThread(userID, threadID, windowID)
+	h.bot.state.SetGroupChatID(userID, threadID, e2eChat)
+	h.bot.state.SetLoud(threadID, true)
+	h.writeSession(t, windowID, "sess-1", "/work/api")
+
+	now := time.Now()
+	quiet := now.Add(-time.Hour).Format("15:04") + "-" + now.Add(time.Hour).Format("15:04")
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "/quiet "+quiet)
+	h.tg.WaitForText("sendMessage", "active now")
+
+	h.appendTranscript(t, "sess-1",
+		`{"type":"assistant","message":{"content":[{"type":"text","text":"Overnight progress"}]}}`)
+	if c := h.tg.WaitForText("sendMessage", "Overnight progress"); c.Params["disable_notification"] != "true" {
+		t.Errorf("output in quiet hours not silent: %v", c.Params)
+	}
+
+	notify := func(text string) {
+		t.Helper()
+		err := state.AppendNotification(filepath.Join(h.cfg.TramuntanaDir, state.NotificationsFile), state.HookNotification{
+			Time:    time.Now(),
+			Key:     e2eSession + ":" + windowID,
+			Message: text,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	notify("Claude needs your permission to use Bash")
+	if c := h.tg.WaitForText("sendMessage", "permission to use Bash"); c.Params["disable_notification"] != "" {
+		t.Errorf("urgent notification silenced by default: %v", c.Params)
+	}
+
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "/quiet urgent off")
+	h.tg.WaitForText("sendMessage", "silent too")
+	notify("Claude is waiting for your input")
+	if c := h.tg.WaitForText("sendMessage", "waiting for your input"); c.Params["disable_notification"] != "true" {
+		t.Errorf("notification not silent after /quiet urgent off: %v", c.Params)
+	}
+}
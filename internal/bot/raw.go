@@ -0,0 +1,258 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/tmux"
+)
+
+const (
+	// rawMaxKeys caps the text runs and keys in one raw message.
+	rawMaxKeys = 64
+	// rawKeyDelay separates the keys of a raw message, so a TUI reads
+	// Escape on its own rather than as Alt with the next key.
+	rawKeyDelay = 50 * time.Millisecond
+	// rawMinInterval is the least time between two raw messages to a window.
+	rawMinInterval = 250 * time.Millisecond
+)
+
+// Topics in raw mode, and when each window last received raw input. Raw mode
+// is not persisted: after a restart messages are prompts again.
+var (
+	rawTopics   = make(map[string]bool)      // threadID → raw mode on
+	rawLastSent = make(map[string]time.Time) // windowID → last raw message
+	rawMu       sync.Mutex
+)
+
+func isRawTopic(threadID string) bool {
+	rawMu.Lock()
+	defer rawMu.Unlock()
+	return rawTopics[threadID]
+}
+
+func setRawTopic(threadID string, on bool) {
+	rawMu.Lock()
+	defer rawMu.Unlock()
+	if on {
+		rawTopics[threadID] = true
+	} else {
+		delete(rawTopics, threadID)
+	}
+}
+
+// rawKey is one step of raw input: literal text, or a tmux key name.
+type rawKey struct {
+	Text string
+	Key  string
+}
+
+// rawKeyNames maps the names accepted in <…> to tmux key names.
+var rawKeyNames = map[string]string{
+	"esc": "Escape", "escape": "Escape",
+	"cr": "Enter", "enter": "Enter", "return": "Enter",
+	"tab": "Tab", "space": "Space",
+	"bs": "BSpace", "backspace": "BSpace",
+	"del": "DC", "delete": "DC", "ins": "IC", "insert": "IC",
+	"up": "Up", "down": "Down", "left": "Left", "right": "Right",
+	"home": "Home", "end": "End",
+	"pageup": "PPage", "pgup": "PPage", "pagedown": "NPage", "pgdn": "NPage",
+}
+
+// parseRawKeys splits a raw message into text and keys. Keys are written
+// vim-style: <Esc>, <Enter>, <Tab>, <Up>, <F1>…<F12>, and with modifiers
+// <C-c>, <M-x>, <S-Tab>; <lt> is a literal "<". Newlines press Enter and
+// tabs press Tab. Anything else in angle brackets is typed as is.
+func parseRawKeys(text string) []rawKey {
+	var keys []rawKey
+	var lit strings.Builder
+	flush := func() {
+		if lit.Len() > 0 {
+			keys = append(keys, rawKey{Text: lit.String()})
+			lit.Reset()
+		}
+	}
+	pressKey := func(name string) {
+		flush()
+		keys = append(keys, rawKey{Key: name})
+	}
+
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; c {
+		case '\n', '\r':
+			pressKey("Enter")
+		case '\t':
+			pressKey("Tab")
+		case '<':
+			end := strings.IndexByte(text[i+1:], '>')
+			if end < 0 {
+				lit.WriteByte(c)
+				continue
+			}
+			name := text[i+1 : i+1+end]
+			if strings.EqualFold(name, "lt") {
+				lit.WriteByte('<')
+			} else if key, ok := tmuxKeyName(name); ok {
+				pressKey(key)
+			} else {
+				lit.WriteByte(c)
+				continue
+			}
+			i += end + 1
+		default:
+			lit.WriteByte(c)
+		}
+	}
+	flush()
+	return keys
+}
+
+// tmuxKeyName translates a key written inside <…> to its tmux name.
+func tmuxKeyName(name string) (string, bool) {
+	var mods string
+	for len(name) > 2 && name[1] == '-' {
+		switch name[0] {
+		case 'C', 'c':
+			mods += "C-"
+		case 'M', 'm', 'A', 'a':
+			mods += "M-"
+		case 'S', 's':
+			mods += "S-"
+		default:
+			return "", false
+		}
+		name = name[2:]
+	}
+	lower := strings.ToLower(name)
+	if key, ok := rawKeyNames[lower]; ok {
+		return mods + key, true
+	}
+	if n, err := strconv.Atoi(strings.TrimPrefix(lower, "f")); err == nil && strings.HasPrefix(lower, "f") && n >= 1 && n <= 12 {
+		return mods + "F" + strconv.Itoa(n), true
+	}
+	if mods != "" && len(name) == 1 && name[0] > ' ' && name[0] < 0x7f {
+		return mods + lower, true
+	}
+	return "", false
+}
+
+// handleRawCommand handles /raw [on|off]: in raw mode this topic's messages
+// are typed into the window as keystrokes, without Enter, for TUIs such as
+// vim or htop. No argument toggles.
+func (b *Bot) handleRawCommand(msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+	threadID := getThreadID(msg)
+	threadIDStr := strconv.Itoa(threadID)
+
+	on := !isRawTopic(threadIDStr)
+	switch strings.ToLower(strings.TrimSpace(msg.CommandArguments())) {
+	case "on":
+		on = true
+	case "off":
+		on = false
+	case "":
+	default:
+		b.reply(chatID, threadID, "Usage: /raw [on|off]")
+		return
+	}
+
+	setRawTopic(threadIDStr, on)
+	if on {
+		b.reply(chatID, threadID, "Raw mode on: messages are typed into the terminal as keystrokes, without Enter. "+
+			"Write keys as <Esc>, <Enter>, <Tab>, <Up>, <F1>, <C-c> or <M-x> (<lt> for \"<\"); a new line presses Enter. "+
+			"/enter presses Enter, /raw off returns to prompts.")
+	} else {
+		b.reply(chatID, threadID, "Raw mode off: messages are sent to Claude as prompts again.")
+	}
+}
+
+// handleEnterCommand presses Enter in the bound window.
+func (b *Bot) handleEnterCommand(msg *tgbotapi.Message) {
+	windowID, bound := b.resolveWindow(msg)
+	if !bound {
+		b.reply(msg.Chat.ID, getThreadID(msg), "Topic not bound to a session.")
+		return
+	}
+	if b.rejectIfObserved(msg) {
+		return
+	}
+
+	if err := tmux.SendEnter(b.config.TmuxSessionName, windowID); err != nil {
+		if tmux.IsWindowDead(err) {
+			b.handleDeadWindow(msg, windowID, "")
+			return
+		}
+		log.Printf("Error sending Enter to %s: %v", windowID, err)
+		b.reply(msg.Chat.ID, getThreadID(msg), "Error: failed to send Enter.")
+	}
+}
+
+// sendRawInput types a raw-mode message into the window, key by key, and
+// no faster than rawMinInterval after the window's previous raw message.
+func (b *Bot) sendRawInput(msg *tgbotapi.Message, windowID, text string) {
+	keys := parseRawKeys(text)
+	if len(keys) > rawMaxKeys {
+		b.reply(msg.Chat.ID, getThreadID(msg), fmt.Sprintf("Too many keys in one message (%d, at most %d). Split it into several.", len(keys), rawMaxKeys))
+		return
+	}
+
+	err := tmux.WithWindow(b.config.TmuxSessionName, windowID, func(w tmux.Writer) error {
+		rawMu.Lock()
+		wait := time.Until(rawLastSent[windowID].Add(rawMinInterval))
+		rawMu.Unlock()
+		if wait > 0 {
+			time.Sleep(wait)
+		}
+		defer func() {
+			rawMu.Lock()
+			rawLastSent[windowID] = time.Now()
+			rawMu.Unlock()
+		}()
+
+		for i, k := range keys {
+			if i > 0 {
+				time.Sleep(rawKeyDelay)
+			}
+			var err error
+			if k.Key != "" {
+				err = w.Key(k.Key)
+			} else {
+				err = w.Keys(k.Text)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if tmux.IsWindowDead(err) {
+			b.handleDeadWindow(msg, windowID, "")
+			return
+		}
+		log.Printf("Error sending raw input to %s: %v", windowID, err)
+		b.reply(msg.Chat.ID, getThreadID(msg), "Error: failed to send keys.")
+	}
+}
+
+// handleRawMessage types msg into its window if its topic is in raw mode,
+// and returns whether it did.
+func (b *Bot) handleRawMessage(msg *tgbotapi.Message) bool {
+	if !isRawTopic(strconv.Itoa(getThreadID(msg))) {
+		return false
+	}
+	windowID, bound := b.resolveWindow(msg)
+	if !bound {
+		return false
+	}
+	if !b.rejectIfObserved(msg) {
+		b.sendRawInput(msg, windowID, msg.Text)
+	}
+	return true
+}
@@ -0,0 +1,61 @@
+package bot
+
+import (
+	"reflect"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func TestParseRawKeys(t *testing.T) {
+	tests := []struct {
+		text string
+		want []rawKey
+	}{
+		{"dd", []rawKey{{Text: "dd"}}},
+		{"iHello<Esc>:wq<Enter>", []rawKey{{Text: "iHello"}, {Key: "Escape"}, {Text: ":wq"}, {Key: "Enter"}}},
+		{"<C-c>", []rawKey{{Key: "C-c"}}},
+		{"<c-X><M-x><S-Tab>", []rawKey{{Key: "C-x"}, {Key: "M-x"}, {Key: "S-Tab"}}},
+		{"<f5><PageDown><up>", []rawKey{{Key: "F5"}, {Key: "NPage"}, {Key: "Up"}}},
+		{"a\nb\tc", []rawKey{{Text: "a"}, {Key: "Enter"}, {Text: "b"}, {Key: "Tab"}, {Text: "c"}}},
+		{"x<lt>y", []rawKey{{Text: "x<y"}}},
+		{"if a<b && c>d", []rawKey{{Text: "if a<b && c>d"}}},
+		{"<F13><>", []rawKey{{Text: "<F13><>"}}},
+		{"a <", []rawKey{{Text: "a <"}}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := parseRawKeys(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseRawKeys(%q) = %+v, want %+v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestE2E_RawMode(t *testing.T) {
+	h := startE2E(t, "fresh")
+	windowID := h.tmux.AddWindow("api", "/work/api")
+	userID, threadID := strconv.FormatInt(e2eUser, 10), strconv.Itoa(e2eThread)
+	h.bot.state.BindThread(userID, threadID, windowID)
+	h.bot.state.SetGroupChatID(userID, threadID, e2eChat)
+	t.Cleanup(func() { setRawTopic(threadID, false) })
+
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "/raw")
+	h.tg.WaitForText("sendMessage", "Raw mode on")
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "ihello<Esc>:wq")
+	h.tmux.WaitForKeys(windowID, ":wq")
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "/s")
+	h.tmux.WaitForKeys(windowID, "/s")
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "/enter")
+	h.tmux.WaitForKeys(windowID, "Enter")
+
+	w, _ := h.tmux.Window(windowID)
+	if want := []string{"ihello", "Escape", ":wq", "/s", "Enter"}; !slices.Equal(w.Keys, want) {
+		t.Errorf("keys = %q, want %q", w.Keys, want)
+	}
+
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "/raw off")
+	h.tg.WaitForText("sendMessage", "Raw mode off")
+	if isRawTopic(threadID) {
+		t.Error("raw mode still on after /raw off")
+	}
+}
@@ -0,0 +1,63 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/otaviocarvalho/tramuntana/internal/tmux"
+)
+
+// Text held for a new window waits up to readyTimeout for Claude's prompt; a
+// notice is posted once it has waited readyNotice. Variables so tests can
+// shorten them.
+var (
+	readyTimeout = 90 * time.Second
+	readyNotice  = 3 * time.Second
+)
+
+// sendWhenReady holds text for a window that was just started and calls send
+// once Claude's prompt is up, so the text can't land in the shell of a slow
+// startup. The wait runs in the background, since it can take up to
+// readyTimeout; the outcome is reported in the topic. If the wait is
+// noticeable a "still starting" notice is posted and later edited with the
+// outcome. what names the text in those messages ("your message").
+func (b *Bot) sendWhenReady(chatID int64, threadID int, windowID, what string, send func() error) {
+	go b.awaitReadyAndSend(chatID, threadID, windowID, what, send)
+}
+
+// awaitReadyAndSend is sendWhenReady's wait. Reports whether the text was
+// sent.
+func (b *Bot) awaitReadyAndSend(chatID int64, threadID int, windowID, what string, send func() error) bool {
+	session := b.config.TmuxSessionName
+	ready := tmux.WaitForReady(session, windowID, readyNotice)
+	noticeID := 0
+	if !ready {
+		msg, err := b.sendMessageInThread(chatID, threadID, fmt.Sprintf("⏳ Claude is still starting… %s will be sent when it's ready.", what))
+		if err == nil {
+			noticeID = msg.MessageID
+		}
+		ready = tmux.WaitForReady(session, windowID, readyTimeout-readyNotice)
+	}
+
+	var outcome string
+	sent := false
+	if !ready {
+		log.Printf("Window %s not ready after %s; dropping held text", windowID, readyTimeout)
+		outcome = fmt.Sprintf("⚠️ Claude didn't start within %d seconds, so %s wasn't sent. Send it again once the session is up.", int(readyTimeout.Seconds()), what)
+	} else if err := send(); err != nil {
+		log.Printf("Error sending held text to %s: %v", windowID, err)
+		outcome = fmt.Sprintf("⚠️ Failed to send %s to the session.", what)
+	} else {
+		sent = true
+		outcome = fmt.Sprintf("✅ Claude is ready; %s was sent.", what)
+	}
+
+	switch {
+	case noticeID != 0:
+		b.editMessageText(chatID, noticeID, outcome)
+	case !sent:
+		b.reply(chatID, threadID, outcome)
+	}
+	return sent
+}
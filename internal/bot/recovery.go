@@ -2,8 +2,10 @@ package bot
 
 import (
 	"log"
+	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/otaviocarvalho/tramuntana/internal/state"
@@ -62,14 +64,14 @@ func (b *Bot) reconcileState() int {
 		dropped++
 	}
 
-	// Clean up stale project bindings for threads with no binding
-	cleanStaleProjects(b.state)
+	// Clean up stale project and worktree records for threads with no binding
+	stale := cleanStaleProjects(b.state) + cleanStaleWorktrees(b.state)
 
 	// Clean up stale session_map entries
 	b.cleanStaleSessionMap(liveIDs)
 
-	if dropped > 0 || reresolved > 0 {
-		b.saveStateUnlocked()
+	if dropped > 0 || reresolved > 0 || stale > 0 {
+		b.flushState()
 	}
 
 	total := 0
@@ -142,10 +144,11 @@ func (b *Bot) handleDeadWindow(msg *tgbotapi.Message, windowID, pendingText stri
 	}
 
 	// Save info we need before cleanup
-	var cwd string
+	var cwd, sessionID string
 	var projectBinding string
 	if ws, ok := b.state.GetWindowState(windowID); ok {
 		cwd = ws.CWD
+		sessionID = ws.SessionID
 	}
 	if proj, ok := b.state.GetProject(threadID); ok {
 		projectBinding = proj
@@ -190,34 +193,127 @@ func (b *Bot) handleDeadWindow(msg *tgbotapi.Message, windowID, pendingText stri
 		return true
 	}
 
+	offer := &recoveryOffer{
+		CWD:         cwd,
+		SessionID:   sessionID,
+		Project:     projectBinding,
+		PendingText: pendingText,
+		ChatID:      chatID,
+		ThreadID:    threadIDInt,
+	}
+
+	if b.config.ResumeMode == "ask" {
+		text := "Session died. How should it restart?"
+		if pendingText != "" {
+			text += "\nYour message will be sent once it is back."
+		}
+		sent, err := b.sendMessageWithKeyboard(chatID, threadIDInt, text, buildRecoveryKeyboard(sessionID != ""))
+		if err != nil {
+			log.Printf("Error sending recovery options: %v", err)
+			return true
+		}
+		offer.MessageID = sent.MessageID
+		b.mu.Lock()
+		b.recoveryOffers[msg.From.ID] = offer
+		b.mu.Unlock()
+		return true
+	}
+
 	// Auto-recreate in the same directory
-	log.Printf("Dead window %s: auto-recreating in %s", windowID, cwd)
+	log.Printf("Dead window %s: auto-recreating in %s (%s)", windowID, cwd, b.config.ResumeMode)
 	b.reply(chatID, threadIDInt, "Session died. Restarting...")
+	b.recreateWindow(msg.From.ID, offer, b.config.ResumeMode)
+	return true
+}
+
+// recoveryOffer holds what is needed to recreate a dead session once the user
+// picks how Claude should start.
+type recoveryOffer struct {
+	CWD         string
+	SessionID   string
+	Project     string
+	PendingText string
+	ChatID      int64
+	ThreadID    int
+	MessageID   int
+}
+
+// claudeLaunchCommand returns the Claude command line for a restart mode:
+// "resume" reopens sessionID (or the latest conversation if unknown),
+// "continue" reopens the latest conversation in the directory, "fork" starts
+// a new session from a copy of sessionID, and anything else starts fresh.
+func claudeLaunchCommand(claudeCmd, mode, sessionID string) string {
+	switch {
+	case mode == "fork" && sessionID != "":
+		return claudeCmd + " --resume " + sessionID + " --fork-session"
+	case mode == "resume" && sessionID != "":
+		return claudeCmd + " --resume " + sessionID
+	case mode == "resume" || mode == "continue":
+		return claudeCmd + " --continue"
+	default:
+		return claudeCmd
+	}
+}
+
+func buildRecoveryKeyboard(hasSession bool) tgbotapi.InlineKeyboardMarkup {
+	var row []tgbotapi.InlineKeyboardButton
+	if hasSession {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData("Resume session", "recover_resume"))
+	}
+	row = append(row,
+		tgbotapi.NewInlineKeyboardButtonData("Continue latest", "recover_continue"),
+		tgbotapi.NewInlineKeyboardButtonData("Start fresh", "recover_fresh"),
+	)
+	return tgbotapi.NewInlineKeyboardMarkup(row)
+}
+
+// processRecoveryCallback handles recover_* callbacks from the dead-session prompt.
+func (b *Bot) processRecoveryCallback(cq *tgbotapi.CallbackQuery) {
+	userID := cq.From.ID
+
+	b.mu.Lock()
+	offer, ok := b.recoveryOffers[userID]
+	if ok {
+		delete(b.recoveryOffers, userID)
+	}
+	b.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	mode := strings.TrimPrefix(cq.Data, "recover_")
+	b.editMessageText(offer.ChatID, offer.MessageID, "Session died. Restarting...")
+	b.recreateWindow(userID, offer, mode)
+}
 
-	result, err := b.createWindowForDir(cwd, msg.From.ID, chatID, threadIDInt)
+// recreateWindow starts a new window in the dead session's directory, restores
+// the project binding and delivers any pending text.
+func (b *Bot) recreateWindow(userID int64, offer *recoveryOffer, mode string) {
+	launch := b.resolveSessionLaunch(offer.CWD, offer.Project)
+	launch.Command = claudeLaunchCommand(launch.Command, mode, offer.SessionID)
+	result, err := b.createWindowWithCommand(offer.CWD, launch, userID, offer.ChatID, offer.ThreadID)
 	if err != nil {
-		log.Printf("Error auto-recreating window in %s: %v", cwd, err)
-		b.reply(chatID, threadIDInt, "Failed to restart. Send a message to try again.")
-		return true
+		log.Printf("Error auto-recreating window in %s: %v", offer.CWD, err)
+		b.alertRecoveryFailed(offer.CWD, err)
+		b.reply(offer.ChatID, offer.ThreadID, "Failed to restart. Send a message to try again.")
+		return
 	}
 
 	// Restore project binding
-	if projectBinding != "" {
-		b.state.BindProject(threadID, projectBinding)
+	if offer.Project != "" {
+		b.state.BindProject(strconv.Itoa(offer.ThreadID), offer.Project)
 		b.saveState()
 	}
 
 	// Rename topic
-	b.renameForumTopic(chatID, threadIDInt, result.WindowName)
+	b.renameForumTopic(offer.ChatID, offer.ThreadID, result.WindowName)
 
 	// Send pending text to new session
-	if pendingText != "" {
-		if err := tmux.SendKeysWithDelay(b.config.TmuxSessionName, result.WindowID, pendingText, 500); err != nil {
-			log.Printf("Error sending pending text after recovery: %v", err)
-		}
+	if offer.PendingText != "" {
+		b.sendWhenReady(offer.ChatID, offer.ThreadID, result.WindowID, "your message", func() error {
+			return tmux.SendKeysWithDelay(b.config.TmuxSessionName, result.WindowID, offer.PendingText, 500)
+		})
 	}
-
-	return true
 }
 
 // cleanupDeadWindow removes all state for a dead window.
@@ -232,6 +328,14 @@ func cleanupDeadWindow(b *Bot, windowID string) {
 
 	// Remove window state and display name
 	b.state.RemoveWindowState(windowID)
+	stopAutoLoop(windowID)
+	stopBatchProgress(windowID)
+	stopToolProgress(windowID)
+	stopPickw(windowID)
+	stopTaskLink(windowID)
+	dropPromptFiles(windowID)
+	forgetTurnSummary(windowID)
+	stopClaudeWatch(windowID)
 
 	// Remove monitor state and session_map entries
 	sessionMapPath := filepath.Join(b.config.TramuntanaDir, "session_map.json")
@@ -248,23 +352,48 @@ func cleanupDeadWindow(b *Bot, windowID string) {
 	}
 }
 
-// cleanStaleProjects removes project bindings for threads that have no bindings.
-func cleanStaleProjects(s *state.State) {
-	// Collect all thread IDs that have active bindings
-	activeThreads := make(map[string]bool)
+// boundThreads returns the thread IDs any user has bound to a window.
+func boundThreads(s *state.State) map[string]bool {
+	active := make(map[string]bool)
 	for _, userID := range s.AllUserIDs() {
-		// Check all threads for this user
-		users := s.FindUsersForWindow("") // this won't work, need different approach
-		for _, ut := range users {
-			if ut.UserID == userID {
-				activeThreads[ut.ThreadID] = true
-			}
+		for threadID := range s.ThreadsForUser(userID) {
+			active[threadID] = true
 		}
 	}
+	return active
+}
 
-	// Note: We can't easily iterate ProjectBindings without exposing internals.
-	// For now, project bindings are cleaned via handleTopicClose and are
-	// not critical enough for startup cleanup.
+// cleanStaleProjects removes project bindings for threads that have no
+// bindings. Returns the number removed.
+func cleanStaleProjects(s *state.State) int {
+	active := boundThreads(s)
+	removed := 0
+	for threadID := range s.AllProjectBindings() {
+		if !active[threadID] {
+			s.RemoveProject(threadID)
+			removed++
+		}
+	}
+	return removed
+}
+
+// cleanStaleWorktrees removes worktree records of unbound threads whose
+// worktree directory is gone. Records of existing worktrees are kept so
+// merges can still clean them up. Returns the number removed.
+func cleanStaleWorktrees(s *state.State) int {
+	active := boundThreads(s)
+	removed := 0
+	for threadID, wi := range s.AllWorktreeInfos() {
+		if active[threadID] {
+			continue
+		}
+		if _, err := os.Stat(wi.WorktreeDir); wi.WorktreeDir != "" && err == nil {
+			continue
+		}
+		s.RemoveWorktreeInfo(threadID)
+		removed++
+	}
+	return removed
 }
 
 // cleanStaleSessionMap removes session_map entries for dead windows.
@@ -287,11 +416,3 @@ func (b *Bot) cleanStaleSessionMap(liveIDs map[string]bool) {
 		state.RemoveSessionMapEntry(sessionMapPath, key)
 	}
 }
-
-// saveStateUnlocked saves state (caller must hold b.mu).
-func (b *Bot) saveStateUnlocked() {
-	path := filepath.Join(b.config.TramuntanaDir, "state.json")
-	if err := b.state.Save(path); err != nil {
-		log.Printf("Error saving state: %v", err)
-	}
-}
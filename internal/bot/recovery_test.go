@@ -53,18 +53,39 @@ func TestReResolveWindow(t *testing.T) {
 
 func TestCleanStaleProjects(t *testing.T) {
 	s := state.NewState()
+	s.BindThread("user1", "thread1", "@1")
 	s.BindProject("thread1", "proj1")
 	s.BindProject("thread2", "proj2")
 
-	// This is more of a smoke test since the function currently
-	// can't iterate ProjectBindings without exposing internals
-	cleanStaleProjects(s)
+	if n := cleanStaleProjects(s); n != 1 {
+		t.Errorf("removed %d projects, want 1", n)
+	}
+	if _, ok := s.GetProject("thread1"); !ok {
+		t.Error("bound thread lost its project")
+	}
+	if _, ok := s.GetProject("thread2"); ok {
+		t.Error("unbound thread kept its project")
+	}
+}
 
-	// Projects should still exist since we can't easily check
-	_, ok1 := s.GetProject("thread1")
-	_, ok2 := s.GetProject("thread2")
-	if !ok1 || !ok2 {
-		t.Error("projects should still exist (cleanup is limited)")
+func TestCleanStaleWorktrees(t *testing.T) {
+	s := state.NewState()
+	live := t.TempDir()
+	s.BindThread("user1", "thread1", "@1")
+	s.SetWorktreeInfo("thread1", state.WorktreeInfo{WorktreeDir: "/gone/bound"})
+	s.SetWorktreeInfo("thread2", state.WorktreeInfo{WorktreeDir: live})
+	s.SetWorktreeInfo("thread3", state.WorktreeInfo{WorktreeDir: "/gone/unbound"})
+
+	if n := cleanStaleWorktrees(s); n != 1 {
+		t.Errorf("removed %d worktree records, want 1", n)
+	}
+	for _, threadID := range []string{"thread1", "thread2"} {
+		if _, ok := s.GetWorktreeInfo(threadID); !ok {
+			t.Errorf("%s lost its worktree record", threadID)
+		}
+	}
+	if _, ok := s.GetWorktreeInfo("thread3"); ok {
+		t.Error("kept the record of a removed worktree with no binding")
 	}
 }
 
@@ -147,3 +168,37 @@ func TestFindUsersForWindow(t *testing.T) {
 		t.Error("should find both user1 and user2")
 	}
 }
+
+func TestClaudeLaunchCommand(t *testing.T) {
+	tests := []struct {
+		mode, sessionID, want string
+	}{
+		{"fresh", "abc", "claude"},
+		{"resume", "abc", "claude --resume abc"},
+		{"resume", "", "claude --continue"},
+		{"continue", "abc", "claude --continue"},
+		{"fork", "abc", "claude --resume abc --fork-session"},
+		{"fork", "", "claude"},
+		{"", "", "claude"},
+	}
+	for _, tt := range tests {
+		if got := claudeLaunchCommand("claude", tt.mode, tt.sessionID); got != tt.want {
+			t.Errorf("claudeLaunchCommand(%q, %q) = %q, want %q", tt.mode, tt.sessionID, got, tt.want)
+		}
+	}
+}
+
+func TestBuildRecoveryKeyboard(t *testing.T) {
+	kb := buildRecoveryKeyboard(true)
+	row := kb.InlineKeyboard[0]
+	if len(row) != 3 || *row[0].CallbackData != "recover_resume" {
+		t.Errorf("with session: got %d buttons, first %q", len(row), *row[0].CallbackData)
+	}
+
+	kb = buildRecoveryKeyboard(false)
+	for _, btn := range kb.InlineKeyboard[0] {
+		if *btn.CallbackData == "recover_resume" {
+			t.Error("resume offered without a session ID")
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package bot
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// hostLoad is a snapshot of what the guard checks before a new session is
+// started. Fields that could not be read are -1.
+type hostLoad struct {
+	Load1   float64 // 1-minute load average
+	CPUs    int
+	FreeMB  int64 // memory available to new processes
+	Claudes int   // running Claude processes
+}
+
+// readHostLoad samples the host. Load and memory come from /proc on Linux and
+// sysctl elsewhere (no memory figure there); Claude processes are counted by
+// process name.
+func readHostLoad() hostLoad {
+	h := hostLoad{Load1: -1, CPUs: runtime.NumCPU(), FreeMB: -1, Claudes: -1}
+	if data, err := os.ReadFile("/proc/loadavg"); err == nil {
+		h.Load1 = parseLoadAvg(string(data))
+	} else if out, err := exec.Command("sysctl", "-n", "vm.loadavg").Output(); err == nil {
+		h.Load1 = parseLoadAvg(strings.Trim(strings.TrimSpace(string(out)), "{}"))
+	}
+	if data, err := os.ReadFile("/proc/meminfo"); err == nil {
+		h.FreeMB = parseMemAvailable(data)
+	}
+	h.Claudes = countClaudeProcesses()
+	return h
+}
+
+// parseLoadAvg returns the first field of a load average line, or -1.
+func parseLoadAvg(s string) float64 {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return -1
+	}
+	v, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return -1
+	}
+	return v
+}
+
+// parseMemAvailable returns MemAvailable from /proc/meminfo in MB, or -1.
+func parseMemAvailable(data []byte) int64 {
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 2 || fields[0] != "MemAvailable:" {
+			continue
+		}
+		kb, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return -1
+		}
+		return kb / 1024
+	}
+	return -1
+}
+
+// countClaudeProcesses counts processes named claude, or returns -1.
+func countClaudeProcesses() int {
+	comms, err := filepath.Glob("/proc/[0-9]*/comm")
+	if err == nil && len(comms) > 0 {
+		n := 0
+		for _, path := range comms {
+			data, err := os.ReadFile(path)
+			if err == nil && strings.TrimSpace(string(data)) == "claude" {
+				n++
+			}
+		}
+		return n
+	}
+	out, err := exec.Command("pgrep", "-x", "claude").Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+			return 0 // no matches
+		}
+		return -1
+	}
+	return len(strings.Fields(string(out)))
+}
+
+// guardLimits are the configured thresholds; zero disables each check.
+type guardLimits struct {
+	MaxLoad     float64 // per CPU
+	MinFreeMB   int64
+	MaxSessions int
+}
+
+func (l guardLimits) enabled() bool {
+	return l.MaxLoad > 0 || l.MinFreeMB > 0 || l.MaxSessions > 0
+}
+
+// check lists the limits h exceeds. Unknown readings never trip a limit.
+func (l guardLimits) check(h hostLoad) []string {
+	var problems []string
+	if l.MaxLoad > 0 && h.Load1 >= 0 && h.CPUs > 0 {
+		if limit := l.MaxLoad * float64(h.CPUs); h.Load1 > limit {
+			problems = append(problems, fmt.Sprintf("load %.1f on %d CPUs (limit %.1f)", h.Load1, h.CPUs, limit))
+		}
+	}
+	if l.MinFreeMB > 0 && h.FreeMB >= 0 && h.FreeMB < l.MinFreeMB {
+		problems = append(problems, fmt.Sprintf("%d MB memory available (minimum %d MB)", h.FreeMB, l.MinFreeMB))
+	}
+	if l.MaxSessions > 0 && h.Claudes >= l.MaxSessions {
+		problems = append(problems, fmt.Sprintf("%d Claude processes running (limit %d)", h.Claudes, l.MaxSessions))
+	}
+	return problems
+}
+
+func (b *Bot) guardLimits() guardLimits {
+	return guardLimits{
+		MaxLoad:     b.config.GuardMaxLoad,
+		MinFreeMB:   b.config.GuardMinFreeMB,
+		MaxSessions: b.config.GuardMaxSessions,
+	}
+}
+
+// pendingStart is a new session held back by the guard until its requester
+// presses Start anyway.
+type pendingStart struct {
+	ChatID    int64
+	MessageID int
+	Start     func()
+}
+
+var (
+	pendingStarts   = make(map[int64]*pendingStart) // userID → held session
+	pendingStartsMu sync.Mutex
+)
+
+// guardSession checks the host before userID's new session is started and
+// returns true if start should run now. When a limit is exceeded it warns in
+// the topic, or with GuardMode "refuse" holds start back behind Start anyway
+// and Cancel buttons and returns false. A user has at most one held session;
+// a newer one replaces it.
+func (b *Bot) guardSession(chatID int64, threadID int, userID int64, start func()) bool {
+	limits := b.guardLimits()
+	if !limits.enabled() {
+		return true
+	}
+	problems := limits.check(readHostLoad())
+	if len(problems) == 0 {
+		return true
+	}
+	text := "⚠️ Host is busy: " + strings.Join(problems, "; ") + "."
+	if b.config.GuardMode == "warn" {
+		b.reply(chatID, threadID, text+" Starting the session anyway.")
+		return true
+	}
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Start anyway", "rg_start"),
+			tgbotapi.NewInlineKeyboardButtonData("Cancel", "rg_cancel"),
+		),
+	)
+	sent, err := b.sendMessageWithKeyboard(chatID, threadID, text+"\nThe new session was not started.", keyboard)
+	if err != nil {
+		return false
+	}
+	pendingStartsMu.Lock()
+	pendingStarts[userID] = &pendingStart{ChatID: chatID, MessageID: sent.MessageID, Start: start}
+	pendingStartsMu.Unlock()
+	return false
+}
+
+// processGuardCallback handles the Start anyway and Cancel buttons.
+func (b *Bot) processGuardCallback(cq *tgbotapi.CallbackQuery) {
+	if cq.Message == nil {
+		return
+	}
+	chatID := cq.Message.Chat.ID
+	messageID := cq.Message.MessageID
+
+	pendingStartsMu.Lock()
+	p, ok := pendingStarts[cq.From.ID]
+	if ok && p.ChatID == chatID && p.MessageID == messageID {
+		delete(pendingStarts, cq.From.ID)
+	} else {
+		p = nil
+	}
+	pendingStartsMu.Unlock()
+	if p == nil {
+		b.editMessageText(chatID, messageID, "This request has expired.")
+		return
+	}
+
+	if cq.Data == "rg_cancel" {
+		b.editMessageText(chatID, messageID, "Cancelled.")
+		return
+	}
+	b.editMessageText(chatID, messageID, "Starting the session anyway.")
+	p.Start()
+}
@@ -0,0 +1,58 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseLoadAvg(t *testing.T) {
+	if got := parseLoadAvg("3.52 2.10 1.05 4/812 12345\n"); got != 3.52 {
+		t.Errorf("parseLoadAvg = %v", got)
+	}
+	// macOS sysctl vm.loadavg with braces trimmed
+	if got := parseLoadAvg(" 1.25 1.40 1.33 "); got != 1.25 {
+		t.Errorf("parseLoadAvg = %v", got)
+	}
+	if got := parseLoadAvg(""); got != -1 {
+		t.Errorf("empty = %v, want -1", got)
+	}
+}
+
+func TestParseMemAvailable(t *testing.T) {
+	data := []byte("MemTotal:       16318480 kB\nMemFree:          812340 kB\nMemAvailable:    4194304 kB\n")
+	if got := parseMemAvailable(data); got != 4096 {
+		t.Errorf("parseMemAvailable = %d, want 4096", got)
+	}
+	if got := parseMemAvailable([]byte("MemTotal: 1 kB\n")); got != -1 {
+		t.Errorf("missing MemAvailable = %d, want -1", got)
+	}
+}
+
+func TestGuardLimitsCheck(t *testing.T) {
+	limits := guardLimits{MaxLoad: 1.5, MinFreeMB: 2048, MaxSessions: 5}
+	busy := hostLoad{Load1: 7.2, CPUs: 4, FreeMB: 900, Claudes: 5}
+	problems := limits.check(busy)
+	if len(problems) != 3 {
+		t.Fatalf("problems = %q", problems)
+	}
+	if !strings.Contains(problems[0], "load 7.2 on 4 CPUs (limit 6.0)") {
+		t.Errorf("load problem = %q", problems[0])
+	}
+
+	idle := hostLoad{Load1: 0.5, CPUs: 4, FreeMB: 8000, Claudes: 2}
+	if problems := limits.check(idle); len(problems) != 0 {
+		t.Errorf("idle host tripped %q", problems)
+	}
+
+	unknown := hostLoad{Load1: -1, CPUs: 4, FreeMB: -1, Claudes: -1}
+	if problems := limits.check(unknown); len(problems) != 0 {
+		t.Errorf("unknown readings tripped %q", problems)
+	}
+
+	if (guardLimits{}).enabled() {
+		t.Error("zero limits should be disabled")
+	}
+	if problems := (guardLimits{}).check(busy); len(problems) != 0 {
+		t.Errorf("disabled limits tripped %q", problems)
+	}
+}
@@ -0,0 +1,497 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/monitor"
+	"github.com/otaviocarvalho/tramuntana/internal/tmux"
+)
+
+const (
+	// rewindWait is how long to wait for Claude's restore screens to change.
+	rewindWait = 5 * time.Second
+	// rewindPoll is how often the pane is checked meanwhile.
+	rewindPoll = 200 * time.Millisecond
+	// rewindKeyDelay spaces the arrow keys that move the list's cursor.
+	rewindKeyDelay = 50 * time.Millisecond
+	// rewindLabelLen caps a checkpoint button's label, in runes.
+	rewindLabelLen = 40
+	// rewindTTL is how long a /rewind left unfinished keeps the generic
+	// interactive keyboard away.
+	rewindTTL = 10 * time.Minute
+)
+
+// checkpointWhenRe matches the time a checkpoint list shows for an entry,
+// e.g. "3m ago", "2 hours ago", "just now" or "14:05".
+var checkpointWhenRe = regexp.MustCompile(`(?i)\(?\b(\d+\s*(?:s|secs?|seconds?|m|mins?|minutes?|h|hrs?|hours?|d|days?)\s+ago|just now|\d{1,2}:\d{2}(?:\s?[ap]m)?)\b\)?`)
+
+// restoreOptionRe matches a numbered choice on the restore confirmation,
+// e.g. "❯ 1. Restore code and conversation".
+var restoreOptionRe = regexp.MustCompile(`(?m)^[\s❯>]*([1-9])\.\s+(.+?)\s*$`)
+
+// checkpoint is an entry in Claude Code's rewind list: a prompt whose
+// conversation and code state can be restored.
+type checkpoint struct {
+	Label   string // the prompt, as the list shows it
+	When    string // e.g. "3m ago"; empty if the list shows none
+	Detail  string // lines shown under it, e.g. "2 files changed"
+	Current bool   // the present state rather than a restore point
+	Pos     int    // position in the list, for moving its cursor
+}
+
+// restoreOption is a choice on the restore confirmation.
+type restoreOption struct {
+	Num  int
+	Text string
+}
+
+// rewindState is a /rewind in progress in a topic.
+type rewindState struct {
+	windowID    string
+	messageID   int
+	checkpoints []checkpoint
+	picked      checkpoint
+	started     time.Time
+}
+
+var (
+	rewindMu sync.Mutex
+	rewinds  = make(map[interactiveKey]*rewindState)
+)
+
+// rewindInProgress reports whether a /rewind drives the restore screen of
+// windowID for this user and topic, so the generic interactive keyboard
+// stays out of its way. uiName is the interactive UI on the pane: any other
+// than the restore screens, such as a permission prompt, means the rewind is
+// over, as does rewindTTL passing, and the rewind is forgotten.
+func rewindInProgress(userID int64, threadID int, windowID, uiName string) bool {
+	rewindMu.Lock()
+	defer rewindMu.Unlock()
+	key := interactiveKey{userID, threadID}
+	rw, ok := rewinds[key]
+	if !ok || rw.windowID != windowID {
+		return false
+	}
+	if !isRestoreScreen(uiName) || time.Since(rw.started) > rewindTTL {
+		delete(rewinds, key)
+		return false
+	}
+	return true
+}
+
+// isRestoreScreen reports whether an interactive UI is one of Claude Code's
+// restore screens.
+func isRestoreScreen(uiName string) bool {
+	return uiName == "RestoreCheckpoint" || uiName == "RestoreConfirm"
+}
+
+// parseCheckpoints reads the entries of the rewind list from its interactive
+// content (header to footer), and which one the cursor is on.
+func parseCheckpoints(content string) ([]checkpoint, int) {
+	lines := strings.Split(content, "\n")
+	if len(lines) < 3 {
+		return nil, -1
+	}
+	type row struct {
+		text     string
+		col      int
+		selected bool
+	}
+	var rows []row
+	itemCol := -1
+	for _, line := range lines[1 : len(lines)-1] {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		trimmed := strings.TrimLeft(line, " ")
+		col := utf8.RuneCountInString(line) - utf8.RuneCountInString(trimmed)
+		selected := false
+		if rest, ok := strings.CutPrefix(trimmed, "❯"); ok {
+			selected = true
+			trimmed = strings.TrimLeft(rest, " ")
+			col = utf8.RuneCountInString(line) - utf8.RuneCountInString(trimmed)
+		}
+		rows = append(rows, row{strings.TrimSpace(trimmed), col, selected})
+		if itemCol < 0 || col < itemCol {
+			itemCol = col
+		}
+	}
+	// Lines above the first item are the header's description
+	for len(rows) > 0 && !rows[0].selected && strings.HasSuffix(rows[0].text, "…") {
+		rows = rows[1:]
+	}
+
+	var cps []checkpoint
+	cursor := -1
+	for _, r := range rows {
+		isDetail := r.col > itemCol || strings.HasPrefix(r.text, "⎿")
+		if isDetail && !r.selected && len(cps) > 0 {
+			d := strings.TrimSpace(strings.TrimPrefix(r.text, "⎿"))
+			cp := &cps[len(cps)-1]
+			if cp.When == "" {
+				if when := checkpointWhenRe.FindStringSubmatch(d); when != nil {
+					cp.When = when[1]
+					d = strings.TrimSpace(strings.Trim(strings.Replace(d, when[0], "", 1), " ·-"))
+				}
+			}
+			if d != "" {
+				cp.Detail = strings.TrimSpace(cp.Detail + " " + d)
+			}
+			continue
+		}
+		cp := checkpoint{Label: r.text, Pos: len(cps)}
+		if when := checkpointWhenRe.FindStringSubmatch(cp.Label); when != nil && when[0] != cp.Label {
+			cp.When = when[1]
+			cp.Label = strings.TrimSpace(strings.Trim(strings.Replace(cp.Label, when[0], "", 1), " ·-"))
+		}
+		cp.Current = strings.Contains(cp.Label, "(current)")
+		if r.selected {
+			cursor = cp.Pos
+		}
+		cps = append(cps, cp)
+	}
+	return cps, cursor
+}
+
+// restoreOptions reads the numbered choices of the restore confirmation on
+// the pane, if it shows one.
+func restoreOptions(paneText string) []restoreOption {
+	ui, ok := monitor.ExtractInteractiveContent(paneText)
+	if !ok || ui.Name != "RestoreConfirm" {
+		return nil
+	}
+	var opts []restoreOption
+	for _, m := range restoreOptionRe.FindAllStringSubmatch(ui.Content, -1) {
+		opts = append(opts, restoreOption{Num: int(m[1][0] - '0'), Text: m[2]})
+	}
+	return opts
+}
+
+// checkpointList returns the rewind list on the pane, if it shows one.
+func checkpointList(paneText string) ([]checkpoint, int, bool) {
+	ui, ok := monitor.ExtractInteractiveContent(paneText)
+	if !ok || ui.Name != "RestoreCheckpoint" {
+		return nil, -1, false
+	}
+	cps, cursor := parseCheckpoints(ui.Content)
+	return cps, cursor, len(cps) > 0
+}
+
+// checkpointTitle is a checkpoint's label and time on one line.
+func checkpointTitle(cp checkpoint) string {
+	if cp.When == "" {
+		return cp.Label
+	}
+	return cp.Label + " · " + cp.When
+}
+
+// formatCheckpoints renders the /rewind list.
+func formatCheckpoints(cps []checkpoint) string {
+	var b strings.Builder
+	b.WriteString("⏪ Rewind: pick the message to restore to the point before.\n")
+	for _, cp := range cps {
+		if cp.Current {
+			continue
+		}
+		b.WriteString("\n• " + checkpointTitle(cp))
+		if cp.Detail != "" {
+			b.WriteString(" — " + cp.Detail)
+		}
+	}
+	return b.String()
+}
+
+// checkpointsKeyboard has a button per restorable checkpoint and Cancel.
+func checkpointsKeyboard(cps []checkpoint) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, cp := range cps {
+		if cp.Current {
+			continue
+		}
+		label := cp.Label
+		if r := []rune(label); len(r) > rewindLabelLen {
+			label = string(r[:rewindLabelLen-1]) + "…"
+		}
+		if cp.When != "" {
+			label += " · " + cp.When
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(label, "rw_pick:"+strconv.Itoa(cp.Pos))))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("Cancel", "rw_cancel")))
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// optionsKeyboard has a button per restore option and Cancel.
+func optionsKeyboard(opts []restoreOption) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, o := range opts {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(o.Text, "rw_opt:"+strconv.Itoa(o.Num))))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("Cancel", "rw_cancel")))
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// handleRewindCommand handles /rewind: opens Claude Code's checkpoint list
+// and offers its entries as buttons.
+func (b *Bot) handleRewindCommand(msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+	threadID := getThreadID(msg)
+	userID := msg.From.ID
+	windowID, bound := b.resolveWindow(msg)
+	if !bound {
+		b.reply(chatID, threadID, "Topic not bound to a session. Send a message to bind.")
+		return
+	}
+	if b.rejectIfObserved(msg) {
+		return
+	}
+
+	key := interactiveKey{userID, threadID}
+	rewindMu.Lock()
+	rewinds[key] = &rewindState{windowID: windowID, started: time.Now()}
+	rewindMu.Unlock()
+
+	if err := tmux.SendKeysWithDelay(b.config.TmuxSessionName, windowID, "/rewind", 500); err != nil {
+		b.endRewind(key)
+		if tmux.IsWindowDead(err) {
+			b.handleDeadWindow(msg, windowID, "")
+			return
+		}
+		log.Printf("Error opening rewind in %s: %v", windowID, err)
+		b.reply(chatID, threadID, "Error: failed to send /rewind.")
+		return
+	}
+
+	go func() {
+		var cps []checkpoint
+		b.waitForPane(windowID, func(pane string) bool {
+			var ok bool
+			cps, _, ok = checkpointList(pane)
+			return ok
+		})
+		restorable := 0
+		for _, cp := range cps {
+			if !cp.Current {
+				restorable++
+			}
+		}
+		if restorable == 0 {
+			b.endRewind(key)
+			if len(cps) > 0 {
+				tmux.SendSpecialKey(b.config.TmuxSessionName, windowID, "Escape")
+				b.reply(chatID, threadID, "No checkpoints to restore yet.")
+			} else {
+				b.reply(chatID, threadID, "Claude's checkpoint list didn't appear. Use /c_screenshot to see the terminal.")
+			}
+			return
+		}
+
+		sent, err := b.sendMessageWithKeyboard(chatID, threadID, formatCheckpoints(cps), checkpointsKeyboard(cps))
+		if err != nil {
+			log.Printf("Error sending rewind list: %v", err)
+			b.endRewind(key)
+			tmux.SendSpecialKey(b.config.TmuxSessionName, windowID, "Escape")
+			return
+		}
+		rewindMu.Lock()
+		if rw, ok := rewinds[key]; ok {
+			rw.messageID, rw.checkpoints = sent.MessageID, cps
+		}
+		rewindMu.Unlock()
+	}()
+}
+
+// waitForPane polls windowID's pane until match accepts it or rewindWait
+// passes, and returns whether it matched.
+func (b *Bot) waitForPane(windowID string, match func(pane string) bool) bool {
+	for deadline := time.Now().Add(rewindWait); time.Now().Before(deadline); time.Sleep(rewindPoll) {
+		pane, err := tmux.CapturePane(b.config.TmuxSessionName, windowID, false)
+		if err != nil {
+			return false
+		}
+		if match(pane) {
+			return true
+		}
+	}
+	return false
+}
+
+// endRewind forgets a topic's /rewind.
+func (b *Bot) endRewind(key interactiveKey) {
+	rewindMu.Lock()
+	delete(rewinds, key)
+	rewindMu.Unlock()
+}
+
+// processRewindCallback handles the /rewind buttons: picking a checkpoint
+// moves the list's cursor to it and opens the confirmation; picking an option
+// confirms; Cancel leaves the restore screen.
+func (b *Bot) processRewindCallback(cq *tgbotapi.CallbackQuery) {
+	chatID := cq.Message.Chat.ID
+	threadID := getThreadID(cq.Message)
+	key := interactiveKey{cq.From.ID, threadID}
+
+	rewindMu.Lock()
+	rw, ok := rewinds[key]
+	var st rewindState
+	if ok {
+		st = *rw
+	}
+	rewindMu.Unlock()
+	if ok && time.Since(st.started) > rewindTTL {
+		b.endRewind(key)
+		ok = false
+	}
+	if !ok || st.messageID != cq.Message.MessageID {
+		b.editMessageText(chatID, cq.Message.MessageID, "This rewind has ended. Send /rewind to start again.")
+		return
+	}
+	session := b.config.TmuxSessionName
+
+	switch data := cq.Data; {
+	case data == "rw_cancel":
+		b.endRewind(key)
+		if err := tmux.SendSpecialKey(session, st.windowID, "Escape"); err != nil {
+			log.Printf("Error leaving rewind in %s: %v", st.windowID, err)
+		}
+		b.editMessageText(chatID, st.messageID, "⏪ Rewind cancelled.")
+
+	case strings.HasPrefix(data, "rw_pick:"):
+		pos, err := strconv.Atoi(strings.TrimPrefix(data, "rw_pick:"))
+		if err != nil || pos < 0 || pos >= len(st.checkpoints) {
+			return
+		}
+		go b.pickCheckpoint(key, chatID, st, st.checkpoints[pos])
+
+	case strings.HasPrefix(data, "rw_opt:"):
+		num := strings.TrimPrefix(data, "rw_opt:")
+		if len(num) != 1 || num[0] < '1' || num[0] > '9' {
+			return
+		}
+		go b.confirmRestore(key, chatID, st, num)
+	}
+}
+
+// pickCheckpoint selects cp in the rewind list, after checking the list on
+// the pane is still the one offered, and shows the confirmation's options.
+func (b *Bot) pickCheckpoint(key interactiveKey, chatID int64, st rewindState, cp checkpoint) {
+	session := b.config.TmuxSessionName
+	pane, err := tmux.CapturePane(session, st.windowID, false)
+	if err != nil {
+		log.Printf("Error capturing %s for rewind: %v", st.windowID, err)
+		return
+	}
+	cps, cursor, ok := checkpointList(pane)
+	if !ok || cp.Pos >= len(cps) || cps[cp.Pos].Label != cp.Label || cursor < 0 {
+		b.endRewind(key)
+		b.editMessageText(chatID, st.messageID, "The checkpoint list in the terminal changed or closed. Send /rewind to start again.")
+		return
+	}
+
+	err = tmux.WithWindow(session, st.windowID, func(w tmux.Writer) error {
+		move, n := "Down", cp.Pos-cursor
+		if n < 0 {
+			move, n = "Up", -n
+		}
+		for range n {
+			if err := w.Key(move); err != nil {
+				return err
+			}
+			time.Sleep(rewindKeyDelay)
+		}
+		return w.Enter()
+	})
+	if err != nil {
+		b.endRewind(key)
+		log.Printf("Error selecting checkpoint in %s: %v", st.windowID, err)
+		b.editMessageText(chatID, st.messageID, "Error: failed to select the checkpoint.")
+		return
+	}
+
+	rewindMu.Lock()
+	if rw, ok := rewinds[key]; ok {
+		rw.picked = cp
+	}
+	rewindMu.Unlock()
+
+	var opts []restoreOption
+	b.waitForPane(st.windowID, func(pane string) bool {
+		opts = restoreOptions(pane)
+		return len(opts) > 0 || !monitor.IsInteractiveUI(pane)
+	})
+	if len(opts) == 0 {
+		b.finishRewind(key, chatID, st.messageID, cp, "")
+		return
+	}
+	text := fmt.Sprintf("⏪ Restore to the point before “%s”?", checkpointTitle(cp))
+	if cp.Detail != "" {
+		text += "\n" + cp.Detail
+	}
+	if err := b.editMessageWithKeyboard(chatID, st.messageID, text, optionsKeyboard(opts)); err != nil {
+		log.Printf("Error showing restore options: %v", err)
+	}
+}
+
+// confirmRestore picks option num on the restore confirmation and reports
+// the result.
+func (b *Bot) confirmRestore(key interactiveKey, chatID int64, st rewindState, num string) {
+	pane, err := tmux.CapturePane(b.config.TmuxSessionName, st.windowID, false)
+	if err != nil {
+		log.Printf("Error capturing %s for rewind: %v", st.windowID, err)
+		return
+	}
+	var choice string
+	for _, o := range restoreOptions(pane) {
+		if strconv.Itoa(o.Num) == num {
+			choice = o.Text
+		}
+	}
+	if choice == "" {
+		b.endRewind(key)
+		b.editMessageText(chatID, st.messageID, "The restore confirmation in the terminal changed or closed. Send /rewind to start again.")
+		return
+	}
+	if err := tmux.SendKeys(b.config.TmuxSessionName, st.windowID, num); err != nil {
+		b.endRewind(key)
+		log.Printf("Error confirming restore in %s: %v", st.windowID, err)
+		b.editMessageText(chatID, st.messageID, "Error: failed to confirm the restore.")
+		return
+	}
+	b.finishRewind(key, chatID, st.messageID, st.picked, choice)
+}
+
+// finishRewind waits for the restore screens to close and reports how the
+// rewind ended.
+func (b *Bot) finishRewind(key interactiveKey, chatID int64, messageID int, cp checkpoint, choice string) {
+	rewindMu.Lock()
+	windowID := ""
+	if rw, ok := rewinds[key]; ok {
+		windowID = rw.windowID
+	}
+	rewindMu.Unlock()
+	defer b.endRewind(key)
+
+	closed := b.waitForPane(windowID, func(pane string) bool { return !monitor.IsInteractiveUI(pane) })
+	var text string
+	switch {
+	case !closed:
+		text = "⏪ Claude is still showing a restore screen. Use /c_screenshot to check it, /esc to leave it."
+	case choice == "":
+		text = fmt.Sprintf("⏪ Restored to the point before “%s”.", checkpointTitle(cp))
+	case strings.Contains(strings.ToLower(choice), "never mind") || strings.Contains(strings.ToLower(choice), "cancel"):
+		text = "⏪ Rewind cancelled."
+	default:
+		text = fmt.Sprintf("⏪ %s: restored to the point before “%s”.", choice, checkpointTitle(cp))
+	}
+	b.editMessageText(chatID, messageID, text)
+}
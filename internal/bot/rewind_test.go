@@ -0,0 +1,157 @@
+package bot
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/otaviocarvalho/tramuntana/internal/testharness"
+)
+
+// rewindListPane is Claude Code's checkpoint list with the cursor on the
+// second entry.
+var rewindListPane = strings.Join([]string{
+	"⏺ Done.",
+	"",
+	" Rewind",
+	"",
+	" Restore the code and/or conversation to the point before…",
+	"",
+	"   add a /health endpoint",
+	"   ⎿ 2 files changed +40 -2 · 14m ago",
+	" ❯ fix the failing login test   3m ago",
+	"   (current)",
+	"",
+	" Enter to continue · Esc to exit",
+}, "\n")
+
+// rewindConfirmPane is the restore confirmation after picking a checkpoint.
+var rewindConfirmPane = strings.Join([]string{
+	" Rewind",
+	"",
+	" Confirm you want to restore to the point before you sent this message:",
+	"",
+	" │ add a /health endpoint",
+	"",
+	" ❯ 1. Restore code and conversation",
+	"   2. Restore conversation",
+	"   3. Restore code",
+	"   4. Never mind",
+	"",
+	" Enter to continue · Esc to exit",
+}, "\n")
+
+func TestParseCheckpoints(t *testing.T) {
+	cps, cursor, ok := checkpointList(rewindListPane)
+	if !ok {
+		t.Fatal("checkpoint list not found")
+	}
+	want := []checkpoint{
+		{Label: "add a /health endpoint", When: "14m ago", Detail: "2 files changed +40 -2", Pos: 0},
+		{Label: "fix the failing login test", When: "3m ago", Pos: 1},
+		{Label: "(current)", Current: true, Pos: 2},
+	}
+	if len(cps) != len(want) {
+		t.Fatalf("got %d checkpoints, want %d: %+v", len(cps), len(want), cps)
+	}
+	for i := range want {
+		if cps[i] != want[i] {
+			t.Errorf("checkpoint %d = %+v, want %+v", i, cps[i], want[i])
+		}
+	}
+	if cursor != 1 {
+		t.Errorf("cursor = %d, want 1", cursor)
+	}
+
+	if _, _, ok := checkpointList(rewindConfirmPane); ok {
+		t.Error("confirmation taken for the checkpoint list")
+	}
+}
+
+func TestRestoreOptions(t *testing.T) {
+	opts := restoreOptions(rewindConfirmPane)
+	if len(opts) != 4 || opts[0] != (restoreOption{1, "Restore code and conversation"}) || opts[3] != (restoreOption{4, "Never mind"}) {
+		t.Errorf("restoreOptions = %+v", opts)
+	}
+	if opts := restoreOptions("1. a numbered list\n2. in Claude's answer\n"); opts != nil {
+		t.Errorf("restoreOptions outside the confirmation = %+v", opts)
+	}
+}
+
+func TestCheckpointsKeyboard(t *testing.T) {
+	cps := []checkpoint{
+		{Label: strings.Repeat("x", rewindLabelLen+5), When: "1h ago", Pos: 0},
+		{Label: "(current)", Current: true, Pos: 1},
+	}
+	kb := checkpointsKeyboard(cps)
+	if len(kb.InlineKeyboard) != 2 {
+		t.Fatalf("got %d rows, want a checkpoint and Cancel", len(kb.InlineKeyboard))
+	}
+	btn := kb.InlineKeyboard[0][0]
+	if want := strings.Repeat("x", rewindLabelLen-1) + "… · 1h ago"; btn.Text != want || *btn.CallbackData != "rw_pick:0" {
+		t.Errorf("button = %q %q", btn.Text, *btn.CallbackData)
+	}
+	if *kb.InlineKeyboard[1][0].CallbackData != "rw_cancel" {
+		t.Error("last row should be Cancel")
+	}
+}
+
+func TestRewindInProgress_EndsOnOtherUIOrTTL(t *testing.T) {
+	key := interactiveKey{100, 42}
+	start := func(started time.Time) {
+		rewindMu.Lock()
+		rewinds[key] = &rewindState{windowID: "@1", started: started}
+		rewindMu.Unlock()
+	}
+	defer func() {
+		rewindMu.Lock()
+		delete(rewinds, key)
+		rewindMu.Unlock()
+	}()
+
+	start(time.Now())
+	if !rewindInProgress(100, 42, "@1", "RestoreCheckpoint") || !rewindInProgress(100, 42, "@1", "RestoreConfirm") {
+		t.Fatal("restore screens should be left to the rewind")
+	}
+	if rewindInProgress(100, 42, "@2", "RestoreCheckpoint") {
+		t.Error("rewind of another window")
+	}
+	if rewindInProgress(100, 42, "@1", "PermissionPrompt") {
+		t.Error("a permission prompt should end the rewind")
+	}
+	if rewindInProgress(100, 42, "@1", "RestoreCheckpoint") {
+		t.Error("the rewind should be forgotten once another UI showed")
+	}
+
+	start(time.Now().Add(-rewindTTL - time.Minute))
+	if rewindInProgress(100, 42, "@1", "RestoreCheckpoint") {
+		t.Error("an abandoned rewind should expire")
+	}
+}
+
+func TestE2E_RewindRestoresCheckpoint(t *testing.T) {
+	h := startE2E(t, "fresh")
+	windowID := h.tmux.AddWindow("api", "/work/api")
+	userID, threadID := strconv.FormatInt(e2eUser, 10), strconv.Itoa(e2eThread)
+	h.bot.state.BindThread(userID, threadID, windowID)
+	h.bot.state.SetGroupChatID(userID, threadID, e2eChat)
+
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "/rewind")
+	h.tmux.WaitForKeys(windowID, "/rewind")
+	h.tmux.SetPane(windowID, rewindListPane)
+	list := h.tg.WaitForText("sendMessage", "add a /health endpoint · 14m ago — 2 files changed")
+	if strings.Contains(list.Params["reply_markup"], "(current)") {
+		t.Errorf("current state offered as a checkpoint: %s", list.Params["reply_markup"])
+	}
+
+	h.tg.PushCallback(e2eChat, e2eThread, e2eUser, list.MessageID, "rw_pick:0")
+	h.tmux.WaitForKeys(windowID, "Up")
+	h.tmux.SetPane(windowID, rewindConfirmPane)
+	h.tg.WaitForText("editMessageText", "Restore to the point before “add a /health endpoint · 14m ago”?")
+
+	h.tg.PushCallback(e2eChat, e2eThread, e2eUser, list.MessageID, "rw_opt:1")
+	h.tmux.WaitForKeys(windowID, "1")
+	h.tmux.SetPane(windowID, testharness.ReadyPane)
+	h.tg.WaitForText("editMessageText", "Restore code and conversation: restored to the point before “add a /health endpoint · 14m ago”.")
+}
@@ -0,0 +1,63 @@
+package bot
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// allowedRoots returns the directories the file browsers and file sending may
+// reach: TRAMUNTANA_ALLOWED_ROOTS, or the home directory plus the working
+// directory of every bound session.
+func (b *Bot) allowedRoots() []string {
+	if len(b.config.AllowedRoots) > 0 {
+		return b.config.AllowedRoots
+	}
+	var roots []string
+	if home, err := os.UserHomeDir(); err == nil {
+		roots = append(roots, home)
+	}
+	for windowID := range b.state.AllBoundWindowIDs() {
+		if ws, ok := b.state.GetWindowState(windowID); ok && ws.CWD != "" {
+			roots = append(roots, ws.CWD)
+		}
+	}
+	return roots
+}
+
+// withinRoots reports whether path lies inside one of roots. Symlinks are
+// resolved first, so a link can't lead out of a root, and ".." can't either.
+func withinRoots(path string, roots []string) bool {
+	resolved := resolvePath(path)
+	for _, root := range roots {
+		rel, err := filepath.Rel(resolvePath(root), resolved)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolvePath cleans path and resolves its symlinks. A path that doesn't
+// exist is only cleaned.
+func resolvePath(path string) string {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.Clean(path)
+	}
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	return path
+}
+
+// startDir returns want when it is inside roots, or else the first root.
+func startDir(want string, roots []string) string {
+	if want != "" && withinRoots(want, roots) {
+		return want
+	}
+	if len(roots) > 0 {
+		return roots[0]
+	}
+	return want
+}
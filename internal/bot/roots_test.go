@@ -0,0 +1,100 @@
+package bot
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+)
+
+func TestWithinRoots(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	os.Mkdir(filepath.Join(root, "src"), 0o755)
+	os.Symlink(outside, filepath.Join(root, "escape"))
+	os.Symlink(filepath.Join(root, "src"), filepath.Join(root, "inner"))
+	roots := []string{root}
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{root, true},
+		{filepath.Join(root, "src"), true},
+		{filepath.Join(root, "src", "missing.go"), true},
+		{filepath.Join(root, "inner"), true},
+		{filepath.Join(root, "src", "..", ".."), false},
+		{root + "/../" + filepath.Base(outside), false},
+		{filepath.Join(root, "escape"), false},
+		{root + "-sibling", false},
+		{"/", false},
+	}
+	for _, tt := range tests {
+		if got := withinRoots(tt.path, roots); got != tt.want {
+			t.Errorf("withinRoots(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+	if withinRoots(root, nil) {
+		t.Error("no roots should allow nothing")
+	}
+}
+
+func TestBuildFileBrowser_StaysInsideRoots(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("x"), 0o644)
+	os.WriteFile(filepath.Join(root, "notes.txt"), []byte("x"), 0o644)
+	os.Symlink(outside, filepath.Join(root, "escape"))
+	os.Symlink(filepath.Join(outside, "secret.txt"), filepath.Join(root, "secret.txt"))
+
+	_, _, entries := buildFileBrowser(root, 0, filesPerPage, []string{root})
+	if len(entries) != 1 || entries[0].Name != "notes.txt" {
+		t.Errorf("entries = %v, want only notes.txt", entries)
+	}
+
+	text, _, entries := buildFileBrowser(outside, 0, filesPerPage, []string{root})
+	if entries != nil {
+		t.Errorf("listed %v outside the roots", entries)
+	}
+	if text == "" {
+		t.Error("expected an error text")
+	}
+}
+
+func TestBuildDirectoryBrowser_StaysInsideRoots(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	os.Mkdir(filepath.Join(root, "project"), 0o755)
+	os.Symlink(outside, filepath.Join(root, "escape"))
+
+	_, _, dirs := buildDirectoryBrowser(root, 0, dirsPerPage, []string{root})
+	if len(dirs) != 1 || dirs[0] != "project" {
+		t.Errorf("dirs = %v, want only project", dirs)
+	}
+	if _, _, dirs := buildDirectoryBrowser(filepath.Dir(root), 0, dirsPerPage, []string{root}); dirs != nil {
+		t.Errorf("listed the parent of the root: %v", dirs)
+	}
+}
+
+func TestAllowedRoots(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	b := newTestBot(t)
+	b.state.BindThread("100", "42", "@1")
+	b.state.SetWindowState("@1", state.WindowState{CWD: "/work/api"})
+	b.state.SetWindowState("@2", state.WindowState{CWD: "/work/unbound"})
+
+	roots := b.allowedRoots()
+	if len(roots) != 2 || roots[0] != home || roots[1] != "/work/api" {
+		t.Errorf("default roots = %v, want home and the bound session's CWD", roots)
+	}
+
+	b.config.AllowedRoots = []string{"/srv/shared"}
+	if roots := b.allowedRoots(); len(roots) != 1 || roots[0] != "/srv/shared" {
+		t.Errorf("configured roots = %v", roots)
+	}
+	if got := startDir(home, b.allowedRoots()); got != "/srv/shared" {
+		t.Errorf("startDir = %q, want the first root", got)
+	}
+}
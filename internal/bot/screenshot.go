@@ -67,8 +67,15 @@ func buildScreenshotKeyboard(windowID string) tgbotapi.InlineKeyboardMarkup {
 	)
 }
 
-// handleScreenshotCommand captures the tmux pane and sends a PNG screenshot.
+// handleScreenshotCommand captures the tmux pane and sends a PNG screenshot,
+// or the pane's text in /lite mode. "/c_screenshot settings" opens the
+// appearance settings keyboard instead.
 func (b *Bot) handleScreenshotCommand(msg *tgbotapi.Message) {
+	if strings.TrimSpace(msg.CommandArguments()) == "settings" {
+		b.handleScreenshotSettings(msg)
+		return
+	}
+
 	windowID, bound := b.resolveWindow(msg)
 	if !bound {
 		b.reply(msg.Chat.ID, getThreadID(msg), "No session bound to this topic.")
@@ -84,7 +91,8 @@ func (b *Bot) handleScreenshotCommand(msg *tgbotapi.Message) {
 		return
 	}
 
-	paneText, err := tmux.CapturePane(b.config.TmuxSessionName, windowID, true)
+	lite := b.isLite(msg.From.ID)
+	paneText, err := tmux.CapturePane(b.config.TmuxSessionName, windowID, !lite)
 	if err != nil {
 		if tmux.IsWindowDead(err) {
 			b.handleDeadWindow(msg, windowID, "")
@@ -95,15 +103,19 @@ func (b *Bot) handleScreenshotCommand(msg *tgbotapi.Message) {
 		return
 	}
 
-	pngData, err := render.RenderScreenshot(paneText)
-	if err != nil {
-		log.Printf("Error rendering screenshot: %v", err)
-		b.reply(chatID, threadID, "Error: failed to render screenshot.")
-		return
-	}
-
 	keyboard := buildScreenshotKeyboard(windowID)
-	sentMsg, err := b.sendDocumentInThread(chatID, threadID, pngData, "screenshot.png", keyboard)
+	var sentMsg tgbotapi.Message
+	if lite {
+		sentMsg, err = b.sendMessageWithKeyboardMD(chatID, threadID, litePane(paneText), keyboard)
+	} else {
+		pngData, renderErr := render.RenderScreenshotWithOptions(paneText, b.screenshotOptions(msg.From.ID))
+		if renderErr != nil {
+			log.Printf("Error rendering screenshot: %v", renderErr)
+			b.reply(chatID, threadID, "Error: failed to render screenshot.")
+			return
+		}
+		sentMsg, err = b.sendDocumentInThread(chatID, threadID, pngData, "screenshot.png", keyboard)
+	}
 	if err != nil {
 		log.Printf("Error sending screenshot: %v", err)
 		// Register flood ban so queue and future screenshots respect it
@@ -162,9 +174,11 @@ func (b *Bot) handleScreenshotCB(cq *tgbotapi.CallbackQuery) {
 	b.refreshScreenshot(cq, windowID)
 }
 
-// refreshScreenshot captures, renders, and edits the screenshot message.
+// refreshScreenshot captures, renders, and edits the screenshot message. A
+// text screenshot from /lite mode is refreshed as text.
 func (b *Bot) refreshScreenshot(cq *tgbotapi.CallbackQuery, windowID string) {
-	paneText, err := tmux.CapturePane(b.config.TmuxSessionName, windowID, true)
+	asText := cq.Message.Document == nil && cq.Message.Text != ""
+	paneText, err := tmux.CapturePane(b.config.TmuxSessionName, windowID, !asText)
 	if err != nil {
 		if tmux.IsWindowDead(err) {
 			log.Printf("Screenshot refresh: window %s is dead", windowID)
@@ -174,16 +188,23 @@ func (b *Bot) refreshScreenshot(cq *tgbotapi.CallbackQuery, windowID string) {
 		return
 	}
 
-	pngData, err := render.RenderScreenshot(paneText)
+	chatID := cq.Message.Chat.ID
+	messageID := cq.Message.MessageID
+	keyboard := buildScreenshotKeyboard(windowID)
+
+	if asText {
+		if err := b.editMessageWithKeyboardMD(chatID, messageID, litePane(paneText), keyboard); err != nil {
+			log.Printf("Error editing text screenshot: %v", err)
+		}
+		return
+	}
+
+	pngData, err := render.RenderScreenshotWithOptions(paneText, b.screenshotOptions(cq.From.ID))
 	if err != nil {
 		log.Printf("Error rendering screenshot for refresh: %v", err)
 		return
 	}
 
-	chatID := cq.Message.Chat.ID
-	messageID := cq.Message.MessageID
-	keyboard := buildScreenshotKeyboard(windowID)
-
 	if err := b.editMessageMedia(chatID, messageID, pngData, "screenshot.png", keyboard); err != nil {
 		log.Printf("Error editing screenshot message: %v", err)
 		if b.msgQueue != nil {
@@ -0,0 +1,125 @@
+package bot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/render"
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+)
+
+const (
+	minScreenshotFontSize  = 12.0
+	maxScreenshotFontSize  = 40.0
+	screenshotFontSizeStep = 4.0
+)
+
+// screenshotOptions merges the configured screenshot defaults with the user's overrides.
+func (b *Bot) screenshotOptions(userID int64) render.ScreenshotOptions {
+	opts := render.ScreenshotOptions{
+		Theme:      b.config.ScreenshotTheme,
+		FontSize:   b.config.ScreenshotFontSize,
+		LineHeight: b.config.ScreenshotLineHeight,
+		MaxCols:    b.config.ScreenshotMaxCols,
+	}
+
+	us := b.state.GetUserSettings(strconv.FormatInt(userID, 10)).Screenshot
+	if us.Theme != "" {
+		opts.Theme = us.Theme
+	}
+	if us.FontSize > 0 {
+		opts.FontSize = us.FontSize
+		// Line height follows the user's font size rather than the configured one.
+		opts.LineHeight = 0
+	}
+	switch {
+	case us.MaxCols > 0:
+		opts.MaxCols = us.MaxCols
+	case us.MaxCols < 0:
+		opts.MaxCols = 0
+	}
+	return opts
+}
+
+// handleScreenshotSettings shows the screenshot appearance keyboard.
+func (b *Bot) handleScreenshotSettings(msg *tgbotapi.Message) {
+	text, kb := b.buildScreenshotSettings(msg.From.ID)
+	b.sendMessageWithKeyboard(msg.Chat.ID, getThreadID(msg), text, kb)
+}
+
+// buildScreenshotSettings renders the current settings summary and keyboard.
+func (b *Bot) buildScreenshotSettings(userID int64) (string, tgbotapi.InlineKeyboardMarkup) {
+	opts := b.screenshotOptions(userID)
+
+	width := "unlimited"
+	if opts.MaxCols > 0 {
+		width = fmt.Sprintf("%d columns", opts.MaxCols)
+	}
+	text := fmt.Sprintf("Screenshot settings\n\nTheme: %s\nFont size: %.0f\nMax width: %s",
+		opts.Theme, opts.FontSize, width)
+
+	kb := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Dark", "sset_theme:dark"),
+			tgbotapi.NewInlineKeyboardButtonData("Light", "sset_theme:light"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("A−", "sset_font:-"),
+			tgbotapi.NewInlineKeyboardButtonData("A+", "sset_font:+"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("80 cols", "sset_width:80"),
+			tgbotapi.NewInlineKeyboardButtonData("120 cols", "sset_width:120"),
+			tgbotapi.NewInlineKeyboardButtonData("No wrap", "sset_width:-1"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Reset", "sset_reset"),
+		),
+	)
+	return text, kb
+}
+
+// processScreenshotSettingsCallback handles sset_* callbacks.
+func (b *Bot) processScreenshotSettingsCallback(cq *tgbotapi.CallbackQuery) {
+	if cq.Message == nil {
+		return
+	}
+
+	userID := strconv.FormatInt(cq.From.ID, 10)
+	us := b.state.GetUserSettings(userID)
+
+	action, arg, _ := strings.Cut(strings.TrimPrefix(cq.Data, "sset_"), ":")
+	switch action {
+	case "theme":
+		if _, ok := render.ScreenshotThemes[arg]; !ok {
+			return
+		}
+		us.Screenshot.Theme = arg
+	case "font":
+		size := b.screenshotOptions(cq.From.ID).FontSize
+		if arg == "+" {
+			size += screenshotFontSizeStep
+		} else {
+			size -= screenshotFontSizeStep
+		}
+		us.Screenshot.FontSize = min(max(size, minScreenshotFontSize), maxScreenshotFontSize)
+	case "width":
+		cols, err := strconv.Atoi(arg)
+		if err != nil {
+			return
+		}
+		us.Screenshot.MaxCols = cols
+	case "reset":
+		us.Screenshot = state.ScreenshotSettings{}
+	default:
+		return
+	}
+
+	b.state.SetUserSettings(userID, us)
+	b.saveState()
+
+	text, kb := b.buildScreenshotSettings(cq.From.ID)
+	b.editMessageWithKeyboard(cq.Message.Chat.ID, cq.Message.MessageID, text, kb)
+}
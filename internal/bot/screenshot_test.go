@@ -2,6 +2,8 @@ package bot
 
 import (
 	"testing"
+
+	"github.com/otaviocarvalho/tramuntana/internal/state"
 )
 
 func TestBuildScreenshotKeyboard(t *testing.T) {
@@ -128,3 +130,30 @@ func TestScreenshotKey(t *testing.T) {
 		t.Errorf("got %q, want 12345:678", key)
 	}
 }
+
+func TestScreenshotOptions_UserOverrides(t *testing.T) {
+	b := newTestBot(t)
+	b.config.ScreenshotTheme = "dark"
+	b.config.ScreenshotFontSize = 28
+	b.config.ScreenshotLineHeight = 39
+	b.config.ScreenshotMaxCols = 100
+
+	opts := b.screenshotOptions(100)
+	if opts.Theme != "dark" || opts.FontSize != 28 || opts.LineHeight != 39 || opts.MaxCols != 100 {
+		t.Errorf("defaults not applied: %+v", opts)
+	}
+
+	b.state.SetUserSettings("100", state.UserSettings{
+		Screenshot: state.ScreenshotSettings{Theme: "light", FontSize: 20, MaxCols: -1},
+	})
+	opts = b.screenshotOptions(100)
+	if opts.Theme != "light" || opts.FontSize != 20 {
+		t.Errorf("overrides not applied: %+v", opts)
+	}
+	if opts.LineHeight != 0 {
+		t.Errorf("line height should be derived from user font size, got %d", opts.LineHeight)
+	}
+	if opts.MaxCols != 0 {
+		t.Errorf("MaxCols -1 should disable wrapping, got %d", opts.MaxCols)
+	}
+}
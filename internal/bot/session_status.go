@@ -0,0 +1,84 @@
+package bot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+)
+
+// handleStatusCommand shows the session bound to this topic, including its Minuano agent ID.
+func (b *Bot) handleStatusCommand(msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+	threadID := getThreadID(msg)
+	threadIDStr := strconv.Itoa(threadID)
+
+	windowID, bound := b.resolveWindow(msg)
+	if !bound {
+		b.reply(chatID, threadID, "Topic not bound to a session.")
+		return
+	}
+
+	ws, _ := b.state.GetWindowState(windowID)
+	name, _ := b.state.GetWindowDisplayName(windowID)
+	project, _ := b.state.GetProject(threadIDStr)
+	wt, hasWT := b.state.GetWorktreeInfo(threadIDStr)
+
+	lines := append([]string{formatSessionStatus(windowID, name, project, ws, wt, hasWT)}, b.forkStatusLines(chatID, threadIDStr)...)
+	if n := b.duplicatesSkipped(windowID); n > 0 {
+		lines = append(lines, fmt.Sprintf("Duplicates skipped: %d transcript entries read again were not resent", n))
+	}
+	b.reply(chatID, threadID, strings.Join(lines, "\n"))
+}
+
+// duplicatesSkipped counts the transcript lines the monitor recently skipped
+// for a window as already delivered.
+func (b *Bot) duplicatesSkipped(windowID string) int64 {
+	if b.monitorState == nil {
+		return 0
+	}
+	var n int64
+	for _, key := range b.monitorState.DeliveredKeys() {
+		if windowIDFromKey(key) == windowID {
+			n += b.monitorState.Duplicates(key)
+		}
+	}
+	return n
+}
+
+// formatSessionStatus renders the /status reply.
+func formatSessionStatus(windowID, name, project string, ws state.WindowState, wt state.WorktreeInfo, hasWT bool) string {
+	var lines []string
+	if name != "" {
+		lines = append(lines, fmt.Sprintf("Window: %s (%s)", windowID, name))
+	} else {
+		lines = append(lines, "Window: "+windowID)
+	}
+	if ws.CWD != "" {
+		lines = append(lines, "Directory: "+ws.CWD)
+	}
+	if ws.CurrentDir != "" {
+		lines = append(lines, "Now in: "+ws.CurrentDir)
+	}
+	if ws.SessionID != "" {
+		lines = append(lines, "Session: "+ws.SessionID)
+	}
+	if ws.AgentID != "" {
+		lines = append(lines, "Agent: "+ws.AgentID)
+	} else {
+		lines = append(lines, "Agent: none (Minuano not configured for this window)")
+	}
+	if project != "" {
+		lines = append(lines, "Project: "+project)
+	}
+	if hasWT {
+		wtLine := fmt.Sprintf("Worktree: %s (%s)", wt.Branch, wt.WorktreeDir)
+		if wt.TaskID != "" {
+			wtLine += " — task " + wt.TaskID
+		}
+		lines = append(lines, wtLine)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,104 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/tmux"
+)
+
+// setupCandidates splits a session's windows into the unbound ones showing
+// Claude Code's TUI, which /setup adopts, and the unbound ones that don't.
+// Bound windows and the placeholder window are left out.
+func setupCandidates(windows []tmux.Window, bound map[string]bool, pane func(windowID string) (string, error)) (claude, other []tmux.Window) {
+	for _, w := range windows {
+		if bound[w.ID] || w.Name == tmux.InitWindowName {
+			continue
+		}
+		if text, err := pane(w.ID); err == nil && tmux.HasChromeSeparator(text) {
+			claude = append(claude, w)
+		} else {
+			other = append(other, w)
+		}
+	}
+	return claude, other
+}
+
+// handleSetupCommand handles /setup: adopts an already-running tmux session
+// into the group by creating a topic for every unbound window that shows
+// Claude Code, binding it, and posting a summary. Owners only.
+func (b *Bot) handleSetupCommand(msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+	threadID := getThreadID(msg)
+	if !b.isOwner(msg.From.ID) {
+		b.reply(chatID, threadID, "Only the bot owner can run /setup.")
+		return
+	}
+	if chatID > 0 {
+		b.reply(chatID, threadID, "Run /setup in the forum group the topics should be created in.")
+		return
+	}
+
+	session := b.config.TmuxSessionName
+	windows, err := tmux.ListWindows(session)
+	if err != nil {
+		log.Printf("Error listing windows: %v", err)
+		b.reply(chatID, threadID, "Error listing tmux windows.")
+		return
+	}
+	claude, other := setupCandidates(windows, b.state.AllBoundWindowIDs(), func(windowID string) (string, error) {
+		return tmux.CapturePane(session, windowID, false)
+	})
+	if len(claude) == 0 {
+		text := "No unbound Claude Code windows to set up."
+		if len(other) > 0 {
+			text += fmt.Sprintf(" %d other unbound window(s) don't show Claude Code.", len(other))
+		}
+		b.reply(chatID, threadID, text)
+		return
+	}
+
+	userIDStr := strconv.FormatInt(msg.From.ID, 10)
+	var adopted, failed []string
+	for _, w := range claude {
+		name := truncateName(w.Name, maxTopicNameLen-2) // room for the state prefix
+		newThreadID, err := b.createForumTopic(chatID, name)
+		if err != nil {
+			log.Printf("Error creating topic for %s: %v", w.ID, err)
+			failed = append(failed, fmt.Sprintf("• %s: %v", w.Name, err))
+			continue
+		}
+		newThreadIDStr := strconv.Itoa(newThreadID)
+		b.state.BindThread(userIDStr, newThreadIDStr, w.ID)
+		b.state.SetGroupChatID(userIDStr, newThreadIDStr, chatID)
+		b.state.SetWindowDisplayName(w.ID, w.Name)
+		b.reply(chatID, newThreadID, fmt.Sprintf("Bound to: %s (%s)", w.Name, w.CWD))
+
+		line := fmt.Sprintf("• %s (%s)", w.Name, shortenPath(w.CWD))
+		if link := b.topicLink(chatID, newThreadID); link != "" {
+			line += ": " + link
+		}
+		adopted = append(adopted, line)
+	}
+	b.saveState()
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "🧭 Set up %d topic(s):", len(adopted))
+	for _, line := range adopted {
+		sb.WriteString("\n" + line)
+	}
+	if len(failed) > 0 {
+		fmt.Fprintf(&sb, "\n\nFailed:\n%s", strings.Join(failed, "\n"))
+	}
+	if len(other) > 0 {
+		var names []string
+		for _, w := range other {
+			names = append(names, w.Name)
+		}
+		fmt.Fprintf(&sb, "\n\nSkipped (no Claude Code on screen): %s", strings.Join(names, ", "))
+	}
+	b.reply(chatID, threadID, sb.String())
+}
@@ -0,0 +1,41 @@
+package bot
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/otaviocarvalho/tramuntana/internal/testharness"
+)
+
+func TestE2E_SetupAdoptsClaudeWindows(t *testing.T) {
+	h := startE2E(t, "fresh")
+	api := h.tmux.AddWindow("api", "/work/api")
+	shell := h.tmux.AddWindow("shell", "/work")
+	h.tmux.SetPane(shell, "$ ls\nREADME.md\n$ ")
+	bound := h.tmux.AddWindow("web", "/work/web")
+	h.bot.state.BindThread(strconv.FormatInt(e2eUser, 10), "7", bound)
+
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "/setup")
+	summary := h.tg.WaitForText("sendMessage", "Set up 1 topic(s):")
+	for _, want := range []string{"• api (/work/api)", "Skipped (no Claude Code on screen): shell"} {
+		if !strings.Contains(summary.Params["text"], want) {
+			t.Errorf("summary missing %q:\n%s", want, summary.Params["text"])
+		}
+	}
+
+	topics := h.tg.Calls("createForumTopic")
+	if len(topics) != 1 || topics[0].Params["name"] != "api" {
+		t.Fatalf("createForumTopic calls = %+v, want one for api", topics)
+	}
+	threadID := strconv.Itoa(topics[0].MessageID)
+	if w, ok := h.bot.state.GetWindowForThread(strconv.FormatInt(e2eUser, 10), threadID); !ok || w != api {
+		t.Errorf("new topic bound to %q, want %s", w, api)
+	}
+	h.tg.WaitFor("sendMessage", func(c testharness.Call) bool {
+		return c.Params["message_thread_id"] == threadID && strings.Contains(c.Params["text"], "Bound to: api")
+	})
+
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "/setup")
+	h.tg.WaitForText("sendMessage", "No unbound Claude Code windows to set up. 1 other unbound window(s)")
+}
@@ -0,0 +1,178 @@
+package bot
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// maxSkillButtons caps the Run buttons under a /skills listing.
+const maxSkillButtons = 20
+
+// skill is a Claude Code skill: a directory holding a SKILL.md whose YAML
+// front matter names and describes it.
+type skill struct {
+	Name        string
+	Description string
+	Personal    bool // from ~/.claude/skills rather than the project
+}
+
+// findSkills lists the skills in dir's .claude/skills, then the personal ones
+// in ~/.claude/skills that the project doesn't override, each sorted by name.
+func findSkills(dir string) []skill {
+	skills := readSkillDir(filepath.Join(dir, ".claude", "skills"), false)
+	home, _ := os.UserHomeDir()
+	if home == "" {
+		return skills
+	}
+	seen := make(map[string]bool, len(skills))
+	for _, s := range skills {
+		seen[s.Name] = true
+	}
+	for _, s := range readSkillDir(filepath.Join(home, ".claude", "skills"), true) {
+		if !seen[s.Name] {
+			skills = append(skills, s)
+		}
+	}
+	return skills
+}
+
+// readSkillDir reads the skills directly under root. A missing root has none.
+func readSkillDir(root string, personal bool) []skill {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil
+	}
+	var skills []skill
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(root, e.Name(), "SKILL.md"))
+		if err != nil {
+			continue
+		}
+		s := parseSkillFrontMatter(string(data))
+		if s.Name == "" {
+			s.Name = e.Name()
+		}
+		s.Personal = personal
+		skills = append(skills, s)
+	}
+	sort.Slice(skills, func(i, j int) bool { return skills[i].Name < skills[j].Name })
+	return skills
+}
+
+// parseSkillFrontMatter reads name and description from the "---" delimited
+// front matter at the top of a SKILL.md.
+func parseSkillFrontMatter(text string) skill {
+	var s skill
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != "---" {
+		return s
+	}
+	for _, line := range lines[1:] {
+		if strings.TrimSpace(line) == "---" {
+			break
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.Trim(strings.TrimSpace(value), `"'`)
+		switch strings.TrimSpace(key) {
+		case "name":
+			s.Name = value
+		case "description":
+			s.Description = value
+		}
+	}
+	return s
+}
+
+// formatSkills renders the /skills listing.
+func formatSkills(dir string, skills []skill) string {
+	if len(skills) == 0 {
+		return fmt.Sprintf("No skills in %s or ~/.claude/skills.", filepath.Join(dir, ".claude", "skills"))
+	}
+	var b strings.Builder
+	b.WriteString("Skills:\n")
+	for _, s := range skills {
+		b.WriteString("\n/" + s.Name)
+		if s.Personal {
+			b.WriteString(" (personal)")
+		}
+		if s.Description != "" {
+			desc := s.Description
+			if r := []rune(desc); len(r) > 120 {
+				desc = string(r[:120]) + "…"
+			}
+			b.WriteString(" — " + desc)
+		}
+	}
+	b.WriteString("\n\nRun one with its button or /cc <name> [args].")
+	return b.String()
+}
+
+// skillsKeyboard has a Run button per skill whose callback data fits.
+func skillsKeyboard(skills []skill) (tgbotapi.InlineKeyboardMarkup, bool) {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	var row []tgbotapi.InlineKeyboardButton
+	n := 0
+	for _, s := range skills {
+		data := "skill_run:" + s.Name
+		if len(data) > 64 || n == maxSkillButtons {
+			continue
+		}
+		n++
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData("▶ "+s.Name, data))
+		if len(row) == 2 {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(rows...), len(rows) > 0
+}
+
+// handleSkillsCommand lists the skills installed for the bound session's
+// project, with buttons that invoke them.
+func (b *Bot) handleSkillsCommand(msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+	threadID := getThreadID(msg)
+
+	windowID, bound := b.resolveWindow(msg)
+	if !bound {
+		b.reply(chatID, threadID, "Topic not bound to a session. Send a message to bind.")
+		return
+	}
+	ws, ok := b.state.GetWindowState(windowID)
+	if !ok || ws.CWD == "" {
+		b.reply(chatID, threadID, "Project directory unknown for this session.")
+		return
+	}
+
+	skills := findSkills(ws.CWD)
+	text := formatSkills(ws.CWD, skills)
+	kb, ok := skillsKeyboard(skills)
+	if !ok {
+		b.reply(chatID, threadID, text)
+		return
+	}
+	b.sendMessageWithKeyboard(chatID, threadID, text, kb)
+}
+
+// processSkillCallback handles a skill's Run button by typing /<name>.
+func (b *Bot) processSkillCallback(cq *tgbotapi.CallbackQuery) {
+	name, ok := strings.CutPrefix(cq.Data, "skill_run:")
+	if !ok || name == "" {
+		return
+	}
+	b.forwardCommand(syntheticMessage(cq), name)
+}
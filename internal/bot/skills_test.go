@@ -0,0 +1,67 @@
+package bot
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeSkill(t *testing.T, root, dir, content string) {
+	t.Helper()
+	path := filepath.Join(root, ".claude", "skills", dir, "SKILL.md")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseSkillFrontMatter(t *testing.T) {
+	s := parseSkillFrontMatter("---\r\nname: pdf\r\ndescription: \"Extract text: tables too\"\r\n---\r\n# PDF\r\nname: not this\r\n")
+	if s.Name != "pdf" || s.Description != "Extract text: tables too" {
+		t.Errorf("got %+v", s)
+	}
+	if s := parseSkillFrontMatter("# No front matter\nname: x\n"); s.Name != "" {
+		t.Errorf("without front matter got %+v", s)
+	}
+}
+
+func TestFindSkills(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	project := t.TempDir()
+	writeSkill(t, project, "release", "---\nname: release\ndescription: Cut a release\n---\n")
+	writeSkill(t, project, "changelog", "# Changelog\n")
+	writeSkill(t, home, "release", "---\nname: release\ndescription: Personal release\n---\n")
+	writeSkill(t, home, "notes", "---\nname: notes\n---\n")
+
+	got := findSkills(project)
+	var names []string
+	for _, s := range got {
+		names = append(names, s.Name)
+	}
+	if strings.Join(names, ",") != "changelog,release,notes" {
+		t.Fatalf("skills = %v", names)
+	}
+	if got[1].Description != "Cut a release" || got[1].Personal || !got[2].Personal {
+		t.Errorf("project skill should win over personal: %+v", got)
+	}
+
+	text := formatSkills(project, got)
+	if !strings.Contains(text, "/release — Cut a release") || !strings.Contains(text, "/notes (personal)") {
+		t.Errorf("listing:\n%s", text)
+	}
+	kb, ok := skillsKeyboard(got)
+	if !ok || kb.InlineKeyboard[0][0].CallbackData == nil || *kb.InlineKeyboard[0][0].CallbackData != "skill_run:changelog" {
+		t.Errorf("keyboard = %+v", kb)
+	}
+}
+
+func TestFindSkills_None(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if got := findSkills(t.TempDir()); len(got) != 0 {
+		t.Errorf("got %+v", got)
+	}
+}
@@ -0,0 +1,48 @@
+package bot
+
+import (
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/queue"
+)
+
+// handleSoundCommand sets whether this topic's routine output (Claude's text,
+// tool calls and results) makes a sound. Claude's notifications always do.
+// Usage: /sound [on|off]; no argument shows the setting.
+func (b *Bot) handleSoundCommand(msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+	threadID := getThreadID(msg)
+	threadIDStr := strconv.Itoa(threadID)
+
+	switch strings.ToLower(strings.TrimSpace(msg.CommandArguments())) {
+	case "":
+		if b.state.IsLoud(threadIDStr) {
+			b.reply(chatID, threadID, "Sound is on: every message from this session notifies. Use /sound off to keep only Claude's notifications.")
+		} else {
+			b.reply(chatID, threadID, "Sound is off: output arrives silently, and only Claude's notifications (🔔, e.g. waiting for permission) make a sound. Use /sound on to hear everything.")
+		}
+		return
+	case "on":
+		b.state.SetLoud(threadIDStr, true)
+		b.saveState()
+		b.reply(chatID, threadID, "Sound on: every message from this session notifies.")
+	case "off":
+		b.state.SetLoud(threadIDStr, false)
+		b.saveState()
+		b.reply(chatID, threadID, "Sound off: only Claude's notifications make a sound.")
+	default:
+		b.reply(chatID, threadID, "Usage: /sound [on|off]")
+	}
+}
+
+// silentDelivery reports whether a queued message is sent without sound:
+// notifications sound unless the user silenced everything for quiet hours,
+// other output only in a loud topic outside quiet hours.
+func (b *Bot) silentDelivery(task queue.MessageTask) bool {
+	if task.ContentType == "notification" {
+		return b.quietFor(task.UserID, true)
+	}
+	return b.quietFor(task.UserID, false) || !b.state.IsLoud(strconv.Itoa(task.ThreadID))
+}
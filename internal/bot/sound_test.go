@@ -0,0 +1,45 @@
+package bot
+
+import (
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+)
+
+func TestE2E_NotificationsSoundRoutineOutputSilent(t *testing.T) {
+	h := startE2E(t, "fresh")
+	windowID := h.tmux.AddWindow("api", "/work/api")
+	userID, threadID := strconv.FormatInt(e2eUser, 10), strconv.Itoa(e2eThread)
+	h.bot.state.BindThread(userID, threadID, windowID)
+	h.bot.state.SetGroupChatID(userID, threadID, e2eChat)
+	h.writeSession(t, windowID, "sess-1", "/work/api")
+
+	h.appendTranscript(t, "sess-1",
+		`{"type":"assistant","message":{"content":[{"type":"text","text":"Routine progress"}]}}`)
+	if c := h.tg.WaitForText("sendMessage", "Routine progress"); c.Params["disable_notification"] != "true" {
+		t.Errorf("routine output not silent: %v", c.Params)
+	}
+
+	err := state.AppendNotification(filepath.Join(h.cfg.TramuntanaDir, state.NotificationsFile), state.HookNotification{
+		Time:    time.Now(),
+		Key:     e2eSession + ":" + windowID,
+		Message: "Claude needs your permission to use Bash",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := h.tg.WaitForText("sendMessage", "🔔 Claude needs your permission to use Bash"); c.Params["disable_notification"] != "" {
+		t.Errorf("notification sent silently: %v", c.Params)
+	}
+
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "/sound on")
+	h.tg.WaitForText("sendMessage", "Sound on")
+	h.appendTranscript(t, "sess-1",
+		`{"type":"assistant","message":{"content":[{"type":"text","text":"Loud progress"}]}}`)
+	if c := h.tg.WaitForText("sendMessage", "Loud progress"); c.Params["disable_notification"] != "" {
+		t.Errorf("output silent after /sound on: %v", c.Params)
+	}
+}
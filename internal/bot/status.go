@@ -4,12 +4,16 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/otaviocarvalho/tramuntana/internal/events"
 	"github.com/otaviocarvalho/tramuntana/internal/monitor"
 	"github.com/otaviocarvalho/tramuntana/internal/queue"
+	"github.com/otaviocarvalho/tramuntana/internal/supervise"
 	"github.com/otaviocarvalho/tramuntana/internal/tmux"
 )
 
@@ -26,27 +30,71 @@ var animFrames = []string{"☕", "⏳", "✨", "🔮"}
 type StatusPoller struct {
 	bot          *Bot
 	queue        *queue.Queue
-	monitor      *monitor.Monitor
+	events       *events.Bus
+	turnStarts   <-chan events.TurnStarted
+	started      map[string]time.Time // windowID → start of the running turn
 	mu           sync.RWMutex
-	lastStatus   map[statusKey]string // last status text per user+thread
-	missCount    map[string]int       // windowID → consecutive miss count
-	animFrame    map[statusKey]int    // animation frame per user+thread
+	lastStatus   map[statusKey]string     // last status text per user+thread
+	missCount    map[string]int           // windowID → consecutive miss count
+	animFrame    map[statusKey]int        // animation frame per user+thread
+	panes        map[string]paneCache     // windowID → last capture; only touched by poll
+	titles       map[string]*windowTitle  // windowID → name sync; only touched by poll
+	waiting      map[string]attentionItem // windowID → session waiting for input; only touched by poll
+	attention    string                   // waiting window IDs last reported to the bot
+	cwds         map[string]*cwdWatch     // windowID → directory the pane moved to; only touched by poll
 	pollInterval time.Duration
+	heartbeat    *supervise.Heartbeat // optional; beaten after every poll
+}
+
+// paneCache is a window's last pane capture and the tmux activity time it saw.
+type paneCache struct {
+	activity   int64
+	capturedAt time.Time
+	text       string
+}
+
+// fresh reports whether the cached capture still matches the pane. tmux
+// activity has one-second resolution, so output in the same second as the
+// capture may not have been included; only older activity is trusted.
+func (c paneCache) fresh(activity int64) bool {
+	return c.capturedAt.Unix() > activity && c.activity == activity
 }
 
 // missThreshold is how many consecutive polls must miss the status
 // before we consider it truly cleared (prevents flicker from unreliable detection).
+// Topics may change it with /tune.
 const missThreshold = 3
 
-// NewStatusPoller creates a new StatusPoller.
-func NewStatusPoller(bot *Bot, q *queue.Queue, mon *monitor.Monitor) *StatusPoller {
+// NewStatusPoller creates a new StatusPoller. It learns turn starts from bus
+// and publishes window deaths, completed turns and interactive prompts to it.
+func NewStatusPoller(bot *Bot, q *queue.Queue, bus *events.Bus) *StatusPoller {
+	return newStatusPoller(bot, q, bus, bus.TurnStarted.Subscribe())
+}
+
+// Fresh returns a copy of sp with empty caches that beats hb after every
+// poll, for a supervisor to run in sp's place and run again after a panic or
+// stall. It shares sp's turn-start subscription, so none are lost between runs.
+func (sp *StatusPoller) Fresh(hb *supervise.Heartbeat) *StatusPoller {
+	fresh := newStatusPoller(sp.bot, sp.queue, sp.events, sp.turnStarts)
+	fresh.pollInterval = sp.pollInterval
+	fresh.heartbeat = hb
+	return fresh
+}
+
+func newStatusPoller(bot *Bot, q *queue.Queue, bus *events.Bus, turnStarts <-chan events.TurnStarted) *StatusPoller {
 	return &StatusPoller{
 		bot:          bot,
 		queue:        q,
-		monitor:      mon,
+		events:       bus,
+		turnStarts:   turnStarts,
+		started:      make(map[string]time.Time),
 		lastStatus:   make(map[statusKey]string),
 		missCount:    make(map[string]int),
 		animFrame:    make(map[statusKey]int),
+		panes:        make(map[string]paneCache),
+		titles:       make(map[string]*windowTitle),
+		waiting:      make(map[string]attentionItem),
+		cwds:         make(map[string]*cwdWatch),
 		pollInterval: 1 * time.Second,
 	}
 }
@@ -62,16 +110,48 @@ func (sp *StatusPoller) Run(ctx context.Context) {
 		case <-ctx.Done():
 			log.Println("Status poller stopped.")
 			return
+		case ev := <-sp.turnStarts:
+			sp.started[ev.WindowID] = ev.At
 		case <-ticker.C:
 			sp.poll()
+			sp.heartbeat.Beat()
 		}
 	}
 }
 
 func (sp *StatusPoller) poll() {
+	// tmux may be restarting; don't capture panes or treat windows as dead
+	if sp.bot.state.IsPaused() {
+		return
+	}
+	sp.bot.checkAutoLoops(time.Now())
+
 	// Get all bound window IDs
 	boundWindows := sp.bot.state.AllBoundWindowIDs()
 
+	// One list-windows call tells which panes changed since their last capture
+	activity, err := tmux.ListWindowActivity(sp.bot.config.TmuxSessionName)
+	if err != nil {
+		activity = nil // capture everything this cycle
+	}
+	for windowID := range sp.panes {
+		if !boundWindows[windowID] {
+			delete(sp.panes, windowID)
+		}
+	}
+	for windowID := range sp.titles {
+		if !boundWindows[windowID] {
+			delete(sp.titles, windowID)
+		}
+	}
+	for windowID := range sp.cwds {
+		if !boundWindows[windowID] {
+			delete(sp.cwds, windowID)
+		}
+	}
+	waitingNow := make(map[string]bool)
+	defer sp.syncAttention(waitingNow)
+
 	for windowID := range boundWindows {
 		// Skip if queue is non-empty for all users of this window (avoid status noise during content delivery)
 		users := sp.bot.state.FindUsersForWindow(windowID)
@@ -79,44 +159,45 @@ func (sp *StatusPoller) poll() {
 			continue
 		}
 
-		// Capture pane (plain text, no ANSI)
-		paneText, err := tmux.CapturePane(sp.bot.config.TmuxSessionName, windowID, false)
+		act, listed := activity[windowID]
+		if listed {
+			sp.bot.checkClaudeProcess(windowID, act.Command, users)
+			sp.trackCWD(windowID, act.Path, users)
+		}
+
+		// Capture pane (plain text, no ANSI), reusing the last capture for idle panes
+		paneText, err := sp.capturePane(windowID, act, listed)
 		if err != nil {
 			if tmux.IsWindowDead(err) {
-				log.Printf("Status poller: window %s is dead, cleaning up", windowID)
-				// Save chat IDs before cleanup removes them
-				type notifyTarget struct {
-					chatID   int64
-					threadID int
-				}
-				var targets []notifyTarget
-				for _, ut := range users {
-					if cid, ok := sp.bot.state.GetGroupChatID(ut.UserID, ut.ThreadID); ok {
-						tid, _ := strconv.Atoi(ut.ThreadID)
-						targets = append(targets, notifyTarget{cid, tid})
-					}
-				}
-				// Clean up UI states for all users on this window
+				sp.mu.Lock()
 				for _, ut := range users {
 					uid, _ := strconv.ParseInt(ut.UserID, 10, 64)
 					tid, _ := strconv.Atoi(ut.ThreadID)
-					cancelBashCapture(uid, tid)
-					clearInteractiveUI(uid, tid)
-					// Clear cached status
-					sp.mu.Lock()
 					delete(sp.lastStatus, statusKey{uid, tid})
-					sp.mu.Unlock()
-				}
-				cleanupDeadWindow(sp.bot, windowID)
-				for _, t := range targets {
-					sp.bot.reply(t.chatID, t.threadID, "Session died. Send a message to restart.")
 				}
+				sp.mu.Unlock()
+				delete(sp.started, windowID)
+				sp.events.WindowDead.Publish(events.WindowDead{WindowID: windowID})
 			}
 			continue
 		}
 
+		sp.bot.updateToolProgress(windowID, paneText, time.Now())
+
 		// Check interactive UI once per pane
 		isInteractive := monitor.IsInteractiveUI(paneText)
+		if isInteractive {
+			waitingNow[windowID] = true
+			item, ok := sp.waiting[windowID]
+			if !ok {
+				item = newAttentionItem(windowID, paneText, time.Now())
+			}
+			if reminder := sp.bot.config.PromptReminder; reminder > 0 && !item.Reminded && time.Since(item.Since) >= reminder {
+				item.Reminded = true
+				sp.bot.remindPrompt(windowID, paneText, item.Since, users)
+			}
+			sp.waiting[windowID] = item
+		}
 
 		// Extract status line (only if not interactive)
 		var statusText string
@@ -135,6 +216,11 @@ func (sp *StatusPoller) poll() {
 			}
 		}
 
+		if sp.bot.config.WindowNameTemplate != "" || sp.bot.config.TopicIcons != nil || sp.bot.config.NowSummary == "title" {
+			st := sp.sessionStateFor(windowID, isInteractive, hasStatus, sp.bot.windowStatusMisses(users))
+			sp.syncTopics(windowID, st, users)
+		}
+
 		// Update for each observing user
 		for _, ut := range users {
 			userID, _ := strconv.ParseInt(ut.UserID, 10, 64)
@@ -161,7 +247,13 @@ func (sp *StatusPoller) poll() {
 			}
 
 			if shouldCheckNew && isInteractive {
-				sp.bot.handleInteractiveUI(chatID, threadID, userID, windowID)
+				sp.events.InteractiveUIDetected.Publish(events.InteractiveUIDetected{
+					WindowID: windowID,
+					UserID:   userID,
+					ThreadID: threadID,
+					ChatID:   chatID,
+					PaneText: paneText,
+				})
 				continue
 			}
 
@@ -183,6 +275,9 @@ func (sp *StatusPoller) poll() {
 				if sp.queue != nil && sp.queue.QueueLen(userID) > 0 {
 					continue
 				}
+				if sp.bot.quietFor(userID, false) {
+					continue
+				}
 
 				sp.mu.Lock()
 				sp.lastStatus[key] = statusText
@@ -190,7 +285,7 @@ func (sp *StatusPoller) poll() {
 				sp.animFrame[key] = (frame + 1) % len(animFrames)
 				sp.mu.Unlock()
 
-				displayText := animFrames[frame] + " " + statusText
+				displayText := sp.bot.labels(ut.ThreadID).Label("system", animFrames[frame]+" "+statusText)
 				if sp.queue != nil {
 					sp.queue.Enqueue(queue.MessageTask{
 						UserID:      userID,
@@ -201,7 +296,7 @@ func (sp *StatusPoller) poll() {
 						WindowID:    windowID,
 					})
 				}
-			} else if lastText != "" && misses >= missThreshold {
+			} else if lastText != "" && misses >= sp.bot.statusMisses(ut.ThreadID) {
 				// Status cleared — only after consecutive misses to avoid flicker
 				sp.mu.Lock()
 				delete(sp.lastStatus, key)
@@ -210,11 +305,11 @@ func (sp *StatusPoller) poll() {
 
 				// Check for turn timing
 				var timingText string
-				if sp.monitor != nil {
-					if start, ok := sp.monitor.GetAndClearTurnStart(windowID); ok {
-						elapsed := time.Since(start)
-						timingText = formatDuration(elapsed)
-					}
+				if start, ok := sp.started[windowID]; ok {
+					delete(sp.started, windowID)
+					elapsed := time.Since(start)
+					timingText = formatDuration(elapsed)
+					sp.events.TurnCompleted.Publish(events.TurnCompleted{WindowID: windowID, Elapsed: elapsed})
 				}
 
 				if sp.queue != nil {
@@ -242,6 +337,50 @@ func (sp *StatusPoller) poll() {
 	}
 }
 
+// syncAttention forgets windows no longer waiting for input and tells the bot
+// when the set changed.
+func (sp *StatusPoller) syncAttention(waitingNow map[string]bool) {
+	ids := make([]string, 0, len(waitingNow))
+	for windowID := range sp.waiting {
+		if !waitingNow[windowID] {
+			delete(sp.waiting, windowID)
+			continue
+		}
+		ids = append(ids, windowID)
+	}
+	sort.Strings(ids)
+	key := strings.Join(ids, ",")
+	if key == sp.attention {
+		return
+	}
+	sp.attention = key
+
+	items := make([]attentionItem, 0, len(ids))
+	for _, id := range ids {
+		items = append(items, sp.waiting[id])
+	}
+	sp.bot.updateAttention(items)
+}
+
+// capturePane returns the window's pane text, capturing it only when tmux
+// reports activity since the cached capture (or the window wasn't listed).
+func (sp *StatusPoller) capturePane(windowID string, act tmux.WindowActivity, listed bool) (string, error) {
+	if c, ok := sp.panes[windowID]; ok && listed && !act.Dead && c.fresh(act.Activity) {
+		return c.text, nil
+	}
+
+	capturedAt := time.Now()
+	text, err := tmux.CapturePane(sp.bot.config.TmuxSessionName, windowID, false)
+	if err != nil {
+		delete(sp.panes, windowID)
+		return "", err
+	}
+	if listed {
+		sp.panes[windowID] = paneCache{activity: act.Activity, capturedAt: capturedAt, text: text}
+	}
+	return text, nil
+}
+
 // formatDuration formats a duration as "Brewed for Xm Ys" or "Brewed for Ys".
 func formatDuration(d time.Duration) string {
 	secs := int(d.Seconds())
@@ -0,0 +1,24 @@
+package bot
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPaneCacheFresh(t *testing.T) {
+	captured := time.Unix(1000, 500_000_000)
+	c := paneCache{activity: 998, capturedAt: captured, text: "pane"}
+
+	if !c.fresh(998) {
+		t.Error("unchanged activity before the capture should reuse the cache")
+	}
+	if c.fresh(999) {
+		t.Error("newer activity should force a capture")
+	}
+
+	// Activity in the same second as the capture may postdate it
+	c.activity = 1000
+	if c.fresh(1000) {
+		t.Error("activity in the capture's second should force a capture")
+	}
+}
@@ -0,0 +1,195 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/minuano"
+)
+
+// taskEditState holds a pending task edit awaiting inline confirmation.
+type taskEditState struct {
+	Action    string // "retitle", "priority", "note" or "drop"
+	Task      minuano.Task
+	NewTitle  string
+	Priority  int
+	Note      string
+	ChatID    int64
+	ThreadID  int
+	MessageID int
+}
+
+// taskEditUsage is the reply for each edit command when arguments are missing.
+var taskEditUsage = map[string]string{
+	"retitle":  "Usage: /p_retitle <task-id> <new title>",
+	"priority": "Usage: /p_priority <task-id> [1-10]",
+	"note":     "Usage: /p_note <task-id> <note>",
+	"drop":     "Usage: /p_drop <task-id>",
+}
+
+// handleTaskEditCommand handles /p_retitle, /p_priority, /p_note and /p_drop.
+func (b *Bot) handleTaskEditCommand(msg *tgbotapi.Message, action string) {
+	chatID := msg.Chat.ID
+	threadID := getThreadID(msg)
+
+	project, ok := b.state.GetProject(strconv.Itoa(threadID))
+	if !ok {
+		b.reply(chatID, threadID, "No project bound. Use /p_bind <name> first.")
+		return
+	}
+
+	partialID, rest, _ := strings.Cut(strings.TrimSpace(msg.CommandArguments()), " ")
+	rest = strings.TrimSpace(rest)
+	if partialID == "" || (rest == "" && (action == "retitle" || action == "note")) {
+		b.reply(chatID, threadID, taskEditUsage[action])
+		return
+	}
+
+	task, ok := b.resolveTaskIDAll(msg, partialID, project)
+	if !ok {
+		return
+	}
+
+	es := &taskEditState{Action: action, Task: task, ChatID: chatID, ThreadID: threadID}
+	switch action {
+	case "retitle":
+		es.NewTitle = rest
+	case "note":
+		es.Note = rest
+	case "drop":
+		if task.Status == "done" {
+			b.reply(chatID, threadID, fmt.Sprintf("Task %s is already done.", task.ID))
+			return
+		}
+	case "priority":
+		if rest == "" {
+			b.sendTaskEditPrompt(msg.From.ID, es,
+				fmt.Sprintf("Priority for %s — %s (currently %d):", task.ID, task.Title, task.Priority),
+				buildEditPriorityKeyboard())
+			return
+		}
+		p, err := strconv.Atoi(rest)
+		if err != nil || p < 1 || p > 10 {
+			b.reply(chatID, threadID, "Priority must be a number from 1 to 10.")
+			return
+		}
+		es.Priority = p
+	}
+
+	b.sendTaskEditPrompt(msg.From.ID, es, describeTaskEdit(es), buildConfirmEditKeyboard())
+}
+
+// sendTaskEditPrompt sends a keyboard for a pending edit and stores its state.
+func (b *Bot) sendTaskEditPrompt(userID int64, es *taskEditState, text string, kb tgbotapi.InlineKeyboardMarkup) {
+	sent, err := b.sendMessageWithKeyboard(es.ChatID, es.ThreadID, text, kb)
+	if err != nil {
+		log.Printf("Error sending task edit prompt: %v", err)
+		return
+	}
+	es.MessageID = sent.MessageID
+
+	b.mu.Lock()
+	b.taskEditStates[userID] = es
+	b.mu.Unlock()
+}
+
+// describeTaskEdit renders the confirmation question for a pending edit.
+func describeTaskEdit(es *taskEditState) string {
+	t := es.Task
+	switch es.Action {
+	case "retitle":
+		return fmt.Sprintf("Rename %s?\n  %s\n→ %s", t.ID, t.Title, es.NewTitle)
+	case "priority":
+		return fmt.Sprintf("Change priority of %s — %s?\n  %d → %d", t.ID, t.Title, t.Priority, es.Priority)
+	case "note":
+		return fmt.Sprintf("Add note to %s — %s?\n  %s", t.ID, t.Title, es.Note)
+	case "drop":
+		return fmt.Sprintf("Drop %s — %s [%s]?\nIt will be marked failed and released.", t.ID, t.Title, t.Status)
+	}
+	return ""
+}
+
+func buildConfirmEditKeyboard() tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Confirm", "tedit_ok"),
+			tgbotapi.NewInlineKeyboardButtonData("Cancel", "tedit_cancel"),
+		),
+	)
+}
+
+// buildEditPriorityKeyboard mirrors the /p_add priority keyboard for edits.
+func buildEditPriorityKeyboard() tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, row := range [][]int{{10, 8, 7}, {6, 5, 4}, {3, 2, 1}} {
+		var buttons []tgbotapi.InlineKeyboardButton
+		for _, p := range row {
+			buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData(strconv.Itoa(p), fmt.Sprintf("tedit_pri:%d", p)))
+		}
+		rows = append(rows, buttons)
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Cancel", "tedit_cancel"),
+	))
+	return tgbotapi.InlineKeyboardMarkup{InlineKeyboard: rows}
+}
+
+// processTaskEditCallback handles tedit_* callbacks.
+func (b *Bot) processTaskEditCallback(cq *tgbotapi.CallbackQuery) {
+	userID := cq.From.ID
+
+	b.mu.Lock()
+	es, ok := b.taskEditStates[userID]
+	if ok {
+		delete(b.taskEditStates, userID)
+	}
+	b.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	switch {
+	case cq.Data == "tedit_cancel":
+		b.editMessageText(es.ChatID, es.MessageID, "Edit cancelled.")
+		return
+	case strings.HasPrefix(cq.Data, "tedit_pri:"):
+		p, err := strconv.Atoi(strings.TrimPrefix(cq.Data, "tedit_pri:"))
+		if err != nil {
+			return
+		}
+		es.Priority = p
+	case cq.Data != "tedit_ok":
+		return
+	}
+
+	b.editMessageText(es.ChatID, es.MessageID, b.applyTaskEdit(es))
+}
+
+// applyTaskEdit performs a confirmed edit and returns the result text.
+func (b *Bot) applyTaskEdit(es *taskEditState) string {
+	var err error
+	var done string
+	t := es.Task
+	switch es.Action {
+	case "retitle":
+		err = b.minuanoBridge.SetTitle(t.ID, es.NewTitle)
+		done = fmt.Sprintf("Renamed %s: %s", t.ID, es.NewTitle)
+	case "priority":
+		err = b.minuanoBridge.SetPriority(t.ID, es.Priority)
+		done = fmt.Sprintf("Priority of %s set to %d.", t.ID, es.Priority)
+	case "note":
+		err = b.minuanoBridge.AddNote(t.ID, es.Note)
+		done = fmt.Sprintf("Note added to %s.", t.ID)
+	case "drop":
+		err = b.minuanoBridge.Drop(t.ID)
+		done = fmt.Sprintf("Dropped %s — %s", t.ID, t.Title)
+	}
+	if err != nil {
+		log.Printf("Error applying %s to task %s: %v", es.Action, t.ID, err)
+		return fmt.Sprintf("Error: %v", err)
+	}
+	return done
+}
@@ -0,0 +1,41 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/otaviocarvalho/tramuntana/internal/minuano"
+)
+
+func TestDescribeTaskEdit(t *testing.T) {
+	task := minuano.Task{ID: "t1", Title: "Old title", Status: "ready", Priority: 5}
+	tests := []struct {
+		es   taskEditState
+		want string
+	}{
+		{taskEditState{Action: "retitle", Task: task, NewTitle: "New title"}, "Old title\n→ New title"},
+		{taskEditState{Action: "priority", Task: task, Priority: 9}, "5 → 9"},
+		{taskEditState{Action: "note", Task: task, Note: "watch the migrations"}, "watch the migrations"},
+		{taskEditState{Action: "drop", Task: task}, "Drop t1 — Old title [ready]?"},
+	}
+	for _, tt := range tests {
+		if got := describeTaskEdit(&tt.es); !strings.Contains(got, tt.want) {
+			t.Errorf("%s: got %q, want it to contain %q", tt.es.Action, got, tt.want)
+		}
+	}
+}
+
+func TestBuildEditPriorityKeyboard(t *testing.T) {
+	kb := buildEditPriorityKeyboard()
+	if len(kb.InlineKeyboard) != 4 {
+		t.Fatalf("expected 4 rows, got %d", len(kb.InlineKeyboard))
+	}
+	first := kb.InlineKeyboard[0][0]
+	if first.CallbackData == nil || *first.CallbackData != "tedit_pri:10" {
+		t.Errorf("first button callback = %v, want tedit_pri:10", first.CallbackData)
+	}
+	cancel := kb.InlineKeyboard[3][0]
+	if *cancel.CallbackData != "tedit_cancel" {
+		t.Errorf("last row should be cancel, got %q", *cancel.CallbackData)
+	}
+}
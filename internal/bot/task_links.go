@@ -0,0 +1,136 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/otaviocarvalho/tramuntana/internal/git"
+	"github.com/otaviocarvalho/tramuntana/internal/monitor"
+)
+
+// taskLinkMaxItems caps the files and commits listed in a task's handoff entry.
+const taskLinkMaxItems = 20
+
+// taskLink follows a /t_pick or /t_pickw task through the window's
+// transcript, so that when Claude marks it done what happened can be written
+// back to Minuano for the tasks that depend on it.
+type taskLink struct {
+	taskID string
+	dir    string // repository the task's commits land in; "" if not a git repo
+	base   string // commit or branch the task's commits are counted from
+	files  []string
+}
+
+var (
+	taskLinks   = make(map[string]*taskLink) // windowID → link
+	taskLinksMu sync.Mutex
+)
+
+// startTaskLink follows a task picked into a window. dir and base locate its
+// commits; for a task worked in place, base is "" and HEAD is recorded now.
+func startTaskLink(windowID, taskID, dir, base string) {
+	if base == "" && dir != "" {
+		head, err := git.Head(dir)
+		if err != nil {
+			dir = ""
+		}
+		base = head
+	}
+	taskLinksMu.Lock()
+	defer taskLinksMu.Unlock()
+	taskLinks[windowID] = &taskLink{taskID: taskID, dir: dir, base: base}
+}
+
+// stopTaskLink stops following a window's task.
+func stopTaskLink(windowID string) {
+	taskLinksMu.Lock()
+	defer taskLinksMu.Unlock()
+	delete(taskLinks, windowID)
+}
+
+// observe records files Claude changed. Returns true once the task is marked
+// done with minuano-done.
+func (l *taskLink) observe(parsed []monitor.ParsedEntry) bool {
+	for _, pe := range parsed {
+		if pe.ContentType != "tool_result" || pe.IsError {
+			continue
+		}
+		switch pe.ToolName {
+		case "Write", "Edit", "MultiEdit", "NotebookEdit":
+			if pe.ToolInput != "" {
+				l.files = appendIfMissing(l.files, pe.ToolInput)
+			}
+		case "Bash":
+			if monitor.DoneTaskID(pe.ToolInput) == l.taskID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// summary renders the handoff entry written to Minuano.
+func (l *taskLink) summary(commits []string) string {
+	var b strings.Builder
+	b.WriteString("Completed from Telegram (tramuntana).")
+	writeList := func(title string, items []string) {
+		if len(items) == 0 {
+			return
+		}
+		b.WriteString("\n\n" + title + ":")
+		for i, item := range items {
+			if i == taskLinkMaxItems {
+				fmt.Fprintf(&b, "\n… +%d more", len(items)-i)
+				break
+			}
+			b.WriteString("\n- " + item)
+		}
+	}
+	files := make([]string, len(l.files))
+	for i, f := range l.files {
+		files[i] = f
+		if l.dir != "" {
+			if rel, err := filepath.Rel(l.dir, f); err == nil && !strings.HasPrefix(rel, "..") {
+				files[i] = rel
+			}
+		}
+	}
+	writeList("Files changed", files)
+	writeList("Commits", commits)
+	if len(files) == 0 && len(commits) == 0 {
+		b.WriteString(" No file changes or commits were seen.")
+	}
+	return b.String()
+}
+
+// updateTaskLink applies transcript entries to the window's task link and,
+// when the task is done, writes its summary to Minuano as a handoff entry.
+func (b *Bot) updateTaskLink(windowID string, parsed []monitor.ParsedEntry) {
+	taskLinksMu.Lock()
+	l, ok := taskLinks[windowID]
+	done := ok && l.observe(parsed)
+	if done {
+		delete(taskLinks, windowID)
+	}
+	taskLinksMu.Unlock()
+	if done {
+		go b.writeTaskLink(*l)
+	}
+}
+
+// writeTaskLink writes a finished task's summary to Minuano.
+func (b *Bot) writeTaskLink(l taskLink) {
+	var commits []string
+	if l.dir != "" {
+		var err error
+		if commits, err = git.CommitsSince(l.dir, l.base); err != nil {
+			log.Printf("Task link %s: %v", l.taskID, err)
+		}
+	}
+	if err := b.minuanoBridge.AddContext(l.taskID, "handoff", l.summary(commits)); err != nil {
+		log.Printf("Task link %s: %v", l.taskID, err)
+	}
+}
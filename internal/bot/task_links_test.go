@@ -0,0 +1,52 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/otaviocarvalho/tramuntana/internal/monitor"
+)
+
+func TestTaskLink_Observe(t *testing.T) {
+	l := &taskLink{taskID: "auth-a1", dir: "/repo"}
+	done := l.observe([]monitor.ParsedEntry{
+		{ContentType: "tool_result", ToolName: "Edit", ToolInput: "/repo/auth/login.go"},
+		{ContentType: "tool_result", ToolName: "Write", ToolInput: "/repo/auth/login_test.go"},
+		{ContentType: "tool_result", ToolName: "Edit", ToolInput: "/repo/auth/login.go"},
+		{ContentType: "tool_result", ToolName: "Edit", ToolInput: "/repo/broken.go", IsError: true},
+		{ContentType: "tool_use", ToolName: "Write", ToolInput: "/repo/pending.go"},
+		{ContentType: "tool_result", ToolName: "Bash", ToolInput: "minuano-done other-b2 'x'"},
+	})
+	if done {
+		t.Fatal("another task's minuano-done should not finish the link")
+	}
+	if got := strings.Join(l.files, ","); got != "/repo/auth/login.go,/repo/auth/login_test.go" {
+		t.Errorf("files = %s", got)
+	}
+	if !l.observe([]monitor.ParsedEntry{{ContentType: "tool_result", ToolName: "Bash", ToolInput: `minuano-done auth-a1 "login works"`}}) {
+		t.Error("minuano-done for the task should finish the link")
+	}
+}
+
+func TestTaskLink_Summary(t *testing.T) {
+	l := &taskLink{taskID: "auth-a1", dir: "/repo", files: []string{"/repo/auth/login.go", "/elsewhere/notes.md"}}
+	got := l.summary([]string{"abc1234 Add login"})
+	for _, want := range []string{"Files changed:\n- auth/login.go\n- /elsewhere/notes.md", "Commits:\n- abc1234 Add login"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("summary missing %q:\n%s", want, got)
+		}
+	}
+
+	empty := (&taskLink{taskID: "auth-a1"}).summary(nil)
+	if !strings.Contains(empty, "No file changes or commits") {
+		t.Errorf("empty summary:\n%s", empty)
+	}
+
+	many := &taskLink{taskID: "auth-a1"}
+	for i := 0; i < taskLinkMaxItems+2; i++ {
+		many.files = append(many.files, "/f"+strings.Repeat("x", i))
+	}
+	if got := many.summary(nil); !strings.Contains(got, "… +2 more") {
+		t.Errorf("long summary not capped:\n%s", got)
+	}
+}
@@ -118,26 +118,26 @@ func (b *Bot) showTaskPicker(msg *tgbotapi.Message, tasks []minuano.Task, mode,
 	}
 
 	b.mu.Lock()
-	b.taskPickerStates[userID] = &taskPickerState{
+	b.taskPickerStates.set(wizardKey{UserID: userID, ChatID: chatID, ThreadID: threadID}, &taskPickerState{
 		Tasks:     tasks,
 		Mode:      mode,
 		ChatID:    chatID,
 		ThreadID:  threadID,
 		MessageID: sent.MessageID,
-	}
+	})
 	b.mu.Unlock()
 }
 
 // processTaskPickerCallback handles tpick_* callbacks.
 func (b *Bot) processTaskPickerCallback(cq *tgbotapi.CallbackQuery) {
 	data := cq.Data
-	userID := cq.From.ID
+	k := callbackWizardKey(cq)
 
 	if data == "tpick_cancel" {
 		b.mu.Lock()
-		tps, ok := b.taskPickerStates[userID]
+		tps, ok := b.taskPickerStates.get(k)
 		if ok {
-			delete(b.taskPickerStates, userID)
+			b.taskPickerStates.remove(k)
 		}
 		b.mu.Unlock()
 		if ok {
@@ -165,10 +165,13 @@ func (b *Bot) processTaskPickerCallback(cq *tgbotapi.CallbackQuery) {
 		return
 	}
 
+	// An older picker's button still works, but must not consume the state
+	// of a newer picker in the same topic
 	b.mu.Lock()
-	tps, ok := b.taskPickerStates[userID]
+	tps, ok := b.taskPickerStates.get(k)
+	ok = ok && tps.MessageID == cq.Message.MessageID
 	if ok {
-		delete(b.taskPickerStates, userID)
+		b.taskPickerStates.remove(k)
 	}
 	b.mu.Unlock()
 
@@ -241,6 +244,9 @@ func (b *Bot) executePickTask(chatID int64, threadID int, userID int64, taskID s
 		return
 	}
 
+	b.usage.RecordTaskPicked(windowID, taskID)
+	repoRoot, _ := b.getRepoRoot(userIDStr, threadIDStr)
+	startTaskLink(windowID, taskID, repoRoot, "")
 	b.reply(chatID, threadID, fmt.Sprintf("Working on task %s...", taskID))
 }
 
@@ -144,6 +144,7 @@ func (b *Bot) sendMessageInThread(chatID int64, threadID int, text string) (tgbo
 		if b.msgQueue != nil {
 			b.msgQueue.HandleFloodError(chatID, err)
 		}
+		b.checkThreadGone(chatID, threadID, err)
 		return tgbotapi.Message{}, err
 	}
 
@@ -184,6 +185,11 @@ func (b *Bot) editMessageText(chatID int64, messageID int, text string) error {
 
 // sendMessageWithKeyboard sends a message with inline keyboard in a thread.
 func (b *Bot) sendMessageWithKeyboard(chatID int64, threadID int, text string, keyboard tgbotapi.InlineKeyboardMarkup) (tgbotapi.Message, error) {
+	return b.sendKeyboardMessage(chatID, threadID, text, keyboard, false)
+}
+
+// sendKeyboardMessage is sendMessageWithKeyboard, optionally without sound.
+func (b *Bot) sendKeyboardMessage(chatID int64, threadID int, text string, keyboard tgbotapi.InlineKeyboardMarkup, silent bool) (tgbotapi.Message, error) {
 	kbJSON, _ := json.Marshal(keyboard)
 
 	params := tgbotapi.Params{}
@@ -193,6 +199,31 @@ func (b *Bot) sendMessageWithKeyboard(chatID int64, threadID int, text string, k
 		params.AddNonZero("message_thread_id", threadID)
 	}
 	params["reply_markup"] = string(kbJSON)
+	params.AddBool("disable_notification", silent)
+
+	resp, err := b.api.MakeRequest("sendMessage", params)
+	if err != nil {
+		b.checkThreadGone(chatID, threadID, err)
+		return tgbotapi.Message{}, err
+	}
+
+	var msg tgbotapi.Message
+	json.Unmarshal(resp.Result, &msg)
+	return msg, nil
+}
+
+// sendMessageWithKeyboardMD is sendMessageWithKeyboard for MarkdownV2 text.
+func (b *Bot) sendMessageWithKeyboardMD(chatID int64, threadID int, text string, keyboard tgbotapi.InlineKeyboardMarkup) (tgbotapi.Message, error) {
+	kbJSON, _ := json.Marshal(keyboard)
+
+	params := tgbotapi.Params{}
+	params.AddNonZero64("chat_id", chatID)
+	params.AddNonEmpty("text", text)
+	if threadID != 0 {
+		params.AddNonZero("message_thread_id", threadID)
+	}
+	params.AddNonEmpty("parse_mode", "MarkdownV2")
+	params["reply_markup"] = string(kbJSON)
 
 	resp, err := b.api.MakeRequest("sendMessage", params)
 	if err != nil {
@@ -217,6 +248,20 @@ func (b *Bot) editMessageWithKeyboard(chatID int64, messageID int, text string,
 	return err
 }
 
+// editMessageWithKeyboardMD is editMessageWithKeyboard for MarkdownV2 text.
+func (b *Bot) editMessageWithKeyboardMD(chatID int64, messageID int, text string, keyboard tgbotapi.InlineKeyboardMarkup) error {
+	kbJSON, _ := json.Marshal(keyboard)
+
+	params := tgbotapi.Params{}
+	params.AddNonZero64("chat_id", chatID)
+	params.AddNonZero("message_id", messageID)
+	params.AddNonEmpty("text", text)
+	params.AddNonEmpty("parse_mode", "MarkdownV2")
+	params["reply_markup"] = string(kbJSON)
+	_, err := b.api.MakeRequest("editMessageText", params)
+	return err
+}
+
 // deleteMessage deletes a message.
 func (b *Bot) deleteMessage(chatID int64, messageID int) error {
 	params := tgbotapi.Params{}
@@ -0,0 +1,38 @@
+package bot
+
+import (
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+)
+
+// thinkingModeText describes each /thinking mode.
+var thinkingModeText = map[string]string{
+	state.ThinkingShort: "the first 500 characters of Claude's thinking, in a collapsed quote",
+	state.ThinkingFull:  "Claude's full thinking, in collapsed quotes",
+	state.ThinkingHide:  "no thinking",
+}
+
+// handleThinkingCommand sets how this topic shows Claude's thinking.
+// Usage: /thinking [short|full|hide]; no argument shows the setting.
+func (b *Bot) handleThinkingCommand(msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+	threadID := getThreadID(msg)
+	threadIDStr := strconv.Itoa(threadID)
+
+	mode := strings.ToLower(strings.TrimSpace(msg.CommandArguments()))
+	if mode == "" {
+		current := b.state.GetThinkingMode(threadIDStr)
+		b.reply(chatID, threadID, "Thinking: "+current+" — this topic shows "+thinkingModeText[current]+".\nUse /thinking short, full or hide to change it.")
+		return
+	}
+	if _, ok := thinkingModeText[mode]; !ok {
+		b.reply(chatID, threadID, "Usage: /thinking [short|full|hide]")
+		return
+	}
+	b.state.SetThinkingMode(threadIDStr, mode)
+	b.saveState()
+	b.reply(chatID, threadID, "Thinking: "+mode+" — this topic now shows "+thinkingModeText[mode]+".")
+}
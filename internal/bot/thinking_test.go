@@ -0,0 +1,54 @@
+package bot
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func thinkingLine(text string) string {
+	data, _ := json.Marshal(text)
+	return `{"type":"assistant","message":{"content":[{"type":"thinking","thinking":` + string(data) + `}]}}`
+}
+
+func TestE2E_ThinkingModes(t *testing.T) {
+	h := startE2E(t, "fresh")
+	windowID := h.tmux.AddWindow("api", "/work/api")
+	userID, threadID := strconv.FormatInt(e2eUser, 10), strconv.Itoa(e2eThread)
+	h.bot.state.BindThread(userID, threadID, windowID)
+	h.bot.state.SetGroupChatID(userID, threadID, e2eChat)
+	h.writeSession(t, windowID, "sess-1", "/work/api")
+
+	// Consecutive thinking blocks arrive as one message
+	h.appendTranscript(t, "sess-1", thinkingLine("Weighing option A"), thinkingLine("Weighing option B"))
+	c := h.tg.WaitForText("sendMessage", "Weighing option A")
+	if !strings.Contains(c.Params["text"], "Weighing option B") {
+		t.Errorf("thinking blocks not batched: %q", c.Params["text"])
+	}
+
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "/thinking hide")
+	h.tg.WaitForText("sendMessage", "now shows no thinking")
+	h.appendTranscript(t, "sess-1",
+		thinkingLine("Secret deliberation"),
+		`{"type":"assistant","message":{"content":[{"type":"text","text":"Visible answer"}]}}`)
+	h.tg.WaitForText("sendMessage", "Visible answer")
+	for _, c := range h.tg.Calls("sendMessage") {
+		if strings.Contains(c.Params["text"], "Secret deliberation") {
+			t.Error("thinking sent while hidden")
+		}
+	}
+
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "/thinking full")
+	h.tg.WaitForText("sendMessage", "full thinking")
+	long := strings.Repeat("A long line of careful reasoning\n", 200) + "Final conclusion"
+	h.appendTranscript(t, "sess-1", thinkingLine(long))
+	h.tg.WaitForText("sendMessage", "Final conclusion")
+	var quotes int
+	for _, c := range h.tg.Calls("sendMessage") {
+		quotes += strings.Count(c.Params["text"], "careful reasoning")
+	}
+	if quotes != 200 {
+		t.Errorf("full thinking delivered %d of 200 lines", quotes)
+	}
+}
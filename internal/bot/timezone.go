@@ -0,0 +1,49 @@
+package bot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// handleTimezoneCommand shows or sets the user's timezone for message timestamps.
+// "/timezone Europe/Madrid" enables timestamps, "/timezone off" disables them.
+func (b *Bot) handleTimezoneCommand(msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+	threadID := getThreadID(msg)
+	userID := strconv.FormatInt(msg.From.ID, 10)
+	us := b.state.GetUserSettings(userID)
+
+	arg := strings.TrimSpace(msg.CommandArguments())
+	switch {
+	case arg == "":
+		loc := us.Location()
+		if loc == nil {
+			b.reply(chatID, threadID, "Timestamps are off. Use /timezone <IANA name>, e.g. /timezone Europe/Madrid")
+			return
+		}
+		now := time.Now().In(loc).Format("15:04")
+		b.reply(chatID, threadID, fmt.Sprintf("Timezone: %s (now %s)\nUse /timezone off to hide timestamps.", us.Timezone, now))
+		return
+	case strings.EqualFold(arg, "off"):
+		us.Timezone = ""
+		b.state.SetUserSettings(userID, us)
+		b.saveState()
+		b.reply(chatID, threadID, "Timestamps disabled.")
+		return
+	}
+
+	loc, err := time.LoadLocation(arg)
+	if err != nil {
+		b.reply(chatID, threadID, fmt.Sprintf("Unknown timezone %q. Use an IANA name like America/Sao_Paulo or UTC.", arg))
+		return
+	}
+
+	us.Timezone = loc.String()
+	b.state.SetUserSettings(userID, us)
+	b.saveState()
+	b.reply(chatID, threadID, fmt.Sprintf("Timezone set to %s (now %s).", us.Timezone, time.Now().In(loc).Format("15:04")))
+}
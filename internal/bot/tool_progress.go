@@ -0,0 +1,155 @@
+package bot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/otaviocarvalho/tramuntana/internal/monitor"
+	"github.com/otaviocarvalho/tramuntana/internal/queue"
+)
+
+// A Bash tool call still running after longToolAfter gets its message edited
+// every toolProgressEvery with the elapsed time and its latest output line.
+// Variables so tests can shorten them.
+var (
+	longToolAfter     = 15 * time.Second
+	toolProgressEvery = 10 * time.Second
+)
+
+// maxProgressLine caps the output line shown in a progress edit, in characters.
+const maxProgressLine = 120
+
+// runningTool is a Bash tool call waiting for its result.
+type runningTool struct {
+	toolUseID string
+	command   string
+	summary   string // text of the tool_use message
+	started   time.Time
+	updated   time.Time // last progress edit
+	lastText  string
+}
+
+var (
+	runningToolsMu sync.Mutex
+	runningTools   = make(map[string]map[string]*runningTool) // windowID → tool_use_id → call
+)
+
+// trackBashTools records Bash tool calls starting and finishing in a batch of
+// a window's transcript entries. A user prompt ends any calls still open,
+// e.g. after an interrupt.
+func trackBashTools(windowID string, parsed []monitor.ParsedEntry, now time.Time) {
+	runningToolsMu.Lock()
+	defer runningToolsMu.Unlock()
+	for _, pe := range parsed {
+		switch {
+		case pe.ContentType == "tool_use" && pe.ToolName == "Bash" && pe.ToolUseID != "":
+			if runningTools[windowID] == nil {
+				runningTools[windowID] = make(map[string]*runningTool)
+			}
+			runningTools[windowID][pe.ToolUseID] = &runningTool{
+				toolUseID: pe.ToolUseID,
+				command:   pe.ToolInput,
+				summary:   pe.Text,
+				started:   now,
+			}
+		case pe.ContentType == "tool_result":
+			delete(runningTools[windowID], pe.ToolUseID)
+		case pe.ContentType == "text" && pe.Role == "user":
+			delete(runningTools, windowID)
+		}
+	}
+	if len(runningTools[windowID]) == 0 {
+		delete(runningTools, windowID)
+	}
+}
+
+// stopToolProgress forgets a window's running tool calls.
+func stopToolProgress(windowID string) {
+	runningToolsMu.Lock()
+	delete(runningTools, windowID)
+	runningToolsMu.Unlock()
+}
+
+// updateToolProgress queues progress edits for the window's long-running
+// Bash calls that are due one, reading their output from paneText.
+func (b *Bot) updateToolProgress(windowID, paneText string, now time.Time) {
+	if b.msgQueue == nil {
+		return
+	}
+	type edit struct{ toolUseID, text string }
+	var due []edit
+	runningToolsMu.Lock()
+	for _, rt := range runningTools[windowID] {
+		if now.Sub(rt.started) < longToolAfter || now.Sub(rt.updated) < toolProgressEvery {
+			continue
+		}
+		text := toolProgressText(rt.summary, now.Sub(rt.started), monitor.ExtractBashToolOutput(paneText, rt.command))
+		if text == rt.lastText {
+			continue
+		}
+		rt.updated, rt.lastText = now, text
+		due = append(due, edit{rt.toolUseID, text})
+	}
+	runningToolsMu.Unlock()
+
+	for _, ut := range b.state.FindUsersForWindow(windowID) {
+		chatID, ok := b.state.GetGroupChatID(ut.UserID, ut.ThreadID)
+		if !ok {
+			continue
+		}
+		userID, _ := strconv.ParseInt(ut.UserID, 10, 64)
+		threadID, _ := strconv.Atoi(ut.ThreadID)
+		labels := b.labels(ut.ThreadID)
+		for _, e := range due {
+			b.msgQueue.Enqueue(queue.MessageTask{
+				UserID:      userID,
+				ThreadID:    threadID,
+				ChatID:      chatID,
+				Parts:       []string{labels.Label("tool", e.text)},
+				ContentType: "tool_progress",
+				ToolUseID:   e.toolUseID,
+				WindowID:    windowID,
+			})
+		}
+	}
+}
+
+// toolProgressText is a tool_use message with the call's elapsed time and the
+// last line of its output so far, if any.
+func toolProgressText(summary string, elapsed time.Duration, output string) string {
+	text := summary + "\n⏳ running for " + formatElapsed(elapsed)
+	if line := lastOutputLine(output); line != "" {
+		text += "\n`" + line + "`"
+	}
+	return text
+}
+
+// lastOutputLine returns the last non-blank line below the echo line of a
+// tool call's pane output, without the "⎿" gutter and backticks.
+func lastOutputLine(output string) string {
+	lines := strings.Split(output, "\n")
+	for i := len(lines) - 1; i > 0; i-- {
+		line := strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(lines[i]), "⎿"))
+		line = strings.ReplaceAll(line, "`", "'")
+		if line == "" {
+			continue
+		}
+		if r := []rune(line); len(r) > maxProgressLine {
+			line = string(r[:maxProgressLine-1]) + "…"
+		}
+		return line
+	}
+	return ""
+}
+
+// formatElapsed formats a duration as "45s" or "3m 05s".
+func formatElapsed(d time.Duration) string {
+	secs := int(d.Seconds())
+	if secs < 60 {
+		return fmt.Sprintf("%ds", secs)
+	}
+	return fmt.Sprintf("%dm %02ds", secs/60, secs%60)
+}
@@ -0,0 +1,74 @@
+package bot
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/otaviocarvalho/tramuntana/internal/monitor"
+	"github.com/otaviocarvalho/tramuntana/internal/testharness"
+)
+
+func TestTrackBashTools(t *testing.T) {
+	defer stopToolProgress("@1")
+	now := time.Now()
+	trackBashTools("@1", []monitor.ParsedEntry{
+		{ContentType: "tool_use", ToolName: "Bash", ToolUseID: "tu_1", ToolInput: "make"},
+		{ContentType: "tool_use", ToolName: "Read", ToolUseID: "tu_2"},
+		{ContentType: "tool_use", ToolName: "Bash", ToolUseID: "tu_3", ToolInput: "go test ./..."},
+		{ContentType: "tool_result", ToolUseID: "tu_1"},
+	}, now)
+	if calls := runningTools["@1"]; len(calls) != 1 || calls["tu_3"] == nil {
+		t.Fatalf("running = %v, want only tu_3", calls)
+	}
+	trackBashTools("@1", []monitor.ParsedEntry{{ContentType: "text", Role: "user", Text: "stop"}}, now)
+	if _, ok := runningTools["@1"]; ok {
+		t.Error("a new prompt should end open calls")
+	}
+}
+
+func TestToolProgressText(t *testing.T) {
+	output := "⏺ Bash(make)\n  ⎿  compiling `api`\n     linking\n\n"
+	got := toolProgressText("**Bash**(make)", 65*time.Second, output)
+	want := "**Bash**(make)\n⏳ running for 1m 05s\n`linking`"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got := toolProgressText("**Bash**(make)", 20*time.Second, "⏺ Bash(make)"); got != "**Bash**(make)\n⏳ running for 20s" {
+		t.Errorf("without output: %q", got)
+	}
+}
+
+func TestE2E_LongBashToolProgress(t *testing.T) {
+	prevAfter, prevEvery := longToolAfter, toolProgressEvery
+	longToolAfter, toolProgressEvery = 100*time.Millisecond, 100*time.Millisecond
+	t.Cleanup(func() { longToolAfter, toolProgressEvery = prevAfter, prevEvery })
+
+	h := startE2E(t, "fresh")
+	h.startStatusPoller()
+	windowID := h.tmux.AddWindow("api", "/work/api")
+	userID, threadID := strconv.FormatInt(e2eUser, 10), strconv.Itoa(e2eThread)
+	h.bot.state.BindThread(userID, threadID, windowID)
+	h.bot.state.SetGroupChatID(userID, threadID, e2eChat)
+	h.writeSession(t, windowID, "sess-1", "/work/api")
+
+	h.tmux.SetPane(windowID, "⏺ Bash(make test)\n  ⎿  ok  \tgithub.com/x/api\t0.2s\n"+testharness.ReadyPane)
+	h.appendTranscript(t, "sess-1",
+		`{"type":"assistant","message":{"content":[{"type":"tool_use","id":"tu_1","name":"Bash","input":{"command":"make test"}}]}}`)
+	toolUse := h.tg.WaitForText("sendMessage", "make test")
+
+	progress := h.tg.WaitForText("editMessageText", "running for")
+	if progress.Params["message_id"] != strconv.Itoa(toolUse.MessageID) || !strings.Contains(progress.Params["text"], "github.com/x/api") {
+		t.Errorf("progress edit = %+v", progress.Params)
+	}
+
+	h.appendTranscript(t, "sess-1",
+		`{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"tu_1","content":"PASS","is_error":false}]}}`)
+	h.tg.WaitForText("editMessageText", "PASS")
+	time.Sleep(300 * time.Millisecond)
+	edits := h.tg.Calls("editMessageText")
+	if last := edits[len(edits)-1].Params["text"]; !strings.Contains(last, "PASS") {
+		t.Errorf("edited after the result: %q", last)
+	}
+}
@@ -0,0 +1,92 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// topicIconRetry is how long topic icons stay off after fetching them failed.
+const topicIconRetry = 5 * time.Minute
+
+// topicIconSticker is the part of a getForumTopicIconStickers result we use.
+type topicIconSticker struct {
+	Emoji         string `json:"emoji"`
+	CustomEmojiID string `json:"custom_emoji_id"`
+}
+
+// normalizeEmoji drops variation selectors, so "✔" matches "✔️".
+func normalizeEmoji(s string) string {
+	return strings.ReplaceAll(s, "️", "")
+}
+
+// iconKey is the TRAMUNTANA_TOPIC_ICONS state a session state is shown as.
+func (s sessionState) iconKey() string {
+	switch s {
+	case stateIdle:
+		return "idle"
+	case stateWorking:
+		return "working"
+	case stateNeedsInput:
+		return "input"
+	case stateDead:
+		return "dead"
+	}
+	return ""
+}
+
+// topicIconID returns the custom emoji ID of the icon configured for a state.
+// False when icons are off, the emoji is not a topic icon, or the icons
+// couldn't be fetched; a failed fetch is retried after topicIconRetry.
+func (b *Bot) topicIconID(st sessionState) (string, bool) {
+	emoji, ok := b.config.TopicIcons[st.iconKey()]
+	if !ok {
+		return "", false
+	}
+
+	b.mu.Lock()
+	ids := b.topicIconIDs
+	fetch := ids == nil && !time.Now().Before(b.topicIconsRetryAt)
+	if fetch {
+		// Hold off other callers while this one fetches
+		b.topicIconsRetryAt = time.Now().Add(topicIconRetry)
+	}
+	b.mu.Unlock()
+
+	if fetch {
+		var err error
+		if ids, err = b.fetchTopicIcons(); err != nil {
+			log.Printf("Error fetching topic icons, retrying in %v: %v", topicIconRetry, err)
+			return "", false
+		}
+		b.mu.Lock()
+		b.topicIconIDs = ids
+		b.mu.Unlock()
+	}
+	id, ok := ids[normalizeEmoji(emoji)]
+	return id, ok
+}
+
+// fetchTopicIcons maps each usable topic icon emoji to its custom emoji ID.
+func (b *Bot) fetchTopicIcons() (map[string]string, error) {
+	resp, err := b.api.MakeRequest("getForumTopicIconStickers", nil)
+	if err != nil {
+		return nil, err
+	}
+	var stickers []topicIconSticker
+	if err := json.Unmarshal(resp.Result, &stickers); err != nil {
+		return nil, fmt.Errorf("decoding topic icons: %w", err)
+	}
+	ids := make(map[string]string)
+	for _, s := range stickers {
+		ids[normalizeEmoji(s.Emoji)] = s.CustomEmojiID
+	}
+	for state, emoji := range b.config.TopicIcons {
+		if _, ok := ids[normalizeEmoji(emoji)]; !ok {
+			log.Printf("TRAMUNTANA_TOPIC_ICONS: %s icon %s is not a forum topic icon", state, emoji)
+		}
+	}
+	return ids, nil
+}
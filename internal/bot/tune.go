@@ -0,0 +1,128 @@
+package bot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/queue"
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+)
+
+const tuneUsage = "Usage: /tune [merge|split|preview|status <n|default> | reset]\nE.g. /tune merge 8000 for a logs topic, /tune status 1 for a snappy one"
+
+// tuneSetting is one threshold /tune can change.
+type tuneSetting struct {
+	name     string
+	desc     string
+	def      int
+	min, max int
+	field    func(t *state.Tuning) *int
+}
+
+// tuneSettings are the thresholds /tune can change, in the order shown.
+var tuneSettings = []tuneSetting{
+	{"merge", "characters consecutive messages are merged up to", queue.DefaultMergeLen, 200, 12000,
+		func(t *state.Tuning) *int { return &t.MergeLen }},
+	{"split", "characters a long message is split at", queue.DefaultSplitLen, 500, queue.DefaultSplitLen,
+		func(t *state.Tuning) *int { return &t.SplitLen }},
+	{"preview", "output lines shown for Bash and other tool results", 3, 1, 50,
+		func(t *state.Tuning) *int { return &t.PreviewLines }},
+	{"status", "polls without a status line before it is cleared", missThreshold, 1, 20,
+		func(t *state.Tuning) *int { return &t.StatusMisses }},
+}
+
+// handleTuneCommand handles /tune: shows or changes this topic's delivery
+// thresholds — how much output is merged into one message, where long ones
+// split, how many lines tool previews show and how quickly the status
+// message clears.
+func (b *Bot) handleTuneCommand(msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+	threadID := getThreadID(msg)
+	threadIDStr := strconv.Itoa(threadID)
+	t := b.state.GetTuning(threadIDStr)
+
+	args := strings.Fields(strings.ToLower(msg.CommandArguments()))
+	switch {
+	case len(args) == 0:
+		b.reply(chatID, threadID, formatTuning(t))
+		return
+	case len(args) == 1 && args[0] == "reset":
+		b.state.SetTuning(threadIDStr, state.Tuning{})
+		b.saveState()
+		b.reply(chatID, threadID, "Thresholds reset.\n"+formatTuning(state.Tuning{}))
+		return
+	case len(args) != 2:
+		b.reply(chatID, threadID, tuneUsage)
+		return
+	}
+
+	var setting *tuneSetting
+	for i := range tuneSettings {
+		if tuneSettings[i].name == args[0] {
+			setting = &tuneSettings[i]
+		}
+	}
+	if setting == nil {
+		b.reply(chatID, threadID, tuneUsage)
+		return
+	}
+	n := 0
+	if args[1] != "default" {
+		v, err := strconv.Atoi(args[1])
+		if err != nil || v < setting.min || v > setting.max {
+			b.reply(chatID, threadID, fmt.Sprintf("%s must be between %d and %d, or default.", setting.name, setting.min, setting.max))
+			return
+		}
+		if v != setting.def {
+			n = v
+		}
+	}
+	*setting.field(&t) = n
+	b.state.SetTuning(threadIDStr, t)
+	b.saveState()
+	b.reply(chatID, threadID, "Thresholds updated.\n"+formatTuning(t))
+}
+
+// formatTuning lists a topic's thresholds against the defaults.
+func formatTuning(t state.Tuning) string {
+	var sb strings.Builder
+	sb.WriteString("🎛 Thresholds for this topic:")
+	for _, s := range tuneSettings {
+		if v := *s.field(&t); v > 0 {
+			fmt.Fprintf(&sb, "\n%s %d — %s (default %d)", s.name, v, s.desc, s.def)
+		} else {
+			fmt.Fprintf(&sb, "\n%s %d — %s", s.name, s.def, s.desc)
+		}
+	}
+	sb.WriteString("\n\n" + tuneUsage)
+	return sb.String()
+}
+
+// tuneLimits returns a topic's merge and split thresholds for the queue.
+func (b *Bot) tuneLimits(chatID int64, threadID int) queue.Limits {
+	t := b.state.GetTuning(strconv.Itoa(threadID))
+	return queue.Limits{MergeLen: t.MergeLen, SplitLen: t.SplitLen}
+}
+
+// statusMisses returns how many polls without a status line clear a
+// topic's status message.
+func (b *Bot) statusMisses(threadID string) int {
+	if n := b.state.GetTuning(threadID).StatusMisses; n > 0 {
+		return n
+	}
+	return missThreshold
+}
+
+// windowStatusMisses returns the fewest misses any of a window's topics
+// waits for, so the window's state follows the most sensitive one.
+func (b *Bot) windowStatusMisses(users []state.UserThread) int {
+	n := missThreshold
+	for i, ut := range users {
+		if m := b.statusMisses(ut.ThreadID); i == 0 || m < n {
+			n = m
+		}
+	}
+	return n
+}
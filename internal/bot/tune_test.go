@@ -0,0 +1,57 @@
+package bot
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+)
+
+func TestE2E_TuneSplitsAtTopicThreshold(t *testing.T) {
+	h := startE2E(t, "fresh")
+	windowID := h.tmux.AddWindow("api", "/work/api")
+	userID, threadID := strconv.FormatInt(e2eUser, 10), strconv.Itoa(e2eThread)
+	h.bot.state.BindThread(userID, threadID, windowID)
+	h.bot.state.SetGroupChatID(userID, threadID, e2eChat)
+	h.writeSession(t, windowID, "sess-1", "/work/api")
+
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "/tune split 100")
+	h.tg.WaitForText("sendMessage", "split must be between 500")
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "/tune split 500")
+	h.tg.WaitForText("sendMessage", "split 500 — characters a long message is split at (default 3000)")
+	if got := h.bot.state.GetTuning(threadID); got != (state.Tuning{SplitLen: 500}) {
+		t.Errorf("tuning = %+v", got)
+	}
+
+	long := strings.Repeat("A line of build output here\n", 60) + "Build finished"
+	h.appendTranscript(t, "sess-1", `{"type":"assistant","message":{"content":[{"type":"text","text":"`+strings.ReplaceAll(long, "\n", `\n`)+`"}]}}`)
+	h.tg.WaitForText("sendMessage", "Build finished")
+	var parts int
+	for _, c := range h.tg.Calls("sendMessage") {
+		if strings.Contains(c.Params["text"], "build output") {
+			parts++
+		}
+	}
+	if parts < 3 {
+		t.Errorf("1.7k characters split into %d part(s) at 500", parts)
+	}
+
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "/tune reset")
+	h.tg.WaitForText("sendMessage", "Thresholds reset")
+	if got := h.bot.state.GetTuning(threadID); got != (state.Tuning{}) {
+		t.Errorf("tuning after reset = %+v", got)
+	}
+}
+
+func TestWindowStatusMisses(t *testing.T) {
+	b := &Bot{state: state.NewState()}
+	users := []state.UserThread{{UserID: "1", ThreadID: "7"}, {UserID: "1", ThreadID: "8"}}
+	if got := b.windowStatusMisses(users); got != missThreshold {
+		t.Errorf("default = %d, want %d", got, missThreshold)
+	}
+	b.state.SetTuning("8", state.Tuning{StatusMisses: 1})
+	if got := b.windowStatusMisses(users); got != 1 {
+		t.Errorf("with a snappy topic = %d, want 1", got)
+	}
+}
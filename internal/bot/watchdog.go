@@ -0,0 +1,168 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+	"github.com/otaviocarvalho/tramuntana/internal/tmux"
+)
+
+// watchdogThreshold is how many consecutive polls a window's pane must sit at a
+// shell prompt before Claude is considered exited (covers the brief shell
+// phase while a new window starts Claude).
+const watchdogThreshold = 5
+
+// claudeWatch tracks whether the Claude process in a window is still running.
+type claudeWatch struct {
+	shellPolls int  // consecutive polls with a shell in the foreground
+	exited     bool // alert sent; cleared once Claude is back
+}
+
+var (
+	claudeWatches   = make(map[string]*claudeWatch) // windowID → watch
+	claudeWatchesMu sync.Mutex
+)
+
+// observe records one poll's foreground command. Returns true exactly once
+// when the window has sat at a shell for watchdogThreshold polls.
+func (w *claudeWatch) observe(command string) bool {
+	if !tmux.IsShell(command) {
+		w.shellPolls = 0
+		w.exited = false
+		return false
+	}
+	w.shellPolls++
+	if w.exited || w.shellPolls < watchdogThreshold {
+		return false
+	}
+	w.exited = true
+	return true
+}
+
+// claudeExited reports whether the watchdog has flagged a window's Claude process as gone.
+func claudeExited(windowID string) bool {
+	claudeWatchesMu.Lock()
+	defer claudeWatchesMu.Unlock()
+	w, ok := claudeWatches[windowID]
+	return ok && w.exited
+}
+
+// stopClaudeWatch forgets a window's watchdog state.
+func stopClaudeWatch(windowID string) {
+	claudeWatchesMu.Lock()
+	delete(claudeWatches, windowID)
+	claudeWatchesMu.Unlock()
+}
+
+// checkClaudeProcess is called by the status poller for each live bound window
+// with the pane's foreground command. When Claude has exited to a bare shell it
+// alerts the window's topics and either relaunches Claude or offers a Restart button.
+func (b *Bot) checkClaudeProcess(windowID, command string, users []state.UserThread) {
+	claudeWatchesMu.Lock()
+	w, ok := claudeWatches[windowID]
+	if !ok {
+		w = &claudeWatch{}
+		claudeWatches[windowID] = w
+	}
+	alert := w.observe(command)
+	claudeWatchesMu.Unlock()
+	if !alert {
+		return
+	}
+
+	log.Printf("Watchdog: Claude exited in window %s (foreground: %s)", windowID, command)
+	b.feedError(windowID, "Claude exited")
+
+	var text string
+	var keyboard *tgbotapi.InlineKeyboardMarkup
+	if b.config.AutoRestart {
+		if err := b.restartClaude(windowID); err != nil {
+			log.Printf("Watchdog: restarting Claude in %s: %v", windowID, err)
+			b.alertRestartFailed(windowID, err)
+			text = fmt.Sprintf("⚠️ Claude exited and could not be restarted: %v", err)
+		} else {
+			text = "⚠️ Claude exited. Restarted it with --resume."
+		}
+	} else {
+		text = "⚠️ Claude exited — the window is at a shell prompt. Messages will not be forwarded until Claude is restarted."
+		kb := tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("Restart Claude", "wd_restart:"+windowID),
+				tgbotapi.NewInlineKeyboardButtonData("Dismiss", "wd_dismiss"),
+			),
+		)
+		keyboard = &kb
+	}
+
+	for _, ut := range users {
+		chatID, ok := b.state.GetGroupChatID(ut.UserID, ut.ThreadID)
+		if !ok {
+			continue
+		}
+		threadID, _ := strconv.Atoi(ut.ThreadID)
+		if keyboard == nil {
+			b.reply(chatID, threadID, text)
+			continue
+		}
+		if _, err := b.sendMessageWithKeyboard(chatID, threadID, text, *keyboard); err != nil {
+			log.Printf("Error sending watchdog alert: %v", err)
+		}
+	}
+}
+
+// restartClaude relaunches Claude in a window, resuming its last session.
+func (b *Bot) restartClaude(windowID string) error {
+	ws, _ := b.state.GetWindowState(windowID)
+	project := ""
+	for _, ut := range b.state.FindUsersForWindow(windowID) {
+		if p, ok := b.state.GetProject(ut.ThreadID); ok {
+			project = p
+			break
+		}
+	}
+	cmd := claudeLaunchCommand(b.resolveSessionLaunch(ws.CWD, project).Command, "resume", ws.SessionID)
+	if err := tmux.SendKeysWithDelay(b.config.TmuxSessionName, windowID, cmd, 500); err != nil {
+		return err
+	}
+	// Reset so held messages flow again and a failed launch alerts a second time.
+	claudeWatchesMu.Lock()
+	if w, ok := claudeWatches[windowID]; ok {
+		w.shellPolls = 0
+		w.exited = false
+	}
+	claudeWatchesMu.Unlock()
+	return nil
+}
+
+// processWatchdogCallback handles the Restart and Dismiss buttons on watchdog alerts.
+func (b *Bot) processWatchdogCallback(cq *tgbotapi.CallbackQuery) {
+	if cq.Message == nil {
+		return
+	}
+	chatID := cq.Message.Chat.ID
+	messageID := cq.Message.MessageID
+
+	if cq.Data == "wd_dismiss" {
+		b.editMessageText(chatID, messageID, cq.Message.Text)
+		return
+	}
+	windowID, ok := strings.CutPrefix(cq.Data, "wd_restart:")
+	if !ok {
+		return
+	}
+
+	if err := b.restartClaude(windowID); err != nil {
+		log.Printf("Error restarting Claude in %s: %v", windowID, err)
+		b.alertRestartFailed(windowID, err)
+		b.answerCallback(cq.ID, "Failed to restart")
+		return
+	}
+	if err := b.editMessageText(chatID, messageID, cq.Message.Text+"\n\nRestarting Claude with --resume…"); err != nil {
+		log.Printf("Error editing watchdog alert: %v", err)
+	}
+}
@@ -0,0 +1,39 @@
+package bot
+
+import "testing"
+
+func TestClaudeWatch_Observe(t *testing.T) {
+	w := &claudeWatch{}
+	for i := 1; i < watchdogThreshold; i++ {
+		if w.observe("bash") {
+			t.Fatalf("alerted after %d shell polls, want %d", i, watchdogThreshold)
+		}
+	}
+	if !w.observe("bash") {
+		t.Fatal("expected alert at threshold")
+	}
+	if !w.exited {
+		t.Error("exited should be set after alert")
+	}
+	if w.observe("bash") {
+		t.Error("should alert only once while at the shell")
+	}
+
+	if w.observe("claude") {
+		t.Error("claude in foreground should not alert")
+	}
+	if w.exited || w.shellPolls != 0 {
+		t.Errorf("watch not reset: %+v", w)
+	}
+}
+
+func TestClaudeWatch_ShellBlipResets(t *testing.T) {
+	w := &claudeWatch{}
+	for i := 0; i < watchdogThreshold-1; i++ {
+		w.observe("zsh")
+	}
+	w.observe("node")
+	if w.observe("zsh") {
+		t.Error("count should restart after Claude is seen again")
+	}
+}
@@ -0,0 +1,100 @@
+package bot
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/queue"
+)
+
+const (
+	// whyMaxSources caps the transcript entries /why lists for one message.
+	whyMaxSources = 5
+	// whyExcerptLen caps the bytes of each entry's line /why quotes.
+	whyExcerptLen = 300
+)
+
+// handleWhyCommand handles /why, sent as a reply to one of the bot's
+// messages: it tells where the message came from — the session, transcript
+// file, byte range and entry type of every transcript entry it shows.
+func (b *Bot) handleWhyCommand(msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+	threadID := getThreadID(msg)
+	if b.msgQueue == nil {
+		return
+	}
+	// In a topic, a message that replies to nothing replies to the topic itself
+	target := msg.ReplyToMessage
+	if target == nil || target.MessageID == threadID {
+		b.reply(chatID, threadID, "Reply to one of the bot's messages with /why to see where it came from.")
+		return
+	}
+
+	sent, ok := b.msgQueue.FindSent(chatID, threadID, target.MessageID)
+	if !ok {
+		b.reply(chatID, threadID, "No record of that message: it isn't one the bot delivered here since it started, or it has been forgotten or deleted.")
+		return
+	}
+	at := sent.At
+	if loc := b.state.GetUserSettings(strconv.FormatInt(msg.From.ID, 10)).Location(); loc != nil {
+		at = at.In(loc)
+	}
+	b.reply(chatID, threadID, formatWhy(sent, at.Format("2006-01-02 15:04:05 MST"), readExcerpt))
+}
+
+// formatWhy describes a delivered message's provenance. excerpt returns the
+// start of a source's transcript line.
+func formatWhy(sent queue.SentMessage, at string, excerpt func(queue.Source) (string, error)) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "🔎 Message %d: %s", sent.MessageID, sent.Kind)
+	if sent.WindowID != "" {
+		fmt.Fprintf(&b, " for window %s", sent.WindowID)
+	}
+	fmt.Fprintf(&b, ", sent %s\n", at)
+
+	if len(sent.Sources) == 0 {
+		b.WriteString("Not from a transcript: the bot composed it (status line, notification or reply).")
+		return b.String()
+	}
+	for i, src := range sent.Sources {
+		if i == whyMaxSources {
+			fmt.Fprintf(&b, "\n… and %d more entries", len(sent.Sources)-whyMaxSources)
+			break
+		}
+		fmt.Fprintf(&b, "\n%d/%d: %s %s entry\n", i+1, len(sent.Sources), src.EntryType, src.Block)
+		fmt.Fprintf(&b, "Session: %s (%s)\n", src.SessionKey, src.SessionID)
+		fmt.Fprintf(&b, "File: %s\n", src.Path)
+		fmt.Fprintf(&b, "Bytes: %d–%d (%d bytes)\n", src.Start, src.End, src.End-src.Start)
+		if text, err := excerpt(src); err != nil {
+			fmt.Fprintf(&b, "Line unreadable: %v\n", err)
+		} else {
+			b.WriteString(text + "\n")
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// readExcerpt reads the start of a source's line from its transcript.
+func readExcerpt(src queue.Source) (string, error) {
+	f, err := os.Open(src.Path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	n := min(src.End-src.Start, whyExcerptLen)
+	buf := make([]byte, n)
+	read, err := f.ReadAt(buf, src.Start)
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	text := strings.ToValidUTF8(strings.TrimSpace(string(buf[:read])), "")
+	if int64(read) < src.End-src.Start {
+		text += "…"
+	}
+	return text, nil
+}
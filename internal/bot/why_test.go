@@ -0,0 +1,59 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/otaviocarvalho/tramuntana/internal/queue"
+)
+
+func TestFormatWhy(t *testing.T) {
+	src := queue.Source{SessionKey: "s:@1", SessionID: "abc", Path: "/t/abc.jsonl", Start: 100, End: 250, EntryType: "user", Block: "tool_result"}
+	sent := queue.SentMessage{MessageID: 7, Kind: "tool_result", WindowID: "@1", Sources: []queue.Source{src}}
+	got := formatWhy(sent, "12:00", func(queue.Source) (string, error) { return `{"type":"user"}`, nil })
+	for _, want := range []string{"Message 7: tool_result for window @1, sent 12:00", "1/1: user tool_result entry", "Session: s:@1 (abc)", "File: /t/abc.jsonl", "Bytes: 100–250 (150 bytes)", `{"type":"user"}`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("formatWhy missing %q in:\n%s", want, got)
+		}
+	}
+
+	sent.Sources = make([]queue.Source, whyMaxSources+2)
+	got = formatWhy(sent, "12:00", func(queue.Source) (string, error) { return "", errors.New("gone") })
+	if !strings.Contains(got, "Line unreadable: gone") || !strings.Contains(got, "… and 2 more entries") {
+		t.Errorf("formatWhy with many sources:\n%s", got)
+	}
+
+	got = formatWhy(queue.SentMessage{MessageID: 8, Kind: "status_update"}, "12:00", nil)
+	if !strings.Contains(got, "Not from a transcript") {
+		t.Errorf("formatWhy without sources:\n%s", got)
+	}
+}
+
+func TestE2E_WhyShowsTranscriptSource(t *testing.T) {
+	h := startE2E(t, "fresh")
+	windowID := h.tmux.AddWindow("api", "/work/api")
+	userID, threadID := strconv.FormatInt(e2eUser, 10), strconv.Itoa(e2eThread)
+	h.bot.state.BindThread(userID, threadID, windowID)
+	h.bot.state.SetGroupChatID(userID, threadID, e2eChat)
+
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "/why")
+	h.tg.WaitForText("sendMessage", "Reply to one of the bot's messages")
+
+	first := `{"type":"user","message":{"content":"hello"}}`
+	second := `{"type":"assistant","message":{"content":[{"type":"text","text":"Answer here"}]}}`
+	h.writeSession(t, windowID, "sess-1", "/work/api")
+	h.appendTranscript(t, "sess-1", first, second)
+	answer := h.tg.WaitForText("sendMessage", "Answer here")
+
+	h.tg.PushReply(e2eChat, e2eThread, e2eUser, answer.MessageID, "/why")
+	start := len(first) + 1
+	got := h.tg.WaitForText("sendMessage", fmt.Sprintf("Bytes: %d–%d", start, start+len(second)+1))
+	for _, want := range []string{"assistant text entry", "sess-1.jsonl", `"text":"Answer here"`} {
+		if !strings.Contains(got.Params["text"], want) {
+			t.Errorf("/why reply missing %q:\n%s", want, got.Params["text"])
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+	"github.com/otaviocarvalho/tramuntana/internal/tmux"
+)
+
+// nameRefreshInterval is how often TRAMUNTANA_WINDOW_NAME is re-rendered for a
+// window, picking up branch switches.
+const nameRefreshInterval = time.Minute
+
+// maxTopicNameLen is Telegram's limit on forum topic names.
+const maxTopicNameLen = 128
+
+// sessionState is a window's activity as shown in its topics' titles.
+type sessionState int
+
+const (
+	stateUnknown    sessionState = iota
+	stateIdle                    // waiting for a prompt
+	stateWorking                 // status line showing
+	stateNeedsInput              // interactive prompt showing
+	stateDead                    // window gone
+)
+
+// emoji is the topic title prefix for a state.
+func (s sessionState) emoji() string {
+	switch s {
+	case stateIdle:
+		return "🟢"
+	case stateWorking:
+		return "🔵"
+	case stateNeedsInput:
+		return "🔴"
+	case stateDead:
+		return "⚫"
+	}
+	return ""
+}
+
+// windowTitle is a window's rendered name and what its topics were last given.
+type windowTitle struct {
+	name     string
+	state    sessionState
+	rendered time.Time
+	topics   map[string]topicLook // "chat/thread" → last sent
+}
+
+// topicLook is a topic's title and icon as last set.
+type topicLook struct {
+	title  string
+	iconID string
+}
+
+// renderWindowName renders the name template, trimmed to fit a topic name
+// with its state prefix.
+func renderWindowName(tmpl string, vars launchVars) (string, error) {
+	name, err := renderTemplate(tmpl, vars)
+	if err != nil {
+		return "", err
+	}
+	name = strings.Join(strings.Fields(name), " ")
+	return truncateName(name, maxTopicNameLen-2), nil // room for the state prefix
+}
+
+// topicTitle is the forum topic name for a window name and state.
+func topicTitle(name string, st sessionState) string {
+	if e := st.emoji(); e != "" {
+		return e + " " + name
+	}
+	return name
+}
+
+// windowProject returns the Minuano project bound to any of a window's topics.
+func (b *Bot) windowProject(users []state.UserThread) string {
+	for _, ut := range users {
+		if p, ok := b.state.GetProject(ut.ThreadID); ok {
+			return p
+		}
+	}
+	return ""
+}
+
+// sessionStateFor classifies a polled pane. Between a status line vanishing
+// and missLimit misses the previous state is kept.
+func (sp *StatusPoller) sessionStateFor(windowID string, interactive, hasStatus bool, missLimit int) sessionState {
+	switch {
+	case interactive:
+		return stateNeedsInput
+	case hasStatus:
+		return stateWorking
+	}
+	sp.mu.RLock()
+	misses := sp.missCount[windowID]
+	sp.mu.RUnlock()
+	if misses >= missLimit {
+		return stateIdle
+	}
+	if t, ok := sp.titles[windowID]; ok && t.state != stateUnknown {
+		return t.state
+	}
+	return stateIdle
+}
+
+// syncTopics keeps a window's tmux name and its topics' titles and icons in
+// line with TRAMUNTANA_WINDOW_NAME, TRAMUNTANA_TOPIC_ICONS, the session state
+// and, with TRAMUNTANA_NOW_SUMMARY=title, the last turn's summary. Only
+// changes cost API calls; topics in flood-banned chats catch up on a later
+// poll.
+func (sp *StatusPoller) syncTopics(windowID string, st sessionState, users []state.UserThread) {
+	b := sp.bot
+	t, ok := sp.titles[windowID]
+	if !ok {
+		t = &windowTitle{topics: make(map[string]topicLook)}
+		sp.titles[windowID] = t
+	}
+	t.state = st
+
+	if b.config.WindowNameTemplate != "" && time.Since(t.rendered) >= nameRefreshInterval {
+		b.refreshWindowName(windowID, t, users)
+	}
+	want := topicLook{}
+	name := t.name
+	if summary := turnSummary(windowID); summary != "" && b.config.NowSummary == "title" {
+		if name == "" {
+			name, _ = b.state.GetWindowDisplayName(windowID)
+		}
+		name = truncateName(name+" · "+summary, maxTopicNameLen-2) // room for the state prefix
+	}
+	if name != "" {
+		want.title = topicTitle(name, st)
+	}
+	want.iconID, _ = b.topicIconID(st)
+	if want == (topicLook{}) {
+		return
+	}
+
+	for _, ut := range users {
+		chatID, ok := b.state.GetGroupChatID(ut.UserID, ut.ThreadID)
+		if !ok || chatID > 0 {
+			continue // private chats have no topics
+		}
+		key := fmt.Sprintf("%d/%s", chatID, ut.ThreadID)
+		last, seen := t.topics[key]
+		if (seen && last == want) || (sp.queue != nil && sp.queue.IsFlooded(chatID)) {
+			continue
+		}
+		t.topics[key] = want
+		threadID, _ := strconv.Atoi(ut.ThreadID)
+		b.editTopicLook(chatID, threadID, last, want)
+	}
+}
+
+// refreshWindowName re-renders a window's name, renaming the tmux window when
+// it changed.
+func (b *Bot) refreshWindowName(windowID string, t *windowTitle, users []state.UserThread) {
+	ws, ok := b.state.GetWindowState(windowID)
+	if !ok || ws.CWD == "" {
+		return // not yet reported by the hook
+	}
+	t.rendered = time.Now()
+	name, err := renderWindowName(b.config.WindowNameTemplate, newLaunchVars(ws.CWD, b.windowProject(users)))
+	if err != nil {
+		log.Printf("Error rendering TRAMUNTANA_WINDOW_NAME for %s: %v", windowID, err)
+		return
+	}
+	if name == "" || name == t.name {
+		return
+	}
+	t.name = name
+	if err := tmux.RenameWindow(b.config.TmuxSessionName, windowID, name); err != nil {
+		log.Printf("Error renaming window %s: %v", windowID, err)
+	}
+	b.state.SetWindowDisplayName(windowID, name)
+	b.saveState()
+}
+
+// editTopicLook sends the parts of want that differ from last in one edit.
+func (b *Bot) editTopicLook(chatID int64, threadID int, last, want topicLook) {
+	var name string
+	if want.title != last.title {
+		name = want.title
+	}
+	var icon *string
+	if want.iconID != "" && want.iconID != last.iconID {
+		icon = &want.iconID
+	}
+	if name != "" || icon != nil {
+		b.editForumTopic(chatID, threadID, name, icon)
+	}
+}
+
+// markTopicsDead shows a dead window's topics as such. name is the window's
+// display name.
+func (b *Bot) markTopicsDead(name string, topics []topicTarget) {
+	var want topicLook
+	if b.config.WindowNameTemplate != "" && name != "" {
+		want.title = topicTitle(name, stateDead)
+	}
+	want.iconID, _ = b.topicIconID(stateDead)
+	for _, t := range topics {
+		if t.chatID < 0 {
+			b.editTopicLook(t.chatID, t.threadID, topicLook{}, want)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestRenderWindowName(t *testing.T) {
+	vars := launchVars{Dir: "/work/api", Project: "api", Branch: "feat/login"}
+	got, err := renderWindowName("{{.Project}}·{{.Branch}}", vars)
+	if err != nil || got != "api·feat/login" {
+		t.Errorf("got %q, %v", got, err)
+	}
+
+	long, _ := renderWindowName(strings.Repeat("é", 200), vars)
+	if n := utf8.RuneCountInString(long); n != maxTopicNameLen-2 || !utf8.ValidString(long) {
+		t.Errorf("long name has %d runes (valid=%v), want %d", n, utf8.ValidString(long), maxTopicNameLen-2)
+	}
+}
+
+func TestTopicTitle(t *testing.T) {
+	tests := []struct {
+		state sessionState
+		want  string
+	}{
+		{stateUnknown, "api"},
+		{stateIdle, "🟢 api"},
+		{stateWorking, "🔵 api"},
+		{stateNeedsInput, "🔴 api"},
+	}
+	for _, tt := range tests {
+		if got := topicTitle("api", tt.state); got != tt.want {
+			t.Errorf("topicTitle(%d) = %q, want %q", tt.state, got, tt.want)
+		}
+	}
+}
@@ -31,13 +31,13 @@ func (b *Bot) showWindowPicker(chatID int64, threadID int, userID int64, windows
 
 	b.mu.Lock()
 	b.windowCache[userID] = windows
-	b.windowPickerStates[userID] = &windowPickerState{
+	b.windowPickerStates.set(wizardKey{UserID: userID, ChatID: chatID, ThreadID: threadID}, &windowPickerState{
 		Windows:     windows,
 		PendingText: pendingText,
 		MessageID:   msg.MessageID,
 		ChatID:      chatID,
 		ThreadID:    threadID,
-	}
+	})
 	b.mu.Unlock()
 }
 
@@ -81,19 +81,12 @@ func (b *Bot) processWindowCallback(cq *tgbotapi.CallbackQuery) {
 
 	log.Printf("DEBUG: processWindowCallback user=%d data=%q", userID, data)
 
-	b.mu.RLock()
-	wps, ok := b.windowPickerStates[userID]
-	b.mu.RUnlock()
+	b.mu.Lock()
+	wps, ok := b.windowPickerStates.get(callbackWizardKey(cq))
+	b.mu.Unlock()
 
 	if !ok {
-		log.Printf("DEBUG: no windowPickerState for user=%d", userID)
-		return
-	}
-
-	// Verify topic match
-	threadID := getThreadID(cq.Message)
-	if threadID != wps.ThreadID {
-		log.Printf("DEBUG: threadID mismatch: callback=%d picker=%d", threadID, wps.ThreadID)
+		log.Printf("DEBUG: no windowPickerState for user=%d in this topic", userID)
 		return
 	}
 
@@ -122,7 +115,7 @@ func (b *Bot) handleWinBind(cq *tgbotapi.CallbackQuery, wps *windowPickerState,
 
 	// Clear picker state
 	b.mu.Lock()
-	delete(b.windowPickerStates, userID)
+	b.windowPickerStates.remove(wizardKey{UserID: userID, ChatID: wps.ChatID, ThreadID: wps.ThreadID})
 	delete(b.windowCache, userID)
 	b.mu.Unlock()
 
@@ -155,7 +148,7 @@ func (b *Bot) handleWinNew(cq *tgbotapi.CallbackQuery, wps *windowPickerState, u
 
 	// Clear picker state
 	b.mu.Lock()
-	delete(b.windowPickerStates, userID)
+	b.windowPickerStates.remove(wizardKey{UserID: userID, ChatID: wps.ChatID, ThreadID: wps.ThreadID})
 	delete(b.windowCache, userID)
 	b.mu.Unlock()
 
@@ -168,7 +161,7 @@ func (b *Bot) handleWinNew(cq *tgbotapi.CallbackQuery, wps *windowPickerState, u
 
 func (b *Bot) handleWinCancel(cq *tgbotapi.CallbackQuery, wps *windowPickerState, userID int64) {
 	b.mu.Lock()
-	delete(b.windowPickerStates, userID)
+	b.windowPickerStates.remove(wizardKey{UserID: userID, ChatID: wps.ChatID, ThreadID: wps.ThreadID})
 	delete(b.windowCache, userID)
 	b.mu.Unlock()
 
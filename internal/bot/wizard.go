@@ -0,0 +1,145 @@
+package bot
+
+import (
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// wizardTTL is how long a browser, picker or wizard keeps answering its
+// buttons after it was last used.
+const wizardTTL = 30 * time.Minute
+
+// wizardKey identifies one user's browser, picker or wizard in one topic, so
+// the same user can have one open in each topic.
+type wizardKey struct {
+	UserID   int64
+	ChatID   int64
+	ThreadID int
+}
+
+// messageWizardKey returns the wizard key of a message's sender and topic.
+func messageWizardKey(msg *tgbotapi.Message) wizardKey {
+	return wizardKey{UserID: msg.From.ID, ChatID: msg.Chat.ID, ThreadID: getThreadID(msg)}
+}
+
+// callbackWizardKey returns the wizard key of the user who pressed a button
+// and the topic the button's message is in.
+func callbackWizardKey(cq *tgbotapi.CallbackQuery) wizardKey {
+	return wizardKey{UserID: cq.From.ID, ChatID: cq.Message.Chat.ID, ThreadID: getThreadID(cq.Message)}
+}
+
+// wizardStates holds one kind of browser, picker or wizard state per user and
+// topic. Entries left idle for wizardTTL expire. The zero value is ready to
+// use; callers hold Bot.mu for writing, since get marks entries used.
+type wizardStates[T any] struct {
+	entries map[wizardKey]wizardEntry[T]
+}
+
+type wizardEntry[T any] struct {
+	state *T
+	used  time.Time
+}
+
+// get returns k's state unless it is missing or expired, and marks it used.
+func (w *wizardStates[T]) get(k wizardKey) (*T, bool) {
+	e, ok := w.entries[k]
+	if !ok {
+		return nil, false
+	}
+	if time.Since(e.used) > wizardTTL {
+		delete(w.entries, k)
+		return nil, false
+	}
+	e.used = time.Now()
+	w.entries[k] = e
+	return e.state, true
+}
+
+// set stores k's state, replacing any earlier one, and drops expired states.
+func (w *wizardStates[T]) set(k wizardKey, s *T) {
+	if w.entries == nil {
+		w.entries = make(map[wizardKey]wizardEntry[T])
+	}
+	for key, e := range w.entries {
+		if time.Since(e.used) > wizardTTL {
+			delete(w.entries, key)
+		}
+	}
+	w.entries[k] = wizardEntry[T]{state: s, used: time.Now()}
+}
+
+// remove drops k's state.
+func (w *wizardStates[T]) remove(k wizardKey) {
+	delete(w.entries, k)
+}
+
+// shownByOther reports whether another user's live state in k's topic is the
+// one showing messageID, as told by shows.
+func (w *wizardStates[T]) shownByOther(k wizardKey, messageID int, shows func(*T) int) bool {
+	for key, e := range w.entries {
+		if key.ChatID != k.ChatID || key.ThreadID != k.ThreadID || key.UserID == k.UserID {
+			continue
+		}
+		if time.Since(e.used) <= wizardTTL && shows(e.state) == messageID {
+			return true
+		}
+	}
+	return false
+}
+
+// staleWizardNotice returns why a browser, picker or wizard button can no
+// longer work for the user pressing it — its state expired, was finished,
+// was replaced by a newer one in the same topic, or belongs to another user —
+// or "" if it still can. Task picker selections carry what they need in
+// their data and always work.
+func (b *Bot) staleWizardNotice(cq *tgbotapi.CallbackQuery) string {
+	if cq.Message == nil {
+		return ""
+	}
+	k := callbackWizardKey(cq)
+	messageID := cq.Message.MessageID
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var what, stale string
+	var mine, others bool
+	switch data := cq.Data; {
+	case strings.HasPrefix(data, "win_"):
+		what, stale = "window picker", "This window picker expired or was replaced. Send a message in this topic to open a new one."
+		wps, ok := b.windowPickerStates.get(k)
+		mine = ok && wps.MessageID == messageID
+		others = b.windowPickerStates.shownByOther(k, messageID, func(s *windowPickerState) int { return s.MessageID })
+	case strings.HasPrefix(data, "dir_"):
+		what, stale = "directory browser", "This directory browser expired or was replaced. Send a message in this topic to open a new one."
+		bs, ok := b.browseStates.get(k)
+		mine = ok && bs.MessageID == messageID
+		others = b.browseStates.shownByOther(k, messageID, func(s *BrowseState) int { return s.MessageID })
+	case strings.HasPrefix(data, "get_"):
+		what, stale = "file browser", "This file browser expired or was replaced. Use /get to open a new one."
+		fs, ok := b.fileBrowseStates.get(k)
+		mine = ok && fs.MessageID == messageID
+		others = b.fileBrowseStates.shownByOther(k, messageID, func(s *FileBrowseState) int { return s.MessageID })
+	case strings.HasPrefix(data, "task_") && !strings.HasPrefix(data, "task_pick:"):
+		what, stale = "task wizard", "This task wizard expired or was replaced. Use /p_add to start again."
+		ats, ok := b.addTaskStates.get(k)
+		mine = ok && ats.MessageID == messageID
+		others = b.addTaskStates.shownByOther(k, messageID, func(s *addTaskState) int { return s.MessageID })
+	case data == "tpick_cancel":
+		what, stale = "task list", "This task list expired or was replaced; there is nothing to cancel."
+		tps, ok := b.taskPickerStates.get(k)
+		mine = ok && tps.MessageID == messageID
+		others = b.taskPickerStates.shownByOther(k, messageID, func(s *taskPickerState) int { return s.MessageID })
+	default:
+		return ""
+	}
+	switch {
+	case mine:
+		return ""
+	case others:
+		return "This " + what + " belongs to another user; only they can use its buttons."
+	default:
+		return stale
+	}
+}
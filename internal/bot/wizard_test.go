@@ -0,0 +1,79 @@
+package bot
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/otaviocarvalho/tramuntana/internal/testharness"
+)
+
+func TestWizardStates_Expire(t *testing.T) {
+	var w wizardStates[BrowseState]
+	a := wizardKey{UserID: 100, ChatID: -1001, ThreadID: 1}
+	b := wizardKey{UserID: 100, ChatID: -1001, ThreadID: 2}
+	w.set(a, &BrowseState{MessageID: 1})
+	w.set(b, &BrowseState{MessageID: 2})
+
+	if s, ok := w.get(a); !ok || s.MessageID != 1 {
+		t.Fatalf("get(a) = %+v, %v", s, ok)
+	}
+	if s, ok := w.get(b); !ok || s.MessageID != 2 {
+		t.Fatalf("get(b) = %+v, %v", s, ok)
+	}
+
+	e := w.entries[a]
+	e.used = time.Now().Add(-wizardTTL - time.Minute)
+	w.entries[a] = e
+	if _, ok := w.get(a); ok {
+		t.Error("expired state still returned")
+	}
+	if _, ok := w.entries[a]; ok {
+		t.Error("expired state not dropped")
+	}
+	w.remove(b)
+	if _, ok := w.get(b); ok {
+		t.Error("removed state still returned")
+	}
+}
+
+func TestE2E_BrowsersPerTopicAndStaleButtons(t *testing.T) {
+	h := startE2E(t, "fresh")
+	const otherThread, otherUser = e2eThread + 1, e2eUser + 1
+	h.cfg.AllowedUsers = append(h.cfg.AllowedUsers, otherUser)
+	inThread := func(thread int, text string) func(testharness.Call) bool {
+		return func(c testharness.Call) bool {
+			return c.Params["message_thread_id"] == strconv.Itoa(thread) && strings.Contains(c.Params["text"], text)
+		}
+	}
+	edited := func(messageID int, text string) func(testharness.Call) bool {
+		return func(c testharness.Call) bool {
+			return c.Params["message_id"] == strconv.Itoa(messageID) && c.Params["text"] == text
+		}
+	}
+
+	// A browser in another topic no longer replaces the first one
+	h.tg.PushMessage(e2eChat, e2eThread, e2eUser, "/c_get")
+	first := h.tg.WaitFor("sendMessage", inThread(e2eThread, "Browse files"))
+	h.tg.PushMessage(e2eChat, otherThread, e2eUser, "/c_get")
+	second := h.tg.WaitFor("sendMessage", inThread(otherThread, "Browse files"))
+
+	h.tg.PushCallback(e2eChat, e2eThread, e2eUser, first.MessageID, "get_cancel")
+	h.tg.WaitFor("editMessageText", edited(first.MessageID, "Cancelled."))
+
+	// A finished browser's buttons explain why they no longer work
+	h.tg.PushCallback(e2eChat, e2eThread, e2eUser, first.MessageID, "get_cancel")
+	h.tg.WaitFor("answerCallbackQuery", func(c testharness.Call) bool {
+		return c.Params["show_alert"] == "true" && strings.Contains(c.Params["text"], "file browser expired or was replaced")
+	})
+
+	// Another user's live browser is theirs, not stale
+	h.tg.PushCallback(e2eChat, otherThread, otherUser, second.MessageID, "get_cancel")
+	h.tg.WaitFor("answerCallbackQuery", func(c testharness.Call) bool {
+		return c.Params["show_alert"] == "true" && strings.Contains(c.Params["text"], "file browser belongs to another user")
+	})
+
+	h.tg.PushCallback(e2eChat, otherThread, e2eUser, second.MessageID, "get_cancel")
+	h.tg.WaitFor("editMessageText", edited(second.MessageID, "Cancelled."))
+}
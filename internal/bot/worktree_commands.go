@@ -6,6 +6,8 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/otaviocarvalho/tramuntana/internal/git"
@@ -102,9 +104,57 @@ func (b *Bot) executePickwTask(chatID int64, threadID int, userID int64, taskID
 		return
 	}
 
+	b.usage.RecordTaskPicked(windowID, taskID)
+	startTaskLink(windowID, taskID, worktreeDir, baseBranch)
+	startPickw(windowID, pickwRun{chatID: chatID, threadID: threadID, taskID: taskID, branch: branch})
 	b.reply(chatID, threadID, fmt.Sprintf("Working on task %s in worktree (branch: %s)", taskID, branch))
 }
 
+// pickwRun is a /t_pickw task awaiting its completion summary.
+type pickwRun struct {
+	chatID   int64
+	threadID int
+	taskID   string
+	branch   string
+}
+
+var (
+	pickwRuns   = make(map[string]pickwRun) // windowID → run
+	pickwRunsMu sync.Mutex
+)
+
+// startPickw tracks a window's /t_pickw task until its turn completes.
+func startPickw(windowID string, run pickwRun) {
+	pickwRunsMu.Lock()
+	defer pickwRunsMu.Unlock()
+	pickwRuns[windowID] = run
+}
+
+// stopPickw stops tracking a window's /t_pickw task and returns it, if any.
+func stopPickw(windowID string) (pickwRun, bool) {
+	pickwRunsMu.Lock()
+	defer pickwRunsMu.Unlock()
+	run, ok := pickwRuns[windowID]
+	delete(pickwRuns, windowID)
+	return run, ok
+}
+
+// finishPickw posts the summary of a /t_pickw task whose turn completed.
+func (b *Bot) finishPickw(windowID string, elapsed time.Duration) {
+	run, ok := stopPickw(windowID)
+	if !ok {
+		return
+	}
+	text := fmt.Sprintf("Task %s: turn finished after %s in worktree (branch: %s).\nReview, then /t_merge %s to merge.",
+		run.taskID, elapsed.Round(time.Second), run.branch, run.branch)
+	sent, err := b.sendMessageInThread(run.chatID, run.threadID, text)
+	if err != nil {
+		log.Printf("Error sending pickw summary: %v", err)
+		return
+	}
+	b.pinMessage(run.chatID, run.threadID, sent.MessageID, pinPickw)
+}
+
 // getRepoRoot returns the git repo root for the current window's CWD.
 // If the CWD itself is not a git repo, it tries CWD/<project> as a fallback.
 func (b *Bot) getRepoRoot(userIDStr, threadIDStr string) (string, error) {
@@ -116,21 +166,22 @@ func (b *Bot) getRepoRoot(userIDStr, threadIDStr string) (string, error) {
 	if !ok || ws.CWD == "" {
 		return "", fmt.Errorf("no CWD known for current session")
 	}
+	dir := ws.Dir()
 
 	// Try CWD directly
-	root, err := git.RepoRoot(ws.CWD)
+	root, err := git.RepoRoot(dir)
 	if err == nil {
 		return root, nil
 	}
 
 	// Fallback: try CWD/<project> (e.g. /home/user/code/terminal-game)
 	if project, ok := b.state.GetProject(threadIDStr); ok {
-		projectDir := filepath.Join(ws.CWD, project)
+		projectDir := filepath.Join(dir, project)
 		if root, err := git.RepoRoot(projectDir); err == nil {
 			return root, nil
 		}
 	}
 
-	return "", fmt.Errorf("git rev-parse --show-toplevel in %s: not a git repository", ws.CWD)
+	return "", fmt.Errorf("git rev-parse --show-toplevel in %s: not a git repository", dir)
 }
 
@@ -0,0 +1,352 @@
+package bot
+
+import (
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/git"
+)
+
+// worktreeStatus describes one /t_pickw worktree under .minuano/worktrees.
+type worktreeStatus struct {
+	Path         string
+	Branch       string
+	Size         int64
+	Age          time.Duration // since the worktree was created
+	Merged       bool          // branch's changes are all in the base branch
+	LocalCommits int           // commits on the branch found neither in base nor on a remote
+	Dirty        bool          // uncommitted changes or untracked files
+	InUse        bool          // a bound session's directory is inside it
+	CheckErr     error         // a safety check could not be run
+}
+
+// removable reports whether the worktree and its branch can be deleted
+// without losing work: merged, clean, not in use, nothing only it holds, and
+// at least minAge old.
+func (w worktreeStatus) removable(minAge time.Duration) bool {
+	return w.CheckErr == nil && w.Branch != "" && w.Merged && !w.Dirty && !w.InUse &&
+		w.LocalCommits == 0 && w.Age >= minAge
+}
+
+// mainRepoRoot returns the main working tree of the repository dir is in, so
+// that a session inside a worktree reports on all of its siblings.
+func mainRepoRoot(dir string) (string, error) {
+	worktrees, err := git.WorktreeList(dir)
+	if err != nil {
+		return "", err
+	}
+	if len(worktrees) == 0 {
+		return "", fmt.Errorf("no worktrees listed for %s", dir)
+	}
+	return worktrees[0].Path, nil
+}
+
+// scanWorktrees inspects the worktrees under repoRoot/.minuano/worktrees,
+// checking their branches against base. Directories in inUse (and anything
+// below them) mark the worktree holding them as in use.
+func scanWorktrees(repoRoot, base string, inUse []string, now time.Time) ([]worktreeStatus, error) {
+	all, err := git.WorktreeList(repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	prefix := filepath.Join(repoRoot, ".minuano", "worktrees") + string(filepath.Separator)
+
+	var statuses []worktreeStatus
+	for _, wt := range all {
+		if !strings.HasPrefix(wt.Path, prefix) {
+			continue
+		}
+		w := worktreeStatus{Path: wt.Path, Branch: wt.Branch, Size: dirSize(wt.Path)}
+		if info, err := os.Stat(filepath.Join(wt.Path, ".git")); err == nil {
+			w.Age = now.Sub(info.ModTime())
+		}
+		for _, dir := range inUse {
+			if dir == wt.Path || strings.HasPrefix(dir, wt.Path+string(filepath.Separator)) {
+				w.InUse = true
+			}
+		}
+		if w.Dirty, err = git.IsDirty(wt.Path); err != nil {
+			w.CheckErr = err
+		}
+		if wt.Branch != "" {
+			w.Merged = git.IsMerged(repoRoot, wt.Branch, base)
+			if w.LocalCommits, err = git.LocalCommits(repoRoot, wt.Branch, base); err != nil {
+				w.CheckErr = err
+			}
+		}
+		statuses = append(statuses, w)
+	}
+	return statuses, nil
+}
+
+// dirSize sums the sizes of the regular files under dir, skipping what it
+// can't read.
+func dirSize(dir string) int64 {
+	var total int64
+	filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			total += info.Size()
+		}
+		return nil
+	})
+	return total
+}
+
+// formatAge renders a worktree's age in hours or days.
+func formatAge(d time.Duration) string {
+	switch {
+	case d < time.Hour:
+		return "<1h"
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	}
+}
+
+// formatWorktrees renders the /worktrees report and, when any worktree is
+// removable at minAge, the cleanup offer. Returns how many are removable.
+func formatWorktrees(repoRoot, base string, statuses []worktreeStatus, minAge time.Duration) (string, int) {
+	if len(statuses) == 0 {
+		return fmt.Sprintf("No worktrees under %s.", shortenPath(filepath.Join(repoRoot, ".minuano", "worktrees"))), 0
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "🌳 Worktrees in %s (base %s):\n", shortenPath(repoRoot), base)
+
+	var total, freed int64
+	n := 0
+	for _, w := range statuses {
+		name := w.Branch
+		if name == "" {
+			name = filepath.Base(w.Path) + " (detached)"
+		}
+		notes := []string{formatFileSize(w.Size), formatAge(w.Age) + " old"}
+		if w.Merged {
+			notes = append(notes, "merged")
+		}
+		if w.LocalCommits > 0 {
+			notes = append(notes, fmt.Sprintf("%d unpushed commit(s)", w.LocalCommits))
+		}
+		if w.Dirty {
+			notes = append(notes, "uncommitted changes")
+		}
+		if w.InUse {
+			notes = append(notes, "in use")
+		}
+		if w.CheckErr != nil {
+			notes = append(notes, "⚠️ check failed")
+		}
+		fmt.Fprintf(&b, "\n• %s — %s", name, strings.Join(notes, ", "))
+
+		total += w.Size
+		if w.removable(minAge) {
+			n++
+			freed += w.Size
+		}
+	}
+	fmt.Fprintf(&b, "\n\nTotal: %s in %d worktree(s).", formatFileSize(total), len(statuses))
+	if n > 0 {
+		fmt.Fprintf(&b, "\n%d merged worktree(s) older than %s can be removed with their branches, freeing %s.",
+			n, formatAge(minAge), formatFileSize(freed))
+	}
+	return b.String(), n
+}
+
+// worktreeCleanupKeyboard offers removing the worktrees removable at minAge.
+func worktreeCleanupKeyboard(n int, minAge time.Duration) tgbotapi.InlineKeyboardMarkup {
+	days := int(minAge.Hours() / 24)
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("🗑 Remove %d", n), "wt_clean:"+strconv.Itoa(days)),
+			tgbotapi.NewInlineKeyboardButtonData("Keep", "wt_keep"),
+		),
+	)
+}
+
+// worktreeReport scans repoRoot's worktrees against its current branch and
+// renders the report. Returns the number removable at minAge.
+func (b *Bot) worktreeReport(repoRoot string, minAge time.Duration) (string, int, error) {
+	base, err := git.CurrentBranch(repoRoot)
+	if err != nil {
+		return "", 0, err
+	}
+	statuses, err := scanWorktrees(repoRoot, base, b.boundDirs(), time.Now())
+	if err != nil {
+		return "", 0, err
+	}
+	text, n := formatWorktrees(repoRoot, base, statuses, minAge)
+	return text, n, nil
+}
+
+// boundDirs returns the working directories of bound sessions.
+func (b *Bot) boundDirs() []string {
+	var dirs []string
+	for windowID := range b.state.AllBoundWindowIDs() {
+		if ws, ok := b.state.GetWindowState(windowID); ok && ws.CWD != "" {
+			dirs = append(dirs, ws.CWD)
+		}
+	}
+	return dirs
+}
+
+// handleWorktreesCommand reports disk usage and age of the repository's
+// worktrees. /worktrees [days] offers removing merged ones at least that old,
+// by default TRAMUNTANA_WORKTREE_CLEANUP_DAYS.
+func (b *Bot) handleWorktreesCommand(msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+	threadID := getThreadID(msg)
+
+	minAge := b.config.WorktreeCleanupAge
+	if arg := strings.TrimSpace(msg.CommandArguments()); arg != "" {
+		days, err := strconv.Atoi(arg)
+		if err != nil || days < 0 {
+			b.reply(chatID, threadID, "Usage: /worktrees [days]")
+			return
+		}
+		minAge = time.Duration(days) * 24 * time.Hour
+	}
+
+	root, err := b.getMergeRepoRoot(msg)
+	if err == nil {
+		root, err = mainRepoRoot(root)
+	}
+	if err != nil {
+		b.reply(chatID, threadID, fmt.Sprintf("Error: %v", err))
+		return
+	}
+	text, n, err := b.worktreeReport(root, minAge)
+	if err != nil {
+		b.reply(chatID, threadID, fmt.Sprintf("Error: %v", err))
+		return
+	}
+	if n == 0 {
+		b.reply(chatID, threadID, text)
+		return
+	}
+	b.sendMessageWithKeyboard(chatID, threadID, text, worktreeCleanupKeyboard(n, minAge))
+}
+
+// processWorktreeCallback handles the cleanup offer's buttons. Removal rescans
+// and re-runs the safety checks, so nothing changed since the offer is lost.
+func (b *Bot) processWorktreeCallback(cq *tgbotapi.CallbackQuery) {
+	if cq.Message == nil {
+		return
+	}
+	chatID := cq.Message.Chat.ID
+	messageID := cq.Message.MessageID
+
+	if cq.Data == "wt_keep" {
+		b.editMessageText(chatID, messageID, "Kept all worktrees.")
+		return
+	}
+	days, err := strconv.Atoi(strings.TrimPrefix(cq.Data, "wt_clean:"))
+	if err != nil {
+		return
+	}
+	minAge := time.Duration(days) * 24 * time.Hour
+
+	root, err := b.getMergeRepoRoot(syntheticMessage(cq))
+	if err == nil {
+		root, err = mainRepoRoot(root)
+	}
+	var base string
+	if err == nil {
+		base, err = git.CurrentBranch(root)
+	}
+	var statuses []worktreeStatus
+	if err == nil {
+		statuses, err = scanWorktrees(root, base, b.boundDirs(), time.Now())
+	}
+	if err != nil {
+		b.editMessageText(chatID, messageID, fmt.Sprintf("Error: %v", err))
+		return
+	}
+
+	lines := []string{"🧹 Worktree cleanup:"}
+	var freed int64
+	for _, w := range statuses {
+		if !w.removable(minAge) {
+			continue
+		}
+		if err := b.removeWorktree(root, w); err != nil {
+			log.Printf("Error cleaning up worktree %s: %v", w.Path, err)
+			lines = append(lines, fmt.Sprintf("❌ %s: %v", w.Branch, err))
+			continue
+		}
+		freed += w.Size
+		lines = append(lines, "✅ "+w.Branch)
+	}
+	if len(lines) == 1 {
+		lines = append(lines, "Nothing to remove any more.")
+	} else {
+		lines = append(lines, "", "Freed "+formatFileSize(freed)+".")
+	}
+	b.editMessageText(chatID, messageID, strings.Join(lines, "\n"))
+}
+
+// removeWorktree deletes a worktree and its branch, and forgets the thread
+// records pointing at it.
+func (b *Bot) removeWorktree(repoRoot string, w worktreeStatus) error {
+	if err := git.WorktreeRemove(repoRoot, w.Path); err != nil {
+		return err
+	}
+	if err := git.DeleteBranch(repoRoot, w.Branch); err != nil {
+		return err
+	}
+	for threadID, wi := range b.state.AllWorktreeInfos() {
+		if wi.WorktreeDir == w.Path {
+			b.state.RemoveWorktreeInfo(threadID)
+		}
+	}
+	b.saveState()
+	log.Printf("Cleaned up merged worktree %s (branch %s)", w.Path, w.Branch)
+	return nil
+}
+
+// offerWorktreeCleanup posts the cleanup offer to one topic per repository
+// that bound sessions work in and that has worktrees removable at
+// WorktreeCleanupAge.
+func (b *Bot) offerWorktreeCleanup() {
+	minAge := b.config.WorktreeCleanupAge
+	offered := make(map[string]bool)
+
+	for windowID := range b.state.AllBoundWindowIDs() {
+		ws, ok := b.state.GetWindowState(windowID)
+		if !ok || ws.CWD == "" {
+			continue
+		}
+		root, err := mainRepoRoot(ws.CWD)
+		if err != nil || offered[root] {
+			continue
+		}
+		offered[root] = true
+
+		text, n, err := b.worktreeReport(root, minAge)
+		if err != nil {
+			log.Printf("Worktree check for %s: %v", root, err)
+			continue
+		}
+		if n == 0 {
+			continue
+		}
+		for _, ut := range b.state.FindUsersForWindow(windowID) {
+			chatID, ok := b.state.GetGroupChatID(ut.UserID, ut.ThreadID)
+			if !ok {
+				continue
+			}
+			threadID, _ := strconv.Atoi(ut.ThreadID)
+			b.sendMessageWithKeyboard(chatID, threadID, text, worktreeCleanupKeyboard(n, minAge))
+			break
+		}
+	}
+}
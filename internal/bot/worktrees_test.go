@@ -0,0 +1,80 @@
+package bot
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWorktreeStatus_Removable(t *testing.T) {
+	day := 24 * time.Hour
+	ok := worktreeStatus{Branch: "minuano/app-t1", Merged: true, Age: 10 * day}
+	if !ok.removable(7 * day) {
+		t.Fatal("merged, clean, old worktree should be removable")
+	}
+
+	for name, w := range map[string]worktreeStatus{
+		"unmerged":   {Branch: "b", Age: 10 * day},
+		"too young":  {Branch: "b", Merged: true, Age: 3 * day},
+		"dirty":      {Branch: "b", Merged: true, Age: 10 * day, Dirty: true},
+		"in use":     {Branch: "b", Merged: true, Age: 10 * day, InUse: true},
+		"unpushed":   {Branch: "b", Merged: true, Age: 10 * day, LocalCommits: 2},
+		"detached":   {Merged: true, Age: 10 * day},
+		"check fail": {Branch: "b", Merged: true, Age: 10 * day, CheckErr: errors.New("boom")},
+	} {
+		if w.removable(7 * day) {
+			t.Errorf("%s worktree should not be removable", name)
+		}
+	}
+}
+
+func TestFormatWorktrees(t *testing.T) {
+	day := 24 * time.Hour
+	statuses := []worktreeStatus{
+		{Path: "/repo/.minuano/worktrees/app-t1", Branch: "minuano/app-t1", Size: 2 * 1024 * 1024, Age: 9 * day, Merged: true},
+		{Path: "/repo/.minuano/worktrees/app-t2", Branch: "minuano/app-t2", Size: 1024 * 1024, Age: 5 * time.Hour, LocalCommits: 3, Dirty: true, InUse: true},
+	}
+	text, n := formatWorktrees("/repo", "main", statuses, 7*day)
+	if n != 1 {
+		t.Errorf("removable = %d, want 1", n)
+	}
+	for _, want := range []string{
+		"(base main)",
+		"• minuano/app-t1 — 2.0 MB, 9d old, merged",
+		"• minuano/app-t2 — 1.0 MB, 5h old, 3 unpushed commit(s), uncommitted changes, in use",
+		"Total: 3.0 MB in 2 worktree(s).",
+		"1 merged worktree(s) older than 7d can be removed with their branches, freeing 2.0 MB.",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("report missing %q:\n%s", want, text)
+		}
+	}
+
+	if _, n := formatWorktrees("/repo", "main", statuses, 10*day); n != 0 {
+		t.Errorf("removable at 10d = %d, want 0", n)
+	}
+	if text, _ := formatWorktrees("/repo", "main", nil, day); !strings.HasPrefix(text, "No worktrees") {
+		t.Errorf("empty report = %q", text)
+	}
+}
+
+func TestWorktreeCleanupKeyboard(t *testing.T) {
+	kb := worktreeCleanupKeyboard(2, 7*24*time.Hour)
+	row := kb.InlineKeyboard[0]
+	if *row[0].CallbackData != "wt_clean:7" || *row[1].CallbackData != "wt_keep" {
+		t.Errorf("callback data = %q, %q", *row[0].CallbackData, *row[1].CallbackData)
+	}
+}
+
+func TestDirSize(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a"), make([]byte, 100), 0o644)
+	os.MkdirAll(filepath.Join(dir, "sub"), 0o755)
+	os.WriteFile(filepath.Join(dir, "sub", "b"), make([]byte, 50), 0o644)
+	if got := dirSize(dir); got != 150 {
+		t.Errorf("dirSize = %d, want 150", got)
+	}
+}
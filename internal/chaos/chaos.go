@@ -0,0 +1,177 @@
+// Package chaos injects random failures so recovery paths (dead windows, flood
+// control, partial transcript reads) can be exercised by hand. It is off
+// unless Enable is called, which only the hidden serve --chaos flag does.
+package chaos
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Fault is a kind of injected failure.
+type Fault string
+
+const (
+	Tmux     Fault = "tmux"     // tmux commands fail; window commands as if the window died
+	Telegram Fault = "telegram" // Bot API calls answer 429 or 500
+	JSONL    Fault = "jsonl"    // transcript reads stop mid-line
+)
+
+var faults = []Fault{Tmux, Telegram, JSONL}
+
+var (
+	mu    sync.Mutex
+	rates map[Fault]float64
+	rng   = rand.New(rand.NewSource(1))
+)
+
+// Parse parses a fault spec: one probability for every fault ("0.05"), or
+// comma-separated fault=probability pairs ("tmux=0.1,telegram=0.02").
+func Parse(spec string) (map[Fault]float64, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, fmt.Errorf("empty chaos spec")
+	}
+	parsed := make(map[Fault]float64)
+	if !strings.Contains(spec, "=") {
+		p, err := parseRate(spec)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range faults {
+			parsed[f] = p
+		}
+		return parsed, nil
+	}
+	for _, pair := range strings.Split(spec, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid chaos pair %q", pair)
+		}
+		f := Fault(strings.TrimSpace(name))
+		if !known(f) {
+			return nil, fmt.Errorf("unknown chaos fault %q (want tmux, telegram or jsonl)", name)
+		}
+		p, err := parseRate(value)
+		if err != nil {
+			return nil, err
+		}
+		parsed[f] = p
+	}
+	return parsed, nil
+}
+
+func parseRate(s string) (float64, error) {
+	p, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || p < 0 || p > 1 {
+		return 0, fmt.Errorf("invalid chaos probability %q (want 0 to 1)", s)
+	}
+	return p, nil
+}
+
+func known(f Fault) bool {
+	for _, k := range faults {
+		if k == f {
+			return true
+		}
+	}
+	return false
+}
+
+// Enable starts injecting faults at the given probabilities.
+func Enable(r map[Fault]float64, seed int64) {
+	mu.Lock()
+	defer mu.Unlock()
+	rates = r
+	rng = rand.New(rand.NewSource(seed))
+}
+
+// Disable stops injecting faults.
+func Disable() {
+	mu.Lock()
+	defer mu.Unlock()
+	rates = nil
+}
+
+// Hit reports whether a fault should be injected now.
+func Hit(f Fault) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	p := rates[f]
+	return p > 0 && rng.Float64() < p
+}
+
+// intn returns a random int in [0, n).
+func intn(n int) int {
+	mu.Lock()
+	defer mu.Unlock()
+	return rng.Intn(n)
+}
+
+// TmuxError returns an injected failure for a tmux command, or nil. Commands
+// that target a window fail as if it were gone, which drives dead-window
+// recovery.
+func TmuxError(args []string) error {
+	if !Hit(Tmux) {
+		return nil
+	}
+	for i, arg := range args {
+		if arg == "-t" && i+1 < len(args) {
+			return fmt.Errorf("chaos: can't find window: %s", args[i+1])
+		}
+	}
+	return fmt.Errorf("chaos: server exited unexpectedly")
+}
+
+// HTTPDoer is the client interface of the Telegram Bot API library.
+type HTTPDoer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+type faultyClient struct {
+	next HTTPDoer
+}
+
+// WrapHTTPClient returns c with injected 429 and 500 responses. getUpdates is
+// spared: failing it only delays input and exercises no recovery path.
+func WrapHTTPClient(c HTTPDoer) HTTPDoer {
+	return faultyClient{next: c}
+}
+
+func (c faultyClient) Do(req *http.Request) (*http.Response, error) {
+	if path.Base(req.URL.Path) == "getUpdates" || !Hit(Telegram) {
+		return c.next.Do(req)
+	}
+	if intn(2) == 0 {
+		retryAfter := 1 + intn(5)
+		return apiError(http.StatusTooManyRequests,
+			fmt.Sprintf(`{"ok":false,"error_code":429,"description":"Too Many Requests: retry after %d (chaos)","parameters":{"retry_after":%d}}`, retryAfter, retryAfter)), nil
+	}
+	return apiError(http.StatusInternalServerError,
+		`{"ok":false,"error_code":500,"description":"Internal Server Error (chaos)"}`), nil
+}
+
+func apiError(code int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: code,
+		Status:     fmt.Sprintf("%d %s", code, http.StatusText(code)),
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+}
+
+// TruncateReader returns r cut off at a random point of its next n bytes,
+// as if the file were read while still being written. Returns r unchanged
+// when no fault is injected.
+func TruncateReader(r io.Reader, n int64) io.Reader {
+	if n <= 1 || !Hit(JSONL) {
+		return r
+	}
+	return io.LimitReader(r, int64(intn(int(n))))
+}
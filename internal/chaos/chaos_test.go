@@ -0,0 +1,90 @@
+package chaos
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	all, err := Parse("0.25")
+	if err != nil || len(all) != 3 || all[Tmux] != 0.25 || all[JSONL] != 0.25 {
+		t.Fatalf("Parse(0.25) = %v, %v", all, err)
+	}
+	pairs, err := Parse("tmux=1, telegram=0.1")
+	if err != nil || pairs[Tmux] != 1 || pairs[Telegram] != 0.1 || pairs[JSONL] != 0 {
+		t.Fatalf("Parse(pairs) = %v, %v", pairs, err)
+	}
+	for _, bad := range []string{"", "2", "-0.1", "disk=0.1", "tmux", "tmux=x"} {
+		if _, err := Parse(bad); err == nil {
+			t.Errorf("Parse(%q): expected error", bad)
+		}
+	}
+}
+
+func TestTmuxError(t *testing.T) {
+	defer Disable()
+	if err := TmuxError([]string{"send-keys", "-t", "@1"}); err != nil {
+		t.Fatalf("disabled chaos injected %v", err)
+	}
+
+	Enable(map[Fault]float64{Tmux: 1}, 1)
+	err := TmuxError([]string{"send-keys", "-t", "@1", "hi"})
+	if err == nil || !strings.Contains(err.Error(), "can't find window: @1") {
+		t.Errorf("window command error = %v, want a dead-window error", err)
+	}
+	if err := TmuxError([]string{"list-sessions"}); err == nil {
+		t.Error("expected injected error for a command without a target")
+	}
+}
+
+type okDoer struct{ calls int }
+
+func (d *okDoer) Do(*http.Request) (*http.Response, error) {
+	d.calls++
+	return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(`{"ok":true}`))}, nil
+}
+
+func TestWrapHTTPClient(t *testing.T) {
+	defer Disable()
+	Enable(map[Fault]float64{Telegram: 1}, 1)
+	next := &okDoer{}
+	c := WrapHTTPClient(next)
+
+	req, _ := http.NewRequest("POST", "https://api.telegram.org/bottoken/sendMessage", nil)
+	for i := 0; i < 10; i++ {
+		resp, err := c.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != 429 && resp.StatusCode != 500 {
+			t.Errorf("status = %d, want 429 or 500", resp.StatusCode)
+		}
+		if resp.StatusCode == 429 && !bytes.Contains(body, []byte(`"retry_after":`)) {
+			t.Errorf("429 without retry_after: %s", body)
+		}
+	}
+	if next.calls != 0 {
+		t.Errorf("faulted requests reached the API %d times", next.calls)
+	}
+
+	updates, _ := http.NewRequest("POST", "https://api.telegram.org/bottoken/getUpdates", nil)
+	if resp, _ := c.Do(updates); resp.StatusCode != 200 || next.calls != 1 {
+		t.Error("getUpdates should never be faulted")
+	}
+}
+
+func TestTruncateReader(t *testing.T) {
+	defer Disable()
+	data := strings.Repeat("x", 100)
+	if got, _ := io.ReadAll(TruncateReader(strings.NewReader(data), 100)); len(got) != 100 {
+		t.Errorf("disabled chaos read %d bytes, want 100", len(got))
+	}
+	Enable(map[Fault]float64{JSONL: 1}, 1)
+	if got, _ := io.ReadAll(TruncateReader(strings.NewReader(data), 100)); len(got) >= 100 {
+		t.Errorf("read %d bytes, want a truncated read", len(got))
+	}
+}
@@ -2,19 +2,30 @@ package config
 
 import (
 	"fmt"
+	"net"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"text/template"
+	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/otaviocarvalho/tramuntana/internal/layout"
 )
 
 type Config struct {
 	TelegramBotToken    string
 	AllowedUsers        []int64
 	AllowedGroups       []int64
-	TramuntanaDir       string
+	TramuntanaDir       string // state files; TRAMUNTANA_DIR, ~/.tramuntana or $XDG_STATE_HOME/tramuntana
+	ConfigDir           string // .env and webhooks.json; the same as TramuntanaDir in a flat layout
+	CacheDir            string // temp prompt files; empty uses the system temp directory
+	LegacyLayout        bool   // using ~/.tramuntana because it exists and TRAMUNTANA_DIR is unset
 	TmuxSessionName     string
 	ClaudeCommand       string
 	MonitorPollInterval float64
@@ -25,6 +36,102 @@ type Config struct {
 	ApprovalsTopicID    int64
 	DefaultProject      string
 	PlannerPromptPath   string
+	Verbosity           string
+	DigestTime          string        // "HH:MM" local time for the daily digest; empty disables it
+	AutoHeartbeat       time.Duration // /auto progress heartbeat interval; 0 disables it
+	AutoStallTimeout    time.Duration // /auto alert after this long without transcript activity; 0 disables it
+	WorktreeCleanupAge  time.Duration // offer daily to remove merged worktrees older than this; 0 disables it
+	GuardMaxLoad        float64       // new sessions: 1-minute load average per CPU above which to hold off; 0 disables it
+	GuardMinFreeMB      int64         // new sessions: available memory (MB) below which to hold off; 0 disables it
+	GuardMaxSessions    int           // new sessions: running Claude processes at which to hold off; 0 disables it
+	GuardMode           string        // what a tripped guard does: "refuse" (with a Start anyway button) or "warn"
+	AutoRestart         bool          // relaunch Claude with --resume when its window drops to a shell
+	ResumeMode          string        // dead-window restart: "fresh", "resume", "continue" or "ask"
+	BootstrapPolicy     string        // where to start reading transcripts found at startup: "eof", "tail" or "full"
+	BootstrapTailBytes  int64         // with "tail", how many trailing bytes to replay
+	MaxEntriesPerPoll   int           // cap on entries delivered per transcript per poll; 0 disables it
+	DedupWindow         time.Duration // skip transcript lines already delivered this recently; 0 disables it
+	TmuxControlMode     bool          // run tmux commands over a persistent control-mode client
+	AutoPin             bool          // pin approved plans, /t_pickw summaries and bookmarks in their topic
+	NowSummary          string        // one-line summary of each turn's work: "pin" (edited pinned "Now:" message), "title" (topic name suffix) or "" (off)
+	ArtifactPatterns    []string      // globs for files Claude writes that are offered in the topic; empty disables it
+	ArtifactAutoSend    int64         // artifacts up to this many bytes are sent without asking; 0 always asks
+	LongPasteMode       string        // how messages over LongPasteChars reach Claude: "file", "buffer" or "keys"
+	LongPasteChars      int           // message length above which LongPasteMode applies
+	AccessRequests      bool          // let unknown users request access, approved from AdminChatID
+	AdminChatID         int64         // chat that receives access requests; defaults to the first allowed user
+	Tracing             bool          // export OpenTelemetry spans over OTLP (OTEL_EXPORTER_OTLP_* configures the endpoint)
+	WindowNameTemplate  string        // window and topic name, e.g. "{{.Project}}·{{.Branch}}"; empty keeps names as created
+	ControlAddr         string        // local control API: "unix:<path>" or a loopback host:port; empty disables it
+	ControlToken        string        // bearer token the control API requires; empty requires none
+	WebhookAddr         string        // listen address for webhook notifications (sources in webhooks.json); empty disables it
+	Coordinator         string        // federation coordinator URL to reach the Bot API through; TELEGRAM_BOT_TOKEN is then "<host>:<secret>"
+	FederationAddr      string        // `tramuntana coordinator` listen address for hosts
+	FederationHosts     string        // `tramuntana coordinator` hosts, "name:secret,..."
+	// Critical alerts sent outside Telegram: an ntfy topic URL, a Pushover
+	// application token and user key, and an SMTP server; each is off when unset
+	AlertNtfyURL       string
+	AlertNtfyToken     string
+	AlertPushoverToken string
+	AlertPushoverUser  string
+	AlertSMTPAddr      string
+	AlertSMTPUser      string
+	AlertSMTPPassword  string
+	AlertSMTPFrom      string
+	AlertSMTPTo        []string
+	AlertFloodBan      time.Duration // alert when Telegram rate-limits a chat for at least this long
+
+	// Restart the session monitor or status poller when a poll hasn't
+	// finished for this long; 0 only restarts them after a panic
+	WatchdogStall time.Duration
+
+	// Remind a topic of a question or permission prompt left unanswered
+	// this long, and alert the sinks; 0 disables reminders
+	PromptReminder time.Duration
+
+	// Archive a session's transcript with a rendered digest when it dies or
+	// its topic closes (see /history archived): to ArchiveDir, or to an S3
+	// bucket; both empty disables archiving
+	ArchiveDir         string
+	ArchiveS3Bucket    string
+	ArchiveS3Region    string
+	ArchiveS3Endpoint  string // S3-compatible endpoint, e.g. http://minio:9000; empty is AWS
+	ArchiveS3Prefix    string
+	AWSAccessKeyID     string
+	AWSSecretAccessKey string
+	AWSSessionToken    string
+	ArchivePrune       bool // delete a closed topic's transcript from ~/.claude once archived
+
+	// Session state ("working", "input", "idle", "dead") → forum topic icon
+	// emoji; nil disables icons
+	TopicIcons map[string]string
+
+	// Glyphs prefixed to delivered messages by where their content came from;
+	// topics can pick another of LabelThemes with /labels
+	Labels LabelTheme
+
+	AttentionTopicID int64 // overview topic for the needs-attention summary; 0 disables it
+	FeedTopicID      int64 // topic every session posts one-line events to; 0 disables it
+	AttentionAdmin   bool  // post the needs-attention summary to AdminChatID instead
+
+	// Directories the file browsers and file sending may reach; empty means
+	// the home directory plus every session's working directory
+	AllowedRoots []string
+
+	// Bot commands forwarded to Claude Code as typed, beyond the built-in
+	// c_* ones (e.g. "review", "init"); lowercase, without the slash
+	ForwardCommands []string
+
+	// Test output parsers for Bash results (see render.RegisterTestParser);
+	// nil keeps the verbosity profile's, empty disables them
+	TestParsers []string
+
+	ScreenshotTheme      string
+	ScreenshotFontSize   float64
+	ScreenshotLineHeight int
+	ScreenshotMaxCols    int
+
+	groupsMu sync.RWMutex // guards AllowedGroups, which a group migration rewrites
 }
 
 func Load(envFile ...string) (*Config, error) {
@@ -32,6 +139,8 @@ func Load(envFile ...string) (*Config, error) {
 		_ = godotenv.Load(f)
 	}
 	_ = godotenv.Load() // default .env, ignore if missing
+	dirs := layout.Resolve()
+	_ = godotenv.Load(filepath.Join(dirs.Config, ".env"))
 
 	token := os.Getenv("TELEGRAM_BOT_TOKEN")
 	if token == "" {
@@ -55,13 +164,8 @@ func Load(envFile ...string) (*Config, error) {
 		}
 	}
 
-	dir := os.Getenv("TRAMUNTANA_DIR")
-	if dir == "" {
-		dir = "~/.tramuntana"
-	}
-	dir = expandHome(dir)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return nil, fmt.Errorf("creating tramuntana dir: %w", err)
+	if err := dirs.MkdirAll(); err != nil {
+		return nil, fmt.Errorf("creating tramuntana dirs: %w", err)
 	}
 
 	sessionName := os.Getenv("TMUX_SESSION_NAME")
@@ -73,6 +177,9 @@ func Load(envFile ...string) (*Config, error) {
 	if claudeCmd == "" {
 		claudeCmd = "claude"
 	}
+	if _, err := template.New("CLAUDE_COMMAND").Parse(claudeCmd); err != nil {
+		return nil, fmt.Errorf("invalid CLAUDE_COMMAND: %w", err)
+	}
 
 	pollInterval := 2.0
 	if p := os.Getenv("MONITOR_POLL_INTERVAL"); p != "" {
@@ -106,11 +213,407 @@ func Load(envFile ...string) (*Config, error) {
 		plannerPromptPath = "/home/otavio/code/minuano/claude/planner-system-prompt.md"
 	}
 
+	verbosity := os.Getenv("TRAMUNTANA_VERBOSITY")
+	if verbosity == "" {
+		verbosity = "normal"
+	}
+
+	digestTime := os.Getenv("TRAMUNTANA_DIGEST_TIME")
+	if digestTime != "" {
+		t, err := time.Parse("15:04", digestTime)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TRAMUNTANA_DIGEST_TIME (want HH:MM): %q", digestTime)
+		}
+		digestTime = t.Format("15:04")
+	}
+
+	autoHeartbeat := 10 * time.Minute
+	if h := os.Getenv("TRAMUNTANA_AUTO_HEARTBEAT_MINUTES"); h != "" {
+		mins, err := strconv.Atoi(h)
+		if err != nil || mins < 0 {
+			return nil, fmt.Errorf("invalid TRAMUNTANA_AUTO_HEARTBEAT_MINUTES: %q", h)
+		}
+		autoHeartbeat = time.Duration(mins) * time.Minute
+	}
+
+	autoStallTimeout := 5 * time.Minute
+	if st := os.Getenv("TRAMUNTANA_AUTO_STALL_MINUTES"); st != "" {
+		mins, err := strconv.Atoi(st)
+		if err != nil || mins < 0 {
+			return nil, fmt.Errorf("invalid TRAMUNTANA_AUTO_STALL_MINUTES: %q", st)
+		}
+		autoStallTimeout = time.Duration(mins) * time.Minute
+	}
+
+	worktreeCleanupAge := 7 * 24 * time.Hour
+	if wc := os.Getenv("TRAMUNTANA_WORKTREE_CLEANUP_DAYS"); wc != "" {
+		days, err := strconv.Atoi(wc)
+		if err != nil || days < 0 {
+			return nil, fmt.Errorf("invalid TRAMUNTANA_WORKTREE_CLEANUP_DAYS: %q", wc)
+		}
+		worktreeCleanupAge = time.Duration(days) * 24 * time.Hour
+	}
+
+	var guardMaxLoad float64
+	if l := os.Getenv("TRAMUNTANA_GUARD_MAX_LOAD"); l != "" {
+		guardMaxLoad, err = strconv.ParseFloat(l, 64)
+		if err != nil || guardMaxLoad < 0 {
+			return nil, fmt.Errorf("invalid TRAMUNTANA_GUARD_MAX_LOAD: %q", l)
+		}
+	}
+
+	var guardMinFreeMB int64
+	if m := os.Getenv("TRAMUNTANA_GUARD_MIN_FREE_MB"); m != "" {
+		guardMinFreeMB, err = strconv.ParseInt(m, 10, 64)
+		if err != nil || guardMinFreeMB < 0 {
+			return nil, fmt.Errorf("invalid TRAMUNTANA_GUARD_MIN_FREE_MB: %q", m)
+		}
+	}
+
+	var guardMaxSessions int
+	if n := os.Getenv("TRAMUNTANA_GUARD_MAX_SESSIONS"); n != "" {
+		guardMaxSessions, err = strconv.Atoi(n)
+		if err != nil || guardMaxSessions < 0 {
+			return nil, fmt.Errorf("invalid TRAMUNTANA_GUARD_MAX_SESSIONS: %q", n)
+		}
+	}
+
+	guardMode := os.Getenv("TRAMUNTANA_GUARD_MODE")
+	switch guardMode {
+	case "":
+		guardMode = "refuse"
+	case "refuse", "warn":
+	default:
+		return nil, fmt.Errorf("invalid TRAMUNTANA_GUARD_MODE: %q (want refuse or warn)", guardMode)
+	}
+
+	resumeMode := os.Getenv("TRAMUNTANA_RESUME")
+	switch resumeMode {
+	case "":
+		resumeMode = "fresh"
+	case "fresh", "resume", "continue", "ask":
+	default:
+		return nil, fmt.Errorf("invalid TRAMUNTANA_RESUME (want fresh, resume, continue or ask): %q", resumeMode)
+	}
+
+	// TRAMUNTANA_BOOTSTRAP is "eof", "full" or a byte count to replay from the end.
+	bootstrapPolicy := "eof"
+	var bootstrapTailBytes int64
+	switch bs := os.Getenv("TRAMUNTANA_BOOTSTRAP"); bs {
+	case "", "eof":
+	case "full":
+		bootstrapPolicy = "full"
+	default:
+		bootstrapTailBytes, err = strconv.ParseInt(bs, 10, 64)
+		if err != nil || bootstrapTailBytes <= 0 {
+			return nil, fmt.Errorf("invalid TRAMUNTANA_BOOTSTRAP (want eof, full or a byte count): %q", bs)
+		}
+		bootstrapPolicy = "tail"
+	}
+
+	maxEntriesPerPoll := 200
+	if me := os.Getenv("TRAMUNTANA_MAX_ENTRIES_PER_POLL"); me != "" {
+		maxEntriesPerPoll, err = strconv.Atoi(me)
+		if err != nil || maxEntriesPerPoll < 0 {
+			return nil, fmt.Errorf("invalid TRAMUNTANA_MAX_ENTRIES_PER_POLL: %q", me)
+		}
+	}
+
+	dedupWindow := 30 * time.Minute
+	if dw := os.Getenv("TRAMUNTANA_DEDUP_WINDOW_MINUTES"); dw != "" {
+		mins, err := strconv.Atoi(dw)
+		if err != nil || mins < 0 {
+			return nil, fmt.Errorf("invalid TRAMUNTANA_DEDUP_WINDOW_MINUTES: %q", dw)
+		}
+		dedupWindow = time.Duration(mins) * time.Minute
+	}
+
+	tmuxControlMode := true
+	if tc := os.Getenv("TRAMUNTANA_TMUX_CONTROL"); tc != "" {
+		tmuxControlMode, err = strconv.ParseBool(tc)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TRAMUNTANA_TMUX_CONTROL: %q", tc)
+		}
+	}
+
+	var autoRestart bool
+	if r := os.Getenv("TRAMUNTANA_AUTO_RESTART"); r != "" {
+		autoRestart, err = strconv.ParseBool(r)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TRAMUNTANA_AUTO_RESTART: %q", r)
+		}
+	}
+
+	var autoPin bool
+	if ap := os.Getenv("TRAMUNTANA_AUTO_PIN"); ap != "" {
+		autoPin, err = strconv.ParseBool(ap)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TRAMUNTANA_AUTO_PIN: %q", ap)
+		}
+	}
+
+	nowSummary := os.Getenv("TRAMUNTANA_NOW_SUMMARY")
+	switch nowSummary {
+	case "off":
+		nowSummary = ""
+	case "", "pin", "title":
+	default:
+		return nil, fmt.Errorf("invalid TRAMUNTANA_NOW_SUMMARY: %q (want pin, title or off)", nowSummary)
+	}
+
+	artifactPatterns := parseList(os.Getenv("TRAMUNTANA_ARTIFACT_PATTERNS"))
+	for _, p := range artifactPatterns {
+		if _, err := filepath.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("invalid TRAMUNTANA_ARTIFACT_PATTERNS entry %q: %w", p, err)
+		}
+	}
+
+	var artifactAutoSend int64
+	if kb := os.Getenv("TRAMUNTANA_ARTIFACT_AUTOSEND_KB"); kb != "" {
+		n, err := strconv.ParseInt(kb, 10, 64)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid TRAMUNTANA_ARTIFACT_AUTOSEND_KB: %q", kb)
+		}
+		artifactAutoSend = n * 1024
+	}
+
+	longPasteMode := os.Getenv("TRAMUNTANA_LONG_PASTE")
+	switch longPasteMode {
+	case "":
+		longPasteMode = "file"
+	case "file", "buffer", "keys":
+	default:
+		return nil, fmt.Errorf("invalid TRAMUNTANA_LONG_PASTE (want file, buffer or keys): %q", longPasteMode)
+	}
+
+	longPasteChars := 2000
+	if lc := os.Getenv("TRAMUNTANA_LONG_PASTE_CHARS"); lc != "" {
+		longPasteChars, err = strconv.Atoi(lc)
+		if err != nil || longPasteChars <= 0 {
+			return nil, fmt.Errorf("invalid TRAMUNTANA_LONG_PASTE_CHARS: %q", lc)
+		}
+	}
+
+	var accessRequests bool
+	if ar := os.Getenv("TRAMUNTANA_ACCESS_REQUESTS"); ar != "" {
+		accessRequests, err = strconv.ParseBool(ar)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TRAMUNTANA_ACCESS_REQUESTS: %q", ar)
+		}
+	}
+
+	adminChatID := users[0]
+	if ac := os.Getenv("TRAMUNTANA_ADMIN_CHAT"); ac != "" {
+		adminChatID, err = strconv.ParseInt(ac, 10, 64)
+		if err != nil || adminChatID == 0 {
+			return nil, fmt.Errorf("invalid TRAMUNTANA_ADMIN_CHAT: %q", ac)
+		}
+	}
+
+	var tracing bool
+	if tr := os.Getenv("TRAMUNTANA_TRACING"); tr != "" {
+		tracing, err = strconv.ParseBool(tr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TRAMUNTANA_TRACING: %q", tr)
+		}
+	}
+
+	controlAddr := os.Getenv("TRAMUNTANA_CONTROL_ADDR")
+	if controlAddr != "" && !strings.HasPrefix(controlAddr, "unix:") {
+		if _, _, err := net.SplitHostPort(controlAddr); err != nil {
+			return nil, fmt.Errorf("invalid TRAMUNTANA_CONTROL_ADDR: %q (want unix:<path> or host:port)", controlAddr)
+		}
+	}
+
+	coordinator := os.Getenv("TRAMUNTANA_COORDINATOR")
+	if coordinator != "" {
+		u, err := url.Parse(coordinator)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid TRAMUNTANA_COORDINATOR: %q (want an http(s) URL)", coordinator)
+		}
+		coordinator = strings.TrimRight(coordinator, "/")
+	}
+
+	alertNtfyURL := os.Getenv("TRAMUNTANA_ALERT_NTFY_URL")
+	if alertNtfyURL != "" {
+		u, err := url.Parse(alertNtfyURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || strings.Trim(u.Path, "/") == "" {
+			return nil, fmt.Errorf("invalid TRAMUNTANA_ALERT_NTFY_URL: %q (want a topic URL, e.g. https://ntfy.sh/<topic>)", alertNtfyURL)
+		}
+	}
+	alertPushoverToken := os.Getenv("TRAMUNTANA_ALERT_PUSHOVER_TOKEN")
+	alertPushoverUser := os.Getenv("TRAMUNTANA_ALERT_PUSHOVER_USER")
+	if (alertPushoverToken == "") != (alertPushoverUser == "") {
+		return nil, fmt.Errorf("TRAMUNTANA_ALERT_PUSHOVER_TOKEN and TRAMUNTANA_ALERT_PUSHOVER_USER must be set together")
+	}
+	alertSMTPAddr := os.Getenv("TRAMUNTANA_ALERT_SMTP_ADDR")
+	alertSMTPUser := os.Getenv("TRAMUNTANA_ALERT_SMTP_USER")
+	alertSMTPFrom := os.Getenv("TRAMUNTANA_ALERT_SMTP_FROM")
+	alertSMTPTo := parseList(os.Getenv("TRAMUNTANA_ALERT_SMTP_TO"))
+	if alertSMTPAddr != "" {
+		if _, _, err := net.SplitHostPort(alertSMTPAddr); err != nil {
+			return nil, fmt.Errorf("invalid TRAMUNTANA_ALERT_SMTP_ADDR: %q (want host:port)", alertSMTPAddr)
+		}
+		if alertSMTPFrom == "" {
+			alertSMTPFrom = alertSMTPUser
+		}
+		if len(alertSMTPTo) == 0 || alertSMTPFrom == "" {
+			return nil, fmt.Errorf("TRAMUNTANA_ALERT_SMTP_ADDR needs TRAMUNTANA_ALERT_SMTP_TO and TRAMUNTANA_ALERT_SMTP_FROM (or _USER)")
+		}
+	}
+	alertFloodBan := 10 * time.Minute
+	if fb := os.Getenv("TRAMUNTANA_ALERT_FLOOD_MINUTES"); fb != "" {
+		mins, err := strconv.Atoi(fb)
+		if err != nil || mins < 0 {
+			return nil, fmt.Errorf("invalid TRAMUNTANA_ALERT_FLOOD_MINUTES: %q", fb)
+		}
+		alertFloodBan = time.Duration(mins) * time.Minute
+	}
+
+	watchdogStall := 2 * time.Minute
+	if ws := os.Getenv("TRAMUNTANA_WATCHDOG_STALL_MINUTES"); ws != "" {
+		mins, err := strconv.Atoi(ws)
+		if err != nil || mins < 0 {
+			return nil, fmt.Errorf("invalid TRAMUNTANA_WATCHDOG_STALL_MINUTES: %q", ws)
+		}
+		watchdogStall = time.Duration(mins) * time.Minute
+	}
+
+	promptReminder := 15 * time.Minute
+	if pr := os.Getenv("TRAMUNTANA_PROMPT_REMINDER_MINUTES"); pr != "" {
+		mins, err := strconv.Atoi(pr)
+		if err != nil || mins < 0 {
+			return nil, fmt.Errorf("invalid TRAMUNTANA_PROMPT_REMINDER_MINUTES: %q", pr)
+		}
+		promptReminder = time.Duration(mins) * time.Minute
+	}
+
+	archiveDir := os.Getenv("TRAMUNTANA_ARCHIVE_DIR")
+	archiveBucket := os.Getenv("TRAMUNTANA_ARCHIVE_S3_BUCKET")
+	archiveRegion := os.Getenv("TRAMUNTANA_ARCHIVE_S3_REGION")
+	if archiveRegion == "" {
+		archiveRegion = "us-east-1"
+	}
+	archiveEndpoint := os.Getenv("TRAMUNTANA_ARCHIVE_S3_ENDPOINT")
+	if archiveDir != "" && archiveBucket != "" {
+		return nil, fmt.Errorf("set TRAMUNTANA_ARCHIVE_DIR or TRAMUNTANA_ARCHIVE_S3_BUCKET, not both")
+	}
+	if archiveBucket != "" && (os.Getenv("AWS_ACCESS_KEY_ID") == "" || os.Getenv("AWS_SECRET_ACCESS_KEY") == "") {
+		return nil, fmt.Errorf("TRAMUNTANA_ARCHIVE_S3_BUCKET needs AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+	if archiveEndpoint != "" {
+		u, err := url.Parse(archiveEndpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid TRAMUNTANA_ARCHIVE_S3_ENDPOINT: %q (want an http(s) URL)", archiveEndpoint)
+		}
+	}
+	var archivePrune bool
+	if ap := os.Getenv("TRAMUNTANA_ARCHIVE_PRUNE"); ap != "" {
+		archivePrune, err = strconv.ParseBool(ap)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TRAMUNTANA_ARCHIVE_PRUNE: %q", ap)
+		}
+		if archivePrune && archiveDir == "" && archiveBucket == "" {
+			return nil, fmt.Errorf("TRAMUNTANA_ARCHIVE_PRUNE needs TRAMUNTANA_ARCHIVE_DIR or TRAMUNTANA_ARCHIVE_S3_BUCKET")
+		}
+	}
+
+	windowName := os.Getenv("TRAMUNTANA_WINDOW_NAME")
+	if _, err := template.New("TRAMUNTANA_WINDOW_NAME").Parse(windowName); err != nil {
+		return nil, fmt.Errorf("invalid TRAMUNTANA_WINDOW_NAME template: %w", err)
+	}
+
+	topicIcons, err := parseTopicIcons(os.Getenv("TRAMUNTANA_TOPIC_ICONS"))
+	if err != nil {
+		return nil, err
+	}
+	labels, err := parseLabels(os.Getenv("TRAMUNTANA_LABELS"))
+	if err != nil {
+		return nil, err
+	}
+
+	var attentionTopicID int64
+	var attentionAdmin bool
+	if at := os.Getenv("TRAMUNTANA_ATTENTION_TOPIC_ID"); at == "admin" {
+		attentionAdmin = true
+	} else if at != "" {
+		attentionTopicID, err = strconv.ParseInt(at, 10, 64)
+		if err != nil || attentionTopicID <= 0 {
+			return nil, fmt.Errorf("invalid TRAMUNTANA_ATTENTION_TOPIC_ID: %q (want a topic ID or \"admin\")", at)
+		}
+	}
+
+	var feedTopicID int64
+	if ft := os.Getenv("TRAMUNTANA_FEED_TOPIC_ID"); ft != "" {
+		feedTopicID, err = strconv.ParseInt(ft, 10, 64)
+		if err != nil || feedTopicID <= 0 {
+			return nil, fmt.Errorf("invalid TRAMUNTANA_FEED_TOPIC_ID: %q", ft)
+		}
+	}
+
+	var allowedRoots []string
+	for _, root := range parseList(os.Getenv("TRAMUNTANA_ALLOWED_ROOTS")) {
+		root = expandHome(root)
+		if !filepath.IsAbs(root) {
+			return nil, fmt.Errorf("invalid TRAMUNTANA_ALLOWED_ROOTS: %q is not an absolute path", root)
+		}
+		allowedRoots = append(allowedRoots, filepath.Clean(root))
+	}
+
+	var forwardCommands []string
+	for _, name := range parseList(os.Getenv("TRAMUNTANA_FORWARD_COMMANDS")) {
+		name = strings.ToLower(strings.TrimPrefix(name, "/"))
+		if !isCommandName(name) {
+			return nil, fmt.Errorf("invalid TRAMUNTANA_FORWARD_COMMANDS: %q is not a Telegram command name", name)
+		}
+		forwardCommands = append(forwardCommands, name)
+	}
+
+	var testParsers []string
+	if v, ok := os.LookupEnv("TRAMUNTANA_TEST_PARSERS"); ok {
+		testParsers = []string{}
+		if v != "none" {
+			testParsers = append(testParsers, parseList(v)...)
+		}
+	}
+
+	screenshotTheme := os.Getenv("SCREENSHOT_THEME")
+	if screenshotTheme == "" {
+		screenshotTheme = "dark"
+	}
+
+	screenshotFontSize := 28.0
+	if f := os.Getenv("SCREENSHOT_FONT_SIZE"); f != "" {
+		screenshotFontSize, err = strconv.ParseFloat(f, 64)
+		if err != nil || screenshotFontSize <= 0 {
+			return nil, fmt.Errorf("invalid SCREENSHOT_FONT_SIZE: %q", f)
+		}
+	}
+
+	var screenshotLineHeight int
+	if l := os.Getenv("SCREENSHOT_LINE_HEIGHT"); l != "" {
+		screenshotLineHeight, err = strconv.Atoi(l)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SCREENSHOT_LINE_HEIGHT: %w", err)
+		}
+	}
+
+	var screenshotMaxCols int
+	if c := os.Getenv("SCREENSHOT_MAX_COLS"); c != "" {
+		screenshotMaxCols, err = strconv.Atoi(c)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SCREENSHOT_MAX_COLS: %w", err)
+		}
+	}
+
 	return &Config{
 		TelegramBotToken:    token,
 		AllowedUsers:        users,
 		AllowedGroups:       groups,
-		TramuntanaDir:       dir,
+		TramuntanaDir:       dirs.State,
+		ConfigDir:           dirs.Config,
+		CacheDir:            dirs.Cache,
+		LegacyLayout:        dirs.Legacy,
 		TmuxSessionName:     sessionName,
 		ClaudeCommand:       claudeCmd,
 		MonitorPollInterval: pollInterval,
@@ -121,6 +624,72 @@ func Load(envFile ...string) (*Config, error) {
 		ApprovalsTopicID:    approvalsTopicID,
 		DefaultProject:      defaultProject,
 		PlannerPromptPath:   plannerPromptPath,
+		Verbosity:           verbosity,
+		DigestTime:          digestTime,
+		AutoHeartbeat:       autoHeartbeat,
+		AutoStallTimeout:    autoStallTimeout,
+		WorktreeCleanupAge:  worktreeCleanupAge,
+		GuardMaxLoad:        guardMaxLoad,
+		GuardMinFreeMB:      guardMinFreeMB,
+		GuardMaxSessions:    guardMaxSessions,
+		GuardMode:           guardMode,
+		AutoRestart:         autoRestart,
+		ResumeMode:          resumeMode,
+		BootstrapPolicy:     bootstrapPolicy,
+		BootstrapTailBytes:  bootstrapTailBytes,
+		MaxEntriesPerPoll:   maxEntriesPerPoll,
+		DedupWindow:         dedupWindow,
+		TmuxControlMode:     tmuxControlMode,
+		AutoPin:             autoPin,
+		NowSummary:          nowSummary,
+		ArtifactPatterns:    artifactPatterns,
+		ArtifactAutoSend:    artifactAutoSend,
+		LongPasteMode:       longPasteMode,
+		LongPasteChars:      longPasteChars,
+		AccessRequests:      accessRequests,
+		AdminChatID:         adminChatID,
+		Tracing:             tracing,
+		WindowNameTemplate:  windowName,
+		ControlAddr:         controlAddr,
+		ControlToken:        os.Getenv("TRAMUNTANA_CONTROL_TOKEN"),
+		WebhookAddr:         os.Getenv("TRAMUNTANA_WEBHOOK_ADDR"),
+		Coordinator:         coordinator,
+		FederationAddr:      os.Getenv("TRAMUNTANA_FEDERATION_ADDR"),
+		FederationHosts:     os.Getenv("TRAMUNTANA_FEDERATION_HOSTS"),
+		TopicIcons:          topicIcons,
+		Labels:              labels,
+		AlertNtfyURL:        alertNtfyURL,
+		AlertNtfyToken:      os.Getenv("TRAMUNTANA_ALERT_NTFY_TOKEN"),
+		AlertPushoverToken:  alertPushoverToken,
+		AlertPushoverUser:   alertPushoverUser,
+		AlertSMTPAddr:       alertSMTPAddr,
+		AlertSMTPUser:       alertSMTPUser,
+		AlertSMTPPassword:   os.Getenv("TRAMUNTANA_ALERT_SMTP_PASSWORD"),
+		AlertSMTPFrom:       alertSMTPFrom,
+		AlertSMTPTo:         alertSMTPTo,
+		AlertFloodBan:       alertFloodBan,
+		WatchdogStall:       watchdogStall,
+		PromptReminder:      promptReminder,
+		ArchiveDir:          archiveDir,
+		ArchiveS3Bucket:     archiveBucket,
+		ArchiveS3Region:     archiveRegion,
+		ArchiveS3Endpoint:   archiveEndpoint,
+		ArchiveS3Prefix:     os.Getenv("TRAMUNTANA_ARCHIVE_S3_PREFIX"),
+		AWSAccessKeyID:      os.Getenv("AWS_ACCESS_KEY_ID"),
+		AWSSecretAccessKey:  os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		AWSSessionToken:     os.Getenv("AWS_SESSION_TOKEN"),
+		ArchivePrune:        archivePrune,
+		AttentionTopicID:    attentionTopicID,
+		FeedTopicID:         feedTopicID,
+		AttentionAdmin:      attentionAdmin,
+		AllowedRoots:        allowedRoots,
+		ForwardCommands:     forwardCommands,
+		TestParsers:         testParsers,
+
+		ScreenshotTheme:      screenshotTheme,
+		ScreenshotFontSize:   screenshotFontSize,
+		ScreenshotLineHeight: screenshotLineHeight,
+		ScreenshotMaxCols:    screenshotMaxCols,
 	}, nil
 }
 
@@ -134,6 +703,8 @@ func (c *Config) IsAllowedUser(userID int64) bool {
 }
 
 func (c *Config) IsAllowedGroup(groupID int64) bool {
+	c.groupsMu.RLock()
+	defer c.groupsMu.RUnlock()
 	if len(c.AllowedGroups) == 0 {
 		return true // no restriction if not configured
 	}
@@ -145,6 +716,31 @@ func (c *Config) IsAllowedGroup(groupID int64) bool {
 	return false
 }
 
+// FirstAllowedGroup returns the first of AllowedGroups, or 0 if none is set.
+func (c *Config) FirstAllowedGroup() int64 {
+	c.groupsMu.RLock()
+	defer c.groupsMu.RUnlock()
+	if len(c.AllowedGroups) == 0 {
+		return 0
+	}
+	return c.AllowedGroups[0]
+}
+
+// ReplaceAllowedGroup swaps from for to in AllowedGroups, as when a group is
+// upgraded to a supergroup, and reports whether from was there.
+func (c *Config) ReplaceAllowedGroup(from, to int64) bool {
+	c.groupsMu.Lock()
+	defer c.groupsMu.Unlock()
+	replaced := false
+	for i, id := range c.AllowedGroups {
+		if id == from {
+			c.AllowedGroups[i] = to
+			replaced = true
+		}
+	}
+	return replaced
+}
+
 func parseIntList(s string) ([]int64, error) {
 	var result []int64
 	for _, part := range strings.Split(s, ",") {
@@ -164,6 +760,162 @@ func parseIntList(s string) ([]int64, error) {
 	return result, nil
 }
 
+// DefaultTopicIcons are the forum topic icons per session state. Telegram only
+// accepts the emoji listed by getForumTopicIconStickers.
+var DefaultTopicIcons = map[string]string{
+	"working": "⚡",
+	"input":   "❓",
+	"idle":    "✔",
+	"dead":    "❗",
+}
+
+// parseTopicIcons parses TRAMUNTANA_TOPIC_ICONS: a boolean for the defaults, or
+// state=emoji pairs overriding them ("working=🔥,idle=💤").
+func parseTopicIcons(s string) (map[string]string, error) {
+	if s == "" {
+		return nil, nil
+	}
+	if on, err := strconv.ParseBool(s); err == nil {
+		if !on {
+			return nil, nil
+		}
+		s = ""
+	}
+	icons := make(map[string]string, len(DefaultTopicIcons))
+	for state, emoji := range DefaultTopicIcons {
+		icons[state] = emoji
+	}
+	for _, pair := range parseList(s) {
+		state, emoji, ok := strings.Cut(pair, "=")
+		state, emoji = strings.TrimSpace(state), strings.TrimSpace(emoji)
+		if _, known := DefaultTopicIcons[state]; !ok || !known || emoji == "" {
+			return nil, fmt.Errorf("invalid TRAMUNTANA_TOPIC_ICONS entry %q (want working, input, idle or dead=<emoji>)", pair)
+		}
+		icons[state] = emoji
+	}
+	return icons, nil
+}
+
+// LabelOrigins are where delivered content can come from, in the order
+// themes are shown: Claude's answers, tool calls and their results, status
+// and notices, and prompts echoed back from the transcript.
+var LabelOrigins = []string{"assistant", "tool", "system", "user"}
+
+// LabelTheme maps a content origin to the glyph its messages start with. An
+// origin without a glyph is left unlabelled.
+type LabelTheme map[string]string
+
+// Label prefixes text with the glyph for origin, if the theme has one. The
+// glyph goes on a line of its own when text opens with Markdown that must
+// start a line: a code fence, heading, list, quote or table.
+func (t LabelTheme) Label(origin, text string) string {
+	g := t[origin]
+	if g == "" || text == "" {
+		return text
+	}
+	if opensBlock(text) {
+		return g + "\n" + text
+	}
+	return g + " " + text
+}
+
+// orderedListItem matches the start of an ordered list item.
+var orderedListItem = regexp.MustCompile(`^\d+[.)]( |$)`)
+
+// opensBlock reports whether text's first line starts a Markdown block that
+// only parses at the start of a line.
+func opensBlock(text string) bool {
+	first, rest, _ := strings.Cut(text, "\n")
+	if strings.HasPrefix(first, "    ") || strings.HasPrefix(first, "\t") {
+		return true // indented code
+	}
+	first = strings.TrimLeft(first, " ")
+	for _, p := range []string{"```", "~~~", "#", ">", "|", "- ", "* ", "+ "} {
+		if strings.HasPrefix(first, p) {
+			return true
+		}
+	}
+	if orderedListItem.MatchString(first) {
+		return true
+	}
+	// A table without a leading pipe: its second line is the delimiter row
+	second, _, _ := strings.Cut(rest, "\n")
+	return strings.Contains(second, "-") && strings.Contains(second, "|") && strings.Trim(second, "|-: ") == ""
+}
+
+// LabelThemes are the themes /labels and TRAMUNTANA_LABELS can pick. The
+// classic theme only marks echoed prompts.
+var LabelThemes = map[string]LabelTheme{
+	"classic": {"user": "👤"},
+	"emoji":   {"assistant": "🤖", "tool": "🔧", "system": "⚙️", "user": "👤"},
+	"dots":    {"assistant": "🟢", "tool": "⚪", "system": "🟡", "user": "🔵"},
+	"none":    {},
+}
+
+// LabelTheme returns the theme called name, or the configured one for ""
+// and names it doesn't know.
+func (c *Config) LabelTheme(name string) LabelTheme {
+	if t, ok := LabelThemes[name]; ok {
+		return t
+	}
+	if c.Labels != nil {
+		return c.Labels
+	}
+	return LabelThemes["classic"]
+}
+
+// labelReserved are the characters a custom glyph can't contain: those
+// MarkdownV2 reserves, the backslash and whitespace.
+const labelReserved = "_*[]()~`>#+-=|{}.!\\ \t"
+
+// parseLabels parses TRAMUNTANA_LABELS: a theme name, or origin=glyph pairs
+// overriding the classic theme ("assistant=💬,tool=🔧").
+func parseLabels(s string) (LabelTheme, error) {
+	if s == "" {
+		return LabelThemes["classic"], nil
+	}
+	if t, ok := LabelThemes[s]; ok {
+		return t, nil
+	}
+	labels := LabelTheme{"user": LabelThemes["classic"]["user"]}
+	for _, pair := range parseList(s) {
+		origin, glyph, ok := strings.Cut(pair, "=")
+		origin, glyph = strings.TrimSpace(origin), strings.TrimSpace(glyph)
+		// Glyphs go in front of Markdown, so they can't carry any, nor any
+		// character MarkdownV2 reserves
+		if !ok || !slices.Contains(LabelOrigins, origin) || strings.ContainsAny(glyph, labelReserved) {
+			return nil, fmt.Errorf("invalid TRAMUNTANA_LABELS entry %q (want a theme (classic, emoji, dots, none) or assistant, tool, system or user=<glyph>)", pair)
+		}
+		labels[origin] = glyph
+	}
+	return labels, nil
+}
+
+// parseList splits a comma-separated list, dropping empty items.
+func parseList(s string) []string {
+	var result []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			result = append(result, part)
+		}
+	}
+	return result
+}
+
+// isCommandName reports whether s is a valid Telegram bot command: 1-32
+// lowercase letters, digits and underscores.
+func isCommandName(s string) bool {
+	if s == "" || len(s) > 32 {
+		return false
+	}
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '_' {
+			return false
+		}
+	}
+	return true
+}
+
 func expandHome(path string) string {
 	if strings.HasPrefix(path, "~/") {
 		home, err := os.UserHomeDir()
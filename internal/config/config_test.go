@@ -3,7 +3,9 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
+	"time"
 )
 
 func clearEnv() {
@@ -11,6 +13,23 @@ func clearEnv() {
 		"TELEGRAM_BOT_TOKEN", "ALLOWED_USERS", "ALLOWED_GROUPS",
 		"TRAMUNTANA_DIR", "TMUX_SESSION_NAME", "CLAUDE_COMMAND",
 		"MONITOR_POLL_INTERVAL", "MINUANO_BIN", "MINUANO_DB",
+		"TRAMUNTANA_DIGEST_TIME", "TRAMUNTANA_RESUME", "TRAMUNTANA_BOOTSTRAP",
+		"TRAMUNTANA_AUTO_PIN", "TRAMUNTANA_ARTIFACT_PATTERNS", "TRAMUNTANA_ARTIFACT_AUTOSEND_KB",
+		"TRAMUNTANA_LONG_PASTE", "TRAMUNTANA_LONG_PASTE_CHARS",
+		"TRAMUNTANA_ACCESS_REQUESTS", "TRAMUNTANA_ADMIN_CHAT",
+		"TRAMUNTANA_TRACING", "TRAMUNTANA_WINDOW_NAME", "TRAMUNTANA_TOPIC_ICONS",
+		"TRAMUNTANA_ATTENTION_TOPIC_ID",
+		"TRAMUNTANA_ALLOWED_ROOTS", "TRAMUNTANA_FORWARD_COMMANDS", "TRAMUNTANA_TEST_PARSERS",
+		"TRAMUNTANA_CONTROL_ADDR", "TRAMUNTANA_CONTROL_TOKEN", "TRAMUNTANA_WEBHOOK_ADDR",
+		"TRAMUNTANA_GUARD_MAX_LOAD", "TRAMUNTANA_GUARD_MIN_FREE_MB", "TRAMUNTANA_GUARD_MAX_SESSIONS", "TRAMUNTANA_GUARD_MODE",
+		"TRAMUNTANA_NOW_SUMMARY", "TRAMUNTANA_FEED_TOPIC_ID", "TRAMUNTANA_DEDUP_WINDOW_MINUTES",
+		"TRAMUNTANA_ALERT_NTFY_URL", "TRAMUNTANA_ALERT_NTFY_TOKEN", "TRAMUNTANA_ALERT_PUSHOVER_TOKEN", "TRAMUNTANA_ALERT_PUSHOVER_USER",
+		"TRAMUNTANA_ALERT_SMTP_ADDR", "TRAMUNTANA_ALERT_SMTP_USER", "TRAMUNTANA_ALERT_SMTP_PASSWORD", "TRAMUNTANA_ALERT_SMTP_FROM",
+		"TRAMUNTANA_ALERT_SMTP_TO", "TRAMUNTANA_ALERT_FLOOD_MINUTES", "TRAMUNTANA_WATCHDOG_STALL_MINUTES",
+		"TRAMUNTANA_PROMPT_REMINDER_MINUTES",
+		"TRAMUNTANA_ARCHIVE_DIR", "TRAMUNTANA_ARCHIVE_S3_BUCKET", "TRAMUNTANA_ARCHIVE_S3_REGION", "TRAMUNTANA_ARCHIVE_S3_ENDPOINT",
+		"TRAMUNTANA_ARCHIVE_S3_PREFIX", "TRAMUNTANA_ARCHIVE_PRUNE", "AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN",
+		"TRAMUNTANA_LABELS",
 	} {
 		os.Unsetenv(key)
 	}
@@ -129,6 +148,34 @@ func TestLoad_CreatesTramuntanaDir(t *testing.T) {
 	}
 }
 
+func TestLoad_XDGLayout(t *testing.T) {
+	clearEnv()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "cfg"))
+	t.Setenv("XDG_STATE_HOME", "")
+	t.Setenv("XDG_CACHE_HOME", "")
+	os.MkdirAll(filepath.Join(home, "cfg", "tramuntana"), 0755)
+	os.WriteFile(filepath.Join(home, "cfg", "tramuntana", ".env"), []byte("TELEGRAM_BOT_TOKEN=xdg-token\nALLOWED_USERS=7\n"), 0600)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.TelegramBotToken != "xdg-token" {
+		t.Errorf("token = %q, want the one from the XDG config .env", cfg.TelegramBotToken)
+	}
+	if want := filepath.Join(home, ".local", "state", "tramuntana"); cfg.TramuntanaDir != want {
+		t.Errorf("state dir = %q, want %q", cfg.TramuntanaDir, want)
+	}
+	if _, err := os.Stat(cfg.CacheDir); err != nil {
+		t.Errorf("cache dir was not created: %v", err)
+	}
+	if cfg.LegacyLayout {
+		t.Error("no ~/.tramuntana, so not a legacy layout")
+	}
+}
+
 func TestLoad_InvalidPollInterval(t *testing.T) {
 	clearEnv()
 	os.Setenv("TELEGRAM_BOT_TOKEN", "tok")
@@ -142,6 +189,629 @@ func TestLoad_InvalidPollInterval(t *testing.T) {
 	}
 }
 
+func TestLoad_DigestTime(t *testing.T) {
+	clearEnv()
+	os.Setenv("TELEGRAM_BOT_TOKEN", "tok")
+	os.Setenv("ALLOWED_USERS", "1")
+	os.Setenv("TRAMUNTANA_DIR", t.TempDir())
+	os.Setenv("TRAMUNTANA_DIGEST_TIME", "9:30")
+	defer os.Unsetenv("TRAMUNTANA_DIGEST_TIME")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DigestTime != "09:30" {
+		t.Errorf("DigestTime = %q, want 09:30", cfg.DigestTime)
+	}
+
+	os.Setenv("TRAMUNTANA_DIGEST_TIME", "late")
+	if _, err := Load(); err == nil {
+		t.Fatal("expected error for invalid digest time")
+	}
+}
+
+func TestLoad_ResumeMode(t *testing.T) {
+	clearEnv()
+	os.Setenv("TELEGRAM_BOT_TOKEN", "tok")
+	os.Setenv("ALLOWED_USERS", "1")
+	os.Setenv("TRAMUNTANA_DIR", t.TempDir())
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ResumeMode != "fresh" {
+		t.Errorf("default ResumeMode = %q, want fresh", cfg.ResumeMode)
+	}
+
+	os.Setenv("TRAMUNTANA_RESUME", "ask")
+	defer os.Unsetenv("TRAMUNTANA_RESUME")
+	if cfg, err = Load(); err != nil || cfg.ResumeMode != "ask" {
+		t.Errorf("ResumeMode = %v, %v; want ask", cfg, err)
+	}
+
+	os.Setenv("TRAMUNTANA_RESUME", "always")
+	if _, err := Load(); err == nil {
+		t.Fatal("expected error for invalid resume mode")
+	}
+}
+
+func TestLoad_Bootstrap(t *testing.T) {
+	clearEnv()
+	os.Setenv("TELEGRAM_BOT_TOKEN", "tok")
+	os.Setenv("ALLOWED_USERS", "1")
+	os.Setenv("TRAMUNTANA_DIR", t.TempDir())
+	defer os.Unsetenv("TRAMUNTANA_BOOTSTRAP")
+
+	tests := []struct {
+		env    string
+		policy string
+		tail   int64
+	}{
+		{"", "eof", 0},
+		{"full", "full", 0},
+		{"65536", "tail", 65536},
+	}
+	for _, tt := range tests {
+		os.Setenv("TRAMUNTANA_BOOTSTRAP", tt.env)
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("%q: %v", tt.env, err)
+		}
+		if cfg.BootstrapPolicy != tt.policy || cfg.BootstrapTailBytes != tt.tail {
+			t.Errorf("%q: got %s/%d, want %s/%d", tt.env, cfg.BootstrapPolicy, cfg.BootstrapTailBytes, tt.policy, tt.tail)
+		}
+	}
+
+	os.Setenv("TRAMUNTANA_BOOTSTRAP", "-5")
+	if _, err := Load(); err == nil {
+		t.Fatal("expected error for invalid bootstrap policy")
+	}
+}
+
+func TestLoad_DedupWindow(t *testing.T) {
+	clearEnv()
+	os.Setenv("TELEGRAM_BOT_TOKEN", "tok")
+	os.Setenv("ALLOWED_USERS", "1")
+	os.Setenv("TRAMUNTANA_DIR", t.TempDir())
+	defer os.Unsetenv("TRAMUNTANA_DEDUP_WINDOW_MINUTES")
+
+	cfg, err := Load()
+	if err != nil || cfg.DedupWindow != 30*time.Minute {
+		t.Fatalf("DedupWindow should default to 30m: %v, %v", cfg.DedupWindow, err)
+	}
+	os.Setenv("TRAMUNTANA_DEDUP_WINDOW_MINUTES", "0")
+	if cfg, err = Load(); err != nil || cfg.DedupWindow != 0 {
+		t.Fatalf("0 should disable it: %v, %v", cfg.DedupWindow, err)
+	}
+	os.Setenv("TRAMUNTANA_DEDUP_WINDOW_MINUTES", "soon")
+	if _, err := Load(); err == nil {
+		t.Fatal("expected error for invalid dedup window")
+	}
+}
+
+func TestLoad_Alerts(t *testing.T) {
+	clearEnv()
+	os.Setenv("TELEGRAM_BOT_TOKEN", "tok")
+	os.Setenv("ALLOWED_USERS", "1")
+	os.Setenv("TRAMUNTANA_DIR", t.TempDir())
+	defer clearEnv()
+
+	cfg, err := Load()
+	if err != nil || cfg.AlertFloodBan != 10*time.Minute || cfg.AlertNtfyURL != "" {
+		t.Fatalf("alerts should default to off with a 10m flood threshold: %+v, %v", cfg, err)
+	}
+
+	os.Setenv("TRAMUNTANA_ALERT_NTFY_URL", "https://ntfy.sh/alerts")
+	os.Setenv("TRAMUNTANA_ALERT_SMTP_ADDR", "mail.example.com:587")
+	os.Setenv("TRAMUNTANA_ALERT_SMTP_USER", "bot@example.com")
+	os.Setenv("TRAMUNTANA_ALERT_SMTP_TO", "me@example.com, oncall@example.com")
+	os.Setenv("TRAMUNTANA_ALERT_FLOOD_MINUTES", "5")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.AlertSMTPFrom != "bot@example.com" || len(cfg.AlertSMTPTo) != 2 || cfg.AlertFloodBan != 5*time.Minute {
+		t.Errorf("got from %q, to %v, flood %v", cfg.AlertSMTPFrom, cfg.AlertSMTPTo, cfg.AlertFloodBan)
+	}
+
+	for key, value := range map[string]string{
+		"TRAMUNTANA_ALERT_NTFY_URL":       "https://ntfy.sh/",
+		"TRAMUNTANA_ALERT_PUSHOVER_TOKEN": "app-token-without-user",
+		"TRAMUNTANA_ALERT_SMTP_ADDR":      "mail.example.com",
+		"TRAMUNTANA_ALERT_FLOOD_MINUTES":  "-1",
+	} {
+		old := os.Getenv(key)
+		os.Setenv(key, value)
+		if _, err := Load(); err == nil {
+			t.Errorf("expected error for %s=%q", key, value)
+		}
+		os.Setenv(key, old)
+	}
+}
+
+func TestLoad_WatchdogStall(t *testing.T) {
+	clearEnv()
+	os.Setenv("TELEGRAM_BOT_TOKEN", "tok")
+	os.Setenv("ALLOWED_USERS", "1")
+	os.Setenv("TRAMUNTANA_DIR", t.TempDir())
+	defer clearEnv()
+
+	cfg, err := Load()
+	if err != nil || cfg.WatchdogStall != 2*time.Minute {
+		t.Fatalf("watchdog stall should default to 2m: %v, %v", cfg.WatchdogStall, err)
+	}
+	os.Setenv("TRAMUNTANA_WATCHDOG_STALL_MINUTES", "0")
+	if cfg, err = Load(); err != nil || cfg.WatchdogStall != 0 {
+		t.Errorf("TRAMUNTANA_WATCHDOG_STALL_MINUTES=0: %v, %v", cfg.WatchdogStall, err)
+	}
+	os.Setenv("TRAMUNTANA_WATCHDOG_STALL_MINUTES", "soon")
+	if _, err := Load(); err == nil {
+		t.Error("expected error for TRAMUNTANA_WATCHDOG_STALL_MINUTES=soon")
+	}
+}
+
+func TestLoad_PromptReminder(t *testing.T) {
+	clearEnv()
+	os.Setenv("TELEGRAM_BOT_TOKEN", "tok")
+	os.Setenv("ALLOWED_USERS", "1")
+	os.Setenv("TRAMUNTANA_DIR", t.TempDir())
+	defer clearEnv()
+
+	cfg, err := Load()
+	if err != nil || cfg.PromptReminder != 15*time.Minute {
+		t.Fatalf("prompt reminder should default to 15m: %v, %v", cfg.PromptReminder, err)
+	}
+	os.Setenv("TRAMUNTANA_PROMPT_REMINDER_MINUTES", "0")
+	if cfg, err = Load(); err != nil || cfg.PromptReminder != 0 {
+		t.Errorf("TRAMUNTANA_PROMPT_REMINDER_MINUTES=0: %v, %v", cfg.PromptReminder, err)
+	}
+	os.Setenv("TRAMUNTANA_PROMPT_REMINDER_MINUTES", "-5")
+	if _, err := Load(); err == nil {
+		t.Error("expected error for TRAMUNTANA_PROMPT_REMINDER_MINUTES=-5")
+	}
+}
+
+func TestLoad_Archive(t *testing.T) {
+	clearEnv()
+	os.Setenv("TELEGRAM_BOT_TOKEN", "tok")
+	os.Setenv("ALLOWED_USERS", "1")
+	os.Setenv("TRAMUNTANA_DIR", t.TempDir())
+	defer clearEnv()
+
+	cfg, err := Load()
+	if err != nil || cfg.ArchiveDir != "" || cfg.ArchiveS3Bucket != "" || cfg.ArchivePrune {
+		t.Fatalf("archiving should default to off: %+v, %v", cfg, err)
+	}
+
+	os.Setenv("TRAMUNTANA_ARCHIVE_S3_BUCKET", "logs")
+	os.Setenv("TRAMUNTANA_ARCHIVE_S3_ENDPOINT", "http://minio:9000")
+	os.Setenv("AWS_ACCESS_KEY_ID", "key")
+	os.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	os.Setenv("TRAMUNTANA_ARCHIVE_PRUNE", "true")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ArchiveS3Region != "us-east-1" || cfg.AWSSecretAccessKey != "secret" || !cfg.ArchivePrune {
+		t.Errorf("got region %q, prune %v", cfg.ArchiveS3Region, cfg.ArchivePrune)
+	}
+
+	for key, value := range map[string]string{
+		"TRAMUNTANA_ARCHIVE_DIR":         "/var/archive",
+		"TRAMUNTANA_ARCHIVE_S3_ENDPOINT": "minio:9000",
+		"AWS_SECRET_ACCESS_KEY":          "",
+		"TRAMUNTANA_ARCHIVE_PRUNE":       "sometimes",
+	} {
+		old := os.Getenv(key)
+		os.Setenv(key, value)
+		if _, err := Load(); err == nil {
+			t.Errorf("expected error for %s=%q", key, value)
+		}
+		os.Setenv(key, old)
+	}
+
+	clearEnv()
+	os.Setenv("TELEGRAM_BOT_TOKEN", "tok")
+	os.Setenv("ALLOWED_USERS", "1")
+	os.Setenv("TRAMUNTANA_DIR", t.TempDir())
+	os.Setenv("TRAMUNTANA_ARCHIVE_PRUNE", "true")
+	if _, err := Load(); err == nil {
+		t.Error("expected error for TRAMUNTANA_ARCHIVE_PRUNE without an archive")
+	}
+}
+
+func TestLoad_Labels(t *testing.T) {
+	clearEnv()
+	os.Setenv("TELEGRAM_BOT_TOKEN", "tok")
+	os.Setenv("ALLOWED_USERS", "1")
+	os.Setenv("TRAMUNTANA_DIR", t.TempDir())
+	defer clearEnv()
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.Labels.Label("user", "hi"); got != "👤 hi" || cfg.Labels.Label("assistant", "hi") != "hi" {
+		t.Errorf("classic labels: user %q", got)
+	}
+
+	os.Setenv("TRAMUNTANA_LABELS", "emoji")
+	if cfg, err = Load(); err != nil || cfg.Labels.Label("tool", "● Bash") != "🔧 ● Bash" {
+		t.Errorf("TRAMUNTANA_LABELS=emoji: %v, %v", cfg.Labels, err)
+	}
+	os.Setenv("TRAMUNTANA_LABELS", "assistant=💬, user=")
+	if cfg, err = Load(); err != nil || cfg.Labels.Label("assistant", "hi") != "💬 hi" || cfg.Labels.Label("user", "hi") != "hi" {
+		t.Errorf("custom labels: %v, %v", cfg.Labels, err)
+	}
+	if got := cfg.LabelTheme("dots").Label("system", "x"); got != "🟡 x" {
+		t.Errorf("LabelTheme(dots) = %q", got)
+	}
+	if got := cfg.LabelTheme("").Label("assistant", "x"); got != "💬 x" {
+		t.Errorf("LabelTheme(\"\") should be the configured theme, got %q", got)
+	}
+
+	for _, bad := range []string{"neon", "robot=🤖", "tool=*", "tool=!", "tool=-", "tool={}", "tool=1."} {
+		os.Setenv("TRAMUNTANA_LABELS", bad)
+		if _, err := Load(); err == nil {
+			t.Errorf("expected error for TRAMUNTANA_LABELS=%q", bad)
+		}
+	}
+}
+
+func TestLabelTheme_GlyphOwnLineBeforeBlocks(t *testing.T) {
+	theme := LabelThemes["emoji"]
+	tests := []struct {
+		text, want string
+	}{
+		{"done", "🤖 done"},
+		{"**Bold** start", "🤖 **Bold** start"},
+		{"```go\nx := 1\n```", "🤖\n```go\nx := 1\n```"},
+		{"# Plan\nsteps", "🤖\n# Plan\nsteps"},
+		{"- one\n- two", "🤖\n- one\n- two"},
+		{"1. one\n2. two", "🤖\n1. one\n2. two"},
+		{"> quoted", "🤖\n> quoted"},
+		{"| a | b |\n|---|---|", "🤖\n| a | b |\n|---|---|"},
+		{"a | b\n--|--", "🤖\na | b\n--|--"},
+		{"    indented code", "🤖\n    indented code"},
+		{"2024 was fine", "🤖 2024 was fine"},
+	}
+	for _, tt := range tests {
+		if got := theme.Label("assistant", tt.text); got != tt.want {
+			t.Errorf("Label(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestLoad_AutoPin(t *testing.T) {
+	clearEnv()
+	os.Setenv("TELEGRAM_BOT_TOKEN", "tok")
+	os.Setenv("ALLOWED_USERS", "1")
+	os.Setenv("TRAMUNTANA_DIR", t.TempDir())
+	defer os.Unsetenv("TRAMUNTANA_AUTO_PIN")
+
+	cfg, err := Load()
+	if err != nil || cfg.AutoPin {
+		t.Fatalf("AutoPin should default to off: %v, %v", cfg, err)
+	}
+	os.Setenv("TRAMUNTANA_AUTO_PIN", "true")
+	if cfg, err = Load(); err != nil || !cfg.AutoPin {
+		t.Errorf("AutoPin = %v, %v; want on", cfg, err)
+	}
+	os.Setenv("TRAMUNTANA_AUTO_PIN", "sometimes")
+	if _, err := Load(); err == nil {
+		t.Fatal("expected error for invalid TRAMUNTANA_AUTO_PIN")
+	}
+}
+
+func TestLoad_Artifacts(t *testing.T) {
+	clearEnv()
+	os.Setenv("TELEGRAM_BOT_TOKEN", "tok")
+	os.Setenv("ALLOWED_USERS", "1")
+	os.Setenv("TRAMUNTANA_DIR", t.TempDir())
+	defer clearEnv()
+
+	cfg, err := Load()
+	if err != nil || len(cfg.ArtifactPatterns) != 0 || cfg.ArtifactAutoSend != 0 {
+		t.Fatalf("artifacts should default to off: %+v, %v", cfg, err)
+	}
+
+	os.Setenv("TRAMUNTANA_ARTIFACT_PATTERNS", "*.png, report.md,,dist/*.tar.gz")
+	os.Setenv("TRAMUNTANA_ARTIFACT_AUTOSEND_KB", "512")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"*.png", "report.md", "dist/*.tar.gz"}
+	if len(cfg.ArtifactPatterns) != len(want) {
+		t.Fatalf("patterns = %q, want %q", cfg.ArtifactPatterns, want)
+	}
+	for i := range want {
+		if cfg.ArtifactPatterns[i] != want[i] {
+			t.Errorf("pattern %d = %q, want %q", i, cfg.ArtifactPatterns[i], want[i])
+		}
+	}
+	if cfg.ArtifactAutoSend != 512*1024 {
+		t.Errorf("ArtifactAutoSend = %d", cfg.ArtifactAutoSend)
+	}
+
+	os.Setenv("TRAMUNTANA_ARTIFACT_PATTERNS", "[")
+	if _, err := Load(); err == nil {
+		t.Error("expected error for malformed pattern")
+	}
+}
+
+func TestLoad_LongPaste(t *testing.T) {
+	clearEnv()
+	os.Setenv("TELEGRAM_BOT_TOKEN", "tok")
+	os.Setenv("ALLOWED_USERS", "1")
+	os.Setenv("TRAMUNTANA_DIR", t.TempDir())
+	defer clearEnv()
+
+	cfg, err := Load()
+	if err != nil || cfg.LongPasteMode != "file" || cfg.LongPasteChars != 2000 {
+		t.Fatalf("defaults = %q/%d, %v; want file/2000", cfg.LongPasteMode, cfg.LongPasteChars, err)
+	}
+
+	os.Setenv("TRAMUNTANA_LONG_PASTE", "buffer")
+	os.Setenv("TRAMUNTANA_LONG_PASTE_CHARS", "500")
+	if cfg, err = Load(); err != nil || cfg.LongPasteMode != "buffer" || cfg.LongPasteChars != 500 {
+		t.Errorf("got %q/%d, %v; want buffer/500", cfg.LongPasteMode, cfg.LongPasteChars, err)
+	}
+
+	os.Setenv("TRAMUNTANA_LONG_PASTE", "clipboard")
+	if _, err := Load(); err == nil {
+		t.Error("expected error for invalid mode")
+	}
+	os.Setenv("TRAMUNTANA_LONG_PASTE", "keys")
+	os.Setenv("TRAMUNTANA_LONG_PASTE_CHARS", "0")
+	if _, err := Load(); err == nil {
+		t.Error("expected error for zero threshold")
+	}
+}
+
+func TestLoad_AccessRequests(t *testing.T) {
+	clearEnv()
+	os.Setenv("TELEGRAM_BOT_TOKEN", "tok")
+	os.Setenv("ALLOWED_USERS", "7,8")
+	os.Setenv("TRAMUNTANA_DIR", t.TempDir())
+	defer clearEnv()
+
+	cfg, err := Load()
+	if err != nil || cfg.AccessRequests || cfg.AdminChatID != 7 {
+		t.Fatalf("defaults = %v/%d, %v; want off, admin chat 7", cfg.AccessRequests, cfg.AdminChatID, err)
+	}
+
+	os.Setenv("TRAMUNTANA_ACCESS_REQUESTS", "true")
+	os.Setenv("TRAMUNTANA_ADMIN_CHAT", "-1001")
+	if cfg, err = Load(); err != nil || !cfg.AccessRequests || cfg.AdminChatID != -1001 {
+		t.Errorf("got %v/%d, %v; want on, admin chat -1001", cfg.AccessRequests, cfg.AdminChatID, err)
+	}
+
+	os.Setenv("TRAMUNTANA_ADMIN_CHAT", "owner")
+	if _, err := Load(); err == nil {
+		t.Error("expected error for invalid TRAMUNTANA_ADMIN_CHAT")
+	}
+}
+
+func TestLoad_Tracing(t *testing.T) {
+	clearEnv()
+	os.Setenv("TELEGRAM_BOT_TOKEN", "tok")
+	os.Setenv("ALLOWED_USERS", "1")
+	os.Setenv("TRAMUNTANA_DIR", t.TempDir())
+	defer clearEnv()
+
+	if cfg, err := Load(); err != nil || cfg.Tracing {
+		t.Fatalf("default Tracing = %v, %v; want off", cfg.Tracing, err)
+	}
+	os.Setenv("TRAMUNTANA_TRACING", "1")
+	if cfg, err := Load(); err != nil || !cfg.Tracing {
+		t.Errorf("Tracing = %v, %v; want on", cfg.Tracing, err)
+	}
+	os.Setenv("TRAMUNTANA_TRACING", "sometimes")
+	if _, err := Load(); err == nil {
+		t.Error("expected error for invalid TRAMUNTANA_TRACING")
+	}
+}
+
+func TestLoad_ControlAddr(t *testing.T) {
+	clearEnv()
+	os.Setenv("TELEGRAM_BOT_TOKEN", "tok")
+	os.Setenv("ALLOWED_USERS", "1")
+	os.Setenv("TRAMUNTANA_DIR", t.TempDir())
+	defer clearEnv()
+
+	for _, addr := range []string{"", "unix:/tmp/tramuntana.sock", "127.0.0.1:8765"} {
+		os.Setenv("TRAMUNTANA_CONTROL_ADDR", addr)
+		if cfg, err := Load(); err != nil || cfg.ControlAddr != addr {
+			t.Errorf("ControlAddr for %q = %q, %v", addr, cfg.ControlAddr, err)
+		}
+	}
+	os.Setenv("TRAMUNTANA_CONTROL_ADDR", "8765")
+	if _, err := Load(); err == nil {
+		t.Error("expected error for a port without a host")
+	}
+}
+
+func TestLoad_WindowNameTemplate(t *testing.T) {
+	clearEnv()
+	os.Setenv("TELEGRAM_BOT_TOKEN", "tok")
+	os.Setenv("ALLOWED_USERS", "1")
+	os.Setenv("TRAMUNTANA_DIR", t.TempDir())
+	defer clearEnv()
+
+	os.Setenv("TRAMUNTANA_WINDOW_NAME", "{{.Project}}·{{.Branch}}")
+	if cfg, err := Load(); err != nil || cfg.WindowNameTemplate != "{{.Project}}·{{.Branch}}" {
+		t.Fatalf("WindowNameTemplate = %q, %v", cfg.WindowNameTemplate, err)
+	}
+	os.Setenv("TRAMUNTANA_WINDOW_NAME", "{{.Project")
+	if _, err := Load(); err == nil {
+		t.Error("expected error for invalid template")
+	}
+}
+
+func TestParseTopicIcons(t *testing.T) {
+	if icons, err := parseTopicIcons(""); icons != nil || err != nil {
+		t.Errorf("unset = %v, %v; want disabled", icons, err)
+	}
+	if icons, err := parseTopicIcons("false"); icons != nil || err != nil {
+		t.Errorf("false = %v, %v; want disabled", icons, err)
+	}
+	icons, err := parseTopicIcons("true")
+	if err != nil || icons["working"] != DefaultTopicIcons["working"] || len(icons) != len(DefaultTopicIcons) {
+		t.Errorf("true = %v, %v; want the defaults", icons, err)
+	}
+	icons, err = parseTopicIcons("working=🔥, idle=💤")
+	if err != nil || icons["working"] != "🔥" || icons["idle"] != "💤" || icons["dead"] != DefaultTopicIcons["dead"] {
+		t.Errorf("overrides = %v, %v", icons, err)
+	}
+	for _, bad := range []string{"sleeping=💤", "working", "working="} {
+		if _, err := parseTopicIcons(bad); err == nil {
+			t.Errorf("parseTopicIcons(%q): expected error", bad)
+		}
+	}
+}
+
+func TestLoad_AttentionTopic(t *testing.T) {
+	clearEnv()
+	os.Setenv("TELEGRAM_BOT_TOKEN", "tok")
+	os.Setenv("ALLOWED_USERS", "1")
+	os.Setenv("TRAMUNTANA_DIR", t.TempDir())
+	defer clearEnv()
+
+	os.Setenv("TRAMUNTANA_ATTENTION_TOPIC_ID", "77")
+	if cfg, err := Load(); err != nil || cfg.AttentionTopicID != 77 || cfg.AttentionAdmin {
+		t.Fatalf("got %d/%v, %v; want topic 77", cfg.AttentionTopicID, cfg.AttentionAdmin, err)
+	}
+	os.Setenv("TRAMUNTANA_ATTENTION_TOPIC_ID", "admin")
+	if cfg, err := Load(); err != nil || cfg.AttentionTopicID != 0 || !cfg.AttentionAdmin {
+		t.Fatalf("got %d/%v, %v; want the admin chat", cfg.AttentionTopicID, cfg.AttentionAdmin, err)
+	}
+	os.Setenv("TRAMUNTANA_ATTENTION_TOPIC_ID", "overview")
+	if _, err := Load(); err == nil {
+		t.Error("expected error for invalid TRAMUNTANA_ATTENTION_TOPIC_ID")
+	}
+}
+
+func TestLoad_AllowedRoots(t *testing.T) {
+	clearEnv()
+	os.Setenv("TELEGRAM_BOT_TOKEN", "tok")
+	os.Setenv("ALLOWED_USERS", "1")
+	os.Setenv("TRAMUNTANA_DIR", t.TempDir())
+	defer clearEnv()
+
+	home, _ := os.UserHomeDir()
+	os.Setenv("TRAMUNTANA_ALLOWED_ROOTS", "~/code, /srv/shared/")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(home, "code"), "/srv/shared"}
+	if !reflect.DeepEqual(cfg.AllowedRoots, want) {
+		t.Errorf("AllowedRoots = %v, want %v", cfg.AllowedRoots, want)
+	}
+
+	os.Setenv("TRAMUNTANA_ALLOWED_ROOTS", "code")
+	if _, err := Load(); err == nil {
+		t.Error("expected error for a relative root")
+	}
+}
+
+func TestLoad_ForwardCommands(t *testing.T) {
+	clearEnv()
+	os.Setenv("TELEGRAM_BOT_TOKEN", "tok")
+	os.Setenv("ALLOWED_USERS", "1")
+	os.Setenv("TRAMUNTANA_DIR", t.TempDir())
+	defer clearEnv()
+
+	os.Setenv("TRAMUNTANA_FORWARD_COMMANDS", "review, /Init,security_review")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"review", "init", "security_review"}
+	if !reflect.DeepEqual(cfg.ForwardCommands, want) {
+		t.Errorf("ForwardCommands = %v, want %v", cfg.ForwardCommands, want)
+	}
+
+	os.Setenv("TRAMUNTANA_FORWARD_COMMANDS", "security-review")
+	if _, err := Load(); err == nil {
+		t.Error("expected error for a name Telegram can't use")
+	}
+}
+
+func TestLoad_TestParsers(t *testing.T) {
+	clearEnv()
+	os.Setenv("TELEGRAM_BOT_TOKEN", "tok")
+	os.Setenv("ALLOWED_USERS", "1")
+	os.Setenv("TRAMUNTANA_DIR", t.TempDir())
+	defer clearEnv()
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.TestParsers != nil {
+		t.Errorf("unset TestParsers = %v, want nil", cfg.TestParsers)
+	}
+
+	os.Setenv("TRAMUNTANA_TEST_PARSERS", "go, pytest")
+	if cfg, _ = Load(); !reflect.DeepEqual(cfg.TestParsers, []string{"go", "pytest"}) {
+		t.Errorf("TestParsers = %v", cfg.TestParsers)
+	}
+	os.Setenv("TRAMUNTANA_TEST_PARSERS", "none")
+	if cfg, _ = Load(); cfg.TestParsers == nil || len(cfg.TestParsers) != 0 {
+		t.Errorf("none = %#v, want an empty list", cfg.TestParsers)
+	}
+}
+
+func TestLoad_InvalidClaudeCommandTemplate(t *testing.T) {
+	clearEnv()
+	os.Setenv("TELEGRAM_BOT_TOKEN", "tok")
+	os.Setenv("ALLOWED_USERS", "1")
+	os.Setenv("TRAMUNTANA_DIR", t.TempDir())
+	os.Setenv("CLAUDE_COMMAND", "claude --add-dir {{.Dir")
+	defer clearEnv()
+
+	if _, err := Load(); err == nil {
+		t.Error("expected error for unparsable CLAUDE_COMMAND template")
+	}
+}
+
+func TestLoad_ResourceGuard(t *testing.T) {
+	clearEnv()
+	os.Setenv("TELEGRAM_BOT_TOKEN", "tok")
+	os.Setenv("ALLOWED_USERS", "1")
+	os.Setenv("TRAMUNTANA_DIR", t.TempDir())
+	defer clearEnv()
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.GuardMaxLoad != 0 || cfg.GuardMinFreeMB != 0 || cfg.GuardMaxSessions != 0 || cfg.GuardMode != "refuse" {
+		t.Errorf("defaults = %v %v %v %q", cfg.GuardMaxLoad, cfg.GuardMinFreeMB, cfg.GuardMaxSessions, cfg.GuardMode)
+	}
+
+	os.Setenv("TRAMUNTANA_GUARD_MAX_LOAD", "1.5")
+	os.Setenv("TRAMUNTANA_GUARD_MIN_FREE_MB", "2048")
+	os.Setenv("TRAMUNTANA_GUARD_MAX_SESSIONS", "5")
+	os.Setenv("TRAMUNTANA_GUARD_MODE", "warn")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.GuardMaxLoad != 1.5 || cfg.GuardMinFreeMB != 2048 || cfg.GuardMaxSessions != 5 || cfg.GuardMode != "warn" {
+		t.Errorf("got %v %v %v %q", cfg.GuardMaxLoad, cfg.GuardMinFreeMB, cfg.GuardMaxSessions, cfg.GuardMode)
+	}
+
+	os.Setenv("TRAMUNTANA_GUARD_MODE", "block")
+	if _, err := Load(); err == nil {
+		t.Error("expected error for unknown TRAMUNTANA_GUARD_MODE")
+	}
+}
+
 func TestIsAllowedUser(t *testing.T) {
 	cfg := &Config{AllowedUsers: []int64{100, 200, 300}}
 
@@ -170,6 +840,22 @@ func TestIsAllowedGroup(t *testing.T) {
 	}
 }
 
+func TestReplaceAllowedGroup(t *testing.T) {
+	cfg := &Config{AllowedGroups: []int64{-123, -100456}}
+	if !cfg.ReplaceAllowedGroup(-123, -100123) {
+		t.Fatal("ReplaceAllowedGroup(-123) = false, want true")
+	}
+	if cfg.IsAllowedGroup(-123) || !cfg.IsAllowedGroup(-100123) {
+		t.Errorf("AllowedGroups = %v, want -123 replaced by -100123", cfg.AllowedGroups)
+	}
+	if cfg.FirstAllowedGroup() != -100123 {
+		t.Errorf("FirstAllowedGroup() = %d, want -100123", cfg.FirstAllowedGroup())
+	}
+	if cfg.ReplaceAllowedGroup(-999, -100999) {
+		t.Error("ReplaceAllowedGroup of an unlisted group = true")
+	}
+}
+
 func TestParseIntList(t *testing.T) {
 	tests := []struct {
 		input string
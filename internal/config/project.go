@@ -0,0 +1,161 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ProjectFile is the per-directory launch override, looked up from a window's
+// directory up to its repository root.
+const ProjectFile = ".tramuntana.yaml"
+
+// ProjectConfig is a directory's launch override. It supports a small YAML
+// subset: a top-level "command" string and "env" and "aliases" maps of
+// strings.
+//
+//	command: claude --model opus --add-dir {{.Dir}}/../shared
+//	env:
+//	  NODE_ENV: development
+//	aliases:
+//	  rv: /review
+//	  test: "!go test ./..."
+type ProjectConfig struct {
+	Path    string            // file the config was read from
+	Command string            // replaces CLAUDE_COMMAND; same template variables
+	Env     map[string]string // extra environment for the window
+	// Telegram commands defined for windows in the directory: text starting
+	// with "!" runs as a bash command, anything else is sent to Claude
+	Aliases map[string]string
+}
+
+// LoadProjectConfig returns the nearest ProjectFile at or above dir, stopping
+// at the first directory containing .git. Returns nil if there is none.
+func LoadProjectConfig(dir string) (*ProjectConfig, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	dir = filepath.Clean(dir)
+	for {
+		path := filepath.Join(dir, ProjectFile)
+		data, err := os.ReadFile(path)
+		if err == nil {
+			pc, err := parseProjectConfig(data)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			pc.Path = path
+			return pc, nil
+		}
+		if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("reading %s: %w", path, err)
+		}
+		// .git is a directory in a repository and a file in a worktree
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return nil, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+// parseProjectConfig parses the ProjectFile subset.
+func parseProjectConfig(data []byte) (*ProjectConfig, error) {
+	pc := &ProjectConfig{}
+	var inMap map[string]string // the map being filled by indented lines
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		key, raw, ok := strings.Cut(trimmed, ":")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected \"key: value\"", n)
+		}
+		key = strings.TrimSpace(key)
+		value, err := parseYAMLScalar(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+
+		if line[0] == ' ' || line[0] == '\t' {
+			if inMap == nil {
+				return nil, fmt.Errorf("line %d: unexpected indentation", n)
+			}
+			inMap[key] = value
+			continue
+		}
+
+		inMap = nil
+		switch key {
+		case "command":
+			pc.Command = value
+		case "env", "aliases":
+			if value != "" {
+				return nil, fmt.Errorf("line %d: %s must be a map", n, key)
+			}
+			if key == "env" {
+				if pc.Env == nil {
+					pc.Env = make(map[string]string)
+				}
+				inMap = pc.Env
+			} else {
+				if pc.Aliases == nil {
+					pc.Aliases = make(map[string]string)
+				}
+				inMap = pc.Aliases
+			}
+		default:
+			return nil, fmt.Errorf("line %d: unknown key %q", n, key)
+		}
+	}
+	return pc, scanner.Err()
+}
+
+// parseYAMLScalar unquotes a scalar value and strips its trailing comment.
+func parseYAMLScalar(s string) (string, error) {
+	var value, rest string
+	switch {
+	case strings.HasPrefix(s, `"`):
+		quoted, err := strconv.QuotedPrefix(s)
+		if err != nil {
+			return "", fmt.Errorf("invalid quoted value %s", s)
+		}
+		value, _ = strconv.Unquote(quoted)
+		rest = s[len(quoted):]
+	case strings.HasPrefix(s, "'"):
+		// '' is an escaped quote inside single-quoted values
+		end := 1
+		for {
+			i := strings.IndexByte(s[end:], '\'')
+			if i < 0 {
+				return "", fmt.Errorf("invalid quoted value %s", s)
+			}
+			end += i
+			if !strings.HasPrefix(s[end:], "''") {
+				break
+			}
+			end += 2
+		}
+		value = strings.ReplaceAll(s[1:end], "''", "'")
+		rest = s[end+1:]
+	default:
+		if i := strings.Index(s, " #"); i >= 0 {
+			s = s[:i]
+		}
+		return strings.TrimSpace(s), nil
+	}
+	if rest = strings.TrimSpace(rest); rest != "" && !strings.HasPrefix(rest, "#") {
+		return "", fmt.Errorf("unexpected text after quoted value: %s", rest)
+	}
+	return value, nil
+}
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseProjectConfig(t *testing.T) {
+	data := `# launch settings
+command: "claude --model opus" # trailing comment
+env:
+  NODE_ENV: development
+  GREETING: 'it''s here'
+  EMPTY:
+aliases:
+  rv: /review
+  test: "!go test ./..."
+`
+	pc, err := parseProjectConfig([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pc.Command != "claude --model opus" {
+		t.Errorf("Command = %q", pc.Command)
+	}
+	want := map[string]string{"NODE_ENV": "development", "GREETING": "it's here", "EMPTY": ""}
+	if !reflect.DeepEqual(pc.Env, want) {
+		t.Errorf("Env = %v, want %v", pc.Env, want)
+	}
+	wantAliases := map[string]string{"rv": "/review", "test": "!go test ./..."}
+	if !reflect.DeepEqual(pc.Aliases, wantAliases) {
+		t.Errorf("Aliases = %v, want %v", pc.Aliases, wantAliases)
+	}
+}
+
+func TestParseProjectConfig_Errors(t *testing.T) {
+	for _, data := range []string{
+		"commnd: claude",
+		"  NODE_ENV: dev",
+		"env: dev",
+		"aliases: rv",
+		"command",
+		`command: "unterminated`,
+		"command: 'claude' --model opus",
+	} {
+		if _, err := parseProjectConfig([]byte(data)); err == nil {
+			t.Errorf("parseProjectConfig(%q) should fail", data)
+		}
+	}
+}
+
+func TestLoadProjectConfig_StopsAtRepoRoot(t *testing.T) {
+	outer := t.TempDir()
+	repo := filepath.Join(outer, "repo")
+	sub := filepath.Join(repo, "pkg", "sub")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(repo, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// Above the repository root, so never consulted
+	if err := os.WriteFile(filepath.Join(outer, ProjectFile), []byte("command: outer\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	pc, err := LoadProjectConfig(sub)
+	if err != nil || pc != nil {
+		t.Fatalf("LoadProjectConfig = %+v, %v; want nil", pc, err)
+	}
+
+	path := filepath.Join(repo, ProjectFile)
+	if err := os.WriteFile(path, []byte("command: claude --model haiku\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	pc, err = LoadProjectConfig(sub)
+	if err != nil || pc == nil {
+		t.Fatalf("LoadProjectConfig = %+v, %v", pc, err)
+	}
+	if pc.Command != "claude --model haiku" || pc.Path != path {
+		t.Errorf("got %q from %s", pc.Command, pc.Path)
+	}
+}
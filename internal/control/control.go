@@ -0,0 +1,235 @@
+// Package control serves a local HTTP API for automation: listing sessions,
+// typing into windows, screenshots, binding topics and posting notifications,
+// without going through Telegram.
+package control
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Session is a tmux window as reported by GET /v1/sessions.
+type Session struct {
+	WindowID  string  `json:"window_id"`
+	Name      string  `json:"name"`
+	CWD       string  `json:"cwd,omitempty"`
+	SessionID string  `json:"session_id,omitempty"`
+	Topics    []Topic `json:"topics,omitempty"`
+}
+
+// Topic is a Telegram forum topic bound to a window.
+type Topic struct {
+	UserID   int64 `json:"user_id"`
+	ChatID   int64 `json:"chat_id"`
+	ThreadID int   `json:"thread_id"`
+}
+
+// Notification is the body of POST /v1/notify. It goes to every topic bound to
+// WindowID, or to the topic ChatID/ThreadID.
+type Notification struct {
+	WindowID string `json:"window_id,omitempty"`
+	ChatID   int64  `json:"chat_id,omitempty"`
+	ThreadID int    `json:"thread_id,omitempty"`
+	Text     string `json:"text"`
+}
+
+// Binding is the body of POST /v1/bind.
+type Binding struct {
+	UserID   int64  `json:"user_id"`
+	ChatID   int64  `json:"chat_id"`
+	ThreadID int    `json:"thread_id"`
+	WindowID string `json:"window_id"`
+}
+
+// Backend performs the operations; the bot implements it.
+type Backend interface {
+	Sessions() ([]Session, error)
+	SendText(windowID, text string) error
+	Screenshot(windowID string) ([]byte, error)
+	Bind(b Binding) error
+	Notify(n Notification) (int, error)
+}
+
+// ErrNotFound is returned by a Backend for an unknown window or topic.
+var ErrNotFound = errors.New("not found")
+
+// ErrRefused is returned by a Backend that won't type into a window right
+// now: the bridge is paused, the window's topic is observed, or its project
+// is over budget.
+var ErrRefused = errors.New("refused")
+
+// maxBodyBytes bounds request bodies; prompts are the largest.
+const maxBodyBytes = 1 << 20
+
+// Handler returns the API's routes. A non-empty token must be sent as
+// "Authorization: Bearer <token>".
+func Handler(be Backend, token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/sessions", func(w http.ResponseWriter, r *http.Request) {
+		sessions, err := be.Sessions()
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"sessions": sessions})
+	})
+	mux.HandleFunc("POST /v1/sessions/{window}/send", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Text string `json:"text"`
+		}
+		if !readJSON(w, r, &body) {
+			return
+		}
+		if strings.TrimSpace(body.Text) == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "text is required"})
+			return
+		}
+		if err := be.SendText(r.PathValue("window"), body.Text); err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]bool{"ok": true})
+	})
+	mux.HandleFunc("GET /v1/sessions/{window}/screenshot", func(w http.ResponseWriter, r *http.Request) {
+		png, err := be.Screenshot(r.PathValue("window"))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(png)
+	})
+	mux.HandleFunc("POST /v1/bind", func(w http.ResponseWriter, r *http.Request) {
+		var b Binding
+		if !readJSON(w, r, &b) {
+			return
+		}
+		if b.UserID == 0 || b.ChatID == 0 || b.ThreadID == 0 || b.WindowID == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "user_id, chat_id, thread_id and window_id are required"})
+			return
+		}
+		if err := be.Bind(b); err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]bool{"ok": true})
+	})
+	mux.HandleFunc("POST /v1/notify", func(w http.ResponseWriter, r *http.Request) {
+		var n Notification
+		if !readJSON(w, r, &n) {
+			return
+		}
+		if strings.TrimSpace(n.Text) == "" || (n.WindowID == "" && (n.ChatID == 0 || n.ThreadID == 0)) {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "text and either window_id or chat_id and thread_id are required"})
+			return
+		}
+		sent, err := be.Notify(n)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]int{"topics": sent})
+	})
+
+	if token == "" {
+		return mux
+	}
+	want := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// Listen opens addr: "unix:<path>" for a socket only the current user can
+// use, or a loopback host:port. Any local process, and any web page through
+// the browser, can reach a loopback port, so one needs a token.
+func Listen(addr, token string) (net.Listener, error) {
+	if path, ok := strings.CutPrefix(addr, "unix:"); ok {
+		os.Remove(path) // stale socket from an earlier run
+		ln, err := net.Listen("unix", path)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.Chmod(path, 0o600); err != nil {
+			ln.Close()
+			return nil, err
+		}
+		return ln, nil
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return nil, fmt.Errorf("%q is not a loopback address", addr)
+	}
+	if token == "" {
+		return nil, fmt.Errorf("%q needs TRAMUNTANA_CONTROL_TOKEN; without one, use a unix: socket", addr)
+	}
+	return net.Listen("tcp", addr)
+}
+
+// Serve runs the API on addr until ctx is cancelled.
+func Serve(ctx context.Context, addr, token string, be Backend) error {
+	ln, err := Listen(addr, token)
+	if err != nil {
+		return fmt.Errorf("control API: %w", err)
+	}
+	srv := &http.Server{Handler: Handler(be, token), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+	log.Printf("Control API listening on %s", addr)
+	if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// readJSON decodes a JSON request body. Only application/json is accepted,
+// so a web page can't post a form or text/plain body without a preflight.
+func readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	if mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mt != "application/json" {
+		writeJSON(w, http.StatusUnsupportedMediaType, map[string]string{"error": "Content-Type must be application/json"})
+		return false
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes)).Decode(v); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON: " + err.Error()})
+		return false
+	}
+	return true
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, ErrRefused):
+		status = http.StatusConflict
+	}
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
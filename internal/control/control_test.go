@@ -0,0 +1,114 @@
+package control
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type fakeBackend struct {
+	sent     map[string]string
+	notified []Notification
+}
+
+func (f *fakeBackend) Sessions() ([]Session, error) {
+	return []Session{{WindowID: "@1", Name: "api", Topics: []Topic{{UserID: 1, ChatID: -100, ThreadID: 7}}}}, nil
+}
+
+func (f *fakeBackend) SendText(windowID, text string) error {
+	if windowID != "@1" {
+		return fmt.Errorf("window %s: %w", windowID, ErrNotFound)
+	}
+	f.sent[windowID] = text
+	return nil
+}
+
+func (f *fakeBackend) Screenshot(windowID string) ([]byte, error) { return []byte("\x89PNG"), nil }
+
+func (f *fakeBackend) Bind(b Binding) error { return nil }
+
+func (f *fakeBackend) Notify(n Notification) (int, error) {
+	f.notified = append(f.notified, n)
+	return 1, nil
+}
+
+func do(t *testing.T, h http.Handler, method, path, token, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHandler(t *testing.T) {
+	be := &fakeBackend{sent: make(map[string]string)}
+	h := Handler(be, "s3cret")
+
+	if rec := do(t, h, "GET", "/v1/sessions", "", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("without token: %d", rec.Code)
+	}
+	rec := do(t, h, "GET", "/v1/sessions", "s3cret", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"window_id":"@1"`) || !strings.Contains(rec.Body.String(), `"thread_id":7`) {
+		t.Errorf("sessions: %d %s", rec.Code, rec.Body)
+	}
+
+	if rec := do(t, h, "POST", "/v1/sessions/@1/send", "s3cret", `{"text":"run the tests"}`); rec.Code != http.StatusOK || be.sent["@1"] != "run the tests" {
+		t.Errorf("send: %d %s, sent %v", rec.Code, rec.Body, be.sent)
+	}
+	if rec := do(t, h, "POST", "/v1/sessions/@9/send", "s3cret", `{"text":"hi"}`); rec.Code != http.StatusNotFound {
+		t.Errorf("send to unknown window: %d", rec.Code)
+	}
+	if rec := do(t, h, "POST", "/v1/sessions/@1/send", "s3cret", `{"text":" "}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("empty text: %d", rec.Code)
+	}
+
+	if rec := do(t, h, "GET", "/v1/sessions/@1/screenshot", "s3cret", ""); rec.Header().Get("Content-Type") != "image/png" {
+		t.Errorf("screenshot content type %q", rec.Header().Get("Content-Type"))
+	}
+
+	if rec := do(t, h, "POST", "/v1/notify", "s3cret", `{"text":"deploy finished"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("notify without a target: %d", rec.Code)
+	}
+	rec = do(t, h, "POST", "/v1/notify", "s3cret", `{"chat_id":-100,"thread_id":7,"text":"deploy finished"}`)
+	if rec.Code != http.StatusOK || len(be.notified) != 1 || be.notified[0].Text != "deploy finished" {
+		t.Errorf("notify: %d %s", rec.Code, rec.Body)
+	}
+
+	if rec := do(t, h, "POST", "/v1/bind", "s3cret", `{"user_id":1,"chat_id":-100,"window_id":"@1"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("bind without thread_id: %d", rec.Code)
+	}
+}
+
+func TestHandler_RequiresJSONContentType(t *testing.T) {
+	be := &fakeBackend{sent: make(map[string]string)}
+	h := Handler(be, "")
+	req := httptest.NewRequest("POST", "/v1/sessions/@1/send", strings.NewReader(`{"text":"rm -rf"}`))
+	req.Header.Set("Content-Type", "text/plain")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnsupportedMediaType || len(be.sent) != 0 {
+		t.Errorf("text/plain send: %d, sent %v", rec.Code, be.sent)
+	}
+}
+
+func TestListen_RejectsNonLoopback(t *testing.T) {
+	if _, err := Listen("0.0.0.0:0", "s3cret"); err == nil {
+		t.Error("expected a non-loopback address to be rejected")
+	}
+	if _, err := Listen("127.0.0.1:0", ""); err == nil {
+		t.Error("expected a TCP address without a token to be rejected")
+	}
+	ln, err := Listen("127.0.0.1:0", "s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln.Close()
+}
@@ -0,0 +1,279 @@
+// Package dryrun stands in for the Telegram Bot API: requests are printed to a
+// writer instead of being sent, and answered with plausible results so the
+// bot and message queue keep working.
+package dryrun
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// maxParamLen is how much of a parameter other than the text is printed.
+const maxParamLen = 80
+
+// callbackPrefix marks a console line as an inline button press.
+const callbackPrefix = "cb:"
+
+// Client is an HTTP client for tgbotapi that prints requests to w.
+type Client struct {
+	mu            sync.Mutex
+	w             io.Writer
+	nextMessageID int
+	lastKeyboard  int // message ID of the latest message sent with buttons
+
+	updates      chan map[string]any // console input, served by getUpdates
+	nextUpdateID int
+}
+
+// NewAPI returns a BotAPI whose requests are printed to w.
+func NewAPI(w io.Writer) (*tgbotapi.BotAPI, error) {
+	return tgbotapi.NewBotAPIWithClient("dry-run", "http://dry-run/bot%s/%s", &Client{w: w, nextMessageID: 1})
+}
+
+// Console is where console input appears to come from.
+type Console struct {
+	UserID   int64
+	ChatID   int64
+	ThreadID int
+}
+
+// NewConsoleAPI is NewAPI plus input: each line read from in arrives as a
+// message from the console user in the console topic. A line "cb:<data>"
+// presses the button with that callback data on the latest keyboard instead.
+func NewConsoleAPI(w io.Writer, in io.Reader, console Console) (*tgbotapi.BotAPI, error) {
+	c := &Client{w: w, nextMessageID: 1, updates: make(chan map[string]any, 16), nextUpdateID: 1}
+	go c.readConsole(in, console)
+	return tgbotapi.NewBotAPIWithClient("dry-run", "http://dry-run/bot%s/%s", c)
+}
+
+// readConsole turns input lines into updates.
+func (c *Client) readConsole(in io.Reader, console Console) {
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		from := map[string]any{"id": console.UserID, "is_bot": false, "first_name": "Console"}
+
+		c.mu.Lock()
+		update := map[string]any{"update_id": c.nextUpdateID}
+		c.nextUpdateID++
+		if data, ok := strings.CutPrefix(line, callbackPrefix); ok {
+			msg := consoleMessage(console, c.lastKeyboard, "")
+			update["callback_query"] = map[string]any{"id": strconv.Itoa(c.nextUpdateID), "from": from, "message": msg, "data": data}
+		} else {
+			msg := consoleMessage(console, c.nextMessageID, line)
+			c.nextMessageID++
+			msg["from"] = from
+			if strings.HasPrefix(line, "/") {
+				cmd, _, _ := strings.Cut(line, " ")
+				msg["entities"] = []map[string]any{{"type": "bot_command", "offset": 0, "length": len(cmd)}}
+			}
+			update["message"] = msg
+		}
+		c.mu.Unlock()
+
+		c.updates <- update
+	}
+}
+
+// consoleMessage builds a message in the console topic.
+func consoleMessage(console Console, id int, text string) map[string]any {
+	msg := map[string]any{
+		"message_id": id,
+		"date":       time.Now().Unix(),
+		"chat":       map[string]any{"id": console.ChatID, "type": "supergroup", "is_forum": true},
+		"text":       text,
+	}
+	if console.ThreadID != 0 {
+		msg["message_thread_id"] = console.ThreadID
+		msg["is_topic_message"] = true
+	}
+	return msg
+}
+
+// Do prints one Bot API request and returns a synthetic response.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	method := req.URL.Path[strings.LastIndex(req.URL.Path, "/")+1:]
+	params, files, err := readParams(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var result any = true
+	switch method {
+	case "getMe":
+		result = map[string]any{"id": 1, "is_bot": true, "first_name": "Dry run", "username": "dry_run_bot"}
+	case "getUpdates":
+		// Behave like a long poll that only ever sees console input
+		timeout, _ := strconv.Atoi(params["timeout"])
+		updates := []any{}
+		select {
+		case u := <-c.updates:
+			updates = append(updates, u)
+		case <-time.After(time.Duration(timeout) * time.Second):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+		result = updates
+	case "sendMessage", "sendPhoto", "sendDocument":
+		c.mu.Lock()
+		id := c.nextMessageID
+		c.nextMessageID++
+		if params["reply_markup"] != "" {
+			c.lastKeyboard = id
+		}
+		c.mu.Unlock()
+		c.print(method, params, files, id)
+		result = message(params, id)
+	case "editMessageText", "editMessageReplyMarkup":
+		id, _ := strconv.Atoi(params["message_id"])
+		if params["reply_markup"] != "" {
+			c.mu.Lock()
+			c.lastKeyboard = id
+			c.mu.Unlock()
+		}
+		c.print(method, params, files, 0)
+		result = message(params, id)
+	default:
+		c.print(method, params, files, 0)
+	}
+
+	body, _ := json.Marshal(map[string]any{"ok": true, "result": result})
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(bytes.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+// print writes one request: a header line with its parameters, then the text.
+func (c *Client) print(method string, params map[string]string, files []string, messageID int) {
+	var keys []string
+	for k := range params {
+		if k != "text" && k != "caption" && k != "reply_markup" && params[k] != "null" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "── %s", method)
+	if messageID != 0 {
+		fmt.Fprintf(&b, " #%d", messageID)
+	}
+	for _, k := range keys {
+		v := params[k]
+		if r := []rune(v); len(r) > maxParamLen {
+			v = string(r[:maxParamLen]) + "…"
+		}
+		fmt.Fprintf(&b, " %s=%s", k, v)
+	}
+	for _, f := range files {
+		fmt.Fprintf(&b, " file=%s", f)
+	}
+	b.WriteByte('\n')
+	for _, k := range []string{"text", "caption"} {
+		if v := params[k]; v != "" {
+			b.WriteString(v)
+			b.WriteByte('\n')
+		}
+	}
+	if kb := params["reply_markup"]; kb != "" {
+		b.WriteString(formatKeyboard(kb))
+	}
+
+	c.mu.Lock()
+	io.WriteString(c.w, b.String())
+	c.mu.Unlock()
+}
+
+// formatKeyboard renders inline keyboard rows as "[label] [label]" lines.
+func formatKeyboard(raw string) string {
+	var kb tgbotapi.InlineKeyboardMarkup
+	if err := json.Unmarshal([]byte(raw), &kb); err != nil || len(kb.InlineKeyboard) == 0 {
+		return "keyboard: " + raw + "\n"
+	}
+	var b strings.Builder
+	for _, row := range kb.InlineKeyboard {
+		for i, btn := range row {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			fmt.Fprintf(&b, "[%s]", btn.Text)
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// message builds the Message result for a send or edit.
+func message(params map[string]string, id int) map[string]any {
+	chatID, _ := strconv.ParseInt(params["chat_id"], 10, 64)
+	msg := map[string]any{
+		"message_id": id,
+		"date":       time.Now().Unix(),
+		"chat":       map[string]any{"id": chatID, "type": "supergroup"},
+		"text":       params["text"],
+	}
+	if tid, err := strconv.Atoi(params["message_thread_id"]); err == nil {
+		msg["message_thread_id"] = tid
+	}
+	return msg
+}
+
+// readParams decodes form or multipart request parameters. Uploaded files are
+// reported by field and file name.
+func readParams(req *http.Request) (map[string]string, []string, error) {
+	params := make(map[string]string)
+	if req.Body == nil {
+		return params, nil, nil
+	}
+	data, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		values, err := url.ParseQuery(string(data))
+		if err != nil {
+			return nil, nil, err
+		}
+		for k, v := range values {
+			params[k] = v[0]
+		}
+		return params, nil, nil
+	}
+
+	mreq := &http.Request{Header: req.Header, Body: io.NopCloser(bytes.NewReader(data)), Method: http.MethodPost}
+	if err := mreq.ParseMultipartForm(32 << 20); err != nil {
+		return nil, nil, fmt.Errorf("parsing multipart: %w", err)
+	}
+	for k, v := range mreq.MultipartForm.Value {
+		params[k] = v[0]
+	}
+	var files []string
+	for field, fhs := range mreq.MultipartForm.File {
+		for _, fh := range fhs {
+			files = append(files, field+":"+fh.Filename)
+		}
+	}
+	sort.Strings(files)
+	return params, files, nil
+}
@@ -0,0 +1,107 @@
+package dryrun
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestAPI_PrintsSendsAndEdits(t *testing.T) {
+	var out bytes.Buffer
+	api, err := NewAPI(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if api.Self.UserName != "dry_run_bot" {
+		t.Errorf("getMe user = %q", api.Self.UserName)
+	}
+
+	msg := tgbotapi.NewMessage(-100, "hello *world*")
+	msg.ParseMode = "MarkdownV2"
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Yes", "y"),
+		tgbotapi.NewInlineKeyboardButtonData("No", "n"),
+	))
+	sent, err := api.Send(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent.MessageID != 1 || sent.Chat.ID != -100 {
+		t.Errorf("sent = %d in %d, want #1 in -100", sent.MessageID, sent.Chat.ID)
+	}
+
+	if _, err := api.Send(tgbotapi.NewEditMessageText(-100, sent.MessageID, "edited")); err != nil {
+		t.Fatal(err)
+	}
+
+	got := out.String()
+	for _, want := range []string{
+		"── sendMessage #1 chat_id=-100 parse_mode=MarkdownV2\nhello *world*\n[Yes] [No]\n",
+		"── editMessageText chat_id=-100 message_id=1\nedited\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestAPI_Upload(t *testing.T) {
+	var out bytes.Buffer
+	api, err := NewAPI(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := tgbotapi.NewDocument(-100, tgbotapi.FileBytes{Name: "report.txt", Bytes: []byte("x")})
+	doc.Caption = "the report"
+	if _, err := api.Send(doc); err != nil {
+		t.Fatal(err)
+	}
+	if got := out.String(); !strings.Contains(got, "file=document:report.txt") || !strings.Contains(got, "the report") {
+		t.Errorf("upload not printed:\n%s", got)
+	}
+}
+
+func TestConsoleAPI_InputBecomesUpdates(t *testing.T) {
+	var out bytes.Buffer
+	in := strings.NewReader("hello\n\n/status now\n")
+	api, err := NewConsoleAPI(&out, in, Console{UserID: 7, ChatID: -100, ThreadID: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kb := tgbotapi.NewMessage(-100, "pick")
+	kb.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("A", "a")))
+	sent, err := api.Send(kb)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []tgbotapi.Update
+	for len(got) < 2 {
+		updates, err := api.GetUpdates(tgbotapi.UpdateConfig{Offset: 0, Timeout: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(updates) == 0 {
+			t.Fatal("timed out waiting for console input")
+		}
+		got = append(got, updates...)
+	}
+	if m := got[0].Message; m == nil || m.Text != "hello" || m.From.ID != 7 || m.Chat.ID != -100 {
+		t.Errorf("first update = %+v", got[0].Message)
+	}
+	if m := got[1].Message; m == nil || !m.IsCommand() || m.Command() != "status" {
+		t.Errorf("second update should be the /status command: %+v", got[1].Message)
+	}
+
+	// Button presses target the latest keyboard
+	c := &Client{updates: make(chan map[string]any, 1), lastKeyboard: sent.MessageID}
+	go c.readConsole(strings.NewReader("cb:a\n"), Console{UserID: 7, ChatID: -100})
+	u := <-c.updates
+	cq, ok := u["callback_query"].(map[string]any)
+	if !ok || cq["data"] != "a" || cq["message"].(map[string]any)["message_id"] != sent.MessageID {
+		t.Errorf("callback update = %v", u)
+	}
+}
@@ -0,0 +1,75 @@
+// Package events is an in-process bus that lets the monitor, status poller and
+// bot react to each other's observations without calling into one another.
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// subscriberBuffer is the channel capacity given to each subscriber.
+const subscriberBuffer = 64
+
+// WindowDead is published when a bound tmux window no longer exists.
+type WindowDead struct {
+	WindowID string
+}
+
+// TurnStarted is published when a user prompt appears in a window's transcript.
+type TurnStarted struct {
+	WindowID string
+	At       time.Time
+}
+
+// TurnCompleted is published when Claude's status line clears after a turn.
+type TurnCompleted struct {
+	WindowID string
+	Elapsed  time.Duration
+}
+
+// InteractiveUIDetected is published when a pane shows an interactive prompt
+// (permission request, plan approval, …) for a user+thread watching the window.
+type InteractiveUIDetected struct {
+	WindowID string
+	UserID   int64
+	ThreadID int
+	ChatID   int64
+	PaneText string
+}
+
+// Topic fans out events of one type to every subscriber.
+type Topic[T any] struct {
+	mu   sync.RWMutex
+	subs []chan T
+}
+
+// Subscribe returns a channel receiving every event published after the call.
+func (t *Topic[T]) Subscribe() <-chan T {
+	ch := make(chan T, subscriberBuffer)
+	t.mu.Lock()
+	t.subs = append(t.subs, ch)
+	t.mu.Unlock()
+	return ch
+}
+
+// Publish delivers ev to all subscribers, blocking while a subscriber's buffer is full.
+func (t *Topic[T]) Publish(ev T) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	for _, ch := range t.subs {
+		ch <- ev
+	}
+}
+
+// Bus holds one topic per event type.
+type Bus struct {
+	WindowDead            Topic[WindowDead]
+	TurnStarted           Topic[TurnStarted]
+	TurnCompleted         Topic[TurnCompleted]
+	InteractiveUIDetected Topic[InteractiveUIDetected]
+}
+
+// New creates an empty Bus.
+func New() *Bus {
+	return &Bus{}
+}
@@ -0,0 +1,43 @@
+package events
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTopic_FansOutToAllSubscribers(t *testing.T) {
+	bus := New()
+	a := bus.WindowDead.Subscribe()
+	b := bus.WindowDead.Subscribe()
+
+	bus.WindowDead.Publish(WindowDead{WindowID: "@1"})
+
+	for _, ch := range []<-chan WindowDead{a, b} {
+		select {
+		case ev := <-ch:
+			if ev.WindowID != "@1" {
+				t.Errorf("got %q, want @1", ev.WindowID)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("subscriber did not receive event")
+		}
+	}
+}
+
+func TestTopic_PublishWithoutSubscribers(t *testing.T) {
+	bus := New()
+	bus.TurnCompleted.Publish(TurnCompleted{WindowID: "@1"}) // must not block
+}
+
+func TestTopic_PreservesOrder(t *testing.T) {
+	bus := New()
+	ch := bus.TurnStarted.Subscribe()
+	for _, id := range []string{"@1", "@2", "@3"} {
+		bus.TurnStarted.Publish(TurnStarted{WindowID: id})
+	}
+	for _, want := range []string{"@1", "@2", "@3"} {
+		if ev := <-ch; ev.WindowID != want {
+			t.Errorf("got %q, want %q", ev.WindowID, want)
+		}
+	}
+}
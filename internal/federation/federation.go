@@ -0,0 +1,613 @@
+// Package federation lets one Telegram bot serve several machines. A
+// coordinator owns the bot token and polls Telegram; each host runs
+// `tramuntana serve` with TRAMUNTANA_COORDINATOR pointing at it, using
+// "<host>:<secret>" as its bot token. To a host the coordinator looks like
+// the Bot API: getUpdates returns the updates of the topics bound to that
+// host, and every other method is relayed to Telegram. A topic's first
+// message asks which host it should run on.
+package federation
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// File is the topic → host routing table, kept in the state directory.
+const File = "federation.json"
+
+const (
+	maxQueued      = 1000             // updates kept for a host that isn't polling
+	maxHeld        = 20               // updates kept per topic while its host is picked
+	maxBodyBytes   = 50 << 20         // Bot API uploads are capped at 50MB
+	maxPollTimeout = 50 * time.Second // longest getUpdates wait granted to a host
+	onlineWindow   = 2 * time.Minute  // a host polled this recently is connected
+)
+
+// Host is a machine allowed to connect, authenticating with "<Name>:<Secret>".
+type Host struct {
+	Name   string
+	Secret string
+}
+
+// ParseHosts parses a comma-separated list of name:secret pairs.
+func ParseHosts(s string) ([]Host, error) {
+	var hosts []Host
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, secret, ok := strings.Cut(part, ":")
+		if !ok || name == "" || secret == "" || strings.ContainsAny(name, " /") {
+			return nil, fmt.Errorf("invalid host %q (want name:secret)", part)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate host %q", name)
+		}
+		seen[name] = true
+		hosts = append(hosts, Host{Name: name, Secret: secret})
+	}
+	return hosts, nil
+}
+
+// Routes maps topics ("<chat>:<thread>") to host names, persisted as JSON.
+type Routes struct {
+	mu     sync.Mutex
+	path   string
+	Topics map[string]string `json:"topics"`
+}
+
+// LoadRoutes reads the routing table at path; a missing file is empty.
+func LoadRoutes(path string) (*Routes, error) {
+	r := &Routes{path: path, Topics: make(map[string]string)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if r.Topics == nil {
+		r.Topics = make(map[string]string)
+	}
+	return r, nil
+}
+
+func topicKey(chatID int64, threadID int) string {
+	return strconv.FormatInt(chatID, 10) + ":" + strconv.Itoa(threadID)
+}
+
+// Get returns the host a topic is bound to.
+func (r *Routes) Get(chatID int64, threadID int) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	host, ok := r.Topics[topicKey(chatID, threadID)]
+	return host, ok
+}
+
+// Set binds a topic to a host and saves the table.
+func (r *Routes) Set(chatID int64, threadID int, host string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Topics[topicKey(chatID, threadID)] = host
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(r.path), ".federation-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), r.path)
+}
+
+// queuedUpdate is a raw Telegram update waiting for a host to fetch it.
+type queuedUpdate struct {
+	id  int
+	raw json.RawMessage
+}
+
+// hostQueue holds a host's updates until it confirms them with a later offset.
+type hostQueue struct {
+	Host
+	mu       sync.Mutex
+	updates  []queuedUpdate
+	wake     chan struct{} // closed when updates arrive
+	lastSeen time.Time
+}
+
+func newHostQueue(h Host) *hostQueue {
+	return &hostQueue{Host: h, wake: make(chan struct{})}
+}
+
+func (q *hostQueue) push(u queuedUpdate) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.updates) == maxQueued {
+		log.Printf("Federation: host %s is not polling; dropping its oldest update", q.Name)
+		q.updates = q.updates[1:]
+	}
+	q.updates = append(q.updates, u)
+	close(q.wake)
+	q.wake = make(chan struct{})
+}
+
+// take drops updates before offset and returns up to limit of the rest,
+// waiting up to timeout for one to arrive.
+func (q *hostQueue) take(ctx context.Context, offset, limit int, timeout time.Duration) []json.RawMessage {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	for {
+		q.mu.Lock()
+		q.lastSeen = time.Now()
+		i := 0
+		for i < len(q.updates) && q.updates[i].id < offset {
+			i++
+		}
+		q.updates = q.updates[i:]
+		var out []json.RawMessage
+		for _, u := range q.updates {
+			if limit > 0 && len(out) == limit {
+				break
+			}
+			out = append(out, u.raw)
+		}
+		wake := q.wake
+		q.mu.Unlock()
+
+		if len(out) > 0 || timeout <= 0 {
+			return out
+		}
+		select {
+		case <-wake:
+		case <-deadline.C:
+			return nil
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (q *hostQueue) seen() time.Time {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.lastSeen
+}
+
+// Options configures a Coordinator.
+type Options struct {
+	Endpoint string // Bot API endpoint format, e.g. "https://api.telegram.org/bot%s/%s"
+	Token    string // the real bot token
+	Hosts    []Host
+	Routes   *Routes
+	Client   *http.Client
+	Allowed  func(userID int64) bool // who may pick a host for a new topic
+}
+
+// Coordinator routes Telegram updates to hosts and relays their Bot API calls.
+type Coordinator struct {
+	opts  Options
+	hosts map[string]*hostQueue
+	order []string
+
+	mu   sync.Mutex
+	held map[string][]queuedUpdate // topic → updates waiting for a host to be picked
+}
+
+// New creates a Coordinator.
+func New(opts Options) *Coordinator {
+	if opts.Client == nil {
+		opts.Client = &http.Client{}
+	}
+	c := &Coordinator{opts: opts, hosts: make(map[string]*hostQueue), held: make(map[string][]queuedUpdate)}
+	for _, h := range opts.Hosts {
+		c.hosts[h.Name] = newHostQueue(h)
+		c.order = append(c.order, h.Name)
+	}
+	return c
+}
+
+// message and update are the parts of Telegram updates routing looks at.
+type message struct {
+	MessageID int   `json:"message_id"`
+	ThreadID  int   `json:"message_thread_id"`
+	IsTopic   bool  `json:"is_topic_message"`
+	From      *user `json:"from"`
+	Chat      struct {
+		ID int64 `json:"id"`
+	} `json:"chat"`
+	Text string `json:"text"`
+}
+
+type user struct {
+	ID int64 `json:"id"`
+}
+
+type update struct {
+	UpdateID      int      `json:"update_id"`
+	Message       *message `json:"message"`
+	CallbackQuery *struct {
+		ID      string   `json:"id"`
+		From    user     `json:"from"`
+		Message *message `json:"message"`
+		Data    string   `json:"data"`
+	} `json:"callback_query"`
+}
+
+// topic returns the chat, topic and sender of an update.
+func (u *update) topic() (chatID int64, threadID int, userID int64, ok bool) {
+	m := u.Message
+	if u.CallbackQuery != nil {
+		m, userID = u.CallbackQuery.Message, u.CallbackQuery.From.ID
+	} else if m != nil && m.From != nil {
+		userID = m.From.ID
+	}
+	if m == nil {
+		return 0, 0, 0, false
+	}
+	if m.IsTopic {
+		threadID = m.ThreadID
+	}
+	return m.Chat.ID, threadID, userID, true
+}
+
+// Run polls Telegram and routes updates until ctx is cancelled.
+func (c *Coordinator) Run(ctx context.Context) error {
+	offset := 0
+	for ctx.Err() == nil {
+		params := url.Values{
+			"timeout":         {"30"},
+			"allowed_updates": {`["message","callback_query"]`},
+		}
+		if offset > 0 {
+			params.Set("offset", strconv.Itoa(offset))
+		}
+		result, err := c.call(ctx, "getUpdates", params)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Federation: getting updates: %v", err)
+				time.Sleep(3 * time.Second)
+			}
+			continue
+		}
+		var raws []json.RawMessage
+		if err := json.Unmarshal(result, &raws); err != nil {
+			log.Printf("Federation: parsing updates: %v", err)
+			continue
+		}
+		for _, raw := range raws {
+			var u update
+			if err := json.Unmarshal(raw, &u); err != nil {
+				continue
+			}
+			if u.UpdateID >= offset {
+				offset = u.UpdateID + 1
+			}
+			c.route(ctx, u, raw)
+		}
+	}
+	return nil
+}
+
+// route hands an update to its topic's host, or asks which host a new topic
+// should run on.
+func (c *Coordinator) route(ctx context.Context, u update, raw json.RawMessage) {
+	qu := queuedUpdate{id: u.UpdateID, raw: raw}
+	chatID, threadID, userID, ok := u.topic()
+	if !ok {
+		return
+	}
+	allowed := c.opts.Allowed == nil || c.opts.Allowed(userID)
+	if cq := u.CallbackQuery; cq != nil && strings.HasPrefix(cq.Data, "fed_host:") {
+		if !allowed {
+			return
+		}
+		c.pickHost(ctx, chatID, threadID, cq.ID, cq.Message.MessageID, strings.TrimPrefix(cq.Data, "fed_host:"))
+		return
+	}
+	if m := u.Message; m != nil && (m.Text == "/host" || strings.HasPrefix(m.Text, "/host@") || strings.HasPrefix(m.Text, "/host ")) {
+		if allowed {
+			c.describeHost(ctx, chatID, threadID)
+		}
+		return
+	}
+
+	if name, ok := c.opts.Routes.Get(chatID, threadID); ok {
+		if q, ok := c.hosts[name]; ok {
+			q.push(qu)
+		} else {
+			log.Printf("Federation: topic %s is bound to unknown host %s", topicKey(chatID, threadID), name)
+		}
+		return
+	}
+	if len(c.order) == 1 {
+		c.bind(chatID, threadID, c.order[0])
+		c.hosts[c.order[0]].push(qu)
+		return
+	}
+	if !allowed {
+		return
+	}
+	if u.CallbackQuery != nil {
+		c.call(ctx, "answerCallbackQuery", url.Values{
+			"callback_query_id": {u.CallbackQuery.ID},
+			"text":              {"This topic isn't on a host yet."},
+		})
+		return
+	}
+
+	key := topicKey(chatID, threadID)
+	c.mu.Lock()
+	first := len(c.held[key]) == 0
+	if len(c.held[key]) < maxHeld {
+		c.held[key] = append(c.held[key], qu)
+	}
+	c.mu.Unlock()
+	if first {
+		c.sendText(ctx, chatID, threadID, "🖥 Which host should this topic run on?", c.hostKeyboard())
+	}
+}
+
+// pickHost binds a topic to the host chosen on the picker and delivers the
+// updates held for it.
+func (c *Coordinator) pickHost(ctx context.Context, chatID int64, threadID int, callbackID string, messageID int, name string) {
+	answer := url.Values{"callback_query_id": {callbackID}}
+	if current, ok := c.opts.Routes.Get(chatID, threadID); ok {
+		answer.Set("text", "This topic already runs on "+current+".")
+		c.call(ctx, "answerCallbackQuery", answer)
+		return
+	}
+	q, ok := c.hosts[name]
+	if !ok {
+		answer.Set("text", "Unknown host.")
+		c.call(ctx, "answerCallbackQuery", answer)
+		return
+	}
+	c.call(ctx, "answerCallbackQuery", answer)
+	c.bind(chatID, threadID, name)
+	c.call(ctx, "editMessageText", url.Values{
+		"chat_id":    {strconv.FormatInt(chatID, 10)},
+		"message_id": {strconv.Itoa(messageID)},
+		"text":       {"🖥 This topic runs on " + name + "."},
+	})
+
+	key := topicKey(chatID, threadID)
+	c.mu.Lock()
+	held := c.held[key]
+	delete(c.held, key)
+	c.mu.Unlock()
+	for _, u := range held {
+		q.push(u)
+	}
+}
+
+func (c *Coordinator) bind(chatID int64, threadID int, name string) {
+	if err := c.opts.Routes.Set(chatID, threadID, name); err != nil {
+		log.Printf("Federation: saving routes: %v", err)
+	}
+	log.Printf("Federation: topic %s bound to host %s", topicKey(chatID, threadID), name)
+}
+
+// describeHost answers /host with the topic's host and every host's status.
+func (c *Coordinator) describeHost(ctx context.Context, chatID int64, threadID int) {
+	var b strings.Builder
+	if name, ok := c.opts.Routes.Get(chatID, threadID); ok {
+		b.WriteString("🖥 This topic runs on " + name + ".\n")
+	} else {
+		b.WriteString("🖥 This topic isn't on a host yet; send a message to pick one.\n")
+	}
+	for _, name := range c.order {
+		b.WriteString("\n• " + name + " — " + c.hostStatus(name, time.Now()))
+	}
+	c.sendText(ctx, chatID, threadID, b.String(), "")
+}
+
+func (c *Coordinator) hostStatus(name string, now time.Time) string {
+	seen := c.hosts[name].seen()
+	switch {
+	case seen.IsZero():
+		return "never connected"
+	case now.Sub(seen) < onlineWindow:
+		return "connected"
+	default:
+		return "last seen " + now.Sub(seen).Round(time.Minute).String() + " ago"
+	}
+}
+
+// hostKeyboard is the host picker's inline keyboard, one button per host.
+func (c *Coordinator) hostKeyboard() string {
+	now := time.Now()
+	var rows [][]map[string]string
+	for _, name := range c.order {
+		label := name
+		if now.Sub(c.hosts[name].seen()) >= onlineWindow {
+			label += " (offline)"
+		}
+		rows = append(rows, []map[string]string{{"text": label, "callback_data": "fed_host:" + name}})
+	}
+	data, _ := json.Marshal(map[string]any{"inline_keyboard": rows})
+	return string(data)
+}
+
+func (c *Coordinator) sendText(ctx context.Context, chatID int64, threadID int, text, keyboard string) {
+	params := url.Values{"chat_id": {strconv.FormatInt(chatID, 10)}, "text": {text}}
+	if threadID != 0 {
+		params.Set("message_thread_id", strconv.Itoa(threadID))
+	}
+	if keyboard != "" {
+		params.Set("reply_markup", keyboard)
+	}
+	if _, err := c.call(ctx, "sendMessage", params); err != nil {
+		log.Printf("Federation: sending to %s: %v", topicKey(chatID, threadID), err)
+	}
+}
+
+// call makes a Bot API request with the coordinator's token.
+func (c *Coordinator) call(ctx context.Context, method string, params url.Values) (json.RawMessage, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf(c.opts.Endpoint, c.opts.Token, method), strings.NewReader(params.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := c.opts.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var body struct {
+		OK          bool            `json:"ok"`
+		Result      json.RawMessage `json:"result"`
+		Description string          `json:"description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("%s: %w", method, err)
+	}
+	if !body.OK {
+		return nil, fmt.Errorf("%s: %s", method, body.Description)
+	}
+	return body.Result, nil
+}
+
+// Handler serves the Bot API to hosts at /bot<name>:<secret>/<method>.
+func (c *Coordinator) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rest, ok := strings.CutPrefix(r.URL.Path, "/bot")
+		token, method, ok2 := strings.Cut(rest, "/")
+		if !ok || !ok2 {
+			writeError(w, http.StatusNotFound, "Not Found")
+			return
+		}
+		name, secret, _ := strings.Cut(token, ":")
+		q, ok := c.hosts[name]
+		if !ok || subtle.ConstantTimeCompare([]byte(secret), []byte(q.Secret)) != 1 {
+			writeError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		if method == "getUpdates" {
+			c.serveUpdates(w, r, q)
+			return
+		}
+		c.relay(w, r, q, method)
+	})
+}
+
+// serveUpdates answers a host's getUpdates from its queue.
+func (c *Coordinator) serveUpdates(w http.ResponseWriter, r *http.Request, q *hostQueue) {
+	r.ParseForm()
+	offset, _ := strconv.Atoi(r.Form.Get("offset"))
+	limit, _ := strconv.Atoi(r.Form.Get("limit"))
+	secs, _ := strconv.Atoi(r.Form.Get("timeout"))
+	timeout := min(time.Duration(secs)*time.Second, maxPollTimeout)
+
+	updates := q.take(r.Context(), offset, limit, timeout)
+	if updates == nil {
+		updates = []json.RawMessage{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"ok": true, "result": updates})
+}
+
+// relay forwards a host's Bot API call to Telegram and copies the answer
+// back. A topic the host creates is bound to it.
+func (c *Coordinator) relay(w http.ResponseWriter, r *http.Request, q *hostQueue, method string) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	if err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, err.Error())
+		return
+	}
+	req, err := http.NewRequestWithContext(r.Context(), r.Method, fmt.Sprintf(c.opts.Endpoint, c.opts.Token, method), bytes.NewReader(body))
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	req.Header.Set("Content-Type", r.Header.Get("Content-Type"))
+	resp, err := c.opts.Client.Do(req)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	defer resp.Body.Close()
+	answer, err := io.ReadAll(resp.Body)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	if method == "createForumTopic" {
+		c.bindCreatedTopic(body, answer, q.Name)
+	}
+	w.Header().Set("Content-Type", resp.Header.Get("Content-Type"))
+	w.WriteHeader(resp.StatusCode)
+	w.Write(answer)
+}
+
+// bindCreatedTopic binds a topic a host just created (e.g. with /fork) to it.
+func (c *Coordinator) bindCreatedTopic(request, answer []byte, name string) {
+	form, err := url.ParseQuery(string(request))
+	if err != nil {
+		return
+	}
+	chatID, err := strconv.ParseInt(form.Get("chat_id"), 10, 64)
+	if err != nil {
+		return
+	}
+	var resp struct {
+		OK     bool `json:"ok"`
+		Result struct {
+			ThreadID int `json:"message_thread_id"`
+		} `json:"result"`
+	}
+	if json.Unmarshal(answer, &resp) != nil || !resp.OK || resp.Result.ThreadID == 0 {
+		return
+	}
+	c.bind(chatID, resp.Result.ThreadID, name)
+}
+
+func writeError(w http.ResponseWriter, code int, description string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]any{"ok": false, "error_code": code, "description": description})
+}
+
+// Serve runs the host-facing API on addr until ctx is cancelled.
+func (c *Coordinator) Serve(ctx context.Context, addr string) error {
+	srv := &http.Server{Addr: addr, Handler: c.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+	log.Printf("Federation: coordinator listening on %s for %d host(s)", addr, len(c.order))
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package federation
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/testharness"
+)
+
+func TestParseHosts(t *testing.T) {
+	hosts, err := ParseHosts("desktop:s1, server:s2:with-colon")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hosts) != 2 || hosts[0] != (Host{"desktop", "s1"}) || hosts[1] != (Host{"server", "s2:with-colon"}) {
+		t.Errorf("hosts = %+v", hosts)
+	}
+	for _, bad := range []string{"desktop", ":s", "a:1,a:2", "my host:s"} {
+		if _, err := ParseHosts(bad); err == nil {
+			t.Errorf("ParseHosts(%q) should fail", bad)
+		}
+	}
+}
+
+func TestRoutes_Persist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), File)
+	r, err := LoadRoutes(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Set(-100, 7, "server"); err != nil {
+		t.Fatal(err)
+	}
+	r2, err := LoadRoutes(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if host, ok := r2.Get(-100, 7); !ok || host != "server" {
+		t.Errorf("reloaded route = %q, %v", host, ok)
+	}
+	if _, ok := r2.Get(-100, 8); ok {
+		t.Error("unbound topic should have no route")
+	}
+}
+
+func TestHostQueue_Take(t *testing.T) {
+	q := newHostQueue(Host{Name: "h"})
+	q.push(queuedUpdate{id: 1, raw: []byte(`{"update_id":1}`)})
+	q.push(queuedUpdate{id: 2, raw: []byte(`{"update_id":2}`)})
+	if got := q.take(context.Background(), 0, 1, 0); len(got) != 1 || string(got[0]) != `{"update_id":1}` {
+		t.Errorf("limit 1 = %s", got)
+	}
+	if got := q.take(context.Background(), 2, 0, 0); len(got) != 1 || string(got[0]) != `{"update_id":2}` {
+		t.Errorf("offset 2 = %s", got)
+	}
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		q.push(queuedUpdate{id: 3, raw: []byte(`{"update_id":3}`)})
+	}()
+	if got := q.take(context.Background(), 3, 0, time.Second); len(got) != 1 {
+		t.Errorf("long poll = %s", got)
+	}
+}
+
+// hostAPI is a host's Bot API client pointed at the coordinator.
+func hostAPI(t *testing.T, srv *httptest.Server, token string) *tgbotapi.BotAPI {
+	t.Helper()
+	api, err := tgbotapi.NewBotAPIWithClient(token, srv.URL+"/bot%s/%s", srv.Client())
+	if err != nil {
+		t.Fatalf("host %s: %v", token, err)
+	}
+	return api
+}
+
+func TestCoordinator_PickHostAndRelay(t *testing.T) {
+	tg := testharness.NewTelegram(t)
+	routes, _ := LoadRoutes(filepath.Join(t.TempDir(), File))
+	c := New(Options{
+		Endpoint: tg.Endpoint(),
+		Token:    "test-token",
+		Hosts:    []Host{{"desktop", "s1"}, {"server", "s2"}},
+		Routes:   routes,
+		Allowed:  func(id int64) bool { return id == 42 },
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Run(ctx)
+	srv := httptest.NewServer(c.Handler())
+	defer srv.Close()
+
+	if resp, err := http.Get(srv.URL + "/botdesktop:wrong/getMe"); err != nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("wrong secret: %v %v", resp, err)
+	}
+	server := hostAPI(t, srv, "server:s2")
+	desktop := hostAPI(t, srv, "desktop:s1")
+
+	// A stranger's message in a new topic is ignored; an allowed user's asks for a host
+	tg.PushMessage(-100, 7, 99, "hi")
+	tg.PushMessage(-100, 7, 42, "fix the build")
+	picker := tg.WaitForText("sendMessage", "Which host")
+	if !strings.Contains(picker.Params["reply_markup"], "fed_host:server") || picker.Params["message_thread_id"] != "7" {
+		t.Fatalf("picker = %+v", picker.Params)
+	}
+
+	// A stranger can't pick the host; updates are handled in order, so the
+	// allowed user's pick below would find the topic taken
+	tg.PushCallback(-100, 7, 99, picker.MessageID, "fed_host:desktop")
+	tg.PushCallback(-100, 7, 42, picker.MessageID, "fed_host:server")
+	tg.WaitForText("editMessageText", "runs on server")
+	if host, _ := routes.Get(-100, 7); host != "server" {
+		t.Fatalf("route = %q", host)
+	}
+
+	updates, err := server.GetUpdates(tgbotapi.UpdateConfig{Timeout: 2})
+	if err != nil || len(updates) != 1 || updates[0].Message.Text != "fix the build" {
+		t.Fatalf("server updates = %+v, %v", updates, err)
+	}
+	if updates, _ := desktop.GetUpdates(tgbotapi.UpdateConfig{}); len(updates) != 0 {
+		t.Errorf("desktop got %d updates for a server topic", len(updates))
+	}
+
+	// Later messages go straight to the host; its replies reach Telegram
+	tg.PushMessage(-100, 7, 42, "and run the tests")
+	updates, _ = server.GetUpdates(tgbotapi.UpdateConfig{Offset: updates[0].UpdateID + 1, Timeout: 2})
+	if len(updates) != 1 || updates[0].Message.Text != "and run the tests" {
+		t.Fatalf("second update = %+v", updates)
+	}
+	if _, err := server.Send(tgbotapi.NewMessage(-100, "on it")); err != nil {
+		t.Fatal(err)
+	}
+	tg.WaitForText("sendMessage", "on it")
+
+	// A topic the desktop creates is routed to it
+	if _, err := desktop.MakeRequest("createForumTopic", tgbotapi.Params{"chat_id": "-100", "name": "fork"}); err != nil {
+		t.Fatal(err)
+	}
+	created := tg.WaitFor("createForumTopic", nil)
+	if host, _ := routes.Get(-100, created.MessageID); host != "desktop" {
+		t.Errorf("created topic route = %q", host)
+	}
+
+	// /host describes the topic's host without reaching it, to allowed users
+	tg.PushMessage(-100, 7, 99, "/host")
+	tg.PushMessage(-100, 7, 42, "/host")
+	status := tg.WaitForText("sendMessage", "This topic runs on server")
+	if !strings.Contains(status.Params["text"], "server — connected") {
+		t.Errorf("/host = %q", status.Params["text"])
+	}
+	var described int
+	for _, c := range tg.Calls("sendMessage") {
+		if strings.Contains(c.Params["text"], "This topic runs on") {
+			described++
+		}
+	}
+	if described != 1 {
+		t.Errorf("/host answered %d times, want only the allowed user", described)
+	}
+}
@@ -3,6 +3,7 @@ package git
 import (
 	"fmt"
 	"os/exec"
+	"strconv"
 	"strings"
 )
 
@@ -142,6 +143,97 @@ func ListUnmergedBranches(dir, baseBranch string) ([]string, error) {
 	return branches, nil
 }
 
+// Worktree is a working tree listed by git worktree list.
+type Worktree struct {
+	Path   string
+	Branch string // short branch name; empty for a detached HEAD
+}
+
+// WorktreeList returns the repository's working trees, the main one first.
+func WorktreeList(repoRoot string) ([]Worktree, error) {
+	cmd := exec.Command("git", "-C", repoRoot, "worktree", "list", "--porcelain")
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git worktree list in %s: %w", repoRoot, err)
+	}
+	return parseWorktreeList(string(out)), nil
+}
+
+func parseWorktreeList(out string) []Worktree {
+	var worktrees []Worktree
+	for _, line := range strings.Split(out, "\n") {
+		switch {
+		case strings.HasPrefix(line, "worktree "):
+			worktrees = append(worktrees, Worktree{Path: strings.TrimPrefix(line, "worktree ")})
+		case strings.HasPrefix(line, "branch ") && len(worktrees) > 0:
+			worktrees[len(worktrees)-1].Branch = strings.TrimPrefix(line, "branch refs/heads/")
+		}
+	}
+	return worktrees
+}
+
+// IsMerged reports whether branch's changes are all in base: it is an
+// ancestor of base, or merging it into base would not change base's tree (as
+// after a squash merge).
+func IsMerged(dir, branch, base string) bool {
+	if exec.Command("git", "-C", dir, "merge-base", "--is-ancestor", branch, base).Run() == nil {
+		return true
+	}
+	out, err := exec.Command("git", "-C", dir, "merge-tree", "--write-tree", base, branch).Output()
+	if err != nil {
+		return false
+	}
+	baseTree, err := revParse(dir, base+"^{tree}")
+	return err == nil && firstLine(string(out)) == baseTree
+}
+
+// LocalCommits counts branch's commits that are neither in base nor on any
+// remote, i.e. would be lost with the branch.
+func LocalCommits(dir, branch, base string) (int, error) {
+	cmd := exec.Command("git", "-C", dir, "rev-list", "--count", branch, "--not", base, "--remotes")
+	out, err := cmd.Output()
+	if err != nil {
+		return 0, fmt.Errorf("git rev-list --count %s: %w", branch, err)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(string(out)))
+	if err != nil {
+		return 0, fmt.Errorf("git rev-list --count %s: %w", branch, err)
+	}
+	return n, nil
+}
+
+// IsDirty reports whether dir has uncommitted changes or untracked files.
+func IsDirty(dir string) (bool, error) {
+	cmd := exec.Command("git", "-C", dir, "status", "--porcelain")
+	out, err := cmd.Output()
+	if err != nil {
+		return false, fmt.Errorf("git status in %s: %w", dir, err)
+	}
+	return strings.TrimSpace(string(out)) != "", nil
+}
+
+// Head returns the commit HEAD points to in dir.
+func Head(dir string) (string, error) {
+	return revParse(dir, "HEAD")
+}
+
+// CommitsSince returns "<short sha> <subject>" for each commit reachable from
+// HEAD but not from base, newest first.
+func CommitsSince(dir, base string) ([]string, error) {
+	cmd := exec.Command("git", "-C", dir, "log", "--format=%h %s", base+"..HEAD")
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git log %s..HEAD: %w", base, err)
+	}
+	var commits []string
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if line != "" {
+			commits = append(commits, line)
+		}
+	}
+	return commits, nil
+}
+
 // ResetHard resets the working tree and index to HEAD.
 func ResetHard(dir string) error {
 	cmd := exec.Command("git", "-C", dir, "reset", "--hard", "HEAD")
@@ -185,3 +277,9 @@ func revParse(dir, ref string) (string, error) {
 	}
 	return strings.TrimSpace(string(out)), nil
 }
+
+// firstLine returns text up to its first newline.
+func firstLine(text string) string {
+	line, _, _ := strings.Cut(text, "\n")
+	return strings.TrimSpace(line)
+}
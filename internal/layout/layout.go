@@ -0,0 +1,182 @@
+// Package layout locates Tramuntana's files on disk: the XDG base directories
+// by default, or the flat directory older versions used for everything.
+package layout
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Dirs are the directories Tramuntana reads and writes. In a flat layout all
+// three are the same directory.
+type Dirs struct {
+	Config string // .env and webhooks.json
+	State  string // state.json, session_map.json, usage.json, logs
+	Cache  string // temp prompt files; safe to delete
+	Legacy bool   // found ~/.tramuntana without TRAMUNTANA_DIR; `tramuntana migrate` moves it
+}
+
+// Flat reports whether every kind of file lives in one directory.
+func (d Dirs) Flat() bool {
+	return d.Config == d.State && d.State == d.Cache
+}
+
+// configFiles are the files that move to the config directory on migration;
+// everything else is state.
+var configFiles = map[string]bool{".env": true, "webhooks.json": true}
+
+// Resolve returns the directories to use: TRAMUNTANA_DIR if set, else an
+// existing ~/.tramuntana, else $XDG_CONFIG_HOME, $XDG_STATE_HOME and
+// $XDG_CACHE_HOME (each defaulting as the XDG spec says) under "tramuntana".
+func Resolve() Dirs {
+	if dir := os.Getenv("TRAMUNTANA_DIR"); dir != "" {
+		dir = expandHome(dir)
+		return Dirs{Config: dir, State: dir, Cache: dir}
+	}
+	if legacy := LegacyDir(); legacy != "" {
+		if info, err := os.Stat(legacy); err == nil && info.IsDir() {
+			return Dirs{Config: legacy, State: legacy, Cache: legacy, Legacy: true}
+		}
+	}
+	return XDG()
+}
+
+// XDG returns the XDG base directories for Tramuntana.
+func XDG() Dirs {
+	return Dirs{
+		Config: xdgDir("XDG_CONFIG_HOME", ".config"),
+		State:  xdgDir("XDG_STATE_HOME", filepath.Join(".local", "state")),
+		Cache:  xdgDir("XDG_CACHE_HOME", ".cache"),
+	}
+}
+
+// LegacyDir returns ~/.tramuntana, or "" without a home directory.
+func LegacyDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".tramuntana")
+}
+
+// MkdirAll creates the directories.
+func (d Dirs) MkdirAll() error {
+	for _, dir := range []string{d.Config, d.State, d.Cache} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("creating %s: %w", dir, err)
+		}
+	}
+	return nil
+}
+
+// xdgDir returns $env/tramuntana, or ~/fallback/tramuntana when env is unset
+// or not absolute (which the spec says to ignore).
+func xdgDir(env, fallback string) string {
+	if base := os.Getenv(env); filepath.IsAbs(base) {
+		return filepath.Join(base, "tramuntana")
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, fallback, "tramuntana")
+}
+
+// Move is one file or directory moved by Migrate.
+type Move struct {
+	From, To string
+}
+
+// Migrate moves the contents of the flat directory from into the split
+// layout to: config files into to.Config, everything else into to.State.
+// It refuses to overwrite anything already there. With dryRun it only
+// returns what it would move. from is removed once empty.
+func Migrate(from string, to Dirs, dryRun bool) ([]Move, error) {
+	entries, err := os.ReadDir(from)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", from, err)
+	}
+	var moves []Move
+	for _, e := range entries {
+		dest := to.State
+		if configFiles[e.Name()] {
+			dest = to.Config
+		}
+		m := Move{From: filepath.Join(from, e.Name()), To: filepath.Join(dest, e.Name())}
+		if _, err := os.Lstat(m.To); err == nil {
+			return nil, fmt.Errorf("%s already exists", m.To)
+		}
+		moves = append(moves, m)
+	}
+	if dryRun {
+		return moves, nil
+	}
+
+	if err := to.MkdirAll(); err != nil {
+		return nil, err
+	}
+	for i, m := range moves {
+		if err := move(m.From, m.To); err != nil {
+			return moves[:i], fmt.Errorf("moving %s: %w", m.From, err)
+		}
+	}
+	if err := os.Remove(from); err != nil {
+		return moves, fmt.Errorf("removing %s: %w", from, err)
+	}
+	return moves, nil
+}
+
+// move renames from to to, copying across filesystems.
+func move(from, to string) error {
+	err := os.Rename(from, to)
+	var linkErr *os.LinkError
+	if err == nil || !errors.As(err, &linkErr) {
+		return err
+	}
+	if err := copyTree(from, to); err != nil {
+		os.RemoveAll(to)
+		return err
+	}
+	return os.RemoveAll(from)
+}
+
+// copyTree copies a file or directory, keeping permissions.
+func copyTree(from, to string) error {
+	return filepath.Walk(from, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(to, strings.TrimPrefix(path, from))
+		switch {
+		case info.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		case !info.Mode().IsRegular():
+			return nil // sockets and the like are recreated by serve
+		}
+		src, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		dst, err := os.OpenFile(target, os.O_CREATE|os.O_EXCL|os.O_WRONLY, info.Mode().Perm())
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(dst, src); err != nil {
+			dst.Close()
+			return err
+		}
+		return dst.Close()
+	})
+}
+
+// expandHome replaces a leading ~/ with the home directory.
+func expandHome(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[2:])
+		}
+	}
+	return path
+}
@@ -0,0 +1,117 @@
+package layout
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func setHome(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("TRAMUNTANA_DIR", "")
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("XDG_STATE_HOME", "")
+	t.Setenv("XDG_CACHE_HOME", "")
+	return home
+}
+
+func TestResolve_XDGDefaults(t *testing.T) {
+	home := setHome(t)
+	d := Resolve()
+	want := Dirs{
+		Config: filepath.Join(home, ".config", "tramuntana"),
+		State:  filepath.Join(home, ".local", "state", "tramuntana"),
+		Cache:  filepath.Join(home, ".cache", "tramuntana"),
+	}
+	if d != want {
+		t.Errorf("Resolve() = %+v, want %+v", d, want)
+	}
+	if d.Flat() {
+		t.Error("XDG layout should not be flat")
+	}
+}
+
+func TestResolve_XDGEnv(t *testing.T) {
+	setHome(t)
+	t.Setenv("XDG_STATE_HOME", "/var/lib/me")
+	t.Setenv("XDG_CACHE_HOME", "relative/ignored")
+	d := Resolve()
+	if d.State != "/var/lib/me/tramuntana" {
+		t.Errorf("State = %q", d.State)
+	}
+	if filepath.Base(filepath.Dir(d.Cache)) != ".cache" {
+		t.Errorf("relative XDG_CACHE_HOME should be ignored, got %q", d.Cache)
+	}
+}
+
+func TestResolve_Legacy(t *testing.T) {
+	home := setHome(t)
+	legacy := filepath.Join(home, ".tramuntana")
+	os.Mkdir(legacy, 0755)
+	d := Resolve()
+	if !d.Legacy || !d.Flat() || d.State != legacy {
+		t.Errorf("Resolve() = %+v, want legacy flat %s", d, legacy)
+	}
+}
+
+func TestResolve_TramuntanaDir(t *testing.T) {
+	home := setHome(t)
+	os.Mkdir(filepath.Join(home, ".tramuntana"), 0755)
+	t.Setenv("TRAMUNTANA_DIR", "~/bridge")
+	d := Resolve()
+	want := filepath.Join(home, "bridge")
+	if d.Legacy || !d.Flat() || d.State != want {
+		t.Errorf("Resolve() = %+v, want flat %s", d, want)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	home := setHome(t)
+	from := filepath.Join(home, ".tramuntana")
+	os.Mkdir(from, 0755)
+	os.WriteFile(filepath.Join(from, ".env"), []byte("A=1\n"), 0600)
+	os.WriteFile(filepath.Join(from, "state.json"), []byte("{}"), 0644)
+	to := XDG()
+
+	moves, err := Migrate(from, to, true)
+	if err != nil || len(moves) != 2 {
+		t.Fatalf("dry run = %v, %v", moves, err)
+	}
+	if _, err := os.Stat(filepath.Join(from, "state.json")); err != nil {
+		t.Fatal("dry run should not move anything")
+	}
+
+	if _, err := Migrate(from, to, false); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{filepath.Join(to.Config, ".env"), filepath.Join(to.State, "state.json")} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("%s missing after migrate", path)
+		}
+	}
+	if _, err := os.Stat(from); !os.IsNotExist(err) {
+		t.Error("legacy directory should be removed")
+	}
+	if d := Resolve(); d.Legacy {
+		t.Error("Resolve should use XDG after migrating")
+	}
+}
+
+func TestMigrate_RefusesOverwrite(t *testing.T) {
+	home := setHome(t)
+	from := filepath.Join(home, ".tramuntana")
+	os.Mkdir(from, 0755)
+	os.WriteFile(filepath.Join(from, "state.json"), []byte("old"), 0644)
+	to := XDG()
+	to.MkdirAll()
+	os.WriteFile(filepath.Join(to.State, "state.json"), []byte("new"), 0644)
+
+	if _, err := Migrate(from, to, false); err == nil {
+		t.Fatal("expected an error for an existing target")
+	}
+	if data, _ := os.ReadFile(filepath.Join(to.State, "state.json")); string(data) != "new" {
+		t.Errorf("target overwritten: %q", data)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os/exec"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -94,6 +95,54 @@ func (b *Bridge) Status(project string) ([]Task, error) {
 	return tasks, nil
 }
 
+// Project summarises a Minuano project. Minuano has no project table: a project
+// exists once a task references it, so projects are derived from the task list.
+type Project struct {
+	ID    string
+	Total int // all tasks
+	Open  int // tasks not yet done or failed
+}
+
+// Projects lists all projects with task counts, sorted by name.
+func (b *Bridge) Projects() ([]Project, error) {
+	tasks, err := b.Status("")
+	if err != nil {
+		return nil, err
+	}
+	return projectsFromTasks(tasks), nil
+}
+
+func projectsFromTasks(tasks []Task) []Project {
+	byID := make(map[string]*Project)
+	for _, t := range tasks {
+		if t.ProjectID == nil || *t.ProjectID == "" {
+			continue
+		}
+		p, ok := byID[*t.ProjectID]
+		if !ok {
+			p = &Project{ID: *t.ProjectID}
+			byID[p.ID] = p
+		}
+		p.Total++
+		if t.Status != "done" && t.Status != "failed" {
+			p.Open++
+		}
+	}
+
+	projects := make([]Project, 0, len(byID))
+	for _, p := range byID {
+		projects = append(projects, *p)
+	}
+	sort.Slice(projects, func(i, j int) bool { return projects[i].ID < projects[j].ID })
+	return projects
+}
+
+// ValidProjectName reports whether name is usable as a new project ID:
+// non-empty, at most 64 characters of letters, digits, '-' and '_'.
+func ValidProjectName(name string) bool {
+	return name != "" && len(name) <= 64 && sanitizeID(name) == name
+}
+
 // Show returns detailed info for a specific task.
 func (b *Bridge) Show(taskID string) (*TaskDetail, error) {
 	out, err := b.run("show", "--json", taskID)
@@ -178,6 +227,80 @@ func (b *Bridge) Delete(taskID string) error {
 	return nil
 }
 
+// execSQL runs a SQL script through psql with the given variables bound via -v.
+// Values are referenced in the script as :'name', so psql quotes them safely.
+// Returns psql's trimmed output (e.g. "UPDATE 1").
+func (b *Bridge) execSQL(script string, vars map[string]string) (string, error) {
+	if b.DBFlag == "" {
+		return "", fmt.Errorf("DATABASE_URL not configured")
+	}
+
+	args := []string{b.DBFlag, "-X", "-q", "-v", "ON_ERROR_STOP=1"}
+	for k, v := range vars {
+		args = append(args, "-v", k+"="+v)
+	}
+	cmd := exec.Command("psql", args...)
+	// Scripts are fed on stdin: psql only interpolates variables there, not in -c.
+	cmd.Stdin = strings.NewReader(script)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("psql: %s", strings.TrimSpace(string(out)))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// updateTask runs a single-row UPDATE on a task and fails if no row matched.
+func (b *Bridge) updateTask(taskID, set string, vars map[string]string) error {
+	if vars == nil {
+		vars = make(map[string]string)
+	}
+	vars["id"] = taskID
+	out, err := b.execSQL(fmt.Sprintf(
+		"UPDATE tasks SET %s WHERE id = :'id' RETURNING id;\n", set), vars)
+	if err != nil {
+		return fmt.Errorf("updating task %s: %w", taskID, err)
+	}
+	if !strings.Contains(out, taskID) {
+		return fmt.Errorf("task %s not found", taskID)
+	}
+	return nil
+}
+
+// SetTitle renames a task.
+func (b *Bridge) SetTitle(taskID, title string) error {
+	return b.updateTask(taskID, "title = :'title'", map[string]string{"title": title})
+}
+
+// SetPriority changes a task's priority.
+func (b *Bridge) SetPriority(taskID string, priority int) error {
+	return b.updateTask(taskID, "priority = :'priority'::int", map[string]string{"priority": strconv.Itoa(priority)})
+}
+
+// AddNote appends a context note to a task, as minuano-observe does.
+func (b *Bridge) AddNote(taskID, note string) error {
+	if err := b.AddContext(taskID, "observation", note); err != nil {
+		return fmt.Errorf("adding note to %s: %w", taskID, err)
+	}
+	return nil
+}
+
+// AddContext appends a context entry of the given kind (e.g. "observation",
+// "handoff") to a task. Tasks that depend on it inherit the entry when claimed.
+func (b *Bridge) AddContext(taskID, kind, content string) error {
+	_, err := b.execSQL(
+		"INSERT INTO task_context (task_id, kind, content) VALUES (:'id', :'kind', :'content');\n",
+		map[string]string{"id": taskID, "kind": kind, "content": content})
+	if err != nil {
+		return fmt.Errorf("adding %s context to %s: %w", kind, taskID, err)
+	}
+	return nil
+}
+
+// Drop cancels a task by marking it failed and releasing any claim.
+func (b *Bridge) Drop(taskID string) error {
+	return b.updateTask(taskID, "status = 'failed', claimed_by = NULL", nil)
+}
+
 // sanitizeID strips everything except alphanumerics and hyphens from a task ID.
 func sanitizeID(id string) string {
 	var b strings.Builder
@@ -210,6 +333,15 @@ func (b *Bridge) Add(title, project, body string, priority int) (*AddResult, err
 	return parseAddOutput(out)
 }
 
+// CreateProject creates a project with its first task via `minuano add`.
+// Minuano has no project table: a project exists once a task references it.
+func (b *Bridge) CreateProject(name, firstTask string) (*AddResult, error) {
+	if !ValidProjectName(name) {
+		return nil, fmt.Errorf("invalid project name %q", name)
+	}
+	return b.Add(firstTask, name, "", 5)
+}
+
 // AddWithDeps creates a new task with dependency ordering via `minuano add --after`.
 func (b *Bridge) AddWithDeps(title, project, body string, priority int, afterIDs []string) (*AddResult, error) {
 	args := []string{"add", title, "--project", project, "--priority", strconv.Itoa(priority)}
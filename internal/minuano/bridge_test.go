@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -318,6 +319,34 @@ echo 'Created: fix-bug-abc12  "Fix the bug"'
 	}
 }
 
+func TestBridge_CreateProject(t *testing.T) {
+	dir := t.TempDir()
+	scriptPath := filepath.Join(dir, "minuano")
+	argsFile := filepath.Join(dir, "args.txt")
+	script := `#!/bin/bash
+echo "$@" > ` + argsFile + `
+echo 'Created: set-up-billing-a1b2c  "Set up billing"'
+`
+	os.WriteFile(scriptPath, []byte(script), 0755)
+
+	b := NewBridge(scriptPath, "")
+	result, err := b.CreateProject("billing", "Set up billing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ID != "set-up-billing-a1b2c" {
+		t.Errorf("ID = %q", result.ID)
+	}
+	args, _ := os.ReadFile(argsFile)
+	if got := strings.TrimSpace(string(args)); got != "add Set up billing --project billing --priority 5" {
+		t.Errorf("args = %q", got)
+	}
+
+	if _, err := b.CreateProject("bad name;", "Set up"); err == nil {
+		t.Error("expected error for invalid project name")
+	}
+}
+
 func TestBridge_Add_NonExistentBinary(t *testing.T) {
 	b := NewBridge("/nonexistent/binary", "")
 	_, err := b.Add("title", "project", "", 5)
@@ -391,3 +420,107 @@ func containsSubstr(s, substr string) bool {
 	}
 	return false
 }
+
+// mockPsql installs a fake psql on PATH that records its args and stdin,
+// then prints output.
+func mockPsql(t *testing.T, output string) (argsFile, stdinFile string) {
+	t.Helper()
+	dir := t.TempDir()
+	argsFile = filepath.Join(dir, "args")
+	stdinFile = filepath.Join(dir, "stdin")
+	script := "#!/bin/bash\nprintf '%s\\n' \"$@\" > " + argsFile + "\ncat > " + stdinFile + "\necho '" + output + "'\n"
+	os.WriteFile(filepath.Join(dir, "psql"), []byte(script), 0755)
+	t.Setenv("PATH", dir+":"+os.Getenv("PATH"))
+	return argsFile, stdinFile
+}
+
+func TestBridge_SetTitle_MockPsql(t *testing.T) {
+	argsFile, stdinFile := mockPsql(t, "t-1")
+
+	b := NewBridge("minuano", "postgres://db")
+	if err := b.SetTitle("t-1", "Robert'); DROP TABLE tasks;--"); err != nil {
+		t.Fatal(err)
+	}
+
+	args, _ := os.ReadFile(argsFile)
+	if !strings.Contains(string(args), "title=Robert'); DROP TABLE tasks;--") {
+		t.Errorf("title should be passed as a psql variable, args:\n%s", args)
+	}
+	stdin, _ := os.ReadFile(stdinFile)
+	if !strings.Contains(string(stdin), "title = :'title'") || strings.Contains(string(stdin), "Robert") {
+		t.Errorf("script should reference the variable, not inline the value:\n%s", stdin)
+	}
+}
+
+func TestBridge_AddContext_MockPsql(t *testing.T) {
+	argsFile, stdinFile := mockPsql(t, "")
+
+	b := NewBridge("minuano", "postgres://db")
+	if err := b.AddContext("t-1", "handoff", "Files changed:\n- a.go"); err != nil {
+		t.Fatal(err)
+	}
+
+	args, _ := os.ReadFile(argsFile)
+	for _, want := range []string{"id=t-1", "kind=handoff", "content=Files changed:"} {
+		if !strings.Contains(string(args), want) {
+			t.Errorf("args missing %q:\n%s", want, args)
+		}
+	}
+	stdin, _ := os.ReadFile(stdinFile)
+	if !strings.Contains(string(stdin), "VALUES (:'id', :'kind', :'content')") {
+		t.Errorf("script:\n%s", stdin)
+	}
+}
+
+func TestBridge_SetPriority_NotFound(t *testing.T) {
+	mockPsql(t, "")
+
+	b := NewBridge("minuano", "postgres://db")
+	if err := b.SetPriority("missing", 3); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected not found error, got %v", err)
+	}
+}
+
+func TestBridge_Edit_NoDB(t *testing.T) {
+	b := NewBridge("minuano", "")
+	if err := b.Drop("t-1"); err == nil {
+		t.Error("expected error without DATABASE_URL")
+	}
+	if err := b.AddNote("t-1", "note"); err == nil {
+		t.Error("expected error without DATABASE_URL")
+	}
+}
+
+func TestProjectsFromTasks(t *testing.T) {
+	auth, web := "auth", "web"
+	tasks := []Task{
+		{ID: "a1", Status: "done", ProjectID: &auth},
+		{ID: "a2", Status: "ready", ProjectID: &auth},
+		{ID: "w1", Status: "claimed", ProjectID: &web},
+		{ID: "x1", Status: "ready"},
+	}
+	got := projectsFromTasks(tasks)
+	want := []Project{{ID: "auth", Total: 2, Open: 1}, {ID: "web", Total: 1, Open: 1}}
+	if len(got) != len(want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("project %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestValidProjectName(t *testing.T) {
+	for name, want := range map[string]bool{
+		"auth-system": true,
+		"web_2":       true,
+		"":            false,
+		"has space":   false,
+		"semi;colon":  false,
+	} {
+		if got := ValidProjectName(name); got != want {
+			t.Errorf("ValidProjectName(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
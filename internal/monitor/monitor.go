@@ -2,20 +2,30 @@ package monitor
 
 import (
 	"bufio"
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
 	"log"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
-	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/otaviocarvalho/tramuntana/internal/chaos"
 	"github.com/otaviocarvalho/tramuntana/internal/config"
+	"github.com/otaviocarvalho/tramuntana/internal/events"
 	"github.com/otaviocarvalho/tramuntana/internal/queue"
 	"github.com/otaviocarvalho/tramuntana/internal/render"
 	"github.com/otaviocarvalho/tramuntana/internal/state"
+	"github.com/otaviocarvalho/tramuntana/internal/supervise"
+	"github.com/otaviocarvalho/tramuntana/internal/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Monitor polls Claude Code JSONL transcript files and routes entries to the message queue.
@@ -24,33 +34,115 @@ type Monitor struct {
 	state          *state.State
 	monitorState   *state.MonitorState
 	queue          *queue.Queue
-	pendingTools   map[string]PendingTool
+	pendingTools   map[string]map[string]PendingTool // windowID → tool_use_id → pending call
 	fileMtimes     map[string]time.Time
 	lastSessionMap map[string]state.SessionMapEntry
 	pollInterval   time.Duration
-	turnStarts     sync.Map // windowID → time.Time
 	PlanHandler    func(userID int64, threadID int, chatID int64, planJSON string)
 	planBuffers    map[string]string // windowID → partial plan text
+	profile        render.Profile
+	Usage          *state.Usage            // optional; records per-window activity for digests
+	lastUsage      map[string]messageUsage // windowID → usage recorded for its latest assistant message
+	started        time.Time               // transcripts last written before this are bootstrapped
+	notifOffset    atomic.Int64            // bytes of notifications.jsonl already delivered; -1 until the first poll
+	ctx            context.Context         // set by Run; once done, nothing more is delivered or recorded
+	Events         *events.Bus             // optional; receives TurnStarted
+	Heartbeat      *supervise.Heartbeat    // optional; beaten after every poll
+
+	// ActivityHandler, if set, is called once per window with each batch of new entries.
+	ActivityHandler func(windowID string, parsed []ParsedEntry)
 }
 
 // New creates a new Monitor.
 func New(cfg *config.Config, st *state.State, ms *state.MonitorState, q *queue.Queue) *Monitor {
-	return &Monitor{
+	profile, ok := render.LookupProfile(cfg.Verbosity)
+	if !ok {
+		log.Printf("Unknown verbosity profile %q, using %q", cfg.Verbosity, render.DefaultProfile.Name)
+		profile = render.DefaultProfile
+	}
+	if cfg.TestParsers != nil {
+		for _, name := range cfg.TestParsers {
+			if !render.HasTestParser(name) {
+				log.Printf("Unknown test parser %q in TRAMUNTANA_TEST_PARSERS", name)
+			}
+		}
+		profile.TestParsers = cfg.TestParsers
+	}
+
+	m := &Monitor{
 		config:         cfg,
 		state:          st,
 		monitorState:   ms,
 		queue:          q,
-		pendingTools:   make(map[string]PendingTool),
+		pendingTools:   make(map[string]map[string]PendingTool),
 		fileMtimes:     make(map[string]time.Time),
 		lastSessionMap: make(map[string]state.SessionMapEntry),
 		pollInterval:   time.Duration(cfg.MonitorPollInterval * float64(time.Second)),
 		planBuffers:    make(map[string]string),
+		profile:        profile,
+		started:        time.Now(),
 	}
+	m.notifOffset.Store(-1)
+	return m
+}
+
+// TakeOver makes m continue where prev, an instance it replaces, left off:
+// notifications are read from prev's offset instead of skipping those written
+// meanwhile. prev's context must be cancelled first, so it stops delivering.
+func (m *Monitor) TakeOver(prev *Monitor) {
+	if offset := prev.notifOffset.Load(); offset >= 0 {
+		m.notifOffset.Store(offset)
+	}
+}
+
+// stopped reports whether Run's context is done. A monitor abandoned by the
+// watchdog may wake up later; it must not deliver alongside its replacement.
+func (m *Monitor) stopped() bool {
+	return m.ctx != nil && m.ctx.Err() != nil
+}
+
+// bootstrapOffset returns where to start reading a transcript seen for the first
+// time: its end ("eof"), its last tailBytes ("tail"), or its start (anything else).
+func bootstrapOffset(policy string, tailBytes, size int64) int64 {
+	switch policy {
+	case "eof":
+		return size
+	case "tail":
+		return max(size-tailBytes, 0)
+	default:
+		return 0
+	}
+}
+
+// capEntries keeps the newest limit entries, returning how many older ones were dropped.
+// A limit of 0 keeps everything.
+func capEntries(parsed []ParsedEntry, limit int) ([]ParsedEntry, int) {
+	if limit <= 0 || len(parsed) <= limit {
+		return parsed, 0
+	}
+	skipped := len(parsed) - limit
+	return parsed[skipped:], skipped
+}
+
+// batchThinking merges each run of consecutive thinking entries into one, so
+// a long reasoning trace arrives as one message.
+func batchThinking(parsed []ParsedEntry) []ParsedEntry {
+	var out []ParsedEntry
+	for _, pe := range parsed {
+		if n := len(out); n > 0 && pe.ContentType == "thinking" && out[n-1].ContentType == "thinking" {
+			out[n-1].Text += "\n\n" + pe.Text
+			out[n-1].End = pe.End
+			continue
+		}
+		out = append(out, pe)
+	}
+	return out
 }
 
 // Run starts the monitor poll loop. Blocks until ctx is cancelled.
 func (m *Monitor) Run(ctx context.Context) {
 	log.Println("Session monitor starting...")
+	m.ctx = ctx
 	ticker := time.NewTicker(m.pollInterval)
 	defer ticker.Stop()
 
@@ -62,11 +154,17 @@ func (m *Monitor) Run(ctx context.Context) {
 			return
 		case <-ticker.C:
 			m.poll()
+			m.Heartbeat.Beat()
 		}
 	}
 }
 
 func (m *Monitor) poll() {
+	// While paused, offsets stay put so the backlog is read on resume
+	if m.state.IsPaused() {
+		return
+	}
+
 	// Load session_map.json
 	sessionMapPath := filepath.Join(m.config.TramuntanaDir, "session_map.json")
 	sm, err := state.LoadSessionMap(sessionMapPath)
@@ -79,6 +177,9 @@ func (m *Monitor) poll() {
 
 	// Process each active session
 	for key, entry := range sm {
+		if m.stopped() {
+			return
+		}
 		windowID := windowIDFromSessionKey(key)
 		if windowID == "" {
 			continue
@@ -100,6 +201,10 @@ func (m *Monitor) poll() {
 	}
 
 	m.lastSessionMap = sm
+	if m.stopped() {
+		return
+	}
+	m.pollNotifications()
 
 	// Periodically save state
 	monitorStatePath := filepath.Join(m.config.TramuntanaDir, "monitor_state.json")
@@ -114,6 +219,50 @@ func (m *Monitor) detectChanges(newMap map[string]state.SessionMapEntry) {
 			delete(m.fileMtimes, key)
 		}
 	}
+
+	// A window whose hook reported a new session ID was cleared in the terminal
+	for key, entry := range newMap {
+		old, ok := m.lastSessionMap[key]
+		if ok && old.SessionID != "" && entry.SessionID != "" && old.SessionID != entry.SessionID {
+			m.switchSession(key, old.SessionID, entry.SessionID)
+		}
+	}
+}
+
+// switchSession moves a window's tracking from its old session to a new one,
+// read from the start once its transcript appears. A /clear sent from Telegram
+// already dropped the old tracking, so only a switch made in the terminal is
+// announced.
+func (m *Monitor) switchSession(sessionKey, oldID, newID string) {
+	tracked, ok := m.monitorState.GetTracked(sessionKey)
+	if !ok || tracked.SessionID != oldID {
+		return
+	}
+	windowID := windowIDFromSessionKey(sessionKey)
+	log.Printf("Monitor: %s cleared in the terminal, session %s → %s", sessionKey, oldID, newID)
+	m.monitorState.UpdateOffset(sessionKey, newID, "", 0)
+	delete(m.pendingTools, windowID)
+	delete(m.planBuffers, windowID)
+
+	if m.queue == nil {
+		return
+	}
+	for _, ut := range m.state.FindUsersForWindow(windowID) {
+		chatID, ok := m.state.GetGroupChatID(ut.UserID, ut.ThreadID)
+		if !ok {
+			continue
+		}
+		threadID, _ := strconv.Atoi(ut.ThreadID)
+		userID, _ := strconv.ParseInt(ut.UserID, 10, 64)
+		m.queue.Enqueue(queue.MessageTask{
+			UserID:      userID,
+			ThreadID:    threadID,
+			ChatID:      chatID,
+			Parts:       []string{"🧹 Session cleared — following the new conversation"},
+			ContentType: "content",
+			WindowID:    windowID,
+		})
+	}
 }
 
 func (m *Monitor) hasFileChanged(path string) bool {
@@ -140,6 +289,15 @@ func (m *Monitor) processSession(sessionKey, sessionID, windowID, jsonlPath stri
 		offset = tracked.LastByteOffset
 	}
 
+	// The window moved to another session (/clear, /compact, resume): the
+	// saved offset belongs to the old file, so read the new one from the start.
+	if hasTracked && (tracked.SessionID != sessionID || (tracked.FilePath != "" && tracked.FilePath != jsonlPath)) {
+		log.Printf("Monitor: %s switched from session %s to %s (%s)", sessionKey, tracked.SessionID, sessionID, jsonlPath)
+		offset = 0
+		delete(m.pendingTools, windowID)
+		m.monitorState.UpdateOffset(sessionKey, sessionID, jsonlPath, 0)
+	}
+
 	// Check file size (detect truncation from /clear)
 	info, err := os.Stat(jsonlPath)
 	if err != nil {
@@ -149,6 +307,19 @@ func (m *Monitor) processSession(sessionKey, sessionID, windowID, jsonlPath stri
 		offset = 0 // file was truncated
 	}
 
+	// Transcripts already on disk at startup (no saved offset) follow the
+	// bootstrap policy instead of replaying from the start.
+	skipPartial := false
+	if !hasTracked && info.ModTime().Before(m.started) {
+		offset = bootstrapOffset(m.config.BootstrapPolicy, m.config.BootstrapTailBytes, info.Size())
+		skipPartial = offset > 0 && offset < info.Size()
+		if offset > 0 {
+			log.Printf("Monitor: bootstrapping %s at byte %d of %d (%s)", sessionKey, offset, info.Size(), m.config.BootstrapPolicy)
+			// Record the start so later polls (after the file changes) resume here
+			m.monitorState.UpdateOffset(sessionKey, sessionID, jsonlPath, offset)
+		}
+	}
+
 	// Open and read new content
 	f, err := os.Open(jsonlPath)
 	if err != nil {
@@ -156,20 +327,36 @@ func (m *Monitor) processSession(sessionKey, sessionID, windowID, jsonlPath stri
 	}
 	defer f.Close()
 
+	if skipPartial {
+		// Offset landed on a line start: nothing to skip
+		prev := make([]byte, 1)
+		if _, err := f.ReadAt(prev, offset-1); err == nil && prev[0] == '\n' {
+			skipPartial = false
+		}
+	}
+
 	if offset > 0 {
 		if _, err := f.Seek(offset, 0); err != nil {
 			return
 		}
 	}
 
+	readAt := time.Now()
 	var entries []*Entry
-	scanner := bufio.NewScanner(f)
+	var digests []string // of each entry's line, for skipping lines delivered before
+	scanner := bufio.NewScanner(chaos.TruncateReader(f, info.Size()-offset))
 	scanner.Buffer(make([]byte, 1024*1024), 1024*1024) // 1MB buffer for large lines
+	scanner.Split(scanCompleteLines)
 	var bytesRead int64
 
 	for scanner.Scan() {
 		line := scanner.Bytes()
+		start := offset + bytesRead
 		bytesRead += int64(len(line)) + 1 // +1 for newline
+		if skipPartial {
+			skipPartial = false // tail bootstrap starts mid-line
+			continue
+		}
 
 		entry, err := ParseLine(line)
 		if err != nil {
@@ -177,7 +364,9 @@ func (m *Monitor) processSession(sessionKey, sessionID, windowID, jsonlPath stri
 			continue
 		}
 		if entry != nil {
+			entry.Start, entry.End = start, offset+bytesRead
 			entries = append(entries, entry)
+			digests = append(digests, lineDigest(line))
 		}
 	}
 	if err := scanner.Err(); err != nil {
@@ -185,6 +374,12 @@ func (m *Monitor) processSession(sessionKey, sessionID, windowID, jsonlPath stri
 		return // don't advance offset — will re-read on next poll
 	}
 
+	// The read may be where a stalled instance was stuck
+	if m.stopped() {
+		return
+	}
+	entries = m.dropDelivered(sessionKey, entries, digests, readAt)
+
 	if len(entries) == 0 {
 		// Update offset even if no entries (skip empty lines)
 		if bytesRead > 0 {
@@ -194,8 +389,19 @@ func (m *Monitor) processSession(sessionKey, sessionID, windowID, jsonlPath stri
 		return
 	}
 
+	m.recordUsage(windowID, entries)
+
 	// Parse entries with tool pairing
-	parsed := ParseEntries(entries, m.pendingTools)
+	parsed := ParseEntries(entries, m.pendingFor(windowID))
+	if m.ActivityHandler != nil {
+		m.ActivityHandler(windowID, parsed)
+	}
+
+	// Deliver only the newest entries when a poll picks up a large backlog
+	deliver, skipped := capEntries(batchThinking(parsed), m.config.MaxEntriesPerPoll)
+	traces, endTraces := traceEntries(windowID, deliver, readAt)
+	defer endTraces()
+	transcript := queue.Source{SessionKey: sessionKey, SessionID: sessionID, Path: jsonlPath}
 
 	// Route to users
 	users := m.state.FindUsersForWindow(windowID)
@@ -207,8 +413,22 @@ func (m *Monitor) processSession(sessionKey, sessionID, windowID, jsonlPath stri
 		threadID, _ := strconv.Atoi(ut.ThreadID)
 		userID, _ := strconv.ParseInt(ut.UserID, 10, 64)
 
-		for _, pe := range parsed {
-			m.enqueueEntry(userID, threadID, chatID, windowID, pe)
+		if skipped > 0 && m.queue != nil {
+			m.queue.Enqueue(queue.MessageTask{
+				UserID:      userID,
+				ThreadID:    threadID,
+				ChatID:      chatID,
+				Parts:       []string{fmt.Sprintf("⏭ %d older entries skipped — /p_history to browse", skipped)},
+				ContentType: "content",
+				WindowID:    windowID,
+			})
+		}
+		for i, pe := range deliver {
+			var traceCtx context.Context
+			if traces != nil {
+				traceCtx = traces[i]
+			}
+			m.enqueueEntry(userID, threadID, chatID, windowID, pe, transcript, traceCtx)
 		}
 	}
 
@@ -217,27 +437,89 @@ func (m *Monitor) processSession(sessionKey, sessionID, windowID, jsonlPath stri
 	m.monitorState.UpdateOffset(sessionKey, sessionID, jsonlPath, newOffset)
 }
 
-// SetTurnStart records the start time of a user turn for a window.
-func (m *Monitor) SetTurnStart(windowID string) {
-	m.turnStarts.Store(windowID, time.Now())
+// dropDelivered removes the entries whose lines were already delivered for
+// the session within the dedup window: an offset reset (truncation, a /clear
+// race, the transcript being resolved again) can read them a second time.
+func (m *Monitor) dropDelivered(sessionKey string, entries []*Entry, digests []string, now time.Time) []*Entry {
+	if m.config.DedupWindow <= 0 || len(entries) == 0 {
+		return entries
+	}
+	keep := m.monitorState.FilterDelivered(sessionKey, digests, now, m.config.DedupWindow)
+	fresh := entries[:0]
+	for i, entry := range entries {
+		if keep[i] {
+			fresh = append(fresh, entry)
+		}
+	}
+	if dropped := len(entries) - len(fresh); dropped > 0 {
+		log.Printf("Monitor: %s skipped %d already delivered entries (%d so far)", sessionKey, dropped, m.monitorState.Duplicates(sessionKey))
+	}
+	return fresh
 }
 
-// GetAndClearTurnStart returns the turn start time and clears it.
-func (m *Monitor) GetAndClearTurnStart(windowID string) (time.Time, bool) {
-	v, ok := m.turnStarts.LoadAndDelete(windowID)
-	if !ok {
-		return time.Time{}, false
+// lineDigest hashes a transcript line. Lines carry their entry's UUID and
+// timestamp, so equal digests mean the same entry read twice.
+func lineDigest(line []byte) string {
+	sum := sha256.Sum256(line)
+	return hex.EncodeToString(sum[:8])
+}
+
+// scanCompleteLines is bufio.ScanLines without a final unterminated line:
+// Claude may still be writing it, so it is left for the next poll instead of
+// failing to parse and being skipped.
+func scanCompleteLines(data []byte, atEOF bool) (int, []byte, error) {
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		return i + 1, bytes.TrimSuffix(data[:i], []byte("\r")), nil
+	}
+	if atEOF && len(data) > 0 {
+		return 0, nil, bufio.ErrFinalToken
+	}
+	return 0, nil, nil
+}
+
+// traceEntries starts a span per entry, from when its line was read until it
+// has been enqueued for every bound user; each delivery is a child span.
+// Returns nil contexts when tracing is off.
+func traceEntries(windowID string, entries []ParsedEntry, readAt time.Time) ([]context.Context, func()) {
+	if !telemetry.Enabled() || len(entries) == 0 {
+		return nil, func() {}
+	}
+	ctxs := make([]context.Context, len(entries))
+	spans := make([]trace.Span, len(entries))
+	for i, pe := range entries {
+		attrs := []attribute.KeyValue{
+			attribute.String("tmux.window_id", windowID),
+			attribute.String("transcript.role", pe.Role),
+			attribute.String("transcript.content_type", pe.ContentType),
+		}
+		if pe.ToolName != "" {
+			attrs = append(attrs, attribute.String("transcript.tool", pe.ToolName))
+		}
+		if !pe.Timestamp.IsZero() {
+			// How long Claude's write took to be picked up
+			attrs = append(attrs, attribute.Int64("transcript.lag_ms", readAt.Sub(pe.Timestamp).Milliseconds()))
+		}
+		ctxs[i], spans[i] = telemetry.StartAt(context.Background(), "transcript.entry", readAt, attrs...)
+	}
+	return ctxs, func() {
+		for _, span := range spans {
+			span.End()
+		}
 	}
-	return v.(time.Time), true
 }
 
-func (m *Monitor) enqueueEntry(userID int64, threadID int, chatID int64, windowID string, pe ParsedEntry) {
+// enqueueEntry formats a parsed entry and queues it for one user. transcript
+// names the file pe was read from (empty Path if none), for /why; traceCtx, if
+// non-nil, parents the delivery span.
+func (m *Monitor) enqueueEntry(userID int64, threadID int, chatID int64, windowID string, pe ParsedEntry, transcript queue.Source, traceCtx context.Context) {
 	var text string
+	var more []string // further messages, for thinking too long for one
 	var contentType string
+	var pin string
 
 	// Track turn start when we see a user entry
-	if pe.Role == "user" && pe.ContentType == "text" {
-		m.SetTurnStart(windowID)
+	if pe.Role == "user" && pe.ContentType == "text" && m.Events != nil {
+		m.Events.TurnStarted.Publish(events.TurnStarted{WindowID: windowID, At: time.Now()})
 	}
 
 	// Detect PLAN_JSON: marker in assistant text
@@ -261,25 +543,45 @@ func (m *Monitor) enqueueEntry(userID int64, threadID int, chatID int64, windowI
 		}
 	}
 
+	// Thinking goes unlabelled: it already arrives as a collapsed quote
+	labels := m.config.LabelTheme(m.state.GetLabelTheme(strconv.Itoa(threadID)))
 	switch pe.ContentType {
 	case "text":
-		if pe.Role == "user" {
-			text = "\U0001F464 " + render.FormatText(pe.Text)
-		} else {
-			text = render.FormatText(pe.Text)
-		}
+		text = labels.Label(pe.Role, render.FormatText(pe.Text))
 		contentType = "content"
 	case "tool_use":
 		text = render.FormatToolUse(pe.ToolName, "")
 		if pe.Text != "" {
 			text = pe.Text // use the pre-formatted summary
 		}
+		text = labels.Label("tool", text)
 		contentType = "tool_use"
 	case "tool_result":
-		text = render.FormatToolResult(pe.ToolName, pe.ToolInput, pe.Text, pe.IsError)
+		profile := m.profile
+		if n := m.state.GetTuning(strconv.Itoa(threadID)).PreviewLines; n > 0 {
+			profile.PreviewLines = n
+		}
+		if m.state.GetUserSettings(strconv.FormatInt(userID, 10)).Lite {
+			profile.Digest, profile.ReadPreviewLines = true, 0
+		}
+		text = labels.Label("tool", profile.FormatToolResult(pe.ToolName, pe.ToolInput, pe.Text, pe.IsError))
 		contentType = "tool_result"
+		if pe.ToolName == "ExitPlanMode" && !pe.IsError {
+			pin = "plan" // the plan was approved
+		}
 	case "thinking":
-		text = render.FormatThinking(pe.Text)
+		switch m.state.GetThinkingMode(strconv.Itoa(threadID)) {
+		case state.ThinkingHide:
+			return
+		case state.ThinkingFull:
+			chunks := render.FormatThinkingFull(pe.Text)
+			text, more = chunks[0], chunks[1:]
+		default:
+			text = render.FormatThinking(pe.Text)
+		}
+		contentType = "content"
+	case "compact":
+		text = labels.Label("system", compactDivider(pe.Text))
 		contentType = "content"
 	default:
 		return
@@ -289,6 +591,21 @@ func (m *Monitor) enqueueEntry(userID int64, threadID int, chatID int64, windowI
 		return
 	}
 
+	if contentType == "content" {
+		loc := m.state.GetUserSettings(strconv.FormatInt(userID, 10)).Location()
+		if ts := render.FormatTimestamp(pe.Timestamp, loc); ts != "" {
+			text = ts + " " + text
+		}
+	}
+
+	var sources []queue.Source
+	if transcript.Path != "" {
+		src := transcript
+		src.Start, src.End = pe.Start, pe.End
+		src.EntryType, src.Block = pe.EntryType, pe.ContentType
+		sources = []queue.Source{src}
+	}
+
 	m.queue.Enqueue(queue.MessageTask{
 		UserID:      userID,
 		ThreadID:    threadID,
@@ -297,7 +614,41 @@ func (m *Monitor) enqueueEntry(userID int64, threadID int, chatID int64, windowI
 		ContentType: contentType,
 		ToolUseID:   pe.ToolUseID,
 		WindowID:    windowID,
+		Pin:         pin,
+		Sources:     sources,
+		Trace:       traceCtx,
 	})
+	for _, t := range more {
+		m.queue.Enqueue(queue.MessageTask{
+			UserID:      userID,
+			ThreadID:    threadID,
+			ChatID:      chatID,
+			Parts:       []string{t},
+			ContentType: contentType,
+			WindowID:    windowID,
+			Sources:     sources,
+			Trace:       traceCtx,
+		})
+	}
+}
+
+// pendingFor returns the window's unmatched tool calls. Windows are kept
+// apart so one session's compaction doesn't drop another's pending tools.
+func (m *Monitor) pendingFor(windowID string) map[string]PendingTool {
+	pending, ok := m.pendingTools[windowID]
+	if !ok {
+		pending = make(map[string]PendingTool)
+		m.pendingTools[windowID] = pending
+	}
+	return pending
+}
+
+// compactDivider marks where Claude compacted the conversation.
+func compactDivider(trigger string) string {
+	if trigger == "auto" {
+		return "— context compacted (auto) —"
+	}
+	return "— context compacted —"
 }
 
 // findJSONLFile locates the JSONL transcript file for a session.
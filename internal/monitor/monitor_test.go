@@ -1,8 +1,11 @@
 package monitor
 
 import (
+	"context"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"testing"
 	"time"
 
@@ -121,6 +124,36 @@ func TestProcessSession_Truncation(t *testing.T) {
 	}
 }
 
+func TestProcessSession_SessionChanged(t *testing.T) {
+	dir := t.TempDir()
+	oldPath := filepath.Join(dir, "old.jsonl")
+	newPath := filepath.Join(dir, "new.jsonl")
+	content := `{"type":"assistant","message":{"content":"first"}}` + "\n" +
+		`{"type":"assistant","message":{"content":"second"}}` + "\n"
+	os.WriteFile(newPath, []byte(content), 0o644)
+
+	ms := state.NewMonitorState()
+	ms.UpdateOffset("s:@1", "old", oldPath, 40) // inside the new file, so not a truncation
+	m := New(&config.Config{TramuntanaDir: dir, MonitorPollInterval: 2.0}, state.NewState(), ms, nil)
+	m.pendingFor("@1")["tool-1"] = PendingTool{ToolName: "Bash"}
+	m.pendingFor("@2")["tool-2"] = PendingTool{ToolName: "Read"}
+
+	var got []ParsedEntry
+	m.ActivityHandler = func(_ string, parsed []ParsedEntry) { got = append(got, parsed...) }
+	m.processSession("s:@1", "new", "@1", newPath)
+
+	if len(got) != 2 {
+		t.Errorf("read %d entries from the new session, want 2", len(got))
+	}
+	tracked, _ := ms.GetTracked("s:@1")
+	if tracked.SessionID != "new" || tracked.FilePath != newPath || tracked.LastByteOffset != int64(len(content)) {
+		t.Errorf("tracked = %+v", tracked)
+	}
+	if len(m.pendingFor("@1")) != 0 || len(m.pendingFor("@2")) != 1 {
+		t.Errorf("pending tools: @1=%v @2=%v", m.pendingFor("@1"), m.pendingFor("@2"))
+	}
+}
+
 func TestDetectChanges_RemovesStale(t *testing.T) {
 	cfg := &config.Config{
 		TramuntanaDir:       t.TempDir(),
@@ -143,6 +176,28 @@ func TestDetectChanges_RemovesStale(t *testing.T) {
 	}
 }
 
+func TestDetectChanges_SessionClearedInTerminal(t *testing.T) {
+	ms := state.NewMonitorState()
+	ms.UpdateOffset("tramuntana:@1", "old", "/some/old.jsonl", 500)
+	m := New(&config.Config{TramuntanaDir: t.TempDir(), MonitorPollInterval: 2.0}, state.NewState(), ms, nil)
+	m.pendingFor("@1")["tool-1"] = PendingTool{ToolName: "Bash"}
+	m.lastSessionMap = map[string]state.SessionMapEntry{
+		"tramuntana:@1": {SessionID: "old"},
+	}
+
+	m.detectChanges(map[string]state.SessionMapEntry{
+		"tramuntana:@1": {SessionID: "new"},
+	})
+
+	tracked, ok := ms.GetTracked("tramuntana:@1")
+	if !ok || tracked.SessionID != "new" || tracked.FilePath != "" || tracked.LastByteOffset != 0 {
+		t.Errorf("tracked = %+v, %v; want the new session from offset 0", tracked, ok)
+	}
+	if len(m.pendingFor("@1")) != 0 {
+		t.Error("pending tools of the old session should be dropped")
+	}
+}
+
 func TestFindJSONLFile_SessionsIndex(t *testing.T) {
 	home := os.Getenv("HOME")
 	if home == "" {
@@ -198,3 +253,158 @@ func TestSearchJSONLFiles(t *testing.T) {
 		t.Error("should not find nonexistent session")
 	}
 }
+
+func TestBootstrapOffset(t *testing.T) {
+	tests := []struct {
+		policy string
+		tail   int64
+		want   int64
+	}{
+		{"eof", 0, 1000},
+		{"tail", 100, 900},
+		{"tail", 5000, 0},
+		{"full", 0, 0},
+		{"", 0, 0},
+	}
+	for _, tt := range tests {
+		if got := bootstrapOffset(tt.policy, tt.tail, 1000); got != tt.want {
+			t.Errorf("bootstrapOffset(%q, %d) = %d, want %d", tt.policy, tt.tail, got, tt.want)
+		}
+	}
+}
+
+func TestCapEntries(t *testing.T) {
+	parsed := make([]ParsedEntry, 5)
+	for i := range parsed {
+		parsed[i].Text = strconv.Itoa(i)
+	}
+
+	kept, skipped := capEntries(parsed, 2)
+	if skipped != 3 || len(kept) != 2 || kept[0].Text != "3" {
+		t.Errorf("capEntries(5, 2) = %d kept (first %q), %d skipped", len(kept), kept[0].Text, skipped)
+	}
+	if kept, skipped := capEntries(parsed, 0); skipped != 0 || len(kept) != 5 {
+		t.Error("limit 0 should keep everything")
+	}
+}
+
+func TestBatchThinking(t *testing.T) {
+	got := batchThinking([]ParsedEntry{
+		{ContentType: "thinking", Text: "first", Start: 0, End: 10},
+		{ContentType: "thinking", Text: "second", Start: 10, End: 25},
+		{ContentType: "text", Text: "answer"},
+		{ContentType: "thinking", Text: "third"},
+	})
+	if len(got) != 3 || got[0].Text != "first\n\nsecond" || got[0].End != 25 || got[2].Text != "third" {
+		t.Errorf("batchThinking = %+v", got)
+	}
+}
+
+func TestProcessSession_BootstrapEOF(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "old.jsonl")
+	content := `{"type":"assistant","message":{"content":"old"}}` + "\n"
+	os.WriteFile(path, []byte(content), 0o644)
+
+	cfg := &config.Config{TramuntanaDir: dir, MonitorPollInterval: 2.0, BootstrapPolicy: "eof"}
+	ms := state.NewMonitorState()
+	m := New(cfg, state.NewState(), ms, nil)
+	m.started = time.Now().Add(time.Minute) // file predates the monitor
+
+	m.processSession("old:@1", "old", "@1", path)
+
+	tracked, ok := ms.GetTracked("old:@1")
+	if !ok {
+		t.Fatal("bootstrapped session should be tracked at EOF")
+	}
+	if tracked.LastByteOffset != int64(len(content)) {
+		t.Errorf("offset = %d, want %d", tracked.LastByteOffset, len(content))
+	}
+}
+
+func TestProcessSession_PartialLineLeftForNextPoll(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "live.jsonl")
+	complete := `{"type":"assistant","message":{"content":"one"}}` + "\n"
+	os.WriteFile(path, []byte(complete+`{"type":"assistant","mess`), 0o644)
+
+	ms := state.NewMonitorState()
+	ms.UpdateOffset("live:@1", "live", path, 0)
+	m := New(&config.Config{TramuntanaDir: dir, MonitorPollInterval: 2.0}, state.NewState(), ms, nil)
+
+	m.processSession("live:@1", "live", "@1", path)
+	tracked, _ := ms.GetTracked("live:@1")
+	if tracked.LastByteOffset != int64(len(complete)) {
+		t.Errorf("offset = %d, want %d (before the unterminated line)", tracked.LastByteOffset, len(complete))
+	}
+}
+
+func TestProcessSession_SkipsEntriesReadAgain(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "s.jsonl")
+	content := `{"type":"assistant","uuid":"1","message":{"content":"one"}}` + "\n" +
+		`{"type":"assistant","uuid":"2","message":{"content":"two"}}` + "\n"
+	os.WriteFile(path, []byte(content), 0o644)
+
+	ms := state.NewMonitorState()
+	ms.UpdateOffset("s:@1", "s", path, 0)
+	m := New(&config.Config{TramuntanaDir: dir, MonitorPollInterval: 2.0, DedupWindow: time.Minute}, state.NewState(), ms, nil)
+	var delivered []string
+	m.ActivityHandler = func(windowID string, entries []ParsedEntry) {
+		for _, pe := range entries {
+			delivered = append(delivered, pe.Text)
+		}
+	}
+
+	m.processSession("s:@1", "s", "@1", path)
+	// An offset reset reads the file again; only the new line gets through
+	ms.UpdateOffset("s:@1", "s", path, 0)
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	f.WriteString(`{"type":"assistant","uuid":"3","message":{"content":"three"}}` + "\n")
+	f.Close()
+	m.processSession("s:@1", "s", "@1", path)
+
+	if want := []string{"one", "two", "three"}; !slices.Equal(delivered, want) {
+		t.Errorf("delivered %q, want %q", delivered, want)
+	}
+	if n := ms.Duplicates("s:@1"); n != 2 {
+		t.Errorf("Duplicates = %d, want 2", n)
+	}
+}
+
+func TestProcessSession_StoppedMonitorLeavesSessionAlone(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "s.jsonl")
+	os.WriteFile(path, []byte(`{"type":"assistant","message":{"content":"hello"}}`+"\n"), 0o644)
+	ms := state.NewMonitorState()
+	m := New(&config.Config{TramuntanaDir: dir, MonitorPollInterval: 2.0}, state.NewState(), ms, nil)
+
+	// A monitor the watchdog gave up on wakes after its context was cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	m.ctx = ctx
+	m.processSession("s:@1", "s", "@1", path)
+	if _, ok := ms.GetTracked("s:@1"); ok {
+		t.Error("stopped monitor advanced the session its replacement reads")
+	}
+}
+
+func TestTakeOver_KeepsNotificationOffset(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &config.Config{TramuntanaDir: dir, MonitorPollInterval: 2.0}
+	prev := New(cfg, state.NewState(), state.NewMonitorState(), nil)
+	prev.notifOffset.Store(120)
+
+	m := New(cfg, state.NewState(), state.NewMonitorState(), nil)
+	m.TakeOver(prev)
+	if got := m.notifOffset.Load(); got != 120 {
+		t.Errorf("notification offset = %d, want 120 carried over", got)
+	}
+
+	fresh := New(cfg, state.NewState(), state.NewMonitorState(), nil)
+	m = New(cfg, state.NewState(), state.NewMonitorState(), nil)
+	m.TakeOver(fresh)
+	if got := m.notifOffset.Load(); got != -1 {
+		t.Errorf("notification offset = %d, want -1 after an instance that never polled", got)
+	}
+}
@@ -0,0 +1,91 @@
+package monitor
+
+import (
+	"bufio"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/otaviocarvalho/tramuntana/internal/queue"
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+)
+
+// notificationPrefix marks Claude's own notifications, which are delivered
+// with sound even in topics whose output is silent.
+const notificationPrefix = "🔔 "
+
+// pollNotifications delivers the Notification hook events appended since the
+// last poll. Events already in the file when the monitor starts are skipped.
+func (m *Monitor) pollNotifications() {
+	path := filepath.Join(m.config.TramuntanaDir, state.NotificationsFile)
+	offset := m.notifOffset.Load()
+	info, err := os.Stat(path)
+	if err != nil {
+		if offset < 0 && os.IsNotExist(err) {
+			m.notifOffset.Store(0) // the first event will create it
+		}
+		return
+	}
+	if offset < 0 {
+		m.notifOffset.Store(info.Size())
+		return
+	}
+	if info.Size() < offset {
+		offset = 0 // file was replaced
+	}
+	if info.Size() == offset {
+		m.notifOffset.Store(offset)
+		return
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	if _, err := f.Seek(offset, 0); err != nil {
+		return
+	}
+	scanner := bufio.NewScanner(f)
+	scanner.Split(scanCompleteLines)
+	for scanner.Scan() {
+		if m.stopped() {
+			return // a replacement delivers the rest
+		}
+		line := scanner.Bytes()
+		offset += int64(len(line)) + 1
+		m.notifOffset.Store(offset)
+		var n state.HookNotification
+		if err := json.Unmarshal(line, &n); err != nil {
+			log.Printf("Notifications: skipping bad line: %v", err)
+			continue
+		}
+		m.deliverNotification(n)
+	}
+}
+
+// deliverNotification sends a hook notification to the topics bound to its window.
+func (m *Monitor) deliverNotification(n state.HookNotification) {
+	windowID := windowIDFromSessionKey(n.Key)
+	if windowID == "" || m.queue == nil {
+		return
+	}
+	for _, ut := range m.state.FindUsersForWindow(windowID) {
+		chatID, ok := m.state.GetGroupChatID(ut.UserID, ut.ThreadID)
+		if !ok {
+			continue
+		}
+		threadID, _ := strconv.Atoi(ut.ThreadID)
+		userID, _ := strconv.ParseInt(ut.UserID, 10, 64)
+		m.queue.Enqueue(queue.MessageTask{
+			UserID:      userID,
+			ThreadID:    threadID,
+			ChatID:      chatID,
+			Parts:       []string{m.config.LabelTheme(m.state.GetLabelTheme(ut.ThreadID)).Label("system", notificationPrefix+n.Message)},
+			ContentType: "notification",
+			WindowID:    windowID,
+		})
+	}
+}
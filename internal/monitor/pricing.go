@@ -0,0 +1,42 @@
+package monitor
+
+import "strings"
+
+// modelPrice is a model family's list price in USD per million tokens.
+type modelPrice struct {
+	family        string // matched as a substring of the model ID
+	input, output float64
+}
+
+// modelPrices are checked in order, so newer families come before the
+// older ones whose IDs they contain. Cache writes cost 1.25× input and
+// cache reads 0.1× input.
+var modelPrices = []modelPrice{
+	{"opus-4-5", 5, 25},
+	{"opus", 15, 75},
+	{"sonnet", 3, 15},
+	{"haiku-4", 1, 5},
+	{"3-5-haiku", 0.8, 4},
+	{"haiku", 0.25, 1.25},
+}
+
+// defaultPrice is used for models not in the table.
+var defaultPrice = modelPrice{"", 3, 15}
+
+// EstimateCost estimates the cost in USD of an assistant message's tokens
+// at list prices. Subscription plans aren't billed per token, so this is
+// what the usage would cost through the API.
+func EstimateCost(model string, u TokenUsage) float64 {
+	p := defaultPrice
+	for _, mp := range modelPrices {
+		if strings.Contains(model, mp.family) {
+			p = mp
+			break
+		}
+	}
+	tokens := float64(u.InputTokens)*p.input +
+		float64(u.OutputTokens)*p.output +
+		float64(u.CacheCreationInputTokens)*p.input*1.25 +
+		float64(u.CacheReadInputTokens)*p.input*0.1
+	return tokens / 1_000_000
+}
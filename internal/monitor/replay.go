@@ -0,0 +1,76 @@
+package monitor
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/otaviocarvalho/tramuntana/internal/queue"
+)
+
+// maxReplayGap caps the recorded pause between two replayed entries, so idle
+// stretches in a transcript don't stall a replay.
+const maxReplayGap = 10 * time.Second
+
+// ReplayTarget is the topic replayed entries are delivered to.
+type ReplayTarget struct {
+	UserID   int64
+	ChatID   int64
+	ThreadID int
+	WindowID string
+}
+
+// Replay feeds a recorded transcript through the same parse, render and enqueue
+// path as a live session. Entries are paced by their recorded timestamps divided
+// by speed; a speed of 0 replays without pauses. Returns the number of entries
+// delivered.
+func (m *Monitor) Replay(ctx context.Context, r io.Reader, target ReplayTarget, speed float64) (int, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+
+	var last time.Time
+	delivered := 0
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		entry, err := ParseLine(line)
+		if err != nil {
+			log.Printf("Replay: skipping line %d: %v", lineNo, err)
+			continue
+		}
+		if entry == nil {
+			continue
+		}
+
+		if speed > 0 && !entry.Timestamp.IsZero() {
+			if !last.IsZero() && entry.Timestamp.After(last) {
+				gap := entry.Timestamp.Sub(last)
+				if gap > maxReplayGap {
+					gap = maxReplayGap
+				}
+				select {
+				case <-time.After(time.Duration(float64(gap) / speed)):
+				case <-ctx.Done():
+					return delivered, ctx.Err()
+				}
+			}
+			last = entry.Timestamp
+		}
+
+		for _, pe := range ParseEntries([]*Entry{entry}, m.pendingFor(target.WindowID)) {
+			m.enqueueEntry(target.UserID, target.ThreadID, target.ChatID, target.WindowID, pe, queue.Source{}, nil)
+			delivered++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return delivered, fmt.Errorf("reading transcript: %w", err)
+	}
+	return delivered, nil
+}
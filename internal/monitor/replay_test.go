@@ -0,0 +1,87 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/otaviocarvalho/tramuntana/internal/config"
+	"github.com/otaviocarvalho/tramuntana/internal/dryrun"
+	"github.com/otaviocarvalho/tramuntana/internal/queue"
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+)
+
+// syncBuffer is a bytes.Buffer safe for the queue worker to write while the test reads.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestReplay_DeliversThroughQueue(t *testing.T) {
+	var out syncBuffer
+	api, err := dryrun.NewAPI(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := queue.New(api)
+	m := New(&config.Config{Verbosity: "normal"}, state.NewState(), state.NewMonitorState(), q)
+
+	transcript := strings.Join([]string{
+		`{"type":"assistant","message":{"content":[{"type":"text","text":"Hello there"}]}}`,
+		``,
+		`not json`,
+		`{"type":"assistant","message":{"content":[{"type":"tool_use","id":"tu_1","name":"Read","input":{"file_path":"/tmp/a.go"}}]}}`,
+		`{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"tu_1","content":"x","is_error":false}]}}`,
+	}, "\n")
+
+	n, err := m.Replay(context.Background(), strings.NewReader(transcript), ReplayTarget{UserID: 1, ChatID: -100, ThreadID: 5, WindowID: "replay"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("delivered %d entries, want 3", n)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !q.Idle() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	got := out.String()
+	for _, want := range []string{"Hello there", "── sendMessage #2", "── editMessageText", "message_id=2"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestReplay_StopsOnCancel(t *testing.T) {
+	api, err := dryrun.NewAPI(io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := New(&config.Config{Verbosity: "normal"}, state.NewState(), state.NewMonitorState(), queue.New(api))
+	transcript := `{"type":"assistant","timestamp":"2025-06-01T10:00:00Z","message":{"content":[{"type":"text","text":"a"}]}}
+{"type":"assistant","timestamp":"2025-06-01T10:00:05Z","message":{"content":[{"type":"text","text":"b"}]}}`
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := m.Replay(ctx, strings.NewReader(transcript), ReplayTarget{}, 1); err != context.Canceled {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,32 @@
+package monitor
+
+import (
+	"regexp"
+	"strings"
+)
+
+// reMinuanoDone matches a minuano-done invocation in a Bash command.
+var reMinuanoDone = regexp.MustCompile(`(?:^|[\s;&|(])minuano-done\s+['"]?([\w.-]+)`)
+
+// reTaskJSONID matches the task ID in minuano-claim's JSON output.
+var reTaskJSONID = regexp.MustCompile(`"id"\s*:\s*"([^"]+)"`)
+
+// DoneTaskID returns the task ID passed to minuano-done in a Bash command, if any.
+func DoneTaskID(command string) string {
+	if m := reMinuanoDone.FindStringSubmatch(command); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// ClaimedTaskID returns the task ID claimed by a minuano-claim (or minuano-pick)
+// Bash command, parsed from its output. Returns "" for other commands.
+func ClaimedTaskID(command, output string) string {
+	if !strings.Contains(command, "minuano-claim") && !strings.Contains(command, "minuano-pick") {
+		return ""
+	}
+	if m := reTaskJSONID.FindStringSubmatch(output); m != nil {
+		return m[1]
+	}
+	return ""
+}
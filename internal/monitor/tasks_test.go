@@ -0,0 +1,33 @@
+package monitor
+
+import "testing"
+
+func TestDoneTaskID(t *testing.T) {
+	tests := []struct {
+		cmd  string
+		want string
+	}{
+		{`minuano-done auth-42 "implemented auth"`, "auth-42"},
+		{`cd repo && minuano-done 'fix.login' "done"`, "fix.login"},
+		{`echo minuano-done`, ""},
+		{`git status`, ""},
+	}
+	for _, tt := range tests {
+		if got := DoneTaskID(tt.cmd); got != tt.want {
+			t.Errorf("DoneTaskID(%q) = %q, want %q", tt.cmd, got, tt.want)
+		}
+	}
+}
+
+func TestClaimedTaskID(t *testing.T) {
+	out := `{"id": "auth-7", "title": "Login flow", "context": []}`
+	if got := ClaimedTaskID("minuano-claim --project auth", out); got != "auth-7" {
+		t.Errorf("got %q, want auth-7", got)
+	}
+	if got := ClaimedTaskID("cat task.json", out); got != "" {
+		t.Errorf("non-claim command should not match, got %q", got)
+	}
+	if got := ClaimedTaskID("minuano-claim --project auth", "no ready tasks"); got != "" {
+		t.Errorf("empty claim should not match, got %q", got)
+	}
+}
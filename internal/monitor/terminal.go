@@ -132,6 +132,11 @@ var uiPatterns = []UIPattern{
 		TopMarkers: []string{"Restore the code"},
 		BotMarkers: []string{"Enter to continue"},
 	},
+	{
+		Name:       "RestoreConfirm",
+		TopMarkers: []string{"Restore code and conversation", "Restore conversation"},
+		BotMarkers: []string{"Enter to", "Esc to"},
+	},
 	{
 		Name:       "Settings",
 		TopMarkers: []string{"Settings:"},
@@ -219,20 +224,42 @@ func tryExtract(lines []string, pattern UIPattern) (UIContent, bool) {
 // Searches from the bottom for the "! <command>" echo line, then returns
 // that line and everything below it. Returns empty string if not found.
 func ExtractBashOutput(paneText, command string) string {
-	stripped := StripPaneChrome(paneText)
-	lines := strings.Split(stripped, "\n")
+	matchPrefix := commandPrefix(command)
+	return extractBelowEcho(paneText, func(line string) bool {
+		return strings.HasPrefix(line, "! "+matchPrefix) || strings.HasPrefix(line, "!"+matchPrefix)
+	})
+}
 
-	// Match on the first 10 chars of the command to handle terminal truncation
-	matchPrefix := command
-	if len(matchPrefix) > 10 {
-		matchPrefix = matchPrefix[:10]
+// ExtractBashToolOutput extracts the output so far of Claude's Bash tool
+// running command: the "⏺ Bash(<command>)" line and everything below it.
+// Returns empty string if not found.
+func ExtractBashToolOutput(paneText, command string) string {
+	matchPrefix := "Bash(" + commandPrefix(command)
+	return extractBelowEcho(paneText, func(line string) bool {
+		return strings.HasPrefix(strings.TrimLeft(line, "⏺● "), matchPrefix)
+	})
+}
+
+// commandPrefix is the part of a command matched against its echo: the first
+// 10 chars of its first line, to handle terminal truncation and wrapping.
+func commandPrefix(command string) string {
+	command, _, _ = strings.Cut(command, "\n")
+	if len(command) > 10 {
+		command = command[:10]
 	}
+	return command
+}
+
+// extractBelowEcho searches the pane from the bottom for the line isEcho
+// accepts (trimmed) and returns it and everything below it, without trailing
+// blank lines.
+func extractBelowEcho(paneText string, isEcho func(line string) bool) string {
+	stripped := StripPaneChrome(paneText)
+	lines := strings.Split(stripped, "\n")
 
-	// Search from bottom for the "! <command>" echo line
 	cmdIdx := -1
 	for i := len(lines) - 1; i >= 0; i-- {
-		trimmed := strings.TrimSpace(lines[i])
-		if strings.HasPrefix(trimmed, "! "+matchPrefix) || strings.HasPrefix(trimmed, "!"+matchPrefix) {
+		if isEcho(strings.TrimSpace(lines[i])) {
 			cmdIdx = i
 			break
 		}
@@ -293,6 +293,26 @@ func TestIsInteractiveUI_RestoreCheckpoint(t *testing.T) {
 	}
 }
 
+func TestExtractInteractiveContent_RestoreConfirm(t *testing.T) {
+	lines := []string{
+		" Confirm you want to restore to the point before you sent this message:",
+		"",
+		" ❯ 1. Restore code and conversation",
+		"   2. Restore conversation",
+		"   3. Restore code",
+		"   4. Never mind",
+		"",
+		" Enter to continue · Esc to exit",
+	}
+	ui, ok := ExtractInteractiveContent(strings.Join(lines, "\n"))
+	if !ok || ui.Name != "RestoreConfirm" {
+		t.Fatalf("got %+v, %v; want RestoreConfirm", ui, ok)
+	}
+	if !strings.HasPrefix(strings.TrimSpace(ui.Content), "❯ 1. Restore code and conversation") {
+		t.Errorf("content should start at the first option:\n%s", ui.Content)
+	}
+}
+
 func TestExtractBashOutput_Found(t *testing.T) {
 	lines := []string{
 		"Some previous output",
@@ -348,6 +368,29 @@ func TestExtractBashOutput_PrefixMatch(t *testing.T) {
 	}
 }
 
+func TestExtractBashToolOutput(t *testing.T) {
+	lines := []string{
+		"⏺ Bash(go build ./...)",
+		"  ⎿  (No content)",
+		"",
+		"⏺ Bash(go test ./... -count=1)",
+		"  ⎿  ok  \tgithub.com/x/api\t0.2s",
+		"     ok  \tgithub.com/x/db\t1.4s",
+		"",
+		strings.Repeat("─", 40),
+		"> prompt",
+	}
+	paneText := strings.Join(lines, "\n")
+
+	got := ExtractBashToolOutput(paneText, "go test ./... -count=1\necho done")
+	if !strings.HasPrefix(got, "⏺ Bash(go test") || !strings.HasSuffix(got, "github.com/x/db\t1.4s") {
+		t.Errorf("got %q, want the go test call and its output", got)
+	}
+	if got := ExtractBashToolOutput(paneText, "make lint"); got != "" {
+		t.Errorf("should not find make lint, got %q", got)
+	}
+}
+
 func TestExtractBashOutput_NoSpace(t *testing.T) {
 	lines := []string{
 		"!git status",
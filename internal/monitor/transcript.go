@@ -4,19 +4,38 @@ import (
 	"encoding/json"
 	"regexp"
 	"strings"
+	"time"
+
+	"github.com/otaviocarvalho/tramuntana/internal/render"
 )
 
 // Entry represents a parsed JSONL transcript entry.
 type Entry struct {
-	Type    string         // "user", "assistant", "summary"
-	Blocks  []ContentBlock // parsed content blocks
-	RawData json.RawMessage
+	Type      string         // "user", "assistant", "summary", "compact"
+	Blocks    []ContentBlock // parsed content blocks
+	Timestamp time.Time      // zero if the entry has no timestamp
+	Usage     TokenUsage     // assistant entries only
+	Model     string         // assistant entries only
+	MessageID string         // assistant entries only; shared by the lines of one API message
+	RawData   json.RawMessage
+
+	// Start and End are the byte range of the entry's line in its
+	// transcript, set by the reader (zero when unknown).
+	Start, End int64
+}
+
+// TokenUsage holds the token counts reported on an assistant message.
+type TokenUsage struct {
+	InputTokens              int64 `json:"input_tokens"`
+	OutputTokens             int64 `json:"output_tokens"`
+	CacheReadInputTokens     int64 `json:"cache_read_input_tokens"`
+	CacheCreationInputTokens int64 `json:"cache_creation_input_tokens"`
 }
 
 // ContentBlock represents a single content block within an entry.
 type ContentBlock struct {
-	Type      string // "text", "tool_use", "tool_result", "thinking"
-	Text      string // for text/thinking blocks
+	Type      string // "text", "tool_use", "tool_result", "thinking", "compact"
+	Text      string // for text/thinking blocks; how it was triggered for compact
 	ToolName  string // for tool_use
 	ToolInput string // for tool_use (summary of input)
 	ToolUseID string // for tool_use and tool_result
@@ -59,19 +78,30 @@ func ParseLine(line []byte) (*Entry, error) {
 		return parseMessageEntry(entryType, raw)
 	case "summary":
 		return parseSummaryEntry(raw)
+	case "system":
+		return parseSystemEntry(raw)
 	default:
 		return nil, nil
 	}
 }
 
 func parseMessageEntry(entryType string, raw map[string]json.RawMessage) (*Entry, error) {
+	// The summary Claude writes after compacting is not something the user said
+	var isCompactSummary bool
+	if json.Unmarshal(raw["isCompactSummary"], &isCompactSummary) == nil && isCompactSummary {
+		return nil, nil
+	}
+
 	msgBytes, ok := raw["message"]
 	if !ok {
 		return &Entry{Type: entryType}, nil
 	}
 
 	var msg struct {
+		ID      string          `json:"id"`
 		Content json.RawMessage `json:"content"`
+		Usage   TokenUsage      `json:"usage"`
+		Model   string          `json:"model"`
 	}
 	if err := json.Unmarshal(msgBytes, &msg); err != nil {
 		return &Entry{Type: entryType}, nil
@@ -81,9 +111,45 @@ func parseMessageEntry(entryType string, raw map[string]json.RawMessage) (*Entry
 
 	rawData, _ := json.Marshal(raw)
 	return &Entry{
-		Type:    entryType,
-		Blocks:  blocks,
-		RawData: rawData,
+		Type:      entryType,
+		Blocks:    blocks,
+		Timestamp: entryTimestamp(raw),
+		Usage:     msg.Usage,
+		Model:     msg.Model,
+		MessageID: msg.ID,
+		RawData:   rawData,
+	}, nil
+}
+
+// entryTimestamp returns an entry's timestamp, or zero if it has none.
+func entryTimestamp(raw map[string]json.RawMessage) time.Time {
+	var tsStr string
+	if json.Unmarshal(raw["timestamp"], &tsStr) != nil {
+		return time.Time{}
+	}
+	ts, _ := time.Parse(time.RFC3339Nano, tsStr)
+	return ts
+}
+
+// parseSystemEntry parses the system entries the monitor acts on: the
+// boundary Claude writes when it compacts the conversation. Others are ignored.
+func parseSystemEntry(raw map[string]json.RawMessage) (*Entry, error) {
+	var subtype string
+	json.Unmarshal(raw["subtype"], &subtype)
+	if subtype != "compact_boundary" {
+		return nil, nil
+	}
+	var meta struct {
+		Trigger string `json:"trigger"`
+	}
+	json.Unmarshal(raw["compactMetadata"], &meta)
+
+	rawData, _ := json.Marshal(raw)
+	return &Entry{
+		Type:      "compact",
+		Blocks:    []ContentBlock{{Type: "compact", Text: meta.Trigger}},
+		Timestamp: entryTimestamp(raw),
+		RawData:   rawData,
 	}, nil
 }
 
@@ -213,12 +279,12 @@ func extractToolInput(toolName string, inputJSON json.RawMessage) string {
 		return jsonString(input["file_path"])
 	case "Write":
 		return jsonString(input["file_path"])
-	case "Edit":
+	case "Edit", "MultiEdit":
 		return jsonString(input["file_path"])
 	case "Bash":
 		cmd := jsonString(input["command"])
 		if len(cmd) > 100 {
-			cmd = cmd[:100] + "..."
+			cmd = render.TruncateAtRune(cmd, 100) + "..."
 		}
 		return cmd
 	case "Grep":
@@ -236,7 +302,14 @@ func extractToolInput(toolName string, inputJSON json.RawMessage) string {
 	case "ExitPlanMode":
 		return "plan"
 	case "Skill":
-		return jsonString(input["skill"])
+		skill := jsonString(input["skill"])
+		if args := jsonString(input["args"]); args != "" {
+			if len(args) > 100 {
+				args = render.TruncateAtRune(args, 100) + "..."
+			}
+			skill += " " + args
+		}
+		return skill
 	default:
 		return ""
 	}
@@ -291,6 +364,7 @@ func ParseEntries(entries []*Entry, pending map[string]PendingTool) []ParsedEntr
 			continue
 		}
 
+		first := len(result)
 		for _, block := range entry.Blocks {
 			switch block.Type {
 			case "text":
@@ -300,6 +374,7 @@ func ParseEntries(entries []*Entry, pending map[string]PendingTool) []ParsedEntr
 						Role:        entry.Type,
 						ContentType: "text",
 						Text:        text,
+						Timestamp:   entry.Timestamp,
 					})
 				}
 
@@ -318,6 +393,8 @@ func ParseEntries(entries []*Entry, pending map[string]PendingTool) []ParsedEntr
 					Text:        summary,
 					ToolUseID:   block.ToolUseID,
 					ToolName:    block.ToolName,
+					ToolInput:   block.ToolInput,
+					Timestamp:   entry.Timestamp,
 				})
 				batchToolUseIdx[block.ToolUseID] = idx
 
@@ -326,6 +403,7 @@ func ParseEntries(entries []*Entry, pending map[string]PendingTool) []ParsedEntr
 					Role:        "user",
 					ContentType: "tool_result",
 					ToolUseID:   block.ToolUseID,
+					Timestamp:   entry.Timestamp,
 				}
 
 				if pt, ok := pending[block.ToolUseID]; ok {
@@ -355,10 +433,27 @@ func ParseEntries(entries []*Entry, pending map[string]PendingTool) []ParsedEntr
 						Role:        "assistant",
 						ContentType: "thinking",
 						Text:        block.Text,
+						Timestamp:   entry.Timestamp,
 					})
 				}
+
+			case "compact":
+				// Tools still open before the boundary will never get results
+				for id := range pending {
+					delete(pending, id)
+				}
+				result = append(result, ParsedEntry{
+					Role:        "system",
+					ContentType: "compact",
+					Text:        block.Text,
+					Timestamp:   entry.Timestamp,
+				})
 			}
 		}
+		for i := first; i < len(result); i++ {
+			result[i].EntryType = entry.Type
+			result[i].Start, result[i].End = entry.Start, entry.End
+		}
 	}
 
 	// Remove suppressed entries (same-batch tool_use that got paired)
@@ -373,13 +468,16 @@ func ParseEntries(entries []*Entry, pending map[string]PendingTool) []ParsedEntr
 
 // ParsedEntry is a display-ready parsed entry for the message queue.
 type ParsedEntry struct {
-	Role        string // "user", "assistant"
-	ContentType string // "text", "tool_use", "tool_result", "thinking"
+	Role        string // "user", "assistant", "system"
+	ContentType string // "text", "tool_use", "tool_result", "thinking", "compact"
 	Text        string
 	ToolUseID   string
 	ToolName    string
 	ToolInput   string // tool input summary (for tool_result combined display)
 	IsError     bool
+	Timestamp   time.Time
+	EntryType   string // type of the transcript entry it was parsed from
+	Start, End  int64  // byte range of that entry's line (see Entry)
 }
 
 // FormatToolUseSummary formats a tool_use into a summary line.
@@ -1,7 +1,11 @@
 package monitor
 
 import (
+	"encoding/json"
+	"strings"
 	"testing"
+	"time"
+	"unicode/utf8"
 )
 
 func TestParseLine_AssistantText(t *testing.T) {
@@ -24,6 +28,35 @@ func TestParseLine_AssistantText(t *testing.T) {
 	}
 }
 
+func TestParseLine_Timestamp(t *testing.T) {
+	line := []byte(`{"type":"assistant","timestamp":"2025-06-01T14:32:05.123Z","message":{"content":[{"type":"text","text":"hi"}]}}`)
+	entry, err := ParseLine(line)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := time.Date(2025, 6, 1, 14, 32, 5, 123000000, time.UTC)
+	if !entry.Timestamp.Equal(want) {
+		t.Errorf("timestamp = %v, want %v", entry.Timestamp, want)
+	}
+
+	parsed := ParseEntries([]*Entry{entry}, make(map[string]PendingTool))
+	if len(parsed) != 1 || !parsed[0].Timestamp.Equal(want) {
+		t.Errorf("parsed entry should carry timestamp: %+v", parsed)
+	}
+}
+
+func TestParseLine_Usage(t *testing.T) {
+	line := []byte(`{"type":"assistant","message":{"content":[],"usage":{"input_tokens":12,"output_tokens":340,"cache_read_input_tokens":5000,"cache_creation_input_tokens":200}}}`)
+	entry, err := ParseLine(line)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := TokenUsage{InputTokens: 12, OutputTokens: 340, CacheReadInputTokens: 5000, CacheCreationInputTokens: 200}
+	if entry.Usage != want {
+		t.Errorf("usage = %+v, want %+v", entry.Usage, want)
+	}
+}
+
 func TestParseLine_UserText(t *testing.T) {
 	line := []byte(`{"type":"user","message":{"content":"fix the bug"}}`)
 	entry, err := ParseLine(line)
@@ -138,6 +171,42 @@ func TestParseLine_UnknownType(t *testing.T) {
 	}
 }
 
+func TestParseLine_CompactBoundary(t *testing.T) {
+	line := []byte(`{"type":"system","subtype":"compact_boundary","content":"Conversation compacted","compactMetadata":{"trigger":"auto","preTokens":155000},"timestamp":"2025-06-01T10:00:00Z"}`)
+	entry, err := ParseLine(line)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry == nil || entry.Type != "compact" || len(entry.Blocks) != 1 || entry.Blocks[0].Text != "auto" {
+		t.Fatalf("entry = %+v", entry)
+	}
+	if entry.Timestamp.IsZero() {
+		t.Error("timestamp not parsed")
+	}
+}
+
+func TestParseLine_CompactSummarySkipped(t *testing.T) {
+	line := []byte(`{"type":"user","isCompactSummary":true,"message":{"role":"user","content":"This session is being continued from a previous conversation..."}}`)
+	entry, err := ParseLine(line)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry != nil {
+		t.Errorf("compact summary should not be delivered as user text: %+v", entry)
+	}
+}
+
+func TestParseEntries_CompactClearsPending(t *testing.T) {
+	pending := map[string]PendingTool{"tool-1": {ToolName: "Bash"}}
+	parsed := ParseEntries([]*Entry{{Type: "compact", Blocks: []ContentBlock{{Type: "compact", Text: "manual"}}}}, pending)
+	if len(pending) != 0 {
+		t.Errorf("pending = %v, want empty", pending)
+	}
+	if len(parsed) != 1 || parsed[0].ContentType != "compact" || parsed[0].Text != "manual" {
+		t.Errorf("parsed = %+v", parsed)
+	}
+}
+
 func TestParseLine_InvalidJSON(t *testing.T) {
 	_, err := ParseLine([]byte(`not json`))
 	if err == nil {
@@ -180,6 +249,7 @@ func TestExtractToolInput_AllTools(t *testing.T) {
 		{"AskUserQuestion", `{"questions":[]}`, "interactive"},
 		{"ExitPlanMode", `{}`, "plan"},
 		{"Skill", `{"skill":"commit"}`, "commit"},
+		{"Skill", `{"skill":"pdf","args":"report.pdf --pages 1-3"}`, "pdf report.pdf --pages 1-3"},
 		{"Unknown", `{"foo":"bar"}`, ""},
 	}
 
@@ -193,6 +263,15 @@ func TestExtractToolInput_AllTools(t *testing.T) {
 	}
 }
 
+func TestExtractToolInput_SkillArgsCutOnRune(t *testing.T) {
+	args := strings.Repeat("a", 99) + "é" + strings.Repeat("b", 10)
+	input, _ := json.Marshal(map[string]string{"skill": "pdf", "args": args})
+	got := extractToolInput("Skill", input)
+	if !utf8.ValidString(got) || got != "pdf "+strings.Repeat("a", 99)+"..." {
+		t.Errorf("extractToolInput = %q", got)
+	}
+}
+
 func TestExtractToolInput_BashTruncation(t *testing.T) {
 	longCmd := ""
 	for i := 0; i < 120; i++ {
@@ -0,0 +1,60 @@
+package monitor
+
+// messageUsage is the usage already recorded for an API message.
+type messageUsage struct {
+	id    string
+	usage TokenUsage
+}
+
+// recordUsage feeds token counts, touched files and finished tasks into the usage tracker.
+// Claude Code writes one line per content block of a message, each repeating
+// the message's usage, so only what a line adds to the previous line of the
+// same message is counted.
+func (m *Monitor) recordUsage(windowID string, entries []*Entry) {
+	if m.Usage == nil {
+		return
+	}
+	for _, e := range entries {
+		if e.Type == "assistant" {
+			u := m.newUsage(windowID, e)
+			m.Usage.RecordTokens(windowID, u.InputTokens, u.OutputTokens,
+				u.CacheReadInputTokens+u.CacheCreationInputTokens)
+			m.Usage.RecordCost(windowID, EstimateCost(e.Model, u))
+		}
+		for _, b := range e.Blocks {
+			if b.Type != "tool_use" {
+				continue
+			}
+			switch b.ToolName {
+			case "Edit", "MultiEdit", "Write":
+				m.Usage.RecordFile(windowID, b.ToolInput)
+			case "Bash":
+				if taskID := DoneTaskID(b.ToolInput); taskID != "" {
+					m.Usage.RecordTaskFinished(windowID, taskID)
+				}
+			}
+		}
+	}
+}
+
+// newUsage returns the part of an assistant entry's usage not yet recorded for
+// its message.
+func (m *Monitor) newUsage(windowID string, e *Entry) TokenUsage {
+	if e.MessageID == "" {
+		return e.Usage
+	}
+	if m.lastUsage == nil {
+		m.lastUsage = make(map[string]messageUsage)
+	}
+	prev := m.lastUsage[windowID]
+	m.lastUsage[windowID] = messageUsage{id: e.MessageID, usage: e.Usage}
+	if prev.id != e.MessageID {
+		return e.Usage
+	}
+	return TokenUsage{
+		InputTokens:              max(e.Usage.InputTokens-prev.usage.InputTokens, 0),
+		OutputTokens:             max(e.Usage.OutputTokens-prev.usage.OutputTokens, 0),
+		CacheReadInputTokens:     max(e.Usage.CacheReadInputTokens-prev.usage.CacheReadInputTokens, 0),
+		CacheCreationInputTokens: max(e.Usage.CacheCreationInputTokens-prev.usage.CacheCreationInputTokens, 0),
+	}
+}
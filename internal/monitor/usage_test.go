@@ -0,0 +1,79 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+)
+
+func TestRecordUsage(t *testing.T) {
+	m := &Monitor{Usage: state.NewUsage()}
+	m.recordUsage("@1", []*Entry{
+		{
+			Type:  "assistant",
+			Usage: TokenUsage{InputTokens: 10, OutputTokens: 20, CacheReadInputTokens: 30},
+			Blocks: []ContentBlock{
+				{Type: "tool_use", ToolName: "Edit", ToolInput: "/repo/a.go"},
+				{Type: "tool_use", ToolName: "Read", ToolInput: "/repo/b.go"},
+				{Type: "tool_use", ToolName: "Bash", ToolInput: `minuano-done t1 "ok"`},
+			},
+		},
+	})
+
+	wu, ok := m.Usage.GetWindowUsage(state.Day(time.Now()), "@1")
+	if !ok {
+		t.Fatal("no usage recorded")
+	}
+	if wu.InputTokens != 10 || wu.OutputTokens != 20 || wu.CacheTokens != 30 {
+		t.Errorf("tokens = %+v", wu)
+	}
+	if files := wu.Files(); len(files) != 1 || files[0] != "/repo/a.go" {
+		t.Errorf("files = %v, want [/repo/a.go]", files)
+	}
+	if len(wu.TasksFinished) != 1 || wu.TasksFinished[0] != "t1" {
+		t.Errorf("tasks finished = %v", wu.TasksFinished)
+	}
+}
+
+func TestRecordUsage_CountsEachMessageOnce(t *testing.T) {
+	m := &Monitor{Usage: state.NewUsage()}
+	usage := TokenUsage{InputTokens: 10, OutputTokens: 5, CacheReadInputTokens: 100}
+	final := usage
+	final.OutputTokens = 40
+	m.recordUsage("@1", []*Entry{
+		{Type: "assistant", MessageID: "msg_1", Usage: usage, Blocks: []ContentBlock{{Type: "thinking"}}},
+		{Type: "assistant", MessageID: "msg_1", Usage: usage, Blocks: []ContentBlock{{Type: "text"}}},
+	})
+	// The message's last line may arrive in a later poll, with final counts
+	m.recordUsage("@1", []*Entry{
+		{Type: "assistant", MessageID: "msg_1", Usage: final, Blocks: []ContentBlock{{Type: "tool_use", ToolName: "Read"}}},
+		{Type: "assistant", MessageID: "msg_2", Usage: usage},
+	})
+
+	wu, _ := m.Usage.GetWindowUsage(state.Day(time.Now()), "@1")
+	if wu.InputTokens != 20 || wu.OutputTokens != 45 || wu.CacheTokens != 200 {
+		t.Errorf("tokens = in %d out %d cache %d, want 20/45/200", wu.InputTokens, wu.OutputTokens, wu.CacheTokens)
+	}
+}
+
+func TestEstimateCost(t *testing.T) {
+	million := TokenUsage{InputTokens: 1_000_000, OutputTokens: 1_000_000}
+	tests := map[string]float64{
+		"claude-opus-4-1-20250805":   90,
+		"claude-opus-4-5-20251101":   30,
+		"claude-sonnet-4-5-20250929": 18,
+		"claude-3-5-haiku-20241022":  4.8,
+		"something-new":              18,
+	}
+	for model, want := range tests {
+		if got := EstimateCost(model, million); got < want-0.001 || got > want+0.001 {
+			t.Errorf("EstimateCost(%q) = %.3f, want %.3f", model, got, want)
+		}
+	}
+
+	cache := TokenUsage{CacheCreationInputTokens: 1_000_000, CacheReadInputTokens: 1_000_000}
+	if got := EstimateCost("claude-sonnet-4-5", cache); got < 4.049 || got > 4.051 {
+		t.Errorf("cache cost = %.3f, want 4.05", got)
+	}
+}
@@ -0,0 +1,175 @@
+// Package notify sends critical alerts through channels other than Telegram
+// (ntfy, Pushover, email), so failures are heard about even when Telegram
+// itself is what is failing.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sendTimeout bounds one delivery to one sink.
+const sendTimeout = 15 * time.Second
+
+// DefaultCooldown is how long an alert of one kind is held back after it
+// was sent, so a failure that repeats every poll alerts once.
+const DefaultCooldown = 30 * time.Minute
+
+// Sink delivers an alert to one channel.
+type Sink interface {
+	Name() string
+	Send(ctx context.Context, title, message string) error
+}
+
+// Alerter fans critical alerts out to every sink, at most once per kind per
+// cooldown. A nil Alerter drops alerts.
+type Alerter struct {
+	sinks    []Sink
+	cooldown time.Duration
+
+	mu   sync.Mutex
+	last map[string]time.Time // kind → when it was last sent
+}
+
+// New creates an Alerter, or returns nil when there are no sinks.
+func New(cooldown time.Duration, sinks ...Sink) *Alerter {
+	if len(sinks) == 0 {
+		return nil
+	}
+	return &Alerter{sinks: sinks, cooldown: cooldown, last: make(map[string]time.Time)}
+}
+
+// Alert sends title and message to every sink in the background, unless an
+// alert of the same kind went out within the cooldown. It reports whether
+// the alert was sent.
+func (a *Alerter) Alert(kind, title, message string) bool {
+	if a == nil {
+		return false
+	}
+	a.mu.Lock()
+	if at, ok := a.last[kind]; ok && time.Since(at) < a.cooldown {
+		a.mu.Unlock()
+		return false
+	}
+	a.last[kind] = time.Now()
+	a.mu.Unlock()
+
+	log.Printf("Alert (%s): %s: %s", kind, title, message)
+	for _, s := range a.sinks {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+			defer cancel()
+			if err := s.Send(ctx, title, message); err != nil {
+				log.Printf("Error sending alert via %s: %v", s.Name(), err)
+			}
+		}()
+	}
+	return true
+}
+
+// Ntfy posts to an ntfy topic URL, e.g. https://ntfy.sh/my-alerts.
+type Ntfy struct {
+	URL   string
+	Token string // access token for protected topics; empty for none
+}
+
+func (n Ntfy) Name() string { return "ntfy" }
+
+func (n Ntfy) Send(ctx context.Context, title, message string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, strings.NewReader(message))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Title", title)
+	req.Header.Set("Priority", "urgent")
+	req.Header.Set("Tags", "rotating_light")
+	if n.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+n.Token)
+	}
+	return do(req)
+}
+
+// pushoverURL is Pushover's message API; a variable so tests can replace it.
+var pushoverURL = "https://api.pushover.net/1/messages.json"
+
+// Pushover sends through the Pushover API.
+type Pushover struct {
+	Token string // application token
+	User  string // user or group key
+}
+
+func (p Pushover) Name() string { return "pushover" }
+
+func (p Pushover) Send(ctx context.Context, title, message string) error {
+	form := url.Values{
+		"token":    {p.Token},
+		"user":     {p.User},
+		"title":    {title},
+		"message":  {message},
+		"priority": {"1"},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, pushoverURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return do(req)
+}
+
+// do sends req and turns a non-2xx response into an error.
+func do(req *http.Request) error {
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	return nil
+}
+
+// SMTP sends email through a mail server.
+type SMTP struct {
+	Addr     string // host:port
+	Username string // empty sends without authentication
+	Password string
+	From     string
+	To       []string
+}
+
+func (s SMTP) Name() string { return "email" }
+
+func (s SMTP) Send(ctx context.Context, title, message string) error {
+	host, _, err := net.SplitHostPort(s.Addr)
+	if err != nil {
+		return err
+	}
+	var auth smtp.Auth
+	if s.Username != "" {
+		auth = smtp.PlainAuth("", s.Username, s.Password, host)
+	}
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nDate: %s\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n%s\r\n",
+		s.From, strings.Join(s.To, ", "), title, time.Now().Format(time.RFC1123Z), strings.ReplaceAll(message, "\n", "\r\n"))
+
+	// smtp.SendMail takes no context; give up waiting for it at the deadline
+	done := make(chan error, 1)
+	go func() { done <- smtp.SendMail(s.Addr, auth, s.From, s.To, []byte(msg)) }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
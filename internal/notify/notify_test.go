@@ -0,0 +1,86 @@
+package notify
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+type chanSink chan string
+
+func (c chanSink) Name() string { return "chan" }
+
+func (c chanSink) Send(ctx context.Context, title, message string) error {
+	c <- title + ": " + message
+	return nil
+}
+
+func TestAlerter_Cooldown(t *testing.T) {
+	sink := make(chanSink, 4)
+	a := New(time.Hour, sink)
+	if !a.Alert("disk", "Disk full", "state.json") {
+		t.Fatal("first alert should be sent")
+	}
+	if got := <-sink; got != "Disk full: state.json" {
+		t.Errorf("sink got %q", got)
+	}
+	if a.Alert("disk", "Disk full", "again") {
+		t.Error("same kind within the cooldown should be held back")
+	}
+	if !a.Alert("flood:1", "Flood", "chat 1") {
+		t.Error("another kind should be sent")
+	}
+
+	var none *Alerter
+	if New(time.Hour) != nil || none.Alert("disk", "x", "y") {
+		t.Error("an Alerter without sinks should drop alerts")
+	}
+}
+
+func TestNtfy_Send(t *testing.T) {
+	var got *http.Request
+	var body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		got, body = r, string(data)
+	}))
+	defer srv.Close()
+
+	err := Ntfy{URL: srv.URL + "/alerts", Token: "tk"}.Send(context.Background(), "Disk full", "state.json not saved")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.URL.Path != "/alerts" || got.Header.Get("Title") != "Disk full" || got.Header.Get("Priority") != "urgent" ||
+		got.Header.Get("Authorization") != "Bearer tk" || body != "state.json not saved" {
+		t.Errorf("request = %s %v, body %q", got.URL, got.Header, body)
+	}
+}
+
+func TestPushover_Send(t *testing.T) {
+	var form url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		form = r.PostForm
+		if form.Get("token") == "bad" {
+			http.Error(w, `{"status":0,"errors":["application token is invalid"]}`, http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+	old := pushoverURL
+	pushoverURL = srv.URL
+	defer func() { pushoverURL = old }()
+
+	if err := (Pushover{Token: "app", User: "me"}).Send(context.Background(), "Flood", "chat 1"); err != nil {
+		t.Fatal(err)
+	}
+	if form.Get("user") != "me" || form.Get("title") != "Flood" || form.Get("message") != "chat 1" || form.Get("priority") != "1" {
+		t.Errorf("form = %v", form)
+	}
+	if err := (Pushover{Token: "bad", User: "me"}).Send(context.Background(), "Flood", "chat 1"); err == nil {
+		t.Error("expected an error for a rejected request")
+	}
+}
@@ -13,13 +13,16 @@ var retryAfterRe = regexp.MustCompile(`retry after (\d+)`)
 
 const sendInterval = 100 * time.Millisecond // minimum gap between API calls per chat
 
-// FloodControl handles Telegram 429 rate limiting.
+// FloodControl handles Telegram 429 rate limiting. Bans and throttling are
+// tracked per chat, matching how Telegram enforces them for a bot.
 type FloodControl struct {
 	mu         sync.RWMutex
 	floodUntil map[int64]time.Time // chat_id → flood ban expiry
 
 	sendMu   sync.Mutex
 	lastSend map[int64]time.Time // chat_id → last API call time
+
+	onBan func(chatID int64, wait time.Duration) // called when a ban is set or extended
 }
 
 // NewFloodControl creates a new FloodControl instance.
@@ -69,18 +72,25 @@ func (fc *FloodControl) HandleError(chatID int64, err error) {
 	fc.mu.Lock()
 	newUntil := time.Now().Add(wait)
 	// Only extend, never shorten an existing ban
+	extended := false
 	if existing, ok := fc.floodUntil[chatID]; !ok || newUntil.After(existing) {
 		fc.floodUntil[chatID] = newUntil
+		extended = true
 		fmt.Printf("Flood control: chat %d rate-limited for %v\n", chatID, wait)
 	}
+	onBan := fc.onBan
 	fc.mu.Unlock()
+
+	if extended && onBan != nil {
+		onBan(chatID, wait)
+	}
 }
 
-// IsFlooded returns true if a user is currently flood-banned.
-func (fc *FloodControl) IsFlooded(userID int64) bool {
+// IsFlooded returns true if a chat is currently flood-banned.
+func (fc *FloodControl) IsFlooded(chatID int64) bool {
 	fc.mu.RLock()
 	defer fc.mu.RUnlock()
-	until, ok := fc.floodUntil[userID]
+	until, ok := fc.floodUntil[chatID]
 	if !ok {
 		return false
 	}
@@ -91,9 +101,9 @@ func (fc *FloodControl) IsFlooded(userID int64) bool {
 }
 
 // WaitIfFlooded blocks until the flood ban expires.
-func (fc *FloodControl) WaitIfFlooded(userID int64) {
+func (fc *FloodControl) WaitIfFlooded(chatID int64) {
 	fc.mu.RLock()
-	until, ok := fc.floodUntil[userID]
+	until, ok := fc.floodUntil[chatID]
 	fc.mu.RUnlock()
 
 	if !ok {
@@ -102,18 +112,18 @@ func (fc *FloodControl) WaitIfFlooded(userID int64) {
 
 	remaining := time.Until(until)
 	if remaining <= 0 {
-		fc.clearFlood(userID)
+		fc.clearFlood(chatID)
 		return
 	}
 	time.Sleep(remaining)
-	fc.clearFlood(userID)
+	fc.clearFlood(chatID)
 }
 
-func (fc *FloodControl) clearFlood(userID int64) {
+func (fc *FloodControl) clearFlood(chatID int64) {
 	fc.mu.Lock()
 	defer fc.mu.Unlock()
-	until, ok := fc.floodUntil[userID]
+	until, ok := fc.floodUntil[chatID]
 	if ok && time.Now().After(until) {
-		delete(fc.floodUntil, userID)
+		delete(fc.floodUntil, chatID)
 	}
 }
@@ -1,31 +1,51 @@
 package queue
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/otaviocarvalho/tramuntana/internal/render"
+	"github.com/otaviocarvalho/tramuntana/internal/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
-	maxMergeLen = 3800
-	chanBufSize = 100
+	// DefaultMergeLen is how long consecutive content messages are merged
+	// up to, unless a topic's Limits say otherwise.
+	DefaultMergeLen = 3800
+	chanBufSize     = 100
 )
 
+// Limits are a topic's merge and split thresholds. Zero fields use
+// DefaultMergeLen and DefaultSplitLen.
+type Limits struct {
+	MergeLen int
+	SplitLen int
+}
+
 // MessageTask represents a message to send to Telegram.
 type MessageTask struct {
 	UserID      int64
 	ThreadID    int
 	ChatID      int64
 	Parts       []string
-	ContentType string // "content", "tool_use", "tool_result", "status_update", "status_clear"
-	ToolUseID   string // for tool_result editing
+	ContentType string // "content", "notification", "feed", "tool_use", "tool_result", "tool_progress", "status_update", "status_clear"
+	ToolUseID   string // for tool_result and tool_progress editing
 	WindowID    string
+	Pin         string   // pin kind for the delivered message (see SetPinHandler); empty for none
+	Sources     []Source // transcript entries the text was rendered from; empty for the bot's own messages
+
+	// Trace parents the task's delivery span (see telemetry); nil if untraced.
+	Trace      context.Context
+	enqueuedAt time.Time
 }
 
 // userThread is a composite key for per-(user, thread) tracking.
@@ -34,6 +54,13 @@ type userThread struct {
 	ThreadID int
 }
 
+// toolKey identifies a tool_use message delivered to one user+thread. The same
+// tool_use_id is fanned out to every user bound to the window.
+type toolKey struct {
+	ToolUseID string
+	userThread
+}
+
 // StatusInfo tracks the current status message for a user+thread.
 type StatusInfo struct {
 	MessageID int
@@ -41,14 +68,27 @@ type StatusInfo struct {
 	Text      string
 }
 
-// Queue manages per-user message sending goroutines.
+// Queue manages per-window message sending goroutines. A window's tasks for one
+// chat go through one FIFO, so every user bound to it there sees the same
+// ordering; each task is then delivered to its own (user, chat, thread) target.
+// Telegram rate-limits per (bot, chat), so each chat gets its own worker and a
+// flood ban in one chat never holds up deliveries to another.
 type Queue struct {
 	mu         sync.RWMutex
 	api        *tgbotapi.BotAPI
-	queues     map[int64]chan MessageTask // user_id → channel
-	toolMsgIDs map[string]toolMsgInfo    // tool_use_id → message info
-	statusMsgs map[userThread]StatusInfo // (user_id, thread_id) → status message
+	queues     map[string]chan MessageTask  // queue key (window ID, chat ID) → channel
+	pending    map[int64]int                // user_id → tasks not yet picked up by a worker
+	active     int                          // workers currently processing a task
+	toolMsgIDs map[toolKey]toolMsgInfo      // (tool_use_id, user, thread) → message info
+	statusMsgs map[userThread]StatusInfo    // (user_id, thread_id) → status message
+	sent       map[chatThread][]SentMessage // topic → messages delivered there, oldest first
 	flood      *FloodControl
+	onPin      func(task MessageTask, messageID int)
+	keyboardFn func(task MessageTask, text string) *tgbotapi.InlineKeyboardMarkup
+	silentFn   func(task MessageTask) bool
+	pagerFn    func(task MessageTask, pages []string) *tgbotapi.InlineKeyboardMarkup
+	limitsFn   func(chatID int64, threadID int) Limits
+	goneFn     func(chatID int64, threadID int)
 }
 
 type toolMsgInfo struct {
@@ -61,50 +101,168 @@ type toolMsgInfo struct {
 func New(api *tgbotapi.BotAPI) *Queue {
 	return &Queue{
 		api:        api,
-		queues:     make(map[int64]chan MessageTask),
-		toolMsgIDs: make(map[string]toolMsgInfo),
+		queues:     make(map[string]chan MessageTask),
+		pending:    make(map[int64]int),
+		toolMsgIDs: make(map[toolKey]toolMsgInfo),
 		statusMsgs: make(map[userThread]StatusInfo),
+		sent:       make(map[chatThread][]SentMessage),
 		flood:      NewFloodControl(),
 	}
 }
 
-// Enqueue adds a message task to the user's queue.
+// SetPinHandler sets the function called with the message a Pin task was
+// delivered as. Call before tasks are enqueued.
+func (q *Queue) SetPinHandler(fn func(task MessageTask, messageID int)) {
+	q.onPin = fn
+}
+
+// SetKeyboardFunc sets the function that may attach an inline keyboard to
+// delivered content text; it returns nil for none. Call before tasks are
+// enqueued.
+func (q *Queue) SetKeyboardFunc(fn func(task MessageTask, text string) *tgbotapi.InlineKeyboardMarkup) {
+	q.keyboardFn = fn
+}
+
+// SetSilentFunc sets the function deciding whether a task's messages are sent
+// without sound. Call before tasks are enqueued.
+func (q *Queue) SetSilentFunc(fn func(task MessageTask) bool) {
+	q.silentFn = fn
+}
+
+// SetPagerFunc sets the function that may deliver long content as one message
+// with page navigation: it returns the first page's keyboard, or nil to send
+// every part as its own message. Call before tasks are enqueued.
+func (q *Queue) SetPagerFunc(fn func(task MessageTask, pages []string) *tgbotapi.InlineKeyboardMarkup) {
+	q.pagerFn = fn
+}
+
+// SetLimitsFunc sets the function returning a topic's merge and split
+// thresholds. Call before tasks are enqueued.
+func (q *Queue) SetLimitsFunc(fn func(chatID int64, threadID int) Limits) {
+	q.limitsFn = fn
+}
+
+// limits returns a topic's merge and split thresholds, defaults filled in.
+func (q *Queue) limits(chatID int64, threadID int) Limits {
+	var l Limits
+	if q.limitsFn != nil {
+		l = q.limitsFn(chatID, threadID)
+	}
+	if l.MergeLen <= 0 {
+		l.MergeLen = DefaultMergeLen
+	}
+	if l.SplitLen <= 0 || l.SplitLen > DefaultSplitLen {
+		l.SplitLen = DefaultSplitLen
+	}
+	return l
+}
+
+// SetThreadGoneHandler sets the function called when a send fails because
+// the topic no longer exists. Telegram sends no update when a forum topic is
+// deleted, so this is how its deletion is noticed. Call before tasks are
+// enqueued.
+func (q *Queue) SetThreadGoneHandler(fn func(chatID int64, threadID int)) {
+	q.goneFn = fn
+}
+
+// SetFloodBanHandler sets the function called when Telegram rate-limits a
+// chat, with how long the ban lasts. Call before tasks are enqueued.
+func (q *Queue) SetFloodBanHandler(fn func(chatID int64, wait time.Duration)) {
+	q.flood.mu.Lock()
+	q.flood.onBan = fn
+	q.flood.mu.Unlock()
+}
+
+// silent reports whether task's messages are sent with disable_notification.
+func (q *Queue) silent(task MessageTask) bool {
+	if q.silentFn == nil {
+		return false
+	}
+	return q.silentFn(task)
+}
+
+// queueKey returns the FIFO a task belongs to: its window, or the user for
+// tasks not tied to a window, within the destination chat.
+func queueKey(task MessageTask) string {
+	if task.WindowID != "" {
+		return fmt.Sprintf("%s/%d", task.WindowID, task.ChatID)
+	}
+	return fmt.Sprintf("user:%d/%d", task.UserID, task.ChatID)
+}
+
+// Enqueue adds a message task to its window's queue.
 func (q *Queue) Enqueue(task MessageTask) {
+	task.enqueuedAt = time.Now()
+
 	// Don't enqueue ephemeral messages during flood — they'd be dropped by the worker
 	// anyway. This prevents the channel from filling with doomed messages, which would
 	// block content messages from being enqueued.
-	if q.flood.IsFlooded(task.ChatID) {
+	flooded := q.flood.IsFlooded(task.ChatID)
+	if flooded {
 		switch task.ContentType {
-		case "status_update", "status_clear", "tool_use", "tool_result":
+		case "status_update", "status_clear", "tool_use", "tool_result", "tool_progress":
+			traceDrop(task, "flood")
 			return
 		}
 	}
 
+	key := queueKey(task)
 	q.mu.Lock()
-	ch, ok := q.queues[task.UserID]
+	ch, ok := q.queues[key]
 	if !ok {
 		ch = make(chan MessageTask, chanBufSize)
-		q.queues[task.UserID] = ch
-		go q.worker(task.UserID, ch)
+		q.queues[key] = ch
+		go q.worker(ch)
 	}
+	q.pending[task.UserID]++
 	q.mu.Unlock()
 
+	// A flooded chat's worker is asleep until the ban ends; waiting for room
+	// would stall the caller's fan-out to every other chat.
+	if flooded {
+		select {
+		case ch <- task:
+		default:
+			q.done(task)
+			traceDrop(task, "queue full")
+			log.Printf("Queue full for flooded %s, dropping message (type=%s)", key, task.ContentType)
+		}
+		return
+	}
 	select {
 	case ch <- task:
 	case <-time.After(5 * time.Second):
-		log.Printf("Queue full for user %d after 5s, dropping message (type=%s)", task.UserID, task.ContentType)
+		q.done(task)
+		traceDrop(task, "queue full")
+		log.Printf("Queue full for %s after 5s, dropping message (type=%s)", key, task.ContentType)
 	}
 }
 
 // QueueLen returns the number of pending messages for a user.
 func (q *Queue) QueueLen(userID int64) int {
 	q.mu.RLock()
-	ch, ok := q.queues[userID]
-	q.mu.RUnlock()
-	if !ok {
-		return 0
+	defer q.mu.RUnlock()
+	return q.pending[userID]
+}
+
+// Idle reports whether every enqueued task has been picked up and processed.
+func (q *Queue) Idle() bool {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	return len(q.pending) == 0 && q.active == 0
+}
+
+// done records that a task left the queue.
+func (q *Queue) done(task MessageTask) {
+	q.mu.Lock()
+	q.doneLocked(task)
+	q.mu.Unlock()
+}
+
+func (q *Queue) doneLocked(task MessageTask) {
+	if q.pending[task.UserID]--; q.pending[task.UserID] <= 0 {
+		delete(q.pending, task.UserID)
 	}
-	return len(ch)
 }
 
 // GetStatusMessage returns the current status message for a user+thread.
@@ -115,70 +273,160 @@ func (q *Queue) GetStatusMessage(userID int64, threadID int) (StatusInfo, bool)
 	return info, ok
 }
 
-// worker processes messages for a single user.
-func (q *Queue) worker(userID int64, ch chan MessageTask) {
-	for task := range ch {
-		q.processTask(task, ch)
+// taskStream reads a worker's channel with one task of lookahead, so merging and
+// flood draining can stop at a task and leave it first in line.
+type taskStream struct {
+	ch     chan MessageTask
+	held   []MessageTask
+	onRecv func(MessageTask) // called once per task read from ch
+}
+
+// next blocks until a task is available. Returns false when the channel is closed.
+func (s *taskStream) next() (MessageTask, bool) {
+	if len(s.held) > 0 {
+		t := s.held[0]
+		s.held = s.held[1:]
+		return t, true
+	}
+	t, ok := <-s.ch
+	if ok && s.onRecv != nil {
+		s.onRecv(t)
+	}
+	return t, ok
+}
+
+// tryNext returns the next task without blocking.
+func (s *taskStream) tryNext() (MessageTask, bool) {
+	if len(s.held) > 0 {
+		return s.next()
+	}
+	select {
+	case t, ok := <-s.ch:
+		if ok && s.onRecv != nil {
+			s.onRecv(t)
+		}
+		return t, ok
+	default:
+		return MessageTask{}, false
+	}
+}
+
+// unread puts a task back at the front of the stream.
+func (s *taskStream) unread(t MessageTask) {
+	s.held = append([]MessageTask{t}, s.held...)
+}
+
+// worker processes messages for a single window and chat, in order.
+func (q *Queue) worker(ch chan MessageTask) {
+	busy := false // counted in q.active; stays set while tasks are held
+	s := &taskStream{ch: ch}
+	s.onRecv = func(task MessageTask) {
+		q.mu.Lock()
+		q.doneLocked(task)
+		if !busy {
+			busy = true
+			q.active++
+		}
+		q.mu.Unlock()
+	}
+	for {
+		task, ok := s.next()
+		if !ok {
+			return
+		}
+		q.processTask(task, s)
+		if len(s.held) == 0 {
+			q.mu.Lock()
+			busy = false
+			q.active--
+			q.mu.Unlock()
+		}
 	}
 }
 
-func (q *Queue) processTask(task MessageTask, ch chan MessageTask) {
+func (q *Queue) processTask(task MessageTask, s *taskStream) {
+	// Bot API calls made while delivering nest under the task's span. Tasks
+	// merged into this one are delivered under it too.
+	ctx := context.Background()
+	span := trace.SpanFromContext(ctx)
+	if task.Trace != nil {
+		ctx, span = telemetry.StartAt(task.Trace, "queue.deliver", task.enqueuedAt,
+			attribute.String("queue.content_type", task.ContentType),
+			attribute.Int64("telegram.chat_id", task.ChatID),
+			attribute.Int("telegram.thread_id", task.ThreadID),
+		)
+		defer span.End()
+	}
+
 	// Check flood control using chatID (flood bans are keyed by chatID, not userID)
 	if q.flood.IsFlooded(task.ChatID) {
 		switch task.ContentType {
-		case "status_update", "status_clear", "tool_use":
+		case "status_update", "status_clear", "tool_use", "tool_progress":
 			// Drop low-value messages during floods — they'll be stale by the time flood clears
+			span.SetAttributes(attribute.String("queue.dropped", "flood"))
 			return
 		case "tool_result":
 			// Drop tool_result too — the tool_use message it would edit was likely dropped
+			span.SetAttributes(attribute.String("queue.dropped", "flood"))
 			return
 		default:
 			// Content messages: wait for flood to clear
 			q.flood.WaitIfFlooded(task.ChatID)
 			// After waking, drain stale messages from the buffer
-			q.drainStale(task.ChatID, ch)
+			q.drainStale(task.ChatID, s)
 		}
 	}
 
 	switch task.ContentType {
 	case "content":
-		q.processContent(task, ch)
+		q.processContent(ctx, task, s)
+	case "notification":
+		q.sendMessage(ctx, originOf(task), task.ChatID, task.ThreadID, strings.Join(task.Parts, "\n"), q.silent(task))
+	case "feed":
+		text := mergeFeed(task, s, q.limits(task.ChatID, task.ThreadID).MergeLen)
+		q.sendMessage(ctx, originOf(task), task.ChatID, task.ThreadID, text, true)
 	case "tool_use":
-		q.processToolUse(task)
+		q.processToolUse(ctx, task)
 	case "tool_result":
-		q.processToolResult(task)
+		q.processToolResult(ctx, task)
+	case "tool_progress":
+		q.processToolProgress(ctx, task)
 	case "status_update":
-		q.processStatusUpdate(task)
+		q.processStatusUpdate(ctx, task)
 	case "status_clear":
-		q.processStatusClear(task)
+		q.processStatusClear(ctx, task)
 	default:
-		q.processContent(task, ch)
+		q.processContent(ctx, task, s)
 	}
 }
 
-func (q *Queue) processContent(task MessageTask, ch chan MessageTask) {
-	text := strings.Join(task.Parts, "\n")
-
-	// Try to merge consecutive content tasks, collecting any non-content tasks
-	var deferred []MessageTask
-	text, deferred = q.mergeFromChannel2(text, task.WindowID, ch)
-
-	// Send the merged content
-	q.sendMessage(task.ChatID, task.ThreadID, text)
-
-	// Process any deferred non-content tasks that were in the channel
-	for _, dt := range deferred {
-		q.processTask(dt, ch)
+func (q *Queue) processContent(ctx context.Context, task MessageTask, s *taskStream) {
+	// Merge consecutive content tasks per target, then deliver each target's text
+	for _, m := range mergeContent(task, s, q.limits) {
+		if q.pagerFn != nil {
+			splitLen := q.limits(m.task.ChatID, m.task.ThreadID).SplitLen
+			if pages := render.SplitMessage(m.text, splitLen); len(pages) > 1 {
+				if nav := q.pagerFn(m.task, pages); nav != nil {
+					q.sendSingleMessage(ctx, originOf(m.task), m.task.ChatID, m.task.ThreadID, pages[0], nav, q.silent(m.task))
+					continue
+				}
+			}
+		}
+		var keyboard *tgbotapi.InlineKeyboardMarkup
+		if q.keyboardFn != nil {
+			keyboard = q.keyboardFn(m.task, m.text)
+		}
+		q.sendMessageWithKeyboard(ctx, originOf(m.task), m.task.ChatID, m.task.ThreadID, m.text, keyboard, q.silent(m.task))
 	}
 }
 
-func (q *Queue) processToolUse(task MessageTask) {
+func (q *Queue) processToolUse(ctx context.Context, task MessageTask) {
 	text := strings.Join(task.Parts, "\n")
-	msgID := q.sendMessage(task.ChatID, task.ThreadID, text)
+	msgID := q.sendMessage(ctx, originOf(task), task.ChatID, task.ThreadID, text, q.silent(task))
 
 	if msgID != 0 && task.ToolUseID != "" {
 		q.mu.Lock()
-		q.toolMsgIDs[task.ToolUseID] = toolMsgInfo{
+		q.toolMsgIDs[toolKeyFor(task)] = toolMsgInfo{
 			ChatID:    task.ChatID,
 			MessageID: msgID,
 			ThreadID:  task.ThreadID,
@@ -187,29 +435,56 @@ func (q *Queue) processToolUse(task MessageTask) {
 	}
 }
 
-func (q *Queue) processToolResult(task MessageTask) {
+func (q *Queue) processToolResult(ctx context.Context, task MessageTask) {
 	text := strings.Join(task.Parts, "\n")
 
 	// Try to edit the tool_use message in-place
+	key := toolKeyFor(task)
 	q.mu.Lock()
-	info, ok := q.toolMsgIDs[task.ToolUseID]
+	info, ok := q.toolMsgIDs[key]
 	if ok {
-		delete(q.toolMsgIDs, task.ToolUseID)
+		delete(q.toolMsgIDs, key)
 	}
 	q.mu.Unlock()
 
+	var msgID int
 	if ok && info.MessageID != 0 {
-		if err := q.editMessage(info.ChatID, info.MessageID, text); err != nil {
+		msgID = info.MessageID
+		// A result too long for one message edits in its first part
+		parts := render.FitMessage(text, maxRenderedLen)
+		if err := q.editMessage(ctx, info.ChatID, info.MessageID, parts[0]); err != nil {
 			// Fallback: send new message
-			q.sendMessage(task.ChatID, task.ThreadID, text)
+			msgID = q.sendMessage(ctx, originOf(task), task.ChatID, task.ThreadID, text, q.silent(task))
+		} else {
+			q.addSources(info.ChatID, info.ThreadID, info.MessageID, task.Sources)
+			for _, part := range parts[1:] {
+				q.sendMessage(ctx, originOf(task), task.ChatID, task.ThreadID, part, q.silent(task))
+			}
 		}
-		return
+	} else {
+		msgID = q.sendMessage(ctx, originOf(task), task.ChatID, task.ThreadID, text, q.silent(task))
 	}
 
-	q.sendMessage(task.ChatID, task.ThreadID, text)
+	if task.Pin != "" && msgID != 0 && q.onPin != nil {
+		q.onPin(task, msgID)
+	}
 }
 
-func (q *Queue) processStatusUpdate(task MessageTask) {
+// processToolProgress edits a tool_use message that is still waiting for its
+// result. Once the result has replaced it, the progress is dropped.
+func (q *Queue) processToolProgress(ctx context.Context, task MessageTask) {
+	q.mu.RLock()
+	info, ok := q.toolMsgIDs[toolKeyFor(task)]
+	q.mu.RUnlock()
+	if !ok || info.MessageID == 0 {
+		return
+	}
+	if err := q.editMessage(ctx, info.ChatID, info.MessageID, strings.Join(task.Parts, "\n")); err != nil {
+		log.Printf("Error editing tool progress: %v", err)
+	}
+}
+
+func (q *Queue) processStatusUpdate(ctx context.Context, task MessageTask) {
 	text := strings.Join(task.Parts, "\n")
 	ut := userThread{task.UserID, task.ThreadID}
 
@@ -224,12 +499,12 @@ func (q *Queue) processStatusUpdate(task MessageTask) {
 
 	// Send typing indicator when Claude is actively working (after dedup to avoid wasted API calls)
 	if strings.Contains(strings.ToLower(text), "esc to interrupt") {
-		q.sendTyping(task.ChatID)
+		q.sendTyping(ctx, task.ChatID)
 	}
 
 	if hasExisting && existing.MessageID != 0 {
 		// Edit existing status message
-		if err := q.editMessage(task.ChatID, existing.MessageID, text); err == nil {
+		if err := q.editMessage(ctx, task.ChatID, existing.MessageID, text); err == nil {
 			q.mu.Lock()
 			q.statusMsgs[ut] = StatusInfo{
 				MessageID: existing.MessageID,
@@ -242,7 +517,7 @@ func (q *Queue) processStatusUpdate(task MessageTask) {
 	}
 
 	// Send new status message
-	msgID := q.sendMessage(task.ChatID, task.ThreadID, text)
+	msgID := q.sendMessage(ctx, originOf(task), task.ChatID, task.ThreadID, text, q.silent(task))
 	q.mu.Lock()
 	q.statusMsgs[ut] = StatusInfo{
 		MessageID: msgID,
@@ -252,7 +527,7 @@ func (q *Queue) processStatusUpdate(task MessageTask) {
 	q.mu.Unlock()
 }
 
-func (q *Queue) processStatusClear(task MessageTask) {
+func (q *Queue) processStatusClear(ctx context.Context, task MessageTask) {
 	ut := userThread{task.UserID, task.ThreadID}
 
 	q.mu.Lock()
@@ -263,69 +538,112 @@ func (q *Queue) processStatusClear(task MessageTask) {
 	q.mu.Unlock()
 
 	if ok && status.MessageID != 0 {
-		q.deleteMessage(task.ChatID, status.MessageID)
+		q.deleteMessage(ctx, task.ChatID, status.MessageID)
 	}
 }
 
-// mergeFromChannel2 merges consecutive content tasks from the channel.
-// Returns the merged text and any non-content tasks that were found in the channel
-// (these must be processed by the caller to preserve ordering).
-func (q *Queue) mergeFromChannel2(text, windowID string, ch chan MessageTask) (string, []MessageTask) {
-	var deferred []MessageTask
+func toolKeyFor(task MessageTask) toolKey {
+	return toolKey{task.ToolUseID, userThread{task.UserID, task.ThreadID}}
+}
+
+// mergedContent is the merged text for one delivery target.
+type mergedContent struct {
+	task MessageTask // first task for the target; supplies chat and thread, and gathers the merged tasks' sources
+	text string
+}
+
+// mergeContent merges the run of content tasks for first's window that are
+// already waiting in the stream, keeping one text per delivery target in the
+// order targets first appear. It stops at the first non-content task, a task
+// for another window, or one that would push its target past its merge
+// length (from limits); that task is left at the front of the stream.
+func mergeContent(first MessageTask, s *taskStream, limits func(chatID int64, threadID int) Limits) []mergedContent {
+	merged := []mergedContent{{task: first, text: strings.Join(first.Parts, "\n")}}
+	index := map[userThread]int{{first.UserID, first.ThreadID}: 0}
+
 	for {
-		select {
-		case next, ok := <-ch:
-			if !ok {
-				return text, deferred
-			}
-			if next.ContentType != "content" || next.WindowID != windowID {
-				deferred = append(deferred, next)
-				return text, deferred
-			}
-			nextText := strings.Join(next.Parts, "\n")
-			if len(text)+len(nextText)+1 > maxMergeLen {
-				deferred = append(deferred, next)
-				return text, deferred
-			}
-			text = text + "\n" + nextText
-		default:
-			return text, deferred
+		next, ok := s.tryNext()
+		if !ok {
+			return merged
 		}
+		if next.ContentType != "content" || next.WindowID != first.WindowID {
+			s.unread(next)
+			return merged
+		}
+		nextText := strings.Join(next.Parts, "\n")
+		ut := userThread{next.UserID, next.ThreadID}
+		i, seen := index[ut]
+		if !seen {
+			index[ut] = len(merged)
+			merged = append(merged, mergedContent{task: next, text: nextText})
+			continue
+		}
+		if len(merged[i].text)+len(nextText)+1 > limits(next.ChatID, next.ThreadID).MergeLen {
+			s.unread(next)
+			return merged
+		}
+		merged[i].text += "\n" + nextText
+		merged[i].task.Sources = slices.Concat(merged[i].task.Sources, next.Sources)
+	}
+}
+
+// mergeFeed joins the feed lines waiting behind first for the same topic, up
+// to mergeLen characters, so a burst of events is one silent message.
+func mergeFeed(first MessageTask, s *taskStream, mergeLen int) string {
+	text := strings.Join(first.Parts, "\n")
+	for {
+		next, ok := s.tryNext()
+		if !ok {
+			return text
+		}
+		nextText := strings.Join(next.Parts, "\n")
+		if next.ContentType != "feed" || next.ChatID != first.ChatID || next.ThreadID != first.ThreadID ||
+			len(text)+len(nextText)+1 > mergeLen {
+			s.unread(next)
+			return text
+		}
+		text += "\n" + nextText
 	}
 }
 
-// drainStale drains stale low-priority messages from the channel after a flood wait.
+// drainStale drains stale low-priority messages from the stream after a flood wait.
 // This prevents a burst of stale tool_use/tool_result/status messages from being sent
 // immediately after the flood clears, which would trigger another flood ban.
-func (q *Queue) drainStale(chatID int64, ch chan MessageTask) {
+func (q *Queue) drainStale(chatID int64, s *taskStream) {
 	drained := 0
+	defer func() {
+		if drained > 0 {
+			log.Printf("Drained %d stale messages after flood for chat %d", drained, chatID)
+		}
+	}()
 	for {
-		select {
-		case msg, ok := <-ch:
-			if !ok {
-				return
-			}
-			switch msg.ContentType {
-			case "status_update", "status_clear", "tool_use", "tool_result":
-				drained++
-				continue
-			default:
-				// Put back content messages by processing them
-				if drained > 0 {
-					log.Printf("Drained %d stale messages after flood for chat %d", drained, chatID)
-				}
-				q.processTask(msg, ch)
-				return
-			}
+		msg, ok := s.tryNext()
+		if !ok {
+			return
+		}
+		switch msg.ContentType {
+		case "status_update", "status_clear", "tool_use", "tool_result", "tool_progress":
+			drained++
 		default:
-			if drained > 0 {
-				log.Printf("Drained %d stale messages after flood for chat %d", drained, chatID)
-			}
+			// Leave content first in line for the worker
+			s.unread(msg)
 			return
 		}
 	}
 }
 
+// traceDrop records a traced task that was dropped before reaching a worker.
+func traceDrop(task MessageTask, reason string) {
+	if task.Trace == nil {
+		return
+	}
+	_, span := telemetry.Start(task.Trace, "queue.drop",
+		attribute.String("queue.content_type", task.ContentType),
+		attribute.String("queue.dropped", reason),
+	)
+	span.End()
+}
+
 // IsFlooded returns true if a chat is currently flood-banned.
 func (q *Queue) IsFlooded(chatID int64) bool {
 	return q.flood.IsFlooded(chatID)
@@ -336,11 +654,30 @@ func (q *Queue) HandleFloodError(chatID int64, err error) {
 	q.flood.HandleError(chatID, err)
 }
 
+// DefaultSplitLen is the longest part a message is split into, leaving room
+// for MarkdownV2 escaping under Telegram's 4096-character limit. Topics may
+// split shorter (see Limits), not longer.
+const DefaultSplitLen = 3000
+
+// maxRenderedLen bounds a rendered message with expandable quotes, leaving
+// room for the "[i/N]" suffix under Telegram's limit.
+const maxRenderedLen = render.MaxMessageLen - 16
+
 // sendMessage sends a message with MarkdownV2, falling back to plain text.
 // Long messages are split at newline boundaries before conversion.
-// Returns the message ID of the last sent message.
-func (q *Queue) sendMessage(chatID int64, threadID int, text string) int {
-	parts := render.SplitMessage(text, 3000)
+// Returns the message ID of the last sent message. Silent messages are sent
+// with disable_notification.
+func (q *Queue) sendMessage(ctx context.Context, o origin, chatID int64, threadID int, text string, silent bool) int {
+	return q.sendMessageWithKeyboard(ctx, o, chatID, threadID, text, nil, silent)
+}
+
+// sendMessageWithKeyboard is sendMessage with an optional inline keyboard,
+// attached to the last part.
+func (q *Queue) sendMessageWithKeyboard(ctx context.Context, o origin, chatID int64, threadID int, text string, keyboard *tgbotapi.InlineKeyboardMarkup, silent bool) int {
+	var parts []string
+	for _, part := range render.SplitMessage(text, q.limits(chatID, threadID).SplitLen) {
+		parts = append(parts, render.FitMessage(part, maxRenderedLen)...)
+	}
 
 	var lastMsgID int
 	for i, part := range parts {
@@ -350,7 +687,11 @@ func (q *Queue) sendMessage(chatID int64, threadID int, text string) int {
 			sendText = fmt.Sprintf("%s\n[%d/%d]", part, i+1, len(parts))
 		}
 
-		msgID := q.sendSingleMessage(chatID, threadID, sendText)
+		var markup *tgbotapi.InlineKeyboardMarkup
+		if i == len(parts)-1 {
+			markup = keyboard
+		}
+		msgID := q.sendSingleMessage(ctx, o, chatID, threadID, sendText, markup, silent)
 		if msgID != 0 {
 			lastMsgID = msgID
 		}
@@ -360,17 +701,22 @@ func (q *Queue) sendMessage(chatID int64, threadID int, text string) int {
 
 // sendSingleMessage sends a single message with MarkdownV2, falling back to plain text.
 // Retries once with flood-aware backoff. Does not retry permanent errors.
-func (q *Queue) sendSingleMessage(chatID int64, threadID int, text string) int {
+// Sent messages are recorded with their origin, for /cleanup and /why.
+func (q *Queue) sendSingleMessage(ctx context.Context, o origin, chatID int64, threadID int, text string, keyboard *tgbotapi.InlineKeyboardMarkup, silent bool) int {
 	// Try MarkdownV2 first
 	mdv2 := render.ToMarkdownV2(text)
-	msgID, err := q.sendRaw(chatID, threadID, mdv2, "MarkdownV2")
+	msgID, err := q.sendRaw(ctx, chatID, threadID, mdv2, "MarkdownV2", keyboard, silent)
 	if err == nil {
+		q.recordSent(chatID, threadID, msgID, o)
 		return msgID
 	}
 
 	// Don't retry permanent errors (bad thread, bad chat, etc.)
 	if isPermanentError(err) {
 		log.Printf("Permanent send error (chat=%d, thread=%d): %v", chatID, threadID, err)
+		if threadID != 0 && IsThreadNotFound(err) && q.goneFn != nil {
+			q.goneFn(chatID, threadID)
+		}
 		return 0
 	}
 
@@ -378,11 +724,12 @@ func (q *Queue) sendSingleMessage(chatID int64, threadID int, text string) int {
 	q.flood.WaitIfFlooded(chatID)
 
 	plain := render.ToPlainText(text)
-	msgID, err = q.sendRaw(chatID, threadID, plain, "")
+	msgID, err = q.sendRaw(ctx, chatID, threadID, plain, "", keyboard, silent)
 	if err != nil {
 		log.Printf("Plain text fallback failed (chat=%d, thread=%d): %v", chatID, threadID, err)
 		return 0
 	}
+	q.recordSent(chatID, threadID, msgID, o)
 	return msgID
 }
 
@@ -398,8 +745,14 @@ func isPermanentError(err error) bool {
 		strings.Contains(msg, "not enough rights")
 }
 
-// sendRaw sends a message via Telegram API.
-func (q *Queue) sendRaw(chatID int64, threadID int, text, parseMode string) (int, error) {
+// IsThreadNotFound reports whether err is Telegram refusing a message because
+// its forum topic no longer exists.
+func IsThreadNotFound(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "message thread not found")
+}
+
+// sendRaw sends a message via Telegram API, with keyboard if not nil.
+func (q *Queue) sendRaw(ctx context.Context, chatID int64, threadID int, text, parseMode string, keyboard *tgbotapi.InlineKeyboardMarkup, silent bool) (int, error) {
 	q.flood.Throttle(chatID)
 	params := tgbotapi.Params{}
 	params.AddNonZero64("chat_id", chatID)
@@ -411,8 +764,14 @@ func (q *Queue) sendRaw(chatID int64, threadID int, text, parseMode string) (int
 		params.AddNonZero("message_thread_id", threadID)
 	}
 	params.AddNonEmpty("link_preview_options", `{"is_disabled":true}`)
+	params.AddBool("disable_notification", silent)
+	if keyboard != nil {
+		if err := params.AddInterface("reply_markup", keyboard); err != nil {
+			return 0, err
+		}
+	}
 
-	resp, err := q.api.MakeRequest("sendMessage", params)
+	resp, err := q.request(ctx, "sendMessage", params)
 	if err != nil {
 		q.flood.HandleError(chatID, err)
 		return 0, err
@@ -424,9 +783,9 @@ func (q *Queue) sendRaw(chatID int64, threadID int, text, parseMode string) (int
 }
 
 // editMessage edits a message, trying MarkdownV2 then plain text.
-func (q *Queue) editMessage(chatID int64, messageID int, text string) error {
+func (q *Queue) editMessage(ctx context.Context, chatID int64, messageID int, text string) error {
 	mdv2 := render.ToMarkdownV2(text)
-	err := q.editRaw(chatID, messageID, mdv2, "MarkdownV2")
+	err := q.editRaw(ctx, chatID, messageID, mdv2, "MarkdownV2")
 	if err == nil {
 		return nil
 	}
@@ -439,10 +798,10 @@ func (q *Queue) editMessage(chatID int64, messageID int, text string) error {
 	q.flood.WaitIfFlooded(chatID)
 
 	plain := render.ToPlainText(text)
-	return q.editRaw(chatID, messageID, plain, "")
+	return q.editRaw(ctx, chatID, messageID, plain, "")
 }
 
-func (q *Queue) editRaw(chatID int64, messageID int, text, parseMode string) error {
+func (q *Queue) editRaw(ctx context.Context, chatID int64, messageID int, text, parseMode string) error {
 	q.flood.Throttle(chatID)
 	params := tgbotapi.Params{}
 	params.AddNonZero64("chat_id", chatID)
@@ -452,24 +811,37 @@ func (q *Queue) editRaw(chatID int64, messageID int, text, parseMode string) err
 		params.AddNonEmpty("parse_mode", parseMode)
 	}
 	params.AddNonEmpty("link_preview_options", `{"is_disabled":true}`)
-	_, err := q.api.MakeRequest("editMessageText", params)
+	_, err := q.request(ctx, "editMessageText", params)
 	if err != nil {
 		q.flood.HandleError(chatID, err)
 	}
 	return err
 }
 
-func (q *Queue) deleteMessage(chatID int64, messageID int) {
+func (q *Queue) deleteMessage(ctx context.Context, chatID int64, messageID int) {
+	q.forgetSent(chatID, messageID)
 	params := tgbotapi.Params{}
 	params.AddNonZero64("chat_id", chatID)
 	params.AddNonZero("message_id", messageID)
-	q.api.MakeRequest("deleteMessage", params)
+	q.request(ctx, "deleteMessage", params)
+}
+
+// request makes a Bot API request under ctx, so its span nests under the
+// delivery being traced.
+func (q *Queue) request(ctx context.Context, method string, params tgbotapi.Params) (*tgbotapi.APIResponse, error) {
+	api := q.api
+	if trace.SpanContextFromContext(ctx).IsValid() {
+		traced := *q.api
+		traced.Client = telemetry.WithContext(ctx, q.api.Client)
+		api = &traced
+	}
+	return api.MakeRequest(method, params)
 }
 
 // sendTyping sends a "typing" chat action to indicate the bot is working.
-func (q *Queue) sendTyping(chatID int64) {
+func (q *Queue) sendTyping(ctx context.Context, chatID int64) {
 	params := tgbotapi.Params{}
 	params.AddNonZero64("chat_id", chatID)
 	params.AddNonEmpty("action", "typing")
-	q.api.MakeRequest("sendChatAction", params)
+	q.request(ctx, "sendChatAction", params)
 }
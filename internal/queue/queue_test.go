@@ -1,8 +1,18 @@
 package queue
 
 import (
+	"context"
+	"fmt"
+	"strings"
 	"testing"
 	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/render"
+	"github.com/otaviocarvalho/tramuntana/internal/telemetry"
+	"github.com/otaviocarvalho/tramuntana/internal/testharness"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 func TestFloodControl_NotFlooded(t *testing.T) {
@@ -38,6 +48,18 @@ func TestFloodControl_HandleError429(t *testing.T) {
 	}
 }
 
+func TestFloodControl_BanHandler(t *testing.T) {
+	fc := NewFloodControl()
+	var bans []time.Duration
+	fc.onBan = func(chatID int64, wait time.Duration) { bans = append(bans, wait) }
+
+	fc.HandleError(100, &mockError{"Too Many Requests: retry after 600"})
+	fc.HandleError(100, &mockError{"Too Many Requests: retry after 5"}) // within the ban: not extended
+	if len(bans) != 1 || bans[0] != 601*time.Second {
+		t.Errorf("bans reported = %v, want one of 601s", bans)
+	}
+}
+
 func TestFloodControl_HandleNon429(t *testing.T) {
 	fc := NewFloodControl()
 	fc.HandleError(100, &mockError{"Bad Request"})
@@ -53,7 +75,7 @@ func TestFloodControl_HandleNil(t *testing.T) {
 }
 
 func TestMessageTaskTypes(t *testing.T) {
-	types := []string{"content", "tool_use", "tool_result", "status_update", "status_clear"}
+	types := []string{"content", "tool_use", "tool_result", "tool_progress", "status_update", "status_clear"}
 	for _, ct := range types {
 		task := MessageTask{ContentType: ct}
 		if task.ContentType != ct {
@@ -76,9 +98,31 @@ func TestUserThread(t *testing.T) {
 }
 
 func TestMergeLimit(t *testing.T) {
-	// Verify maxMergeLen constant
-	if maxMergeLen != 3800 {
-		t.Errorf("maxMergeLen = %d, want 3800", maxMergeLen)
+	// Verify DefaultMergeLen constant
+	if DefaultMergeLen != 3800 {
+		t.Errorf("DefaultMergeLen = %d, want 3800", DefaultMergeLen)
+	}
+}
+
+func TestLimits_PerTopic(t *testing.T) {
+	q := New(nil)
+	if l := q.limits(-100, 7); l != (Limits{DefaultMergeLen, DefaultSplitLen}) {
+		t.Errorf("limits without a func = %+v", l)
+	}
+	q.SetLimitsFunc(func(chatID int64, threadID int) Limits {
+		if threadID == 7 {
+			return Limits{MergeLen: 500, SplitLen: 9000}
+		}
+		return Limits{}
+	})
+	if l := q.limits(-100, 7); l != (Limits{500, DefaultSplitLen}) {
+		t.Errorf("tuned limits = %+v, want merge 500 and the split capped", l)
+	}
+
+	s := streamOf(content(7, "@1", strings.Repeat("x", 500)), content(8, "@1", strings.Repeat("y", 500)))
+	merged := mergeContent(content(7, "@1", "a"), s, q.limits)
+	if len(merged) != 1 {
+		t.Errorf("merged past the topic's merge length: %d targets", len(merged))
 	}
 }
 
@@ -96,6 +140,104 @@ func TestStatusInfo(t *testing.T) {
 	}
 }
 
+func TestQueueKey(t *testing.T) {
+	if k := queueKey(MessageTask{UserID: 1, ChatID: -100, WindowID: "@3"}); k != "@3/-100" {
+		t.Errorf("queueKey with window = %q, want @3/-100", k)
+	}
+	if k := queueKey(MessageTask{UserID: 1, ChatID: -100}); k != "user:1/-100" {
+		t.Errorf("queueKey without window = %q, want user:1/-100", k)
+	}
+}
+
+func streamOf(tasks ...MessageTask) *taskStream {
+	ch := make(chan MessageTask, len(tasks))
+	for _, t := range tasks {
+		ch <- t
+	}
+	return &taskStream{ch: ch}
+}
+
+func content(user int64, window, text string) MessageTask {
+	return MessageTask{UserID: user, ThreadID: int(user), WindowID: window, ContentType: "content", Parts: []string{text}}
+}
+
+func TestMergeContent_FansOutPerTarget(t *testing.T) {
+	s := streamOf(
+		content(2, "@1", "a"),
+		content(1, "@1", "b"),
+		content(2, "@1", "b"),
+		MessageTask{UserID: 1, WindowID: "@1", ContentType: "tool_use"},
+		content(1, "@1", "c"),
+	)
+	merged := mergeContent(content(1, "@1", "a"), s, New(nil).limits)
+
+	if len(merged) != 2 {
+		t.Fatalf("got %d targets, want 2", len(merged))
+	}
+	if merged[0].task.UserID != 1 || merged[0].text != "a\nb" {
+		t.Errorf("user 1: %+v", merged[0])
+	}
+	if merged[1].task.UserID != 2 || merged[1].text != "a\nb" {
+		t.Errorf("user 2: %+v", merged[1])
+	}
+
+	// The tool_use stays first in line, ahead of later content
+	next, _ := s.next()
+	if next.ContentType != "tool_use" {
+		t.Errorf("next = %q, want tool_use", next.ContentType)
+	}
+	next, _ = s.next()
+	if next.Parts[0] != "c" {
+		t.Errorf("next = %q, want c", next.Parts[0])
+	}
+}
+
+func TestMergeContent_StopsAtOtherWindowAndLimit(t *testing.T) {
+	s := streamOf(content(1, "@2", "x"))
+	if merged := mergeContent(content(1, "@1", "a"), s, New(nil).limits); len(merged) != 1 || merged[0].text != "a" {
+		t.Errorf("merged across windows: %+v", merged)
+	}
+	if next, _ := s.next(); next.WindowID != "@2" {
+		t.Error("other window's task should be left in the stream")
+	}
+
+	long := strings.Repeat("x", DefaultMergeLen)
+	s = streamOf(content(1, "@1", long))
+	if merged := mergeContent(content(1, "@1", "a"), s, New(nil).limits); merged[0].text != "a" {
+		t.Error("should not merge past DefaultMergeLen")
+	}
+	if _, ok := s.tryNext(); !ok {
+		t.Error("oversized task should be left in the stream")
+	}
+}
+
+func TestMergeFeed(t *testing.T) {
+	feed := func(thread int, line string) MessageTask {
+		return MessageTask{ChatID: -100, ThreadID: thread, ContentType: "feed", Parts: []string{line}}
+	}
+	s := streamOf(feed(9, "b"), feed(9, "c"), feed(8, "other topic"), feed(9, "d"))
+	if got := mergeFeed(feed(9, "a"), s, DefaultMergeLen); got != "a\nb\nc" {
+		t.Errorf("mergeFeed = %q", got)
+	}
+	if next, _ := s.next(); next.ThreadID != 8 {
+		t.Error("another topic's line should be left in the stream")
+	}
+}
+
+func TestQueueLen_CountsUntilPickedUp(t *testing.T) {
+	q := &Queue{pending: make(map[int64]int)}
+	task := content(7, "@1", "a")
+	q.pending[7] = 2
+	q.done(task)
+	if n := q.QueueLen(7); n != 1 {
+		t.Errorf("QueueLen = %d, want 1", n)
+	}
+	q.done(task)
+	if n := q.QueueLen(7); n != 0 {
+		t.Errorf("QueueLen = %d, want 0", n)
+	}
+}
+
 type mockError struct {
 	msg string
 }
@@ -103,3 +245,284 @@ type mockError struct {
 func (e *mockError) Error() string {
 	return e.msg
 }
+
+func TestQueue_FloodErrorFallsBackAfterBan(t *testing.T) {
+	tg := testharness.NewTelegram(t)
+	tg.Flood("sendMessage", 1)
+	q := New(tg.API())
+
+	start := time.Now()
+	q.Enqueue(MessageTask{UserID: 1, ChatID: -100, ThreadID: 7, Parts: []string{"hello"}, ContentType: "content", WindowID: "@1"})
+
+	call := tg.WaitForText("sendMessage", "hello")
+	if call.Params["parse_mode"] != "" {
+		t.Errorf("parse_mode = %q, want plain-text fallback", call.Params["parse_mode"])
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("resent after %v, want to wait out the 1s retry_after", elapsed)
+	}
+	if n := len(tg.Calls("sendMessage")); n != 1 {
+		t.Errorf("delivered %d messages, want 1", n)
+	}
+}
+
+func TestQueue_IdleAfterDelivery(t *testing.T) {
+	tg := testharness.NewTelegram(t)
+	q := New(tg.API())
+	if !q.Idle() {
+		t.Fatal("new queue should be idle")
+	}
+
+	for i := 0; i < 3; i++ {
+		q.Enqueue(MessageTask{UserID: 1, ChatID: -100, Parts: []string{"part"}, ContentType: "tool_use", WindowID: "@1"})
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for !q.Idle() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := len(tg.Calls("sendMessage")); n != 3 {
+		t.Errorf("idle after %d of 3 sends", n)
+	}
+}
+
+func TestQueue_PinHandlerGetsToolMessage(t *testing.T) {
+	tg := testharness.NewTelegram(t)
+	q := New(tg.API())
+	pinned := make(chan int, 1)
+	q.SetPinHandler(func(task MessageTask, messageID int) {
+		if task.Pin != "plan" {
+			t.Errorf("pin kind = %q, want plan", task.Pin)
+		}
+		pinned <- messageID
+	})
+
+	q.Enqueue(MessageTask{UserID: 1, ChatID: -100, Parts: []string{"ExitPlanMode"}, ContentType: "tool_use", ToolUseID: "tu_1", WindowID: "@1"})
+	q.Enqueue(MessageTask{UserID: 1, ChatID: -100, Parts: []string{"approved"}, ContentType: "tool_result", ToolUseID: "tu_1", WindowID: "@1", Pin: "plan"})
+
+	toolUse := tg.WaitForText("sendMessage", "ExitPlanMode")
+	select {
+	case id := <-pinned:
+		if id != toolUse.MessageID {
+			t.Errorf("pinned message %d, want the edited tool_use message %d", id, toolUse.MessageID)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("pin handler not called")
+	}
+}
+
+func TestQueue_KeyboardFuncOnContent(t *testing.T) {
+	tg := testharness.NewTelegram(t)
+	q := New(tg.API())
+	q.SetKeyboardFunc(func(task MessageTask, text string) *tgbotapi.InlineKeyboardMarkup {
+		if !strings.Contains(text, "main.go:3") {
+			return nil
+		}
+		kb := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Open main.go", "open"),
+		))
+		return &kb
+	})
+
+	q.Enqueue(MessageTask{UserID: 1, ChatID: -100, Parts: []string{"plain"}, ContentType: "tool_use", WindowID: "@1"})
+	q.Enqueue(MessageTask{UserID: 1, ChatID: -100, Parts: []string{"see main.go:3"}, ContentType: "content", WindowID: "@1"})
+
+	if c := tg.WaitForText("sendMessage", "main"); !strings.Contains(c.Params["reply_markup"], "Open main.go") {
+		t.Errorf("content reply_markup = %q", c.Params["reply_markup"])
+	}
+	if c := tg.WaitForText("sendMessage", "plain"); c.Params["reply_markup"] != "" {
+		t.Errorf("tool_use got a keyboard: %q", c.Params["reply_markup"])
+	}
+}
+
+func TestQueue_SilentFunc(t *testing.T) {
+	tg := testharness.NewTelegram(t)
+	q := New(tg.API())
+	q.SetSilentFunc(func(task MessageTask) bool { return task.ThreadID == 7 && task.ContentType != "notification" })
+
+	q.Enqueue(MessageTask{UserID: 1, ChatID: -100, ThreadID: 7, Parts: []string{"routine"}, ContentType: "content", WindowID: "@1"})
+	q.Enqueue(MessageTask{UserID: 1, ChatID: -100, ThreadID: 7, Parts: []string{"Bash(ls)"}, ContentType: "tool_use", WindowID: "@1"})
+	q.Enqueue(MessageTask{UserID: 1, ChatID: -100, ThreadID: 7, Parts: []string{"needs permission"}, ContentType: "notification", WindowID: "@1"})
+	q.Enqueue(MessageTask{UserID: 1, ChatID: -100, ThreadID: 8, Parts: []string{"loud topic"}, ContentType: "content", WindowID: "@2"})
+
+	for text, silent := range map[string]bool{"routine": true, "Bash": true, "needs permission": false, "loud topic": false} {
+		c := tg.WaitForText("sendMessage", text)
+		if got := c.Params["disable_notification"] == "true"; got != silent {
+			t.Errorf("%q: disable_notification = %q, want silent=%v", text, c.Params["disable_notification"], silent)
+		}
+	}
+}
+
+func TestQueue_ThreadGoneHandler(t *testing.T) {
+	tg := testharness.NewTelegram(t)
+	tg.DeleteTopic(-100, 7)
+	q := New(tg.API())
+	gone := make(chan [2]int64, 1)
+	q.SetThreadGoneHandler(func(chatID int64, threadID int) { gone <- [2]int64{chatID, int64(threadID)} })
+
+	q.Enqueue(MessageTask{UserID: 1, ChatID: -100, ThreadID: 7, Parts: []string{"into the void"}, ContentType: "content", WindowID: "@1"})
+	select {
+	case got := <-gone:
+		if got != [2]int64{-100, 7} {
+			t.Errorf("thread gone handler got %v, want chat -100 thread 7", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("thread gone handler not called")
+	}
+	if n := len(tg.Calls("sendMessage")); n != 0 {
+		t.Errorf("%d messages delivered into a deleted topic", n)
+	}
+}
+
+func TestQueue_ToolProgressEditsUntilResult(t *testing.T) {
+	tg := testharness.NewTelegram(t)
+	q := New(tg.API())
+
+	task := MessageTask{UserID: 1, ChatID: -100, ToolUseID: "tu_1", WindowID: "@1"}
+	enqueue := func(contentType, text string) {
+		task.ContentType, task.Parts = contentType, []string{text}
+		q.Enqueue(task)
+	}
+	enqueue("tool_use", "Bash(make)")
+	enqueue("tool_progress", "Bash(make) 20s")
+	enqueue("tool_result", "Bash(make) done")
+	enqueue("tool_progress", "Bash(make) 30s")
+	tg.WaitForText("editMessageText", "done")
+	for !q.Idle() {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	var edits []string
+	for _, c := range tg.Calls("editMessageText") {
+		edits = append(edits, c.Params["text"])
+	}
+	if len(edits) != 2 || !strings.Contains(edits[0], "20s") || !strings.Contains(edits[1], "done") {
+		t.Errorf("edits = %q, want progress then the result, nothing after", edits)
+	}
+}
+
+func TestQueue_FloodInOneChatDoesNotBlockAnother(t *testing.T) {
+	tg := testharness.NewTelegram(t)
+	tg.Flood("sendMessage", 3)
+	q := New(tg.API())
+
+	q.Enqueue(MessageTask{UserID: 1, ChatID: -100, Parts: []string{"to A"}, ContentType: "content", WindowID: "@1"})
+	deadline := time.Now().Add(2 * time.Second)
+	for !q.IsFlooded(-100) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !q.IsFlooded(-100) {
+		t.Fatal("chat A should be flood-banned")
+	}
+
+	// Same window, another chat: delivered while chat A waits out its ban
+	start := time.Now()
+	q.Enqueue(MessageTask{UserID: 2, ChatID: -200, Parts: []string{"to B"}, ContentType: "content", WindowID: "@1"})
+	tg.WaitForText("sendMessage", "to B")
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("chat B delivery took %v, want it unaffected by chat A's ban", elapsed)
+	}
+	if q.IsFlooded(-200) {
+		t.Error("chat B should not be flood-banned")
+	}
+	tg.WaitForText("sendMessage", "to A")
+}
+
+func TestQueue_TracedTaskDeliveredUnderEntrySpan(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	telemetry.Use(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	tg := testharness.NewTelegram(t)
+	api := tg.API()
+	api.Client = telemetry.WrapHTTPClient(api.Client)
+	q := New(api)
+
+	ctx, entry := telemetry.Start(context.Background(), "transcript.entry")
+	q.Enqueue(MessageTask{UserID: 1, ChatID: -100, Parts: []string{"traced"}, ContentType: "content", WindowID: "@1", Trace: ctx})
+	entry.End()
+	tg.WaitForText("sendMessage", "traced")
+	deadline := time.Now().Add(5 * time.Second)
+	for !q.Idle() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, s := range rec.Ended() {
+		spans[s.Name()] = s
+	}
+	deliver, send := spans["queue.deliver"], spans["telegram.sendMessage"]
+	if deliver == nil || send == nil {
+		t.Fatalf("missing spans, got %v", spans)
+	}
+	if deliver.Parent().SpanID() != entry.SpanContext().SpanID() {
+		t.Error("queue.deliver is not a child of the entry span")
+	}
+	if send.Parent().SpanID() != deliver.SpanContext().SpanID() {
+		t.Error("telegram.sendMessage is not a child of queue.deliver")
+	}
+}
+
+func TestQueue_LongQuoteFitsTelegramLimit(t *testing.T) {
+	tg := testharness.NewTelegram(t)
+	q := New(tg.API())
+
+	notes := strings.Repeat("Rebuilt the index (step 1.2).\n", 120)
+	result := notes + render.ExpQuoteStart + strings.Repeat("warning: x.y\n", 290) + render.ExpQuoteEnd
+	task := MessageTask{UserID: 1, ChatID: -100, ToolUseID: "tu_1", WindowID: "@1"}
+	task.ContentType, task.Parts = "tool_use", []string{"Bash(make)"}
+	q.Enqueue(task)
+	task.ContentType, task.Parts = "tool_result", []string{result}
+	q.Enqueue(task)
+
+	tg.WaitForText("editMessageText", "Rebuilt")
+	tg.WaitForText("sendMessage", "warning")
+	for _, c := range append(tg.Calls("editMessageText"), tg.Calls("sendMessage")...) {
+		if n := len([]rune(c.Params["text"])); n > render.MaxMessageLen {
+			t.Errorf("%s of %d characters", c.Method, n)
+		}
+	}
+}
+
+func TestQueue_SentMessagesAndDelete(t *testing.T) {
+	tg := testharness.NewTelegram(t)
+	q := New(tg.API())
+
+	task := MessageTask{UserID: 1, ChatID: -100, ThreadID: 7, WindowID: "@1"}
+	for _, kind := range []string{"content", "tool_use", "status_update"} {
+		task.ContentType, task.Parts = kind, []string{kind + " text"}
+		task.ToolUseID = ""
+		q.Enqueue(task)
+	}
+	task.ContentType, task.Parts, task.ToolUseID = "tool_use", []string{"waiting"}, "tu_1"
+	q.Enqueue(task)
+	tg.WaitForText("sendMessage", "waiting")
+	for !q.Idle() {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	sent := q.SentMessages(-100, 7)
+	var kinds []string
+	var live []bool
+	for _, m := range sent {
+		kinds = append(kinds, m.Kind)
+		live = append(live, m.Live)
+	}
+	if want := []string{"content", "tool_use", "status_update", "tool_use"}; strings.Join(kinds, ",") != strings.Join(want, ",") {
+		t.Fatalf("kinds = %v, want %v", kinds, want)
+	}
+	if !live[2] || !live[3] || live[0] || live[1] {
+		t.Errorf("live = %v, want the status and the waiting tool call live", live)
+	}
+	if other := q.SentMessages(-100, 8); len(other) != 0 {
+		t.Errorf("other topic has %d messages", len(other))
+	}
+
+	if n := q.DeleteMessages(-100, []int{sent[0].MessageID, sent[1].MessageID}); n != 0 {
+		t.Errorf("refused %d, want 0", n)
+	}
+	calls := tg.Calls("deleteMessages")
+	if len(calls) != 1 || calls[0].Params["message_ids"] != fmt.Sprintf("[%d,%d]", sent[0].MessageID, sent[1].MessageID) {
+		t.Errorf("deleteMessages calls = %v", calls)
+	}
+	if left := q.SentMessages(-100, 7); len(left) != 2 {
+		t.Errorf("%d messages left recorded, want 2", len(left))
+	}
+}
@@ -0,0 +1,202 @@
+package queue
+
+import (
+	"encoding/json"
+	"log"
+	"slices"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// maxSentPerTopic caps the messages remembered per topic; older ones are
+	// forgotten first.
+	maxSentPerTopic = 2000
+	// deleteBatchSize is the most messages one deleteMessages call takes.
+	deleteBatchSize = 100
+	// deleteBatchDelay spaces deleteMessages calls, on top of flood control.
+	deleteBatchDelay = time.Second
+)
+
+// chatThread identifies a topic.
+type chatThread struct {
+	ChatID   int64
+	ThreadID int
+}
+
+// Source locates the transcript entry a message was rendered from.
+type Source struct {
+	SessionKey string // monitor session key (tmux session:window ID)
+	SessionID  string // Claude session ID
+	Path       string // transcript JSONL file
+	Start, End int64  // byte range of the entry's line(s) in Path
+	EntryType  string // transcript entry type: "user", "assistant", "system"
+	Block      string // parsed content: "text", "tool_use", "tool_result", "thinking", "compact"
+}
+
+// SentMessage is a message the queue delivered to a topic.
+type SentMessage struct {
+	MessageID int
+	Kind      string // the task's ContentType
+	WindowID  string
+	Sources   []Source // transcript entries it shows, in order; empty for the bot's own messages
+	At        time.Time
+	Live      bool // still edited or deleted by the queue: the current status, or a tool call awaiting its result
+}
+
+// origin is what a message is sent for: the task's kind, window and sources.
+type origin struct {
+	kind     string
+	windowID string
+	sources  []Source
+}
+
+func originOf(task MessageTask) origin {
+	return origin{task.ContentType, task.WindowID, task.Sources}
+}
+
+// recordSent remembers a delivered message for SentMessages.
+func (q *Queue) recordSent(chatID int64, threadID int, messageID int, o origin) {
+	if messageID == 0 {
+		return
+	}
+	key := chatThread{chatID, threadID}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	msgs := append(q.sent[key], SentMessage{MessageID: messageID, Kind: o.kind, WindowID: o.windowID, Sources: o.sources, At: time.Now()})
+	if len(msgs) > maxSentPerTopic {
+		msgs = msgs[len(msgs)-maxSentPerTopic:]
+	}
+	q.sent[key] = msgs
+}
+
+// addSources records that a delivered message was edited to show more
+// transcript entries, as when a tool result replaces its call.
+func (q *Queue) addSources(chatID int64, threadID int, messageID int, sources []Source) {
+	if len(sources) == 0 {
+		return
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	msgs := q.sent[chatThread{chatID, threadID}]
+	for i := len(msgs) - 1; i >= 0; i-- {
+		if msgs[i].MessageID == messageID {
+			msgs[i].Sources = slices.Concat(msgs[i].Sources, sources)
+			return
+		}
+	}
+}
+
+// forgetSent drops deleted messages from every topic's record.
+func (q *Queue) forgetSent(chatID int64, messageIDs ...int) {
+	gone := make(map[int]bool, len(messageIDs))
+	for _, id := range messageIDs {
+		gone[id] = true
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for key, msgs := range q.sent {
+		if key.ChatID != chatID {
+			continue
+		}
+		kept := msgs[:0]
+		for _, m := range msgs {
+			if !gone[m.MessageID] {
+				kept = append(kept, m)
+			}
+		}
+		q.sent[key] = kept
+	}
+}
+
+// SentMessages returns the messages delivered to a topic since the bot
+// started, oldest first, at most maxSentPerTopic.
+func (q *Queue) SentMessages(chatID int64, threadID int) []SentMessage {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	live := make(map[int]bool)
+	for ut, st := range q.statusMsgs {
+		if ut.ThreadID == threadID {
+			live[st.MessageID] = true
+		}
+	}
+	for _, info := range q.toolMsgIDs {
+		if info.ChatID == chatID && info.ThreadID == threadID {
+			live[info.MessageID] = true
+		}
+	}
+	msgs := append([]SentMessage(nil), q.sent[chatThread{chatID, threadID}]...)
+	for i := range msgs {
+		msgs[i].Live = live[msgs[i].MessageID]
+	}
+	return msgs
+}
+
+// FindSent returns a message delivered to a topic, if it is still recorded.
+func (q *Queue) FindSent(chatID int64, threadID int, messageID int) (SentMessage, bool) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	msgs := q.sent[chatThread{chatID, threadID}]
+	for i := len(msgs) - 1; i >= 0; i-- {
+		if msgs[i].MessageID == messageID {
+			return msgs[i], true
+		}
+	}
+	return SentMessage{}, false
+}
+
+// DeleteMessages deletes messages from a chat in batches, pacing the calls
+// and waiting out flood bans, and returns how many Telegram refused to
+// delete. A batch Telegram refuses is retried one message at a time, so a few
+// undeletable messages (e.g. older than 48 hours) don't keep the rest. An
+// accepted batch silently skips messages that are already gone, so it does
+// not say how many it deleted.
+func (q *Queue) DeleteMessages(chatID int64, messageIDs []int) int {
+	refused := 0
+	for start := 0; start < len(messageIDs); start += deleteBatchSize {
+		if start > 0 {
+			time.Sleep(deleteBatchDelay)
+		}
+		batch := messageIDs[start:min(start+deleteBatchSize, len(messageIDs))]
+		err := q.deleteBatch(chatID, batch)
+		if err != nil && q.flood.IsFlooded(chatID) {
+			q.flood.WaitIfFlooded(chatID)
+			err = q.deleteBatch(chatID, batch)
+		}
+		if err == nil {
+			continue
+		}
+		log.Printf("Error deleting %d messages in chat %d, deleting one by one: %v", len(batch), chatID, err)
+		for _, id := range batch {
+			q.flood.WaitIfFlooded(chatID)
+			q.flood.Throttle(chatID)
+			params := tgbotapi.Params{}
+			params.AddNonZero64("chat_id", chatID)
+			params.AddNonZero("message_id", id)
+			if _, err := q.api.MakeRequest("deleteMessage", params); err != nil {
+				q.flood.HandleError(chatID, err)
+				refused++
+			}
+		}
+	}
+	q.forgetSent(chatID, messageIDs...)
+	return refused
+}
+
+// deleteBatch deletes up to deleteBatchSize messages in one call.
+func (q *Queue) deleteBatch(chatID int64, messageIDs []int) error {
+	ids, err := json.Marshal(messageIDs)
+	if err != nil {
+		return err
+	}
+	q.flood.Throttle(chatID)
+	params := tgbotapi.Params{}
+	params.AddNonZero64("chat_id", chatID)
+	params.AddNonEmpty("message_ids", string(ids))
+	if _, err := q.api.MakeRequest("deleteMessages", params); err != nil {
+		q.flood.HandleError(chatID, err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// maxSources caps the numbered source list under a web tool result.
+const maxSources = 8
+
+// Source is a page cited by a WebSearch or WebFetch result.
+type Source struct {
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+var (
+	markdownLinkPattern = regexp.MustCompile(`\[([^\]\n]+)\]\((https?://[^)\s]+)\)`)
+	bareURLPattern      = regexp.MustCompile(`https?://[^\s<>"'\])]+`)
+)
+
+// parseCitations pulls the sources out of a web tool result. WebSearch results
+// carry a "Links: [...]" JSON line; otherwise markdown links and bare URLs in
+// the text are used. The returned body has the links line and the
+// "Web search results for query" header removed.
+func parseCitations(content string) (sources []Source, body string) {
+	seen := make(map[string]bool)
+	add := func(title, rawURL string) {
+		rawURL = strings.TrimRight(rawURL, ".,;:")
+		if seen[rawURL] {
+			return
+		}
+		u, err := url.Parse(rawURL)
+		if err != nil || u.Host == "" {
+			return
+		}
+		seen[rawURL] = true
+		if title = strings.TrimSpace(title); title == "" {
+			title = strings.TrimPrefix(u.Host, "www.")
+		}
+		sources = append(sources, Source{Title: title, URL: rawURL})
+	}
+
+	var kept []string
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "Web search results for query:") {
+			continue
+		}
+		if rest, ok := strings.CutPrefix(trimmed, "Links:"); ok {
+			var links []Source
+			if json.Unmarshal([]byte(strings.TrimSpace(rest)), &links) == nil {
+				for _, l := range links {
+					add(l.Title, l.URL)
+				}
+				continue
+			}
+		}
+		kept = append(kept, line)
+	}
+	body = strings.TrimSpace(strings.Join(kept, "\n"))
+
+	for _, m := range markdownLinkPattern.FindAllStringSubmatch(body, -1) {
+		add(m[1], m[2])
+	}
+	for _, u := range bareURLPattern.FindAllString(body, -1) {
+		add("", u)
+	}
+	return sources, body
+}
+
+// formatSources renders sources as a numbered list of markdown links.
+func formatSources(sources []Source) string {
+	var b strings.Builder
+	for i, s := range sources {
+		if i == maxSources {
+			fmt.Fprintf(&b, "\n… +%d more", len(sources)-maxSources)
+			break
+		}
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		title := strings.NewReplacer("[", "(", "]", ")", "\n", " ").Replace(s.Title)
+		if r := []rune(title); len(r) > 80 {
+			title = string(r[:79]) + "…"
+		}
+		link := strings.NewReplacer("(", "%28", ")", "%29").Replace(s.URL)
+		fmt.Fprintf(&b, "%d. [%s](%s)", i+1, title, link)
+	}
+	return b.String()
+}
+
+// formatWebResult renders a WebSearch or WebFetch result as summary, then the
+// numbered sources, then the body in an expandable quote.
+func formatWebResult(summary, content string) string {
+	sources, body := parseCitations(content)
+	var parts []string
+	parts = append(parts, summary)
+	if len(sources) > 0 {
+		parts = append(parts, formatSources(sources))
+	}
+	if body != "" {
+		parts = append(parts, formatPreviewQuote(body))
+	}
+	return strings.Join(parts, "\n")
+}
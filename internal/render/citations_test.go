@@ -0,0 +1,71 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const webSearchResult = `Web search results for query: "go 1.24 release"
+
+Links: [{"title":"Go 1.24 Release Notes","url":"https://go.dev/doc/go1.24"},{"title":"Go 1.24 is released! [blog]","url":"https://go.dev/blog/go1.24"},{"title":"Go 1.24 Release Notes","url":"https://go.dev/doc/go1.24"}]
+
+Go 1.24 was released in February 2025 with generic type aliases. See https://go.dev/blog/go1.24 for details.`
+
+func TestParseCitations_WebSearchLinks(t *testing.T) {
+	sources, body := parseCitations(webSearchResult)
+	want := []Source{
+		{"Go 1.24 Release Notes", "https://go.dev/doc/go1.24"},
+		{"Go 1.24 is released! [blog]", "https://go.dev/blog/go1.24"},
+	}
+	if fmt.Sprint(sources) != fmt.Sprint(want) {
+		t.Errorf("sources = %v, want %v", sources, want)
+	}
+	if strings.Contains(body, "Links:") || strings.Contains(body, "Web search results") || !strings.HasPrefix(body, "Go 1.24 was released") {
+		t.Errorf("body = %q", body)
+	}
+}
+
+func TestParseCitations_LinksInText(t *testing.T) {
+	sources, _ := parseCitations("Per [the spec](https://go.dev/ref/spec), see also https://pkg.go.dev/errors.\nAnd https://go.dev/ref/spec again.")
+	want := []Source{
+		{"the spec", "https://go.dev/ref/spec"},
+		{"pkg.go.dev", "https://pkg.go.dev/errors"},
+	}
+	if fmt.Sprint(sources) != fmt.Sprint(want) {
+		t.Errorf("sources = %v, want %v", sources, want)
+	}
+}
+
+func TestFormatToolResult_WebSearchSources(t *testing.T) {
+	got := FormatToolResult("WebSearch", "go 1.24 release", webSearchResult, false)
+	for _, want := range []string{
+		"2 sources",
+		"1. [Go 1.24 Release Notes](https://go.dev/doc/go1.24)",
+		"2. [Go 1.24 is released! (blog)](https://go.dev/blog/go1.24)",
+		ExpQuoteStart + "Go 1.24 was released",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in\n%s", want, got)
+		}
+	}
+
+	mdv2 := ToMarkdownV2(got)
+	if !strings.Contains(mdv2, "[Go 1\\.24 Release Notes](https://go.dev/doc/go1.24)") {
+		t.Errorf("source is not a link in MarkdownV2:\n%s", mdv2)
+	}
+}
+
+func TestFormatSources_Caps(t *testing.T) {
+	var sources []Source
+	for i := 1; i <= maxSources+3; i++ {
+		sources = append(sources, Source{Title: fmt.Sprintf("Page %d", i), URL: fmt.Sprintf("https://example.com/%d", i)})
+	}
+	got := formatSources(sources)
+	if n := strings.Count(got, "](https://"); n != maxSources {
+		t.Errorf("%d links, want %d", n, maxSources)
+	}
+	if !strings.HasSuffix(got, "… +3 more") {
+		t.Errorf("got %q", got)
+	}
+}
@@ -0,0 +1,134 @@
+package render
+
+import (
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// MaxMessageLen is Telegram's limit on a message's text, in UTF-16 code units.
+const MaxMessageLen = 4096
+
+// quoteCutMark ends quote content that was cut to fit a message.
+const quoteCutMark = "\n... (truncated)"
+
+// FitMessage makes a message with expandable quotes deliverable: it returns
+// one or more messages whose MarkdownV2 rendering is at most maxLen long, so
+// the plain-text fallback, which is never longer, fits too. When little text
+// surrounds the quotes they are shortened to share the room left; otherwise
+// the text and the quotes are sent as separate messages, each quote shortened
+// only as far as it must be on its own. Text without quotes is returned as is
+// (SplitMessage handles it).
+func FitMessage(text string, maxLen int) []string {
+	if !strings.Contains(text, ExpQuoteStart) || RenderedLen(text) <= maxLen {
+		return []string{text}
+	}
+
+	segments := extractExpandableQuotes(text)
+	quotes, surrounding := 0, 0
+	for _, seg := range segments {
+		if seg.isQuote {
+			quotes++
+			surrounding++ // the newline a quote starts on
+		} else {
+			surrounding += RenderedLen(seg.content)
+		}
+	}
+
+	if surrounding <= maxLen/4 {
+		budget := (maxLen - surrounding) / quotes
+		var b strings.Builder
+		for _, seg := range segments {
+			if seg.isQuote {
+				b.WriteString(formatExpandableQuote(shrinkQuote(seg.content, budget)))
+			} else {
+				b.WriteString(seg.content)
+			}
+		}
+		if fitted := b.String(); RenderedLen(fitted) <= maxLen {
+			return []string{fitted}
+		}
+	}
+
+	var pieces []string
+	for _, seg := range segments {
+		if seg.isQuote {
+			pieces = append(pieces, formatExpandableQuote(shrinkQuote(seg.content, maxLen)))
+		} else {
+			pieces = append(pieces, splitRendered(seg.content, maxLen)...)
+		}
+	}
+	var msgs []string
+	for _, p := range pieces {
+		if n := len(msgs); n > 0 && RenderedLen(msgs[n-1]+"\n"+p) <= maxLen {
+			msgs[n-1] += "\n" + p
+		} else {
+			msgs = append(msgs, p)
+		}
+	}
+	return msgs
+}
+
+// RenderedLen is the length of text's MarkdownV2 rendering in UTF-16 code
+// units, as Telegram counts it. Escapes are included, so it is an upper
+// bound on the length Telegram checks.
+func RenderedLen(text string) int {
+	return utf16Len(ToMarkdownV2(text))
+}
+
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		if utf16.RuneLen(r) == 2 {
+			n += 2
+		} else {
+			n++
+		}
+	}
+	return n
+}
+
+// shrinkQuote cuts quote content, marking the cut, until the quote renders
+// within budget. Content that can't fit is cut down to the mark alone.
+func shrinkQuote(content string, budget int) string {
+	quoted, cut := content, len(content)
+	for {
+		l := RenderedLen(formatExpandableQuote(quoted))
+		if l <= budget || cut == 0 {
+			return quoted
+		}
+		cut = min(cut-1, cut*budget/l*9/10)
+		quoted = TruncateAtRune(content, cut) + quoteCutMark
+	}
+}
+
+// splitRendered splits text without quotes into pieces that each render
+// within maxLen, dropping blank ones.
+func splitRendered(text string, maxLen int) []string {
+	for n := maxLen; ; n = n * 3 / 4 {
+		var pieces []string
+		fits := true
+		for _, p := range SplitMessage(text, max(n, 1)) {
+			if p = strings.Trim(p, "\n"); strings.TrimSpace(p) == "" {
+				continue
+			}
+			pieces = append(pieces, p)
+			fits = fits && RenderedLen(p) <= maxLen
+		}
+		if fits || n <= 1 {
+			return pieces
+		}
+	}
+}
+
+// TruncateAtRune returns at most the first n bytes of s, cut on a rune
+// boundary.
+func TruncateAtRune(s string, n int) string {
+	if n >= len(s) {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
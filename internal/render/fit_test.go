@@ -0,0 +1,79 @@
+package render
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestFitMessage_Unchanged(t *testing.T) {
+	for _, text := range []string{
+		"short",
+		"header\n" + formatExpandableQuote("quoted"),
+		strings.Repeat("no quotes. ", 1000),
+	} {
+		if got := FitMessage(text, MaxMessageLen); len(got) != 1 || got[0] != text {
+			t.Errorf("FitMessage changed %.40q", text)
+		}
+	}
+}
+
+func TestFitMessage_ShrinksQuote(t *testing.T) {
+	// 3800 dots escape to 7600 characters
+	text := "● Bash(make)\n" + formatExpandableQuote(strings.Repeat(".", 3800))
+	if RenderedLen(text) <= MaxMessageLen {
+		t.Fatal("test message should not fit")
+	}
+
+	got := FitMessage(text, MaxMessageLen)
+	if len(got) != 1 {
+		t.Fatalf("got %d messages, want the quote shortened into one", len(got))
+	}
+	if n := RenderedLen(got[0]); n > MaxMessageLen {
+		t.Errorf("rendered length %d > %d", n, MaxMessageLen)
+	}
+	if !strings.HasPrefix(got[0], "● Bash(make)\n"+ExpQuoteStart) || !strings.Contains(got[0], "... (truncated)") {
+		t.Errorf("got %.80q…, want the header and a cut quote", got[0])
+	}
+}
+
+func TestFitMessage_SplitsSurroundingText(t *testing.T) {
+	text := strings.Repeat("Some *notes* on the change (v1.2).\n", 100) +
+		formatExpandableQuote(strings.Repeat("log line - ok!\n", 250)) +
+		"\nDone."
+
+	got := FitMessage(text, MaxMessageLen)
+	if len(got) < 2 {
+		t.Fatalf("got %d messages, want the text and the quote apart", len(got))
+	}
+	for i, m := range got {
+		if n := RenderedLen(m); n > MaxMessageLen {
+			t.Errorf("message %d renders to %d > %d", i, n, MaxMessageLen)
+		}
+	}
+	all := strings.Join(got, "\n")
+	if strings.Count(all, "Some *notes*") != 100 || !strings.Contains(all, "Done.") {
+		t.Error("surrounding text was lost")
+	}
+	if strings.Count(all, ExpQuoteStart) != 1 || strings.Count(all, ExpQuoteEnd) != 1 {
+		t.Error("quote markers were not kept as one quote")
+	}
+}
+
+func TestFitMessage_CutsOnRunes(t *testing.T) {
+	text := "x\n" + formatExpandableQuote(strings.Repeat("é_😀", 1500))
+	for _, m := range FitMessage(text, MaxMessageLen) {
+		if !utf8.ValidString(m) {
+			t.Fatal("cut inside a rune")
+		}
+		if n := RenderedLen(m); n > MaxMessageLen {
+			t.Errorf("rendered length %d > %d", n, MaxMessageLen)
+		}
+	}
+}
+
+func TestRenderedLen_CountsUTF16(t *testing.T) {
+	if n := RenderedLen("a😀."); n != 5 { // a, surrogate pair, \.
+		t.Errorf("RenderedLen = %d, want 5", n)
+	}
+}
@@ -2,7 +2,9 @@ package render
 
 import (
 	"fmt"
+	"path/filepath"
 	"strings"
+	"time"
 )
 
 // Sentinel markers for expandable quotes. These are replaced during MarkdownV2 conversion.
@@ -11,7 +13,8 @@ const (
 	ExpQuoteEnd   = "\x02EXPQUOTE_END\x02"
 )
 
-// previewLines is how many content lines to show before truncating with "… +N lines".
+// previewLines is how many content lines to show before truncating with "… +N lines",
+// unless the profile sets PreviewLines.
 const previewLines = 3
 
 // FormatToolUse formats a tool_use block as the initial message (before result arrives).
@@ -21,15 +24,9 @@ func FormatToolUse(name, input string) string {
 
 // FormatToolResult formats a tool_result combined with its tool_use header.
 // The result replaces the tool_use message, so it includes both the header and result.
+// It uses DefaultProfile; see Profile.FormatToolResult for other verbosity levels.
 func FormatToolResult(toolName, toolInput, content string, isError bool) string {
-	header := "● " + toolHeader(toolName, toolInput)
-
-	if isError {
-		return header + "\n  ⎿ " + formatErrorBody(content)
-	}
-
-	body := formatResultBody(toolName, content)
-	return header + "\n  ⎿ " + body
+	return DefaultProfile.FormatToolResult(toolName, toolInput, content, isError)
 }
 
 // FormatThinking formats a thinking block: truncate to 500 chars and wrap in expandable quote.
@@ -41,11 +38,33 @@ func FormatThinking(text string) string {
 	return formatExpandableQuote(truncated)
 }
 
+// thinkingChunkLen is the most thinking text FormatThinkingFull puts in one
+// quote, under renderExpandableQuote's truncation.
+const thinkingChunkLen = 3500
+
+// FormatThinkingFull formats a whole thinking block as expandable quotes, one
+// per message-sized chunk.
+func FormatThinkingFull(text string) []string {
+	chunks := SplitMessage(text, thinkingChunkLen)
+	for i, chunk := range chunks {
+		chunks[i] = formatExpandableQuote(chunk)
+	}
+	return chunks
+}
+
 // FormatText strips system tags and returns clean text.
 func FormatText(text string) string {
 	return text
 }
 
+// FormatTimestamp renders t as "15:04" in loc. Returns "" if t is zero or loc is nil.
+func FormatTimestamp(t time.Time, loc *time.Location) string {
+	if t.IsZero() || loc == nil {
+		return ""
+	}
+	return t.In(loc).Format("15:04")
+}
+
 // toolHeader builds "**Name**(input)" or "**Name**()" for use in tool formatting.
 func toolHeader(name, input string) string {
 	if input != "" {
@@ -55,7 +74,7 @@ func toolHeader(name, input string) string {
 }
 
 // formatResultBody produces the result body for a given tool type.
-func formatResultBody(toolName, content string) string {
+func (p Profile) formatResultBody(toolName, toolInput, content string) string {
 	if content == "" {
 		return "(No output)"
 	}
@@ -69,7 +88,11 @@ func formatResultBody(toolName, content string) string {
 
 	switch toolName {
 	case "Read":
-		return fmt.Sprintf("Read %d lines", lineCount)
+		summary := fmt.Sprintf("Read %d lines", lineCount)
+		if p.ReadPreviewLines > 0 && lineCount > 0 {
+			summary += "\n" + formatReadPreview(toolInput, lines, p.ReadPreviewLines)
+		}
+		return summary
 	case "Write":
 		return fmt.Sprintf("Wrote %d lines", lineCount)
 	case "Edit":
@@ -80,7 +103,7 @@ func formatResultBody(toolName, content string) string {
 		// No diff — show first line (e.g. "The file ... has been updated successfully.")
 		return firstLine(content)
 	case "Bash":
-		return formatPreview(lines, lineCount)
+		return formatPreview(lines, lineCount, p.previewLines())
 	case "Grep":
 		matchCount := countNonEmpty(lines)
 		summary := fmt.Sprintf("Found %d matches", matchCount)
@@ -97,29 +120,35 @@ func formatResultBody(toolName, content string) string {
 		return summary
 	case "Task":
 		return fmt.Sprintf("Agent output %d lines", lineCount)
+	case "Skill":
+		// "Launching skill: <name>", then whatever the skill reported
+		summary := firstLine(content)
+		if lineCount > 1 {
+			summary += "\n" + formatPreviewQuote(strings.Join(lines[1:], "\n"))
+		}
+		return summary
 	case "WebFetch":
-		return fmt.Sprintf("Fetched %d characters", len(content))
+		return formatWebResult(fmt.Sprintf("Fetched %d characters", len(content)), content)
 	case "WebSearch":
-		resultCount := countSearchResults(content)
-		summary := fmt.Sprintf("%d search results", resultCount)
-		if content != "" {
-			summary += "\n" + formatPreviewQuote(content)
+		summary := fmt.Sprintf("%d search results", countSearchResults(content))
+		if sources, _ := parseCitations(content); len(sources) > 0 {
+			summary = fmt.Sprintf("%d sources", len(sources))
 		}
-		return summary
+		return formatWebResult(summary, content)
 	default:
-		return formatPreview(lines, lineCount)
+		return formatPreview(lines, lineCount, p.previewLines())
 	}
 }
 
-// formatPreview shows up to previewLines of content, then "… +N lines".
-func formatPreview(lines []string, totalLines int) string {
+// formatPreview shows up to maxLines of content, then "… +N lines".
+func formatPreview(lines []string, totalLines, maxLines int) string {
 	if totalLines == 0 {
 		return "(No output)"
 	}
 
 	show := lines
-	if len(show) > previewLines {
-		show = show[:previewLines]
+	if len(show) > maxLines {
+		show = show[:maxLines]
 	}
 
 	var b strings.Builder
@@ -138,6 +167,98 @@ func formatPreview(lines []string, totalLines int) string {
 	return b.String()
 }
 
+// formatReadPreview shows the first maxLines of a file as a language-tagged
+// code block inside an expandable quote. Line-number prefixes added by the
+// Read tool ("   12→") are stripped.
+func formatReadPreview(filePath string, lines []string, maxLines int) string {
+	show := lines
+	if len(show) > maxLines {
+		show = show[:maxLines]
+	}
+
+	var b strings.Builder
+	b.WriteString("```" + languageForPath(filePath) + "\n")
+	for _, line := range show {
+		b.WriteString(stripLineNumber(line))
+		b.WriteString("\n")
+	}
+	b.WriteString("```")
+
+	if remaining := len(lines) - len(show); remaining > 0 {
+		b.WriteString(fmt.Sprintf("\n… +%d lines", remaining))
+	}
+
+	return formatExpandableQuote(truncateContent(b.String(), 3000))
+}
+
+// stripLineNumber removes a cat -n style "   12→" or "   12\t" prefix.
+func stripLineNumber(line string) string {
+	i := 0
+	for i < len(line) && line[i] == ' ' {
+		i++
+	}
+	start := i
+	for i < len(line) && line[i] >= '0' && line[i] <= '9' {
+		i++
+	}
+	if i == start {
+		return line
+	}
+	if strings.HasPrefix(line[i:], "→") {
+		return line[i+len("→"):]
+	}
+	if i < len(line) && line[i] == '\t' {
+		return line[i+1:]
+	}
+	return line
+}
+
+// languageByExt maps file extensions to code block language tags.
+var languageByExt = map[string]string{
+	".go":    "go",
+	".py":    "python",
+	".js":    "javascript",
+	".jsx":   "jsx",
+	".ts":    "typescript",
+	".tsx":   "tsx",
+	".rs":    "rust",
+	".java":  "java",
+	".kt":    "kotlin",
+	".c":     "c",
+	".h":     "c",
+	".cc":    "cpp",
+	".cpp":   "cpp",
+	".hpp":   "cpp",
+	".cs":    "csharp",
+	".rb":    "ruby",
+	".php":   "php",
+	".swift": "swift",
+	".sh":    "bash",
+	".bash":  "bash",
+	".zsh":   "bash",
+	".sql":   "sql",
+	".html":  "html",
+	".css":   "css",
+	".json":  "json",
+	".yaml":  "yaml",
+	".yml":   "yaml",
+	".toml":  "toml",
+	".xml":   "xml",
+	".md":    "markdown",
+	".lua":   "lua",
+}
+
+// languageForPath guesses a code block language from a file path's extension.
+func languageForPath(path string) string {
+	if strings.HasSuffix(path, "Makefile") {
+		return "makefile"
+	}
+	if strings.HasSuffix(path, "Dockerfile") {
+		return "dockerfile"
+	}
+	return languageByExt[strings.ToLower(filepath.Ext(path))]
+}
+
 // formatPreviewQuote wraps content in an expandable quote, truncated.
 func formatPreviewQuote(content string) string {
 	return formatExpandableQuote(truncateContent(content, 3000))
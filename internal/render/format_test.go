@@ -1,8 +1,10 @@
 package render
 
 import (
+	"fmt"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestFormatToolUse(t *testing.T) {
@@ -36,6 +38,68 @@ func TestFormatToolResult_Read(t *testing.T) {
 	}
 }
 
+func TestFormatToolResult_ReadPreview(t *testing.T) {
+	var lines []string
+	for i := 1; i <= 15; i++ {
+		lines = append(lines, fmt.Sprintf("%6d→line %d", i, i))
+	}
+	got := FormatToolResult("Read", "/src/main.go", strings.Join(lines, "\n"), false)
+	if !strings.Contains(got, "⎿ Read 15 lines") {
+		t.Errorf("missing summary in %q", got)
+	}
+	if !strings.Contains(got, ExpQuoteStart+"```go\nline 1\n") {
+		t.Errorf("missing go code block in %q", got)
+	}
+	if strings.Contains(got, "line 11") {
+		t.Errorf("preview should stop at 10 lines: %q", got)
+	}
+	if !strings.Contains(got, "… +5 lines") {
+		t.Errorf("missing remaining count in %q", got)
+	}
+}
+
+func TestFormatToolResult_ReadCompactProfile(t *testing.T) {
+	p, ok := LookupProfile("compact")
+	if !ok {
+		t.Fatal("compact profile missing")
+	}
+	got := p.FormatToolResult("Read", "main.go", "a\nb\n", false)
+	if strings.Contains(got, ExpQuoteStart) {
+		t.Errorf("compact profile should not preview: %q", got)
+	}
+}
+
+func TestFormatToolResult_Digest(t *testing.T) {
+	p := DefaultProfile
+	p.Digest = true
+	got := p.FormatToolResult("Bash", "ls", "one\ntwo\nthree\n", false)
+	if !strings.HasSuffix(got, "⎿ one …") {
+		t.Errorf("digested Bash result = %q", got)
+	}
+	got = p.FormatToolResult("Bash", "make", strings.Repeat("x", 150)+"\nmore", true)
+	if strings.Contains(got, ExpQuoteStart) || !strings.HasSuffix(got, " …") {
+		t.Errorf("digested error kept its output: %q", got)
+	}
+	got = p.FormatToolResult("Write", "a.go", "a\nb\n", false)
+	if !strings.HasSuffix(got, "⎿ Wrote 2 lines") {
+		t.Errorf("digested Write result = %q", got)
+	}
+}
+
+func TestLanguageForPath(t *testing.T) {
+	tests := map[string]string{
+		"main.go":         "go",
+		"/a/b/script.PY":  "python",
+		"docker/Makefile": "makefile",
+		"notes.txt":       "",
+	}
+	for path, want := range tests {
+		if got := languageForPath(path); got != want {
+			t.Errorf("languageForPath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
 func TestFormatToolResult_Write(t *testing.T) {
 	content := "a\nb\n"
 	got := FormatToolResult("Write", "file.go", content, false)
@@ -67,6 +131,15 @@ func TestFormatToolResult_BashLong(t *testing.T) {
 	}
 }
 
+func TestFormatToolResult_BashPreviewLines(t *testing.T) {
+	p := DefaultProfile
+	p.PreviewLines = 8
+	got := p.FormatToolResult("Bash", "make", strings.Repeat("output line\n", 10), false)
+	if !strings.Contains(got, "… +2 lines") {
+		t.Errorf("should preview 8 lines, got %q", got)
+	}
+}
+
 func TestFormatToolResult_BashEmpty(t *testing.T) {
 	got := FormatToolResult("Bash", "go build", "", false)
 	if !strings.Contains(got, "⎿ (No output)") {
@@ -117,6 +190,21 @@ func TestFormatToolResult_Task(t *testing.T) {
 	}
 }
 
+func TestFormatToolResult_Skill(t *testing.T) {
+	got := FormatToolResult("Skill", "pdf report.pdf", "Launching skill: pdf", false)
+	if !strings.Contains(got, "**Skill**(pdf report.pdf)") || !strings.Contains(got, "Launching skill: pdf") {
+		t.Errorf("got %q", got)
+	}
+	if strings.Contains(got, ExpQuoteStart) {
+		t.Error("one-line result should not be quoted")
+	}
+
+	got = FormatToolResult("Skill", "pdf", "Launching skill: pdf\nExtracted 3 pages\n", false)
+	if !strings.Contains(got, ExpQuoteStart+"Extracted 3 pages"+ExpQuoteEnd) {
+		t.Errorf("got %q, want the rest of the result quoted", got)
+	}
+}
+
 func TestFormatToolResult_WebFetch(t *testing.T) {
 	content := "some html content here"
 	got := FormatToolResult("WebFetch", "https://example.com", content, false)
@@ -212,7 +300,7 @@ func TestFormatToolResult_EmptyContent(t *testing.T) {
 
 func TestFormatPreview(t *testing.T) {
 	lines := []string{"line1", "line2", "line3", "line4", "line5"}
-	got := formatPreview(lines, 5)
+	got := formatPreview(lines, 5, previewLines)
 	if !strings.Contains(got, "line1") {
 		t.Error("should include first line")
 	}
@@ -223,3 +311,17 @@ func TestFormatPreview(t *testing.T) {
 		t.Error("should not include line4")
 	}
 }
+
+func TestFormatTimestamp(t *testing.T) {
+	ts := time.Date(2025, 6, 1, 14, 32, 0, 0, time.UTC)
+	loc := time.FixedZone("UTC-3", -3*3600)
+	if got := FormatTimestamp(ts, loc); got != "11:32" {
+		t.Errorf("got %q, want 11:32", got)
+	}
+	if got := FormatTimestamp(ts, nil); got != "" {
+		t.Errorf("nil location should disable timestamps, got %q", got)
+	}
+	if got := FormatTimestamp(time.Time{}, loc); got != "" {
+		t.Errorf("zero time should produce no timestamp, got %q", got)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"regexp"
 	"strings"
+	"sync"
 
 	"github.com/yuin/goldmark"
 	"github.com/yuin/goldmark/extension"
@@ -40,7 +41,12 @@ func ToMarkdownV2(text string) string {
 			}
 			b.WriteString(renderExpandableQuote(seg.content))
 		} else {
-			b.WriteString(convertWithGoldmark(seg.content, false))
+			converted := convertWithGoldmark(seg.content, false)
+			// Text after a quote must not continue its last line
+			if converted != "" && strings.HasSuffix(b.String(), "||") {
+				b.WriteString("\n")
+			}
+			b.WriteString(converted)
 		}
 	}
 
@@ -144,62 +150,104 @@ func extractExpandableQuotes(text string) []segment {
 func renderExpandableQuote(content string) string {
 	// Truncate at 3800 chars to stay within Telegram limits
 	if len(content) > 3800 {
-		content = content[:3800] + "\n... (truncated)"
+		content = TruncateAtRune(content, 3800) + quoteCutMark
 	}
 
-	escaped := escapeMarkdownV2(content)
-	lines := strings.Split(escaped, "\n")
+	// Telegram can't nest pre blocks inside blockquotes, so fenced code
+	// lines are rendered as inline code and the fences themselves dropped.
 	var quoted []string
-	for i, line := range lines {
-		if i == len(lines)-1 {
-			quoted = append(quoted, ">"+line+"||")
+	inFence := false
+	for _, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(line, "```") {
+			inFence = !inFence
+			continue
+		}
+		if inFence && line != "" {
+			quoted = append(quoted, ">`"+escapeCodeContent(line)+"`")
 		} else {
-			quoted = append(quoted, ">"+line)
+			quoted = append(quoted, ">"+escapeMarkdownV2(line))
 		}
 	}
+	if len(quoted) == 0 {
+		quoted = append(quoted, ">")
+	}
+	quoted[len(quoted)-1] += "||"
 	return strings.Join(quoted, "\n")
 }
 
+// markdownExtensions is the shared extension set for every converter.
+var markdownExtensions = []goldmark.Extender{extension.GFM}
+
+// resettableRenderer is a node renderer whose per-document state can be cleared
+// so a converter can be reused.
+type resettableRenderer interface {
+	renderer.NodeRenderer
+	reset()
+}
+
+// converter is a goldmark pipeline bound to one node renderer instance.
+type converter struct {
+	md       goldmark.Markdown
+	renderer resettableRenderer
+	buf      bytes.Buffer
+}
+
+func newConverter(r resettableRenderer) *converter {
+	return &converter{
+		renderer: r,
+		md: goldmark.New(
+			goldmark.WithExtensions(markdownExtensions...),
+			goldmark.WithRenderer(
+				renderer.NewRenderer(
+					renderer.WithNodeRenderers(
+						// Priority 100: must beat GFM's HTML renderers (priority 500)
+						util.Prioritized(r, 100),
+					),
+				),
+			),
+		),
+	}
+}
+
+// Converters carry mutable renderer state, so each is used by one call at a time.
+var (
+	telegramConverters = sync.Pool{New: func() any { return newConverter(newTelegramRenderer()) }}
+	plainConverters    = sync.Pool{New: func() any { return newConverter(newPlainRenderer()) }}
+)
+
+// convert renders text, returning false if goldmark fails.
+func (c *converter) convert(text string) (string, bool) {
+	c.renderer.reset()
+	c.buf.Reset()
+	if err := c.md.Convert([]byte(text), &c.buf); err != nil {
+		return "", false
+	}
+	// Trim trailing newlines (goldmark always adds trailing paragraph newlines)
+	return strings.TrimRight(c.buf.String(), "\n"), true
+}
+
 // convertWithGoldmark parses text as CommonMark and renders it with the appropriate renderer.
-// A fresh goldmark instance is created per call (cheap, enables mutable renderer state).
+// Converters are pooled; renderer state is reset before each use.
 func convertWithGoldmark(text string, plain bool) string {
 	if text == "" {
 		return ""
 	}
 
-	var nodeRenderer renderer.NodeRenderer
+	pool := &telegramConverters
 	if plain {
-		nodeRenderer = newPlainRenderer()
-	} else {
-		nodeRenderer = newTelegramRenderer()
-	}
-
-	md := goldmark.New(
-		goldmark.WithExtensions(extension.GFM),
-		goldmark.WithRenderer(
-			renderer.NewRenderer(
-				renderer.WithNodeRenderers(
-					// Priority 100: must beat GFM's HTML renderers (priority 500)
-					util.Prioritized(nodeRenderer, 100),
-				),
-			),
-		),
-	)
+		pool = &plainConverters
+	}
+	c := pool.Get().(*converter)
+	result, ok := c.convert(text)
+	pool.Put(c)
 
-	var buf bytes.Buffer
-	if err := md.Convert([]byte(text), &buf); err != nil {
+	if !ok {
 		// Fallback: escape as plain text
 		if plain {
 			return text
 		}
 		return escapeMarkdownV2(text)
 	}
-
-	result := buf.String()
-
-	// Trim trailing newlines (goldmark always adds trailing paragraph newlines)
-	result = strings.TrimRight(result, "\n")
-
 	return result
 }
 
@@ -3,6 +3,8 @@ package render
 import (
 	"strings"
 	"testing"
+
+	"github.com/mattn/go-runewidth"
 )
 
 func TestToMarkdownV2_PlainText(t *testing.T) {
@@ -114,6 +116,48 @@ func TestToMarkdownV2_ExpandableQuote(t *testing.T) {
 	}
 }
 
+func TestToMarkdownV2_ExpandableQuoteCodeBlock(t *testing.T) {
+	input := ExpQuoteStart + "```go\nx := a.b\n```\n… +2 lines" + ExpQuoteEnd
+	got := ToMarkdownV2(input)
+	want := ">`x := a.b`\n>… \\+2 lines||"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestToMarkdownV2_AdjacentItalics(t *testing.T) {
+	got := ToMarkdownV2("*a*_b_")
+	if want := "_a_\r_b_"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestToMarkdownV2_ImageInLink(t *testing.T) {
+	got := ToMarkdownV2("[![badge](https://img.example/b.svg)](https://example.com)")
+	if want := "[badge](https://example.com)"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestToMarkdownV2_QuotedCodeAndTable(t *testing.T) {
+	got := ToMarkdownV2("> ```\n> x := 1\n> ```\n>\n> | a | b |\n> |---|---|\n> | `c` | d |")
+	for _, want := range []string{">`x := 1`\n", ">`| a | b | `\n>`| - | - | `\n>`| c | d | `"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("got %q, want it to contain %q", got, want)
+		}
+	}
+	if strings.Contains(got, "```") {
+		t.Errorf("pre block inside a quote: %q", got)
+	}
+}
+
+func TestToMarkdownV2_TableCellsEscaped(t *testing.T) {
+	got := ToMarkdownV2("| a |\n|---|\n| x\\`y |")
+	if !strings.HasSuffix(got, "\\`y | \n```") || checkMarkdownV2(got) != nil {
+		t.Errorf("backtick in a table cell not escaped: %q", got)
+	}
+}
+
 func TestToMarkdownV2_Heading(t *testing.T) {
 	got := ToMarkdownV2("# Title")
 	// Headings become bold
@@ -142,6 +186,30 @@ func TestToMarkdownV2_Table(t *testing.T) {
 	}
 }
 
+func TestToPlainText_TableWideChars(t *testing.T) {
+	input := "| Name | Note |\n|---|---|\n| 日本語 | ok |\n| 🚀 | go |\n| abcdef | x |"
+	got := ToPlainText(input)
+
+	// Every row's second column separator must line up in terminal cells.
+	var positions []int
+	for _, line := range strings.Split(got, "\n") {
+		if !strings.HasPrefix(line, "|") {
+			continue
+		}
+		idx := strings.Index(line[1:], "|") + 1
+		positions = append(positions, runewidth.StringWidth(line[:idx]))
+	}
+	if len(positions) < 4 {
+		t.Fatalf("expected table rows, got %q", got)
+	}
+	for _, p := range positions[1:] {
+		if p != positions[0] {
+			t.Errorf("misaligned columns %v in %q", positions, got)
+			break
+		}
+	}
+}
+
 func TestToMarkdownV2_UnorderedList(t *testing.T) {
 	input := "- item one\n- item two"
 	got := ToMarkdownV2(input)
@@ -150,6 +218,13 @@ func TestToMarkdownV2_UnorderedList(t *testing.T) {
 	}
 }
 
+func TestToMarkdownV2_TightListItemsOnOwnLines(t *testing.T) {
+	got := ToMarkdownV2("- item one\n- item two")
+	if want := "\\- item one\n\\- item two"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
 func TestToMarkdownV2_OrderedList(t *testing.T) {
 	input := "1. first\n2. second"
 	got := ToMarkdownV2(input)
@@ -394,3 +469,59 @@ func TestToPlainText_EmptyString(t *testing.T) {
 		t.Errorf("empty input should produce empty output: got %q", got)
 	}
 }
+
+func TestConvertWithGoldmark_PooledStateReset(t *testing.T) {
+	// A blockquote leaves renderer state behind if not reset; the next
+	// conversion on the same pooled converter must not inherit it.
+	c := newConverter(newTelegramRenderer())
+	c.renderer.(*telegramRenderer).blockquoteDepth = 3
+	got, ok := c.convert("plain line")
+	if !ok || strings.HasPrefix(got, ">") {
+		t.Errorf("convert after stale state = %q", got)
+	}
+	if got != convertWithGoldmark("plain line", false) {
+		t.Errorf("pooled and fresh output differ: %q", got)
+	}
+}
+
+// benchTranscript is a typical assistant message: prose, a list, inline code
+// and a fenced block.
+var benchTranscript = strings.Repeat(`I updated **internal/queue/queue.go** so that tasks are ordered per window.
+
+- Added `+"`taskStream`"+` with one task of lookahead
+- Merged content per _target_ before sending
+- See [the docs](https://example.com/docs) for details
+
+`+"```go\nfunc (q *Queue) worker(ch chan MessageTask) {\n\tfor task := range ch {\n\t\tq.processTask(task)\n\t}\n}\n```"+`
+
+Tests pass: `+"`go test ./...`"+` (0.42s).
+`, 3)
+
+func BenchmarkToMarkdownV2(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ToMarkdownV2(benchTranscript)
+	}
+}
+
+func BenchmarkToPlainText(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ToPlainText(benchTranscript)
+	}
+}
+
+// BenchmarkConvertUnpooled builds a new pipeline per call, as before pooling.
+func BenchmarkConvertUnpooled(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		newConverter(newTelegramRenderer()).convert(benchTranscript)
+	}
+}
+
+func BenchmarkConvertPooled(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		convertWithGoldmark(benchTranscript, false)
+	}
+}
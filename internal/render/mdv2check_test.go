@@ -0,0 +1,240 @@
+package render
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// checkMarkdownV2 parses s the way Telegram parses MarkdownV2 and returns the
+// error a send would fail with ("can't parse entities"), or nil. It is
+// stricter than Telegram where Telegram's behavior is unclear: code with a
+// line break may not sit in a blockquote, and an entity may not outlive the
+// blockquote it starts in.
+func checkMarkdownV2(s string) error {
+	var stack []string // open entities, innermost last
+	quote, lineStart := false, true
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		top := ""
+		if len(stack) > 0 {
+			top = stack[len(stack)-1]
+		}
+
+		if lineStart && top != "pre" && top != "code" && top != "url" {
+			lineStart = false
+			startsQuote := c == '>' || strings.HasPrefix(s[i:], "**>")
+			if quote && !startsQuote && len(stack) > 0 {
+				return fmt.Errorf("byte %d: %s entity crosses the end of a blockquote", i, top)
+			}
+			quote = startsQuote
+			if startsQuote {
+				if c == '*' {
+					i += 2
+				}
+				continue
+			}
+		}
+		lineStart = false
+
+		if c == '\\' {
+			if i+1 >= len(s) || s[i+1] == 0 || s[i+1] > 126 {
+				return fmt.Errorf("byte %d: character '\\' is reserved and must be escaped", i)
+			}
+			i++
+			continue
+		}
+		if c == '\n' {
+			if quote && (top == "pre" || top == "code") {
+				return fmt.Errorf("byte %d: %s entity spans lines of a blockquote", i, top)
+			}
+			if top == "url" {
+				return fmt.Errorf("byte %d: line break in a link URL", i)
+			}
+			lineStart = true
+			continue
+		}
+
+		switch top {
+		case "code":
+			if c == '`' {
+				stack = stack[:len(stack)-1]
+			}
+			continue
+		case "pre":
+			if strings.HasPrefix(s[i:], "```") {
+				stack = stack[:len(stack)-1]
+				i += 2
+			} else if c == '`' {
+				return fmt.Errorf("byte %d: character '`' is reserved in pre and must be escaped", i)
+			}
+			continue
+		case "url":
+			if c == ')' {
+				stack = stack[:len(stack)-1]
+			}
+			continue
+		}
+
+		toggle := func(kind string) {
+			if top == kind {
+				stack = stack[:len(stack)-1]
+			} else {
+				stack = append(stack, kind)
+			}
+		}
+		switch c {
+		case '`':
+			if strings.HasPrefix(s[i:], "```") {
+				stack = append(stack, "pre")
+				i += 2
+				if end := strings.IndexByte(s[i+1:], '\n'); end >= 0 {
+					i += end + 1 // language
+				}
+			} else {
+				stack = append(stack, "code")
+			}
+		case '_':
+			if i+1 < len(s) && s[i+1] == '_' {
+				i++
+				toggle("underline")
+			} else {
+				toggle("italic")
+			}
+		case '*':
+			toggle("bold")
+		case '~':
+			toggle("strikethrough")
+		case '|':
+			if i+1 >= len(s) || s[i+1] != '|' {
+				return fmt.Errorf("byte %d: character '|' is reserved and must be escaped", i)
+			}
+			i++
+			if quote && top != "spoiler" && (i+1 == len(s) || s[i+1] == '\n') {
+				continue // expandable blockquote mark
+			}
+			toggle("spoiler")
+		case '[':
+			if slices.Contains(stack, "link") {
+				return fmt.Errorf("byte %d: link inside a link", i)
+			}
+			stack = append(stack, "link")
+		case ']':
+			if top != "link" {
+				return fmt.Errorf("byte %d: character ']' is reserved and must be escaped", i)
+			}
+			if i+1 >= len(s) || s[i+1] != '(' {
+				return fmt.Errorf("byte %d: link without a URL", i)
+			}
+			i++
+			stack[len(stack)-1] = "url"
+		case '(', ')', '>', '#', '+', '-', '=', '{', '}', '.', '!':
+			return fmt.Errorf("byte %d: character '%c' is reserved and must be escaped", i, c)
+		}
+	}
+	if len(stack) > 0 {
+		return fmt.Errorf("can't find end of %s entity", stack[len(stack)-1])
+	}
+	return nil
+}
+
+func TestCheckMarkdownV2(t *testing.T) {
+	valid := []string{
+		"plain text",
+		`1\. item \- done\!`,
+		"*bold _italic_* ~strike~ ||spoiler|| __under__",
+		"[link *bold*](https://example.com/a\\)b)",
+		"`code with * and _`",
+		"```go\nfunc() { a.b() }\n```",
+		">quote line\n>second||",
+		"**>expandable\n>quote||\nafter",
+		"_a_\r_b_",
+	}
+	for _, s := range valid {
+		if err := checkMarkdownV2(s); err != nil {
+			t.Errorf("checkMarkdownV2(%q) = %v, want valid", s, err)
+		}
+	}
+
+	invalid := []string{
+		"1. item",
+		"a | b",
+		"*unclosed",
+		"_a__b_",
+		"[text]",
+		"[a [b](x)](y)",
+		"`unclosed",
+		"```\ncode ` here\n```",
+		">*bold\nafter*",
+		">```\ncode\n```",
+		`trailing \`,
+	}
+	for _, s := range invalid {
+		if err := checkMarkdownV2(s); err == nil {
+			t.Errorf("checkMarkdownV2(%q) = nil, want an error", s)
+		}
+	}
+}
+
+// mdv2Cases are Markdown inputs that have produced MarkdownV2 Telegram
+// rejects, or come close to it.
+var mdv2Cases = []string{
+	"See [**the docs**](https://example.com/docs) and [_this_ `file`](https://example.com/f).",
+	"Run `git log ~3..HEAD` or `a~b~c`.",
+	"> a | b | c\n> --- | --- | ---\n> 1 | 2 | 3",
+	"> | col | val |\n> |-----|-----|\n> | `x` | a\\b |",
+	"| a | b |\n|---|---|\n| `x` | y\\`z |",
+	"*a*_b_",
+	"_a_ _b_",
+	"***both***",
+	"**bold *italic* bold**",
+	"[![badge](https://img.shields.io/x.svg)](https://example.com)",
+	"[see <https://example.com>](https://example.com)",
+	"> quote with code:\n> ```\n> x := 1\n> ```",
+	"> - item one\n> - item two",
+	"1. first\n   > nested quote\n2. second",
+	"Text with ~~strike `code~` end~~ and ~tilde~.",
+	"<b>html</b> and <!-- comment -->",
+	"a  \nhard break",
+	"Escapes: \\* \\_ \\` \\\\ \\[x\\]",
+	"# Heading with **bold** and `code`",
+	"- [ ] todo\n- [x] done",
+	"Line one\n\n---\n\nLine two",
+	"Path C:\\Users\\me\\file.txt and regex \\d+",
+	"Email <me@example.com> and https://example.com/a_b_c",
+	"[link](https://example.com/a_(b)_c)",
+	"footnote[^1]\n\n[^1]: note",
+	"- > quote opening an item",
+	"> `code\n> span` across lines",
+	"> outer\n>> inner",
+	"# [**Title**](https://example.com) ~~old~~",
+	"_*_nested_*_ and *a* *b*",
+}
+
+func TestToMarkdownV2_ParsesAsTelegramDoes(t *testing.T) {
+	for _, md := range mdv2Cases {
+		out := ToMarkdownV2(md)
+		if err := checkMarkdownV2(out); err != nil {
+			t.Errorf("ToMarkdownV2(%q) = %q: %v", md, out, err)
+		}
+	}
+}
+
+func FuzzToMarkdownV2(f *testing.F) {
+	for _, md := range mdv2Cases {
+		f.Add(md)
+	}
+	f.Add("prefix\n" + ExpQuoteStart + "quoted ```\ncode`\n```\n| a |" + ExpQuoteEnd + "\n*after*")
+	f.Add(ExpQuoteStart + strings.Repeat("x", 3801) + ExpQuoteEnd)
+	f.Fuzz(func(t *testing.T, md string) {
+		if !utf8.ValidString(md) {
+			return // Claude's output is UTF-8
+		}
+		out := ToMarkdownV2(md)
+		if err := checkMarkdownV2(out); err != nil {
+			t.Errorf("ToMarkdownV2(%q) = %q: %v", md, out, err)
+		}
+	})
+}
@@ -0,0 +1,81 @@
+package render
+
+// Profile controls how much tool output is shown in formatted messages.
+type Profile struct {
+	Name             string
+	ReadPreviewLines int // file lines shown for Read results; 0 shows only the line count
+	PreviewLines     int // output lines shown for Bash and other results; 0 uses the default of 3
+	// Test output parsers tried on Bash results, in order (see
+	// RegisterTestParser); a recognized run is shown as a summary
+	TestParsers []string
+	// Digest cuts every result down to its one-line summary, for /lite mode
+	Digest bool
+}
+
+// Built-in verbosity profiles, selected via TRAMUNTANA_VERBOSITY.
+var profiles = map[string]Profile{
+	"compact": {Name: "compact", ReadPreviewLines: 0, TestParsers: DefaultTestParsers},
+	"normal":  {Name: "normal", ReadPreviewLines: 10, TestParsers: DefaultTestParsers},
+	"verbose": {Name: "verbose", ReadPreviewLines: 40, TestParsers: DefaultTestParsers},
+}
+
+// DefaultProfile is used when no verbosity profile is configured.
+var DefaultProfile = profiles["normal"]
+
+// LookupProfile returns the named verbosity profile.
+func LookupProfile(name string) (Profile, bool) {
+	p, ok := profiles[name]
+	return p, ok
+}
+
+// previewLines returns how many output lines a preview shows.
+func (p Profile) previewLines() int {
+	if p.PreviewLines > 0 {
+		return p.PreviewLines
+	}
+	return previewLines
+}
+
+// FormatToolResult formats a tool_result using this profile's preview settings.
+func (p Profile) FormatToolResult(toolName, toolInput, content string, isError bool) string {
+	header := "● " + toolHeader(toolName, toolInput)
+
+	if toolName == "Bash" {
+		if s, ok := p.parseTestOutput(content); ok {
+			body := s.String()
+			if isError && !p.Digest {
+				// Failing runs keep the output at hand
+				body += "\n" + formatExpandableQuote(truncateContent(content, 3000))
+			}
+			return header + "\n  ⎿ " + body
+		}
+	}
+
+	var body string
+	if isError {
+		body = formatErrorBody(content)
+	} else {
+		body = p.formatResultBody(toolName, toolInput, content)
+	}
+	if p.Digest {
+		body = digestBody(body)
+	}
+	return header + "\n  ⎿ " + body
+}
+
+// digestLen caps the characters of a digested result.
+const digestLen = 100
+
+// digestBody keeps the first line of a formatted result body, marking
+// anything cut with "…".
+func digestBody(body string) string {
+	first := firstLine(body)
+	cut := len(first) < len(body)
+	if r := []rune(first); len(r) > digestLen {
+		first, cut = string(r[:digestLen]), true
+	}
+	if cut {
+		first += " …"
+	}
+	return first
+}
@@ -6,10 +6,10 @@ import (
 	"image/color"
 	"image/draw"
 	"image/png"
-	"regexp"
 	"strconv"
 	"strings"
 
+	"github.com/mattn/go-runewidth"
 	"golang.org/x/image/font"
 	"golang.org/x/image/math/fixed"
 )
@@ -48,28 +48,69 @@ type styledRun struct {
 	Bold bool
 }
 
-var reANSI = regexp.MustCompile(`\x1b\[([0-9;]*)m`)
+// ScreenshotTheme is the palette used for default-colored text and background.
+type ScreenshotTheme struct {
+	BG color.RGBA
+	FG color.RGBA
+}
 
-const (
-	fontSize   = 28.0
-	lineHeight = 39 // int(fontSize * 1.4), matching CCBot
-	padding    = 16
-)
+// ScreenshotThemes are the built-in screenshot palettes.
+var ScreenshotThemes = map[string]ScreenshotTheme{
+	"dark":  {BG: defaultBG, FG: defaultFG},
+	"light": {BG: color.RGBA{250, 250, 250, 255}, FG: color.RGBA{36, 36, 36, 255}},
+}
+
+// ScreenshotOptions controls screenshot appearance.
+type ScreenshotOptions struct {
+	Theme      string  // key into ScreenshotThemes
+	FontSize   float64 // points
+	LineHeight int     // pixels; 0 derives it from FontSize
+	MaxCols    int     // wrap lines wider than this many cells; 0 disables wrapping
+}
+
+// DefaultScreenshotOptions matches CCBot's 28px dark rendering.
+var DefaultScreenshotOptions = ScreenshotOptions{
+	Theme:      "dark",
+	FontSize:   28,
+	LineHeight: 39, // int(fontSize * 1.4), matching CCBot
+}
+
+const padding = 16
 
-// RenderScreenshot renders ANSI terminal text to a PNG image.
+// RenderScreenshot renders ANSI terminal text to a PNG image using the default options.
 func RenderScreenshot(paneText string) ([]byte, error) {
+	return RenderScreenshotWithOptions(paneText, DefaultScreenshotOptions)
+}
+
+// RenderScreenshotWithOptions renders ANSI terminal text to a PNG image.
+func RenderScreenshotWithOptions(paneText string, opts ScreenshotOptions) ([]byte, error) {
+	fontSize := opts.FontSize
+	if fontSize <= 0 {
+		fontSize = DefaultScreenshotOptions.FontSize
+	}
+	lineHeight := opts.LineHeight
+	if lineHeight <= 0 {
+		lineHeight = int(fontSize * 1.4)
+	}
+	theme, ok := ScreenshotThemes[opts.Theme]
+	if !ok {
+		theme = ScreenshotThemes["dark"]
+	}
+
 	faces, err := newFaces(fontSize)
 	if err != nil {
 		return nil, err
 	}
 
-	lines := strings.Split(paneText, "\n")
-
-	// Parse each line into styled runs
+	// Replay the capture on a terminal grid so cursor movement, erases and
+	// DEC line drawing render the way tmux shows them.
 	var parsedLines [][]styledRun
-	for _, line := range lines {
-		runs := parseANSILine(line)
-		parsedLines = append(parsedLines, runs)
+	for _, runs := range emulateTerminal(paneText) {
+		if opts.MaxCols > 0 {
+			parsedLines = append(parsedLines, wrapRuns(runs, opts.MaxCols)...)
+		} else {
+			parsedLines = append(parsedLines, runs)
+		}
 	}
 
 	// Measure: find the widest line using the primary font's advance width.
@@ -78,14 +119,15 @@ func RenderScreenshot(paneText string) ([]byte, error) {
 	metrics := primaryFace.Metrics()
 	ascent := metrics.Ascent.Ceil()
 
-	// Measure char width from the primary face (monospace — all glyphs same width)
+	// Measure char width from the primary face (monospace — all glyphs same width).
+	// Line widths are counted in terminal cells, so wide runes count double.
 	charWidth := font.MeasureString(primaryFace, "M").Ceil()
 
 	maxCols := 0
 	for _, runs := range parsedLines {
 		cols := 0
 		for _, run := range runs {
-			cols += len([]rune(run.Text))
+			cols += runewidth.StringWidth(run.Text)
 		}
 		if cols > maxCols {
 			maxCols = cols
@@ -105,14 +147,20 @@ func RenderScreenshot(paneText string) ([]byte, error) {
 	img := image.NewRGBA(image.Rect(0, 0, imgWidth, imgHeight))
 
 	// Fill background using draw.Draw (faster than pixel loop for large images)
-	draw.Draw(img, img.Bounds(), image.NewUniform(defaultBG), image.Point{}, draw.Src)
+	draw.Draw(img, img.Bounds(), image.NewUniform(theme.BG), image.Point{}, draw.Src)
 
 	// Render text
 	for lineIdx, runs := range parsedLines {
 		x := padding
+		lastX := x
 		baseY := padding + lineIdx*lineHeight + ascent
 
 		for _, run := range runs {
+			fg := run.FG
+			if fg == defaultFG {
+				fg = theme.FG
+			}
+
 			// Split each styled run by font tier for fallback rendering
 			segments := splitByFontTier(run.Text)
 
@@ -120,21 +168,32 @@ func RenderScreenshot(paneText string) ([]byte, error) {
 				face := faces[seg.Tier]
 
 				for _, ch := range seg.Text {
+					// Wide (CJK, emoji) runes take two cells; combining marks
+					// take none and are drawn over the previous cell.
+					cells := runewidth.RuneWidth(ch)
+					drawX := x
+					if cells == 0 {
+						drawX = lastX
+					}
+
 					// Draw background rect if non-default
-					if run.BG != defaultBG {
-						bgRect := image.Rect(x, padding+lineIdx*lineHeight, x+charWidth, padding+(lineIdx+1)*lineHeight)
+					if run.BG != defaultBG && cells > 0 {
+						bgRect := image.Rect(x, padding+lineIdx*lineHeight, x+cells*charWidth, padding+(lineIdx+1)*lineHeight)
 						draw.Draw(img, bgRect, image.NewUniform(run.BG), image.Point{}, draw.Src)
 					}
 
 					// Draw character
 					d := &font.Drawer{
 						Dst:  img,
-						Src:  image.NewUniform(run.FG),
+						Src:  image.NewUniform(fg),
 						Face: face,
-						Dot:  fixed.P(x, baseY),
+						Dot:  fixed.P(drawX, baseY),
 					}
 					d.DrawString(string(ch))
-					x += charWidth
+					if cells > 0 {
+						lastX = x
+						x += cells * charWidth
+					}
 				}
 			}
 		}
@@ -148,45 +207,39 @@ func RenderScreenshot(paneText string) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
-// parseANSILine parses a line with ANSI escape sequences into styled runs.
-func parseANSILine(line string) []styledRun {
-	var runs []styledRun
-
-	fg := defaultFG
-	bg := defaultBG
-	bold := false
-
-	indices := reANSI.FindAllStringSubmatchIndex(line, -1)
-	lastEnd := 0
-
-	for _, loc := range indices {
-		// Text before this escape sequence
-		if loc[0] > lastEnd {
-			text := line[lastEnd:loc[0]]
-			if text != "" {
-				runs = append(runs, styledRun{Text: text, FG: fg, BG: bg, Bold: bold})
+// wrapRuns splits a line of styled runs into lines of at most maxCols cells.
+func wrapRuns(runs []styledRun, maxCols int) [][]styledRun {
+	var lines [][]styledRun
+	var current []styledRun
+	cols := 0
+
+	for _, run := range runs {
+		var b strings.Builder
+		for _, ch := range run.Text {
+			w := runewidth.RuneWidth(ch)
+			if cols+w > maxCols && cols > 0 {
+				if b.Len() > 0 {
+					current = append(current, styledRun{Text: b.String(), FG: run.FG, BG: run.BG, Bold: run.Bold})
+					b.Reset()
+				}
+				lines = append(lines, current)
+				current = nil
+				cols = 0
 			}
+			b.WriteRune(ch)
+			cols += w
 		}
-
-		// Parse the SGR parameters
-		params := line[loc[2]:loc[3]]
-		fg, bg, bold = applySGR(params, fg, bg, bold)
-		lastEnd = loc[1]
-	}
-
-	// Remaining text
-	if lastEnd < len(line) {
-		text := line[lastEnd:]
-		if text != "" {
-			runs = append(runs, styledRun{Text: text, FG: fg, BG: bg, Bold: bold})
+		if b.Len() > 0 || len(current) == 0 {
+			current = append(current, styledRun{Text: b.String(), FG: run.FG, BG: run.BG, Bold: run.Bold})
 		}
 	}
 
-	if len(runs) == 0 {
-		runs = append(runs, styledRun{Text: "", FG: fg, BG: bg})
-	}
+	return append(lines, current)
+}
 
-	return runs
+// parseANSILine parses a single line with ANSI escape sequences into styled runs.
+func parseANSILine(line string) []styledRun {
+	return emulateTerminal(line)[0]
 }
 
 // applySGR applies SGR (Select Graphic Rendition) parameters.
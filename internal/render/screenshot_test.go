@@ -294,3 +294,89 @@ func TestRenderScreenshot_ImageSize(t *testing.T) {
 		t.Errorf("image height %d is too small", bounds.Dy())
 	}
 }
+
+func TestRenderScreenshot_WideChars(t *testing.T) {
+	// 10 CJK/emoji runes occupy 20 cells, so the image must match a 20-column ASCII line.
+	render := func(text string) int {
+		data, err := RenderScreenshot(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return img.Bounds().Dx()
+	}
+
+	ascii := render(strings.Repeat("X", 20))
+	if got := render("日本語のテキスト🚀🎉"); got != ascii {
+		t.Errorf("wide line width = %d, want %d", got, ascii)
+	}
+	// Combining marks take no cell of their own.
+	if got := render(strings.Repeat("e\u0301", 20)); got != ascii {
+		t.Errorf("combining line width = %d, want %d", got, ascii)
+	}
+}
+
+func TestRenderScreenshotWithOptions_LightTheme(t *testing.T) {
+	opts := DefaultScreenshotOptions
+	opts.Theme = "light"
+	data, err := RenderScreenshotWithOptions("hello", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, g, b, _ := img.At(1, 1).RGBA()
+	want := ScreenshotThemes["light"].BG
+	if uint8(r>>8) != want.R || uint8(g>>8) != want.G || uint8(b>>8) != want.B {
+		t.Errorf("background = (%d,%d,%d), want %v", r>>8, g>>8, b>>8, want)
+	}
+}
+
+func TestRenderScreenshotWithOptions_Wrap(t *testing.T) {
+	size := func(text string, opts ScreenshotOptions) (int, int) {
+		data, err := RenderScreenshotWithOptions(text, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return img.Bounds().Dx(), img.Bounds().Dy()
+	}
+
+	opts := DefaultScreenshotOptions
+	opts.FontSize = 16
+	w1, h1 := size(strings.Repeat("X", 40), opts)
+
+	opts.MaxCols = 40
+	w2, h2 := size(strings.Repeat("X", 120), opts)
+	if w2 != w1 {
+		t.Errorf("wrapped width = %d, want %d", w2, w1)
+	}
+	if h2 <= h1 {
+		t.Errorf("wrapped height %d should exceed single-line height %d", h2, h1)
+	}
+}
+
+func TestWrapRuns(t *testing.T) {
+	runs := []styledRun{
+		{Text: "abc", FG: defaultFG, BG: defaultBG},
+		{Text: "日本", FG: ansi16Colors[1], BG: defaultBG},
+	}
+	lines := wrapRuns(runs, 4)
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d: %+v", len(lines), lines)
+	}
+	if lines[0][0].Text != "abc" || lines[1][0].Text != "日本" {
+		t.Errorf("unexpected wrap: %+v", lines)
+	}
+	if lines[1][0].FG != ansi16Colors[1] {
+		t.Error("wrapped run should keep its style")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/mattn/go-runewidth"
 	"github.com/yuin/goldmark/ast"
 	east "github.com/yuin/goldmark/extension/ast"
 	"github.com/yuin/goldmark/renderer"
@@ -16,16 +17,21 @@ type telegramRenderer struct {
 	blockquoteDepth int
 }
 
-func newTelegramRenderer() renderer.NodeRenderer {
+func newTelegramRenderer() *telegramRenderer {
 	return &telegramRenderer{}
 }
 
+func (r *telegramRenderer) reset() {
+	r.blockquoteDepth = 0
+}
+
 // RegisterFuncs registers render functions for each AST node kind.
 func (r *telegramRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
 	// Block nodes
 	reg.Register(ast.KindDocument, r.renderDocument)
 	reg.Register(ast.KindHeading, r.renderHeading)
 	reg.Register(ast.KindParagraph, r.renderParagraph)
+	reg.Register(ast.KindTextBlock, r.renderTextBlock)
 	reg.Register(ast.KindThematicBreak, r.renderThematicBreak)
 	reg.Register(ast.KindCodeBlock, r.renderCodeBlock)
 	reg.Register(ast.KindFencedCodeBlock, r.renderFencedCodeBlock)
@@ -81,9 +87,37 @@ func (r *telegramRenderer) renderParagraph(w util.BufWriter, source []byte, node
 	return ast.WalkContinue, nil
 }
 
+// renderTextBlock ends a tight list item's text, which has no paragraph.
+func (r *telegramRenderer) renderTextBlock(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		r.newline(w)
+	}
+	return ast.WalkContinue, nil
+}
+
+// newline ends a line, continuing the blockquote if inside one.
+func (r *telegramRenderer) newline(w util.BufWriter) {
+	if r.blockquoteDepth > 0 {
+		w.WriteString("\n>")
+	} else {
+		w.WriteString("\n")
+	}
+}
+
+// quotedCode writes code lines inside a blockquote as inline code, one per
+// quote line: Telegram can't nest pre blocks inside blockquotes.
+func (r *telegramRenderer) quotedCode(w util.BufWriter, lines []string) {
+	for _, line := range lines {
+		if line = strings.TrimRight(line, "\n"); line != "" {
+			w.WriteString("`" + escapeCodeContent(line) + "`")
+		}
+		r.newline(w)
+	}
+}
+
 func (r *telegramRenderer) renderThematicBreak(w util.BufWriter, _ []byte, _ ast.Node, entering bool) (ast.WalkStatus, error) {
 	if entering {
-		w.WriteString("\\—\\—\\—\n")
+		w.WriteString("———\n")
 	}
 	return ast.WalkContinue, nil
 }
@@ -102,6 +136,16 @@ func (r *telegramRenderer) renderCodeBlock(w util.BufWriter, source []byte, node
 }
 
 func (r *telegramRenderer) renderFencedCodeBlock(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering && r.blockquoteDepth > 0 {
+		var code []string
+		lines := node.Lines()
+		for i := 0; i < lines.Len(); i++ {
+			line := lines.At(i)
+			code = append(code, string(line.Value(source)))
+		}
+		r.quotedCode(w, code)
+		return ast.WalkSkipChildren, nil
+	}
 	if entering {
 		n := node.(*ast.FencedCodeBlock)
 		w.WriteString("```")
@@ -122,12 +166,21 @@ func (r *telegramRenderer) renderFencedCodeBlock(w util.BufWriter, source []byte
 }
 
 func (r *telegramRenderer) renderBlockquote(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	// Telegram has no nested quotes: an inner quote continues the outer one
 	if entering {
+		if r.blockquoteDepth == 0 {
+			// A quote opening a list item starts below its marker
+			if node.PreviousSibling() == nil && node.Parent().Kind() == ast.KindListItem {
+				w.WriteString("\n")
+			}
+			w.WriteString(">")
+		}
 		r.blockquoteDepth++
-		w.WriteString(">")
 	} else {
 		r.blockquoteDepth--
-		w.WriteString("\n")
+		if r.blockquoteDepth == 0 {
+			w.WriteString("\n")
+		}
 	}
 	return ast.WalkContinue, nil
 }
@@ -227,7 +280,8 @@ func (r *telegramRenderer) renderCodeSpan(w util.BufWriter, source []byte, node
 		// Collect raw text from children
 		for c := node.FirstChild(); c != nil; c = c.NextSibling() {
 			if t, ok := c.(*ast.Text); ok {
-				raw := string(t.Segment.Value(source))
+				// A line break in a code span is a space
+				raw := strings.ReplaceAll(string(t.Segment.Value(source)), "\n", " ")
 				w.WriteString(escapeCodeContent(raw))
 			}
 		}
@@ -241,13 +295,38 @@ func (r *telegramRenderer) renderEmphasis(w util.BufWriter, source []byte, node
 	n := node.(*ast.Emphasis)
 	if n.Level == 2 {
 		w.WriteString("*")
-	} else {
-		w.WriteString("_")
+		return ast.WalkContinue, nil
 	}
+	// Telegram reads "__" as underline, so italic marks that would touch are
+	// kept apart with a carriage return, which it ignores
+	if entering && (isItalic(node.PreviousSibling()) || isItalic(node.Parent()) && node.PreviousSibling() == nil) ||
+		!entering && isItalic(node.LastChild()) {
+		w.WriteString("\r")
+	}
+	w.WriteString("_")
 	return ast.WalkContinue, nil
 }
 
+// inLink reports whether node is inside a link's or image's text. Telegram
+// links can't nest, so only the outermost is rendered as one.
+func inLink(node ast.Node) bool {
+	for p := node.Parent(); p != nil; p = p.Parent() {
+		if p.Kind() == ast.KindLink || p.Kind() == ast.KindImage {
+			return true
+		}
+	}
+	return false
+}
+
+func isItalic(node ast.Node) bool {
+	e, ok := node.(*ast.Emphasis)
+	return ok && e.Level == 1
+}
+
 func (r *telegramRenderer) renderLink(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if inLink(node) {
+		return ast.WalkContinue, nil
+	}
 	if entering {
 		w.WriteString("[")
 	} else {
@@ -260,6 +339,9 @@ func (r *telegramRenderer) renderLink(w util.BufWriter, source []byte, node ast.
 }
 
 func (r *telegramRenderer) renderImage(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if inLink(node) {
+		return ast.WalkContinue, nil
+	}
 	if entering {
 		w.WriteString("[")
 	} else {
@@ -339,43 +421,53 @@ func (r *telegramRenderer) renderTable(w util.BufWriter, source []byte, node ast
 	colWidths := make([]int, numCols)
 	for _, row := range rows {
 		for i, cell := range row {
-			if len(cell) > colWidths[i] {
-				colWidths[i] = len(cell)
+			if width := runewidth.StringWidth(cell); width > colWidths[i] {
+				colWidths[i] = width
 			}
 		}
 	}
 
 	// Render as code block
-	w.WriteString("```\n")
+	var lines []string
 	for i, row := range rows {
-		w.WriteString("| ")
+		var line strings.Builder
+		line.WriteString("| ")
 		for j := 0; j < numCols; j++ {
 			cell := ""
 			if j < len(row) {
 				cell = row[j]
 			}
 			// Pad cell
-			padding := colWidths[j] - len(cell)
-			w.WriteString(cell)
+			padding := colWidths[j] - runewidth.StringWidth(cell)
+			line.WriteString(cell)
 			for p := 0; p < padding; p++ {
-				w.WriteString(" ")
+				line.WriteString(" ")
 			}
-			w.WriteString(" | ")
+			line.WriteString(" | ")
 		}
-		w.WriteString("\n")
+		lines = append(lines, line.String())
 
 		// Separator after header
 		if i == 0 && len(rows) > 1 {
-			w.WriteString("| ")
+			line.Reset()
+			line.WriteString("| ")
 			for j := 0; j < numCols; j++ {
 				for p := 0; p < colWidths[j]; p++ {
-					w.WriteString("-")
+					line.WriteString("-")
 				}
-				w.WriteString(" | ")
+				line.WriteString(" | ")
 			}
-			w.WriteString("\n")
+			lines = append(lines, line.String())
 		}
 	}
+	if r.blockquoteDepth > 0 {
+		r.quotedCode(w, lines)
+		return ast.WalkSkipChildren, nil
+	}
+	w.WriteString("```\n")
+	for _, line := range lines {
+		w.WriteString(escapeCodeContent(line) + "\n")
+	}
 	w.WriteString("```\n")
 
 	return ast.WalkSkipChildren, nil
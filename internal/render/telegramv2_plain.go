@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/mattn/go-runewidth"
 	"github.com/yuin/goldmark/ast"
 	east "github.com/yuin/goldmark/extension/ast"
 	"github.com/yuin/goldmark/renderer"
@@ -13,10 +14,12 @@ import (
 // plainRenderer renders goldmark AST nodes to plain text (no formatting markers).
 type plainRenderer struct{}
 
-func newPlainRenderer() renderer.NodeRenderer {
+func newPlainRenderer() *plainRenderer {
 	return &plainRenderer{}
 }
 
+func (r *plainRenderer) reset() {}
+
 func (r *plainRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
 	// Block nodes
 	reg.Register(ast.KindDocument, r.noop)
@@ -235,8 +238,8 @@ func (r *plainRenderer) renderTable(w util.BufWriter, source []byte, node ast.No
 	colWidths := make([]int, numCols)
 	for _, row := range rows {
 		for i, cell := range row {
-			if len(cell) > colWidths[i] {
-				colWidths[i] = len(cell)
+			if width := runewidth.StringWidth(cell); width > colWidths[i] {
+				colWidths[i] = width
 			}
 		}
 	}
@@ -248,7 +251,7 @@ func (r *plainRenderer) renderTable(w util.BufWriter, source []byte, node ast.No
 			if j < len(row) {
 				cell = row[j]
 			}
-			padding := colWidths[j] - len(cell)
+			padding := colWidths[j] - runewidth.StringWidth(cell)
 			w.WriteString(cell)
 			for p := 0; p < padding; p++ {
 				w.WriteString(" ")
@@ -0,0 +1,248 @@
+package render
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// maxListedFailures caps the failing test names shown in a test summary.
+const maxListedFailures = 10
+
+// TestSummary is a test run recognized in Bash output.
+type TestSummary struct {
+	Runner   string // e.g. "go test"
+	Unit     string // what Passed and Failed count when not tests, e.g. "packages"
+	Passed   int
+	Failed   int
+	Skipped  int
+	Failures []string // failing test names, in output order
+}
+
+// TestParser recognizes one test runner's output, reporting false for
+// anything else.
+type TestParser func(content string) (TestSummary, bool)
+
+// testParsers is the registry of test output parsers by name.
+var testParsers = map[string]TestParser{
+	"go":     parseGoTest,
+	"pytest": parsePytest,
+	"jest":   parseJest,
+	"cargo":  parseCargoTest,
+}
+
+// DefaultTestParsers are the parsers the built-in profiles try, in order.
+var DefaultTestParsers = []string{"go", "pytest", "jest", "cargo"}
+
+// RegisterTestParser adds or replaces a named test output parser. Profiles
+// use it once its name is in their TestParsers.
+func RegisterTestParser(name string, parse TestParser) {
+	testParsers[name] = parse
+}
+
+// HasTestParser reports whether a parser is registered under name.
+func HasTestParser(name string) bool {
+	_, ok := testParsers[name]
+	return ok
+}
+
+// parseTestOutput tries the profile's test parsers on Bash output in order.
+func (p Profile) parseTestOutput(content string) (TestSummary, bool) {
+	for _, name := range p.TestParsers {
+		if parse, ok := testParsers[name]; ok {
+			if s, ok := parse(content); ok {
+				return s, true
+			}
+		}
+	}
+	return TestSummary{}, false
+}
+
+// String renders the summary: a ✅/❌ line with the counts, then the failing
+// tests.
+func (s TestSummary) String() string {
+	var b strings.Builder
+	if s.Failed > 0 {
+		b.WriteString("❌ ")
+	} else {
+		b.WriteString("✅ ")
+	}
+	if s.Unit != "" {
+		fmt.Fprintf(&b, "%d %s passed", s.Passed, s.Unit)
+	} else {
+		fmt.Fprintf(&b, "%d passed", s.Passed)
+	}
+	if s.Failed > 0 {
+		fmt.Fprintf(&b, ", %d failed", s.Failed)
+	}
+	if s.Skipped > 0 {
+		fmt.Fprintf(&b, ", %d skipped", s.Skipped)
+	}
+	fmt.Fprintf(&b, " (%s)", s.Runner)
+
+	show := s.Failures
+	if len(show) > maxListedFailures {
+		show = show[:maxListedFailures]
+	}
+	for _, name := range show {
+		b.WriteString("\n     • " + name)
+	}
+	if remaining := len(s.Failures) - len(show); remaining > 0 {
+		fmt.Fprintf(&b, "\n     … +%d more", remaining)
+	}
+	return b.String()
+}
+
+var (
+	reGoTestResult  = regexp.MustCompile(`^\s*--- (PASS|FAIL|SKIP): (\S+)`)
+	reGoPackageOK   = regexp.MustCompile(`^ok\s+\S+\s+(\(cached\)|[\d.]+s)`)
+	reGoPackageFail = regexp.MustCompile(`^FAIL\s+\S+\s+([\d.]+s|\[)`)
+)
+
+// parseGoTest counts go test results. With -v the tests themselves are
+// counted; otherwise packages are, and failing tests come from the "--- FAIL"
+// lines go test always prints.
+func parseGoTest(content string) (TestSummary, bool) {
+	tests := TestSummary{Runner: "go test"}
+	pkgs := TestSummary{Runner: "go test", Unit: "packages"}
+	found := false
+	for _, line := range strings.Split(content, "\n") {
+		if m := reGoTestResult.FindStringSubmatch(line); m != nil {
+			found = true
+			switch m[1] {
+			case "PASS":
+				tests.Passed++
+			case "FAIL":
+				tests.Failed++
+				tests.Failures = append(tests.Failures, m[2])
+			case "SKIP":
+				tests.Skipped++
+			}
+			continue
+		}
+		switch {
+		case reGoPackageOK.MatchString(line):
+			found = true
+			pkgs.Passed++
+		case reGoPackageFail.MatchString(line):
+			found = true
+			pkgs.Failed++
+		}
+	}
+	if !found {
+		return TestSummary{}, false
+	}
+	if tests.Passed > 0 {
+		return tests, true
+	}
+	pkgs.Failures, pkgs.Skipped = tests.Failures, tests.Skipped
+	return pkgs, true
+}
+
+var (
+	rePytestSummary = regexp.MustCompile(`^=+ (.*\d+ (?:passed|failed|skipped|errors?)\b.*) in [\d.]+s.* =+$`)
+	rePytestCount   = regexp.MustCompile(`(\d+) (passed|failed|skipped|errors?)`)
+	rePytestFailed  = regexp.MustCompile(`^(?:FAILED|ERROR) (\S+)`)
+)
+
+// parsePytest reads pytest's final "== 2 failed, 12 passed in 0.5s ==" line
+// and the "FAILED <test>" lines of its short summary.
+func parsePytest(content string) (TestSummary, bool) {
+	s := TestSummary{Runner: "pytest"}
+	found := false
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if m := rePytestFailed.FindStringSubmatch(line); m != nil {
+			s.Failures = append(s.Failures, m[1])
+			continue
+		}
+		m := rePytestSummary.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		found = true
+		for _, c := range rePytestCount.FindAllStringSubmatch(m[1], -1) {
+			n, _ := strconv.Atoi(c[1])
+			switch c[2] {
+			case "passed":
+				s.Passed = n
+			case "failed", "error", "errors":
+				s.Failed += n
+			case "skipped":
+				s.Skipped = n
+			}
+		}
+	}
+	return s, found
+}
+
+var (
+	reJestTests   = regexp.MustCompile(`^Tests:\s+(.*\d+ total)`)
+	reJestCount   = regexp.MustCompile(`(\d+) (passed|failed|skipped|todo)`)
+	reJestFailure = regexp.MustCompile(`^\s*● (.+)$`)
+)
+
+// parseJest reads jest's "Tests: 2 failed, 12 passed, 14 total" line and the
+// "● Suite › test" headers of its failure reports.
+func parseJest(content string) (TestSummary, bool) {
+	s := TestSummary{Runner: "jest"}
+	found := false
+	for _, line := range strings.Split(content, "\n") {
+		if m := reJestFailure.FindStringSubmatch(line); m != nil {
+			if name := strings.TrimSpace(m[1]); !slices.Contains(s.Failures, name) {
+				s.Failures = append(s.Failures, name)
+			}
+			continue
+		}
+		m := reJestTests.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		found = true
+		for _, c := range reJestCount.FindAllStringSubmatch(m[1], -1) {
+			n, _ := strconv.Atoi(c[1])
+			switch c[2] {
+			case "passed":
+				s.Passed = n
+			case "failed":
+				s.Failed = n
+			case "skipped", "todo":
+				s.Skipped += n
+			}
+		}
+	}
+	return s, found
+}
+
+var (
+	reCargoResult = regexp.MustCompile(`^test result: \w+\. (\d+) passed; (\d+) failed; (\d+) ignored`)
+	reCargoFailed = regexp.MustCompile(`^test (\S+) \.\.\. FAILED`)
+)
+
+// parseCargoTest sums cargo's "test result:" lines, one per test binary, and
+// collects the "test <name> ... FAILED" lines.
+func parseCargoTest(content string) (TestSummary, bool) {
+	s := TestSummary{Runner: "cargo test"}
+	found := false
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if m := reCargoFailed.FindStringSubmatch(line); m != nil {
+			s.Failures = append(s.Failures, m[1])
+			continue
+		}
+		m := reCargoResult.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		found = true
+		passed, _ := strconv.Atoi(m[1])
+		failed, _ := strconv.Atoi(m[2])
+		ignored, _ := strconv.Atoi(m[3])
+		s.Passed += passed
+		s.Failed += failed
+		s.Skipped += ignored
+	}
+	return s, found
+}
@@ -0,0 +1,95 @@
+package render
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTestParsers(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    TestSummary
+	}{
+		{"go", "ok  \tgithub.com/x/api\t0.21s\n" +
+			"--- FAIL: TestLogin (0.00s)\n    --- FAIL: TestLogin/expired (0.00s)\n        auth_test.go:40: got 200\n" +
+			"FAIL\n" +
+			"FAIL\tgithub.com/x/auth\t0.12s\n" +
+			"ok  \tgithub.com/x/db\t(cached)\n" +
+			"?   \tgithub.com/x/cmd\t[no test files]",
+			TestSummary{Runner: "go test", Unit: "packages", Passed: 2, Failed: 1, Failures: []string{"TestLogin", "TestLogin/expired"}}},
+		{"go", "=== RUN   TestA\n--- PASS: TestA (0.00s)\n=== RUN   TestB\n--- SKIP: TestB (0.00s)\n--- PASS: TestC (0.01s)\nPASS\nok  \tgithub.com/x/api\t0.2s",
+			TestSummary{Runner: "go test", Passed: 2, Skipped: 1}},
+		{"pytest", "tests/test_api.py ..F.s\n" +
+			"=========================== short test summary info ============================\n" +
+			"FAILED tests/test_api.py::test_login - AssertionError: 401\n" +
+			"=================== 1 failed, 3 passed, 1 skipped in 0.53s ====================",
+			TestSummary{Runner: "pytest", Passed: 3, Failed: 1, Skipped: 1, Failures: []string{"tests/test_api.py::test_login"}}},
+		{"jest", "FAIL src/auth.test.js\n  ● auth › rejects expired tokens\n\n    expect(received).toBe(expected)\n\n" +
+			"Test Suites: 1 failed, 2 passed, 3 total\nTests:       1 failed, 1 skipped, 12 passed, 14 total\nTime:        1.2 s",
+			TestSummary{Runner: "jest", Passed: 12, Failed: 1, Skipped: 1, Failures: []string{"auth › rejects expired tokens"}}},
+		{"cargo", "running 3 tests\ntest parse::ok ... ok\ntest parse::bad ... FAILED\ntest slow ... ignored\n\n" +
+			"test result: FAILED. 1 passed; 1 failed; 1 ignored; 0 measured; 0 filtered out; finished in 0.01s\n\n" +
+			"running 2 tests\ntest result: ok. 2 passed; 0 failed; 0 ignored; 0 measured; 0 filtered out; finished in 0.00s",
+			TestSummary{Runner: "cargo test", Passed: 3, Failed: 1, Skipped: 1, Failures: []string{"parse::bad"}}},
+	}
+	for _, tt := range tests {
+		got, ok := testParsers[tt.name](tt.content)
+		if !ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %+v, %v; want %+v", tt.name, got, ok, tt.want)
+		}
+	}
+	for name, parse := range testParsers {
+		if _, ok := parse("total 8\ndrwxr-xr-x 2 me me 4096 main.go"); ok {
+			t.Errorf("%s recognized ls output", name)
+		}
+	}
+}
+
+func TestTestSummaryString(t *testing.T) {
+	s := TestSummary{Runner: "pytest", Passed: 3, Failed: 12}
+	for i := 0; i < 12; i++ {
+		s.Failures = append(s.Failures, "test_"+string(rune('a'+i)))
+	}
+	got := s.String()
+	if !strings.HasPrefix(got, "❌ 3 passed, 12 failed (pytest)\n     • test_a") || !strings.HasSuffix(got, "… +2 more") {
+		t.Errorf("got %q", got)
+	}
+	ok := TestSummary{Runner: "go test", Unit: "packages", Passed: 4}
+	if got := ok.String(); got != "✅ 4 packages passed (go test)" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestFormatToolResult_TestRun(t *testing.T) {
+	content := "--- FAIL: TestLogin (0.00s)\nFAIL\nFAIL\tgithub.com/x/auth\t0.12s"
+	got := FormatToolResult("Bash", "go test ./...", content, true)
+	if !strings.Contains(got, "⎿ ❌ 0 packages passed, 1 failed (go test)\n     • TestLogin") {
+		t.Errorf("missing summary in %q", got)
+	}
+	if !strings.Contains(got, ExpQuoteStart) {
+		t.Errorf("failing run should keep its output in a quote: %q", got)
+	}
+
+	p := DefaultProfile
+	p.TestParsers = nil
+	if got := p.FormatToolResult("Bash", "go test ./...", content, false); strings.Contains(got, "❌") {
+		t.Errorf("parsers disabled but got a summary: %q", got)
+	}
+}
+
+func TestRegisterTestParser(t *testing.T) {
+	RegisterTestParser("fake", func(content string) (TestSummary, bool) {
+		return TestSummary{Runner: "fake", Passed: 1}, strings.Contains(content, "FAKE OK")
+	})
+	defer delete(testParsers, "fake")
+
+	p := Profile{Name: "custom", TestParsers: []string{"fake"}}
+	if got := p.FormatToolResult("Bash", "./run", "FAKE OK", false); !strings.Contains(got, "✅ 1 passed (fake)") {
+		t.Errorf("got %q", got)
+	}
+	if !HasTestParser("fake") || HasTestParser("nope") {
+		t.Error("HasTestParser")
+	}
+}
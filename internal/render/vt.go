@@ -0,0 +1,329 @@
+package render
+
+import (
+	"image/color"
+	"strconv"
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// vtMaxCols bounds the columns cursor movement can reach; no pane worth a
+// screenshot is wider.
+const vtMaxCols = 500
+
+// vtCell is one cell of the emulated terminal grid.
+type vtCell struct {
+	text string // base rune plus any combining marks; "" for an untouched cell
+	fg   color.RGBA
+	bg   color.RGBA
+	bold bool
+	cont bool // right half of a wide rune
+}
+
+// vtGrid is a minimal terminal emulator: enough of VT100/xterm to replay
+// what tmux capture-pane emits (SGR, cursor movement, erases, DEC line art).
+// Lines never auto-wrap; the capture is already split at the pane width.
+type vtGrid struct {
+	rows     [][]vtCell
+	row, col int
+	height   int // screen rows: the capture's line count
+	fg, bg   color.RGBA
+	bold     bool
+
+	g0Graphics, g1Graphics bool // DEC special graphics designated to G0/G1
+	shifted                bool // SO active (G1 selected)
+
+	savedRow, savedCol int
+}
+
+// decGraphics maps DEC special graphics characters to their Unicode equivalents.
+var decGraphics = map[rune]rune{
+	'`': '◆', 'a': '▒', 'b': '␉', 'c': '␌', 'd': '␍', 'e': '␊', 'f': '°', 'g': '±',
+	'h': '␤', 'i': '␋', 'j': '┘', 'k': '┐', 'l': '┌', 'm': '└', 'n': '┼', 'o': '⎺',
+	'p': '⎻', 'q': '─', 'r': '⎼', 's': '⎽', 't': '├', 'u': '┤', 'v': '┴', 'w': '┬',
+	'x': '│', 'y': '≤', 'z': '≥', '{': 'π', '|': '≠', '}': '£', '~': '·',
+}
+
+// emulateTerminal replays captured pane output on a terminal grid and returns
+// one slice of styled runs per screen row.
+func emulateTerminal(text string) [][]styledRun {
+	g := &vtGrid{fg: defaultFG, bg: defaultBG, rows: [][]vtCell{nil}, height: strings.Count(text, "\n") + 1}
+	g.feed(text)
+
+	lines := make([][]styledRun, len(g.rows))
+	for i, row := range g.rows {
+		lines[i] = rowToRuns(row)
+	}
+	return lines
+}
+
+func (g *vtGrid) feed(text string) {
+	rs := []rune(text)
+	for i := 0; i < len(rs); i++ {
+		r := rs[i]
+		switch r {
+		case '\x1b':
+			i = g.escape(rs, i+1)
+		case '\r':
+			g.col = 0
+		case '\n':
+			g.moveTo(g.row+1, 0)
+		case '\b':
+			if g.col > 0 {
+				g.col--
+			}
+		case '\t':
+			g.col = (g.col/8 + 1) * 8
+		case '\x0e':
+			g.shifted = true
+		case '\x0f':
+			g.shifted = false
+		default:
+			if r < 0x20 || r == 0x7f {
+				continue // other control characters have no visible effect
+			}
+			g.put(r)
+		}
+	}
+}
+
+// escape parses an escape sequence starting after ESC and returns the index
+// of its last rune.
+func (g *vtGrid) escape(rs []rune, i int) int {
+	if i >= len(rs) {
+		return i
+	}
+	switch rs[i] {
+	case '[':
+		return g.csi(rs, i+1)
+	case ']', 'P', '_', '^':
+		// OSC/DCS/APC/PM: skip to BEL or ST (ESC \)
+		for j := i + 1; j < len(rs); j++ {
+			if rs[j] == '\a' {
+				return j
+			}
+			if rs[j] == '\x1b' && j+1 < len(rs) && rs[j+1] == '\\' {
+				return j + 1
+			}
+		}
+		return len(rs)
+	case '(', ')':
+		if i+1 < len(rs) {
+			graphics := rs[i+1] == '0'
+			if rs[i] == '(' {
+				g.g0Graphics = graphics
+			} else {
+				g.g1Graphics = graphics
+			}
+			return i + 1
+		}
+		return i
+	case '7':
+		g.savedRow, g.savedCol = g.row, g.col
+	case '8':
+		g.moveTo(g.savedRow, g.savedCol)
+	}
+	return i
+}
+
+// csi parses a control sequence starting after "ESC [" and applies it.
+func (g *vtGrid) csi(rs []rune, i int) int {
+	start := i
+	for i < len(rs) && (rs[i] < 0x40 || rs[i] > 0x7e) {
+		i++
+	}
+	if i >= len(rs) {
+		return len(rs)
+	}
+	params := string(rs[start:i])
+	final := rs[i]
+
+	if strings.HasPrefix(params, "?") || strings.HasPrefix(params, ">") {
+		return i // private modes (cursor visibility, etc.) don't affect the image
+	}
+
+	if final == 'm' {
+		g.fg, g.bg, g.bold = applySGR(params, g.fg, g.bg, g.bold)
+		return i
+	}
+
+	args := strings.Split(params, ";")
+	arg := func(n, def int) int {
+		if n < len(args) {
+			if v, err := strconv.Atoi(args[n]); err == nil && v > 0 {
+				return v
+			}
+		}
+		return def
+	}
+
+	switch final {
+	case 'A':
+		g.cursorTo(g.row-arg(0, 1), g.col)
+	case 'B', 'e':
+		g.cursorTo(g.row+arg(0, 1), g.col)
+	case 'C', 'a':
+		g.cursorTo(g.row, g.col+arg(0, 1))
+	case 'D':
+		g.cursorTo(g.row, g.col-arg(0, 1))
+	case 'E':
+		g.cursorTo(g.row+arg(0, 1), 0)
+	case 'F':
+		g.cursorTo(g.row-arg(0, 1), 0)
+	case 'G', '`':
+		g.cursorTo(g.row, arg(0, 1)-1)
+	case 'H', 'f':
+		g.cursorTo(arg(0, 1)-1, arg(1, 1)-1)
+	case 'd':
+		g.cursorTo(arg(0, 1)-1, g.col)
+	case 'K':
+		g.eraseLine(arg(0, 0))
+	case 'J':
+		g.eraseDisplay(arg(0, 0))
+	case 'X':
+		g.eraseCells(g.row, g.col, g.col+arg(0, 1))
+	}
+	return i
+}
+
+// cursorTo moves the cursor as a control sequence asks, clamped to the
+// screen, so a bogus "CSI 99999;99999H" can't grow the grid.
+func (g *vtGrid) cursorTo(row, col int) {
+	g.moveTo(min(max(row, 0), g.height-1), min(max(col, 0), vtMaxCols-1))
+}
+
+// moveTo positions the cursor, growing the grid as needed.
+func (g *vtGrid) moveTo(row, col int) {
+	for len(g.rows) <= row {
+		g.rows = append(g.rows, nil)
+	}
+	g.row, g.col = row, col
+}
+
+// put writes a printable rune at the cursor.
+func (g *vtGrid) put(r rune) {
+	if (g.shifted && g.g1Graphics) || (!g.shifted && g.g0Graphics) {
+		if mapped, ok := decGraphics[r]; ok {
+			r = mapped
+		}
+	}
+
+	w := runewidth.RuneWidth(r)
+	if w == 0 {
+		// Combining mark: attach to the previous cell.
+		row := g.rows[g.row]
+		for c := min(g.col, len(row)) - 1; c >= 0; c-- {
+			if !row[c].cont && row[c].text != "" {
+				row[c].text += string(r)
+				return
+			}
+		}
+		return
+	}
+
+	g.ensureCols(g.row, g.col+w)
+	row := g.rows[g.row]
+
+	// Overwriting half of a wide rune blanks the other half.
+	if row[g.col].cont && g.col > 0 {
+		row[g.col-1] = vtCell{fg: row[g.col-1].fg, bg: row[g.col-1].bg}
+	}
+	if end := g.col + w; end < len(row) && row[end].cont {
+		row[end] = vtCell{fg: row[end].fg, bg: row[end].bg}
+	}
+
+	row[g.col] = vtCell{text: string(r), fg: g.fg, bg: g.bg, bold: g.bold}
+	if w == 2 {
+		row[g.col+1] = vtCell{fg: g.fg, bg: g.bg, bold: g.bold, cont: true}
+	}
+	g.col += w
+}
+
+// ensureCols grows a row to at least n cells.
+func (g *vtGrid) ensureCols(row, n int) {
+	for len(g.rows[row]) < n {
+		g.rows[row] = append(g.rows[row], vtCell{fg: defaultFG, bg: defaultBG})
+	}
+}
+
+// eraseCells blanks cells [from, to) on a row using the current background.
+func (g *vtGrid) eraseCells(row, from, to int) {
+	cells := g.rows[row]
+	to = min(to, len(cells))
+	for c := from; c < to; c++ {
+		cells[c] = vtCell{fg: g.fg, bg: g.bg}
+	}
+	if g.bg == defaultBG && to == len(cells) {
+		// Trailing default blanks are indistinguishable from no cells at all.
+		g.rows[row] = cells[:min(from, len(cells))]
+	}
+}
+
+func (g *vtGrid) eraseLine(mode int) {
+	switch mode {
+	case 0:
+		g.eraseCells(g.row, g.col, len(g.rows[g.row]))
+	case 1:
+		g.eraseCells(g.row, 0, g.col+1)
+	case 2:
+		g.eraseCells(g.row, 0, len(g.rows[g.row]))
+	}
+}
+
+func (g *vtGrid) eraseDisplay(mode int) {
+	switch mode {
+	case 0:
+		g.eraseLine(0)
+		for r := g.row + 1; r < len(g.rows); r++ {
+			g.rows[r] = nil
+		}
+	case 1:
+		for r := 0; r < g.row; r++ {
+			g.rows[r] = nil
+		}
+		g.eraseLine(1)
+	case 2, 3:
+		for r := range g.rows {
+			g.rows[r] = nil
+		}
+	}
+}
+
+// rowToRuns groups a row's cells into runs of identical style.
+func rowToRuns(row []vtCell) []styledRun {
+	var runs []styledRun
+	var b strings.Builder
+	var cur styledRun
+
+	flush := func() {
+		if b.Len() > 0 {
+			cur.Text = b.String()
+			runs = append(runs, cur)
+			b.Reset()
+		}
+	}
+
+	for _, c := range row {
+		if c.cont {
+			continue
+		}
+		text := c.text
+		if text == "" {
+			text = " "
+		}
+		if b.Len() > 0 && (c.fg != cur.FG || c.bg != cur.BG || c.bold != cur.Bold) {
+			flush()
+		}
+		if b.Len() == 0 {
+			cur = styledRun{FG: c.fg, BG: c.bg, Bold: c.bold}
+		}
+		b.WriteString(text)
+	}
+	flush()
+
+	if len(runs) == 0 {
+		runs = append(runs, styledRun{Text: "", FG: defaultFG, BG: defaultBG})
+	}
+	return runs
+}
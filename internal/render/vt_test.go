@@ -0,0 +1,93 @@
+package render
+
+import (
+	"strings"
+	"testing"
+)
+
+// plainRows flattens emulated rows to their text, trimming trailing spaces.
+func plainRows(text string) []string {
+	var out []string
+	for _, runs := range emulateTerminal(text) {
+		var b strings.Builder
+		for _, r := range runs {
+			b.WriteString(r.Text)
+		}
+		out = append(out, strings.TrimRight(b.String(), " "))
+	}
+	return out
+}
+
+func TestEmulateTerminal_CarriageReturn(t *testing.T) {
+	got := plainRows("progress 10%\rprogress 99%")
+	if got[0] != "progress 99%" {
+		t.Errorf("got %q", got[0])
+	}
+}
+
+func TestEmulateTerminal_CursorMovement(t *testing.T) {
+	got := plainRows("abc\x1b[2Dz\n\n\x1b[3;5Hq")
+	if len(got) != 3 {
+		t.Fatalf("expected 3 rows, got %q", got)
+	}
+	if got[0] != "azc" {
+		t.Errorf("row 0 = %q, want azc", got[0])
+	}
+	if got[2] != "    q" {
+		t.Errorf("row 2 = %q, want '    q'", got[2])
+	}
+}
+
+func TestEmulateTerminal_ClampsCursorToScreen(t *testing.T) {
+	got := plainRows("ab\ncd\x1b[999;999Hx")
+	if len(got) != 2 {
+		t.Fatalf("expected 2 rows, got %d", len(got))
+	}
+	if want := "cd" + strings.Repeat(" ", vtMaxCols-3) + "x"; got[1] != want {
+		t.Errorf("row 1 is %d cells, want x in the last column", len(got[1]))
+	}
+}
+
+func TestEmulateTerminal_EraseLine(t *testing.T) {
+	got := plainRows("hello world\x1b[6G\x1b[K")
+	if got[0] != "hello" {
+		t.Errorf("got %q, want hello", got[0])
+	}
+}
+
+func TestEmulateTerminal_DECLineDrawing(t *testing.T) {
+	got := plainRows("\x1b(0lqqk\x1b(B ok")
+	if got[0] != "┌──┐ ok" {
+		t.Errorf("got %q", got[0])
+	}
+}
+
+func TestEmulateTerminal_StripsOSC(t *testing.T) {
+	got := plainRows("\x1b]0;window title\x07visible")
+	if got[0] != "visible" {
+		t.Errorf("got %q", got[0])
+	}
+}
+
+func TestEmulateTerminal_WideAndCombining(t *testing.T) {
+	// Moving back one cell lands on the right half of 本; overwriting it
+	// blanks the left half, and the combining accent joins the new "e".
+	got := plainRows("日本\x1b[1De\u0301")
+	if got[0] != "日 e\u0301" {
+		t.Errorf("got %q", got[0])
+	}
+}
+
+func TestEmulateTerminal_SGRRuns(t *testing.T) {
+	rows := emulateTerminal("\x1b[31mred\x1b[0m plain")
+	runs := rows[0]
+	if len(runs) != 2 {
+		t.Fatalf("expected 2 runs, got %+v", runs)
+	}
+	if runs[0].Text != "red" || runs[0].FG != ansi16Colors[1] {
+		t.Errorf("first run = %+v", runs[0])
+	}
+	if runs[1].FG != defaultFG {
+		t.Errorf("second run FG = %v, want default", runs[1].FG)
+	}
+}
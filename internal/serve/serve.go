@@ -0,0 +1,209 @@
+// Package serve runs the Telegram ↔ Claude Code bridge: the bot, session
+// monitor, status poller and digests that `tramuntana serve` starts. The
+// public pkg/bridge wraps it.
+package serve
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+	"sync/atomic"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/bot"
+	"github.com/otaviocarvalho/tramuntana/internal/config"
+	"github.com/otaviocarvalho/tramuntana/internal/control"
+	"github.com/otaviocarvalho/tramuntana/internal/events"
+	"github.com/otaviocarvalho/tramuntana/internal/monitor"
+	"github.com/otaviocarvalho/tramuntana/internal/queue"
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+	"github.com/otaviocarvalho/tramuntana/internal/supervise"
+	"github.com/otaviocarvalho/tramuntana/internal/tmux"
+	"github.com/otaviocarvalho/tramuntana/internal/webhook"
+)
+
+// Session is a tmux window bound to at least one Telegram topic.
+type Session struct {
+	WindowID  string // tmux window ID, e.g. "@12"
+	Name      string
+	CWD       string
+	SessionID string // Claude session ID; empty until the hook reports it
+}
+
+// Bridge is a running (or ready to run) bridge.
+type Bridge struct {
+	cfg *config.Config
+	bot *bot.Bot
+}
+
+// New creates a bridge that talks to the Telegram Bot API with cfg's token.
+func New(cfg *config.Config) (*Bridge, error) {
+	b, err := bot.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &Bridge{cfg: cfg, bot: b}, nil
+}
+
+// NewWithAPI creates a bridge over an existing Bot API client, e.g. one
+// pointed at a test server.
+func NewWithAPI(cfg *config.Config, api *tgbotapi.BotAPI) (*Bridge, error) {
+	b, err := bot.NewWithAPI(cfg, api)
+	if err != nil {
+		return nil, err
+	}
+	return &Bridge{cfg: cfg, bot: b}, nil
+}
+
+// API returns the Bot API client, so callers can wrap its HTTP client before Run.
+func (br *Bridge) API() *tgbotapi.BotAPI {
+	return br.bot.API()
+}
+
+// Run starts the bridge and blocks until ctx is cancelled, then saves its state.
+func (br *Bridge) Run(ctx context.Context) error {
+	cfg, b := br.cfg, br.bot
+
+	// Fail before anything starts, so no goroutine outlives the error
+	var sources map[string]*webhook.Source
+	if cfg.WebhookAddr != "" {
+		var err error
+		sources, err = webhook.LoadSources(filepath.Join(cfg.ConfigDir, webhook.File))
+		if err != nil {
+			return fmt.Errorf("loading webhook sources: %w", err)
+		}
+	}
+
+	// Load monitor state
+	msPath := filepath.Join(cfg.TramuntanaDir, "monitor_state.json")
+	ms, err := state.LoadMonitorState(msPath)
+	if err != nil {
+		log.Printf("Warning: loading monitor state: %v (starting fresh)", err)
+		ms = state.NewMonitorState()
+	}
+	b.SetMonitorState(ms)
+
+	// Load usage tracker for daily digests
+	usagePath := filepath.Join(cfg.TramuntanaDir, "usage.json")
+	usage, err := state.LoadUsage(usagePath)
+	if err != nil {
+		log.Printf("Warning: loading usage: %v (starting fresh)", err)
+		usage = state.NewUsage()
+	}
+	b.SetUsage(usage)
+
+	// Route tmux commands over one control-mode client instead of a process each
+	if cfg.TmuxControlMode {
+		tmux.EnableControlMode(cfg.TmuxSessionName)
+		defer tmux.DisableControlMode()
+	}
+
+	// Startup recovery: reconcile state with live tmux windows
+	liveBindings := b.ReconcileState()
+	log.Printf("Startup: %d live bindings recovered", liveBindings)
+
+	// Create message queue
+	q := queue.New(b.API())
+	b.SetQueue(q)
+
+	// Event bus connecting the monitor, status poller and bot
+	bus := events.New()
+	b.SetEventBus(bus)
+
+	// Session monitor and status poller, restarted by the watchdog if they
+	// panic or stop polling; each run gets a fresh instance, and a monitor
+	// picks up the notifications where the one it replaces stopped
+	var lastMonitor atomic.Pointer[monitor.Monitor]
+	monitorTask := supervise.Task{Name: "session monitor", Stall: cfg.WatchdogStall,
+		Start: func(ctx context.Context, hb *supervise.Heartbeat) {
+			mon := monitor.New(cfg, b.State(), ms, q)
+			if prev := lastMonitor.Swap(mon); prev != nil {
+				mon.TakeOver(prev)
+			}
+			mon.PlanHandler = b.HandlePlanFromMonitor
+			mon.Usage = usage
+			mon.ActivityHandler = b.HandleMonitorActivity
+			mon.Events = bus
+			mon.Heartbeat = hb
+			mon.Run(ctx)
+		}}
+	sp := bot.NewStatusPoller(b, q, bus)
+	pollerTask := supervise.Task{Name: "status poller", Stall: cfg.WatchdogStall,
+		Start: func(ctx context.Context, hb *supervise.Heartbeat) {
+			sp.Fresh(hb).Run(ctx)
+		}}
+	ds := bot.NewDigestScheduler(b, usage)
+
+	go supervise.Run(ctx, monitorTask, b.ReportRestart)
+	go b.RunEventHandlers(ctx)
+	go supervise.Run(ctx, pollerTask, b.ReportRestart)
+	go ds.Run(ctx)
+
+	// Local automation API
+	if cfg.ControlAddr != "" {
+		go func() {
+			if err := control.Serve(ctx, cfg.ControlAddr, cfg.ControlToken, b.ControlBackend()); err != nil {
+				log.Printf("Error: %v", err)
+			}
+		}()
+	}
+
+	// Notifications from external systems into mapped topics
+	if cfg.WebhookAddr != "" {
+		notify := func(chatID int64, threadID int, text string) error {
+			_, err := b.ControlBackend().Notify(control.Notification{ChatID: chatID, ThreadID: threadID, Text: text})
+			return err
+		}
+		go func() {
+			if err := webhook.Serve(ctx, cfg.WebhookAddr, sources, notify); err != nil {
+				log.Printf("Error: %v", err)
+			}
+		}()
+	}
+
+	// Run bot (blocks until ctx is cancelled)
+	err = b.Run(ctx)
+
+	// Graceful shutdown: save all state
+	log.Println("Saving state...")
+	if err := ms.ForceSave(msPath); err != nil {
+		log.Printf("Error saving monitor state: %v", err)
+	}
+	if err := usage.SaveIfDirty(usagePath); err != nil {
+		log.Printf("Error saving usage: %v", err)
+	}
+
+	return err
+}
+
+// Sessions lists the windows bound to topics, by window ID.
+func (br *Bridge) Sessions() []Session {
+	st := br.bot.State()
+	var sessions []Session
+	for windowID := range st.AllBoundWindowIDs() {
+		ws, _ := st.GetWindowState(windowID)
+		name := ws.WindowName
+		if display, ok := st.GetWindowDisplayName(windowID); ok {
+			name = display
+		}
+		sessions = append(sessions, Session{WindowID: windowID, Name: name, CWD: ws.CWD, SessionID: ws.SessionID})
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].WindowID < sessions[j].WindowID })
+	return sessions
+}
+
+// Send types text into a session's window as if it came from its topic.
+// Long text follows the configured long-paste mode; it fails while paused.
+func (br *Bridge) Send(windowID, text string) error {
+	if err := br.bot.SendText(windowID, text); err != nil {
+		return fmt.Errorf("sending to %s: %w", windowID, err)
+	}
+	return nil
+}
+
+// Capture returns the visible text of a session's window.
+func (br *Bridge) Capture(windowID string) (string, error) {
+	return tmux.CapturePane(br.cfg.TmuxSessionName, windowID, false)
+}
@@ -0,0 +1,101 @@
+package serve
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/otaviocarvalho/tramuntana/internal/config"
+	"github.com/otaviocarvalho/tramuntana/internal/render"
+	"github.com/otaviocarvalho/tramuntana/internal/testharness"
+)
+
+func TestBridge_RunSendAndSave(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	tg := testharness.NewTelegram(t)
+	fake := testharness.NewTmux(t, "tramuntana-test")
+	windowID := fake.AddWindow("api", "/work/api")
+
+	cfg := &config.Config{
+		AllowedUsers:        []int64{100},
+		TramuntanaDir:       filepath.Join(home, ".tramuntana"),
+		TmuxSessionName:     "tramuntana-test",
+		ClaudeCommand:       "claude",
+		MonitorPollInterval: 0.05,
+		Verbosity:           render.DefaultProfile.Name,
+		BootstrapPolicy:     "eof",
+	}
+	os.MkdirAll(cfg.TramuntanaDir, 0o755)
+	br, err := NewWithAPI(cfg, tg.API())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- br.Run(ctx) }()
+
+	if err := br.Send(windowID, "hello from the supervisor"); err != nil {
+		t.Fatal(err)
+	}
+	fake.WaitForKeys(windowID, "hello from the supervisor")
+	if sessions := br.Sessions(); len(sessions) != 0 {
+		t.Errorf("sessions = %+v, want none bound", sessions)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after cancel")
+	}
+	if _, err := os.Stat(filepath.Join(cfg.TramuntanaDir, "monitor_state.json")); err != nil {
+		t.Errorf("monitor state not saved: %v", err)
+	}
+}
+
+func TestBridge_BadWebhookSourcesFailBeforeStarting(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	tg := testharness.NewTelegram(t)
+	testharness.NewTmux(t, "tramuntana-test")
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	controlAddr := l.Addr().String()
+	l.Close()
+
+	cfg := &config.Config{
+		AllowedUsers:        []int64{100},
+		TramuntanaDir:       filepath.Join(home, ".tramuntana"),
+		ConfigDir:           filepath.Join(home, ".tramuntana"),
+		TmuxSessionName:     "tramuntana-test",
+		MonitorPollInterval: 0.05,
+		Verbosity:           render.DefaultProfile.Name,
+		BootstrapPolicy:     "eof",
+		WebhookAddr:         "127.0.0.1:0",
+		ControlAddr:         controlAddr,
+		ControlToken:        "secret",
+	}
+	os.MkdirAll(cfg.TramuntanaDir, 0o755)
+	os.WriteFile(filepath.Join(cfg.ConfigDir, "webhooks.json"), []byte("{"), 0o600)
+	br, err := NewWithAPI(cfg, tg.API())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := br.Run(context.Background()); err == nil || !strings.Contains(err.Error(), "loading webhook sources") {
+		t.Fatalf("Run = %v, want the webhook sources error", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if conn, err := net.Dial("tcp", controlAddr); err == nil {
+		conn.Close()
+		t.Error("control API started although Run failed")
+	}
+}
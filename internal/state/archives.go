@@ -0,0 +1,81 @@
+package state
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sort"
+	"time"
+)
+
+// ArchivedSession records a session transcript archived when the session
+// died or its topic closed. Records outlive the topic's other settings, since
+// the archive itself is kept.
+type ArchivedSession struct {
+	Name       string    `json:"name"` // where the archive is stored, see archive.Store
+	SessionID  string    `json:"session_id"`
+	WindowName string    `json:"window_name,omitempty"`
+	CWD        string    `json:"cwd,omitempty"`
+	ThreadIDs  []string  `json:"thread_ids"`
+	Reason     string    `json:"reason"` // "died" or "closed"
+	Entries    int       `json:"entries"`
+	Bytes      int64     `json:"bytes"` // transcript size before compression
+	StartedAt  time.Time `json:"started_at,omitempty"`
+	ArchivedAt time.Time `json:"archived_at"`
+}
+
+// archivesMax caps the archive records kept; the oldest are dropped first.
+// Their archives stay in the store.
+const archivesMax = 500
+
+// AddArchive stores a under a new short ID and returns it, dropping the
+// oldest records beyond archivesMax.
+func (s *State) AddArchive(a ArchivedSession) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for len(s.Archives) >= archivesMax {
+		oldest := ""
+		for id, r := range s.Archives {
+			if oldest == "" || r.ArchivedAt.Before(s.Archives[oldest].ArchivedAt) {
+				oldest = id
+			}
+		}
+		delete(s.Archives, oldest)
+	}
+	for {
+		buf := make([]byte, 4)
+		rand.Read(buf)
+		id := hex.EncodeToString(buf)
+		if _, taken := s.Archives[id]; !taken {
+			s.Archives[id] = a
+			return id
+		}
+	}
+}
+
+// GetArchive returns an archived session by ID.
+func (s *State) GetArchive(id string) (ArchivedSession, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	a, ok := s.Archives[id]
+	return a, ok
+}
+
+// ArchivesForThread returns the IDs of a thread's archived sessions, newest
+// first.
+func (s *State) ArchivesForThread(threadID string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var ids []string
+	for id, a := range s.Archives {
+		for _, t := range a.ThreadIDs {
+			if t == threadID {
+				ids = append(ids, id)
+				break
+			}
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return s.Archives[ids[i]].ArchivedAt.After(s.Archives[ids[j]].ArchivedAt)
+	})
+	return ids
+}
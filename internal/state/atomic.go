@@ -3,6 +3,7 @@ package state
 import (
 	"encoding/json"
 	"fmt"
+	"log"
 	"math/rand"
 	"os"
 	"path/filepath"
@@ -65,3 +66,19 @@ func loadJSON(path string, dest any) error {
 	}
 	return nil
 }
+
+// rotateBackups shifts path.1 to path.n-1 up by one and copies path to path.1,
+// keeping the n most recent versions. A current file that isn't valid JSON is
+// not backed up, so backups only hold loadable data.
+func rotateBackups(path string, n int) {
+	data, err := os.ReadFile(path)
+	if err != nil || !json.Valid(data) {
+		return
+	}
+	for i := n - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", path, i), fmt.Sprintf("%s.%d", path, i+1))
+	}
+	if err := os.WriteFile(path+".1", data, 0o644); err != nil {
+		log.Printf("Warning: backing up %s: %v", path, err)
+	}
+}
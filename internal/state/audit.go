@@ -0,0 +1,35 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// AuditEntry records one prompt forwarded to a Claude window.
+type AuditEntry struct {
+	Time     time.Time `json:"time"`
+	UserID   int64     `json:"user_id"`
+	User     string    `json:"user"`
+	ThreadID string    `json:"thread_id"`
+	WindowID string    `json:"window_id"`
+	Text     string    `json:"text"`
+}
+
+// AppendAudit appends an entry as one JSON line to the audit log at path.
+func AppendAudit(path string, e AuditEntry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("encoding audit entry: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("opening audit log: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("writing audit log: %w", err)
+	}
+	return nil
+}
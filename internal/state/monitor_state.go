@@ -2,8 +2,12 @@ package state
 
 import (
 	"sync"
+	"time"
 )
 
+// maxDeliveredDigests caps the delivered-line digests kept per session.
+const maxDeliveredDigests = 1000
+
 // TrackedSession tracks byte offset for a JSONL session file.
 type TrackedSession struct {
 	SessionID      string `json:"session_id"`
@@ -11,17 +15,34 @@ type TrackedSession struct {
 	LastByteOffset int64  `json:"last_byte_offset"`
 }
 
+// DeliveredLines remembers the transcript lines recently delivered for a
+// session, so a line read again after an offset reset isn't sent twice.
+type DeliveredLines struct {
+	Digests    []LineDigest `json:"digests"`              // oldest first
+	Duplicates int64        `json:"duplicates,omitempty"` // lines skipped as already delivered
+}
+
+// LineDigest is the hash of a delivered line and when it was delivered.
+type LineDigest struct {
+	Hash string    `json:"hash"`
+	At   time.Time `json:"at"`
+}
+
 // MonitorState tracks all monitored sessions with byte offsets.
 type MonitorState struct {
 	mu              sync.Mutex
 	TrackedSessions map[string]TrackedSession `json:"tracked_sessions"`
-	dirty           bool
+	// Delivered outlives RemoveSession: a session read again from the start
+	// after its tracking was dropped is exactly what it guards against.
+	Delivered map[string]*DeliveredLines `json:"delivered,omitempty"`
+	dirty     bool
 }
 
 // NewMonitorState creates a new empty MonitorState.
 func NewMonitorState() *MonitorState {
 	return &MonitorState{
 		TrackedSessions: make(map[string]TrackedSession),
+		Delivered:       make(map[string]*DeliveredLines),
 	}
 }
 
@@ -34,6 +55,9 @@ func LoadMonitorState(path string) (*MonitorState, error) {
 	if ms.TrackedSessions == nil {
 		ms.TrackedSessions = make(map[string]TrackedSession)
 	}
+	if ms.Delivered == nil {
+		ms.Delivered = make(map[string]*DeliveredLines)
+	}
 	return ms, nil
 }
 
@@ -103,6 +127,75 @@ func (ms *MonitorState) AllKeys() []string {
 	return keys
 }
 
+// FilterDelivered checks a batch of line digests read for a session against
+// those delivered within window, returning false for each already delivered
+// (including a repeat within the batch) and counting it as a duplicate. The
+// rest are recorded as delivered at now. Digests older than window are
+// forgotten, as are sessions with none left.
+func (ms *MonitorState) FilterDelivered(key string, digests []string, now time.Time, window time.Duration) []bool {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	for k, d := range ms.Delivered {
+		if k != key && (len(d.Digests) == 0 || now.Sub(d.Digests[len(d.Digests)-1].At) > window) {
+			delete(ms.Delivered, k)
+			ms.dirty = true
+		}
+	}
+
+	d, ok := ms.Delivered[key]
+	if !ok {
+		d = &DeliveredLines{}
+		ms.Delivered[key] = d
+	}
+	expired := 0
+	for expired < len(d.Digests) && now.Sub(d.Digests[expired].At) > window {
+		expired++
+	}
+	d.Digests = d.Digests[expired:]
+
+	seen := make(map[string]bool, len(d.Digests)+len(digests))
+	for _, dg := range d.Digests {
+		seen[dg.Hash] = true
+	}
+	keep := make([]bool, len(digests))
+	for i, h := range digests {
+		if seen[h] {
+			d.Duplicates++
+			continue
+		}
+		seen[h] = true
+		keep[i] = true
+		d.Digests = append(d.Digests, LineDigest{Hash: h, At: now})
+	}
+	if n := len(d.Digests); n > maxDeliveredDigests {
+		d.Digests = append([]LineDigest(nil), d.Digests[n-maxDeliveredDigests:]...)
+	}
+	ms.dirty = true
+	return keep
+}
+
+// Duplicates returns how many lines were skipped as already delivered for a
+// session, while its delivered lines are remembered.
+func (ms *MonitorState) Duplicates(key string) int64 {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if d, ok := ms.Delivered[key]; ok {
+		return d.Duplicates
+	}
+	return 0
+}
+
+// DeliveredKeys returns the session keys with remembered delivered lines.
+func (ms *MonitorState) DeliveredKeys() []string {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	keys := make([]string, 0, len(ms.Delivered))
+	for k := range ms.Delivered {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
 // IsDirty returns whether the state has been modified since last save.
 func (ms *MonitorState) IsDirty() bool {
 	ms.mu.Lock()
@@ -2,7 +2,10 @@ package state
 
 import (
 	"path/filepath"
+	"slices"
+	"strconv"
 	"testing"
+	"time"
 )
 
 func TestMonitorState_NewEmpty(t *testing.T) {
@@ -110,3 +113,47 @@ func TestMonitorState_LoadMissing(t *testing.T) {
 		t.Error("should be initialized")
 	}
 }
+
+func TestMonitorState_FilterDelivered(t *testing.T) {
+	ms := NewMonitorState()
+	now := time.Now()
+	window := 10 * time.Minute
+
+	if keep := ms.FilterDelivered("s:@1", []string{"a", "b", "a"}, now, window); !slices.Equal(keep, []bool{true, true, false}) {
+		t.Errorf("first batch keep = %v", keep)
+	}
+	// Tracking dropped by /clear doesn't forget what was delivered
+	ms.RemoveSession("s:@1")
+	if keep := ms.FilterDelivered("s:@1", []string{"b", "c"}, now.Add(time.Minute), window); !slices.Equal(keep, []bool{false, true}) {
+		t.Errorf("second batch keep = %v", keep)
+	}
+	if n := ms.Duplicates("s:@1"); n != 2 {
+		t.Errorf("Duplicates = %d, want 2", n)
+	}
+	// Past the window a line is delivered again
+	if keep := ms.FilterDelivered("s:@1", []string{"a"}, now.Add(11*time.Minute), window); !slices.Equal(keep, []bool{true}) {
+		t.Errorf("after the window keep = %v", keep)
+	}
+
+	// Other sessions idle past the window are forgotten
+	ms.FilterDelivered("s:@2", []string{"x"}, now.Add(30*time.Minute), window)
+	if keys := ms.DeliveredKeys(); len(keys) != 1 || keys[0] != "s:@2" {
+		t.Errorf("DeliveredKeys = %v, want only s:@2", keys)
+	}
+}
+
+func TestMonitorState_FilterDeliveredCapped(t *testing.T) {
+	ms := NewMonitorState()
+	now := time.Now()
+	digests := make([]string, maxDeliveredDigests+10)
+	for i := range digests {
+		digests[i] = strconv.Itoa(i)
+	}
+	ms.FilterDelivered("k", digests, now, time.Hour)
+	if n := len(ms.Delivered["k"].Digests); n != maxDeliveredDigests {
+		t.Errorf("kept %d digests, want %d", n, maxDeliveredDigests)
+	}
+	if keep := ms.FilterDelivered("k", []string{"0", "10"}, now, time.Hour); !slices.Equal(keep, []bool{true, false}) {
+		t.Errorf("keep = %v; the oldest digests should have been dropped", keep)
+	}
+}
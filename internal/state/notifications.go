@@ -0,0 +1,49 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// NotificationsFile is where the hook appends Claude's Notification events.
+const NotificationsFile = "notifications.jsonl"
+
+// notificationsMaxSize is how large the notifications file grows before it is
+// moved to NotificationsFile+".1" and started afresh. A variable so tests can
+// lower it.
+var notificationsMaxSize int64 = 1 << 20
+
+// HookNotification is a Claude Code Notification hook event (a permission
+// request, or Claude waiting for input) for the window it fired in.
+type HookNotification struct {
+	Time      time.Time `json:"time"`
+	Key       string    `json:"key"` // session_map key, "session:@window"
+	SessionID string    `json:"session_id"`
+	Message   string    `json:"message"`
+}
+
+// AppendNotification appends an event as one JSON line to the file at path,
+// first rotating the file once it is over notificationsMaxSize. The monitor
+// reads a shrunken file from the start.
+func AppendNotification(path string, n HookNotification) error {
+	line, err := json.Marshal(n)
+	if err != nil {
+		return fmt.Errorf("encoding notification: %w", err)
+	}
+	if info, err := os.Stat(path); err == nil && info.Size() > notificationsMaxSize {
+		if err := os.Rename(path, path+".1"); err != nil {
+			return fmt.Errorf("rotating notifications: %w", err)
+		}
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("opening notifications: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("writing notifications: %w", err)
+	}
+	return nil
+}
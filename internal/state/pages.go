@@ -0,0 +1,68 @@
+package state
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// PageTTL is how long a paged message's Prev/Next buttons keep working.
+const PageTTL = 48 * time.Hour
+
+// PagedMessage holds the pages behind a long message delivered as one
+// Telegram message with Prev/Next buttons.
+type PagedMessage struct {
+	Pages     []string  `json:"pages"`
+	WindowID  string    `json:"window_id,omitempty"` // for file-reference buttons
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SetPaged sets whether a thread's long output is paged in one message
+// instead of split into several.
+func (s *State) SetPaged(threadID string, on bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if on {
+		s.PagedThreads[threadID] = true
+	} else {
+		delete(s.PagedThreads, threadID)
+	}
+}
+
+// IsPaged reports whether a thread's long output is paged.
+func (s *State) IsPaged(threadID string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.PagedThreads[threadID]
+}
+
+// AddPagedMessage stores pm under a new short ID, dropping expired ones.
+func (s *State) AddPagedMessage(pm PagedMessage) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, old := range s.PagedMessages {
+		if time.Since(old.CreatedAt) > PageTTL {
+			delete(s.PagedMessages, id)
+		}
+	}
+	for {
+		buf := make([]byte, 4)
+		rand.Read(buf)
+		id := hex.EncodeToString(buf)
+		if _, taken := s.PagedMessages[id]; !taken {
+			s.PagedMessages[id] = pm
+			return id
+		}
+	}
+}
+
+// GetPagedMessage returns a paged message, if it exists and hasn't expired.
+func (s *State) GetPagedMessage(id string) (PagedMessage, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	pm, ok := s.PagedMessages[id]
+	if !ok || time.Since(pm.CreatedAt) > PageTTL {
+		return PagedMessage{}, false
+	}
+	return pm, true
+}
@@ -1,15 +1,33 @@
 package state
 
 import (
+	"encoding/json"
 	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 )
 
 // WindowState holds session info for a bound window.
 type WindowState struct {
 	SessionID  string `json:"session_id"`
-	CWD        string `json:"cwd"`
+	CWD        string `json:"cwd"` // directory the session was launched in; its transcript is found by it
 	WindowName string `json:"window_name"`
+	AgentID    string `json:"agent_id,omitempty"`    // Minuano agent identity injected into the window
+	CurrentDir string `json:"current_dir,omitempty"` // directory the pane has since moved to, if any
+}
+
+// Dir returns the directory the window's pane is in now: CurrentDir once it
+// has moved, else CWD.
+func (ws WindowState) Dir() string {
+	if ws.CurrentDir != "" {
+		return ws.CurrentDir
+	}
+	return ws.CWD
 }
 
 // UserThread identifies a user+thread binding.
@@ -28,16 +46,201 @@ type WorktreeInfo struct {
 	IsMergeTopic bool   `json:"is_merge_topic,omitempty"`
 }
 
+// ScreenshotSettings holds per-user screenshot overrides. Zero values fall back to config.
+type ScreenshotSettings struct {
+	Theme    string  `json:"theme,omitempty"`
+	FontSize float64 `json:"font_size,omitempty"`
+	MaxCols  int     `json:"max_cols,omitempty"` // -1 disables wrapping
+}
+
+// UserSettings holds per-user preferences.
+type UserSettings struct {
+	Screenshot ScreenshotSettings `json:"screenshot"`
+	Timezone   string             `json:"timezone,omitempty"` // IANA name; empty disables timestamps
+	// QuietHours is "HH:MM-HH:MM" in the user's timezone; deliveries inside it
+	// are silent and status messages are skipped.
+	QuietHours string `json:"quiet_hours,omitempty"`
+	// QuietAll also silences notifications and permission prompts in quiet hours.
+	QuietAll bool `json:"quiet_all,omitempty"`
+	// Lite saves bandwidth: screenshots come as text, tool results as one
+	// line, and browsers show fewer entries per page.
+	Lite bool `json:"lite,omitempty"`
+}
+
+// Zones loaded by name, nil for invalid ones, since Location is called for
+// every delivered message and time.LoadLocation reads the zone database.
+var (
+	locations   = make(map[string]*time.Location)
+	locationsMu sync.Mutex
+)
+
+// Location returns the user's timezone, or nil if none is set or it is invalid.
+func (us UserSettings) Location() *time.Location {
+	if us.Timezone == "" {
+		return nil
+	}
+	locationsMu.Lock()
+	defer locationsMu.Unlock()
+	loc, ok := locations[us.Timezone]
+	if !ok {
+		loc, _ = time.LoadLocation(us.Timezone) // nil on error
+		locations[us.Timezone] = loc
+	}
+	return loc
+}
+
+// ParseQuietHours parses "HH:MM-HH:MM" into minutes after midnight. The end
+// may be earlier than the start for a range past midnight.
+func ParseQuietHours(s string) (start, end int, err error) {
+	from, to, ok := strings.Cut(s, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("quiet hours %q: want HH:MM-HH:MM", s)
+	}
+	if start, err = parseClock(from); err != nil {
+		return 0, 0, err
+	}
+	if end, err = parseClock(to); err != nil {
+		return 0, 0, err
+	}
+	if start == end {
+		return 0, 0, fmt.Errorf("quiet hours %q: start and end are the same", s)
+	}
+	return start, end, nil
+}
+
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a time like 23:00", strings.TrimSpace(s))
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// InQuietHours reports whether t falls in the user's quiet hours, read in
+// their timezone, or the server's if none is set.
+func (us UserSettings) InQuietHours(t time.Time) bool {
+	start, end, err := ParseQuietHours(us.QuietHours)
+	if err != nil {
+		return false
+	}
+	if loc := us.Location(); loc != nil {
+		t = t.In(loc)
+	} else {
+		t = t.Local()
+	}
+	now := t.Hour()*60 + t.Minute()
+	if start < end {
+		return now >= start && now < end
+	}
+	return now >= start || now < end
+}
+
+// Bookmark marks a point in a topic's session transcript.
+type Bookmark struct {
+	ID        int       `json:"id"` // unique within the topic, for buttons
+	Label     string    `json:"label"`
+	SessionID string    `json:"session_id"`
+	FilePath  string    `json:"file_path"` // transcript JSONL
+	Offset    int64     `json:"offset"`    // transcript size in bytes when bookmarked
+	CreatedAt time.Time `json:"created_at"`
+	CreatedBy string    `json:"created_by,omitempty"`
+}
+
+// PinnedMessage is a message the bot pinned in a topic.
+type PinnedMessage struct {
+	ChatID    int64 `json:"chat_id"`
+	MessageID int   `json:"message_id"`
+}
+
+// QuickAction is a button on a topic's /buttons keyboard. Action is handled
+// as if the user had sent it: a bot command, a "!" bash command or a prompt.
+type QuickAction struct {
+	Label  string `json:"label"`
+	Action string `json:"action"`
+}
+
+// Preamble is a topic's standing instructions, prepended to prompts sent from
+// it: to every prompt ("turn") or the first of each Claude session ("session").
+type Preamble struct {
+	Text           string `json:"text"`
+	Mode           string `json:"mode"`
+	AppliedSession string `json:"applied_session,omitempty"` // session mode: last session it was sent in
+}
+
+// ForkInfo records where a topic's session was forked from with /fork.
+type ForkInfo struct {
+	ParentThreadID  string    `json:"parent_thread_id"`
+	ParentWindowID  string    `json:"parent_window_id"`
+	ParentSessionID string    `json:"parent_session_id"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// LaunchChoice is the model and permission mode picked for a new session in
+// the directory browser. Empty fields keep Claude's default.
+type LaunchChoice struct {
+	Model          string `json:"model,omitempty"`
+	PermissionMode string `json:"permission_mode,omitempty"`
+}
+
+// Budget caps a project's estimated Claude spend over a day or a week.
+type Budget struct {
+	Limit  float64 `json:"limit"`           // USD
+	Period string  `json:"period"`          // "day" or "week"
+	Pause  bool    `json:"pause,omitempty"` // interrupt /auto and /batch runs when it is crossed
+	// Period keys (see /budget) in which the crossing was reported, and in
+	// which an owner chose to continue anyway
+	AlertedFor    string `json:"alerted_for,omitempty"`
+	OverriddenFor string `json:"overridden_for,omitempty"`
+}
+
+// Tuning overrides a topic's delivery thresholds (see /tune). Zero fields
+// keep the defaults.
+type Tuning struct {
+	MergeLen     int `json:"merge_len,omitempty"`     // characters consecutive messages are merged up to
+	SplitLen     int `json:"split_len,omitempty"`     // characters a long message is split at
+	PreviewLines int `json:"preview_lines,omitempty"` // output lines shown for Bash and other tool results
+	StatusMisses int `json:"status_misses,omitempty"` // polls without a status line before it is cleared
+}
+
+// AccessGrant records a user let in through an access request.
+type AccessGrant struct {
+	Name      string    `json:"name,omitempty"`
+	GrantedBy string    `json:"granted_by,omitempty"`
+	GrantedAt time.Time `json:"granted_at"`
+}
+
 // State is the main application state, persisted as state.json.
 type State struct {
 	mu                 sync.RWMutex
-	ThreadBindings     map[string]map[string]string `json:"thread_bindings"`      // user_id → thread_id → window_id
-	WindowStates       map[string]WindowState       `json:"window_states"`        // window_id → state
-	WindowDisplayNames map[string]string            `json:"window_display_names"` // window_id → display_name
-	UserWindowOffsets  map[string]map[string]int64  `json:"user_window_offsets"`  // user_id → window_id → byte_offset
-	GroupChatIDs       map[string]int64             `json:"group_chat_ids"`       // "user_id:thread_id" → chat_id
-	ProjectBindings    map[string]string            `json:"project_bindings"`     // thread_id → project_id
-	WorktreeBindings   map[string]WorktreeInfo      `json:"worktree_bindings"`    // thread_id → worktree info
+	ThreadBindings     map[string]map[string]string        `json:"thread_bindings"`         // user_id → thread_id → window_id
+	WindowStates       map[string]WindowState              `json:"window_states"`           // window_id → state
+	WindowDisplayNames map[string]string                   `json:"window_display_names"`    // window_id → display_name
+	UserWindowOffsets  map[string]map[string]int64         `json:"user_window_offsets"`     // user_id → window_id → byte_offset
+	GroupChatIDs       map[string]int64                    `json:"group_chat_ids"`          // "user_id:thread_id" → chat_id
+	ProjectBindings    map[string]string                   `json:"project_bindings"`        // thread_id → project_id
+	WorktreeBindings   map[string]WorktreeInfo             `json:"worktree_bindings"`       // thread_id → worktree info
+	UserSettings       map[string]UserSettings             `json:"user_settings"`           // user_id → preferences
+	AttributedThreads  map[string]bool                     `json:"attributed_threads"`      // thread_id → prefix prompts with the sender
+	Bookmarks          map[string][]Bookmark               `json:"bookmarks"`               // thread_id → bookmarks, oldest first
+	Pins               map[string]map[string]PinnedMessage `json:"pins"`                    // thread_id → pin kind → pinned message
+	ObservedThreads    map[string]bool                     `json:"observed_threads"`        // thread_id → read-only: output only, input rejected
+	LoudThreads        map[string]bool                     `json:"loud_threads"`            // thread_id → routine output notifies too (/sound on)
+	PagedThreads       map[string]bool                     `json:"paged_threads"`           // thread_id → long output as one message with Prev/Next (/paging on)
+	PagedMessages      map[string]PagedMessage             `json:"paged_messages"`          // short ID → pages behind a paged message
+	ConfirmThreads     map[string]bool                     `json:"confirm_threads"`         // thread_id → preview prompts before typing them (/confirm on)
+	ThinkingModes      map[string]string                   `json:"thinking_modes"`          // thread_id → /thinking mode, when not ThinkingShort
+	GrantedUsers       map[string]AccessGrant              `json:"granted_users"`           // user_id → approved access request, on top of ALLOWED_USERS
+	GroupUsernames     map[string]string                   `json:"group_usernames"`         // chat_id → public @username, for topic links
+	QuickActions       map[string][]QuickAction            `json:"quick_actions"`           // thread_id → /buttons keyboard, in order
+	Forks              map[string]ForkInfo                 `json:"forks"`                   // child thread_id → what it was forked from
+	Preambles          map[string]Preamble                 `json:"preambles"`               // thread_id → /preamble instructions
+	DirLaunches        map[string]LaunchChoice             `json:"dir_launches"`            // directory → model and permission mode last picked for it
+	Budgets            map[string]Budget                   `json:"budgets"`                 // project → /budget spend cap
+	Tunings            map[string]Tuning                   `json:"tunings"`                 // thread_id → /tune thresholds, when not all defaults
+	LabelThemes        map[string]string                   `json:"label_themes"`            // thread_id → /labels theme, when not the configured one
+	Archives           map[string]ArchivedSession          `json:"archives"`                // short ID → archived session transcript
+	Paused             bool                                `json:"paused,omitempty"`        // /pause: nothing is sent to tmux or read from transcripts
+	HeldMessages       []json.RawMessage                   `json:"held_messages,omitempty"` // Telegram messages held while paused, oldest first
 }
 
 // NewState creates a new empty state.
@@ -50,11 +253,55 @@ func NewState() *State {
 		GroupChatIDs:       make(map[string]int64),
 		ProjectBindings:    make(map[string]string),
 		WorktreeBindings:   make(map[string]WorktreeInfo),
+		UserSettings:       make(map[string]UserSettings),
+		AttributedThreads:  make(map[string]bool),
+		Bookmarks:          make(map[string][]Bookmark),
+		Pins:               make(map[string]map[string]PinnedMessage),
+		ObservedThreads:    make(map[string]bool),
+		LoudThreads:        make(map[string]bool),
+		PagedThreads:       make(map[string]bool),
+		PagedMessages:      make(map[string]PagedMessage),
+		ConfirmThreads:     make(map[string]bool),
+		ThinkingModes:      make(map[string]string),
+		GrantedUsers:       make(map[string]AccessGrant),
+		GroupUsernames:     make(map[string]string),
+		QuickActions:       make(map[string][]QuickAction),
+		Forks:              make(map[string]ForkInfo),
+		Preambles:          make(map[string]Preamble),
+		DirLaunches:        make(map[string]LaunchChoice),
+		Budgets:            make(map[string]Budget),
+		Tunings:            make(map[string]Tuning),
+		LabelThemes:        make(map[string]string),
+		Archives:           make(map[string]ArchivedSession),
 	}
 }
 
+// stateBackups is how many previous versions Save keeps, as path.1 (newest)
+// to path.3.
+const stateBackups = 3
+
 // Load reads state from a JSON file. Returns empty state if file doesn't exist.
+// An unparseable file falls back to the newest parseable backup, with a
+// warning, instead of failing.
 func Load(path string) (*State, error) {
+	s, err := loadState(path)
+	if err == nil {
+		return s, nil
+	}
+	for i := 1; i <= stateBackups; i++ {
+		backup := fmt.Sprintf("%s.%d", path, i)
+		if _, statErr := os.Stat(backup); statErr != nil {
+			continue
+		}
+		if bs, berr := loadState(backup); berr == nil {
+			log.Printf("Warning: %v; restored state from %s", err, backup)
+			return bs, nil
+		}
+	}
+	return nil, err
+}
+
+func loadState(path string) (*State, error) {
 	s := NewState()
 	if err := loadJSON(path, s); err != nil {
 		return nil, err
@@ -81,13 +328,83 @@ func Load(path string) (*State, error) {
 	if s.WorktreeBindings == nil {
 		s.WorktreeBindings = make(map[string]WorktreeInfo)
 	}
+	if s.UserSettings == nil {
+		s.UserSettings = make(map[string]UserSettings)
+	}
+	if s.AttributedThreads == nil {
+		s.AttributedThreads = make(map[string]bool)
+	}
+	if s.Bookmarks == nil {
+		s.Bookmarks = make(map[string][]Bookmark)
+	}
+	for _, bms := range s.Bookmarks {
+		// Bookmarks saved before they had IDs
+		for i := range bms {
+			if bms[i].ID == 0 {
+				bms[i].ID = nextBookmarkID(bms)
+			}
+		}
+	}
+	if s.Pins == nil {
+		s.Pins = make(map[string]map[string]PinnedMessage)
+	}
+	if s.ObservedThreads == nil {
+		s.ObservedThreads = make(map[string]bool)
+	}
+	if s.LoudThreads == nil {
+		s.LoudThreads = make(map[string]bool)
+	}
+	if s.PagedThreads == nil {
+		s.PagedThreads = make(map[string]bool)
+	}
+	if s.ConfirmThreads == nil {
+		s.ConfirmThreads = make(map[string]bool)
+	}
+	if s.PagedMessages == nil {
+		s.PagedMessages = make(map[string]PagedMessage)
+	}
+	if s.ThinkingModes == nil {
+		s.ThinkingModes = make(map[string]string)
+	}
+	if s.GrantedUsers == nil {
+		s.GrantedUsers = make(map[string]AccessGrant)
+	}
+	if s.GroupUsernames == nil {
+		s.GroupUsernames = make(map[string]string)
+	}
+	if s.QuickActions == nil {
+		s.QuickActions = make(map[string][]QuickAction)
+	}
+	if s.Forks == nil {
+		s.Forks = make(map[string]ForkInfo)
+	}
+	if s.Preambles == nil {
+		s.Preambles = make(map[string]Preamble)
+	}
+	if s.DirLaunches == nil {
+		s.DirLaunches = make(map[string]LaunchChoice)
+	}
+	if s.Budgets == nil {
+		s.Budgets = make(map[string]Budget)
+	}
+	if s.Tunings == nil {
+		s.Tunings = make(map[string]Tuning)
+	}
+	if s.LabelThemes == nil {
+		s.LabelThemes = make(map[string]string)
+	}
+	if s.Archives == nil {
+		s.Archives = make(map[string]ArchivedSession)
+	}
 	return s, nil
 }
 
-// Save writes state to a JSON file atomically.
+// Save writes state to a JSON file atomically, first rotating the previous
+// version into the backups.
 func (s *State) Save(path string) error {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
+	rotateBackups(path, stateBackups)
 	return atomicWriteJSON(path, s)
 }
 
@@ -146,6 +463,25 @@ func (s *State) SetWindowState(windowID string, ws WindowState) {
 	s.WindowStates[windowID] = ws
 }
 
+// SetWindowCurrentDir records the directory a window's pane has moved to;
+// the launch directory is kept. Returns the previous directory and false if
+// the window has no state or is already there.
+func (s *State) SetWindowCurrentDir(windowID, dir string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ws, ok := s.WindowStates[windowID]
+	if !ok || ws.CWD == "" || dir == ws.Dir() {
+		return "", false
+	}
+	prev := ws.Dir()
+	ws.CurrentDir = dir
+	if dir == ws.CWD {
+		ws.CurrentDir = ""
+	}
+	s.WindowStates[windowID] = ws
+	return prev, true
+}
+
 // GetWindowState returns the state for a window.
 func (s *State) GetWindowState(windowID string) (WindowState, bool) {
 	s.mu.RLock()
@@ -194,6 +530,31 @@ func (s *State) RemoveGroupChatID(userID, threadID string) {
 	delete(s.GroupChatIDs, key)
 }
 
+// SetGroupUsername records a group's public username; "" forgets it. Returns
+// whether anything changed.
+func (s *State) SetGroupUsername(chatID int64, username string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := strconv.FormatInt(chatID, 10)
+	if s.GroupUsernames[key] == username {
+		return false
+	}
+	if username == "" {
+		delete(s.GroupUsernames, key)
+	} else {
+		s.GroupUsernames[key] = username
+	}
+	return true
+}
+
+// GetGroupUsername returns a group's public username, if it has one.
+func (s *State) GetGroupUsername(chatID int64) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	name, ok := s.GroupUsernames[strconv.FormatInt(chatID, 10)]
+	return name, ok
+}
+
 // BindProject binds a thread to a Minuano project.
 func (s *State) BindProject(threadID, projectID string) {
 	s.mu.Lock()
@@ -201,6 +562,17 @@ func (s *State) BindProject(threadID, projectID string) {
 	s.ProjectBindings[threadID] = projectID
 }
 
+// AllProjectBindings returns a snapshot of every thread's project binding.
+func (s *State) AllProjectBindings() map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	result := make(map[string]string, len(s.ProjectBindings))
+	for tid, pid := range s.ProjectBindings {
+		result[tid] = pid
+	}
+	return result
+}
+
 // GetProject returns the Minuano project for a thread.
 func (s *State) GetProject(threadID string) (string, bool) {
 	s.mu.RLock()
@@ -216,6 +588,142 @@ func (s *State) RemoveProject(threadID string) {
 	delete(s.ProjectBindings, threadID)
 }
 
+// SetAttribution enables or disables sender prefixes on prompts from a thread.
+func (s *State) SetAttribution(threadID string, on bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if on {
+		s.AttributedThreads[threadID] = true
+	} else {
+		delete(s.AttributedThreads, threadID)
+	}
+}
+
+// IsAttributed reports whether prompts from a thread are prefixed with the sender.
+func (s *State) IsAttributed(threadID string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.AttributedThreads[threadID]
+}
+
+// SetObserved enables or disables read-only observation of a thread.
+func (s *State) SetObserved(threadID string, on bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if on {
+		s.ObservedThreads[threadID] = true
+	} else {
+		delete(s.ObservedThreads, threadID)
+	}
+}
+
+// IsObserved reports whether a thread is read-only.
+func (s *State) IsObserved(threadID string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.ObservedThreads[threadID]
+}
+
+// SetLoud sets whether a thread's routine output is delivered with sound.
+func (s *State) SetLoud(threadID string, on bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if on {
+		s.LoudThreads[threadID] = true
+	} else {
+		delete(s.LoudThreads, threadID)
+	}
+}
+
+// Thinking display modes, set per topic with /thinking.
+const (
+	ThinkingShort = "short" // the first 500 characters (default)
+	ThinkingFull  = "full"  // everything, in expandable quotes
+	ThinkingHide  = "hide"  // not sent
+)
+
+// SetThinkingMode sets how a thread shows Claude's thinking.
+func (s *State) SetThinkingMode(threadID, mode string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if mode == ThinkingShort {
+		delete(s.ThinkingModes, threadID)
+	} else {
+		s.ThinkingModes[threadID] = mode
+	}
+}
+
+// GetThinkingMode returns how a thread shows Claude's thinking.
+func (s *State) GetThinkingMode(threadID string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if mode, ok := s.ThinkingModes[threadID]; ok {
+		return mode
+	}
+	return ThinkingShort
+}
+
+// SetConfirmed sets whether a thread's prompts are previewed with Send, Edit
+// and Cancel buttons before they are typed.
+func (s *State) SetConfirmed(threadID string, on bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if on {
+		s.ConfirmThreads[threadID] = true
+	} else {
+		delete(s.ConfirmThreads, threadID)
+	}
+}
+
+// IsConfirmed reports whether a thread's prompts are previewed before they
+// are typed.
+func (s *State) IsConfirmed(threadID string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.ConfirmThreads[threadID]
+}
+
+// IsLoud reports whether a thread's routine output is delivered with sound.
+func (s *State) IsLoud(threadID string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.LoudThreads[threadID]
+}
+
+// SetPaused turns maintenance mode on or off.
+func (s *State) SetPaused(on bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Paused = on
+}
+
+// HoldMessage keeps a message, as Telegram sent it, for delivery on resume,
+// dropping the oldest beyond max.
+func (s *State) HoldMessage(msg json.RawMessage, max int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.HeldMessages = append(s.HeldMessages, msg)
+	if n := len(s.HeldMessages); n > max {
+		s.HeldMessages = s.HeldMessages[n-max:]
+	}
+}
+
+// TakeHeldMessages returns and forgets the messages held while paused.
+func (s *State) TakeHeldMessages() []json.RawMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	held := s.HeldMessages
+	s.HeldMessages = nil
+	return held
+}
+
+// IsPaused reports whether the bridge is in maintenance mode.
+func (s *State) IsPaused() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.Paused
+}
+
 // SetWindowDisplayName sets the display name for a window.
 func (s *State) SetWindowDisplayName(windowID, name string) {
 	s.mu.Lock()
@@ -251,6 +759,20 @@ func (s *State) GetUserWindowOffset(userID, windowID string) int64 {
 	return 0
 }
 
+// GetUserSettings returns the preferences for a user (zero value if unset).
+func (s *State) GetUserSettings(userID string) UserSettings {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.UserSettings[userID]
+}
+
+// SetUserSettings stores the preferences for a user.
+func (s *State) SetUserSettings(userID string, us UserSettings) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.UserSettings[userID] = us
+}
+
 // AllUserIDs returns all user IDs that have thread bindings.
 func (s *State) AllUserIDs() []string {
 	s.mu.RLock()
@@ -262,6 +784,18 @@ func (s *State) AllUserIDs() []string {
 	return ids
 }
 
+// ThreadsForUser returns a snapshot of a user's thread bindings
+// (thread_id → window_id).
+func (s *State) ThreadsForUser(userID string) map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	result := make(map[string]string, len(s.ThreadBindings[userID]))
+	for tid, wid := range s.ThreadBindings[userID] {
+		result[tid] = wid
+	}
+	return result
+}
+
 // AllBoundWindowIDs returns all window IDs that are currently bound to any thread.
 func (s *State) AllBoundWindowIDs() map[string]bool {
 	s.mu.RLock()
@@ -297,13 +831,363 @@ func (s *State) RemoveWorktreeInfo(threadID string) {
 	delete(s.WorktreeBindings, threadID)
 }
 
-// AllWorktreeThreadIDs returns all thread IDs that have worktree bindings.
-func (s *State) AllWorktreeThreadIDs() []string {
+// AllWorktreeInfos returns a snapshot of every thread's worktree metadata.
+func (s *State) AllWorktreeInfos() map[string]WorktreeInfo {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	ids := make([]string, 0, len(s.WorktreeBindings))
-	for tid := range s.WorktreeBindings {
-		ids = append(ids, tid)
+	result := make(map[string]WorktreeInfo, len(s.WorktreeBindings))
+	for tid, wi := range s.WorktreeBindings {
+		result[tid] = wi
 	}
-	return ids
+	return result
+}
+
+// AddBookmark appends a bookmark to a thread, giving it the next ID.
+func (s *State) AddBookmark(threadID string, bm Bookmark) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	bm.ID = nextBookmarkID(s.Bookmarks[threadID])
+	s.Bookmarks[threadID] = append(s.Bookmarks[threadID], bm)
+}
+
+// nextBookmarkID is one past the highest ID in bms, so IDs of deleted
+// bookmarks are not reused while later ones remain.
+func nextBookmarkID(bms []Bookmark) int {
+	id := 0
+	for _, bm := range bms {
+		id = max(id, bm.ID)
+	}
+	return id + 1
+}
+
+// GetBookmark returns a thread's bookmark by ID.
+func (s *State) GetBookmark(threadID string, id int) (Bookmark, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, bm := range s.Bookmarks[threadID] {
+		if bm.ID == id {
+			return bm, true
+		}
+	}
+	return Bookmark{}, false
+}
+
+// GetBookmarks returns a copy of a thread's bookmarks, oldest first.
+func (s *State) GetBookmarks(threadID string) []Bookmark {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]Bookmark(nil), s.Bookmarks[threadID]...)
+}
+
+// RemoveBookmark deletes a thread's bookmark by ID. Returns false if it does not exist.
+func (s *State) RemoveBookmark(threadID string, id int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	bms := s.Bookmarks[threadID]
+	idx := -1
+	for i, bm := range bms {
+		if bm.ID == id {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		return false
+	}
+	bms = append(bms[:idx:idx], bms[idx+1:]...)
+	if len(bms) == 0 {
+		delete(s.Bookmarks, threadID)
+	} else {
+		s.Bookmarks[threadID] = bms
+	}
+	return true
+}
+
+// SetPin records a thread's pin of the given kind and returns the one it replaces, if any.
+func (s *State) SetPin(threadID, kind string, pm PinnedMessage) (PinnedMessage, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Pins[threadID] == nil {
+		s.Pins[threadID] = make(map[string]PinnedMessage)
+	}
+	prev, ok := s.Pins[threadID][kind]
+	s.Pins[threadID][kind] = pm
+	return prev, ok
+}
+
+// GetPin returns a thread's pin of the given kind.
+func (s *State) GetPin(threadID, kind string) (PinnedMessage, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	pm, ok := s.Pins[threadID][kind]
+	return pm, ok
+}
+
+// SetQuickAction adds a button to a thread's keyboard, or replaces the action
+// of the button with the same label.
+func (s *State) SetQuickAction(threadID string, qa QuickAction) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	actions := s.QuickActions[threadID]
+	for i := range actions {
+		if actions[i].Label == qa.Label {
+			actions[i] = qa
+			return
+		}
+	}
+	s.QuickActions[threadID] = append(actions, qa)
+}
+
+// GetQuickActions returns a copy of a thread's keyboard buttons, in order.
+func (s *State) GetQuickActions(threadID string) []QuickAction {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]QuickAction(nil), s.QuickActions[threadID]...)
+}
+
+// RemoveQuickAction deletes a thread's button by label. An empty label removes
+// all of them. Returns false if nothing was removed.
+func (s *State) RemoveQuickAction(threadID, label string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	actions := s.QuickActions[threadID]
+	if label == "" {
+		delete(s.QuickActions, threadID)
+		return len(actions) > 0
+	}
+	for i := range actions {
+		if actions[i].Label == label {
+			actions = append(actions[:i:i], actions[i+1:]...)
+			if len(actions) == 0 {
+				delete(s.QuickActions, threadID)
+			} else {
+				s.QuickActions[threadID] = actions
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// SetFork records that threadID was forked from another topic.
+func (s *State) SetFork(threadID string, fi ForkInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Forks[threadID] = fi
+}
+
+// GetFork returns where threadID was forked from.
+func (s *State) GetFork(threadID string) (ForkInfo, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	fi, ok := s.Forks[threadID]
+	return fi, ok
+}
+
+// ForksOf returns the threads forked from threadID, sorted.
+func (s *State) ForksOf(threadID string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var children []string
+	for child, fi := range s.Forks {
+		if fi.ParentThreadID == threadID {
+			children = append(children, child)
+		}
+	}
+	sort.Strings(children)
+	return children
+}
+
+// SetPreamble stores a thread's preamble.
+func (s *State) SetPreamble(threadID string, p Preamble) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Preambles[threadID] = p
+}
+
+// GetPreamble returns a thread's preamble.
+func (s *State) GetPreamble(threadID string) (Preamble, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	p, ok := s.Preambles[threadID]
+	return p, ok
+}
+
+// RemovePreamble deletes a thread's preamble. Returns false if it had none.
+func (s *State) RemovePreamble(threadID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.Preambles[threadID]
+	delete(s.Preambles, threadID)
+	return ok
+}
+
+// SetDirLaunch remembers the launch choice for new sessions in dir. The zero
+// choice forgets it.
+func (s *State) SetDirLaunch(dir string, lc LaunchChoice) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if lc == (LaunchChoice{}) {
+		delete(s.DirLaunches, dir)
+		return
+	}
+	s.DirLaunches[dir] = lc
+}
+
+// GetDirLaunch returns the launch choice last picked for dir.
+func (s *State) GetDirLaunch(dir string) LaunchChoice {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.DirLaunches[dir]
+}
+
+// SetBudget sets a project's budget.
+func (s *State) SetBudget(project string, b Budget) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Budgets[project] = b
+}
+
+// GetBudget returns a project's budget.
+func (s *State) GetBudget(project string) (Budget, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	b, ok := s.Budgets[project]
+	return b, ok
+}
+
+// RemoveBudget removes a project's budget. Returns false if it had none.
+func (s *State) RemoveBudget(project string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.Budgets[project]
+	delete(s.Budgets, project)
+	return ok
+}
+
+// HasBudgets reports whether any project has a budget.
+func (s *State) HasBudgets() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.Budgets) > 0
+}
+
+// SetTuning sets a thread's delivery thresholds; the zero Tuning restores
+// the defaults.
+func (s *State) SetTuning(threadID string, t Tuning) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t == (Tuning{}) {
+		delete(s.Tunings, threadID)
+	} else {
+		s.Tunings[threadID] = t
+	}
+}
+
+// GetTuning returns a thread's delivery thresholds.
+func (s *State) GetTuning(threadID string) Tuning {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.Tunings[threadID]
+}
+
+// SetLabelTheme sets the theme a thread's messages are labelled with; ""
+// restores the configured one.
+func (s *State) SetLabelTheme(threadID, theme string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if theme == "" {
+		delete(s.LabelThemes, threadID)
+	} else {
+		s.LabelThemes[threadID] = theme
+	}
+}
+
+// GetLabelTheme returns a thread's label theme, or "" for the configured one.
+func (s *State) GetLabelTheme(threadID string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.LabelThemes[threadID]
+}
+
+// RemoveThreadSettings drops a thread's per-topic settings, bookmarks, pins,
+// buttons, fork record and preamble. Bindings are removed separately.
+func (s *State) RemoveThreadSettings(threadID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.AttributedThreads, threadID)
+	delete(s.ObservedThreads, threadID)
+	delete(s.LoudThreads, threadID)
+	delete(s.PagedThreads, threadID)
+	delete(s.ConfirmThreads, threadID)
+	delete(s.ThinkingModes, threadID)
+	delete(s.Tunings, threadID)
+	delete(s.LabelThemes, threadID)
+	delete(s.Bookmarks, threadID)
+	delete(s.Pins, threadID)
+	delete(s.QuickActions, threadID)
+	delete(s.Forks, threadID)
+	delete(s.Preambles, threadID)
+}
+
+// MigrateChatID rewrites every stored reference to chat from as chat to, for a
+// group upgraded to a supergroup. Returns the number of references rewritten.
+func (s *State) MigrateChatID(from, to int64) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for key, chatID := range s.GroupChatIDs {
+		if chatID == from {
+			s.GroupChatIDs[key] = to
+			n++
+		}
+	}
+	for _, pins := range s.Pins {
+		for kind, pm := range pins {
+			if pm.ChatID == from {
+				pm.ChatID = to
+				pins[kind] = pm
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// GrantAccess adds a user to the allowlist overlay.
+func (s *State) GrantAccess(userID string, g AccessGrant) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.GrantedUsers[userID] = g
+}
+
+// RevokeAccess removes a user from the allowlist overlay. Returns false if
+// the user had no grant.
+func (s *State) RevokeAccess(userID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.GrantedUsers[userID]; !ok {
+		return false
+	}
+	delete(s.GrantedUsers, userID)
+	return true
+}
+
+// HasAccess reports whether a user was granted access.
+func (s *State) HasAccess(userID string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.GrantedUsers[userID]
+	return ok
+}
+
+// AccessGrants returns a copy of the allowlist overlay.
+func (s *State) AccessGrants() map[string]AccessGrant {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	grants := make(map[string]AccessGrant, len(s.GrantedUsers))
+	for id, g := range s.GrantedUsers {
+		grants[id] = g
+	}
+	return grants
 }
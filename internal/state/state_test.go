@@ -1,9 +1,12 @@
 package state
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestNewState(t *testing.T) {
@@ -75,6 +78,83 @@ func TestLoad_MissingFile(t *testing.T) {
 	}
 }
 
+func TestSave_RotatesBackups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	s := NewState()
+	for _, name := range []string{"v1", "v2", "v3", "v4", "v5"} {
+		s.SetWindowDisplayName("@1", name)
+		if err := s.Save(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i, want := range []string{"v4", "v3", "v2"} {
+		backup, err := Load(fmt.Sprintf("%s.%d", path, i+1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if name, _ := backup.GetWindowDisplayName("@1"); name != want {
+			t.Errorf("backup %d holds %q, want %q", i+1, name, want)
+		}
+	}
+	if _, err := os.Stat(path + ".4"); !os.IsNotExist(err) {
+		t.Error("kept more than 3 backups")
+	}
+}
+
+func TestSave_SkipsBackingUpCorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	s := NewState()
+	for _, name := range []string{"good", "next"} {
+		s.SetWindowDisplayName("@1", name)
+		s.Save(path)
+	}
+	os.WriteFile(path, []byte(`{"thread_bindings": {`), 0o644)
+
+	s.SetWindowDisplayName("@1", "after")
+	s.Save(path)
+	backup, _ := Load(path + ".1")
+	if name, _ := backup.GetWindowDisplayName("@1"); name != "good" {
+		t.Errorf("backup 1 holds %q, want the last good version", name)
+	}
+	if _, err := os.Stat(path + ".2"); !os.IsNotExist(err) {
+		t.Error("the corrupt file was rotated into the backups")
+	}
+}
+
+func TestLoad_FallsBackToBackup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	s := NewState()
+	for _, name := range []string{"oldest", "older", "newer"} {
+		s.SetWindowDisplayName("@1", name)
+		s.Save(path)
+	}
+
+	os.WriteFile(path, []byte("not json"), 0o644)
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load with a backup: %v", err)
+	}
+	if name, _ := loaded.GetWindowDisplayName("@1"); name != "older" {
+		t.Errorf("restored %q, want the newest backup", name)
+	}
+
+	os.WriteFile(path+".1", []byte("also broken"), 0o644)
+	loaded, err = Load(path)
+	if err != nil {
+		t.Fatalf("Load should skip an unparseable backup: %v", err)
+	}
+	if name, _ := loaded.GetWindowDisplayName("@1"); name != "oldest" {
+		t.Errorf("restored %q, want the next backup", name)
+	}
+
+	os.Remove(path + ".1")
+	os.Remove(path + ".2")
+	if _, err := Load(path); err == nil {
+		t.Error("expected an error with no usable backup")
+	}
+}
+
 func TestBindUnbindThread(t *testing.T) {
 	s := NewState()
 	s.BindThread("u1", "t1", "@1")
@@ -127,6 +207,27 @@ func TestRemoveWindowState(t *testing.T) {
 	}
 }
 
+func TestSetWindowCurrentDir(t *testing.T) {
+	s := NewState()
+	if _, ok := s.SetWindowCurrentDir("@1", "/work/api/sub"); ok {
+		t.Error("set a directory for a window without state")
+	}
+	s.SetWindowState("@1", WindowState{SessionID: "s1", CWD: "/work/api"})
+
+	prev, ok := s.SetWindowCurrentDir("@1", "/work/api/sub")
+	ws, _ := s.GetWindowState("@1")
+	if !ok || prev != "/work/api" || ws.CWD != "/work/api" || ws.Dir() != "/work/api/sub" {
+		t.Errorf("after cd: prev %q, %v, state %+v", prev, ok, ws)
+	}
+	if _, ok := s.SetWindowCurrentDir("@1", "/work/api/sub"); ok {
+		t.Error("same directory reported as a change")
+	}
+	s.SetWindowCurrentDir("@1", "/work/api")
+	if ws, _ := s.GetWindowState("@1"); ws.CurrentDir != "" || ws.Dir() != "/work/api" {
+		t.Errorf("back in the launch directory: %+v", ws)
+	}
+}
+
 func TestGroupChatIDs(t *testing.T) {
 	s := NewState()
 	s.SetGroupChatID("u1", "t1", -100)
@@ -191,3 +292,406 @@ func TestAtomicWriteJSON(t *testing.T) {
 		t.Error("file should not be empty")
 	}
 }
+
+func TestUserSettings(t *testing.T) {
+	s := NewState()
+	if got := s.GetUserSettings("1"); got != (UserSettings{}) {
+		t.Errorf("expected zero settings, got %+v", got)
+	}
+
+	s.SetUserSettings("1", UserSettings{Screenshot: ScreenshotSettings{Theme: "light"}})
+	if got := s.GetUserSettings("1").Screenshot.Theme; got != "light" {
+		t.Errorf("theme = %q, want light", got)
+	}
+}
+
+func TestUserSettingsLocation(t *testing.T) {
+	if loc := (UserSettings{}).Location(); loc != nil {
+		t.Errorf("expected nil location, got %v", loc)
+	}
+	if loc := (UserSettings{Timezone: "Not/AZone"}).Location(); loc != nil {
+		t.Errorf("invalid timezone should yield nil, got %v", loc)
+	}
+	if loc := (UserSettings{Timezone: "UTC"}).Location(); loc == nil || loc.String() != "UTC" {
+		t.Errorf("expected UTC, got %v", loc)
+	}
+	madrid := UserSettings{Timezone: "Europe/Madrid"}
+	if a, b := madrid.Location(), madrid.Location(); a == nil || a != b {
+		t.Errorf("zone not loaded once: %p, %p", a, b)
+	}
+}
+
+func TestUserSettingsInQuietHours(t *testing.T) {
+	madrid, err := time.LoadLocation("Europe/Madrid")
+	if err != nil {
+		t.Skip("no tzdata")
+	}
+	at := func(hhmm string) time.Time {
+		clock, _ := time.Parse("15:04", hhmm)
+		return time.Date(2026, 1, 15, clock.Hour(), clock.Minute(), 0, 0, madrid)
+	}
+	overnight := UserSettings{Timezone: "Europe/Madrid", QuietHours: "23:00-08:00"}
+	daytime := UserSettings{Timezone: "Europe/Madrid", QuietHours: "13:00-14:30"}
+	for _, tc := range []struct {
+		us   UserSettings
+		at   string
+		want bool
+	}{
+		{overnight, "23:00", true},
+		{overnight, "03:15", true},
+		{overnight, "08:00", false},
+		{overnight, "22:59", false},
+		{daytime, "13:30", true},
+		{daytime, "14:30", false},
+		{UserSettings{Timezone: "Europe/Madrid"}, "03:00", false},
+	} {
+		if got := tc.us.InQuietHours(at(tc.at).UTC()); got != tc.want {
+			t.Errorf("%q at %s = %v, want %v", tc.us.QuietHours, tc.at, got, tc.want)
+		}
+	}
+
+	for _, bad := range []string{"23:00", "25:00-08:00", "8-9", "09:00-09:00"} {
+		if _, _, err := ParseQuietHours(bad); err == nil {
+			t.Errorf("ParseQuietHours(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestAttribution(t *testing.T) {
+	s := NewState()
+	if s.IsAttributed("42") {
+		t.Error("attribution should default to off")
+	}
+	s.SetAttribution("42", true)
+	if !s.IsAttributed("42") {
+		t.Error("attribution should be on")
+	}
+	s.SetAttribution("42", false)
+	if s.IsAttributed("42") {
+		t.Error("attribution should be off")
+	}
+}
+
+func TestObserved(t *testing.T) {
+	s := NewState()
+	if s.IsObserved("42") {
+		t.Error("observation should default to off")
+	}
+	s.SetObserved("42", true)
+	if !s.IsObserved("42") || s.IsObserved("43") {
+		t.Error("only thread 42 should be observed")
+	}
+	s.SetObserved("42", false)
+	if s.IsObserved("42") {
+		t.Error("observation should be off")
+	}
+}
+
+func TestQuickActions(t *testing.T) {
+	s := NewState()
+	s.SetQuickAction("42", QuickAction{Label: "Test", Action: "!make test"})
+	s.SetQuickAction("42", QuickAction{Label: "Diff", Action: "/c_get"})
+	s.SetQuickAction("42", QuickAction{Label: "Test", Action: "!go test ./..."})
+
+	got := s.GetQuickActions("42")
+	if len(got) != 2 || got[0].Action != "!go test ./..." || got[1].Label != "Diff" {
+		t.Fatalf("actions = %+v, want Test replaced in place, then Diff", got)
+	}
+	if s.RemoveQuickAction("42", "Deploy") {
+		t.Error("removing a missing label should report false")
+	}
+	if !s.RemoveQuickAction("42", "Test") || len(s.GetQuickActions("42")) != 1 {
+		t.Errorf("after removing Test: %+v", s.GetQuickActions("42"))
+	}
+	if !s.RemoveQuickAction("42", "") || len(s.QuickActions) != 0 {
+		t.Errorf("clearing should drop the thread: %+v", s.QuickActions)
+	}
+}
+
+func TestRemoveThreadSettings(t *testing.T) {
+	s := NewState()
+	for _, thread := range []string{"42", "43"} {
+		s.SetAttribution(thread, true)
+		s.SetObserved(thread, true)
+		s.AddBookmark(thread, Bookmark{Label: "b"})
+		s.SetPin(thread, "plan", PinnedMessage{ChatID: -100, MessageID: 1})
+		s.SetQuickAction(thread, QuickAction{Label: "Test", Action: "!make test"})
+	}
+	s.RemoveThreadSettings("42")
+
+	if s.IsAttributed("42") || s.IsObserved("42") || len(s.GetBookmarks("42")) != 0 {
+		t.Error("thread 42 settings should be gone")
+	}
+	if _, ok := s.GetPin("42", "plan"); ok {
+		t.Error("thread 42 pin should be gone")
+	}
+	if len(s.GetQuickActions("42")) != 0 || len(s.GetQuickActions("43")) != 1 {
+		t.Error("only thread 42 buttons should be gone")
+	}
+	if !s.IsAttributed("43") || !s.IsObserved("43") || len(s.GetBookmarks("43")) != 1 {
+		t.Error("thread 43 settings should be kept")
+	}
+}
+
+func TestAccessGrants(t *testing.T) {
+	s := NewState()
+	if s.HasAccess("555") {
+		t.Error("no access by default")
+	}
+	s.GrantAccess("555", AccessGrant{Name: "alice", GrantedBy: "owner"})
+	if !s.HasAccess("555") || s.AccessGrants()["555"].Name != "alice" {
+		t.Error("grant not recorded")
+	}
+	if !s.RevokeAccess("555") || s.HasAccess("555") {
+		t.Error("revoke should remove the grant")
+	}
+	if s.RevokeAccess("555") {
+		t.Error("second revoke should report no grant")
+	}
+}
+
+func TestMigrateChatID(t *testing.T) {
+	s := NewState()
+	s.SetGroupChatID("1", "42", -100)
+	s.SetGroupChatID("2", "42", -100)
+	s.SetGroupChatID("1", "7", -200)
+	s.SetPin("42", "plan", PinnedMessage{ChatID: -100, MessageID: 5})
+
+	if n := s.MigrateChatID(-100, -1001); n != 3 {
+		t.Errorf("rewrote %d references, want 3", n)
+	}
+	if id, _ := s.GetGroupChatID("2", "42"); id != -1001 {
+		t.Errorf("chat ID = %d, want -1001", id)
+	}
+	if id, _ := s.GetGroupChatID("1", "7"); id != -200 {
+		t.Errorf("other chat rewritten to %d", id)
+	}
+	if pm, _ := s.GetPin("42", "plan"); pm.ChatID != -1001 || pm.MessageID != 5 {
+		t.Errorf("pin = %+v", pm)
+	}
+}
+
+func TestGroupUsername(t *testing.T) {
+	s := NewState()
+	if !s.SetGroupUsername(-1001, "myteam") {
+		t.Error("first set reported no change")
+	}
+	if s.SetGroupUsername(-1001, "myteam") {
+		t.Error("same username reported a change")
+	}
+	if name, ok := s.GetGroupUsername(-1001); !ok || name != "myteam" {
+		t.Errorf("username = %q, %v", name, ok)
+	}
+	if !s.SetGroupUsername(-1001, "") {
+		t.Error("clearing reported no change")
+	}
+	if _, ok := s.GetGroupUsername(-1001); ok {
+		t.Error("username kept after the group went private")
+	}
+}
+
+func TestSnapshots(t *testing.T) {
+	s := NewState()
+	s.BindThread("1", "42", "@1")
+	s.BindThread("1", "7", "@2")
+	s.BindProject("42", "api")
+	s.SetWorktreeInfo("7", WorktreeInfo{Branch: "fix"})
+
+	threads := s.ThreadsForUser("1")
+	if len(threads) != 2 || threads["42"] != "@1" {
+		t.Errorf("ThreadsForUser = %v", threads)
+	}
+	threads["99"] = "@9"
+	if _, ok := s.GetWindowForThread("1", "99"); ok {
+		t.Error("ThreadsForUser returned the live map")
+	}
+	if len(s.ThreadsForUser("2")) != 0 {
+		t.Error("unknown user has threads")
+	}
+
+	projects := s.AllProjectBindings()
+	if len(projects) != 1 || projects["42"] != "api" {
+		t.Errorf("AllProjectBindings = %v", projects)
+	}
+	delete(projects, "42")
+	if _, ok := s.GetProject("42"); !ok {
+		t.Error("AllProjectBindings returned the live map")
+	}
+
+	if wts := s.AllWorktreeInfos(); len(wts) != 1 || wts["7"].Branch != "fix" {
+		t.Errorf("AllWorktreeInfos = %v", wts)
+	}
+}
+
+func TestBookmarks(t *testing.T) {
+	s := NewState()
+	s.AddBookmark("42", Bookmark{Label: "first", Offset: 10})
+	s.AddBookmark("42", Bookmark{Label: "second", Offset: 20})
+
+	bms := s.GetBookmarks("42")
+	if len(bms) != 2 || bms[0].Label != "first" || bms[1].Offset != 20 || bms[0].ID != 1 || bms[1].ID != 2 {
+		t.Fatalf("unexpected bookmarks: %+v", bms)
+	}
+	if bm, ok := s.GetBookmark("42", 2); !ok || bm.Label != "second" {
+		t.Errorf("GetBookmark(2) = %+v, %v", bm, ok)
+	}
+	bms[0].Label = "changed"
+	if s.GetBookmarks("42")[0].Label != "first" {
+		t.Error("GetBookmarks should return a copy")
+	}
+
+	if s.RemoveBookmark("42", 5) {
+		t.Error("removing a missing bookmark should fail")
+	}
+	if !s.RemoveBookmark("42", 1) {
+		t.Fatal("RemoveBookmark failed")
+	}
+	if bms := s.GetBookmarks("42"); len(bms) != 1 || bms[0].Label != "second" {
+		t.Errorf("unexpected bookmarks after remove: %+v", bms)
+	}
+	// IDs are not reused while later bookmarks remain
+	s.AddBookmark("42", Bookmark{Label: "third"})
+	if bms := s.GetBookmarks("42"); bms[1].ID != 3 {
+		t.Errorf("new bookmark ID = %d, want 3", bms[1].ID)
+	}
+	if s.RemoveBookmark("42", 1) {
+		t.Error("removed bookmark 1 twice")
+	}
+	s.RemoveBookmark("42", 2)
+	s.RemoveBookmark("42", 3)
+	if _, ok := s.Bookmarks["42"]; ok {
+		t.Error("empty bookmark list should be deleted")
+	}
+}
+
+func TestLoad_NumbersOldBookmarks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	os.WriteFile(path, []byte(`{"bookmarks":{"42":[{"label":"a"},{"label":"b"}]}}`), 0o600)
+	s, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bms := s.GetBookmarks("42"); len(bms) != 2 || bms[0].ID != 1 || bms[1].ID != 2 {
+		t.Errorf("bookmarks = %+v", bms)
+	}
+}
+
+func TestAppendAudit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	for _, text := range []string{"first", "second"} {
+		if err := AppendAudit(path, AuditEntry{UserID: 1, User: "alice", WindowID: "@1", Text: text}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[1], `"text":"second"`) || !strings.Contains(lines[0], `"user":"alice"`) {
+		t.Errorf("unexpected audit log:\n%s", data)
+	}
+}
+
+func TestForks(t *testing.T) {
+	s := NewState()
+	s.SetFork("51", ForkInfo{ParentThreadID: "42", ParentWindowID: "@1", ParentSessionID: "sess-1"})
+	s.SetFork("50", ForkInfo{ParentThreadID: "42", ParentWindowID: "@1", ParentSessionID: "sess-1"})
+	s.SetFork("60", ForkInfo{ParentThreadID: "51", ParentWindowID: "@3", ParentSessionID: "sess-3"})
+
+	if fi, ok := s.GetFork("51"); !ok || fi.ParentThreadID != "42" || fi.ParentSessionID != "sess-1" {
+		t.Errorf("GetFork(51) = %+v, %v", fi, ok)
+	}
+	if got := s.ForksOf("42"); len(got) != 2 || got[0] != "50" || got[1] != "51" {
+		t.Errorf("ForksOf(42) = %v", got)
+	}
+
+	s.RemoveThreadSettings("51")
+	if _, ok := s.GetFork("51"); ok {
+		t.Error("fork record survived topic removal")
+	}
+	if got := s.ForksOf("51"); len(got) != 1 {
+		t.Errorf("ForksOf(51) = %v, the grandchild keeps its record", got)
+	}
+}
+
+func TestDirLaunches(t *testing.T) {
+	s := NewState()
+	if lc := s.GetDirLaunch("/src/app"); lc != (LaunchChoice{}) {
+		t.Errorf("unset dir = %+v", lc)
+	}
+	s.SetDirLaunch("/src/app", LaunchChoice{Model: "opus", PermissionMode: "plan"})
+	if lc := s.GetDirLaunch("/src/app"); lc.Model != "opus" || lc.PermissionMode != "plan" {
+		t.Errorf("GetDirLaunch = %+v", lc)
+	}
+	s.SetDirLaunch("/src/app", LaunchChoice{})
+	if _, ok := s.DirLaunches["/src/app"]; ok {
+		t.Error("zero choice should forget the directory")
+	}
+}
+
+func TestPagedMessages(t *testing.T) {
+	s := NewState()
+	id := s.AddPagedMessage(PagedMessage{Pages: []string{"one", "two"}, CreatedAt: time.Now()})
+	if pm, ok := s.GetPagedMessage(id); !ok || len(pm.Pages) != 2 {
+		t.Fatalf("GetPagedMessage(%q) = %v, %v", id, pm, ok)
+	}
+
+	s.PagedMessages[id] = PagedMessage{Pages: []string{"old"}, CreatedAt: time.Now().Add(-PageTTL - time.Minute)}
+	if _, ok := s.GetPagedMessage(id); ok {
+		t.Error("expired pages still served")
+	}
+	s.AddPagedMessage(PagedMessage{Pages: []string{"new"}, CreatedAt: time.Now()})
+	if _, ok := s.PagedMessages[id]; ok {
+		t.Error("expired pages not pruned")
+	}
+}
+
+func TestArchives(t *testing.T) {
+	s := NewState()
+	older := s.AddArchive(ArchivedSession{SessionID: "a", ThreadIDs: []string{"7"}, ArchivedAt: time.Now().Add(-time.Hour)})
+	newer := s.AddArchive(ArchivedSession{SessionID: "b", ThreadIDs: []string{"7", "8"}, ArchivedAt: time.Now()})
+	s.AddArchive(ArchivedSession{SessionID: "c", ThreadIDs: []string{"9"}, ArchivedAt: time.Now()})
+
+	if ids := s.ArchivesForThread("7"); len(ids) != 2 || ids[0] != newer || ids[1] != older {
+		t.Errorf("ArchivesForThread(7) = %v, want [%s %s]", ids, newer, older)
+	}
+	s.RemoveThreadSettings("7")
+	if a, ok := s.GetArchive(older); !ok || a.SessionID != "a" {
+		t.Errorf("archive lost with the topic's settings: %v, %v", a, ok)
+	}
+
+	for i := 0; i < archivesMax; i++ {
+		s.AddArchive(ArchivedSession{ArchivedAt: time.Now()})
+	}
+	if len(s.Archives) != archivesMax {
+		t.Errorf("kept %d archive records, want %d", len(s.Archives), archivesMax)
+	}
+	if _, ok := s.GetArchive(older); ok {
+		t.Error("oldest archive record not dropped")
+	}
+}
+
+func TestAppendNotification_Rotates(t *testing.T) {
+	prev := notificationsMaxSize
+	notificationsMaxSize = 100
+	t.Cleanup(func() { notificationsMaxSize = prev })
+
+	path := filepath.Join(t.TempDir(), NotificationsFile)
+	n := HookNotification{Key: "tramuntana:@1", SessionID: "s", Message: strings.Repeat("x", 60)}
+	for i := 0; i < 3; i++ {
+		if err := AppendNotification(path, n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines != 1 {
+		t.Errorf("%d lines after rotating, want 1", lines)
+	}
+	if _, err := os.Stat(path + ".1"); err != nil {
+		t.Errorf("rotated file: %v", err)
+	}
+}
@@ -0,0 +1,292 @@
+package state
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// usageRetentionDays is how many days of usage are kept on disk.
+const usageRetentionDays = 7
+
+// WindowUsage accumulates activity for one window over one day.
+type WindowUsage struct {
+	Turns         int             `json:"turns"`
+	BrewedSeconds int64           `json:"brewed_seconds"`
+	TasksPicked   []string        `json:"tasks_picked,omitempty"`
+	TasksFinished []string        `json:"tasks_finished,omitempty"`
+	FilesTouched  map[string]bool `json:"files_touched,omitempty"`
+	InputTokens   int64           `json:"input_tokens"`
+	OutputTokens  int64           `json:"output_tokens"`
+	CacheTokens   int64           `json:"cache_tokens"`       // cache reads + writes
+	CostUSD       float64         `json:"cost_usd,omitempty"` // estimated from the tokens at list prices
+}
+
+// Files returns the touched file paths, sorted.
+func (wu WindowUsage) Files() []string {
+	files := make([]string, 0, len(wu.FilesTouched))
+	for f := range wu.FilesTouched {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// Usage tracks per-day, per-window activity for digests, persisted as usage.json.
+// All Record methods are safe to call on a nil *Usage.
+type Usage struct {
+	mu                sync.Mutex
+	Days              map[string]map[string]*WindowUsage `json:"days"`               // YYYY-MM-DD → window_id → usage
+	Projects          map[string]map[string]float64      `json:"projects,omitempty"` // YYYY-MM-DD → budget project → cost in USD
+	LastDigest        string                             `json:"last_digest,omitempty"`
+	LastWorktreeCheck string                             `json:"last_worktree_check,omitempty"`
+	dirty             bool
+	now               func() time.Time
+	projectOf         func(windowID string) string
+}
+
+// NewUsage creates a new empty Usage tracker.
+func NewUsage() *Usage {
+	return &Usage{
+		Days: make(map[string]map[string]*WindowUsage),
+		now:  time.Now,
+	}
+}
+
+// LoadUsage reads usage from a JSON file.
+func LoadUsage(path string) (*Usage, error) {
+	u := NewUsage()
+	if err := loadJSON(path, u); err != nil {
+		return nil, err
+	}
+	if u.Days == nil {
+		u.Days = make(map[string]map[string]*WindowUsage)
+	}
+	return u, nil
+}
+
+// SaveIfDirty saves usage only if it has been modified.
+func (u *Usage) SaveIfDirty(path string) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if !u.dirty {
+		return nil
+	}
+	if err := atomicWriteJSON(path, u); err != nil {
+		return err
+	}
+	u.dirty = false
+	return nil
+}
+
+// Day formats t as a usage day key.
+func Day(t time.Time) string {
+	return t.Format("2006-01-02")
+}
+
+// window returns today's usage for a window, creating it and pruning old days.
+// Caller must hold u.mu.
+func (u *Usage) window(windowID string) *WindowUsage {
+	today := u.now()
+	day := Day(today)
+	windows, ok := u.Days[day]
+	if !ok {
+		windows = make(map[string]*WindowUsage)
+		u.Days[day] = windows
+		cutoff := Day(today.AddDate(0, 0, -usageRetentionDays))
+		for d := range u.Days {
+			if d < cutoff {
+				delete(u.Days, d)
+			}
+		}
+		for d := range u.Projects {
+			if d < cutoff {
+				delete(u.Projects, d)
+			}
+		}
+	}
+	wu, ok := windows[windowID]
+	if !ok {
+		wu = &WindowUsage{}
+		windows[windowID] = wu
+	}
+	u.dirty = true
+	return wu
+}
+
+// RecordTurn records a completed turn and its duration.
+func (u *Usage) RecordTurn(windowID string, elapsed time.Duration) {
+	if u == nil {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	wu := u.window(windowID)
+	wu.Turns++
+	wu.BrewedSeconds += int64(elapsed.Seconds())
+}
+
+// RecordFile records a file modified by Edit/Write.
+func (u *Usage) RecordFile(windowID, path string) {
+	if u == nil || path == "" {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	wu := u.window(windowID)
+	if wu.FilesTouched == nil {
+		wu.FilesTouched = make(map[string]bool)
+	}
+	wu.FilesTouched[path] = true
+}
+
+// RecordTokens adds token counts from an assistant message.
+func (u *Usage) RecordTokens(windowID string, input, output, cache int64) {
+	if u == nil || input+output+cache == 0 {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	wu := u.window(windowID)
+	wu.InputTokens += input
+	wu.OutputTokens += output
+	wu.CacheTokens += cache
+}
+
+// SetProjectFunc sets the function naming the budget project a window's
+// spend counts toward. It is asked when cost is recorded, so the spend stays
+// with the project after the window is gone.
+func (u *Usage) SetProjectFunc(fn func(windowID string) string) {
+	if u == nil {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.projectOf = fn
+}
+
+// RecordCost adds the estimated cost of an assistant message, in USD, to the
+// window and to its project.
+func (u *Usage) RecordCost(windowID string, usd float64) {
+	if u == nil || usd <= 0 {
+		return
+	}
+	u.mu.Lock()
+	projectOf := u.projectOf
+	u.mu.Unlock()
+	var project string
+	if projectOf != nil {
+		project = projectOf(windowID)
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.window(windowID).CostUSD += usd
+	if project == "" {
+		return
+	}
+	day := Day(u.now())
+	if u.Projects == nil {
+		u.Projects = make(map[string]map[string]float64)
+	}
+	if u.Projects[day] == nil {
+		u.Projects[day] = make(map[string]float64)
+	}
+	u.Projects[day][project] += usd
+}
+
+// ProjectCost returns the estimated cost recorded for a project over days.
+func (u *Usage) ProjectCost(days []string, project string) float64 {
+	if u == nil {
+		return 0
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	var total float64
+	for _, day := range days {
+		total += u.Projects[day][project]
+	}
+	return total
+}
+
+// RecordTaskPicked records task IDs sent to a window.
+func (u *Usage) RecordTaskPicked(windowID string, taskIDs ...string) {
+	if u == nil {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	wu := u.window(windowID)
+	wu.TasksPicked = appendUnique(wu.TasksPicked, taskIDs...)
+}
+
+// RecordTaskFinished records a task marked done from a window.
+func (u *Usage) RecordTaskFinished(windowID, taskID string) {
+	if u == nil || taskID == "" {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	wu := u.window(windowID)
+	wu.TasksFinished = appendUnique(wu.TasksFinished, taskID)
+}
+
+// GetWindowUsage returns a copy of a window's usage for a day.
+func (u *Usage) GetWindowUsage(day, windowID string) (WindowUsage, bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	wu, ok := u.Days[day][windowID]
+	if !ok {
+		return WindowUsage{}, false
+	}
+	cp := *wu
+	cp.TasksPicked = append([]string(nil), wu.TasksPicked...)
+	cp.TasksFinished = append([]string(nil), wu.TasksFinished...)
+	cp.FilesTouched = make(map[string]bool, len(wu.FilesTouched))
+	for f := range wu.FilesTouched {
+		cp.FilesTouched[f] = true
+	}
+	return cp, true
+}
+
+// MarkDigestSent records that the digest for day was posted.
+// Returns false if it had already been sent.
+func (u *Usage) MarkDigestSent(day string) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.LastDigest >= day {
+		return false
+	}
+	u.LastDigest = day
+	u.dirty = true
+	return true
+}
+
+// MarkWorktreeCheck records that stale worktrees were checked for on day.
+// Returns false if they already were.
+func (u *Usage) MarkWorktreeCheck(day string) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.LastWorktreeCheck >= day {
+		return false
+	}
+	u.LastWorktreeCheck = day
+	u.dirty = true
+	return true
+}
+
+func appendUnique(list []string, items ...string) []string {
+	for _, item := range items {
+		found := false
+		for _, existing := range list {
+			if existing == item {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, item)
+		}
+	}
+	return list
+}
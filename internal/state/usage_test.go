@@ -0,0 +1,128 @@
+package state
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestUsage_RecordAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.json")
+
+	u := NewUsage()
+	u.RecordTurn("@1", 90*time.Second)
+	u.RecordTurn("@1", 30*time.Second)
+	u.RecordFile("@1", "/repo/b.go")
+	u.RecordFile("@1", "/repo/a.go")
+	u.RecordFile("@1", "/repo/a.go")
+	u.RecordTokens("@1", 100, 50, 1000)
+	u.RecordTaskPicked("@1", "t1", "t2")
+	u.RecordTaskPicked("@1", "t1")
+	u.RecordTaskFinished("@1", "t1")
+
+	if err := u.SaveIfDirty(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadUsage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wu, ok := loaded.GetWindowUsage(Day(time.Now()), "@1")
+	if !ok {
+		t.Fatal("window usage not found")
+	}
+	if wu.Turns != 2 || wu.BrewedSeconds != 120 {
+		t.Errorf("turns=%d brewed=%d, want 2 and 120", wu.Turns, wu.BrewedSeconds)
+	}
+	if files := wu.Files(); len(files) != 2 || files[0] != "/repo/a.go" {
+		t.Errorf("files = %v", files)
+	}
+	if wu.InputTokens != 100 || wu.OutputTokens != 50 || wu.CacheTokens != 1000 {
+		t.Errorf("tokens = %+v", wu)
+	}
+	if len(wu.TasksPicked) != 2 || len(wu.TasksFinished) != 1 {
+		t.Errorf("picked=%v finished=%v", wu.TasksPicked, wu.TasksFinished)
+	}
+}
+
+func TestUsage_NilSafe(t *testing.T) {
+	var u *Usage
+	u.RecordTurn("@1", time.Second)
+	u.RecordFile("@1", "/a")
+	u.RecordTokens("@1", 1, 1, 1)
+	u.RecordTaskPicked("@1", "t")
+	u.RecordTaskFinished("@1", "t")
+}
+
+func TestUsage_PrunesOldDays(t *testing.T) {
+	u := NewUsage()
+	day := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	u.now = func() time.Time { return day }
+	u.RecordTurn("@1", time.Second)
+
+	day = day.AddDate(0, 0, usageRetentionDays+1)
+	u.RecordTurn("@1", time.Second)
+
+	if _, ok := u.GetWindowUsage("2025-03-01", "@1"); ok {
+		t.Error("old day should have been pruned")
+	}
+	if _, ok := u.GetWindowUsage(Day(day), "@1"); !ok {
+		t.Error("current day missing")
+	}
+}
+
+func TestUsage_MarkDigestSent(t *testing.T) {
+	u := NewUsage()
+	if !u.MarkDigestSent("2025-03-01") {
+		t.Error("first mark should succeed")
+	}
+	if u.MarkDigestSent("2025-03-01") {
+		t.Error("second mark for same day should fail")
+	}
+	if !u.MarkDigestSent("2025-03-02") {
+		t.Error("next day should succeed")
+	}
+}
+
+func TestUsage_MarkWorktreeCheck(t *testing.T) {
+	u := NewUsage()
+	u.MarkDigestSent("2025-03-01")
+	if !u.MarkWorktreeCheck("2025-03-01") {
+		t.Error("worktree check is tracked apart from the digest")
+	}
+	if u.MarkWorktreeCheck("2025-03-01") {
+		t.Error("second check for same day should fail")
+	}
+}
+
+func TestUsage_ProjectCost(t *testing.T) {
+	u := NewUsage()
+	u.RecordCost("@0", 8) // before the project function is set
+	u.SetProjectFunc(func(windowID string) string {
+		if windowID == "@3" {
+			return "web"
+		}
+		return "api"
+	})
+	u.RecordCost("@1", 1.5)
+	u.RecordCost("@1", 0.25)
+	u.RecordCost("@2", 2)
+	u.RecordCost("@3", 4)
+
+	// Spend stays with the project whatever becomes of its windows
+	u.SetProjectFunc(func(string) string { return "" })
+	today := Day(time.Now())
+	if got := u.ProjectCost([]string{today, "2000-01-01"}, "api"); got != 3.75 {
+		t.Errorf("ProjectCost(api) = %v, want 3.75", got)
+	}
+	if got := u.ProjectCost([]string{today}, "web"); got != 4 {
+		t.Errorf("ProjectCost(web) = %v, want 4", got)
+	}
+	if wu, _ := u.GetWindowUsage(today, "@1"); wu.CostUSD != 1.75 {
+		t.Errorf("window cost = %v, want 1.75", wu.CostUSD)
+	}
+	if got := (*Usage)(nil).ProjectCost([]string{today}, "api"); got != 0 {
+		t.Errorf("nil ProjectCost = %v", got)
+	}
+}
@@ -0,0 +1,140 @@
+// Package supervise keeps long-running loops alive: a loop that panics, or
+// whose heartbeat stops, is restarted and the restart reported.
+package supervise
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+)
+
+// Backoff between restarts, doubling from minBackoff up to maxBackoff. A loop
+// that ran for maxBackoff before failing starts again from minBackoff.
+var (
+	minBackoff = time.Second
+	maxBackoff = time.Minute
+)
+
+// shutdownWait bounds how long Run waits on shutdown for a running instance
+// to return; one that is stuck is left behind.
+var shutdownWait = 5 * time.Second
+
+// Heartbeat records when a loop last made progress. A nil Heartbeat ignores beats.
+type Heartbeat struct {
+	last atomic.Int64 // unix nanoseconds
+}
+
+// NewHeartbeat creates a Heartbeat that last beat now.
+func NewHeartbeat() *Heartbeat {
+	h := &Heartbeat{}
+	h.Beat()
+	return h
+}
+
+// Beat records progress.
+func (h *Heartbeat) Beat() {
+	if h != nil {
+		h.last.Store(time.Now().UnixNano())
+	}
+}
+
+// Since returns how long ago the last beat was.
+func (h *Heartbeat) Since() time.Duration {
+	return time.Since(time.Unix(0, h.last.Load()))
+}
+
+// Task is a supervised loop.
+type Task struct {
+	Name string
+	// Start runs one instance of the loop until ctx is cancelled, beating hb
+	// as it makes progress. After a stall the stuck instance's ctx is
+	// cancelled and it is abandoned: it may still wake up while its
+	// replacement runs, so Start should build fresh state, and stop touching
+	// anything shared once ctx is done.
+	Start func(ctx context.Context, hb *Heartbeat)
+	// Stall restarts the loop when it hasn't beaten for this long; 0 only
+	// restarts it after a panic or an unexpected return.
+	Stall time.Duration
+}
+
+// Run runs t until ctx is cancelled, restarting it whenever it panics,
+// stalls or returns early, and calling onRestart with the reason each time.
+// Blocks until ctx is cancelled and the running instance has returned, or
+// shutdownWait has passed.
+func Run(ctx context.Context, t Task, onRestart func(name, reason string)) {
+	backoff := minBackoff
+	for {
+		started := time.Now()
+		reason, stopped := runOnce(ctx, t)
+		if stopped {
+			return
+		}
+		log.Printf("Watchdog: restarting %s: %s", t.Name, reason)
+		if onRestart != nil {
+			onRestart(t.Name, reason)
+		}
+
+		if time.Since(started) >= maxBackoff {
+			backoff = minBackoff
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+// runOnce runs one instance of t. It returns why the instance ended, or
+// stopped if that was ctx being cancelled.
+func runOnce(ctx context.Context, t Task) (reason string, stopped bool) {
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	hb := NewHeartbeat()
+	done := make(chan string, 1) // why the instance returned; "" for no panic
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("Watchdog: %s panicked: %v\n%s", t.Name, r, debug.Stack())
+				done <- fmt.Sprintf("panic: %v", r)
+				return
+			}
+			done <- ""
+		}()
+		t.Start(runCtx, hb)
+	}()
+
+	interval := time.Second
+	if t.Stall > 0 {
+		interval = min(t.Stall/4, interval)
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			select {
+			case <-done:
+			case <-time.After(shutdownWait):
+				log.Printf("Watchdog: %s did not stop within %s; leaving it behind", t.Name, shutdownWait)
+			}
+			return "", true
+		case r := <-done:
+			if ctx.Err() != nil {
+				return "", true
+			}
+			if r == "" {
+				r = "returned unexpectedly"
+			}
+			return r, false
+		case <-ticker.C:
+			if t.Stall > 0 && hb.Since() > t.Stall {
+				return fmt.Sprintf("no progress for %s", hb.Since().Round(time.Second)), false
+			}
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package supervise
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func init() {
+	minBackoff = time.Millisecond
+	maxBackoff = 10 * time.Millisecond
+	shutdownWait = 50 * time.Millisecond
+}
+
+// restarts collects onRestart calls.
+type restarts struct {
+	mu      sync.Mutex
+	reasons []string
+}
+
+func (r *restarts) add(name, reason string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reasons = append(r.reasons, name+": "+reason)
+}
+
+func (r *restarts) get() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.reasons...)
+}
+
+func TestRun_RestartsAfterPanic(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var starts atomic.Int32
+	var r restarts
+	stopped := make(chan struct{})
+	go func() {
+		Run(ctx, Task{Name: "loop", Start: func(ctx context.Context, hb *Heartbeat) {
+			if starts.Add(1) == 1 {
+				panic("boom")
+			}
+			<-ctx.Done()
+		}}, r.add)
+		close(stopped)
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for starts.Load() < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got := r.get(); len(got) != 1 || got[0] != "loop: panic: boom" {
+		t.Errorf("restarts = %q", got)
+	}
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after cancel")
+	}
+	if n := starts.Load(); n != 2 {
+		t.Errorf("started %d times, want 2", n)
+	}
+}
+
+func TestRun_RestartsAfterStall(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var starts atomic.Int32
+	var r restarts
+	stuck := make(chan struct{})
+	defer close(stuck)
+	abandoned := make(chan context.Context, 1)
+	go Run(ctx, Task{Name: "poller", Stall: 50 * time.Millisecond, Start: func(ctx context.Context, hb *Heartbeat) {
+		if starts.Add(1) == 1 {
+			abandoned <- ctx
+			<-stuck // deadlocked: ignores ctx and never beats
+			return
+		}
+		for ctx.Err() == nil {
+			hb.Beat()
+			time.Sleep(5 * time.Millisecond)
+		}
+	}}, r.add)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for starts.Load() < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(150 * time.Millisecond) // the beating instance isn't restarted
+	got := r.get()
+	if len(got) != 1 || !strings.HasPrefix(got[0], "poller: no progress for") {
+		t.Errorf("restarts = %q", got)
+	}
+	if (<-abandoned).Err() == nil {
+		t.Error("the stuck instance's context should be cancelled")
+	}
+}
+
+func TestRun_ShutdownDoesNotWaitForStuckInstance(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	stuck := make(chan struct{})
+	defer close(stuck)
+	started := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		Run(ctx, Task{Name: "loop", Start: func(ctx context.Context, hb *Heartbeat) {
+			close(started)
+			<-stuck // ignores ctx
+		}}, nil)
+		close(stopped)
+	}()
+
+	<-started
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Run waited for an instance that ignores its context")
+	}
+}
+
+func TestRun_RestartsAfterReturn(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var r restarts
+	var starts atomic.Int32
+	go Run(ctx, Task{Name: "loop", Start: func(ctx context.Context, hb *Heartbeat) {
+		if starts.Add(1) > 1 {
+			<-ctx.Done()
+		}
+	}}, r.add)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for starts.Load() < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got := r.get(); len(got) != 1 || got[0] != "loop: returned unexpectedly" {
+		t.Errorf("restarts = %q", got)
+	}
+}
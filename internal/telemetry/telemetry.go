@@ -0,0 +1,143 @@
+// Package telemetry provides optional OpenTelemetry tracing. Until Setup
+// installs an exporter every span is a no-op.
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentation = "github.com/otaviocarvalho/tramuntana"
+
+var enabled atomic.Bool
+
+// Setup exports spans over OTLP/HTTP, configured by the standard
+// OTEL_EXPORTER_OTLP_* and OTEL_SERVICE_NAME variables. The returned function
+// flushes and stops the exporter.
+func Setup(ctx context.Context) (func(context.Context) error, error) {
+	exp, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("creating OTLP exporter: %w", err)
+	}
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", "tramuntana")),
+		resource.WithFromEnv(), // OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES win
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("building trace resource: %w", err)
+	}
+	tp := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exp), sdktrace.WithResource(res))
+	Use(tp)
+	return tp.Shutdown, nil
+}
+
+// Use installs a tracer provider, e.g. an in-memory one in tests.
+func Use(tp trace.TracerProvider) {
+	otel.SetTracerProvider(tp)
+	enabled.Store(true)
+}
+
+// Enabled reports whether spans are recorded.
+func Enabled() bool {
+	return enabled.Load()
+}
+
+// Start starts a span.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentation).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// StartAt is Start with an explicit start time, for stages that began before
+// the span could be created.
+func StartAt(ctx context.Context, name string, at time.Time, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentation).Start(ctx, name, trace.WithTimestamp(at), trace.WithAttributes(attrs...))
+}
+
+// StartChild starts a span under the span in ctx. Without one it returns a
+// no-op span, so background polling does not produce a trace per tmux command
+// or API call.
+func StartChild(ctx context.Context, name string, attrs ...attribute.KeyValue) trace.Span {
+	if !enabled.Load() || ctx == nil || !trace.SpanContextFromContext(ctx).IsValid() {
+		return trace.SpanFromContext(context.Background())
+	}
+	_, span := Start(ctx, name, attrs...)
+	return span
+}
+
+// End records err on span, if any, and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// HTTPDoer is the client interface of the Telegram Bot API library.
+type HTTPDoer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// tracedClient adds a span per Bot API request.
+type tracedClient struct {
+	next HTTPDoer
+}
+
+// WrapHTTPClient returns c with a span per Bot API request, named after the
+// API method, under the span in the request's context (see WithContext). The
+// URL, which embeds the bot token, is never recorded.
+func WrapHTTPClient(c HTTPDoer) HTTPDoer {
+	return tracedClient{next: c}
+}
+
+func (c tracedClient) Do(req *http.Request) (*http.Response, error) {
+	method := path.Base(req.URL.Path)
+	span := StartChild(req.Context(), "telegram."+method, attribute.String("telegram.method", method))
+	resp, err := c.next.Do(req)
+	if resp != nil {
+		span.SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
+		if resp.StatusCode >= 400 && err == nil {
+			span.SetStatus(codes.Error, resp.Status)
+		}
+	}
+	// Transport errors quote the URL, token included
+	recorded := err
+	var uerr *url.Error
+	if errors.As(err, &uerr) {
+		recorded = uerr.Err
+	}
+	End(span, recorded)
+	return resp, err
+}
+
+// contextClient sends every request with one context.
+type contextClient struct {
+	ctx  context.Context
+	next HTTPDoer
+}
+
+// WithContext returns c sending every request with ctx, so the Bot API spans
+// of a client wrapped by WrapHTTPClient nest under the span in ctx. The Bot
+// API library builds its requests without a context.
+func WithContext(ctx context.Context, c HTTPDoer) HTTPDoer {
+	return contextClient{ctx: ctx, next: c}
+}
+
+func (c contextClient) Do(req *http.Request) (*http.Response, error) {
+	return c.next.Do(req.WithContext(c.ctx))
+}
@@ -0,0 +1,86 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func useRecorder(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	rec := tracetest.NewSpanRecorder()
+	Use(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	return rec
+}
+
+func TestStartChild_NestsUnderContextSpan(t *testing.T) {
+	rec := useRecorder(t)
+
+	// No span in the context: nothing is recorded
+	StartChild(context.Background(), "tmux.orphan").End()
+
+	ctx, parent := Start(context.Background(), "telegram.update")
+	StartChild(ctx, "tmux.send-keys").End()
+	parent.End()
+
+	ended := rec.Ended()
+	if len(ended) != 2 {
+		t.Fatalf("got %d spans, want 2", len(ended))
+	}
+	child, root := ended[0], ended[1]
+	if child.Name() != "tmux.send-keys" || root.Name() != "telegram.update" {
+		t.Fatalf("spans = %q, %q", child.Name(), root.Name())
+	}
+	if child.Parent().SpanID() != root.SpanContext().SpanID() {
+		t.Error("child span is not parented to the context's span")
+	}
+}
+
+type fakeDoer struct{ err error }
+
+func (f fakeDoer) Do(req *http.Request) (*http.Response, error) {
+	if f.err != nil {
+		return nil, &url.Error{Op: "Post", URL: req.URL.String(), Err: f.err}
+	}
+	return &http.Response{StatusCode: 200, Status: "200 OK"}, nil
+}
+
+func TestWrapHTTPClient(t *testing.T) {
+	rec := useRecorder(t)
+	ctx, parent := Start(context.Background(), "queue.deliver")
+	defer parent.End()
+
+	req, _ := http.NewRequest("POST", "https://api.telegram.org/bot123:SECRET/sendMessage", nil)
+	if _, err := WithContext(ctx, WrapHTTPClient(fakeDoer{})).Do(req); err != nil {
+		t.Fatal(err)
+	}
+	WithContext(ctx, WrapHTTPClient(fakeDoer{err: errors.New("connection reset")})).Do(req)
+	// Without a span in its context a request is not traced
+	WrapHTTPClient(fakeDoer{}).Do(req)
+
+	ended := rec.Ended()
+	if len(ended) != 2 {
+		t.Fatalf("got %d spans, want 2", len(ended))
+	}
+	for _, span := range ended {
+		if span.Name() != "telegram.sendMessage" {
+			t.Errorf("span name = %q", span.Name())
+		}
+		for _, ev := range span.Events() {
+			for _, attr := range ev.Attributes {
+				if strings.Contains(attr.Value.Emit(), "SECRET") {
+					t.Errorf("span event leaks the bot token: %s", attr.Value.Emit())
+				}
+			}
+		}
+		if strings.Contains(span.Status().Description, "SECRET") {
+			t.Errorf("span status leaks the bot token: %s", span.Status().Description)
+		}
+	}
+}
@@ -0,0 +1,315 @@
+// Package testharness provides in-process fakes for end-to-end tests: a Telegram
+// Bot API server that records calls and a scripted tmux that stands in for the
+// tmux binary.
+package testharness
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// waitTimeout bounds the WaitFor helpers.
+const waitTimeout = 10 * time.Second
+
+// TopicIcons are the forum topic icons the fake server offers, emoji → custom
+// emoji ID.
+var TopicIcons = map[string]string{
+	"⚡️": "icon-working",
+	"❓":  "icon-input",
+	"✔️": "icon-idle",
+	"❗️": "icon-dead",
+}
+
+// Call is one Bot API request received by the fake server.
+type Call struct {
+	Method    string
+	Params    map[string]string
+	MessageID int // message ID returned for sendMessage/sendPhoto/sendDocument
+}
+
+// Telegram is a fake Bot API server. It records every request, hands out
+// message IDs, serves scripted updates to getUpdates and can answer with 429s.
+type Telegram struct {
+	t      testing.TB
+	server *httptest.Server
+
+	mu            sync.Mutex
+	calls         []Call
+	nextMessageID int
+	updates       []map[string]any
+	nextUpdateID  int
+	floods        map[string][]int // method → queued retry_after values
+	deleted       map[topic]bool   // topics that answer sends with "message thread not found"
+}
+
+type topic struct {
+	chatID   int64
+	threadID int
+}
+
+// NewTelegram starts a fake Bot API server, closed when the test ends.
+func NewTelegram(t testing.TB) *Telegram {
+	tg := &Telegram{
+		t:             t,
+		nextMessageID: 1000,
+		nextUpdateID:  1,
+		floods:        make(map[string][]int),
+		deleted:       make(map[topic]bool),
+	}
+	tg.server = httptest.NewServer(http.HandlerFunc(tg.serve))
+	t.Cleanup(tg.server.Close)
+	return tg
+}
+
+// Endpoint returns the fake server's Bot API endpoint format, for clients
+// that make their own requests.
+func (tg *Telegram) Endpoint() string {
+	return tg.server.URL + "/bot%s/%s"
+}
+
+// API returns a client pointed at the fake server.
+func (tg *Telegram) API() *tgbotapi.BotAPI {
+	api, err := tgbotapi.NewBotAPIWithClient("test-token", tg.Endpoint(), tg.server.Client())
+	if err != nil {
+		tg.t.Fatalf("creating fake bot API: %v", err)
+	}
+	return api
+}
+
+// Flood makes the next request to method fail with 429 and the given retry_after.
+func (tg *Telegram) Flood(method string, retryAfter int) {
+	tg.mu.Lock()
+	tg.floods[method] = append(tg.floods[method], retryAfter)
+	tg.mu.Unlock()
+}
+
+// DeleteTopic makes later sends into a topic fail the way Telegram answers
+// sends into a deleted topic.
+func (tg *Telegram) DeleteTopic(chatID int64, threadID int) {
+	tg.mu.Lock()
+	tg.deleted[topic{chatID, threadID}] = true
+	tg.mu.Unlock()
+}
+
+// PushMessage queues a text message in a forum topic for getUpdates and returns its message ID.
+func (tg *Telegram) PushMessage(chatID int64, threadID int, userID int64, text string) int {
+	return tg.PushReply(chatID, threadID, userID, 0, text)
+}
+
+// PushReply is PushMessage for a message replying to replyTo (none if 0).
+func (tg *Telegram) PushReply(chatID int64, threadID int, userID int64, replyTo int, text string) int {
+	tg.mu.Lock()
+	defer tg.mu.Unlock()
+	msg := tg.newMessageLocked(chatID, threadID, text)
+	msg["from"] = map[string]any{"id": userID, "is_bot": false, "first_name": "User" + strconv.FormatInt(userID, 10)}
+	if strings.HasPrefix(text, "/") {
+		cmd, _, _ := strings.Cut(text, " ")
+		msg["entities"] = []map[string]any{{"type": "bot_command", "offset": 0, "length": len(cmd)}}
+	}
+	if replyTo != 0 {
+		msg["reply_to_message"] = map[string]any{
+			"message_id": replyTo,
+			"date":       time.Now().Unix(),
+			"chat":       msg["chat"],
+		}
+	}
+	tg.pushLocked("message", msg)
+	return msg["message_id"].(int)
+}
+
+// PushCallback queues an inline button press on messageID for getUpdates.
+func (tg *Telegram) PushCallback(chatID int64, threadID int, userID int64, messageID int, data string) {
+	tg.mu.Lock()
+	defer tg.mu.Unlock()
+	msg := tg.newMessageLocked(chatID, threadID, "")
+	msg["message_id"] = messageID
+	tg.pushLocked("callback_query", map[string]any{
+		"id":      strconv.Itoa(tg.nextUpdateID),
+		"from":    map[string]any{"id": userID, "is_bot": false, "first_name": "User" + strconv.FormatInt(userID, 10)},
+		"message": msg,
+		"data":    data,
+	})
+}
+
+// Calls returns the recorded requests to method, or all requests if method is empty.
+func (tg *Telegram) Calls(method string) []Call {
+	tg.mu.Lock()
+	defer tg.mu.Unlock()
+	var out []Call
+	for _, c := range tg.calls {
+		if method == "" || c.Method == method {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// WaitFor blocks until a request to method satisfies match, failing the test on timeout.
+func (tg *Telegram) WaitFor(method string, match func(Call) bool) Call {
+	tg.t.Helper()
+	deadline := time.Now().Add(waitTimeout)
+	for time.Now().Before(deadline) {
+		for _, c := range tg.Calls(method) {
+			if match == nil || match(c) {
+				return c
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	tg.t.Fatalf("timed out waiting for %s; got %d calls: %v", method, len(tg.Calls("")), tg.Calls(""))
+	return Call{}
+}
+
+// WaitForText waits for a request to method whose text contains substr.
+func (tg *Telegram) WaitForText(method, substr string) Call {
+	tg.t.Helper()
+	return tg.WaitFor(method, func(c Call) bool {
+		return strings.Contains(c.Params["text"], substr)
+	})
+}
+
+func (tg *Telegram) newMessageLocked(chatID int64, threadID int, text string) map[string]any {
+	id := tg.nextMessageID
+	tg.nextMessageID++
+	msg := map[string]any{
+		"message_id": id,
+		"date":       time.Now().Unix(),
+		"chat":       map[string]any{"id": chatID, "type": "supergroup", "is_forum": true},
+		"text":       text,
+	}
+	if threadID != 0 {
+		msg["message_thread_id"] = threadID
+		msg["is_topic_message"] = true
+	}
+	return msg
+}
+
+func (tg *Telegram) pushLocked(kind string, payload map[string]any) {
+	tg.updates = append(tg.updates, map[string]any{"update_id": tg.nextUpdateID, kind: payload})
+	tg.nextUpdateID++
+}
+
+func (tg *Telegram) serve(w http.ResponseWriter, r *http.Request) {
+	method := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		r.ParseForm()
+	}
+	params := make(map[string]string)
+	for k, v := range r.Form {
+		params[k] = v[0]
+	}
+	if r.MultipartForm != nil {
+		for field, files := range r.MultipartForm.File {
+			params[field] = files[0].Filename // uploads are recorded by file name
+		}
+	}
+
+	if method == "getUpdates" {
+		tg.writeResult(w, tg.takeUpdates(params["offset"]))
+		return
+	}
+
+	tg.mu.Lock()
+	if queued := tg.floods[method]; len(queued) > 0 {
+		tg.floods[method] = queued[1:]
+		tg.mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]any{
+			"ok":          false,
+			"error_code":  429,
+			"description": fmt.Sprintf("Too Many Requests: retry after %d", queued[0]),
+			"parameters":  map[string]any{"retry_after": queued[0]},
+		})
+		return
+	}
+	if strings.HasPrefix(method, "send") {
+		chatID, _ := strconv.ParseInt(params["chat_id"], 10, 64)
+		threadID, _ := strconv.Atoi(params["message_thread_id"])
+		if tg.deleted[topic{chatID, threadID}] {
+			tg.mu.Unlock()
+			json.NewEncoder(w).Encode(map[string]any{
+				"ok":          false,
+				"error_code":  400,
+				"description": "Bad Request: message thread not found",
+			})
+			return
+		}
+	}
+
+	call := Call{Method: method, Params: params}
+	var result any = true
+	switch method {
+	case "getMe":
+		result = map[string]any{"id": 1, "is_bot": true, "first_name": "Test", "username": "test_bot"}
+	case "getForumTopicIconStickers":
+		var stickers []map[string]any
+		for emoji, id := range TopicIcons {
+			stickers = append(stickers, map[string]any{"emoji": emoji, "custom_emoji_id": id})
+		}
+		result = stickers
+	case "sendMessage", "sendPhoto", "sendDocument":
+		chatID, _ := strconv.ParseInt(params["chat_id"], 10, 64)
+		threadID, _ := strconv.Atoi(params["message_thread_id"])
+		msg := tg.newMessageLocked(chatID, threadID, params["text"])
+		call.MessageID = msg["message_id"].(int)
+		result = msg
+	case "createForumTopic":
+		// A topic's thread ID is the ID of the service message that opens it
+		threadID := tg.nextMessageID
+		tg.nextMessageID++
+		call.MessageID = threadID
+		result = map[string]any{"message_thread_id": threadID, "name": params["name"], "icon_color": 7322096}
+	case "editMessageText":
+		chatID, _ := strconv.ParseInt(params["chat_id"], 10, 64)
+		messageID, _ := strconv.Atoi(params["message_id"])
+		result = map[string]any{
+			"message_id": messageID,
+			"date":       time.Now().Unix(),
+			"chat":       map[string]any{"id": chatID, "type": "supergroup"},
+			"text":       params["text"],
+		}
+	}
+	tg.calls = append(tg.calls, call)
+	tg.mu.Unlock()
+
+	tg.writeResult(w, result)
+}
+
+// takeUpdates returns queued updates at or after offset, waiting briefly when
+// there are none so a polling bot does not spin.
+func (tg *Telegram) takeUpdates(offsetParam string) []map[string]any {
+	offset, _ := strconv.Atoi(offsetParam)
+	deadline := time.Now().Add(50 * time.Millisecond)
+	for {
+		tg.mu.Lock()
+		var out, keep []map[string]any
+		for _, u := range tg.updates {
+			if u["update_id"].(int) >= offset {
+				out = append(out, u)
+				keep = append(keep, u)
+			}
+		}
+		tg.updates = keep
+		tg.mu.Unlock()
+		if len(out) > 0 || time.Now().After(deadline) {
+			if out == nil {
+				out = []map[string]any{}
+			}
+			return out
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func (tg *Telegram) writeResult(w http.ResponseWriter, result any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"ok": true, "result": result})
+}
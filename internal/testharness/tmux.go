@@ -0,0 +1,414 @@
+package testharness
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/otaviocarvalho/tramuntana/internal/tmux"
+)
+
+// ReadyPane is pane text that passes tmux.WaitForReady: an idle Claude Code prompt.
+var ReadyPane = strings.Repeat("─", 40) + "\n❯ \n" + strings.Repeat("─", 40) + "\n"
+
+// Window is one window in the scripted tmux session.
+type Window struct {
+	ID       string
+	Name     string
+	CWD      string
+	Pane     string   // returned by capture-pane
+	History  []string // scrollback lines above the pane, oldest first
+	Command  string   // pane_current_command
+	Activity int64    // window_activity
+	Keys     []string
+
+	scroll int    // copy mode: lines scrolled up from the pane
+	cursor string // copy mode: the line the cursor is on
+	input  string // typed since the last Enter, shown on the "❯" line
+	drop   int    // literal sends still to lose
+}
+
+// screen returns the pane with the typed input on its last "❯" prompt line.
+func (w *Window) screen() string {
+	if w.input == "" {
+		return w.Pane
+	}
+	lines := strings.Split(w.Pane, "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		if strings.HasPrefix(strings.TrimSpace(lines[i]), "❯") {
+			lines[i] = "❯ " + w.input
+			return strings.Join(lines, "\n")
+		}
+	}
+	return w.Pane
+}
+
+// lines returns the scrollback and pane lines, and where the pane starts.
+func (w *Window) lines() ([]string, int) {
+	pane := strings.Split(strings.TrimSuffix(w.Pane, "\n"), "\n")
+	return append(append([]string{}, w.History...), pane...), len(w.History)
+}
+
+// Tmux is a scripted stand-in for the tmux binary, installed with tmux.SetRunner.
+// It keeps an in-memory session of windows, records keys sent to them and
+// reports missing windows the way tmux does.
+type Tmux struct {
+	t       testing.TB
+	session string
+
+	mu      sync.Mutex
+	exists  bool
+	windows []*Window
+	nextID  int
+	buffers map[string]string
+
+	// OnNewWindow, if set, is called (without the lock held) for each window
+	// created via new-window, e.g. to write the session_map entry the hook would.
+	OnNewWindow func(w Window)
+}
+
+// NewTmux installs a scripted tmux for the duration of the test.
+func NewTmux(t testing.TB, session string) *Tmux {
+	f := &Tmux{t: t, session: session, nextID: 1, buffers: make(map[string]string)}
+	tmux.SetRunner(f.run)
+	t.Cleanup(func() { tmux.SetRunner(nil) })
+	return f
+}
+
+// AddWindow adds a window running Claude and returns its ID.
+func (f *Tmux) AddWindow(name, cwd string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.exists = true
+	return f.addWindowLocked(name, cwd).ID
+}
+
+// SetPane replaces a window's pane text and bumps its activity.
+func (f *Tmux) SetPane(windowID, text string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if w := f.windowLocked(windowID); w != nil {
+		w.Pane = text
+		w.Activity = time.Now().Unix()
+	}
+}
+
+// SetCWD changes a window's pane_current_path, as if its shell had cd'd.
+func (f *Tmux) SetCWD(windowID, cwd string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if w := f.windowLocked(windowID); w != nil {
+		w.CWD = cwd
+	}
+}
+
+// SetHistory replaces the scrollback above a window's pane.
+func (f *Tmux) SetHistory(windowID string, lines ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if w := f.windowLocked(windowID); w != nil {
+		w.History = lines
+	}
+}
+
+// DropTyping makes the next n literal sends to a window get lost, as if tmux
+// mangled them.
+func (f *Tmux) DropTyping(windowID string, n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if w := f.windowLocked(windowID); w != nil {
+		w.drop = n
+	}
+}
+
+// Kill removes a window, as if its process tree exited.
+func (f *Tmux) Kill(windowID string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.removeLocked(windowID)
+}
+
+// Window returns a copy of a window, if it exists.
+func (f *Tmux) Window(windowID string) (Window, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if w := f.windowLocked(windowID); w != nil {
+		return *w, true
+	}
+	return Window{}, false
+}
+
+// Windows returns copies of all windows in creation order.
+func (f *Tmux) Windows() []Window {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := make([]Window, len(f.windows))
+	for i, w := range f.windows {
+		out[i] = *w
+	}
+	return out
+}
+
+// WaitForKeys blocks until text has been typed into a window, failing the test on timeout.
+func (f *Tmux) WaitForKeys(windowID, text string) {
+	f.t.Helper()
+	deadline := time.Now().Add(waitTimeout)
+	for time.Now().Before(deadline) {
+		if w, ok := f.Window(windowID); ok {
+			for _, k := range w.Keys {
+				if k == text {
+					return
+				}
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	w, _ := f.Window(windowID)
+	f.t.Fatalf("timed out waiting for %q in %s; keys: %q", text, windowID, w.Keys)
+}
+
+func (f *Tmux) addWindowLocked(name, cwd string) *Window {
+	w := &Window{
+		ID:       "@" + strconv.Itoa(f.nextID),
+		Name:     name,
+		CWD:      cwd,
+		Pane:     ReadyPane,
+		Command:  "claude",
+		Activity: time.Now().Unix(),
+	}
+	f.nextID++
+	f.windows = append(f.windows, w)
+	return w
+}
+
+func (f *Tmux) windowLocked(id string) *Window {
+	for _, w := range f.windows {
+		if w.ID == id {
+			return w
+		}
+	}
+	return nil
+}
+
+func (f *Tmux) removeLocked(id string) bool {
+	for i, w := range f.windows {
+		if w.ID == id {
+			f.windows = append(f.windows[:i], f.windows[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// run executes one tmux command against the in-memory session.
+func (f *Tmux) run(args ...string) (string, error) {
+	if len(args) == 0 {
+		return "", fmt.Errorf("no command")
+	}
+	opts, rest := parseFlags(args[1:])
+
+	f.mu.Lock()
+	var created *Window
+	out, err := func() (string, error) {
+		switch args[0] {
+		case "has-session":
+			if !f.exists || opts["-t"] != f.session {
+				return "", fmt.Errorf("can't find session: %s", opts["-t"])
+			}
+			return "", nil
+		case "new-session":
+			f.exists = true
+			f.addWindowLocked(opts["-n"], "/")
+			return "", nil
+		case "list-windows":
+			if !f.exists {
+				return "", fmt.Errorf("can't find session: %s", opts["-t"])
+			}
+			var lines []string
+			for _, w := range f.windows {
+				lines = append(lines, expandFormat(opts["-F"], w))
+			}
+			return strings.Join(lines, "\n") + "\n", nil
+		case "new-window":
+			created = f.addWindowLocked(opts["-n"], opts["-c"])
+			return expandFormat(opts["-F"], created) + "\n", nil
+		case "set-buffer":
+			if len(rest) == 0 {
+				return "", fmt.Errorf("no data specified")
+			}
+			f.buffers[opts["-b"]] = rest[0]
+			return "", nil
+		case "set-environment", "rename-window", "kill-window", "send-keys", "capture-pane", "display-message", "paste-buffer", "copy-mode":
+		default:
+			return "", fmt.Errorf("unknown command %s", args[0])
+		}
+
+		w := f.windowLocked(targetWindow(opts["-t"]))
+		if w == nil {
+			if args[0] == "set-environment" {
+				return "", nil
+			}
+			return "", fmt.Errorf("can't find window: %s", targetWindow(opts["-t"]))
+		}
+		switch args[0] {
+		case "rename-window":
+			if len(rest) > 0 {
+				w.Name = rest[0]
+			}
+		case "kill-window":
+			f.removeLocked(w.ID)
+		case "copy-mode":
+			w.scroll, w.cursor = 0, ""
+		case "send-keys":
+			if _, copyCmd := opts["-X"]; copyCmd {
+				copyModeCommand(w, rest)
+				break
+			}
+			_, literal := opts["-l"]
+			if _, hex := opts["-H"]; hex {
+				literal, rest = true, []string{decodeHexKeys(rest)}
+			}
+			switch {
+			case literal && w.drop > 0:
+				w.drop--
+			case literal:
+				text := strings.Join(rest, " ")
+				w.Keys = append(w.Keys, text)
+				w.input += text
+			default:
+				w.Keys = append(w.Keys, rest...)
+				typeKeys(w, opts["-N"], rest)
+			}
+		case "paste-buffer":
+			data, ok := f.buffers[opts["-b"]]
+			if !ok {
+				return "", fmt.Errorf("no buffer %s", opts["-b"])
+			}
+			w.Keys = append(w.Keys, data)
+			w.input += data
+			if _, del := opts["-d"]; del {
+				delete(f.buffers, opts["-b"])
+			}
+		case "capture-pane":
+			if start, ok := opts["-S"]; ok {
+				lines, paneStart := w.lines()
+				from, _ := strconv.Atoi(start)
+				to, _ := strconv.Atoi(opts["-E"])
+				from, to = max(paneStart+from, 0), min(paneStart+to, len(lines)-1)
+				if from > to {
+					return "", nil
+				}
+				return strings.Join(lines[from:to+1], "\n") + "\n", nil
+			}
+			return w.screen(), nil
+		case "display-message":
+			if len(rest) > 0 {
+				return expandFormat(rest[0], w) + "\n", nil
+			}
+		}
+		return "", nil
+	}()
+	f.mu.Unlock()
+
+	if created != nil && f.OnNewWindow != nil {
+		f.OnNewWindow(*created)
+	}
+	return out, err
+}
+
+// copyModeCommand runs a send-keys -X copy-mode command: a backward search
+// scrolls the match to the top of the pane, or keeps the view if it's on it.
+func copyModeCommand(w *Window, args []string) {
+	if len(args) < 2 || args[0] != "search-backward-text" {
+		w.scroll, w.cursor = 0, ""
+		return
+	}
+	lines, paneStart := w.lines()
+	for i := len(lines) - 1; i >= 0; i-- {
+		if strings.Contains(strings.ToLower(lines[i]), strings.ToLower(args[1])) {
+			w.scroll, w.cursor = max(paneStart-i, 0), lines[i]
+			return
+		}
+	}
+}
+
+// typeKeys applies named keys to a window's input, repeat times each.
+func typeKeys(w *Window, repeat string, keys []string) {
+	n, err := strconv.Atoi(repeat)
+	if err != nil {
+		n = 1
+	}
+	for _, key := range keys {
+		for range n {
+			switch key {
+			case "Enter":
+				w.input = ""
+			case "BSpace":
+				if r := []rune(w.input); len(r) > 0 {
+					w.input = string(r[:len(r)-1])
+				}
+			}
+		}
+	}
+}
+
+// decodeHexKeys turns send-keys -H arguments into the text they type.
+func decodeHexKeys(args []string) string {
+	var b []byte
+	for _, a := range args {
+		if c, err := strconv.ParseUint(a, 16, 8); err == nil {
+			b = append(b, byte(c))
+		}
+	}
+	return string(b)
+}
+
+// parseFlags splits tmux arguments into flags (with values for the ones that
+// take one) and positional arguments.
+func parseFlags(args []string) (map[string]string, []string) {
+	valued := map[string]bool{"-t": true, "-n": true, "-c": true, "-F": true, "-s": true, "-f": true, "-b": true, "-S": true, "-E": true, "-N": true}
+	opts := make(map[string]string)
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == "--" && len(rest) == 0:
+			return opts, append(rest, args[i+1:]...)
+		case valued[a] && i+1 < len(args):
+			opts[a] = args[i+1]
+			i++
+		case strings.HasPrefix(a, "-") && len(a) == 2 && len(rest) == 0:
+			opts[a] = ""
+		default:
+			rest = append(rest, a)
+		}
+	}
+	return opts, rest
+}
+
+// targetWindow extracts the window ID from a "session:@N" target.
+func targetWindow(target string) string {
+	if i := strings.LastIndex(target, ":"); i >= 0 {
+		return target[i+1:]
+	}
+	return target
+}
+
+// expandFormat substitutes the tmux format variables tramuntana uses.
+func expandFormat(format string, w *Window) string {
+	lines, paneStart := w.lines()
+	return strings.NewReplacer(
+		"#{window_id}", w.ID,
+		"#{window_name}", w.Name,
+		"#{pane_current_path}", w.CWD,
+		"#{window_activity}", strconv.FormatInt(w.Activity, 10),
+		"#{pane_dead}", "0",
+		"#{pane_current_command}", w.Command,
+		"#{scroll_position}", strconv.Itoa(w.scroll),
+		"#{pane_height}", strconv.Itoa(len(lines)-paneStart),
+		"#{copy_cursor_line}", w.cursor,
+	).Replace(format)
+}
@@ -0,0 +1,322 @@
+package tmux
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/otaviocarvalho/tramuntana/internal/chaos"
+	"github.com/otaviocarvalho/tramuntana/internal/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	controlTimeout       = 10 * time.Second
+	controlRedialBackoff = 5 * time.Second
+)
+
+// errControlClosed is returned for commands in flight when the control client dies.
+// They are not retried, since tmux may already have run them.
+var errControlClosed = errors.New("tmux control client closed")
+
+// controlClient is a persistent `tmux -C` connection. Commands are written one
+// per line; tmux answers each with a %begin … %end (or %error) block in the
+// order received, so replies are matched to a FIFO of waiting requests.
+type controlClient struct {
+	session string
+
+	mu       sync.Mutex
+	cmd      *exec.Cmd
+	stdin    io.WriteCloser
+	waiting  []chan controlReply
+	alive    bool
+	lastDial time.Time
+}
+
+type controlReply struct {
+	output string
+	err    error
+}
+
+var (
+	control   *controlClient
+	controlMu sync.Mutex
+
+	runner   Runner // replaces the tmux binary when set
+	runnerMu sync.RWMutex
+)
+
+// Runner executes one tmux command and returns its stdout.
+type Runner func(args ...string) (string, error)
+
+// SetRunner sends every tmux command to fn instead of the tmux binary or the
+// control client, so tests can script tmux. A nil fn restores the default.
+func SetRunner(fn Runner) {
+	runnerMu.Lock()
+	runner = fn
+	runnerMu.Unlock()
+}
+
+func currentRunner() Runner {
+	runnerMu.RLock()
+	defer runnerMu.RUnlock()
+	return runner
+}
+
+// EnableControlMode routes tmux commands through a control-mode client attached
+// to session. The client connects lazily, reconnects after failures, and falls
+// back to running the tmux binary whenever it is unavailable.
+func EnableControlMode(session string) {
+	controlMu.Lock()
+	defer controlMu.Unlock()
+	if control != nil {
+		control.close()
+	}
+	control = &controlClient{session: session}
+}
+
+// DisableControlMode closes the control client; commands run via the tmux binary again.
+func DisableControlMode() {
+	controlMu.Lock()
+	defer controlMu.Unlock()
+	if control != nil {
+		control.close()
+		control = nil
+	}
+}
+
+// run executes a tmux command and returns its stdout. It uses the control
+// client when enabled and connected, otherwise it spawns the tmux binary.
+func run(args ...string) (string, error) {
+	return runContext(context.Background(), args...)
+}
+
+// runContext is run with a span for the command under the span in ctx.
+func runContext(ctx context.Context, args ...string) (string, error) {
+	span := telemetry.StartChild(ctx, "tmux."+args[0], attribute.String("tmux.command", args[0]))
+	var out string
+	err := chaos.TmuxError(args)
+	if err == nil {
+		out, err = runCommand(args...)
+	}
+	telemetry.End(span, err)
+	return out, err
+}
+
+func runCommand(args ...string) (string, error) {
+	if r := currentRunner(); r != nil {
+		return r(args...)
+	}
+
+	controlMu.Lock()
+	c := control
+	controlMu.Unlock()
+
+	if c != nil {
+		if out, err, ok := c.run(args); ok {
+			return out, err
+		}
+	}
+	return runExec(args...)
+}
+
+// runExec runs the tmux binary, folding stderr into the error.
+func runExec(args ...string) (string, error) {
+	return runExecEnv(nil, args...)
+}
+
+// runExecEnv is runExec with extra environment variables for the tmux process.
+func runExecEnv(env []string, args ...string) (string, error) {
+	if r := currentRunner(); r != nil {
+		return r(args...)
+	}
+
+	cmd := exec.Command("tmux", args...)
+	if env != nil {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return string(out), fmt.Errorf("%s: %w", strings.TrimSpace(stderr.String()), err)
+	}
+	return string(out), nil
+}
+
+// run sends one command. ok is false when the command was not sent, so the
+// caller should fall back to exec.
+func (c *controlClient) run(args []string) (out string, err error, ok bool) {
+	line, quotable := controlCommandLine(args)
+	if !quotable {
+		return "", nil, false
+	}
+
+	reply := make(chan controlReply, 1)
+	c.mu.Lock()
+	if !c.alive && !c.dialLocked() {
+		c.mu.Unlock()
+		return "", nil, false
+	}
+	if _, werr := io.WriteString(c.stdin, line+"\n"); werr != nil {
+		c.shutdownLocked()
+		c.mu.Unlock()
+		return "", nil, false
+	}
+	c.waiting = append(c.waiting, reply)
+	c.mu.Unlock()
+
+	select {
+	case r := <-reply:
+		return r.output, r.err, true
+	case <-time.After(controlTimeout):
+		log.Printf("tmux control: no reply to %q after %s, reconnecting", args[0], controlTimeout)
+		c.mu.Lock()
+		c.shutdownLocked()
+		c.mu.Unlock()
+		return "", fmt.Errorf("%s: %w", args[0], errControlClosed), true
+	}
+}
+
+// dialLocked starts the control client. Caller holds c.mu.
+func (c *controlClient) dialLocked() bool {
+	if time.Since(c.lastDial) < controlRedialBackoff {
+		return false
+	}
+	c.lastDial = time.Now()
+	if !SessionExists(c.session) {
+		return false
+	}
+
+	cmd := exec.Command("tmux", "-C", "attach-session", "-t", c.session, "-f", "ignore-size,no-output")
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return false
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return false
+	}
+	if err := cmd.Start(); err != nil {
+		log.Printf("tmux control: %v", err)
+		return false
+	}
+
+	c.cmd = cmd
+	c.stdin = stdin
+	c.alive = true
+	go c.readLoop(cmd, stdout)
+	log.Printf("tmux control: attached to %s", c.session)
+	return true
+}
+
+// readLoop parses replies until the connection ends.
+func (c *controlClient) readLoop(cmd *exec.Cmd, stdout io.Reader) {
+	r := bufio.NewReader(stdout)
+	var header string // "%begin" arguments of the open block
+	var body strings.Builder
+
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			break
+		}
+		line = strings.TrimSuffix(line, "\n")
+
+		if header == "" {
+			if args, ok := strings.CutPrefix(line, "%begin "); ok {
+				header = args
+				body.Reset()
+			}
+			// Anything else outside a block is a notification
+			continue
+		}
+
+		end, isEnd := strings.CutPrefix(line, "%end ")
+		fail, isErr := strings.CutPrefix(line, "%error ")
+		if (isEnd && end == header) || (isErr && fail == header) {
+			// Blocks flagged 0 answer tmux's own commands (e.g. the attach)
+			if strings.HasSuffix(header, " 1") {
+				c.deliver(body.String(), isErr)
+			}
+			header = ""
+			continue
+		}
+		body.WriteString(line)
+		body.WriteByte('\n')
+	}
+
+	c.mu.Lock()
+	if c.cmd == cmd {
+		c.shutdownLocked()
+	}
+	c.mu.Unlock()
+	_ = cmd.Wait()
+}
+
+// deliver hands a reply to the oldest waiting request.
+func (c *controlClient) deliver(output string, failed bool) {
+	c.mu.Lock()
+	if len(c.waiting) == 0 {
+		c.mu.Unlock()
+		return
+	}
+	reply := c.waiting[0]
+	c.waiting = c.waiting[1:]
+	c.mu.Unlock()
+
+	if failed {
+		reply <- controlReply{output: "", err: errors.New(strings.TrimSpace(output))}
+		return
+	}
+	reply <- controlReply{output: output}
+}
+
+// shutdownLocked kills the client and fails waiting requests. Caller holds c.mu.
+func (c *controlClient) shutdownLocked() {
+	if !c.alive {
+		return
+	}
+	c.alive = false
+	if c.stdin != nil {
+		c.stdin.Close()
+	}
+	if c.cmd != nil && c.cmd.Process != nil {
+		_ = c.cmd.Process.Kill()
+	}
+	c.cmd = nil
+	for _, reply := range c.waiting {
+		reply <- controlReply{err: errControlClosed}
+	}
+	c.waiting = nil
+}
+
+func (c *controlClient) close() {
+	c.mu.Lock()
+	c.shutdownLocked()
+	c.mu.Unlock()
+}
+
+// controlCommandLine quotes args as a single tmux command line. Returns false for
+// arguments that cannot be sent on one line (newlines and other control bytes).
+func controlCommandLine(args []string) (string, bool) {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		for _, r := range a {
+			if r < 0x20 || r == 0x7f {
+				return "", false
+			}
+		}
+		// Single quotes disable all tmux parsing; embed ' as '\''
+		quoted[i] = "'" + strings.ReplaceAll(a, "'", `'\''`) + "'"
+	}
+	return strings.Join(quoted, " "), true
+}
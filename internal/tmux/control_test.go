@@ -0,0 +1,90 @@
+package tmux
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestControlCommandLine(t *testing.T) {
+	line, ok := controlCommandLine([]string{"send-keys", "-t", "s:@1", "-l", `it's $HOME; "q"`})
+	if !ok {
+		t.Fatal("expected quotable")
+	}
+	want := `'send-keys' '-t' 's:@1' '-l' 'it'\''s $HOME; "q"'`
+	if line != want {
+		t.Errorf("got  %s\nwant %s", line, want)
+	}
+
+	if _, ok := controlCommandLine([]string{"send-keys", "-l", "two\nlines"}); ok {
+		t.Error("newlines should not be quotable")
+	}
+}
+
+func TestControlMode_RoundTrip(t *testing.T) {
+	skipWithoutTmux(t)
+	cleanupTestSession(t)
+	defer cleanupTestSession(t)
+
+	if err := EnsureSession(testSession); err != nil {
+		t.Fatalf("EnsureSession: %v", err)
+	}
+	EnableControlMode(testSession)
+	defer DisableControlMode()
+
+	windowID, err := NewWindow(testSession, "ctl", "/tmp", "", nil)
+	if err != nil {
+		t.Fatalf("NewWindow: %v", err)
+	}
+
+	// Several commands in flight at once must each get their own reply
+	done := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		go func() {
+			out, err := DisplayMessage(testSession+":"+windowID, "#{window_id}")
+			if err == nil && out != windowID {
+				err = &mismatchError{out}
+			}
+			done <- err
+		}()
+	}
+	for i := 0; i < 3; i++ {
+		if err := <-done; err != nil {
+			t.Errorf("DisplayMessage: %v", err)
+		}
+	}
+
+	if err := SendKeys(testSession, windowID, "echo 'control mode' $((1+1))"); err != nil {
+		t.Fatalf("SendKeys: %v", err)
+	}
+	SendEnter(testSession, windowID)
+	var text string
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(200 * time.Millisecond) {
+		if text, err = CapturePane(testSession, windowID, false); err != nil {
+			t.Fatalf("CapturePane: %v", err)
+		}
+		if strings.Contains(text, "control mode 2") {
+			break
+		}
+	}
+	if !strings.Contains(text, "control mode 2") {
+		t.Errorf("pane should contain command output, got:\n%s", text)
+	}
+
+	// Errors come back as %error blocks that IsWindowDead still recognises
+	_, err = CapturePane(testSession, "@99999", false)
+	if !IsWindowDead(err) {
+		t.Errorf("expected dead-window error, got %v", err)
+	}
+
+	controlMu.Lock()
+	alive := control != nil && control.alive
+	controlMu.Unlock()
+	if !alive {
+		t.Error("commands should have gone through the control client")
+	}
+}
+
+type mismatchError struct{ got string }
+
+func (e *mismatchError) Error() string { return "unexpected output " + e.got }
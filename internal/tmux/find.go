@@ -0,0 +1,57 @@
+package tmux
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Found is the screenful of a window's scrollback around a search match.
+type Found struct {
+	Text    string // the captured screenful
+	Line    string // the line the match is on
+	LinesUp int    // how far the screenful is scrolled up from the live pane
+}
+
+// FindInHistory searches a window's scrollback backwards from the bottom for
+// text using copy mode, captures the screenful copy mode scrolled to, and
+// leaves copy mode. The window's write lock is held throughout so no input
+// lands in copy mode. ok is false if text wasn't found.
+func FindInHistory(session, windowID, text string, withAnsi bool) (found Found, ok bool, err error) {
+	err = WithWindow(session, windowID, func(w Writer) error {
+		if _, err := runContext(w.ctx, "copy-mode", "-t", w.target); err != nil {
+			return fmt.Errorf("copy-mode in %s: %w", w.target, err)
+		}
+		defer runContext(w.ctx, "send-keys", "-t", w.target, "-X", "cancel")
+
+		if _, err := runContext(w.ctx, "send-keys", "-t", w.target, "-X", "search-backward-text", text); err != nil {
+			return fmt.Errorf("searching %s: %w", w.target, err)
+		}
+		out, err := runContext(w.ctx, "display-message", "-t", w.target, "-p", "#{scroll_position}\t#{pane_height}\t#{copy_cursor_line}")
+		if err != nil {
+			return fmt.Errorf("display-message for %s: %w", w.target, err)
+		}
+		fields := strings.SplitN(strings.TrimRight(out, "\n"), "\t", 3)
+		if len(fields) < 3 {
+			return fmt.Errorf("unexpected copy-mode state %q from %s", out, w.target)
+		}
+		// tmux searches case-insensitively for an all-lowercase string
+		if !strings.Contains(strings.ToLower(fields[2]), strings.ToLower(text)) {
+			return nil
+		}
+		up, _ := strconv.Atoi(fields[0])
+		height, _ := strconv.Atoi(fields[1])
+
+		args := []string{"capture-pane", "-t", w.target, "-p", "-S", strconv.Itoa(-up), "-E", strconv.Itoa(height - 1 - up)}
+		if withAnsi {
+			args = append(args, "-e")
+		}
+		captured, err := runContext(w.ctx, args...)
+		if err != nil {
+			return fmt.Errorf("capturing %s: %w", w.target, err)
+		}
+		found, ok = Found{Text: captured, Line: fields[2], LinesUp: up}, true
+		return nil
+	})
+	return found, ok, err
+}
@@ -0,0 +1,49 @@
+package tmux
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFindInHistory(t *testing.T) {
+	var commands []string
+	cursorLine := "FAIL TestParser (0.01s)"
+	SetRunner(func(args ...string) (string, error) {
+		commands = append(commands, strings.Join(args, " "))
+		switch args[0] {
+		case "display-message":
+			return "120\t40\t" + cursorLine + "\n", nil
+		case "capture-pane":
+			return "--- FAIL: TestParser\n", nil
+		}
+		return "", nil
+	})
+	defer SetRunner(nil)
+
+	found, ok, err := FindInHistory("s", "@1", "fail", false)
+	if err != nil || !ok {
+		t.Fatalf("FindInHistory = %v, %v", ok, err)
+	}
+	if found.LinesUp != 120 || found.Line != cursorLine || !strings.Contains(found.Text, "TestParser") {
+		t.Errorf("found = %+v", found)
+	}
+	want := []string{
+		"copy-mode -t s:@1",
+		"send-keys -t s:@1 -X search-backward-text fail",
+		"display-message -t s:@1 -p #{scroll_position}\t#{pane_height}\t#{copy_cursor_line}",
+		"capture-pane -t s:@1 -p -S -120 -E -81",
+		"send-keys -t s:@1 -X cancel",
+	}
+	if strings.Join(commands, "\n") != strings.Join(want, "\n") {
+		t.Errorf("commands:\n%s\nwant:\n%s", strings.Join(commands, "\n"), strings.Join(want, "\n"))
+	}
+
+	commands = nil
+	cursorLine = "❯ "
+	if _, ok, err := FindInHistory("s", "@1", "nowhere", false); ok || err != nil {
+		t.Errorf("missing text: ok=%v err=%v", ok, err)
+	}
+	if last := commands[len(commands)-1]; last != "send-keys -t s:@1 -X cancel" {
+		t.Errorf("copy mode not left: %q", last)
+	}
+}
@@ -0,0 +1,156 @@
+package tmux
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// keysChunkBytes bounds the text in one send-keys command, well under tmux's
+// command length limits.
+const keysChunkBytes = 512
+
+// sendRetries is how many times a failed send-keys is retried.
+const sendRetries = 2
+
+// sendRetryDelay is the pause before retrying a failed send-keys.
+var sendRetryDelay = 100 * time.Millisecond
+
+// ErrInputMismatch is returned when typed text doesn't show in the window's
+// input after a retry; Enter is not pressed.
+var ErrInputMismatch = errors.New("typed text does not match the window's input")
+
+// sanitizeInput makes user text safe to enter: CRLF and lone CR become LF,
+// and other control characters but tabs, including the ESC that starts ANSI
+// sequences (and could end a bracketed paste early), are dropped.
+func sanitizeInput(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '\n' || r == '\t':
+			return r
+		case r == '\r':
+			return '\n'
+		case unicode.IsControl(r):
+			return -1
+		}
+		return r
+	}, text)
+}
+
+// sanitizeKeys is sanitizeInput for typing, where a tab would trigger
+// completion, so tabs become spaces.
+func sanitizeKeys(text string) string {
+	return strings.ReplaceAll(sanitizeInput(text), "\t", " ")
+}
+
+// chunkKeys splits text into pieces of at most keysChunkBytes on rune
+// boundaries. A piece never ends in ';', which tmux would take as a command
+// separator; trailing semicolons of the whole text are returned separately to
+// be sent as hex keys.
+func chunkKeys(text string) (chunks []string, semicolons int) {
+	trimmed := strings.TrimRight(text, ";")
+	semicolons = len(text) - len(trimmed)
+	for len(trimmed) > 0 {
+		n := min(len(trimmed), keysChunkBytes)
+		for n < len(trimmed) && !utf8.RuneStart(trimmed[n]) {
+			n--
+		}
+		end := n
+		for n > 0 && trimmed[n-1] == ';' {
+			n--
+		}
+		if n == 0 { // only semicolons: run on to the next rune instead
+			n = end
+			for trimmed[n] == ';' {
+				n++
+			}
+			_, size := utf8.DecodeRuneInString(trimmed[n:])
+			n += size
+		}
+		chunks = append(chunks, trimmed[:n])
+		trimmed = trimmed[n:]
+	}
+	return chunks, semicolons
+}
+
+// Type enters text into the window's input without submitting it: a single
+// line is typed, multi-line text is pasted as one bracketed paste so its
+// newlines don't submit each line on its own.
+func (w Writer) Type(text string) error {
+	if strings.Contains(sanitizeInput(text), "\n") {
+		return w.Paste(text)
+	}
+	return w.Keys(text)
+}
+
+// runRetry runs a command, retrying failures that may be transient. Dead
+// windows and commands the control client may already have run are not retried.
+func runRetry(ctx context.Context, args ...string) (string, error) {
+	out, err := runContext(ctx, args...)
+	for i := 0; i < sendRetries && err != nil && !IsWindowDead(err) && !errors.Is(err, errControlClosed); i++ {
+		time.Sleep(sendRetryDelay)
+		out, err = runContext(ctx, args...)
+	}
+	return out, err
+}
+
+// inputTail is how much of typed text must show in the pane to verify it.
+const inputTail = 40
+
+// verifiable strips what the terminal changes when showing input: whitespace
+// (wrapping, indentation) and the input box border.
+func verifiable(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || r == '│' {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// InputShows reports whether the end of text shows in the window's input
+// prompt, i.e. it was typed intact. A pane without Claude Code's "❯" prompt
+// can't be checked and counts as shown, as does text collapsed into a paste
+// placeholder.
+func (w Writer) InputShows(text string) (bool, error) {
+	pane, err := runContext(w.ctx, "capture-pane", "-t", w.target, "-p")
+	if err != nil {
+		return false, fmt.Errorf("capturing %s: %w", w.target, err)
+	}
+	lines := strings.Split(pane, "\n")
+	prompt := -1
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimLeft(line, " │"), "❯") {
+			prompt = i
+		}
+	}
+	if prompt < 0 {
+		return true, nil
+	}
+	input := strings.Join(lines[prompt:], "\n")
+	if strings.Contains(input, "[Pasted text") {
+		return true, nil
+	}
+	want := []rune(verifiable(sanitizeKeys(text)))
+	if len(want) > inputTail {
+		want = want[len(want)-inputTail:]
+	}
+	return strings.Contains(verifiable(input), string(want)), nil
+}
+
+// Erase presses Backspace n times.
+func (w Writer) Erase(n int) error {
+	if n <= 0 {
+		return nil
+	}
+	if _, err := runRetry(w.ctx, "send-keys", "-t", w.target, "-N", strconv.Itoa(n), "BSpace"); err != nil {
+		return fmt.Errorf("erasing input in %s: %w", w.target, err)
+	}
+	return nil
+}
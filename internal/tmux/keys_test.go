@@ -0,0 +1,209 @@
+package tmux
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSanitizeKeys(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"emoji", "ship it 🚀👍🏽", "ship it 🚀👍🏽"},
+		{"telegram newlines", "line one\r\nline two\rline three", "line one\nline two\nline three"},
+		{"ansi escape", "\x1b[31mred\x1b[0m text", "[31mred[0m text"},
+		{"controls", "a\tb\x00c\x7fd\u0085e", "a bcde"},
+		{"shell quoting", `echo "a;b" 'c' $HOME \n`, `echo "a;b" 'c' $HOME \n`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sanitizeKeys(tt.in); got != tt.want {
+				t.Errorf("sanitizeKeys(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeInput_KeepsTabsAndNewlines(t *testing.T) {
+	in := "func f() {\r\n\treturn\r\n}\x1b[201~"
+	if got, want := sanitizeInput(in), "func f() {\n\treturn\n}[201~"; got != want {
+		t.Errorf("sanitizeInput = %q, want %q", got, want)
+	}
+}
+
+func TestSendKeysWithDelay_PastesMultiLine(t *testing.T) {
+	var commands []string
+	SetRunner(func(args ...string) (string, error) {
+		commands = append(commands, strings.Join(args, " "))
+		return "", nil
+	})
+	defer SetRunner(nil)
+
+	if err := SendKeysWithDelay("s", "@7", "fix this:\r\n\tx := 1", 0); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"set-buffer -b tramuntana-7 -- fix this:\n\tx := 1",
+		"paste-buffer -d -p -b tramuntana-7 -t s:@7",
+		"capture-pane -t s:@7 -p",
+		"send-keys -t s:@7 Enter",
+	}
+	if strings.Join(commands, "\n") != strings.Join(want, "\n") {
+		t.Errorf("commands:\n%s\nwant:\n%s", strings.Join(commands, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestChunkKeys(t *testing.T) {
+	tests := []struct {
+		name       string
+		in         string
+		semicolons int
+	}{
+		{"short", "hello", 0},
+		{"trailing semicolons", "run it;;", 2},
+		{"only semicolons", ";;;", 3},
+		{"emoji across boundary", strings.Repeat("a", keysChunkBytes-1) + strings.Repeat("🚀", 300), 0},
+		{"semicolon at boundary", strings.Repeat("a", keysChunkBytes-1) + ";b", 0},
+		{"semicolon run at boundary", strings.Repeat(";", keysChunkBytes*2) + "x", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks, semicolons := chunkKeys(tt.in)
+			if semicolons != tt.semicolons {
+				t.Errorf("semicolons = %d, want %d", semicolons, tt.semicolons)
+			}
+			if got := strings.Join(chunks, "") + strings.Repeat(";", semicolons); got != tt.in {
+				t.Errorf("chunks don't add up to the input")
+			}
+			for i, c := range chunks {
+				if !utf8.ValidString(c) || strings.HasSuffix(c, ";") || c == "" {
+					t.Errorf("chunk %d = %q", i, c)
+				}
+				if len(c) > keysChunkBytes+utf8.UTFMax && !strings.Contains(tt.name, "semicolon run") {
+					t.Errorf("chunk %d is %d bytes", i, len(c))
+				}
+			}
+		})
+	}
+}
+
+func TestKeys_ChunksAndHexSemicolons(t *testing.T) {
+	var commands []string
+	SetRunner(func(args ...string) (string, error) {
+		commands = append(commands, strings.Join(args, " "))
+		return "", nil
+	})
+	defer SetRunner(nil)
+
+	long := strings.Repeat("x", keysChunkBytes+10) + ";"
+	if err := WithWindow("s", "@1", func(w Writer) error { return w.Keys(long + ";") }); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"send-keys -t s:@1 -l -- " + strings.Repeat("x", keysChunkBytes),
+		"send-keys -t s:@1 -l -- " + strings.Repeat("x", 10),
+		"send-keys -t s:@1 -H 3b 3b",
+	}
+	if strings.Join(commands, "\n") != strings.Join(want, "\n") {
+		t.Errorf("commands:\n%s\nwant:\n%s", strings.Join(commands, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestKeys_RetriesTransientErrors(t *testing.T) {
+	saved := sendRetryDelay
+	sendRetryDelay = 0
+	defer func() { sendRetryDelay = saved }()
+
+	calls := 0
+	SetRunner(func(args ...string) (string, error) {
+		calls++
+		if calls < 3 {
+			return "", errors.New("server exited unexpectedly")
+		}
+		return "", nil
+	})
+	defer SetRunner(nil)
+	if err := SendKeys("s", "@1", "hello"); err != nil || calls != 3 {
+		t.Errorf("SendKeys = %v after %d calls, want success after 3", err, calls)
+	}
+
+	calls = 0
+	SetRunner(func(args ...string) (string, error) {
+		calls++
+		return "", errors.New("can't find window: @1")
+	})
+	if err := SendKeys("s", "@1", "hello"); !IsWindowDead(err) || calls != 1 {
+		t.Errorf("SendKeys = %v after %d calls, want a dead window without retries", err, calls)
+	}
+}
+
+func TestSendKeysWithDelay_RetypesMangledInput(t *testing.T) {
+	const prompt = "────\n❯ "
+	var input string
+	var commands []string
+	mangle := true
+	SetRunner(func(args ...string) (string, error) {
+		commands = append(commands, args[0]+" "+args[len(args)-1])
+		switch {
+		case args[0] == "capture-pane":
+			return prompt + input + "\n────\n", nil
+		case args[len(args)-2] == "--":
+			if mangle {
+				input, mangle = "fix the bug", false // lost "; then test"
+			} else {
+				input = args[len(args)-1]
+			}
+		}
+		return "", nil
+	})
+	defer SetRunner(nil)
+
+	if err := SendKeysWithDelay("s", "@1", "fix the bug; then test 🚀", 0); err != nil {
+		t.Fatal(err)
+	}
+	want := "send-keys fix the bug; then test 🚀 | capture-pane -p | send-keys BSpace | send-keys fix the bug; then test 🚀 | capture-pane -p | send-keys Enter"
+	if got := strings.Join(commands, " | "); got != want {
+		t.Errorf("commands = %s\nwant %s", got, want)
+	}
+
+	commands, input = nil, ""
+	SetRunner(func(args ...string) (string, error) {
+		commands = append(commands, args[0]+" "+args[len(args)-1])
+		if args[0] == "capture-pane" {
+			return prompt + "garbled\n", nil
+		}
+		return "", nil
+	})
+	err := SendKeysWithDelay("s", "@1", "hello", 0)
+	if !errors.Is(err, ErrInputMismatch) {
+		t.Fatalf("err = %v, want ErrInputMismatch", err)
+	}
+	if got := strings.Join(commands, " | "); strings.Contains(got, "Enter") {
+		t.Errorf("Enter pressed after a mismatch: %s", got)
+	}
+}
+
+func TestInputShows(t *testing.T) {
+	tests := []struct {
+		name, pane string
+		want       bool
+	}{
+		{"wrapped", "│ ❯ please refactor the\n│   parser module\n", true},
+		{"missing tail", "❯ please refactor the\n", false},
+		{"paste placeholder", "❯ [Pasted text #1 +40 lines]\n", true},
+		{"no prompt", "$ \n", true},
+		{"earlier prompt only", "❯ please refactor the parser module\n⏺ Done\n❯ \n", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetRunner(func(args ...string) (string, error) { return tt.pane, nil })
+			defer SetRunner(nil)
+			got, err := Writer{target: "s:@1"}.InputShows("please refactor the parser module")
+			if err != nil || got != tt.want {
+				t.Errorf("InputShows = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
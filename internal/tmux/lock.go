@@ -0,0 +1,86 @@
+package tmux
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// windowLocks holds a mutex per "session:window" target. Entries are never
+// removed; window IDs are not reused within a server's lifetime.
+var windowLocks sync.Map
+
+// Writer sends input to a window while holding its write lock. Get one from
+// WithWindow.
+type Writer struct {
+	ctx      context.Context // parent of the commands' trace spans
+	target   string
+	windowID string
+}
+
+// WithWindow runs fn with the window's write lock held, so a sequence of
+// writes (text, a pause, Enter) can't be interleaved with writes to the same
+// window from other goroutines. The exported send functions take the lock
+// themselves and must not be called from fn.
+func WithWindow(session, windowID string, fn func(w Writer) error) error {
+	return WithWindowContext(context.Background(), session, windowID, fn)
+}
+
+// WithWindowContext is WithWindow with the writes traced under the span in
+// ctx.
+func WithWindowContext(ctx context.Context, session, windowID string, fn func(w Writer) error) error {
+	target := session + ":" + windowID
+	mu, _ := windowLocks.LoadOrStore(target, new(sync.Mutex))
+	mu.(*sync.Mutex).Lock()
+	defer mu.(*sync.Mutex).Unlock()
+	return fn(Writer{ctx: ctx, target: target, windowID: windowID})
+}
+
+// Keys types literal text, in chunks short enough for tmux, retrying
+// chunks that fail transiently.
+func (w Writer) Keys(text string) error {
+	chunks, semicolons := chunkKeys(sanitizeKeys(text))
+	for _, chunk := range chunks {
+		if _, err := runRetry(w.ctx, "send-keys", "-t", w.target, "-l", "--", chunk); err != nil {
+			return fmt.Errorf("send-keys to %s: %w", w.target, err)
+		}
+	}
+	if semicolons > 0 {
+		hex := strings.TrimSpace(strings.Repeat("3b ", semicolons))
+		if _, err := runRetry(w.ctx, append([]string{"send-keys", "-t", w.target, "-H"}, strings.Fields(hex)...)...); err != nil {
+			return fmt.Errorf("send-keys to %s: %w", w.target, err)
+		}
+	}
+	return nil
+}
+
+// Enter presses Enter.
+func (w Writer) Enter() error {
+	if _, err := runContext(w.ctx, "send-keys", "-t", w.target, "Enter"); err != nil {
+		return fmt.Errorf("send-enter to %s: %w", w.target, err)
+	}
+	return nil
+}
+
+// Key presses a named key (e.g., "Escape", "Up", "Down").
+func (w Writer) Key(name string) error {
+	if _, err := runContext(w.ctx, "send-keys", "-t", w.target, name); err != nil {
+		return fmt.Errorf("send-key %s to %s: %w", name, w.target, err)
+	}
+	return nil
+}
+
+// Paste loads text into a paste buffer named after the window and pastes it
+// as one bracketed paste, without pressing Enter. The buffer is deleted
+// afterwards.
+func (w Writer) Paste(text string) error {
+	buffer := "tramuntana-" + strings.TrimPrefix(w.windowID, "@")
+	if _, err := runRetry(w.ctx, "set-buffer", "-b", buffer, "--", sanitizeInput(text)); err != nil {
+		return fmt.Errorf("set-buffer for %s: %w", w.target, err)
+	}
+	if _, err := runContext(w.ctx, "paste-buffer", "-d", "-p", "-b", buffer, "-t", w.target); err != nil {
+		return fmt.Errorf("paste-buffer to %s: %w", w.target, err)
+	}
+	return nil
+}
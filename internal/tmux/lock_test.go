@@ -0,0 +1,45 @@
+package tmux
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestWithWindow_SerializesWrites(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		commands []string
+	)
+	typed := make(chan struct{})
+	var once sync.Once
+	SetRunner(func(args ...string) (string, error) {
+		mu.Lock()
+		commands = append(commands, strings.Join(args, " "))
+		mu.Unlock()
+		if args[len(args)-1] == "hello" {
+			once.Do(func() { close(typed) })
+		}
+		return "", nil
+	})
+	defer SetRunner(nil)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		SendKeysWithDelay("s", "@1", "hello", 50)
+	}()
+	go func() {
+		defer wg.Done()
+		<-typed
+		SendSpecialKey("s", "@1", "Escape") // must wait for Enter
+	}()
+	wg.Wait()
+
+	got := strings.Join(commands, " | ")
+	want := "send-keys -t s:@1 -l -- hello | capture-pane -t s:@1 -p | send-keys -t s:@1 Enter | send-keys -t s:@1 Escape"
+	if got != want {
+		t.Errorf("commands = %s\nwant %s", got, want)
+	}
+}
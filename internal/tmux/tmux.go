@@ -1,11 +1,13 @@
 package tmux
 
 import (
+	"context"
 	"fmt"
 	"os"
-	"os/exec"
+	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 // Window represents a tmux window.
@@ -17,7 +19,8 @@ type Window struct {
 
 // SessionExists checks if a tmux session exists.
 func SessionExists(name string) bool {
-	return exec.Command("tmux", "has-session", "-t", name).Run() == nil
+	_, err := runExec("has-session", "-t", name)
+	return err == nil
 }
 
 // InitWindowName is the name given to the placeholder window created by EnsureSession.
@@ -30,24 +33,22 @@ func EnsureSession(name string) error {
 	if SessionExists(name) {
 		return nil
 	}
-	cmd := exec.Command("tmux", "new-session", "-d", "-s", name, "-n", InitWindowName)
-	if out, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("creating session %s: %s: %w", name, string(out), err)
+	if _, err := runExec("new-session", "-d", "-s", name, "-n", InitWindowName); err != nil {
+		return fmt.Errorf("creating session %s: %w", name, err)
 	}
 	return nil
 }
 
 // ListWindows returns all windows in a session.
 func ListWindows(session string) ([]Window, error) {
-	cmd := exec.Command("tmux", "list-windows", "-t", session,
+	out, err := run("list-windows", "-t", session,
 		"-F", "#{window_id}\t#{window_name}\t#{pane_current_path}")
-	out, err := cmd.Output()
 	if err != nil {
 		return nil, fmt.Errorf("listing windows in %s: %w", session, err)
 	}
 
 	var windows []Window
-	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
 		if line == "" {
 			continue
 		}
@@ -64,22 +65,59 @@ func ListWindows(session string) ([]Window, error) {
 	return windows, nil
 }
 
+// WindowActivity is the per-window state reported by ListWindowActivity.
+type WindowActivity struct {
+	Activity int64  // #{window_activity}: Unix seconds of the last output
+	Dead     bool   // #{pane_dead}: the active pane's process has exited
+	Command  string // #{pane_current_command}: foreground process of the active pane
+	Path     string // #{pane_current_path}: working directory of the active pane
+}
+
+// ListWindowActivity reports activity for every window in a session with one
+// list-windows call, so pollers can skip capturing idle panes.
+func ListWindowActivity(session string) (map[string]WindowActivity, error) {
+	out, err := run("list-windows", "-t", session,
+		"-F", "#{window_id}\t#{window_activity}\t#{pane_dead}\t#{pane_current_command}\t#{pane_current_path}")
+	if err != nil {
+		return nil, fmt.Errorf("listing window activity in %s: %w", session, err)
+	}
+	return parseWindowActivity(out), nil
+}
+
+func parseWindowActivity(out string) map[string]WindowActivity {
+	windows := make(map[string]WindowActivity)
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		parts := strings.SplitN(line, "\t", 5)
+		if len(parts) < 3 {
+			continue
+		}
+		activity, _ := strconv.ParseInt(parts[1], 10, 64)
+		wa := WindowActivity{Activity: activity, Dead: parts[2] == "1"}
+		if len(parts) > 3 {
+			wa.Command = parts[3]
+		}
+		if len(parts) > 4 {
+			wa.Path = parts[4]
+		}
+		windows[parts[0]] = wa
+	}
+	return windows
+}
+
 // NewWindow creates a new window, sets env vars, and starts the Claude command.
 // Returns the window ID.
 func NewWindow(session, name, dir, claudeCmd string, env map[string]string) (string, error) {
 	args := []string{"new-window", "-t", session, "-n", name, "-c", dir, "-P", "-F", "#{window_id}"}
-	cmd := exec.Command("tmux", args...)
-	cmdEnv := os.Environ()
+	cmdEnv := []string{}
 	for k, v := range env {
 		cmdEnv = append(cmdEnv, k+"="+v)
 	}
-	cmd.Env = cmdEnv
 
-	out, err := cmd.Output()
+	out, err := runExecEnv(cmdEnv, args...)
 	if err != nil {
 		return "", fmt.Errorf("creating window %s in %s: %w", name, session, err)
 	}
-	windowID := strings.TrimSpace(string(out))
+	windowID := strings.TrimSpace(out)
 
 	// Set environment variables inside the tmux window.
 	// Use both set-environment (for tmux-level inheritance) and
@@ -89,19 +127,15 @@ func NewWindow(session, name, dir, claudeCmd string, env map[string]string) (str
 		// Expand $PATH references against the current process environment
 		expanded := os.ExpandEnv(v)
 		// tmux set-environment -t window for new panes/processes
-		setEnvCmd := exec.Command("tmux", "set-environment", "-t", target, k, expanded)
-		_ = setEnvCmd.Run()
+		_, _ = runExec("set-environment", "-t", target, k, expanded)
 		// Also export in the running shell
-		setCmd := exec.Command("tmux", "send-keys", "-t", target,
-			fmt.Sprintf("export %s=%q", k, expanded), "Enter")
-		_ = setCmd.Run()
+		_, _ = runExec("send-keys", "-t", target, fmt.Sprintf("export %s=%q", k, expanded), "Enter")
 	}
 
 	// Start Claude
 	if claudeCmd != "" {
 		time.Sleep(200 * time.Millisecond)
-		startCmd := exec.Command("tmux", "send-keys", "-t", target, claudeCmd, "Enter")
-		if err := startCmd.Run(); err != nil {
+		if _, err := runExec("send-keys", "-t", target, claudeCmd, "Enter"); err != nil {
 			return windowID, fmt.Errorf("starting claude in %s: %w", windowID, err)
 		}
 	}
@@ -109,43 +143,59 @@ func NewWindow(session, name, dir, claudeCmd string, env map[string]string) (str
 	return windowID, nil
 }
 
-// SendKeys sends literal text followed by Enter to a tmux window.
+// SendKeys sends literal text to a tmux window, without pressing Enter.
 func SendKeys(session, windowID, keys string) error {
-	target := session + ":" + windowID
-	cmd := exec.Command("tmux", "send-keys", "-t", target, "-l", keys)
-	if out, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("send-keys to %s: %s: %w", target, string(out), err)
-	}
-	return nil
+	return WithWindow(session, windowID, func(w Writer) error { return w.Keys(keys) })
 }
 
 // SendEnter sends the Enter key to a tmux window.
 func SendEnter(session, windowID string) error {
-	target := session + ":" + windowID
-	cmd := exec.Command("tmux", "send-keys", "-t", target, "Enter")
-	if out, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("send-enter to %s: %s: %w", target, string(out), err)
-	}
-	return nil
+	return WithWindow(session, windowID, Writer.Enter)
 }
 
-// SendKeysWithDelay sends text, waits delayMs, then sends Enter.
+// SendKeysWithDelay enters text (see Writer.Type), waits delayMs, then sends
+// Enter, holding the window's write lock throughout. If the text doesn't show
+// in the input prompt it is erased and entered again once; if it still
+// doesn't, Enter is not pressed and ErrInputMismatch is returned.
 func SendKeysWithDelay(session, windowID, text string, delayMs int) error {
-	if err := SendKeys(session, windowID, text); err != nil {
-		return err
-	}
-	time.Sleep(time.Duration(delayMs) * time.Millisecond)
-	return SendEnter(session, windowID)
+	return SendKeysWithDelayContext(context.Background(), session, windowID, text, delayMs)
+}
+
+// SendKeysWithDelayContext is SendKeysWithDelay with the tmux commands traced
+// under the span in ctx.
+func SendKeysWithDelayContext(ctx context.Context, session, windowID, text string, delayMs int) error {
+	return WithWindowContext(ctx, session, windowID, func(w Writer) error {
+		for attempt := 0; ; attempt++ {
+			if err := w.Type(text); err != nil {
+				return err
+			}
+			time.Sleep(time.Duration(delayMs) * time.Millisecond)
+			ok, err := w.InputShows(text)
+			if err != nil {
+				return err
+			}
+			if ok {
+				return w.Enter()
+			}
+			if attempt == 1 {
+				return fmt.Errorf("%s: %w", w.target, ErrInputMismatch)
+			}
+			if err := w.Erase(utf8.RuneCountInString(sanitizeKeys(text))); err != nil {
+				return err
+			}
+		}
+	})
+}
+
+// PasteText loads text into a tmux paste buffer and pastes it into a window as
+// one bracketed paste, without pressing Enter. The buffer is deleted afterwards.
+func PasteText(session, windowID, text string) error {
+	return WithWindow(session, windowID, func(w Writer) error { return w.Paste(text) })
 }
 
 // SendSpecialKey sends a named key (e.g., "Escape", "Up", "Down") to a tmux window.
 func SendSpecialKey(session, windowID, key string) error {
-	target := session + ":" + windowID
-	cmd := exec.Command("tmux", "send-keys", "-t", target, key)
-	if out, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("send-key %s to %s: %s: %w", key, target, string(out), err)
-	}
-	return nil
+	return WithWindow(session, windowID, func(w Writer) error { return w.Key(key) })
 }
 
 // CapturePane captures visible pane content.
@@ -156,12 +206,11 @@ func CapturePane(session, windowID string, withAnsi bool) (string, error) {
 	if withAnsi {
 		args = append(args, "-e")
 	}
-	cmd := exec.Command("tmux", args...)
-	out, err := cmd.Output()
+	out, err := run(args...)
 	if err != nil {
 		return "", fmt.Errorf("capturing pane %s: %w", target, err)
 	}
-	return string(out), nil
+	return out, nil
 }
 
 // IsWindowDead checks if a tmux error indicates the target window/session no longer exists.
@@ -193,9 +242,8 @@ func CleanupInitWindow(session string) {
 // KillWindow kills a tmux window. Returns nil if window doesn't exist.
 func KillWindow(session, windowID string) error {
 	target := session + ":" + windowID
-	cmd := exec.Command("tmux", "kill-window", "-t", target)
-	if out, err := cmd.CombinedOutput(); err != nil {
-		wrapped := fmt.Errorf("killing window %s: %s: %w", target, string(out), err)
+	if _, err := run("kill-window", "-t", target); err != nil {
+		wrapped := fmt.Errorf("killing window %s: %w", target, err)
 		if IsWindowDead(wrapped) {
 			return nil
 		}
@@ -210,7 +258,7 @@ func WaitForReady(session, windowID string, timeout time.Duration) bool {
 	deadline := time.Now().Add(timeout)
 	for time.Now().Before(deadline) {
 		text, err := CapturePane(session, windowID, false)
-		if err == nil && hasChromeSeparator(text) {
+		if err == nil && HasChromeSeparator(text) {
 			return true
 		}
 		time.Sleep(500 * time.Millisecond)
@@ -218,8 +266,8 @@ func WaitForReady(session, windowID string, timeout time.Duration) bool {
 	return false
 }
 
-// hasChromeSeparator checks if pane text contains Claude Code's chrome separator (≥20 ─ chars).
-func hasChromeSeparator(text string) bool {
+// HasChromeSeparator reports whether pane text contains Claude Code's chrome separator (≥20 ─ chars).
+func HasChromeSeparator(text string) bool {
 	for _, line := range strings.Split(text, "\n") {
 		trimmed := strings.TrimSpace(line)
 		if len(trimmed) == 0 {
@@ -240,20 +288,30 @@ func hasChromeSeparator(text string) bool {
 
 // DisplayMessage runs tmux display-message and returns the output.
 func DisplayMessage(paneID, format string) (string, error) {
-	cmd := exec.Command("tmux", "display-message", "-t", paneID, "-p", format)
-	out, err := cmd.Output()
+	out, err := run("display-message", "-t", paneID, "-p", format)
 	if err != nil {
 		return "", fmt.Errorf("display-message for %s: %w", paneID, err)
 	}
-	return strings.TrimSpace(string(out)), nil
+	return strings.TrimSpace(out), nil
+}
+
+// shellNames are foreground commands that mean the pane is sitting at a shell prompt.
+var shellNames = map[string]bool{
+	"bash": true, "zsh": true, "sh": true, "fish": true,
+	"dash": true, "ksh": true, "tcsh": true, "csh": true,
+}
+
+// IsShell reports whether a pane_current_command value is an interactive shell.
+// Login shells are reported with a leading "-" (e.g. "-zsh").
+func IsShell(command string) bool {
+	return shellNames[strings.TrimPrefix(strings.TrimSpace(command), "-")]
 }
 
 // RenameWindow renames a tmux window.
 func RenameWindow(session, windowID, newName string) error {
 	target := session + ":" + windowID
-	cmd := exec.Command("tmux", "rename-window", "-t", target, newName)
-	if out, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("renaming window %s: %s: %w", target, string(out), err)
+	if _, err := run("rename-window", "-t", target, newName); err != nil {
+		return fmt.Errorf("renaming window %s: %w", target, err)
 	}
 	return nil
 }
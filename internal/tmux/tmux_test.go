@@ -252,3 +252,41 @@ func TestDisplayMessage(t *testing.T) {
 		t.Errorf("result %q should contain session name %q", result, testSession)
 	}
 }
+
+func TestIsShell(t *testing.T) {
+	tests := []struct {
+		command string
+		want    bool
+	}{
+		{"bash", true},
+		{"zsh", true},
+		{"-zsh", true},
+		{"fish\n", true},
+		{"claude", false},
+		{"node", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := IsShell(tt.command); got != tt.want {
+			t.Errorf("IsShell(%q) = %v, want %v", tt.command, got, tt.want)
+		}
+	}
+}
+
+func TestParseWindowActivity(t *testing.T) {
+	out := "@1\t1792178362\t0\tclaude\t/work/my api\n@2\t1792178300\t1\tbash\t/tmp\n@3\t1792178000\t0\t\t\nbad\n"
+	got := parseWindowActivity(out)
+
+	if len(got) != 3 {
+		t.Fatalf("got %d windows, want 3: %+v", len(got), got)
+	}
+	if w := got["@1"]; w.Activity != 1792178362 || w.Dead || w.Command != "claude" || w.Path != "/work/my api" {
+		t.Errorf("@1 = %+v", w)
+	}
+	if w := got["@2"]; !w.Dead || w.Command != "bash" {
+		t.Errorf("@2 = %+v", w)
+	}
+	if w := got["@3"]; w.Command != "" || w.Path != "" {
+		t.Errorf("@3 = %+v", w)
+	}
+}
@@ -0,0 +1,53 @@
+package webhook
+
+import (
+	"encoding/json"
+	"strings"
+	"text/template"
+)
+
+// genericTemplate shows a payload without a template of its own: its "text"
+// or "message" field if it has one, else the JSON itself.
+const genericTemplate = `{{with or .text .message .title}}{{.}}{{else}}` + "```\n{{json . | truncate 1500}}\n```" + `{{end}}`
+
+var funcs = template.FuncMap{
+	// firstLine keeps a commit message's subject
+	"firstLine": func(s string) string {
+		line, _, _ := strings.Cut(s, "\n")
+		return line
+	},
+	"truncate": func(n int, s string) string {
+		if r := []rune(s); len(r) > n {
+			return string(r[:n-1]) + "…"
+		}
+		return s
+	},
+	"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+	"json": func(v any) string {
+		data, _ := json.MarshalIndent(v, "", "  ")
+		return string(data)
+	},
+}
+
+// githubTemplates are the built-in renderings per X-GitHub-Event; other
+// events are not posted.
+var githubTemplates = map[string]*template.Template{}
+
+func init() {
+	for event, text := range map[string]string{
+		"ping": `🔔 GitHub webhook connected for {{or .repository.full_name .organization.login}}`,
+		"push": `{{if .commits}}📦 {{.pusher.name}} pushed {{len .commits}} commit(s) to {{.repository.full_name}}@{{trimPrefix "refs/heads/" .ref}}
+{{range .commits}}• {{firstLine .message | truncate 100}}
+{{end}}{{.compare}}{{end}}`,
+		"pull_request": `{{if eq .action "opened" "closed" "reopened" "ready_for_review"}}🔀 PR #{{.number}} {{if .pull_request.merged}}merged{{else}}{{.action}}{{end}}: {{.pull_request.title}} ({{.sender.login}})
+{{.pull_request.html_url}}{{end}}`,
+		"pull_request_review": `{{if eq .action "submitted"}}👀 {{.review.user.login}} {{.review.state}} PR #{{.pull_request.number}}: {{.pull_request.title}}
+{{.review.html_url}}{{end}}`,
+		"issues": `{{if eq .action "opened" "closed" "reopened"}}🐛 Issue #{{.issue.number}} {{.action}}: {{.issue.title}} ({{.sender.login}})
+{{.issue.html_url}}{{end}}`,
+		"workflow_run": `{{if eq .action "completed"}}{{if eq .workflow_run.conclusion "success"}}✅{{else}}❌{{end}} {{.workflow_run.name}} {{.workflow_run.conclusion}} on {{.workflow_run.head_branch}}
+{{.workflow_run.html_url}}{{end}}`,
+	} {
+		githubTemplates[event] = template.Must(template.New(event).Funcs(funcs).Option("missingkey=zero").Parse(text))
+	}
+}
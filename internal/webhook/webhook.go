@@ -0,0 +1,196 @@
+// Package webhook accepts notifications from external systems (GitHub,
+// alerting) and renders them into a mapped Telegram topic.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// File is the sources file, read from the Tramuntana config directory.
+const File = "webhooks.json"
+
+// maxBodyBytes bounds a webhook payload; GitHub caps its own at 25MB but the
+// events worth posting are far smaller.
+const maxBodyBytes = 1 << 20
+
+// Source is one sender, posting to /hooks/<name>.
+type Source struct {
+	// Kind is "github" (HMAC-signed, with built-in templates per event) or
+	// "generic" (bearer token or ?token=)
+	Kind     string `json:"kind"`
+	Secret   string `json:"secret"`
+	ChatID   int64  `json:"chat_id"`
+	ThreadID int    `json:"thread_id"`
+	// Template renders the JSON payload with text/template; for "github" it
+	// replaces the built-in templates. Output that is only whitespace posts nothing.
+	Template string `json:"template,omitempty"`
+
+	tmpl *template.Template
+}
+
+// Notifier delivers rendered text to a topic.
+type Notifier func(chatID int64, threadID int, text string) error
+
+// LoadSources reads a sources file. A missing file means no sources.
+func LoadSources(path string) (map[string]*Source, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var sources map[string]*Source
+	if err := json.Unmarshal(data, &sources); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for name, s := range sources {
+		if err := s.init(); err != nil {
+			return nil, fmt.Errorf("%s: source %q: %w", path, name, err)
+		}
+	}
+	return sources, nil
+}
+
+func (s *Source) init() error {
+	switch s.Kind {
+	case "":
+		s.Kind = "generic"
+	case "github", "generic":
+	default:
+		return fmt.Errorf("unknown kind %q (want github or generic)", s.Kind)
+	}
+	if s.Secret == "" {
+		return errors.New("secret is required")
+	}
+	if s.ChatID == 0 || s.ThreadID == 0 {
+		return errors.New("chat_id and thread_id are required")
+	}
+	if s.Template == "" && s.Kind == "generic" {
+		s.Template = genericTemplate
+	}
+	if s.Template != "" {
+		t, err := template.New("template").Funcs(funcs).Option("missingkey=zero").Parse(s.Template)
+		if err != nil {
+			return err
+		}
+		s.tmpl = t
+	}
+	return nil
+}
+
+// Handler serves POST /hooks/{source}.
+func Handler(sources map[string]*Source, notify Notifier) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /hooks/{source}", func(w http.ResponseWriter, r *http.Request) {
+		s, ok := sources[r.PathValue("source")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+		if err != nil {
+			http.Error(w, "payload too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		if !s.authorized(r, body) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		text, err := s.render(r.Header.Get("X-GitHub-Event"), body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		if text == "" {
+			w.WriteHeader(http.StatusNoContent) // an event this source doesn't post
+			return
+		}
+		if err := notify(s.ChatID, s.ThreadID, text); err != nil {
+			log.Printf("Webhook %s: %v", r.PathValue("source"), err)
+			http.Error(w, "delivery failed", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+	return mux
+}
+
+// authorized checks GitHub's X-Hub-Signature-256, or for generic sources a
+// bearer token or ?token= (for senders that can't set headers).
+func (s *Source) authorized(r *http.Request, body []byte) bool {
+	if s.Kind == "github" {
+		sig, ok := strings.CutPrefix(r.Header.Get("X-Hub-Signature-256"), "sha256=")
+		if !ok {
+			return false
+		}
+		got, err := hex.DecodeString(sig)
+		if err != nil {
+			return false
+		}
+		mac := hmac.New(sha256.New, []byte(s.Secret))
+		mac.Write(body)
+		return hmac.Equal(got, mac.Sum(nil))
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		token = r.URL.Query().Get("token")
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(s.Secret)) == 1
+}
+
+// render runs the source's template, or GitHub's built-in one for event, over
+// the payload. An empty result means the event is not posted.
+func (s *Source) render(event string, body []byte) (string, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber() // print IDs and counts as written, not as floats
+	var payload any
+	if err := dec.Decode(&payload); err != nil {
+		return "", fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	tmpl := s.tmpl
+	if tmpl == nil {
+		tmpl = githubTemplates[event]
+		if tmpl == nil {
+			return "", nil
+		}
+	}
+	var out strings.Builder
+	if err := tmpl.Execute(&out, payload); err != nil {
+		return "", fmt.Errorf("rendering: %w", err)
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+// Serve runs the endpoint on addr until ctx is cancelled.
+func Serve(ctx context.Context, addr string, sources map[string]*Source, notify Notifier) error {
+	srv := &http.Server{Addr: addr, Handler: Handler(sources, notify), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+	log.Printf("Webhooks listening on %s (%d sources)", addr, len(sources))
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("webhooks: %w", err)
+	}
+	return nil
+}
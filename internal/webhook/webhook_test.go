@@ -0,0 +1,141 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type delivery struct {
+	chatID   int64
+	threadID int
+	text     string
+}
+
+func setup(t *testing.T, file string) (http.Handler, *[]delivery) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), File)
+	os.WriteFile(path, []byte(file), 0o600)
+	sources, err := LoadSources(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []delivery
+	h := Handler(sources, func(chatID int64, threadID int, text string) error {
+		got = append(got, delivery{chatID, threadID, text})
+		return nil
+	})
+	return h, &got
+}
+
+func post(h http.Handler, target, body string, header map[string]string) int {
+	req := httptest.NewRequest("POST", target, strings.NewReader(body))
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func sign(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestGitHubPush(t *testing.T) {
+	h, got := setup(t, `{"api": {"kind": "github", "secret": "gh", "chat_id": -100, "thread_id": 7}}`)
+	body := `{"ref":"refs/heads/main","compare":"https://github.com/o/api/compare/a...b","pusher":{"name":"ana"},
+		"repository":{"full_name":"o/api"},"commits":[{"message":"Fix login\n\nDetails"},{"message":"Add tests"}]}`
+
+	if code := post(h, "/hooks/api", body, map[string]string{"X-GitHub-Event": "push", "X-Hub-Signature-256": sign("wrong", body)}); code != http.StatusUnauthorized {
+		t.Errorf("bad signature: %d", code)
+	}
+	if code := post(h, "/hooks/api", body, map[string]string{"X-GitHub-Event": "push", "X-Hub-Signature-256": sign("gh", body)}); code != http.StatusAccepted {
+		t.Fatalf("signed push: %d", code)
+	}
+	if len(*got) != 1 {
+		t.Fatalf("deliveries = %v", *got)
+	}
+	d := (*got)[0]
+	want := "📦 ana pushed 2 commit(s) to o/api@main\n• Fix login\n• Add tests\nhttps://github.com/o/api/compare/a...b"
+	if d.chatID != -100 || d.threadID != 7 || d.text != want {
+		t.Errorf("delivered %+v, want text %q", d, want)
+	}
+}
+
+func TestGitHubIgnoredEvents(t *testing.T) {
+	h, got := setup(t, `{"api": {"kind": "github", "secret": "gh", "chat_id": -100, "thread_id": 7}}`)
+	for event, body := range map[string]string{
+		"star":         `{"action":"created"}`,
+		"workflow_run": `{"action":"requested","workflow_run":{"name":"CI"}}`,
+	} {
+		if code := post(h, "/hooks/api", body, map[string]string{"X-GitHub-Event": event, "X-Hub-Signature-256": sign("gh", body)}); code != http.StatusNoContent {
+			t.Errorf("%s: %d", event, code)
+		}
+	}
+	if len(*got) != 0 {
+		t.Errorf("deliveries = %v", *got)
+	}
+}
+
+func TestGenericTemplateAndToken(t *testing.T) {
+	h, got := setup(t, `{
+		"alerts": {"secret": "tok", "chat_id": -100, "thread_id": 9, "template": "🚨 {{.alert}} is {{.state}}"},
+		"ci": {"secret": "tok2", "chat_id": -100, "thread_id": 3}
+	}`)
+
+	if code := post(h, "/hooks/alerts", `{"alert":"disk","state":"firing"}`, nil); code != http.StatusUnauthorized {
+		t.Errorf("without token: %d", code)
+	}
+	if code := post(h, "/hooks/alerts?token=tok", `{"alert":"disk","state":"firing"}`, nil); code != http.StatusAccepted {
+		t.Errorf("query token: %d", code)
+	}
+	if code := post(h, "/hooks/ci", `{"text":"deploy finished","id":12345678901}`, map[string]string{"Authorization": "Bearer tok2"}); code != http.StatusAccepted {
+		t.Errorf("bearer token: %d", code)
+	}
+	if code := post(h, "/hooks/ci", `{"build":12345678901}`, map[string]string{"Authorization": "Bearer tok2"}); code != http.StatusAccepted {
+		t.Errorf("bearer token: %d", code)
+	}
+	if code := post(h, "/hooks/nope", `{}`, nil); code != http.StatusNotFound {
+		t.Errorf("unknown source: %d", code)
+	}
+
+	if len(*got) != 3 {
+		t.Fatalf("deliveries = %v", *got)
+	}
+	if d := (*got)[0]; d.threadID != 9 || d.text != "🚨 disk is firing" {
+		t.Errorf("template delivery = %+v", d)
+	}
+	if d := (*got)[1]; d.threadID != 3 || d.text != "deploy finished" {
+		t.Errorf("text field delivery = %+v", d)
+	}
+	if d := (*got)[2]; !strings.Contains(d.text, `"build": 12345678901`) {
+		t.Errorf("JSON fallback = %q", d.text)
+	}
+}
+
+func TestLoadSources_Validation(t *testing.T) {
+	for name, file := range map[string]string{
+		"no secret":    `{"a": {"chat_id": -100, "thread_id": 1}}`,
+		"no topic":     `{"a": {"secret": "s"}}`,
+		"bad kind":     `{"a": {"kind": "gitlab", "secret": "s", "chat_id": -100, "thread_id": 1}}`,
+		"bad template": `{"a": {"secret": "s", "chat_id": -100, "thread_id": 1, "template": "{{.x"}}`,
+	} {
+		path := filepath.Join(t.TempDir(), File)
+		os.WriteFile(path, []byte(file), 0o600)
+		if _, err := LoadSources(path); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if sources, err := LoadSources(filepath.Join(t.TempDir(), File)); err != nil || sources != nil {
+		t.Errorf("missing file = %v, %v", sources, err)
+	}
+}
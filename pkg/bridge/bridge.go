@@ -0,0 +1,132 @@
+// Package bridge runs the Telegram ↔ Claude Code bridge inside another
+// program: the same bot, session monitor, status poller and digests that
+// `tramuntana serve` starts.
+//
+// It is part of tramuntana's public API and follows semantic versioning.
+package bridge
+
+import (
+	"context"
+	"errors"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/config"
+	"github.com/otaviocarvalho/tramuntana/internal/serve"
+)
+
+// Config is the bridge configuration. LoadConfig reads it from the
+// environment and a .env file the way `tramuntana serve` does, including the
+// settings not listed here; fields changed before New override what was read.
+type Config struct {
+	BotToken      string  // Telegram bot token
+	AllowedUsers  []int64 // Telegram users allowed to use the bot
+	AllowedGroups []int64 // group chats the bot answers in; empty allows any
+	StateDir      string  // where state.json and the other state files are kept
+	TmuxSession   string  // tmux session the Claude windows run in
+	ClaudeCommand string  // command that starts Claude in a new window
+
+	loaded *config.Config // everything LoadConfig read
+}
+
+// LoadConfig reads the configuration the way `tramuntana serve` does.
+func LoadConfig(envFile ...string) (*Config, error) {
+	c, err := config.Load(envFile...)
+	if err != nil {
+		return nil, err
+	}
+	return &Config{
+		BotToken:      c.TelegramBotToken,
+		AllowedUsers:  c.AllowedUsers,
+		AllowedGroups: c.AllowedGroups,
+		StateDir:      c.TramuntanaDir,
+		TmuxSession:   c.TmuxSessionName,
+		ClaudeCommand: c.ClaudeCommand,
+		loaded:        c,
+	}, nil
+}
+
+// internal returns the configuration the bridge runs with: what LoadConfig
+// read, with cfg's fields applied.
+func (cfg *Config) internal() (*config.Config, error) {
+	if cfg.loaded == nil {
+		return nil, errors.New("bridge: the Config must come from LoadConfig")
+	}
+	c := cfg.loaded
+	c.TelegramBotToken = cfg.BotToken
+	c.AllowedUsers = cfg.AllowedUsers
+	c.AllowedGroups = cfg.AllowedGroups
+	c.TramuntanaDir = cfg.StateDir
+	c.TmuxSessionName = cfg.TmuxSession
+	c.ClaudeCommand = cfg.ClaudeCommand
+	return c, nil
+}
+
+// Session is a tmux window bound to at least one Telegram topic.
+type Session struct {
+	WindowID  string // tmux window ID, e.g. "@12"
+	Name      string
+	CWD       string
+	SessionID string // Claude session ID; empty until the hook reports it
+}
+
+// Bridge is a running (or ready to run) bridge.
+type Bridge struct {
+	br *serve.Bridge
+}
+
+// New creates a bridge that talks to the Telegram Bot API with cfg's token.
+func New(cfg *Config) (*Bridge, error) {
+	c, err := cfg.internal()
+	if err != nil {
+		return nil, err
+	}
+	br, err := serve.New(c)
+	if err != nil {
+		return nil, err
+	}
+	return &Bridge{br: br}, nil
+}
+
+// NewWithAPI creates a bridge over an existing Bot API client, e.g. one
+// pointed at a test server.
+func NewWithAPI(cfg *Config, api *tgbotapi.BotAPI) (*Bridge, error) {
+	c, err := cfg.internal()
+	if err != nil {
+		return nil, err
+	}
+	br, err := serve.NewWithAPI(c, api)
+	if err != nil {
+		return nil, err
+	}
+	return &Bridge{br: br}, nil
+}
+
+// API returns the Bot API client, so callers can wrap its HTTP client before Run.
+func (b *Bridge) API() *tgbotapi.BotAPI {
+	return b.br.API()
+}
+
+// Run starts the bridge and blocks until ctx is cancelled, then saves its state.
+func (b *Bridge) Run(ctx context.Context) error {
+	return b.br.Run(ctx)
+}
+
+// Sessions lists the windows bound to topics, by window ID.
+func (b *Bridge) Sessions() []Session {
+	var sessions []Session
+	for _, s := range b.br.Sessions() {
+		sessions = append(sessions, Session{WindowID: s.WindowID, Name: s.Name, CWD: s.CWD, SessionID: s.SessionID})
+	}
+	return sessions
+}
+
+// Send types text into a session's window as if it came from its topic.
+// Long text follows the configured long-paste mode; it fails while paused.
+func (b *Bridge) Send(windowID, text string) error {
+	return b.br.Send(windowID, text)
+}
+
+// Capture returns the visible text of a session's window.
+func (b *Bridge) Capture(windowID string) (string, error) {
+	return b.br.Capture(windowID)
+}
@@ -0,0 +1,60 @@
+package bridge
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/otaviocarvalho/tramuntana/internal/testharness"
+)
+
+func TestBridge_LoadConfigRunAndSend(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("TELEGRAM_BOT_TOKEN", "123:test")
+	t.Setenv("ALLOWED_USERS", "100")
+	t.Setenv("TRAMUNTANA_DIR", filepath.Join(home, ".tramuntana"))
+	t.Setenv("MONITOR_POLL_INTERVAL", "0.05")
+	tg := testharness.NewTelegram(t)
+	fake := testharness.NewTmux(t, "tramuntana-test")
+	windowID := fake.AddWindow("api", "/work/api")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.TmuxSession != "tramuntana" || len(cfg.AllowedUsers) != 1 || cfg.AllowedUsers[0] != 100 {
+		t.Errorf("loaded config = %+v", cfg)
+	}
+	cfg.TmuxSession = "tramuntana-test"
+	br, err := NewWithAPI(cfg, tg.API())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- br.Run(ctx) }()
+
+	if err := br.Send(windowID, "hello from the supervisor"); err != nil {
+		t.Fatal(err)
+	}
+	fake.WaitForKeys(windowID, "hello from the supervisor")
+	if sessions := br.Sessions(); len(sessions) != 0 {
+		t.Errorf("sessions = %+v, want none bound", sessions)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after cancel")
+	}
+}
+
+func TestNew_RequiresLoadedConfig(t *testing.T) {
+	if _, err := New(&Config{BotToken: "123:test", AllowedUsers: []int64{100}}); err == nil {
+		t.Error("New accepted a Config that did not come from LoadConfig")
+	}
+}
@@ -0,0 +1,45 @@
+// Package render converts Markdown to Telegram MarkdownV2 and terminal
+// captures to PNG screenshots.
+//
+// It is part of tramuntana's public API and follows semantic versioning.
+package render
+
+import "github.com/otaviocarvalho/tramuntana/internal/render"
+
+// ScreenshotOptions controls screenshot appearance.
+type ScreenshotOptions struct {
+	Theme      string  // "dark" or "light"
+	FontSize   float64 // points
+	LineHeight int     // pixels; 0 derives it from FontSize
+	MaxCols    int     // wrap lines wider than this many cells; 0 disables wrapping
+}
+
+// DefaultScreenshotOptions is the dark 28pt rendering the bot uses.
+var DefaultScreenshotOptions = ScreenshotOptions{
+	Theme:      render.DefaultScreenshotOptions.Theme,
+	FontSize:   render.DefaultScreenshotOptions.FontSize,
+	LineHeight: render.DefaultScreenshotOptions.LineHeight,
+	MaxCols:    render.DefaultScreenshotOptions.MaxCols,
+}
+
+// ToMarkdownV2 converts standard Markdown to Telegram MarkdownV2, escaping
+// everything Telegram would otherwise reject.
+func ToMarkdownV2(text string) string {
+	return render.ToMarkdownV2(text)
+}
+
+// RenderScreenshot renders ANSI terminal text to a PNG image.
+func RenderScreenshot(paneText string) ([]byte, error) {
+	return render.RenderScreenshot(paneText)
+}
+
+// RenderScreenshotWithOptions renders ANSI terminal text to a PNG image with
+// the given theme, font size and wrapping.
+func RenderScreenshotWithOptions(paneText string, opts ScreenshotOptions) ([]byte, error) {
+	return render.RenderScreenshotWithOptions(paneText, render.ScreenshotOptions{
+		Theme:      opts.Theme,
+		FontSize:   opts.FontSize,
+		LineHeight: opts.LineHeight,
+		MaxCols:    opts.MaxCols,
+	})
+}
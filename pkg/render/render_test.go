@@ -0,0 +1,22 @@
+package render
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestToMarkdownV2(t *testing.T) {
+	if got, want := ToMarkdownV2("**done** in 1.5s"), "*done* in 1\\.5s"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestRenderScreenshot(t *testing.T) {
+	png, err := RenderScreenshot("\x1b[32m$\x1b[0m go test ./...")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(png, []byte("\x89PNG")) {
+		t.Error("not a PNG")
+	}
+}
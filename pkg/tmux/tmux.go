@@ -0,0 +1,88 @@
+// Package tmux drives the tmux windows Claude Code sessions run in.
+//
+// It is part of tramuntana's public API and follows semantic versioning.
+package tmux
+
+import (
+	"time"
+
+	"github.com/otaviocarvalho/tramuntana/internal/tmux"
+)
+
+// Window is a tmux window.
+type Window struct {
+	ID   string // e.g. "@12"
+	Name string
+	CWD  string
+}
+
+// EnsureSession creates the tmux session if it does not exist.
+func EnsureSession(session string) error {
+	return tmux.EnsureSession(session)
+}
+
+// ListWindows lists the windows of a session.
+func ListWindows(session string) ([]Window, error) {
+	list, err := tmux.ListWindows(session)
+	if err != nil {
+		return nil, err
+	}
+	windows := make([]Window, len(list))
+	for i, w := range list {
+		windows[i] = Window{ID: w.ID, Name: w.Name, CWD: w.CWD}
+	}
+	return windows, nil
+}
+
+// NewWindow opens a window in dir running command with extra environment
+// variables, and returns its ID.
+func NewWindow(session, name, dir, command string, env map[string]string) (string, error) {
+	return tmux.NewWindow(session, name, dir, command, env)
+}
+
+// SendKeys types literal text into a window, without pressing Enter.
+func SendKeys(session, windowID, text string) error {
+	return tmux.SendKeys(session, windowID, text)
+}
+
+// SendEnter presses Enter in a window.
+func SendEnter(session, windowID string) error {
+	return tmux.SendEnter(session, windowID)
+}
+
+// SendKeysWithDelay types text, waits delayMs, then presses Enter. The bot
+// waits 500ms so Claude's input box has taken the text first.
+func SendKeysWithDelay(session, windowID, text string, delayMs int) error {
+	return tmux.SendKeysWithDelay(session, windowID, text, delayMs)
+}
+
+// PasteText pastes text into a window as one bracketed paste, without
+// pressing Enter.
+func PasteText(session, windowID, text string) error {
+	return tmux.PasteText(session, windowID, text)
+}
+
+// SendSpecialKey sends a named key such as "Escape", "Up" or "C-c".
+func SendSpecialKey(session, windowID, key string) error {
+	return tmux.SendSpecialKey(session, windowID, key)
+}
+
+// CapturePane returns a window's visible text, with ANSI escapes if withAnsi.
+func CapturePane(session, windowID string, withAnsi bool) (string, error) {
+	return tmux.CapturePane(session, windowID, withAnsi)
+}
+
+// KillWindow closes a window.
+func KillWindow(session, windowID string) error {
+	return tmux.KillWindow(session, windowID)
+}
+
+// WaitForReady waits until Claude's prompt is visible in a window.
+func WaitForReady(session, windowID string, timeout time.Duration) bool {
+	return tmux.WaitForReady(session, windowID, timeout)
+}
+
+// IsWindowDead reports whether err means the window no longer exists.
+func IsWindowDead(err error) bool {
+	return tmux.IsWindowDead(err)
+}
@@ -0,0 +1,133 @@
+// Package transcript parses Claude Code JSONL session transcripts, pairing
+// tool calls with their results.
+//
+// It is part of tramuntana's public API and follows semantic versioning.
+package transcript
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/otaviocarvalho/tramuntana/internal/monitor"
+)
+
+// Entry is one parsed JSONL line.
+type Entry struct {
+	Type      string         // "user", "assistant", "summary", "compact"
+	Blocks    []ContentBlock // parsed content blocks
+	Timestamp time.Time      // zero if the entry has no timestamp
+	Usage     TokenUsage     // assistant entries only
+	Model     string         // assistant entries only
+	MessageID string         // assistant entries only; shared by the lines of one API message
+	RawData   json.RawMessage
+}
+
+// TokenUsage holds the token counts reported on an assistant message.
+type TokenUsage struct {
+	InputTokens              int64
+	OutputTokens             int64
+	CacheReadInputTokens     int64
+	CacheCreationInputTokens int64
+}
+
+// ContentBlock is a piece of an entry's message content.
+type ContentBlock struct {
+	Type      string // "text", "tool_use", "tool_result", "thinking", "compact"
+	Text      string // for text and thinking blocks; how it was triggered for compact
+	ToolName  string // for tool_use
+	ToolInput string // for tool_use (summary of input)
+	ToolUseID string // for tool_use and tool_result
+	Content   string // for tool_result
+	IsError   bool   // for tool_result
+}
+
+// ParsedEntry is a user-facing item: text, a tool call or result, thinking,
+// or a compaction boundary.
+type ParsedEntry struct {
+	Role        string // "user", "assistant", "system"
+	ContentType string // "text", "tool_use", "tool_result", "thinking", "compact"
+	Text        string
+	ToolUseID   string
+	ToolName    string
+	ToolInput   string // tool input summary, on tool results
+	IsError     bool
+	Timestamp   time.Time
+	EntryType   string // type of the entry it was parsed from
+}
+
+// PendingTool is a tool call waiting for its result.
+type PendingTool struct {
+	ToolUseID string
+	ToolName  string
+	Input     string
+	Summary   string
+}
+
+// ParseLine parses one JSONL line. It returns nil, nil for lines that carry
+// nothing to show (metadata, unknown types).
+func ParseLine(line []byte) (*Entry, error) {
+	e, err := monitor.ParseLine(line)
+	if e == nil || err != nil {
+		return nil, err
+	}
+	entry := &Entry{
+		Type:      e.Type,
+		Blocks:    make([]ContentBlock, len(e.Blocks)),
+		Timestamp: e.Timestamp,
+		Usage:     TokenUsage(e.Usage),
+		Model:     e.Model,
+		MessageID: e.MessageID,
+		RawData:   e.RawData,
+	}
+	for i, b := range e.Blocks {
+		entry.Blocks[i] = ContentBlock(b)
+	}
+	return entry, nil
+}
+
+// ParseEntries turns entries into parsed items. pending carries unmatched
+// tool calls between calls, so a result read later finds its call; keep one
+// map per transcript.
+func ParseEntries(entries []*Entry, pending map[string]PendingTool) []ParsedEntry {
+	in := make([]*monitor.Entry, len(entries))
+	for i, e := range entries {
+		in[i] = &monitor.Entry{
+			Type:      e.Type,
+			Blocks:    make([]monitor.ContentBlock, len(e.Blocks)),
+			Timestamp: e.Timestamp,
+			Usage:     monitor.TokenUsage(e.Usage),
+			Model:     e.Model,
+			MessageID: e.MessageID,
+			RawData:   e.RawData,
+		}
+		for j, b := range e.Blocks {
+			in[i].Blocks[j] = monitor.ContentBlock(b)
+		}
+	}
+	tools := make(map[string]monitor.PendingTool, len(pending))
+	for id, p := range pending {
+		tools[id] = monitor.PendingTool(p)
+	}
+
+	parsed := monitor.ParseEntries(in, tools)
+
+	clear(pending)
+	for id, p := range tools {
+		pending[id] = PendingTool(p)
+	}
+	out := make([]ParsedEntry, len(parsed))
+	for i, p := range parsed {
+		out[i] = ParsedEntry{
+			Role:        p.Role,
+			ContentType: p.ContentType,
+			Text:        p.Text,
+			ToolUseID:   p.ToolUseID,
+			ToolName:    p.ToolName,
+			ToolInput:   p.ToolInput,
+			IsError:     p.IsError,
+			Timestamp:   p.Timestamp,
+			EntryType:   p.EntryType,
+		}
+	}
+	return out
+}
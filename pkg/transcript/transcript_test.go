@@ -0,0 +1,27 @@
+package transcript
+
+import "testing"
+
+func TestParseToolPairing(t *testing.T) {
+	var entries []*Entry
+	for _, line := range []string{
+		`{"type":"assistant","message":{"content":[{"type":"tool_use","id":"tu_1","name":"Bash","input":{"command":"go test ./..."}}]}}`,
+		`{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"tu_1","content":"ok"}]}}`,
+	} {
+		e, err := ParseLine([]byte(line))
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, e)
+	}
+
+	pending := make(map[string]PendingTool)
+	parsed := ParseEntries(entries[:1], pending)
+	if len(parsed) != 1 || parsed[0].ContentType != "tool_use" || len(pending) != 1 {
+		t.Fatalf("tool_use: parsed %+v, pending %v", parsed, pending)
+	}
+	parsed = ParseEntries(entries[1:], pending)
+	if len(parsed) != 1 || parsed[0].ContentType != "tool_result" || parsed[0].ToolName != "Bash" {
+		t.Errorf("tool_result: %+v", parsed)
+	}
+}